@@ -1,8 +1,12 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"os"
+	"strings"
+	"time"
 
 	"log/slog"
 
@@ -12,13 +16,49 @@ import (
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/sabbatD/srest-api/internal/config"
 	sdb "github.com/sabbatD/srest-api/internal/database"
+	util "github.com/sabbatD/srest-api/internal/http-server/handleUtil"
 	"github.com/sabbatD/srest-api/internal/http-server/handlers/admin"
+	"github.com/sabbatD/srest-api/internal/http-server/handlers/banner"
+	"github.com/sabbatD/srest-api/internal/http-server/handlers/batch"
+	"github.com/sabbatD/srest-api/internal/http-server/handlers/meta"
+	"github.com/sabbatD/srest-api/internal/http-server/handlers/oidc"
+	"github.com/sabbatD/srest-api/internal/http-server/handlers/report"
+	"github.com/sabbatD/srest-api/internal/http-server/handlers/scim"
 	"github.com/sabbatD/srest-api/internal/http-server/handlers/todo"
 	"github.com/sabbatD/srest-api/internal/http-server/handlers/user"
 	httpSwagger "github.com/swaggo/http-swagger"
 
+	"github.com/sabbatD/srest-api/internal/lib/alerting"
 	"github.com/sabbatD/srest-api/internal/lib/api/access"
+	"github.com/sabbatD/srest-api/internal/lib/api/chaos"
+	"github.com/sabbatD/srest-api/internal/lib/api/deadline"
+	"github.com/sabbatD/srest-api/internal/lib/api/ratelimit"
+	"github.com/sabbatD/srest-api/internal/lib/api/readonly"
+	"github.com/sabbatD/srest-api/internal/lib/api/routes"
+	"github.com/sabbatD/srest-api/internal/lib/api/spec"
+	"github.com/sabbatD/srest-api/internal/lib/backup"
+	"github.com/sabbatD/srest-api/internal/lib/cache"
+	"github.com/sabbatD/srest-api/internal/lib/clients"
+	"github.com/sabbatD/srest-api/internal/lib/clock"
+	"github.com/sabbatD/srest-api/internal/lib/expiry"
+	"github.com/sabbatD/srest-api/internal/lib/flight"
+	"github.com/sabbatD/srest-api/internal/lib/geoip"
+	"github.com/sabbatD/srest-api/internal/lib/jobs"
+	"github.com/sabbatD/srest-api/internal/lib/ldap"
+	"github.com/sabbatD/srest-api/internal/lib/lockout"
 	"github.com/sabbatD/srest-api/internal/lib/logger/sl"
+	"github.com/sabbatD/srest-api/internal/lib/mail"
+	m "github.com/sabbatD/srest-api/internal/lib/meta"
+	"github.com/sabbatD/srest-api/internal/lib/metrics"
+	"github.com/sabbatD/srest-api/internal/lib/moderation"
+	"github.com/sabbatD/srest-api/internal/lib/oauth"
+	"github.com/sabbatD/srest-api/internal/lib/queries"
+	"github.com/sabbatD/srest-api/internal/lib/retention"
+	rc "github.com/sabbatD/srest-api/internal/lib/roleConfig"
+	"github.com/sabbatD/srest-api/internal/lib/secrets"
+	"github.com/sabbatD/srest-api/internal/lib/support"
+	"github.com/sabbatD/srest-api/internal/password"
+	"github.com/sabbatD/srest-api/internal/storage/blob"
 )
 
 // @title           sAPI
@@ -42,83 +82,494 @@ import (
 
 // @schemes http https
 func main() {
+	started := time.Now()
 	cfg := config.MustLoad()
 
-	log := sl.SetupLogger(cfg.Env)
-	log.Info("Starting sAPI server")
+	// The last errors are kept for support bundles
+	recent := sl.NewRecent(200, slog.LevelError)
+	log := slog.New(recent.Handler(sl.SetupLogger(cfg.Env).Handler()))
+	log.Info("Starting sAPI server", slog.String("version", m.Version), slog.String("commit", m.BuildCommit()), slog.String("env", cfg.Env))
 	log.Debug("Debug mode enabled")
 
-	storage, err := sdb.SetupDataBase(cfg.DbString, cfg.Env)
+	if cfg.Clock.Frozen != "" {
+		if cfg.Env == "prod" {
+			log.Error("Frozen clock is not available in prod, ignoring it")
+		} else {
+			frozen, err := clock.FromConfig(cfg.Clock)
+			if err != nil {
+				log.Error("Failed to setup clock", sl.Err(err))
+				os.Exit(1)
+			}
+			clock.Set(frozen)
+			log.Warn("Clock frozen", slog.Time("now", frozen.Now()))
+		}
+	}
+
+	key, err := access.LoadKey(cfg.JWT.Algorithm, cfg.JWT.Key, cfg.JWT.KeyFile)
+	switch {
+	case errors.Is(err, access.ErrNoKey) && cfg.Env == "local":
+		log.Warn("JWT signing key is not set, using a random one: tokens are invalid after a restart")
+		if key, err = access.GenerateKey(cfg.JWT.Algorithm); err != nil {
+			log.Error("Failed to generate JWT signing key", sl.Err(err))
+			os.Exit(1)
+		}
+	case err != nil:
+		log.Error("Failed to load JWT signing key", sl.Err(err))
+		os.Exit(1)
+	default:
+		log.Info("JWT signing key loaded", slog.String("algorithm", key.Algorithm()))
+	}
+
+	storage, err := sdb.SetupDataBase(cfg.DbString, cfg.Env, log, cfg.SlowQuery)
 	if err != nil {
 		log.Error("Failed to setup database", sl.Err(err))
 		os.Exit(1)
 	}
+	access.SetDenylist(storage)
+	access.SetRoles(storage)
+	access.SetCookies(access.Cookies(cfg.Sessions.Cookies))
+
+	// Rotated signing keys are stored and take over from the configured one, retired keys verify
+	// as long as the longest lived token
+	keys := access.NewRotator(log, storage, key, max(access.AccessTTL, cfg.Guests.TokenTTL), cfg.JWT.ReloadInterval)
+	if err := keys.Load(context.Background()); err != nil {
+		log.Error("Failed to load JWT signing keys", sl.Err(err))
+		os.Exit(1)
+	}
+	go keys.Run(context.Background())
+
+	// Sign ins are located by the GeoIP database when one is configured, it is reloaded when its file changes
+	geo, err := geoip.Open(log, cfg.GeoIP)
+	if err != nil {
+		log.Error("Failed to open GeoIP database", sl.Err(err))
+		os.Exit(1)
+	}
+	go geo.Run(context.Background())
+	signIns := user.NewSignIns(storage, geo)
+
+	mod, err := moderation.FromConfig(log, cfg.Moderation, storage)
+	if err != nil {
+		log.Error("Failed to setup moderation", sl.Err(err))
+		os.Exit(1)
+	}
+
+	policy, err := password.NewPolicy(cfg.PasswordPolicy)
+	if err != nil {
+		log.Error("Failed to load password policy", sl.Err(err))
+		os.Exit(1)
+	}
+
+	store, err := blob.New(cfg.Blob)
+	if err != nil {
+		log.Error("Failed to setup blob storage", sl.Err(err))
+		os.Exit(1)
+	}
+
+	// Webhooks are delivered by background jobs retried with backoff, the ones out of attempts are kept for admins
+	work := jobs.New(log, storage, cfg.Jobs)
+
+	backups := backup.FromConfig(log, cfg.Backups, cfg.DbString, store, storage)
+	// Without workers the jobs would never run, webhooks are then posted once in place
+	if cfg.Jobs.Workers > 0 {
+		backups.SetJobs(work)
+	}
+	go backups.Run(context.Background())
+
+	purge := retention.New(log, storage, cfg.Retention)
+	go purge.Run(context.Background())
+
+	// Read-only mode is an admin setting every instance reloads, or forced by the configuration
+	readOnly := readonly.New(log, storage, cfg.ReadOnly.Enabled, cfg.ReadOnly.Refresh)
+	if err := readOnly.Refresh(context.Background()); err != nil {
+		log.Error("failed to load the read-only mode", sl.Err(err))
+	}
+	go readOnly.Run(context.Background())
+
+	// Sign in checks the LDAP directory first when one is configured
+	var directory user.Directory
+	if cfg.LDAP.URL != "" {
+		directory = ldap.New(cfg.LDAP)
+	}
+
+	todoWrites := ratelimit.New("todo_writes", cfg.RateLimits.TodoWrites, cfg.RateLimits.Window)
+	reports := ratelimit.New("reports", cfg.RateLimits.Reports, cfg.RateLimits.Window)
+	guests := ratelimit.New("guests", cfg.RateLimits.Guests, cfg.RateLimits.Window)
+	forgot := ratelimit.New("password_forgot", cfg.RateLimits.PasswordForgot, cfg.RateLimits.Window)
+	resends := ratelimit.New("verify_email", cfg.RateLimits.VerifyEmail, cfg.RateLimits.Window)
+
+	// Admins override the rate limits per user, the overrides are stored and applied on startup
+	rates := admin.RateLimits{TodoWrites: todoWrites, Reports: reports}
+	overrides, err := storage.AllUserLimits(context.Background())
+	if err != nil {
+		log.Error("Failed to load user limits", sl.Err(err))
+		os.Exit(1)
+	}
+	for id, l := range overrides {
+		rates.Apply(id, l)
+	}
+
+	latency := metrics.NewLatency(cfg.Metrics.LatencySamples)
+	// Requests by client, to know who still calls a route before removing it
+	usage := clients.New(log, storage, cfg.Clients)
+	go usage.Run(context.Background())
+
+	// Integration secrets set by admins are stored encrypted, they need a master key
+	var vault *secrets.Secrets
+	cipher, err := secrets.Load(cfg.Secrets)
+	switch {
+	case errors.Is(err, secrets.ErrNoKey):
+		log.Warn("Secrets master key is not set, admins cannot set integration secrets")
+	case err != nil:
+		log.Error("Failed to load secrets master key", sl.Err(err))
+		os.Exit(1)
+	default:
+		vault = secrets.New(storage, cipher)
+		// Secrets sealed with a previous key are resealed, the previous keys can be dropped afterwards
+		n, err := vault.Rekey(context.Background())
+		if err != nil {
+			log.Error("Failed to reseal secrets", sl.Err(err))
+			os.Exit(1)
+		}
+		if n > 0 {
+			log.Info("Secrets resealed with the current master key", slog.Int("count", n))
+		}
+	}
+
+	mailer := mail.New(cfg.SMTP)
+	mailer.SetSecrets(vault)
+
+	// Sign in with Google or GitHub for the providers with a configured client
+	social := oauth.New(cfg.OAuth)
+	social.SetSecrets(vault)
+	// Emails are rendered from the templates admins set, or the built-in ones
+	templates := mail.NewTemplates(storage, mailer)
+
+	// New accounts confirm their email while email is configured
+	verify := &user.Verification{Store: storage, Mail: templates, TTL: cfg.EmailVerification.TTL, Link: cfg.EmailVerification.Link}
+	// Sign ins from a new country or device wait for the user to confirm them by email
+	var confirm *user.Confirmation
+	if cfg.SignInConfirmation.Enabled {
+		confirm = &user.Confirmation{Store: storage, Mail: templates, NewCountry: cfg.SignInConfirmation.NewCountry,
+			NewDevice: cfg.SignInConfirmation.NewDevice, TTL: cfg.SignInConfirmation.TTL, Link: cfg.SignInConfirmation.Link}
+	}
+
+	alerts := alerting.New(log, storage, latency, templates, cfg.Alerting)
+	alerts.SetSecrets(vault)
+	if cfg.Jobs.Workers > 0 {
+		alerts.SetJobs(work)
+	}
+	go alerts.Run(context.Background())
+	go work.Run(context.Background())
+
+	passwords := expiry.New(log, storage, templates, cfg.PasswordExpiry)
+	go passwords.Run(context.Background())
+
+	// Reads of users' own data are cached by version, each write of a user changes theirs
+	versions := cache.NewVersions()
+	heatmaps := cache.New("heatmap", todo.HeatmapTTL, todo.HeatmapMaxEntries)
+	profiles := cache.New("profile", cfg.Cache.ProfileTTL, cfg.Cache.MaxEntries)
+	lists := cache.New("todos", cfg.Cache.TodosTTL, cfg.Cache.MaxEntries)
+	caches := cache.Group{heatmaps, profiles, lists}
+
+	bundle := support.New(cfg, recent, storage, latency)
+
+	reportQueries, err := queries.New(storage, cfg.Queries)
+	if err != nil {
+		log.Error("Failed to load report queries", sl.Err(err))
+		os.Exit(1)
+	}
+
+	about := deploymentInfo(cfg, mailer.Enabled(), mod != nil)
 
 	route := chi.NewRouter()
+	route.Use(m.Middleware)
+
+	// Outside the API base path for load balancers and incident response
+	route.Get("/healthz", meta.Healthz(log, started))
+	route.Get("/.well-known/jwks.json", meta.JWKS(log))
+
+	// Every API route declares who may call it and its rate limit class here,
+	// the router and the served spec are both built from the declarations.
+	reg := routes.New()
+	reg.Auth(routes.Token, access.AnyTokenMiddleware)
+	reg.Auth(routes.PasswordChange, access.PasswordChangeMiddleware)
+	reg.Auth(routes.User, access.JWTAuthMiddleware)
+	reg.Auth(routes.Guest, access.GuestAuthMiddleware)
+	// All of admin handlers use AdmCheck.
+	reg.Auth(routes.Admin, access.JWTAuthMiddleware)
+	reg.Scopes(func(scopes []string) routes.Middleware { return access.RequireScope(scopes...) })
+	reg.RequireScopes(routes.Admin)
+	// Routes that write answer 503 READ_ONLY while read-only mode is on, see routes.AllowReadOnly
+	reg.ReadOnly(readOnly.Middleware)
+	reg.Limit("todo_writes", todoWrites.Middleware(access.UserKey))
+	reg.Limit("reports", reports.Middleware(access.UserKey))
+	reg.Limit("guests", guests.Middleware(access.IPKey))
+	reg.Limit("password_forgot", forgot.Middleware(access.IPKey))
+	reg.Limit("verify_email", resends.Middleware(access.IPKey))
+
+	// swagger endpoint
+	// The spec only changes with a new build, so it is cached for an hour from the start time.
+	swagger := routes.With(util.Cache(time.Hour, started, false))
+	// The UI toggles between the languages of /openapi.json
+	reg.Get("/swagger/*", httpSwagger.Handler(httpSwagger.UIConfig(spec.SwaggerUI("/api/v1/openapi.json"))), routes.WithAuth(routes.Public), swagger)
+	// The spec of the routes this build registers, for client generation
+	reg.Get("/openapi.json", meta.OpenAPI(log, route, reg.Routes, "/api/v1"), routes.WithAuth(routes.Public), swagger)
+
+	// Unknown users handlers
+	authRoutes := reg.Group("/auth", routes.WithAuth(routes.Public), routes.With(deadline.New(cfg.Deadlines.Auth)))
+	authRoutes.Post("/signup", user.Register(log, storage, mod, policy, verify))
+	authRoutes.Post("/signin", user.Auth(log, storage, directory, lockout.New(storage, cfg.Lockout), signIns, confirm, cfg.Sessions.RefreshTTL,
+		cfg.Sessions.RememberTTL, cfg.EmailVerification.Required), routes.AllowReadOnly())
+	authRoutes.Get("/signin/confirm", user.ConfirmSignIn(log, storage))
+	authRoutes.Post("/signin/pending", user.CompleteSignIn(log, storage, storage, signIns, cfg.Sessions.RefreshTTL, cfg.Sessions.RememberTTL),
+		routes.AllowReadOnly())
+	authRoutes.Post("/refresh", user.Refresh(log, storage, cfg.Sessions.RefreshTTL, cfg.Sessions.RememberTTL), routes.AllowReadOnly())
+	authRoutes.Get("/{provider}/login", user.OAuthLogin(log, social))
+	authRoutes.Get("/{provider}/callback", user.OAuthCallback(log, storage, social, signIns, confirm, cfg.Sessions.RefreshTTL, cfg.EmailVerification.Required))
+	// Under /auth to receive the device cookie of a remembered device
+	authRoutes.Post("/logout", user.Logout(log, storage), routes.WithAuth(routes.Token), routes.AllowReadOnly())
+
+	passwordRoutes := reg.Group("/password", routes.WithAuth(routes.Public), routes.With(deadline.New(cfg.Deadlines.Auth)))
+	passwordRoutes.Post("/forgot", user.ForgotPassword(log, storage, templates, cfg.PasswordResets.ForgotTTL, cfg.PasswordResets.Link),
+		routes.WithLimit("password_forgot"))
+	passwordRoutes.Post("/reset", user.ResetPassword(log, storage, policy))
+
+	verifyRoutes := reg.Group("/verify-email", routes.WithAuth(routes.Public), routes.With(deadline.New(cfg.Deadlines.Auth)))
+	verifyRoutes.Get("/", user.VerifyEmail(log, storage))
+	verifyRoutes.Post("/resend", user.ResendVerification(log, verify), routes.WithLimit("verify_email"))
+
+	// Guest sessions, limited to the todo routes until the guest signs up
+	reg.Post("/guest", user.Guest(log, storage, cfg.Guests.MaxTodos, cfg.Guests.TokenTTL),
+		routes.WithAuth(routes.Public), routes.WithLimit("guests"), routes.With(deadline.New(cfg.Deadlines.Auth)))
+
+	// Authenticated user handlers
+	userRoutes := reg.Group("/user", routes.WithAuth(routes.User), routes.With(deadline.New(cfg.Deadlines.Default), versions.Middleware(access.UserID)))
+	// Users who must change their password may only do that
+	userRoutes.Put("/profile/reset-password", user.ChangePassword(log, storage, policy), routes.WithAuth(routes.PasswordChange))
+	userRoutes.Put("/password", user.UpdatePassword(log, storage, policy), routes.WithAuth(routes.PasswordChange))
+	userRoutes.Get("/profile", user.Profile(log, storage, passwords, cache.NewUserCache(profiles, versions)))
+	userRoutes.Get("/profile/fields", user.ProfileFields(log, storage))
+	userRoutes.Put("/profile", user.UpdateUser(log, storage, mod))
+	userRoutes.Put("/profile/login", user.ChangeLogin(log, storage, cfg.Logins.ChangeCooldown, cfg.Logins.ReleaseHold))
+	userRoutes.Get("/devices", user.Devices(log, storage))
+	userRoutes.Delete("/devices/{device}", user.ForgetDevice(log, storage))
+
+	// Authenticated admin handlers
+	r := reg.Group("/admin", routes.WithAuth(routes.Admin), routes.With(deadline.New(cfg.Deadlines.Admin)))
+
+	// Every admin route demands permissions of the token, granted by the role of the user, see access.RequireScope
+	read, write, block := routes.WithScopes(rc.UsersRead), routes.WithScopes(rc.UsersWrite), routes.WithScopes(rc.UsersBlock)
+	system, systemWrite := routes.WithScopes(rc.SystemRead), routes.WithScopes(rc.SystemWrite)
+	settings, settingsWrite := routes.WithScopes(rc.SettingsRead), routes.WithScopes(rc.SettingsWrite)
+	moderation, moderationWrite := routes.WithScopes(rc.ModerationRead), routes.WithScopes(rc.ModerationWrite)
+
+	r.Get("/users", admin.All(log, storage, flight.New("admin_users")), read)
+
+	r.Get("/users/{id}", admin.Profile(log, storage), read)
+
+	// Writes to a user change the user's cache version, like the user's own writes do
+	target := routes.With(versions.Middleware(admin.TargetUser(storage)))
+	r.Put("/users/{id}", admin.UpdateUser(log, storage, mod), target, write)
+	r.Delete("/users/{id}", admin.Remove(log, storage), target, routes.WithScopes(rc.UsersDelete))
+
+	r.Post("/users/{id}/block", admin.Block(log, storage), target, block)
+	r.Post("/users/{id}/unblock", admin.Unblock(log, storage), target, block)
+	r.Post("/users/{id}/require-password-change", admin.RequirePasswordChange(log, storage), target, write)
+	r.Post("/users/{id}/rights", admin.Update(log, storage), target, write)
+	r.Post("/users/merge", admin.Merge(log, storage, versions), write)
+	r.Post("/users/{id}/todos/restore", admin.RestoreTodos(log, storage), target, write)
+	r.Get("/users/{id}/limits", admin.UserLimits(log, storage), read)
+	r.Get("/users/{id}/sign-ins", admin.SignIns(log, storage), read)
+	r.Put("/users/{id}/limits", admin.SetUserLimits(log, storage, rates), write)
+	r.Put("/users/{id}/role", admin.SetUserRole(log, storage), target, write, routes.WithScopes(rc.RolesWrite))
+	r.Post("/users/{id}/reset-credentials", admin.ResetCredentials(log, storage, templates, cfg.PasswordResets.TokenTTL, cfg.PasswordResets.Link), target, write)
+
+	// Accounts created by admins need no verification
+	r.Post("/users/registrate", user.Register(log, storage, mod, policy, nil), write)
+
+	roles := routes.WithScopes(rc.RolesWrite)
+	r.Get("/roles", admin.Roles(log, storage), routes.WithScopes(rc.RolesRead))
+	r.Post("/roles", admin.CreateRole(log, storage), roles)
+	r.Put("/roles/{name}", admin.UpdateRole(log, storage), roles)
+	r.Delete("/roles/{name}", admin.DeleteRole(log, storage), roles)
+
+	r.Get("/metrics", admin.Metrics(log), system)
+	r.Get("/metrics/summary", admin.MetricsSummary(log, latency), system)
+
+	r.Post("/jwt/rotate", admin.RotateKey(log, keys), systemWrite)
+
+	r.Post("/cache/invalidate", admin.InvalidateCache(log, caches, storage), systemWrite)
+	r.Get("/cache/stats", admin.CacheStats(log, caches), system)
+
+	r.Post("/backups", admin.StartBackup(log, backups), systemWrite)
+	r.Get("/backups", admin.ListBackups(log, backups), system)
+
+	r.Get("/jobs", admin.JobQueues(log, work), system)
+	r.Get("/jobs/dead", admin.DeadJobs(log, work), system)
+	r.Post("/jobs/dead/{id}/requeue", admin.RequeueDeadJob(log, work), systemWrite)
+	r.Delete("/jobs/dead/{id}", admin.DeleteDeadJob(log, work), systemWrite)
+
+	r.Get("/settings/retention", admin.Retention(log, purge), settings)
+	r.Put("/settings/retention", admin.SetRetention(log, purge), settingsWrite)
+	r.Get("/settings/alerting", admin.Alerting(log, alerts), settings)
+	r.Put("/settings/alerting", admin.SetAlerting(log, alerts), settingsWrite)
+	r.Get("/settings/password-expiry", admin.PasswordExpiry(log, passwords), settings)
+	r.Put("/settings/password-expiry", admin.SetPasswordExpiry(log, passwords), settingsWrite)
+	r.Get("/settings/user-fields", admin.UserFields(log, storage), settings)
+	r.Put("/settings/user-fields", admin.SetUserFields(log, storage), settingsWrite)
+	r.Get("/settings/read-only", admin.ReadOnly(log, readOnly), settings)
+	r.Put("/settings/read-only", admin.SetReadOnly(log, readOnly), settingsWrite, routes.AllowReadOnly())
+
+	secrets := routes.WithScopes(rc.SecretsWrite)
+	r.Get("/secrets", admin.Secrets(log, vault), secrets)
+	r.Put("/secrets/{name}", admin.SetSecret(log, vault), secrets)
+	r.Delete("/secrets/{name}", admin.DeleteSecret(log, vault), secrets)
+
+	r.Get("/templates", admin.Templates(log, templates), settings)
+	r.Get("/templates/{name}", admin.Template(log, templates), settings)
+	r.Put("/templates/{name}", admin.SetTemplate(log, templates), settingsWrite)
+	r.Delete("/templates/{name}", admin.ResetTemplate(log, templates), settingsWrite)
+	r.Post("/templates/{name}/preview", admin.PreviewTemplate(log, templates), settingsWrite, routes.AllowReadOnly())
+
+	r.Get("/support-bundle", admin.SupportBundle(log, bundle), system)
+
+	r.Get("/queries", admin.Queries(log, reportQueries), system)
+	r.Post("/query", admin.RunQuery(log, reportQueries), systemWrite, routes.AllowReadOnly())
+
+	r.Get("/moderation/flagged", admin.Flagged(log, storage), moderation)
+
+	r.Get("/reports", report.All(log, storage), moderation)
+	r.Get("/reports/clients", admin.Clients(log, storage), system)
+	r.Post("/reports/{id}/resolve", report.Resolve(log, storage), moderationWrite)
+	r.Post("/reports/{id}/dismiss", report.Dismiss(log, storage), moderationWrite)
+
+	banners := routes.WithScopes(rc.BannersWrite)
+	r.Get("/banners", banner.All(log, storage), banners)
+	r.Post("/banners", banner.Create(log, storage), banners)
+	r.Put("/banners/{id}", banner.Update(log, storage), banners)
+	r.Delete("/banners/{id}", banner.Delete(log, storage), banners)
+
+	// SCIM provisioning for identity providers, authenticated with its own token
+	if cfg.SCIM.Token != "" {
+		reg.Auth(routes.SCIM, scim.Auth(cfg.SCIM.Token))
+
+		r := reg.Group("/scim/v2/Users", routes.WithAuth(routes.SCIM), routes.With(deadline.New(cfg.Deadlines.Admin)))
+		r.Get("/", scim.List(log, storage))
+		r.Post("/", scim.Create(log, storage))
+		r.Get("/{id}", scim.Get(log, storage))
+		r.Put("/{id}", scim.Replace(log, storage))
+		r.Patch("/{id}", scim.Patch(log, storage))
+		r.Delete("/{id}", scim.Delete(log, storage))
+	}
+
+	// OpenID Connect provider for other tools, their users sign in with sAPI accounts.
+	// ID tokens are verified with the published JWKS, a shared HS256 secret cannot be published.
+	if cfg.OIDC.Issuer != "" {
+		if key.Algorithm() == access.HS256 {
+			log.Error("OpenID Connect needs an RS256 or ES256 JWT signing key")
+			os.Exit(1)
+		}
+
+		issuer := strings.TrimSuffix(cfg.OIDC.Issuer, "/")
+		provider := &oidc.Provider{
+			Store:      storage,
+			Issuer:     issuer,
+			API:        issuer + "/api/v1",
+			Algorithm:  key.Algorithm(),
+			LoginURL:   cfg.OIDC.LoginURL,
+			ConsentURL: cfg.OIDC.ConsentURL,
+			CodeTTL:    cfg.OIDC.CodeTTL,
+		}
+		route.Get("/.well-known/openid-configuration", oidc.Discover(log, provider))
+
+		reg.Auth(routes.OIDC, oidc.Auth)
+		o := reg.Group("/oidc", routes.WithAuth(routes.Public), routes.With(deadline.New(cfg.Deadlines.Auth)))
+		o.Get("/authorize", oidc.Authorize(log, provider))
+		o.Post("/token", oidc.Token(log, provider), routes.AllowReadOnly())
+		o.Get("/userinfo", oidc.GetUserInfo(log, provider), routes.WithAuth(routes.OIDC))
+		// The consent screen answers for the signed in user
+		o.Get("/consent", oidc.Consent(log, provider), routes.WithAuth(routes.User))
+		o.Post("/consent", oidc.Decide(log, provider), routes.WithAuth(routes.User))
+
+		userRoutes.Get("/authorized-apps", oidc.AuthorizedApps(log, storage))
+		userRoutes.Delete("/authorized-apps/{id}", oidc.RevokeApp(log, storage))
+
+		clients := routes.WithScopes(rc.ClientsWrite)
+		r.Get("/oidc/clients", oidc.Clients(log, storage), clients)
+		r.Post("/oidc/clients", oidc.CreateClient(log, storage), clients)
+		r.Delete("/oidc/clients/{id}", oidc.DeleteClient(log, storage), clients)
+	}
+
+	// Deployment metadata only changes with a restart
+	reg.Get("/meta", meta.Get(log, about), routes.WithAuth(routes.Public), routes.With(util.Cache(time.Hour, started, false)))
+
+	// Banners are shown before sign in too, a bearer token only adds the banners for its audience
+	reg.Get("/banners", banner.Active(log, storage), routes.WithAuth(routes.Public), routes.With(deadline.New(cfg.Deadlines.Default)))
+
+	// Sub-requests go through the whole API again, each with its own middleware
+	reg.Post("/batch", batch.Handle(log, route, "/api/v1"), routes.WithAuth(routes.Public), routes.AllowReadOnly())
+
+	// Abuse reports
+	reg.Group("/reports", routes.WithAuth(routes.User), routes.WithLimit("reports"), routes.With(deadline.New(cfg.Deadlines.Default))).
+		Post("/", report.Create(log, storage))
+
+	// Todo handlers
+	// Every task route is scoped to the authenticated user, todo.Ownership hides tasks of other users.
+	// Guests may use them too, their todo count is limited by the storage.
+	t := reg.Group("/todos", routes.WithAuth(routes.Guest), routes.WithLimit("todo_writes"), routes.With(versions.Middleware(access.UserID)))
+
+	// Long polling outlives the default deadline
+	t.Get("/changes", todo.Changes(log, storage), routes.With(deadline.New(cfg.Deadlines.LongPoll)))
+
+	t = t.Group("", routes.With(deadline.New(cfg.Deadlines.Default)))
+	t.Post("/", todo.Create(log, storage, mod))
+	t.Get("/", todo.GetAll(log, storage, cache.NewUserCache(lists, versions), flight.New("todos"), cfg.Cache.MaxListSize))
+	t.Post("/sync", todo.Sync(log, storage, mod))
+	t.Get("/calendar", todo.Calendar(log, storage))
+	t.Get("/heatmap", todo.Heatmap(log, storage, heatmaps))
+	t.Get("/filters", todo.Filters(log, storage))
+	t.Post("/filters", todo.CreateFilter(log, storage))
+	t.Get("/filters/{filter}/todos", todo.ApplyFilter(log, storage))
+	t.Delete("/filters/{filter}", todo.DeleteFilter(log, storage))
+	t.Get("/fields", todo.Fields(log, storage))
+	t.Put("/fields", todo.SetFields(log, storage))
+	t.Get("/workflow", todo.Workflow(log, storage))
+	t.Put("/workflow", todo.SetWorkflow(log, storage))
+
+	t = t.Group("/{id}", routes.With(todo.Ownership(log, storage)))
+	t.Get("/", todo.Get(log, storage))
+	t.Put("/", todo.Update(log, storage, mod))
+	t.Delete("/", todo.Delete(log, storage))
+	t.Post("/pin", todo.Pin(log, storage))
+	t.Post("/unpin", todo.Unpin(log, storage))
+	t.Post("/duplicate", todo.Duplicate(log, storage))
+	t.Put("/blocked-by/{blocker}", todo.AddBlocker(log, storage))
+	t.Delete("/blocked-by/{blocker}", todo.RemoveBlocker(log, storage))
+
 	route.Route("/api/v1", func(router chi.Router) {
 
 		router.Use(middleware.RequestID)
+		router.Use(latency.Middleware)
+		router.Use(usage.Middleware)
+		router.Use(sl.Middleware(log))
 		router.Use(middleware.Logger)
 		router.Use(middleware.Recoverer)
+		// Faults are injected after logging and the latency summary, so they show up there
+		if cfg.Chaos.Enabled {
+			if cfg.Env == "prod" {
+				log.Error("Chaos mode is not available in prod, ignoring it")
+			} else {
+				log.Warn("Chaos mode enabled, requests get injected latency and errors", slog.Int("rules", len(cfg.Chaos.Rules)))
+				router.Use(chaos.New(cfg.Chaos).Middleware)
+			}
+		}
 		router.Use(middleware.URLFormat)
 		router.Use(CORSMiddleware)
 
-		// swagger endpoint
-		if cfg.Env != "prod" {
-			router.Get("/swagger/*", httpSwagger.Handler(
-				httpSwagger.URL("http://51.250.113.72:8082/api/v1/swagger/doc.json"),
-			))
-		} else {
-			router.Get("/swagger/*", httpSwagger.Handler(
-				httpSwagger.URL("https://easydev.club/api/v1/swagger/doc.json"),
-			))
+		if err := reg.Mount(router); err != nil {
+			log.Error("Failed to register routes", sl.Err(err))
+			os.Exit(1)
 		}
-
-		// Unknown users handlers
-		router.Route("/auth", func(u chi.Router) {
-			u.Post("/signup", user.Register(log, storage))
-			u.Post("/signin", user.Auth(log, storage))
-			u.Post("/refresh", user.Refresh(log, storage))
-		})
-
-		// Authenticated user handlers
-		// JWTAuthMiddleware used for authenticating users with jwt token from heade with prefix "Bearer "
-		router.Route("/user", func(u chi.Router) {
-			u.Use(access.JWTAuthMiddleware)
-
-			u.Get("/profile", user.Profile(log, storage))
-			u.Put("/profile", user.UpdateUser(log, storage))
-			u.Put("/profile/reset-password", user.ChangePassword(log, storage))
-		})
-
-		// Authenticated admin handlers
-		// JWTAuthMiddleware used for authenticating users with jwt token from heade with prefix "Bearer "
-		// All of handlers use AdmCheck.
-		router.Route("/admin", func(r chi.Router) {
-			r.Use(access.JWTAuthMiddleware)
-
-			r.Get("/users", admin.All(log, storage))
-
-			r.Get("/users/{id}", admin.Profile(log, storage))
-			r.Put("/users/{id}", admin.UpdateUser(log, storage))
-			r.Delete("/users/{id}", admin.Remove(log, storage))
-
-			r.Post("/users/{id}/block", admin.Block(log, storage))
-			r.Post("/users/{id}/unblock", admin.Unblock(log, storage))
-			r.Post("/users/{id}/rights", admin.Update(log, storage))
-
-			r.Post("/users/registrate", user.Register(log, storage))
-		})
-
-		router.Route("/todos", func(t chi.Router) {
-			t.Get("/{id}", todo.Get(log, storage))
-			t.Put("/{id}", todo.Update(log, storage))
-			t.Delete("/{id}", todo.Delete(log, storage))
-		})
-
-		// Todo handlers
-		router.Post("/todos", todo.Create(log, storage))
-		router.Get("/todos", todo.GetAll(log, storage))
 	})
 
 	log.Info("starting server", slog.String("address", cfg.Address))
@@ -137,6 +588,42 @@ func main() {
 	log.Error("server stopped")
 }
 
+// deploymentInfo lists the features and sign in methods this deployment is configured with for GET /meta
+func deploymentInfo(cfg *config.Config, email, moderation bool) m.Info {
+	features := []string{m.FeatureGuests, m.FeatureRememberMe, m.FeatureBatch}
+	auth := []string{m.AuthPassword, m.AuthGuest}
+	if email {
+		features = append(features, m.FeatureEmail)
+		if cfg.EmailVerification.Required {
+			features = append(features, m.FeatureVerifiedSignIn)
+		}
+		if cfg.SignInConfirmation.Enabled {
+			features = append(features, m.FeatureSignInConfirmation)
+		}
+	}
+	if moderation {
+		features = append(features, m.FeatureModeration)
+	}
+	if cfg.LDAP.URL != "" {
+		features = append(features, m.FeatureLDAP)
+		auth = append(auth, m.AuthLDAP)
+	}
+	if cfg.OAuth.GoogleClientID != "" {
+		auth = append(auth, m.AuthGoogle)
+	}
+	if cfg.OAuth.GitHubClientID != "" {
+		auth = append(auth, m.AuthGitHub)
+	}
+	if cfg.SCIM.Token != "" {
+		features = append(features, m.FeatureSCIM)
+	}
+	if cfg.OIDC.Issuer != "" {
+		features = append(features, m.FeatureOIDC)
+	}
+
+	return m.New(features, auth, cfg.Branding)
+}
+
 func CORSMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		origin := r.Header.Get("Origin")
@@ -145,9 +632,10 @@ func CORSMiddleware(next http.Handler) http.Handler {
 		}
 
 		w.Header().Set("Access-Control-Allow-Origin", origin)
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Client, X-Strict-JSON, X-CSRF-Token")
 		w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Expose-Headers", "X-API-Version, X-RateLimit-Limit, X-RateLimit-Remaining, X-RateLimit-Reset, Retry-After, Deprecation, Sunset, Link")
 
 		if r.Method == http.MethodOptions {
 			w.WriteHeader(http.StatusOK)
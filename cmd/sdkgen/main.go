@@ -0,0 +1,452 @@
+// Command sdkgen generates the typed methods and models of the Go client in pkg/sapi from the swagger spec.
+// It runs with go generate in pkg/sapi after the spec is regenerated:
+//
+//	go generate ./pkg/sapi
+//
+// Every operation needs an @ID, it names the method, and operations served at the host root instead of the API
+// base path are marked with @x-outside-base. Types take the name of their Go type, prefixed with
+// their package when names collide; x-nullable fields become pointers so a zero value can be sent.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+type schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Description          string             `json:"description"`
+	Items                *schema            `json:"items"`
+	Properties           map[string]*schema `json:"properties"`
+	AdditionalProperties json.RawMessage    `json:"additionalProperties"`
+	Required             []string           `json:"required"`
+	Nullable             bool               `json:"x-nullable"`
+}
+
+type parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Type        string  `json:"type"`
+	Description string  `json:"description"`
+	Required    bool    `json:"required"`
+	Schema      *schema `json:"schema"`
+}
+
+type response struct {
+	Schema *schema `json:"schema"`
+}
+
+type operation struct {
+	ID          string              `json:"operationId"`
+	Summary     string              `json:"summary"`
+	Description string              `json:"description"`
+	Parameters  []parameter         `json:"parameters"`
+	Responses   map[string]response `json:"responses"`
+	OutsideBase bool                `json:"x-outside-base"`
+}
+
+type spec struct {
+	Paths       map[string]map[string]*operation `json:"paths"`
+	Definitions map[string]*schema               `json:"definitions"`
+}
+
+// Words written in capitals in Go names
+var initialisms = map[string]bool{
+	"API": true, "HTTP": true, "ID": true, "IDS": true, "IP": true, "JSON": true, "JWT": true, "JWKS": true,
+	"SCIM": true, "URL": true, "URI": true, "UUID": true, "SMTP": true, "TTL": true, "UI": true, "OPENAPI": true,
+}
+
+// Names of the hand-written code in the package
+var reserved = map[string]bool{"Client": true, "Error": true, "Option": true}
+
+func main() {
+	in := flag.String("spec", "../../docs/swagger.json", "swagger spec to generate from")
+	out := flag.String("out", "sapi.gen.go", "file to write")
+	pkg := flag.String("package", "sapi", "package name")
+	flag.Parse()
+
+	data, err := os.ReadFile(*in)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var s spec
+	if err := json.Unmarshal(data, &s); err != nil {
+		log.Fatal(err)
+	}
+
+	src, err := generate(s, *pkg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*out, src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+type generator struct {
+	spec  spec
+	names map[string]string
+	buf   bytes.Buffer
+}
+
+func generate(s spec, pkg string) ([]byte, error) {
+	g := &generator{spec: s}
+	if err := g.nameTypes(); err != nil {
+		return nil, err
+	}
+
+	defs := make([]string, 0, len(s.Definitions))
+	for def := range s.Definitions {
+		defs = append(defs, def)
+	}
+	sort.Slice(defs, func(i, j int) bool { return g.names[defs[i]] < g.names[defs[j]] })
+	for _, def := range defs {
+		g.model(g.names[def], s.Definitions[def])
+	}
+
+	type op struct {
+		path, method string
+		*operation
+	}
+	var ops []op
+	for path, methods := range s.Paths {
+		for method, o := range methods {
+			if o.ID == "" {
+				return nil, fmt.Errorf("%s %s has no @ID", strings.ToUpper(method), path)
+			}
+			ops = append(ops, op{path, method, o})
+		}
+	}
+	sort.Slice(ops, func(i, j int) bool { return goName(ops[i].ID) < goName(ops[j].ID) })
+	for _, o := range ops {
+		if err := g.method(o.path, o.method, o.operation); err != nil {
+			return nil, err
+		}
+	}
+
+	var src bytes.Buffer
+	fmt.Fprintf(&src, "// Code generated by sdkgen from docs/swagger.json. DO NOT EDIT.\n\npackage %s\n\nimport (\n", pkg)
+	for _, imp := range []string{"context", "net/url", "strconv"} {
+		if bytes.Contains(g.buf.Bytes(), []byte(imp[strings.LastIndex(imp, "/")+1:]+".")) {
+			fmt.Fprintf(&src, "%q\n", imp)
+		}
+	}
+	src.WriteString(")\n\n")
+	src.Write(g.buf.Bytes())
+
+	out, err := format.Source(src.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format: %v\n%s", err, src.String())
+	}
+	return out, nil
+}
+
+func (g *generator) printf(format string, args ...any) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// nameTypes names each definition after its Go type, e.g. "..._lib_todoConfig.Todo" is Todo.
+// Colliding and reserved names get their package as prefix, without the Config suffix: TodoMeta, SCIMUser.
+func (g *generator) nameTypes() error {
+	count := make(map[string]int)
+	for def := range g.spec.Definitions {
+		count[typeName(def)]++
+	}
+
+	g.names = make(map[string]string)
+	taken := make(map[string]string)
+	for def := range g.spec.Definitions {
+		name := typeName(def)
+		if count[name] > 1 || reserved[name] {
+			if prefix := packagePrefix(def); prefix != name {
+				name = prefix + name
+			}
+		}
+		if other, found := taken[name]; found {
+			return fmt.Errorf("%s and %s are both named %s", def, other, name)
+		}
+		taken[name] = def
+		g.names[def] = name
+	}
+	return nil
+}
+
+func typeName(def string) string {
+	return def[strings.LastIndex(def, ".")+1:]
+}
+
+func packagePrefix(def string) string {
+	path := def[:strings.LastIndex(def, ".")]
+	pkg := strings.TrimSuffix(path[strings.LastIndex(path, "_")+1:], "Config")
+	return goName(pkg)
+}
+
+func (g *generator) model(name string, s *schema) {
+	if len(s.Properties) == 0 {
+		g.printf("type %s %s\n\n", name, g.goType(s))
+		return
+	}
+
+	g.printf("type %s struct {\n", name)
+	required := make(map[string]bool)
+	for _, r := range s.Required {
+		required[r] = true
+	}
+	props := make([]string, 0, len(s.Properties))
+	for p := range s.Properties {
+		props = append(props, p)
+	}
+	sort.Strings(props)
+	for _, p := range props {
+		prop := s.Properties[p]
+		comment(&g.buf, prop.Description)
+		typ := g.goType(prop)
+		if prop.Nullable {
+			typ = "*" + typ
+		}
+		tag := p
+		if !required[p] {
+			tag += ",omitempty"
+		}
+		g.printf("%s %s `json:%q`\n", goName(p), typ, tag)
+	}
+	g.printf("}\n\n")
+}
+
+func (g *generator) goType(s *schema) string {
+	if s == nil {
+		return "any"
+	}
+	if s.Ref != "" {
+		return g.names[strings.TrimPrefix(s.Ref, "#/definitions/")]
+	}
+	switch s.Type {
+	case "string":
+		return "string"
+	case "integer":
+		return "int"
+	case "number":
+		return "float64"
+	case "boolean":
+		return "bool"
+	case "file":
+		return "[]byte"
+	case "array":
+		return "[]" + g.goType(s.Items)
+	case "object":
+		var values schema
+		if len(s.AdditionalProperties) > 0 && json.Unmarshal(s.AdditionalProperties, &values) == nil && (values.Type != "" || values.Ref != "") {
+			return "map[string]" + g.goType(&values)
+		}
+		return "map[string]any"
+	}
+	return "any"
+}
+
+// method writes the client method of an operation: path parameters are arguments in path order,
+// the body is typed and query parameters are an optional params struct.
+func (g *generator) method(path, method string, o *operation) error {
+	name := goName(o.ID)
+
+	var args []string
+	var body, query []parameter
+	for _, p := range o.Parameters {
+		switch p.In {
+		case "body":
+			body = append(body, p)
+		case "query":
+			query = append(query, p)
+		}
+	}
+
+	args = append(args, "ctx context.Context")
+	for rest := path; strings.Contains(rest, "{"); {
+		start, end := strings.Index(rest, "{"), strings.Index(rest, "}")
+		args = append(args, argName(rest[start+1:end])+" string")
+		rest = rest[end+1:]
+	}
+	in := "nil"
+	if len(body) > 0 {
+		args = append(args, "body "+g.goType(body[0].Schema))
+		in = "body"
+	}
+	values := "nil"
+	if len(query) > 0 {
+		params := name + "Params"
+		g.params(params, query)
+		args = append(args, "params *"+params)
+		values = "params.values()"
+	}
+
+	var result string
+	for _, code := range []string{"200", "201", "202"} {
+		if r, ok := o.Responses[code]; ok && r.Schema != nil && r.Schema.Type != "string" {
+			result = g.goType(r.Schema)
+			break
+		}
+	}
+
+	url := pathExpr(path)
+	do := "c.do"
+	if o.OutsideBase {
+		do = "c.doRoot"
+	}
+
+	comment(&g.buf, fmt.Sprintf("%s calls %s %s: %s.", name, strings.ToUpper(method), path, o.Summary))
+	if result == "" {
+		g.printf("func (c *Client) %s(%s) error {\n", name, strings.Join(args, ", "))
+		g.printf("return %s(ctx, %q, %s, %s, %s, nil)\n}\n\n", do, strings.ToUpper(method), url, values, in)
+		return nil
+	}
+
+	ret := result
+	if !strings.HasPrefix(result, "[]") && !strings.HasPrefix(result, "map[") && result != "any" {
+		ret = "*" + result
+	}
+	g.printf("func (c *Client) %s(%s) (%s, error) {\n", name, strings.Join(args, ", "), ret)
+	g.printf("var out %s\n", result)
+	g.printf("if err := %s(ctx, %q, %s, %s, %s, &out); err != nil {\nreturn nil, err\n}\n", do, strings.ToUpper(method), url, values, in)
+	if strings.HasPrefix(ret, "*") {
+		g.printf("return &out, nil\n}\n\n")
+	} else {
+		g.printf("return out, nil\n}\n\n")
+	}
+	return nil
+}
+
+// params writes the query parameters struct of an operation. A "name.key" parameter is a map sent as name.<key>=value.
+// Booleans are pointers so false can be sent, zero numbers and empty strings are left out.
+func (g *generator) params(name string, query []parameter) {
+	comment(&g.buf, fmt.Sprintf("%s are the query parameters of %s, zero fields are not sent.", name, strings.TrimSuffix(name, "Params")))
+	g.printf("type %s struct {\n", name)
+	for _, p := range query {
+		comment(&g.buf, p.Description)
+		if prefix, ok := strings.CutSuffix(p.Name, ".key"); ok {
+			g.printf("%s map[string]string\n", goName(prefix))
+			continue
+		}
+		g.printf("%s %s\n", goName(p.Name), queryType(p.Type))
+	}
+	g.printf("}\n\n")
+
+	g.printf("func (p *%s) values() url.Values {\nv := url.Values{}\nif p == nil {\nreturn v\n}\n", name)
+	for _, p := range query {
+		field := "p." + goName(p.Name)
+		if prefix, ok := strings.CutSuffix(p.Name, ".key"); ok {
+			field = "p." + goName(prefix)
+			g.printf("for k, val := range %s {\nv.Set(%q+k, val)\n}\n", field, prefix+".")
+			continue
+		}
+		switch queryType(p.Type) {
+		case "*bool":
+			g.printf("if %s != nil {\nv.Set(%q, strconv.FormatBool(*%s))\n}\n", field, p.Name, field)
+		case "int":
+			g.printf("if %s != 0 {\nv.Set(%q, strconv.Itoa(%s))\n}\n", field, p.Name, field)
+		default:
+			g.printf("if %s != \"\" {\nv.Set(%q, %s)\n}\n", field, p.Name, field)
+		}
+	}
+	g.printf("return v\n}\n\n")
+}
+
+func queryType(t string) string {
+	switch t {
+	case "boolean":
+		return "*bool"
+	case "integer":
+		return "int"
+	}
+	return "string"
+}
+
+// pathExpr returns the Go expression of path with its parameters escaped, e.g. "/todos/" + url.PathEscape(id)
+func pathExpr(path string) string {
+	var parts []string
+	for {
+		start := strings.Index(path, "{")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(path, "}")
+		parts = append(parts, fmt.Sprintf("%q", path[:start]), "url.PathEscape("+argName(path[start+1:end])+")")
+		path = path[end+1:]
+	}
+	if path != "" || len(parts) == 0 {
+		parts = append(parts, fmt.Sprintf("%q", path))
+	}
+	return strings.Join(parts, " + ")
+}
+
+// argName turns a path parameter into an unexported Go name: "id" is id and "userId" userID.
+func argName(param string) string {
+	name := goName(param)
+	upper := 0
+	for upper < len(name) && unicode.IsUpper(rune(name[upper])) {
+		upper++
+	}
+	if upper > 1 && upper < len(name) {
+		// The last capital of a run starts the next word, as in URLPath
+		upper--
+	}
+	return strings.ToLower(name[:upper]) + name[upper:]
+}
+
+// goName turns a JSON, parameter or operation name into an exported Go name: "publicId" is PublicID,
+// "as_of" AsOf and "scimListUsers" SCIMListUsers.
+func goName(s string) string {
+	var words []string
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, string(word))
+			word = word[:0]
+		}
+	}
+	runes := []rune(s)
+	for i, r := range runes {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+			continue
+		case unicode.IsUpper(r) && len(word) > 0:
+			// A new word starts at an upper case letter, unless it continues an upper case run
+			prevUpper := unicode.IsUpper(word[len(word)-1])
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if !prevUpper || nextLower {
+				flush()
+			}
+		}
+		word = append(word, r)
+	}
+	flush()
+
+	var b strings.Builder
+	for _, w := range words {
+		if upper := strings.ToUpper(w); initialisms[upper] {
+			if upper == "OPENAPI" {
+				upper = "OpenAPI"
+			}
+			b.WriteString(upper)
+			continue
+		}
+		b.WriteString(strings.ToUpper(w[:1]) + w[1:])
+	}
+	return b.String()
+}
+
+func comment(buf *bytes.Buffer, text string) {
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			fmt.Fprintf(buf, "// %s\n", line)
+		}
+	}
+}
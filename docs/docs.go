@@ -22,64 +22,79 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/admin/users": {
+        "/.well-known/jwks.json": {
+            "get": {
+                "description": "Returns the public keys verifying access tokens as a JSON Web Key Set (RFC 7517), tokens name their key",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "meta"
+                ],
+                "summary": "Get the token verification keys",
+                "operationId": "getJWKS",
+                "responses": {
+                    "200": {
+                        "description": "Public keys.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_api_access.JWKSet"
+                        }
+                    }
+                },
+                "x-outside-base": true
+            }
+        },
+        "/.well-known/openid-configuration": {
+            "get": {
+                "description": "Returns the issuer, endpoints, scopes, flows and signing algorithm of sAPI as an OpenID Connect provider.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "oidc"
+                ],
+                "summary": "Get the OpenID Connect provider metadata",
+                "operationId": "getOpenIDConfiguration",
+                "responses": {
+                    "200": {
+                        "description": "Provider metadata.",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_oidc.Discovery"
+                        }
+                    }
+                },
+                "x-outside-base": true
+            }
+        },
+        "/admin/backups": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Fetches a list of users based on optional query parameters such as filters and sorting.",
+                "description": "Lists recent database backups, newest first, with their size and status: 'running', 'succeeded' or 'failed'.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "admin"
                 ],
-                "summary": "Get all users",
+                "summary": "Get recent backups",
+                "operationId": "listBackups",
                 "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Filter users by username or email",
-                        "name": "search",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Sort by 'email', 'username', or 'id'. Default is 'id'.",
-                        "name": "sortBy",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Sort order: 'asc', 'desc', or 'none'. Default is 'asc'.",
-                        "name": "sortOrder",
-                        "in": "query"
-                    },
-                    {
-                        "type": "boolean",
-                        "description": "Filter by block status (true/false)",
-                        "name": "isBlocked",
-                        "in": "query"
-                    },
                     {
                         "type": "integer",
-                        "description": "Limit the number of users returned (default is 20)",
+                        "description": "Limit the number of backups returned (default is 20)",
                         "name": "limit",
                         "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Offset for pagination (default is 0)",
-                        "name": "offset",
-                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Successful retrieval of users.",
+                        "description": "Backups retrieved.",
                         "schema": {
-                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_userConfig.MetaResponse"
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_backup.ListResponse"
                         }
                     },
                     "401": {
@@ -91,53 +106,90 @@ const docTemplate = `{
                     "403": {
                         "description": "Insufficient permissions.",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Starts a database backup in the background and returns its record with status 'running'.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Start a database backup",
+                "operationId": "startBackup",
+                "responses": {
+                    "202": {
+                        "description": "Backup started.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_backup.Backup"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "403": {
+                        "description": "Insufficient permissions.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "A backup is already running.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
                     }
                 }
             }
         },
-        "/admin/users/{id}": {
+        "/admin/banners": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Retrieves a user's profile by their ID.",
+                "description": "Returns every banner, past and scheduled ones included, latest start first.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "admin"
                 ],
-                "summary": "Retrieve user's profile",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "ID of the user",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
+                "summary": "Get all banners",
+                "operationId": "listBanners",
                 "responses": {
                     "200": {
-                        "description": "Successful retrieval of user profile.",
+                        "description": "Banners retrieved.",
                         "schema": {
-                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_userConfig.TableUser"
-                        }
-                    },
-                    "400": {
-                        "description": "Invalid or missing user ID.",
-                        "schema": {
-                            "type": "string"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_bannerConfig.Banner"
+                            }
                         }
                     },
                     "401": {
@@ -149,30 +201,24 @@ const docTemplate = `{
                     "403": {
                         "description": "Insufficient permissions.",
                         "schema": {
-                            "type": "string"
-                        }
-                    },
-                    "404": {
-                        "description": "User not found.",
-                        "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal server error.",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
                         }
                     }
                 }
             },
-            "put": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Updates the details of a user by accepting a JSON payload.",
+                "description": "Creates a banner clients show from starts (default now) until ends, or until it is deleted without ends.",
                 "consumes": [
                     "application/json"
                 ],
@@ -182,36 +228,30 @@ const docTemplate = `{
                 "tags": [
                     "admin"
                 ],
-                "summary": "Update user's profile",
+                "summary": "Create a banner",
+                "operationId": "createBanner",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "ID of the user",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "User data payload",
-                        "name": "UserData",
+                        "description": "Banner",
+                        "name": "Banner",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_userConfig.PutUser"
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_bannerConfig.BannerRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "User profile updated successfully.",
+                    "201": {
+                        "description": "Banner created.",
                         "schema": {
-                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_userConfig.TableUser"
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_bannerConfig.Banner"
                         }
                     },
                     "400": {
-                        "description": "Duplicate login or email.",
+                        "description": "Invalid input or ends not after starts.",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
                         }
                     },
                     "401": {
@@ -223,57 +263,66 @@ const docTemplate = `{
                     "403": {
                         "description": "Insufficient permissions.",
                         "schema": {
-                            "type": "string"
-                        }
-                    },
-                    "404": {
-                        "description": "User not found.",
-                        "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal server error.",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/admin/banners/{id}": {
+            "put": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Deletes a user by their ID.",
+                "description": "Replaces the banner, starts defaults to now. The change is recorded in the audit log.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "admin"
                 ],
-                "summary": "Remove user",
+                "summary": "Replace a banner",
+                "operationId": "replaceBanner",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "ID of the user",
+                        "type": "string",
+                        "description": "Public ID (UUID) of the banner",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Banner",
+                        "name": "Banner",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_bannerConfig.BannerRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "User successfully removed.",
+                        "description": "Banner replaced.",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_bannerConfig.Banner"
                         }
                     },
                     "400": {
-                        "description": "Invalid or missing user ID.",
+                        "description": "Invalid ID or input, or ends not after starts.",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
                         }
                     },
                     "401": {
@@ -285,43 +334,42 @@ const docTemplate = `{
                     "403": {
                         "description": "Insufficient permissions.",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
                         }
                     },
                     "404": {
-                        "description": "User not found.",
+                        "description": "Banner not found.",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal server error.",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
                         }
                     }
                 }
-            }
-        },
-        "/admin/users/{id}/block": {
-            "post": {
+            },
+            "delete": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Blocks a user by their ID, disabling their account.",
+                "description": "Deletes the banner, clients stop showing it. The deletion is recorded in the audit log.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "admin"
                 ],
-                "summary": "Block user",
+                "summary": "Delete a banner",
+                "operationId": "deleteBanner",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "ID of the user",
+                        "type": "string",
+                        "description": "Public ID (UUID) of the banner",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -329,35 +377,52 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "User successfully blocked.",
+                        "description": "Banner deleted.",
                         "schema": {
-                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_userConfig.TableUser"
+                            "type": "string"
                         }
                     },
                     "400": {
-                        "description": "Invalid or missing user ID.",
+                        "description": "Invalid ID.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
                         "schema": {
                             "type": "string"
                         }
                     },
+                    "403": {
+                        "description": "Insufficient permissions.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
                     "404": {
-                        "description": "User not found.",
+                        "description": "Banner not found.",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal server error.",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
                         }
                     }
                 }
             }
         },
-        "/admin/users/{id}/rights": {
+        "/admin/cache/invalidate": {
             "post": {
-                "description": "Updates specific fields related to user's rights by accepting a JSON payload.",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Deletes cached responses so the next request reads fresh data, e.g. on a stale-data complaint.",
                 "consumes": [
                     "application/json"
                 ],
@@ -367,387 +432,576 @@ const docTemplate = `{
                 "tags": [
                     "admin"
                 ],
-                "summary": "Update user's rights",
+                "summary": "Invalidate cached responses",
+                "operationId": "invalidateCache",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "ID of the user",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "User data for updating rights",
-                        "name": "UserData",
+                        "description": "Cache, key pattern or user public ID",
+                        "name": "Invalidation",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/internal_http-server_handlers_admin.UpdateRequest"
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_cache.Invalidation"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Rights successfully updated.",
+                        "description": "Entries deleted per cache.",
                         "schema": {
-                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_userConfig.TableUser"
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_cache.Invalidated"
                         }
                     },
                     "400": {
-                        "description": "No such field.",
+                        "description": "Invalid request payload or pattern.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
                         "schema": {
                             "type": "string"
                         }
                     },
+                    "403": {
+                        "description": "Insufficient permissions.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
                     "404": {
-                        "description": "User not found.",
+                        "description": "No such cache or user.",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal server error.",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
                         }
                     }
                 }
             }
         },
-        "/admin/users/{id}/unlock": {
-            "post": {
+        "/admin/cache/stats": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Unblocks a user by their ID, re-enabling their account.",
+                "description": "Returns the size, limits, hits, misses, invalidations and evictions of each cache since the start.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "admin"
                 ],
-                "summary": "Unlock user",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "ID of the user",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
+                "summary": "Get cache statistics",
+                "operationId": "getCacheStats",
                 "responses": {
                     "200": {
-                        "description": "User successfully unblocked.",
+                        "description": "Statistics per cache.",
                         "schema": {
-                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_userConfig.TableUser"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_cache.Stats"
+                            }
                         }
                     },
-                    "400": {
-                        "description": "Invalid or missing user ID.",
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
                         "schema": {
                             "type": "string"
                         }
                     },
-                    "404": {
-                        "description": "User not found.",
+                    "403": {
+                        "description": "Insufficient permissions.",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/jobs": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the background job queues of the instance by priority, highest first, with their limits and the jobs queued and running.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get background job queues",
+                "operationId": "getJobQueues",
+                "responses": {
+                    "200": {
+                        "description": "Queues retrieved.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_jobs.Stats"
                         }
                     },
-                    "500": {
-                        "description": "Internal server error.",
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "403": {
+                        "description": "Insufficient permissions.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
                     }
                 }
             }
         },
-        "/auth/refresh": {
-            "post": {
-                "description": "Recieve a user's refresh token in JSON format.",
-                "consumes": [
-                    "application/json"
+        "/admin/jobs/dead": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
                 ],
+                "description": "Lists the background jobs that failed every attempt, newest first, with the error of the last attempt.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "user"
+                    "admin"
                 ],
-                "summary": "Refresh user's access token",
+                "summary": "Get dead background jobs",
+                "operationId": "listDeadJobs",
                 "parameters": [
                     {
-                        "description": "User's refresh token",
-                        "name": "RefreshToken",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/internal_http-server_handlers_user.RefreshToken"
-                        }
+                        "type": "integer",
+                        "description": "Limit the number of jobs returned (default is 20)",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Authentication successful. Returns a JWT token.",
+                        "description": "Dead jobs retrieved.",
                         "schema": {
-                            "$ref": "#/definitions/internal_http-server_handlers_user.Tokens"
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_jobs.DeadList"
                         }
                     },
-                    "400": {
-                        "description": "failed to deserialize json request.",
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
                         "schema": {
                             "type": "string"
                         }
                     },
-                    "401": {
-                        "description": "Invalid credentials: token is expired - must auth again.",
+                    "403": {
+                        "description": "Insufficient permissions.",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
                         }
                     },
                     "500": {
-                        "description": "Internal error.",
+                        "description": "Internal server error.",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
                         }
                     }
                 }
             }
         },
-        "/auth/signin": {
-            "post": {
-                "description": "Authenticates a user by accepting their login credentials (login and password) in JSON format.",
-                "consumes": [
-                    "application/json"
+        "/admin/jobs/dead/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
                 ],
+                "description": "Deletes the dead job without running it again. The deletion is recorded in the audit log.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "user"
+                    "admin"
                 ],
-                "summary": "Authenticate user",
+                "summary": "Delete a dead background job",
+                "operationId": "deleteDeadJob",
                 "parameters": [
                     {
-                        "description": "User login credentials",
-                        "name": "AuthData",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_userConfig.AuthData"
-                        }
+                        "type": "integer",
+                        "description": "ID of the dead job",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Authentication successful. Returns a JWT token.",
+                        "description": "Job deleted.",
                         "schema": {
-                            "$ref": "#/definitions/internal_http-server_handlers_user.Tokens"
+                            "type": "string"
                         }
                     },
                     "400": {
-                        "description": "Invalid input.",
+                        "description": "Invalid ID.",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
                         }
                     },
                     "401": {
-                        "description": "Invalid credentials.",
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
                         "schema": {
                             "type": "string"
                         }
                     },
+                    "403": {
+                        "description": "Insufficient permissions.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Dead job not found.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
                     "500": {
-                        "description": "Internal error.",
+                        "description": "Internal server error.",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
                         }
                     }
                 }
             }
         },
-        "/auth/signup": {
+        "/admin/jobs/dead/{id}/requeue": {
             "post": {
-                "description": "Handles the registration of a new user by accepting a JSON payload containing user data.",
-                "consumes": [
-                    "application/json"
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
                 ],
+                "description": "Queues the dead job again with every attempt and removes it from the dead jobs. The requeue is recorded in the audit log.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "user"
+                    "admin"
                 ],
-                "summary": "Register a new user",
+                "summary": "Requeue a dead background job",
+                "operationId": "requeueDeadJob",
                 "parameters": [
                     {
-                        "description": "Complete user data for registration",
-                        "name": "UserData",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_userConfig.User"
-                        }
+                        "type": "integer",
+                        "description": "ID of the dead job",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Registration successful. Returns user data.",
+                    "200": {
+                        "description": "Job queued.",
                         "schema": {
-                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_userConfig.TableUser"
+                            "type": "string"
                         }
                     },
                     "400": {
-                        "description": "Invalid input.",
+                        "description": "Invalid ID.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
                         "schema": {
                             "type": "string"
                         }
                     },
+                    "403": {
+                        "description": "Insufficient permissions.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Dead job not found.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
                     "409": {
-                        "description": "User already exists.",
+                        "description": "The queue of the job is no longer configured.",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
                         }
                     },
                     "500": {
-                        "description": "Internal error.",
+                        "description": "Internal server error.",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "The queue is full, or no job workers are configured.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
                         }
                     }
                 }
             }
         },
-        "/todos": {
-            "get": {
-                "description": "Retrieves all tasks with optional filtering by status (e.g., completed or in-progress).",
+        "/admin/jwt/rotate": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Creates a signing key of the configured algorithm that signs tokens from now on, e.g. when the key may have leaked.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "todo"
-                ],
-                "summary": "Retrieve all tasks",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Filter tasks by status: all, completed, or inWork",
-                        "name": "filter",
-                        "in": "query"
-                    }
+                    "admin"
                 ],
+                "summary": "Rotate the JWT signing key",
+                "operationId": "rotateSigningKey",
                 "responses": {
                     "200": {
-                        "description": "Tasks retrieved successfully.",
+                        "description": "Key rotated.",
                         "schema": {
-                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_todoConfig.MetaResponse"
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_keyConfig.Rotation"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permissions.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal server error.",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
                         }
                     }
                 }
-            },
-            "post": {
-                "description": "Creates a new task by accepting a JSON payload with the task's details.",
-                "consumes": [
-                    "application/json"
+            }
+        },
+        "/admin/metrics": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
                 ],
+                "description": "Returns process counters (e.g. db_slow_queries by storage method) and runtime memory stats as JSON.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "todo"
-                ],
-                "summary": "Create a new task",
-                "parameters": [
-                    {
-                        "description": "Task data for creating a new task",
-                        "name": "UserData",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_todoConfig.TodoRequest"
-                        }
-                    }
+                    "admin"
                 ],
+                "summary": "Get process metrics",
+                "operationId": "getMetrics",
                 "responses": {
                     "200": {
-                        "description": "Task successfully created, returns the created task.",
+                        "description": "Metrics retrieved.",
                         "schema": {
-                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_todoConfig.Todo"
+                            "type": "object",
+                            "additionalProperties": true
                         }
                     },
-                    "400": {
-                        "description": "Invalid request body or missing/incorrect fields.",
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
                         "schema": {
                             "type": "string"
                         }
                     },
-                    "500": {
-                        "description": "Internal server error.",
+                    "403": {
+                        "description": "Insufficient permissions.",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
                         }
                     }
                 }
             }
         },
-        "/todos/{id}": {
+        "/admin/metrics/summary": {
             "get": {
-                "description": "Retrieves a specific task by its ID from the URL.",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns p50/p95 latency and error rates by route over the last hour, busiest routes first.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "todo"
-                ],
-                "summary": "Retrieve a task by ID",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "ID of the task to retrieve",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
+                    "admin"
                 ],
+                "summary": "Get latency and error rates by route",
+                "operationId": "getMetricsSummary",
                 "responses": {
                     "200": {
-                        "description": "Task retrieved successfully.",
+                        "description": "Summary retrieved.",
                         "schema": {
-                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_todoConfig.Todo"
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_metrics.Summary"
                         }
                     },
-                    "400": {
-                        "description": "Invalid or missing task ID.",
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
                         "schema": {
                             "type": "string"
                         }
                     },
-                    "404": {
-                        "description": "Task not found.",
+                    "403": {
+                        "description": "Insufficient permissions.",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/moderation/flagged": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lists content that moderation flagged but accepted (moderation action 'flag'), newest first.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get flagged content",
+                "operationId": "listFlaggedContent",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Filter by kind: 'todo_title' or 'username'",
+                        "name": "kind",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Limit the number of entries returned (default is 20)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Offset for pagination (default is 0)",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Flagged content retrieved.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_moderation.FlagsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Unknown kind.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permissions.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/oidc/clients": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the registered OpenID Connect clients, latest first. Their secrets are never returned.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get OpenID Connect clients",
+                "operationId": "listOIDCClients",
+                "responses": {
+                    "200": {
+                        "description": "Clients retrieved.",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_oidcConfig.Client"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "403": {
+                        "description": "Insufficient permissions.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
                     }
                 }
             },
-            "put": {
-                "description": "Updates an existing task by accepting a JSON payload with the updated task details.",
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Registers a client allowed to sign its users in with sAPI accounts, redirecting only to the given URIs.",
                 "consumes": [
                     "application/json"
                 ],
@@ -755,67 +1009,75 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "todo"
+                    "admin"
                 ],
-                "summary": "Update an existing task",
+                "summary": "Register an OpenID Connect client",
+                "operationId": "createOIDCClient",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "ID of the task to update",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "Updated task data",
-                        "name": "UserData",
+                        "description": "Client",
+                        "name": "Client",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_todoConfig.TodoRequest"
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_oidcConfig.ClientRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "Task updated successfully, returns the updated task.",
+                    "201": {
+                        "description": "Client registered, with its secret.",
                         "schema": {
-                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_todoConfig.Todo"
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_oidcConfig.Client"
                         }
                     },
                     "400": {
-                        "description": "Invalid request body, missing/incorrect fields, or invalid ID.",
+                        "description": "Invalid input.",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
                         }
                     },
-                    "404": {
-                        "description": "Task not found.",
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
                         "schema": {
                             "type": "string"
                         }
                     },
+                    "403": {
+                        "description": "Insufficient permissions.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
                     "500": {
                         "description": "Internal server error.",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
                         }
                     }
                 }
-            },
+            }
+        },
+        "/admin/oidc/clients/{id}": {
             "delete": {
-                "description": "Deletes a task by its ID from the URL.",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Deletes the client, its users can no longer sign in with it. Issued tokens stay valid until they expire.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "todo"
+                    "admin"
                 ],
-                "summary": "Delete a task by ID",
+                "summary": "Delete an OpenID Connect client",
+                "operationId": "deleteOIDCClient",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "ID of the task to delete",
+                        "type": "string",
+                        "description": "Client ID (UUID)",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -823,389 +1085,8977 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "Task deleted successfully.",
+                        "description": "Client deleted.",
                         "schema": {
                             "type": "string"
                         }
                     },
                     "400": {
-                        "description": "Invalid or missing task ID.",
+                        "description": "Invalid ID.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
                         "schema": {
                             "type": "string"
                         }
                     },
+                    "403": {
+                        "description": "Insufficient permissions.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
                     "404": {
-                        "description": "Task not found.",
+                        "description": "Client not found.",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal server error.",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
                         }
                     }
                 }
             }
         },
-        "/user/profile": {
+        "/admin/queries": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Retrieves the full profile of the currently authenticated user.",
+                "description": "Returns the read-only report queries that can be run with POST /admin/query, with their parameters.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "user"
+                    "admin"
                 ],
-                "summary": "Get user profile",
+                "summary": "List report queries",
+                "operationId": "listQueries",
                 "responses": {
                     "200": {
-                        "description": "Returns the user profile data.",
+                        "description": "Report queries retrieved.",
                         "schema": {
-                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_userConfig.TableUser"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/ReportQuery"
+                            }
                         }
                     },
-                    "400": {
-                        "description": "No such user.",
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
                         "schema": {
                             "type": "string"
                         }
                     },
+                    "403": {
+                        "description": "Insufficient permissions.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
                     "500": {
-                        "description": "Internal error.",
+                        "description": "Internal server error.",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
                         }
                     }
                 }
-            },
-            "put": {
+            }
+        },
+        "/admin/query": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Updates the user profile with new data provided in the JSON payload.",
+                "description": "Runs one of the report queries listed by GET /admin/queries with the given parameters, missing ones take their defaults.",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "text/csv"
                 ],
                 "tags": [
-                    "user"
+                    "admin"
                 ],
-                "summary": "Update user profile",
+                "summary": "Run a report query",
+                "operationId": "runQuery",
                 "parameters": [
                     {
-                        "description": "Updated user's any data",
-                        "name": "Userdata",
+                        "description": "Query name, parameters and result format",
+                        "name": "Query",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_userConfig.PutUser"
+                            "$ref": "#/definitions/internal_http-server_handlers_admin.QueryRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Profile successfully updated.",
+                        "description": "Query result.",
                         "schema": {
-                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_userConfig.TableUser"
+                            "$ref": "#/definitions/ReportResult"
                         }
                     },
                     "400": {
-                        "description": "Login or email already used.",
+                        "description": "Invalid request payload or query parameters.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
                         "schema": {
                             "type": "string"
                         }
                     },
+                    "403": {
+                        "description": "Insufficient permissions.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
                     "404": {
-                        "description": "No such user.",
+                        "description": "Unknown query.",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
                         }
                     },
                     "500": {
-                        "description": "Internal error.",
+                        "description": "Internal server error.",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
                         }
                     }
                 }
             }
         },
-        "/user/profile/reset-password": {
-            "put": {
+        "/admin/reports": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Updates the user's password with new data provided in the JSON payload.",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Returns the review queue, oldest reports first.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "user"
+                    "admin"
                 ],
-                "summary": "Update user' Password",
+                "summary": "Get abuse reports",
+                "operationId": "listReports",
                 "parameters": [
                     {
-                        "description": "New password",
-                        "name": "Password",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_userConfig.Pwd"
-                        }
+                        "type": "string",
+                        "description": "Filter by status: 'open' (default), 'resolved', 'dismissed' or 'all'",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Limit the number of reports returned (default is 20)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Offset for pagination (default is 0)",
+                        "name": "offset",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Profile successfully updated.",
+                        "description": "Reports retrieved.",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_reportConfig.MetaResponse"
                         }
                     },
                     "400": {
-                        "description": "failed to deserialize json request.",
+                        "description": "Unknown status.",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
                         }
                     },
-                    "404": {
-                        "description": "No such user.",
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
                         "schema": {
                             "type": "string"
                         }
                     },
+                    "403": {
+                        "description": "Insufficient permissions.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
                     "500": {
-                        "description": "Internal error.",
+                        "description": "Internal server error.",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "github_com_sabbatD_srest-api_internal_lib_todoConfig.Meta": {
-            "type": "object",
-            "properties": {
-                "totalAmount": {
-                    "type": "integer"
-                }
+        },
+        "/admin/reports/clients": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the requests by client and route over the last days, the latest seen first.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get requests by client",
+                "operationId": "getClientUsage",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Days covered, today included (default is 30, at most 365)",
+                        "name": "days",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only routes containing it, e.g. '/api/v1/todos'",
+                        "name": "route",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Report retrieved.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_clients.Report"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid days.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permissions.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/reports/{id}/dismiss": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Closes an open report without action. The review is recorded in the audit log.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Dismiss abuse report",
+                "operationId": "dismissReport",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Public ID (UUID) of the report",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Resolution note",
+                        "name": "Review",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_reportConfig.ReviewRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Report dismissed.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_reportConfig.Report"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID or input.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permissions.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Report not found.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Report is already closed.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/reports/{id}/resolve": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Closes an open report as acted upon. The review is recorded in the audit log.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Resolve abuse report",
+                "operationId": "resolveReport",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Public ID (UUID) of the report",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Resolution note",
+                        "name": "Review",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_reportConfig.ReviewRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Report resolved.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_reportConfig.Report"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID or input.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permissions.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Report not found.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Report is already closed.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/roles": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lists the roles with the permissions they grant and the number of their users, built-in roles first.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get roles",
+                "operationId": "listRoles",
+                "responses": {
+                    "200": {
+                        "description": "Roles retrieved.",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_roleConfig.Role"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permissions.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Creates a role granting the permissions, e.g. 'users:read' and 'moderation:write'. The creation is recorded in the audit log.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Create a role",
+                "operationId": "createRole",
+                "parameters": [
+                    {
+                        "description": "Role",
+                        "name": "Role",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_roleConfig.RoleRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Role created.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_roleConfig.Role"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid name or unknown permission.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permissions.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "A role with the name exists.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/roles/{name}": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Replaces the description and permissions of the role, the name in the body is ignored. The change is recorded in the audit log.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Replace a role",
+                "operationId": "replaceRole",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Name of the role",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Role",
+                        "name": "Role",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_roleConfig.RoleRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Role replaced.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_roleConfig.Role"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input or unknown permission.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permissions.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Role not found.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "The role cannot be changed.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Deletes the role, built-in roles and roles assigned to users cannot be deleted. The deletion is recorded in the audit log.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Delete a role",
+                "operationId": "deleteRole",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Name of the role",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Role deleted.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permissions.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Role not found.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "The role is built in or assigned to users.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/secrets": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lists the integration secrets admins can set, whether each is set and when it was last changed. Values are never returned.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get integration secrets",
+                "operationId": "listSecrets",
+                "responses": {
+                    "200": {
+                        "description": "Secrets retrieved.",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_secrets.Info"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permissions.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "No secrets master key is configured.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/secrets/{name}": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Encrypts the value with the master key and stores it, the integration uses it instead of the configured one from then on.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Set an integration secret",
+                "operationId": "setSecret",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Secret name, e.g. smtp.password",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Secret value",
+                        "name": "Value",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_secrets.Value"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Secret set.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request payload.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permissions.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Unknown secret.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "No secrets master key is configured.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Deletes the secret, the integration falls back to its configured value. The deletion is recorded in the audit log.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Delete an integration secret",
+                "operationId": "deleteSecret",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Secret name, e.g. smtp.password",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Secret deleted.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permissions.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Secret not set.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "No secrets master key is configured.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/settings/alerting": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the alert rules in effect: thresholds for the share of 5xx responses, failed logins and failed background jobs",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get alert rules",
+                "operationId": "getAlertRules",
+                "responses": {
+                    "200": {
+                        "description": "Alert rules retrieved.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_alerting.Rules"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permissions.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Replaces the alert rules, they apply from the next check. The change is recorded in the audit log.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Set alert rules",
+                "operationId": "setAlertRules",
+                "parameters": [
+                    {
+                        "description": "Thresholds, window in minutes and notification targets",
+                        "name": "Rules",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_alerting.Rules"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Alert rules set.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_alerting.Rules"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request payload.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permissions.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/settings/password-expiry": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the password expiry policy in effect: after how many days passwords expire and how many days ahead users are warned.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get password expiry policy",
+                "operationId": "getPasswordExpiry",
+                "responses": {
+                    "200": {
+                        "description": "Password expiry policy retrieved.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_expiry.Policy"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permissions.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Replaces the password expiry policy, it applies from the next scheduled run: users are emailed once they are within",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Set password expiry policy",
+                "operationId": "setPasswordExpiry",
+                "parameters": [
+                    {
+                        "description": "Days a password is valid and days to warn ahead, zero disables either",
+                        "name": "Policy",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_expiry.Policy"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Password expiry policy set.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_expiry.Policy"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request payload.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permissions.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/settings/read-only": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns whether the API is in read-only mode on this instance, with the message clients get. While it is on,",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get read-only mode",
+                "operationId": "getReadOnly",
+                "responses": {
+                    "200": {
+                        "description": "Read-only mode retrieved.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_api_readonly.Mode"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permissions.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Switches read-only mode on or off, e.g. for migrations and failovers of the primary database. The change is recorded in the audit log.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Switch read-only mode",
+                "operationId": "setReadOnly",
+                "parameters": [
+                    {
+                        "description": "Whether writes are rejected and the message clients get",
+                        "name": "Mode",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_api_readonly.Mode"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Read-only mode set.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_api_readonly.Mode"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request payload.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permissions.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Read-only mode is forced by the configuration.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/settings/retention": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the retention policy in effect: how many days former logins, soft-deleted users and",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get retention policy",
+                "operationId": "getRetention",
+                "responses": {
+                    "200": {
+                        "description": "Retention policy retrieved.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_retention.Policy"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permissions.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Replaces the retention policy, it applies from the next scheduled purge. The change is recorded in the audit log.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Set retention policy",
+                "operationId": "setRetention",
+                "parameters": [
+                    {
+                        "description": "Days to keep each kind of data, zero keeps it forever",
+                        "name": "Policy",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_retention.Policy"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Retention policy set.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_retention.Policy"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request payload.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permissions.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/settings/user-fields": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the custom fields admins defined for user profiles, with their type, whether they are required and who sees them.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get custom profile fields",
+                "operationId": "getUserFieldSchema",
+                "responses": {
+                    "200": {
+                        "description": "Custom fields retrieved.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_fields.Schema"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permissions.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Replaces the custom profile fields. Values of removed fields are kept but no longer returned. The change is recorded in the audit log.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Set custom profile fields",
+                "operationId": "setUserFieldSchema",
+                "parameters": [
+                    {
+                        "description": "Custom field definitions",
+                        "name": "Schema",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_fields.Schema"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Custom fields set.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_fields.Schema"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request payload.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permissions.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/support-bundle": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns a zip archive to attach to bug reports: the configuration with secrets redacted (about.json, config.json),",
+                "produces": [
+                    "application/zip"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Download a support bundle",
+                "operationId": "getSupportBundle",
+                "responses": {
+                    "200": {
+                        "description": "Support bundle.",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permissions.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/templates": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the template in effect for every email the server sends in the locale, with the variables it may use.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get email templates",
+                "operationId": "listTemplates",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "BCP 47 language tag, the default locale when empty",
+                        "name": "locale",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Templates retrieved.",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_mail.TemplateInfo"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid locale.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permissions.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/templates/{name}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the template in effect for the email in the locale: the one an admin set (custom) or the built-in one,",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get an email template",
+                "operationId": "getTemplate",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Template name: password_expiry, credentials_reset or alert",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "BCP 47 language tag, the default locale when empty",
+                        "name": "locale",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Template retrieved.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_mail.TemplateInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid locale.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permissions.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "No such template.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Replaces the subject and body of the email in the locale, they apply to the next email sent. Both use text/template syntax,",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Set an email template",
+                "operationId": "setTemplate",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Template name: password_expiry, credentials_reset or alert",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "BCP 47 language tag, the default locale when empty",
+                        "name": "locale",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Subject and body",
+                        "name": "Template",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_mail.Template"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Template set.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_mail.TemplateInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request payload, template or locale.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permissions.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "No such template.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Deletes the template an admin set for the email in the locale, the built-in one or the one of the parent locale applies again. The change is recorded in the audit log.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Reset an email template",
+                "operationId": "resetTemplate",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Template name: password_expiry, credentials_reset or alert",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "BCP 47 language tag, the default locale when empty",
+                        "name": "locale",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Template reset, returns the one in effect now.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_mail.TemplateInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid locale.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permissions.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "No such template.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/templates/{name}/preview": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Renders the template in the body, or the template in effect in the locale when the body is empty, with the sample values of the variables.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Preview an email template",
+                "operationId": "previewTemplate",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Template name: password_expiry, credentials_reset or alert",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "BCP 47 language tag, the default locale when empty",
+                        "name": "locale",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Draft subject and body",
+                        "name": "Template",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_mail.Template"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Rendered email.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_mail.Rendered"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request payload, template or locale.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permissions.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "No such template.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/users": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Fetches a list of users based on optional query parameters such as filters and sorting.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get all users",
+                "operationId": "listUsers",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Filter users by username, email, login or a former login",
+                        "name": "search",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort by 'email', 'username', or 'id'. Default is 'id'.",
+                        "name": "sortBy",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort order: 'asc', 'desc', or 'none'. Default is 'asc'.",
+                        "name": "sortOrder",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by state: 'active', 'blocked', 'deleted' or 'pending'. Overrides isBlocked.",
+                        "name": "state",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Filter by block status (true/false), ignored when state is set",
+                        "name": "isBlocked",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by role, e.g. 'moderator'",
+                        "name": "role",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by the value of the custom field 'key', e.g. custom.department=sales; several may be given",
+                        "name": "custom.key",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Limit the number of users returned (default is 20)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Offset for pagination (default is 0)",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful retrieval of users.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_userConfig.MetaResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Unknown state or custom field.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permissions.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/users/merge": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Merges a duplicate account into the primary one: the duplicate's todos are moved to the primary user,",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Merge duplicate account",
+                "operationId": "mergeUsers",
+                "parameters": [
+                    {
+                        "description": "Public IDs of the primary and the duplicate account",
+                        "name": "MergeRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_userConfig.MergeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Accounts merged, or the dry run result.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_userConfig.MergeResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request payload or both IDs are the same.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permissions.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/users/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieves a user's profile by their ID.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Retrieve user's profile",
+                "operationId": "getUser",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Public ID (UUID) of the user",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful retrieval of user profile.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_userConfig.TableUser"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid or missing user ID.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permissions.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Updates the details of a user by accepting a JSON payload.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Update user's profile",
+                "operationId": "updateUser",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Public ID (UUID) of the user",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "User data payload",
+                        "name": "UserData",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_userConfig.PutUser"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "User profile updated successfully.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_userConfig.UpdatedUser"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid custom field value or locale.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permissions.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Username rejected by moderation.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Soft-deletes a user by their ID. The user can no longer sign in but stays visible to admins with state=deleted.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Remove user",
+                "operationId": "removeUser",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Public ID (UUID) of the user",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "User successfully removed.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid or missing user ID.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permissions.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/users/{id}/block": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Blocks a user by their ID, disabling their account. The user's access tokens lose their permissions at once.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Block user",
+                "operationId": "blockUser",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Public ID (UUID) of the user",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "User successfully blocked.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_userConfig.UpdatedUser"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid or missing user ID.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permissions.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "The user is the last admin.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/users/{id}/limits": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the limits set for the user that override the configured ones: todo writes and abuse reports",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get user limits",
+                "operationId": "getUserLimits",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Public ID (UUID) of the user",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Limit overrides retrieved.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_userConfig.Limits"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid or missing user ID.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permissions.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Replaces the limit overrides of the user, they apply from the next request. Zero lifts a rate limit,",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Set user limits",
+                "operationId": "setUserLimits",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Public ID (UUID) of the user",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Limits that override the defaults",
+                        "name": "Limits",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_userConfig.Limits"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Limit overrides set.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_userConfig.Limits"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request payload or user ID.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permissions.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/users/{id}/require-password-change": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Flags a user to change their password: from their next sign in or token refresh their access token",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Require password change",
+                "operationId": "requirePasswordChange",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Public ID (UUID) of the user",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "User flagged.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_userConfig.UpdatedUser"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid or missing user ID.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permissions.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/users/{id}/reset-credentials": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Handles a compromised account: the user's password stops working and their refresh token and remembered devices are revoked,",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Reset user's credentials",
+                "operationId": "resetCredentials",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Public ID (UUID) of the user",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Email the reset link to the user",
+                        "name": "notify",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Credentials reset.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_userConfig.CredentialsReset"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid or missing user ID.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permissions.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/users/{id}/rights": {
+            "post": {
+                "description": "Sets the \"block\" or \"must_change_password\" flag of a user, roles are assigned with setUserRole.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Update user's rights",
+                "operationId": "updateUserRights",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Public ID (UUID) of the user",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "User data for updating rights",
+                        "name": "UserData",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_admin.UpdateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Rights successfully updated.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_userConfig.UpdatedUser"
+                        }
+                    },
+                    "400": {
+                        "description": "No such field.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permissions.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "The user is the last admin.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/users/{id}/role": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Assigns the role to the user, the last admin keeps the admin role. The change is recorded in the audit log.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Set the role of a user",
+                "operationId": "setUserRole",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Public ID (UUID) of the user",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Role",
+                        "name": "Role",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_roleConfig.UserRole"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Role set.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input or user ID.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permissions.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User or role not found.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "The user is the last admin.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/users/{id}/sign-ins": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lists the latest successful sign ins of the user, newest first, with the method, address and, when a GeoIP database is configured, the country and city.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get sign ins of a user",
+                "operationId": "listUserSignIns",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Public ID (UUID) of the user",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Limit the number of sign ins returned (default is 20, at most 100)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Sign ins retrieved.",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_userConfig.SignIn"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid or missing user ID.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permissions.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/users/{id}/todos/restore": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Sets the user's todos back to their state at as_of: todos created later are deleted,",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Restore user's todos to a point in time",
+                "operationId": "restoreUserTodos",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Public ID (UUID) of the user",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Point in time to restore, RFC 3339, e.g. 2024-10-09T12:00:00Z",
+                        "name": "as_of",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only report what would change",
+                        "name": "dry_run",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Todos restored, or the dry run result.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_todoConfig.RestoreResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Missing, malformed or future as_of, or invalid ID.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permissions.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "as_of predates the recorded todo history.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/users/{id}/unblock": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Unblocks a user by their ID, re-enabling their account. Users whose role grants permissions",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Unblock user",
+                "operationId": "unblockUser",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Public ID (UUID) of the user",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "User successfully unblocked.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_userConfig.UpdatedUser"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid or missing user ID.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Insufficient permissions.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/logout": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Deletes the refresh token of the user and revokes the access token of the request, it gets 401 from then on.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Log out",
+                "operationId": "logout",
+                "responses": {
+                    "200": {
+                        "description": "Logged out.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/refresh": {
+            "post": {
+                "description": "Recieve a user's refresh token in JSON format.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Refresh user's access token",
+                "operationId": "refresh",
+                "parameters": [
+                    {
+                        "description": "User's refresh token",
+                        "name": "RefreshToken",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_user.RefreshToken"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Authentication successful. Returns a JWT token.",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_user.Tokens"
+                        }
+                    },
+                    "400": {
+                        "description": "failed to deserialize json request.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid credentials: token is expired - must auth again.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Missing or invalid CSRF token.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/signin": {
+            "post": {
+                "description": "Authenticates a user by accepting their login credentials (login and password) in JSON format.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Authenticate user",
+                "operationId": "signIn",
+                "parameters": [
+                    {
+                        "description": "User login credentials",
+                        "name": "AuthData",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_userConfig.AuthData"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Authentication successful. Returns a JWT token.",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_user.Tokens"
+                        }
+                    },
+                    "202": {
+                        "description": "Sign in held until it is confirmed by email.",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_user.PendingSignIn"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid credentials.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Email not verified.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "423": {
+                        "description": "Too many failed sign ins of the login from this address, see Retry-After.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/signin/confirm": {
+            "get": {
+                "description": "Confirms the sign in from a new country or device with the token of the link emailed when it was held.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Confirm a sign in",
+                "operationId": "confirmSignIn",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Confirmation token",
+                        "name": "token",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Sign in confirmed.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Missing token.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Unknown or expired confirmation token.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/signin/pending": {
+            "post": {
+                "description": "Issues the tokens of a sign in held for confirmation once the emailed link is followed, like POST /auth/signin does.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Complete a held sign in",
+                "operationId": "completeSignIn",
+                "parameters": [
+                    {
+                        "description": "Session of the held sign in",
+                        "name": "PendingSession",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_user.PendingSession"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Sign in confirmed. Returns a JWT token.",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_user.Tokens"
+                        }
+                    },
+                    "202": {
+                        "description": "Not confirmed yet.",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_user.PendingSignIn"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Unknown, completed or expired session.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/signup": {
+            "post": {
+                "description": "Handles the registration of a new user by accepting a JSON payload containing user data.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Register a new user",
+                "operationId": "signUp",
+                "parameters": [
+                    {
+                        "description": "Complete user data for registration",
+                        "name": "UserData",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_userConfig.User"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Registration successful. Returns user data.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_userConfig.TableUser"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input, or a password violating the password policy.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "User already exists.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Username rejected by moderation.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/{provider}/callback": {
+            "get": {
+                "description": "Exchanges the code the provider redirected back with for the user's identity and returns the tokens of the local account.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Complete a sign in with Google or GitHub",
+                "operationId": "oauthCallback",
+                "parameters": [
+                    {
+                        "enum": [
+                            "google",
+                            "github"
+                        ],
+                        "type": "string",
+                        "description": "Provider",
+                        "name": "provider",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Code from the provider",
+                        "name": "code",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "State from the provider, it has to match the state cookie",
+                        "name": "state",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Authentication successful.",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_user.Tokens"
+                        }
+                    },
+                    "202": {
+                        "description": "Sign in held until it is confirmed by email.",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_user.PendingSignIn"
+                        }
+                    },
+                    "400": {
+                        "description": "Missing code or state not matching the state cookie.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Sign in refused or failed at the provider.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "No email at the provider, or email not verified.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Provider not configured.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Email of an account the identity cannot be linked to.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/{provider}/login": {
+            "get": {
+                "description": "Redirects to the sign in page of the provider, which redirects back to GET /auth/{provider}/callback with a code.",
+                "tags": [
+                    "user"
+                ],
+                "summary": "Sign in with Google or GitHub",
+                "operationId": "oauthLogin",
+                "parameters": [
+                    {
+                        "enum": [
+                            "google",
+                            "github"
+                        ],
+                        "type": "string",
+                        "description": "Provider",
+                        "name": "provider",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "302": {
+                        "description": "Redirect to the provider.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Provider not configured.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/banners": {
+            "get": {
+                "description": "Returns the banners to show now, most severe first. Authentication is optional: without a valid bearer token",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "banners"
+                ],
+                "summary": "Get active banners",
+                "operationId": "listActiveBanners",
+                "responses": {
+                    "200": {
+                        "description": "Active banners.",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_bannerConfig.Banner"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/batch": {
+            "post": {
+                "description": "Executes up to 20 sub-requests and returns their responses in the same order.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "batch"
+                ],
+                "summary": "Run several requests at once",
+                "operationId": "batch",
+                "parameters": [
+                    {
+                        "description": "Sub-requests, paths relative to /api/v1",
+                        "name": "Batch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_batch.BatchRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Responses of the sub-requests.",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/internal_http-server_handlers_batch.Response"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid batch, too many or nested sub-requests.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/guest": {
+            "post": {
+                "description": "Creates a guest account and returns its access token, with which the todo routes can be used",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Start a guest session",
+                "operationId": "startGuestSession",
+                "responses": {
+                    "201": {
+                        "description": "Guest session started.",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_user.GuestSession"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests, see Retry-After.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/healthz": {
+            "get": {
+                "description": "Reports the server is up, with the version and commit of the running build and when it started,",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "meta"
+                ],
+                "summary": "Health check",
+                "operationId": "healthz",
+                "responses": {
+                    "200": {
+                        "description": "Server is up.",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_meta.Health"
+                        }
+                    }
+                },
+                "x-outside-base": true
+            }
+        },
+        "/meta": {
+            "get": {
+                "description": "Returns the server version and build commit, the enabled features, the supported sign in methods",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "meta"
+                ],
+                "summary": "Get deployment metadata",
+                "operationId": "getMeta",
+                "responses": {
+                    "200": {
+                        "description": "Deployment metadata.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_meta.Info"
+                        }
+                    }
+                }
+            }
+        },
+        "/oidc/authorize": {
+            "get": {
+                "description": "Redirects the signed in user back to the client's redirect_uri with a single-use code or an OAuth error.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "oidc"
+                ],
+                "summary": "Authorize an OpenID Connect client",
+                "operationId": "oidcAuthorize",
+                "parameters": [
+                    {
+                        "enum": [
+                            "code"
+                        ],
+                        "type": "string",
+                        "description": "code",
+                        "name": "response_type",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Client ID",
+                        "name": "client_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "A registered redirect URI of the client",
+                        "name": "redirect_uri",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Space separated scopes, openid is required",
+                        "name": "scope",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Returned to the client as it is",
+                        "name": "state",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Set in the ID token",
+                        "name": "nonce",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "PKCE code challenge",
+                        "name": "code_challenge",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "S256"
+                        ],
+                        "type": "string",
+                        "description": "PKCE method",
+                        "name": "code_challenge_method",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "none",
+                            "consent"
+                        ],
+                        "type": "string",
+                        "description": "none to fail instead of sending the user to sign in or consent, consent to ask for consent again",
+                        "name": "prompt",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "302": {
+                        "description": "Redirect to the client with a code or an error.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Unknown client or redirect URI.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/oidc/consent": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the client of the authorization request, the scopes it requests and those the user already granted it, for the consent screen.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "oidc"
+                ],
+                "summary": "Get the consent prompt of an OpenID Connect authorization request",
+                "operationId": "getOIDCConsent",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Client ID",
+                        "name": "client_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "A registered redirect URI of the client",
+                        "name": "redirect_uri",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Space separated scopes, openid is required",
+                        "name": "scope",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Consent prompt.",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_oidc.ConsentPrompt"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid authorization request.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Records the scopes the user grants the client of the authorization request and returns where to send the user: back to the client with a code or, when denied, with access_denied.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "oidc"
+                ],
+                "summary": "Grant or deny an OpenID Connect authorization request",
+                "operationId": "decideOIDCConsent",
+                "parameters": [
+                    {
+                        "description": "Consent decision",
+                        "name": "Decision",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_oidcConfig.ConsentDecision"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Where to send the user.",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_oidc.ConsentResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid authorization request or openid not granted.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/oidc/token": {
+            "post": {
+                "description": "Exchanges an authorization code for an ID token and an access token of the userinfo endpoint.",
+                "consumes": [
+                    "application/x-www-form-urlencoded"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "oidc"
+                ],
+                "summary": "Redeem an OpenID Connect authorization code",
+                "operationId": "oidcToken",
+                "parameters": [
+                    {
+                        "enum": [
+                            "authorization_code"
+                        ],
+                        "type": "string",
+                        "description": "authorization_code",
+                        "name": "grant_type",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Authorization code",
+                        "name": "code",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Redirect URI the code was sent to",
+                        "name": "redirect_uri",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Client ID, unless sent with HTTP Basic",
+                        "name": "client_id",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Client secret, unless sent with HTTP Basic",
+                        "name": "client_secret",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "PKCE code verifier",
+                        "name": "code_verifier",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Tokens issued.",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_oidc.TokenResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request or grant.",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_oidc.TokenError"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid client credentials.",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_oidc.TokenError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error.",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_oidc.TokenError"
+                        }
+                    }
+                }
+            }
+        },
+        "/oidc/userinfo": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the claims about the user of the access token by its scopes: sub always, preferred_username with profile, email and email_verified with email.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "oidc"
+                ],
+                "summary": "Get claims about the user of an OpenID Connect access token",
+                "operationId": "oidcUserInfo",
+                "responses": {
+                    "200": {
+                        "description": "Claims about the user.",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_oidc.UserInfo"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid access token.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/openapi.json": {
+            "get": {
+                "description": "Returns the Swagger 2.0 (OpenAPI 2) spec of this API version as the running build serves it: the documented",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "meta"
+                ],
+                "summary": "Get the API spec",
+                "operationId": "getOpenAPI",
+                "parameters": [
+                    {
+                        "enum": [
+                            "en",
+                            "ru"
+                        ],
+                        "type": "string",
+                        "description": "Language of the descriptions",
+                        "name": "lang",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "The spec.",
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                }
+            }
+        },
+        "/password/forgot": {
+            "post": {
+                "description": "Emails a single-use password reset link to the account with the given login or email, valid for an hour by default.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Request a password reset link",
+                "operationId": "forgotPassword",
+                "parameters": [
+                    {
+                        "description": "Login or email of the account",
+                        "name": "Forgot",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_userConfig.PasswordForgot"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Reset link emailed if the account exists.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "Email is not configured.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/password/reset": {
+            "post": {
+                "description": "Sets a new password with a single-use reset token, from the link emailed after POST /password/forgot or after an admin reset the user's credentials.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Reset password with a token",
+                "operationId": "resetPassword",
+                "parameters": [
+                    {
+                        "description": "Reset token and new password",
+                        "name": "Reset",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_userConfig.PasswordReset"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Password changed.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input, or a password violating the password policy.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Unknown, used or expired reset token.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/reports": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Files a report against another user. Reasons are 'spam', 'abuse', 'impersonation' or 'other'.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reports"
+                ],
+                "summary": "Report abuse",
+                "operationId": "reportAbuse",
+                "parameters": [
+                    {
+                        "description": "Reported target and reason",
+                        "name": "Report",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_reportConfig.ReportRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Report filed.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_reportConfig.Report"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input or reporting yourself.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Reported user not found.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "An open report for this target already exists.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/scim/v2/Users": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lists users in SCIM format ordered by creation. Supports the filters userName eq \"login\" and externalId eq \"id\".",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "scim"
+                ],
+                "summary": "List users for provisioning",
+                "operationId": "scimListUsers",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "userName eq \\",
+                        "name": "filter",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "1-based index of the first result (default is 1)",
+                        "name": "startIndex",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size (default and maximum is 100)",
+                        "name": "count",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Users retrieved.",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_scim.ListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Unsupported filter.",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_scim.Error"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid provisioning token.",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_scim.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error.",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_scim.Error"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Creates a user from a SCIM user. An email is required, without a password the user cannot sign in until one is set.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "scim"
+                ],
+                "summary": "Provision a user",
+                "operationId": "scimCreateUser",
+                "parameters": [
+                    {
+                        "description": "SCIM user",
+                        "name": "User",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_scim.User"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "User created.",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_scim.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid user.",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_scim.Error"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid provisioning token.",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_scim.Error"
+                        }
+                    },
+                    "409": {
+                        "description": "Login, email or externalId is taken.",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_scim.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error.",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_scim.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/scim/v2/Users/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the user in SCIM format.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "scim"
+                ],
+                "summary": "Get a user for provisioning",
+                "operationId": "scimGetUser",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "User retrieved.",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_scim.User"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid provisioning token.",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_scim.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found.",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_scim.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error.",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_scim.Error"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Replaces all attributes of the user. Setting active to false blocks the user and revokes their sessions.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "scim"
+                ],
+                "summary": "Replace a provisioned user",
+                "operationId": "scimReplaceUser",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "SCIM user",
+                        "name": "User",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_scim.User"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "User replaced.",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_scim.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid user.",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_scim.Error"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid provisioning token.",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_scim.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found.",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_scim.Error"
+                        }
+                    },
+                    "409": {
+                        "description": "Login, email or externalId is taken.",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_scim.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error.",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_scim.Error"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Deletes the user like an admin would and revokes their sessions.",
+                "tags": [
+                    "scim"
+                ],
+                "summary": "Deprovision a user",
+                "operationId": "scimDeleteUser",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "User deleted."
+                    },
+                    "401": {
+                        "description": "Missing or invalid provisioning token.",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_scim.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found.",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_scim.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error.",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_scim.Error"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Applies SCIM PatchOp operations (add, replace, remove) to the user, e.g. {\"op\": \"replace\", \"path\": \"active\", \"value\": false} deactivates it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "scim"
+                ],
+                "summary": "Update a provisioned user",
+                "operationId": "scimPatchUser",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "SCIM patch",
+                        "name": "Patch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_scim.PatchRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "User updated.",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_scim.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid operation.",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_scim.Error"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid provisioning token.",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_scim.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found.",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_scim.Error"
+                        }
+                    },
+                    "409": {
+                        "description": "Login, email or externalId is taken.",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_scim.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error.",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_scim.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/todos": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieves all tasks with optional filtering by status (e.g., completed or in-progress). Pinned tasks come first.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "todo"
+                ],
+                "summary": "Retrieve all tasks",
+                "operationId": "listTodos",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Filter tasks by completion: all, completed, or inWork",
+                        "name": "filter",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter tasks by a status of the workflow",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Filter tasks by whether a task they depend on is still open",
+                        "name": "blocked",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter tasks by due date: today, overdue (due before today, UTC) or a date as YYYY-MM-DD",
+                        "name": "due",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by the value of the custom field 'key', e.g. custom.priority=high; several may be given",
+                        "name": "custom.key",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Tasks retrieved successfully.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_todoConfig.MetaResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Unknown status, custom field or invalid value, invalid blocked or due.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Creates a new task by accepting a JSON payload with the task's details.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "todo"
+                ],
+                "summary": "Create a new task",
+                "operationId": "createTodo",
+                "parameters": [
+                    {
+                        "description": "Task data for creating a new task",
+                        "name": "UserData",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_todoConfig.TodoRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Task successfully created, returns the created task.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_todoConfig.Todo"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body, missing/incorrect fields, unknown status, invalid due date or custom field values.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Todo limit of the guest session reached.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Title rejected by moderation.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests, see Retry-After.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/todos/calendar": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the tasks due from one date to another, both inclusive, grouped by day in date order.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "todo"
+                ],
+                "summary": "Retrieve tasks by due date",
+                "operationId": "getCalendar",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "First day as YYYY-MM-DD",
+                        "name": "from",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last day as YYYY-MM-DD",
+                        "name": "to",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Tasks retrieved successfully.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_todoConfig.CalendarResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Missing or invalid from or to, or a range that is reversed or too long.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/todos/changes": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns tasks created, updated or deleted after the cursor, oldest first. Deleted tasks are reported by id only.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "todo"
+                ],
+                "summary": "Retrieve task changes since a cursor",
+                "operationId": "getChanges",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Cursor returned by the previous call",
+                        "name": "since",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Seconds to wait for changes, up to 30 (default is 0)",
+                        "name": "wait",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Changes retrieved successfully.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_todoConfig.ChangesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid cursor or wait.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/todos/fields": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the custom fields the user defined for their tasks.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "todo"
+                ],
+                "summary": "Get custom todo fields",
+                "operationId": "getTodoFieldSchema",
+                "responses": {
+                    "200": {
+                        "description": "Custom fields retrieved.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_fields.Schema"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Replaces the custom fields of the user's tasks. Values of removed fields are deleted from the tasks.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "todo"
+                ],
+                "summary": "Set custom todo fields",
+                "operationId": "setTodoFieldSchema",
+                "parameters": [
+                    {
+                        "description": "Custom field definitions",
+                        "name": "Schema",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_fields.Schema"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Custom fields set.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_fields.Schema"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request payload.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests, see Retry-After.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/todos/filters": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the user's saved filters in the order they were created.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "todo"
+                ],
+                "summary": "List saved filters",
+                "operationId": "listFilters",
+                "responses": {
+                    "200": {
+                        "description": "Saved filters retrieved.",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_todoConfig.SavedFilter"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Saves a named snapshot of GET /todos query parameters: filter, status, blocked, due and custom.\u003ckey\u003e.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "todo"
+                ],
+                "summary": "Save a filter",
+                "operationId": "saveFilter",
+                "parameters": [
+                    {
+                        "description": "Name and query parameters of the filter",
+                        "name": "Filter",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_todoConfig.FilterRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Filter saved.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_todoConfig.SavedFilter"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request payload or query parameters.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Filter limit reached.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "A filter with the name exists.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests, see Retry-After.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/todos/filters/{filter}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "todo"
+                ],
+                "summary": "Delete a saved filter",
+                "operationId": "deleteFilter",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Public ID (UUID) of the saved filter",
+                        "name": "filter",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Filter deleted.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid filter ID.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Filter not found.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests, see Retry-After.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/todos/filters/{filter}/todos": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lists the tasks matching the saved filter, as GET /todos with its query parameters would.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "todo"
+                ],
+                "summary": "Retrieve the tasks of a saved filter",
+                "operationId": "listFilterTodos",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Public ID (UUID) of the saved filter",
+                        "name": "filter",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Tasks retrieved successfully.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_todoConfig.MetaResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid filter ID, or the filter is no longer valid.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Filter not found.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/todos/heatmap": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns how many tasks the user completed on each day of the year (UTC), for a GitHub-style heatmap.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "todo"
+                ],
+                "summary": "Retrieve completions per day",
+                "operationId": "getHeatmap",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Year, the current one by default",
+                        "name": "year",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Completions retrieved successfully.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_todoConfig.HeatmapResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid year.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/todos/sync": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Applies the client's pending mutations in order and returns the server changes since the client's cursor.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "todo"
+                ],
+                "summary": "Synchronize offline changes",
+                "operationId": "syncTodos",
+                "parameters": [
+                    {
+                        "description": "Pending mutations and the last cursor",
+                        "name": "SyncData",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_todoConfig.SyncRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Per mutation results and the server delta.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_todoConfig.SyncResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body, cursor or strategy.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests, see Retry-After.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/todos/workflow": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the statuses the user's tasks move through and the allowed transitions, the default workflow",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "todo"
+                ],
+                "summary": "Get the todo workflow",
+                "operationId": "getWorkflow",
+                "responses": {
+                    "200": {
+                        "description": "Workflow retrieved.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_workflow.Workflow"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Replaces the workflow of the user's tasks. New tasks start in the first open status. A status without next",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "todo"
+                ],
+                "summary": "Set the todo workflow",
+                "operationId": "setWorkflow",
+                "parameters": [
+                    {
+                        "description": "Statuses with their transitions",
+                        "name": "Workflow",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_workflow.Workflow"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Workflow set.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_workflow.Workflow"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request payload.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests, see Retry-After.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/todos/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieves a specific task by its ID from the URL, with the ids of the tasks it depends on (blockedBy)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "todo"
+                ],
+                "summary": "Retrieve a task by ID",
+                "operationId": "getTodo",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Public ID (UUID) of the task to retrieve",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Task retrieved successfully.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_todoConfig.Todo"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid or missing task ID.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Task not found.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Updates an existing task by accepting a JSON payload with the updated task details.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "todo"
+                ],
+                "summary": "Update an existing task",
+                "operationId": "updateTodo",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Public ID (UUID) of the task to update",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Updated task data",
+                        "name": "UserData",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_todoConfig.TodoRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Task updated successfully, returns the updated task.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_todoConfig.Todo"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body, missing/incorrect fields, transition not allowed, invalid due date, custom field values or ID.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Task not found.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "The task is blocked by an open task.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Title rejected by moderation.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests, see Retry-After.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Deletes a task by its ID from the URL.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "todo"
+                ],
+                "summary": "Delete a task by ID",
+                "operationId": "deleteTodo",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Public ID (UUID) of the task to delete",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Task deleted successfully.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid or missing task ID.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Task not found.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests, see Retry-After.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/todos/{id}/blocked-by/{blocker}": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Records that the task cannot be completed while the blocking task is open. Dependencies that would create",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "todo"
+                ],
+                "summary": "Mark a task as blocked by another",
+                "operationId": "addBlocker",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Public ID (UUID) of the blocked task",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Public ID (UUID) of the blocking task",
+                        "name": "blocker",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Dependency recorded, returns the blocked task.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_todoConfig.Todo"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid task ID.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Task not found.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "The dependency would create a cycle.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests, see Retry-After.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Deletes the record that the task is blocked by the other one.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "todo"
+                ],
+                "summary": "Remove a dependency between tasks",
+                "operationId": "removeBlocker",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Public ID (UUID) of the blocked task",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Public ID (UUID) of the blocking task",
+                        "name": "blocker",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Dependency removed, returns the formerly blocked task.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_todoConfig.Todo"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid task ID.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Task or dependency not found.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests, see Retry-After.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/todos/{id}/duplicate": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Creates a copy of the task with a new ID: its title, status, custom field values, due date and the tasks it depends on (blockedBy).",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "todo"
+                ],
+                "summary": "Duplicate a task",
+                "operationId": "duplicateTodo",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Public ID (UUID) of the task to duplicate",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Task duplicated, returns the copy.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_todoConfig.Todo"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid task ID.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Todo limit of the guest session reached.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Task not found.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests, see Retry-After.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/todos/{id}/pin": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Pins the task, pinned tasks are listed first. A user may pin up to 10 tasks. Pinning a pinned task changes nothing.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "todo"
+                ],
+                "summary": "Pin a task",
+                "operationId": "pinTodo",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Public ID (UUID) of the task to pin",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Task pinned, returns the task.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_todoConfig.Todo"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid task ID.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Pinned task limit reached.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Task not found.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests, see Retry-After.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/todos/{id}/unpin": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Unpins the task. Unpinning a task that is not pinned changes nothing.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "todo"
+                ],
+                "summary": "Unpin a task",
+                "operationId": "unpinTodo",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Public ID (UUID) of the task to unpin",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Task unpinned, returns the task.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_todoConfig.Todo"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid task ID.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Task not found.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests, see Retry-After.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/authorized-apps": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the OpenID Connect clients the user granted scopes to on the consent screen, the last granted first.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "List authorized apps",
+                "operationId": "listAuthorizedApps",
+                "responses": {
+                    "200": {
+                        "description": "Authorized apps.",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_oidcConfig.AuthorizedApp"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/authorized-apps/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Revokes the scopes the user granted the client, its tokens of the user are refused from now on and it has to ask for consent again.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Revoke an authorized app",
+                "operationId": "revokeAuthorizedApp",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Client ID (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "App revoked.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid client ID.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "No such authorized app.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/devices": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Returns the devices the user signed in on with rememberMe whose refresh token did not expire, the most recently used first.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "List remembered devices",
+                "operationId": "listDevices",
+                "responses": {
+                    "200": {
+                        "description": "Remembered devices.",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_userConfig.Device"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/devices/{device}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Revokes the refresh token of a remembered device, it has to sign in again once its access token expires.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Revoke a remembered device",
+                "operationId": "revokeDevice",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Public ID (UUID) of the device",
+                        "name": "device",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Device revoked.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid device ID.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "No such device.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/password": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Sets a new password after checking the current one. Every refresh token and remembered device of the user is revoked,",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Change password",
+                "operationId": "updatePassword",
+                "parameters": [
+                    {
+                        "description": "Current and new password",
+                        "name": "Password",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_userConfig.PasswordChange"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Password changed.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_userConfig.PasswordChanged"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input, or a password violating the password policy.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized access. Bearer token missing or invalid.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Wrong current password.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "No such user.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/profile": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieves the full profile of the currently authenticated user.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Get user profile",
+                "operationId": "getProfile",
+                "responses": {
+                    "200": {
+                        "description": "Returns the user profile data.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_userConfig.TableUser"
+                        }
+                    },
+                    "404": {
+                        "description": "No such user.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Updates the user profile with new data provided in the JSON payload.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Update user profile",
+                "operationId": "updateProfile",
+                "parameters": [
+                    {
+                        "description": "Updated user's any data",
+                        "name": "Userdata",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_userConfig.PutUser"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Profile successfully updated.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_userConfig.UpdatedUser"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid custom field value or locale.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "No such user.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Username rejected by moderation.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/profile/fields": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lists the custom profile fields shown to the user: their type, whether they are required",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Get custom profile fields",
+                "operationId": "getProfileFields",
+                "responses": {
+                    "200": {
+                        "description": "Returns the custom fields.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_fields.Schema"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/profile/login": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Changes the login of the authenticated user. Logins can be changed once per cooldown period",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Change user's login",
+                "operationId": "changeLogin",
+                "parameters": [
+                    {
+                        "description": "New login",
+                        "name": "Login",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_userConfig.LoginRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Login changed.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_userConfig.LoginChange"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "No such user.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Login already used or reserved.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Login was changed recently, see Retry-After.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/profile/reset-password": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Updates the user's password with new data provided in the JSON payload.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Update user' Password",
+                "operationId": "changePassword",
+                "parameters": [
+                    {
+                        "description": "New password",
+                        "name": "Password",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_userConfig.Pwd"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Password changed.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input, or a password violating the password policy.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "No such user.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/verify-email": {
+            "get": {
+                "description": "Marks the account verified with the single-use token of the link emailed after sign up or POST /verify-email/resend.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Verify the email of an account",
+                "operationId": "verifyEmail",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Verification token",
+                        "name": "token",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Email verified.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Missing token.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Unknown, used or expired verification token.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/verify-email/resend": {
+            "post": {
+                "description": "Emails a new verification link to the unverified account with the given login or email, the earlier link stops working.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Request a new email verification link",
+                "operationId": "resendVerification",
+                "parameters": [
+                    {
+                        "description": "Login or email of the account",
+                        "name": "Resend",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_userConfig.VerificationResend"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Verification link emailed if the account exists and is unverified.",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal error.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "Email is not configured.",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "ReportParam": {
+            "type": "object",
+            "properties": {
+                "default": {
+                    "description": "Default is used when the parameter is not given, a parameter without one is required"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "ReportQuery": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "params": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/ReportParam"
+                    }
+                }
+            }
+        },
+        "ReportResult": {
+            "type": "object",
+            "properties": {
+                "columns": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "query": {
+                    "type": "string"
+                },
+                "rows": {
+                    "type": "array",
+                    "items": {
+                        "type": "array",
+                        "items": {}
+                    }
+                },
+                "truncated": {
+                    "description": "Truncated tells more rows matched than the configured maximum",
+                    "type": "boolean"
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_http-server_handleUtil.Problem": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "detail": {
+                    "type": "string"
+                },
+                "fields": {
+                    "description": "Fields lists the unknown fields of a strictly decoded body, see StrictJSON",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "instance": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "violations": {
+                    "description": "Violations lists the password policy rules a password violates, see password.Policy",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_alerting.Rules": {
+            "type": "object",
+            "properties": {
+                "cooldownMinutes": {
+                    "type": "integer",
+                    "maximum": 1440,
+                    "minimum": 0
+                },
+                "emails": {
+                    "description": "Emails receive every alert when SMTP is configured, see mail.Config",
+                    "type": "array",
+                    "maxItems": 20,
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "errorRate": {
+                    "description": "ErrorRate is the share of 5xx responses, from 0 to 1",
+                    "type": "number",
+                    "maximum": 1,
+                    "minimum": 0
+                },
+                "failedLogins": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "jobFailures": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "minRequests": {
+                    "description": "MinRequests keeps a few failed requests on a quiet server from raising the error rate alert",
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "webhookUrl": {
+                    "description": "WebhookURL receives every alert as a JSON POST",
+                    "type": "string"
+                },
+                "windowMinutes": {
+                    "type": "integer",
+                    "maximum": 60,
+                    "minimum": 1
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_api_access.JWK": {
+            "type": "object",
+            "properties": {
+                "alg": {
+                    "type": "string"
+                },
+                "crv": {
+                    "description": "Crv, X and Y are the curve and coordinates of an ECDSA key",
+                    "type": "string"
+                },
+                "e": {
+                    "type": "string"
+                },
+                "kid": {
+                    "type": "string"
+                },
+                "kty": {
+                    "type": "string"
+                },
+                "n": {
+                    "description": "N and E are the modulus and exponent of an RSA key",
+                    "type": "string"
+                },
+                "use": {
+                    "type": "string"
+                },
+                "x": {
+                    "type": "string"
+                },
+                "y": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_api_access.JWKSet": {
+            "type": "object",
+            "properties": {
+                "keys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_api_access.JWK"
+                    }
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_api_readonly.Mode": {
+            "type": "object",
+            "properties": {
+                "enabled": {
+                    "type": "boolean"
+                },
+                "forced": {
+                    "description": "Forced is set when the configuration enables read-only mode, admins cannot switch it off. It is ignored when set.",
+                    "type": "boolean"
+                },
+                "message": {
+                    "description": "Message tells clients why writes are rejected, e.g. until when",
+                    "type": "string",
+                    "maxLength": 200
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_backup.Backup": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "finished": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "key": {
+                    "description": "Key is the blob key of the dump",
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer"
+                },
+                "started": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_backup.ListResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_backup.Backup"
+                    }
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_bannerConfig.Banner": {
+            "type": "object",
+            "properties": {
+                "audience": {
+                    "type": "string"
+                },
+                "created": {
+                    "type": "string"
+                },
+                "ends": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "severity": {
+                    "type": "string"
+                },
+                "starts": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_bannerConfig.BannerRequest": {
+            "type": "object",
+            "required": [
+                "message",
+                "severity"
+            ],
+            "properties": {
+                "audience": {
+                    "type": "string",
+                    "enum": [
+                        "all",
+                        "users",
+                        "guests",
+                        "admins"
+                    ]
+                },
+                "ends": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string",
+                    "maxLength": 500
+                },
+                "severity": {
+                    "type": "string",
+                    "enum": [
+                        "info",
+                        "warning",
+                        "critical"
+                    ]
+                },
+                "starts": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_cache.Invalidated": {
+            "type": "object",
+            "properties": {
+                "deleted": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_cache.Invalidation": {
+            "type": "object",
+            "properties": {
+                "cache": {
+                    "type": "string"
+                },
+                "pattern": {
+                    "type": "string",
+                    "maxLength": 200
+                },
+                "userId": {
+                    "description": "UserID is the public id of the user",
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_cache.Stats": {
+            "type": "object",
+            "properties": {
+                "entries": {
+                    "type": "integer"
+                },
+                "evictions": {
+                    "type": "integer"
+                },
+                "hits": {
+                    "type": "integer"
+                },
+                "invalidations": {
+                    "type": "integer"
+                },
+                "maxEntries": {
+                    "type": "integer"
+                },
+                "misses": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "ttlSeconds": {
+                    "type": "number"
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_clients.Report": {
+            "type": "object",
+            "properties": {
+                "clients": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_clients.Usage"
+                    }
+                },
+                "from": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_clients.Usage": {
+            "type": "object",
+            "properties": {
+                "client": {
+                    "type": "string"
+                },
+                "lastSeen": {
+                    "type": "string"
+                },
+                "requests": {
+                    "type": "integer"
+                },
+                "route": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_expiry.Policy": {
+            "type": "object",
+            "properties": {
+                "days": {
+                    "type": "integer",
+                    "maximum": 3650,
+                    "minimum": 0
+                },
+                "warnDays": {
+                    "description": "WarnDays is how long before the expiry users are warned, zero disables the warning",
+                    "type": "integer",
+                    "maximum": 365,
+                    "minimum": 0
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_fields.Field": {
+            "type": "object",
+            "required": [
+                "key",
+                "options",
+                "type"
+            ],
+            "properties": {
+                "key": {
+                    "description": "Key is the name of the value, lower case letters, digits and underscores",
+                    "type": "string",
+                    "maxLength": 40
+                },
+                "label": {
+                    "type": "string",
+                    "maxLength": 100
+                },
+                "options": {
+                    "description": "Options are the values of a select field",
+                    "type": "array",
+                    "maxItems": 100,
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "required": {
+                    "type": "boolean"
+                },
+                "type": {
+                    "type": "string",
+                    "enum": [
+                        "text",
+                        "number",
+                        "boolean",
+                        "date",
+                        "select"
+                    ]
+                },
+                "visibility": {
+                    "description": "Visibility applies to user profile fields, it defaults to editable",
+                    "type": "string",
+                    "enum": [
+                        "editable",
+                        "visible",
+                        "admin"
+                    ]
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_fields.Schema": {
+            "type": "object",
+            "properties": {
+                "fields": {
+                    "type": "array",
+                    "maxItems": 50,
+                    "items": {
+                        "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_fields.Field"
+                    }
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_jobs.DeadJob": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "error": {
+                    "description": "Error is the error of the last attempt",
+                    "type": "string"
+                },
+                "failedAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "kind": {
+                    "type": "string"
+                },
+                "payload": {
+                    "description": "Payload is the JSON the job was enqueued with",
+                    "type": "object"
+                },
+                "queue": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_jobs.DeadList": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_jobs.DeadJob"
+                    }
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_jobs.QueueStats": {
+            "type": "object",
+            "properties": {
+                "backoffSeconds": {
+                    "type": "integer"
+                },
+                "capacity": {
+                    "description": "Capacity bounds the jobs waiting in the queue, Enqueue fails with ErrFull beyond it",
+                    "type": "integer"
+                },
+                "concurrency": {
+                    "description": "Concurrency bounds the jobs of the queue running at once",
+                    "type": "integer"
+                },
+                "maxAttempts": {
+                    "description": "MaxAttempts is the number of runs before a failing job becomes a dead letter",
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "priority": {
+                    "description": "Priority orders the queues, workers take jobs from higher ones first",
+                    "type": "integer"
+                },
+                "queued": {
+                    "type": "integer"
+                },
+                "running": {
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_jobs.Stats": {
+            "type": "object",
+            "properties": {
+                "queues": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_jobs.QueueStats"
+                    }
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_keyConfig.Rotation": {
+            "type": "object",
+            "properties": {
+                "algorithm": {
+                    "type": "string"
+                },
+                "kid": {
+                    "type": "string"
+                },
+                "retiredKid": {
+                    "type": "string"
+                },
+                "verifiesUntil": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_mail.Rendered": {
+            "type": "object",
+            "properties": {
+                "body": {
+                    "type": "string"
+                },
+                "subject": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_mail.Template": {
+            "type": "object",
+            "required": [
+                "body",
+                "subject"
+            ],
+            "properties": {
+                "body": {
+                    "type": "string",
+                    "maxLength": 20000
+                },
+                "subject": {
+                    "type": "string",
+                    "maxLength": 200
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_mail.TemplateInfo": {
+            "type": "object",
+            "required": [
+                "body",
+                "subject"
+            ],
+            "properties": {
+                "body": {
+                    "type": "string",
+                    "maxLength": 20000
+                },
+                "custom": {
+                    "description": "Custom tells whether an admin changed the template, otherwise it is the built-in one",
+                    "type": "boolean"
+                },
+                "locale": {
+                    "description": "Locale is the locale of the template in effect, empty for the default one",
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "subject": {
+                    "type": "string",
+                    "maxLength": 200
+                },
+                "vars": {
+                    "description": "Vars are the variables the template may use, with the sample values previews are rendered with",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_meta.Branding": {
+            "type": "object",
+            "properties": {
+                "logoUrl": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "primaryColor": {
+                    "type": "string"
+                },
+                "privacyUrl": {
+                    "type": "string"
+                },
+                "supportEmail": {
+                    "type": "string"
+                },
+                "tagline": {
+                    "type": "string"
+                },
+                "termsUrl": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_meta.Info": {
+            "type": "object",
+            "properties": {
+                "authMethods": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "branding": {
+                    "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_meta.Branding"
+                },
+                "commit": {
+                    "type": "string"
+                },
+                "features": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "version": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_metrics.RouteSummary": {
+            "type": "object",
+            "properties": {
+                "clientErrors": {
+                    "type": "integer"
+                },
+                "errorRate": {
+                    "description": "ErrorRate is the share of 5xx responses",
+                    "type": "number"
+                },
+                "p50Ms": {
+                    "type": "number"
+                },
+                "p95Ms": {
+                    "type": "number"
+                },
+                "requests": {
+                    "type": "integer"
+                },
+                "route": {
+                    "description": "Route is the method and the route pattern, e.g. \"GET /api/v1/todos/{id}/\"",
+                    "type": "string"
+                },
+                "serverErrors": {
+                    "description": "ServerErrors counts 5xx responses, ClientErrors 4xx ones",
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_metrics.Summary": {
+            "type": "object",
+            "properties": {
+                "from": {
+                    "type": "string"
+                },
+                "routes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_metrics.RouteSummary"
+                    }
+                },
+                "to": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_moderation.Flag": {
+            "type": "object",
+            "properties": {
+                "created": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "kind": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "ref": {
+                    "description": "Ref is the public ID of the todo or user holding the text",
+                    "type": "string"
+                },
+                "text": {
+                    "type": "string"
+                },
+                "user": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_moderation.FlagsMeta": {
+            "type": "object",
+            "properties": {
+                "kind": {
+                    "type": "string"
+                },
+                "totalAmount": {
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_moderation.FlagsResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_moderation.Flag"
+                    }
+                },
+                "meta": {
+                    "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_moderation.FlagsMeta"
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_oidcConfig.AuthorizedApp": {
+            "type": "object",
+            "properties": {
+                "clientId": {
+                    "type": "string"
+                },
+                "granted": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_oidcConfig.Client": {
+            "type": "object",
+            "properties": {
+                "clientId": {
+                    "type": "string"
+                },
+                "clientSecret": {
+                    "description": "Secret is only returned once, when the client is registered",
+                    "type": "string"
+                },
+                "created": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "redirectUris": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_oidcConfig.ClientRequest": {
+            "type": "object",
+            "required": [
+                "name",
+                "redirectUris"
+            ],
+            "properties": {
+                "name": {
+                    "type": "string",
+                    "maxLength": 100
+                },
+                "redirectUris": {
+                    "type": "array",
+                    "maxItems": 10,
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_oidcConfig.ConsentDecision": {
+            "type": "object",
+            "required": [
+                "request"
+            ],
+            "properties": {
+                "deny": {
+                    "type": "boolean"
+                },
+                "request": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_reportConfig.Meta": {
+            "type": "object",
+            "properties": {
+                "status": {
+                    "type": "string"
+                },
+                "totalAmount": {
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_reportConfig.MetaResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_reportConfig.Report"
+                    }
+                },
+                "meta": {
+                    "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_reportConfig.Meta"
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_reportConfig.Report": {
+            "type": "object",
+            "properties": {
+                "created": {
+                    "type": "string"
+                },
+                "details": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "reporter": {
+                    "type": "string"
+                },
+                "resolution": {
+                    "type": "string"
+                },
+                "resolvedAt": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "target": {
+                    "type": "string"
+                },
+                "targetType": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_reportConfig.ReportRequest": {
+            "type": "object",
+            "required": [
+                "reason",
+                "targetId",
+                "targetType"
+            ],
+            "properties": {
+                "details": {
+                    "type": "string",
+                    "maxLength": 1000
+                },
+                "reason": {
+                    "type": "string",
+                    "enum": [
+                        "spam",
+                        "abuse",
+                        "impersonation",
+                        "other"
+                    ]
+                },
+                "targetId": {
+                    "type": "string"
+                },
+                "targetType": {
+                    "type": "string",
+                    "enum": [
+                        "user"
+                    ]
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_reportConfig.ReviewRequest": {
+            "type": "object",
+            "properties": {
+                "resolution": {
+                    "type": "string",
+                    "maxLength": 1000
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_retention.Policy": {
+            "type": "object",
+            "properties": {
+                "auditLogDays": {
+                    "type": "integer",
+                    "maximum": 36500,
+                    "minimum": 0
+                },
+                "deletedUsersDays": {
+                    "description": "DeletedUsersDays is the time soft-deleted users are kept before they are deleted with all their data",
+                    "type": "integer",
+                    "maximum": 36500,
+                    "minimum": 0
+                },
+                "guestDays": {
+                    "description": "GuestDays is the time guests are kept after their session started, with their todos",
+                    "type": "integer",
+                    "maximum": 36500,
+                    "minimum": 0
+                },
+                "loginHistoryDays": {
+                    "description": "LoginHistoryDays applies to former logins, a login still reserved for its former owner is kept",
+                    "type": "integer",
+                    "maximum": 36500,
+                    "minimum": 0
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_roleConfig.Role": {
+            "type": "object",
+            "properties": {
+                "builtin": {
+                    "description": "Builtin roles cannot be deleted",
+                    "type": "boolean"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "permissions": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "users": {
+                    "description": "Users is the number of users with the role",
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_roleConfig.RoleRequest": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string",
+                    "maxLength": 200
+                },
+                "name": {
+                    "type": "string"
+                },
+                "permissions": {
+                    "type": "array",
+                    "maxItems": 50,
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_roleConfig.UserRole": {
+            "type": "object",
+            "required": [
+                "role"
+            ],
+            "properties": {
+                "role": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_secrets.Info": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "set": {
+                    "type": "boolean"
+                },
+                "updated": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_secrets.Value": {
+            "type": "object",
+            "required": [
+                "value"
+            ],
+            "properties": {
+                "value": {
+                    "type": "string",
+                    "maxLength": 4096
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_todoConfig.CalendarDay": {
+            "type": "object",
+            "properties": {
+                "completed": {
+                    "type": "integer"
+                },
+                "date": {
+                    "type": "string"
+                },
+                "todos": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_todoConfig.Todo"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_todoConfig.CalendarResponse": {
+            "type": "object",
+            "properties": {
+                "days": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_todoConfig.CalendarDay"
+                    }
+                },
+                "from": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_todoConfig.Change": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "todo": {
+                    "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_todoConfig.Todo"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_todoConfig.ChangesResponse": {
+            "type": "object",
+            "properties": {
+                "changes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_todoConfig.Change"
+                    }
+                },
+                "cursor": {
+                    "type": "string"
+                },
+                "hasMore": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_todoConfig.FilterRequest": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "name": {
+                    "type": "string",
+                    "maxLength": 100
+                },
+                "query": {
+                    "description": "Query holds GET /todos query parameters, e.g. {\"status\": \"in_progress\", \"custom.priority\": \"high\"}",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_todoConfig.HeatmapDay": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "date": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_todoConfig.HeatmapResponse": {
+            "type": "object",
+            "properties": {
+                "days": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_todoConfig.HeatmapDay"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "year": {
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_todoConfig.Meta": {
+            "type": "object",
+            "properties": {
+                "totalAmount": {
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_todoConfig.MetaResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_todoConfig.Todo"
+                    }
+                },
+                "info": {
+                    "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_todoConfig.TodoInfo"
+                },
+                "meta": {
+                    "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_todoConfig.Meta"
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_todoConfig.Mutation": {
+            "type": "object",
+            "properties": {
+                "custom": {
+                    "description": "Custom holds the changed custom field values of create and update, null removes a value",
+                    "type": "object",
+                    "additionalProperties": {}
+                },
+                "due": {
+                    "type": "string",
+                    "x-nullable": true
+                },
+                "id": {
+                    "type": "string"
+                },
+                "isDone": {
+                    "type": "boolean",
+                    "x-nullable": true
+                },
+                "op": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_todoConfig.RestoreResult": {
+            "type": "object",
+            "properties": {
+                "asOf": {
+                    "type": "string"
+                },
+                "deleted": {
+                    "type": "integer"
+                },
+                "dryRun": {
+                    "type": "boolean"
+                },
+                "restored": {
+                    "description": "Restored counts deleted todos brought back, Reverted changed ones set back to their old state\nand Deleted the ones created after AsOf",
+                    "type": "integer"
+                },
+                "reverted": {
+                    "type": "integer"
+                },
+                "skipped": {
+                    "description": "Skipped are ids of todos that belong to another user by now, e.g. after an account merge",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_todoConfig.SavedFilter": {
+            "type": "object",
+            "properties": {
+                "created": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "query": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_todoConfig.SyncRequest": {
+            "type": "object",
+            "properties": {
+                "cursor": {
+                    "type": "string"
+                },
+                "mutations": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_todoConfig.Mutation"
+                    }
+                },
+                "strategy": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_todoConfig.SyncResponse": {
+            "type": "object",
+            "properties": {
+                "changes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_todoConfig.Change"
+                    }
+                },
+                "cursor": {
+                    "type": "string"
+                },
+                "hasMore": {
+                    "type": "boolean"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_todoConfig.SyncResult"
+                    }
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_todoConfig.SyncResult": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "todo": {
+                    "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_todoConfig.Todo"
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_todoConfig.Todo": {
+            "type": "object",
+            "properties": {
+                "blocked": {
+                    "description": "Blocked tells whether a task this one depends on is still open, it is not part of the changes feed",
+                    "type": "boolean"
+                },
+                "blockedBy": {
+                    "description": "BlockedBy and Blocks are the ids of the tasks this one depends on and of the ones depending on it,\nonly a single retrieved task has them",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "blocks": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "created": {
+                    "type": "string"
+                },
+                "custom": {
+                    "description": "Custom holds the values of the user's todo fields",
+                    "type": "object",
+                    "additionalProperties": {}
+                },
+                "due": {
+                    "description": "Due is the due date as YYYY-MM-DD",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "isDone": {
+                    "type": "boolean"
+                },
+                "pinned": {
+                    "description": "Pinned tasks are listed first",
+                    "type": "boolean"
+                },
+                "status": {
+                    "description": "Status is a status of the owner's workflow, IsDone tells whether it completes the task",
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_todoConfig.TodoInfo": {
+            "type": "object",
+            "properties": {
+                "all": {
+                    "type": "integer"
+                },
+                "completed": {
+                    "type": "integer"
+                },
+                "inWork": {
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_todoConfig.TodoRequest": {
+            "type": "object",
+            "properties": {
+                "custom": {
+                    "description": "Custom holds the changed custom field values, null removes a value",
+                    "type": "object",
+                    "additionalProperties": {}
+                },
+                "due": {
+                    "description": "Due sets the due date as YYYY-MM-DD, an empty string removes it",
+                    "type": "string",
+                    "x-nullable": true
+                },
+                "isDone": {
+                    "type": "boolean",
+                    "x-nullable": true
+                },
+                "status": {
+                    "description": "Status moves the task in the workflow, without it isDone completes or reopens the task",
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_userConfig.AuthData": {
+            "type": "object",
+            "required": [
+                "login",
+                "password"
+            ],
+            "properties": {
+                "login": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                },
+                "rememberMe": {
+                    "description": "RememberMe remembers the device: its refresh token lives longer and only works with the device cookie",
+                    "type": "boolean"
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_userConfig.CredentialsReset": {
+            "type": "object",
+            "properties": {
+                "emailed": {
+                    "type": "boolean"
+                },
+                "expires": {
+                    "type": "string"
+                },
+                "resetToken": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_userConfig.Device": {
+            "type": "object",
+            "properties": {
+                "created": {
+                    "type": "string"
+                },
+                "expires": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "lastUsed": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_userConfig.FieldChange": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string"
+                },
+                "new": {},
+                "old": {}
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_userConfig.Limits": {
+            "type": "object",
+            "properties": {
+                "maxTodos": {
+                    "type": "integer",
+                    "minimum": 0,
+                    "x-nullable": true
+                },
+                "reports": {
+                    "type": "integer",
+                    "minimum": 0,
+                    "x-nullable": true
+                },
+                "todoWrites": {
+                    "type": "integer",
+                    "minimum": 0,
+                    "x-nullable": true
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_userConfig.LoginChange": {
+            "type": "object",
+            "properties": {
+                "login": {
+                    "type": "string"
+                },
+                "nextChangeAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_userConfig.LoginRequest": {
+            "type": "object",
+            "required": [
+                "login"
+            ],
+            "properties": {
+                "login": {
+                    "type": "string",
+                    "maxLength": 60,
+                    "minLength": 2
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_userConfig.MergeRequest": {
+            "type": "object",
+            "required": [
+                "duplicate",
+                "primary"
+            ],
+            "properties": {
+                "dryRun": {
+                    "type": "boolean"
+                },
+                "duplicate": {
+                    "type": "string"
+                },
+                "primary": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_userConfig.MergeResult": {
+            "type": "object",
+            "properties": {
+                "dryRun": {
+                    "type": "boolean"
+                },
+                "duplicate": {
+                    "type": "string"
+                },
+                "primary": {
+                    "type": "string"
+                },
+                "sessionsRevoked": {
+                    "type": "integer"
+                },
+                "todosMoved": {
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_userConfig.Meta": {
+            "type": "object",
+            "properties": {
+                "sortBy": {
+                    "type": "string"
+                },
+                "sortOrder": {
+                    "type": "string"
+                },
+                "state": {
+                    "type": "string"
+                },
+                "summary": {
+                    "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_userConfig.StateSummary"
+                },
+                "totalAmount": {
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_userConfig.MetaResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_userConfig.TableUser"
+                    }
+                },
+                "meta": {
+                    "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_userConfig.Meta"
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_userConfig.PasswordChange": {
+            "type": "object",
+            "required": [
+                "currentPassword",
+                "newPassword"
+            ],
+            "properties": {
+                "currentPassword": {
+                    "type": "string",
+                    "maxLength": 60
+                },
+                "newPassword": {
+                    "type": "string",
+                    "maxLength": 60
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_userConfig.PasswordChanged": {
+            "type": "object",
+            "properties": {
+                "revokedSessions": {
+                    "description": "RevokedSessions is the number of refresh tokens and remembered devices revoked, the user signs in again everywhere",
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_userConfig.PasswordForgot": {
+            "type": "object",
+            "required": [
+                "login"
+            ],
+            "properties": {
+                "login": {
+                    "type": "string",
+                    "maxLength": 254
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_userConfig.PasswordReset": {
+            "type": "object",
+            "required": [
+                "password",
+                "token"
+            ],
+            "properties": {
+                "password": {
+                    "type": "string",
+                    "maxLength": 60
+                },
+                "token": {
+                    "type": "string",
+                    "maxLength": 128
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_userConfig.PutUser": {
+            "type": "object",
+            "properties": {
+                "custom": {
+                    "description": "Custom holds the changed custom field values, null removes a value",
+                    "type": "object",
+                    "additionalProperties": {}
+                },
+                "email": {
+                    "type": "string",
+                    "maxLength": 60,
+                    "minLength": 6
+                },
+                "locale": {
+                    "description": "Locale is the BCP 47 language tag emails to the user are rendered in, e.g. \"ru\" or \"pt-BR\".\nIt is stored in its canonical form, see mail.ParseLocale.",
+                    "type": "string",
+                    "maxLength": 35
+                },
+                "phoneNumber": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string",
+                    "maxLength": 60,
+                    "minLength": 1
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_userConfig.Pwd": {
+            "type": "object",
+            "required": [
+                "password"
+            ],
+            "properties": {
+                "password": {
+                    "type": "string",
+                    "maxLength": 60
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_userConfig.SignIn": {
+            "type": "object",
+            "properties": {
+                "at": {
+                    "type": "string"
+                },
+                "city": {
+                    "type": "string"
+                },
+                "country": {
+                    "type": "string"
+                },
+                "ip": {
+                    "type": "string"
+                },
+                "method": {
+                    "description": "Method is password, ldap, google or github",
+                    "type": "string"
+                },
+                "userAgent": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_userConfig.StateSummary": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "integer"
+                },
+                "blocked": {
+                    "type": "integer"
+                },
+                "deleted": {
+                    "type": "integer"
+                },
+                "pending": {
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_userConfig.TableUser": {
+            "type": "object",
+            "properties": {
+                "custom": {
+                    "description": "Custom holds the values of the custom profile fields, users only see the fields visible to them",
+                    "type": "object",
+                    "additionalProperties": {}
+                },
+                "date": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "isAdmin": {
+                    "type": "boolean"
+                },
+                "isBlocked": {
+                    "type": "boolean"
+                },
+                "isVerified": {
+                    "description": "IsVerified tells the user confirmed their email, accounts are unverified from sign up until they do",
+                    "type": "boolean"
+                },
+                "locale": {
+                    "description": "Locale is the locale emails to the user are rendered in, empty for the default one",
+                    "type": "string"
+                },
+                "mustChangePassword": {
+                    "description": "MustChangePassword restricts the user to changing their password until they do",
+                    "type": "boolean"
+                },
+                "passwordExpires": {
+                    "description": "PasswordExpires is set on the profile when passwords expire, PasswordExpiresSoon once the warning is due",
+                    "type": "string"
+                },
+                "passwordExpiresSoon": {
+                    "type": "boolean"
+                },
+                "phoneNumber": {
+                    "type": "string"
+                },
+                "role": {
+                    "description": "Role grants the user the admin Permissions, IsAdmin tells it is the admin role",
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_userConfig.UpdatedUser": {
+            "type": "object",
+            "properties": {
+                "changes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_userConfig.FieldChange"
+                    }
+                },
+                "custom": {
+                    "description": "Custom holds the values of the custom profile fields, users only see the fields visible to them",
+                    "type": "object",
+                    "additionalProperties": {}
+                },
+                "date": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "isAdmin": {
+                    "type": "boolean"
+                },
+                "isBlocked": {
+                    "type": "boolean"
+                },
+                "isVerified": {
+                    "description": "IsVerified tells the user confirmed their email, accounts are unverified from sign up until they do",
+                    "type": "boolean"
+                },
+                "locale": {
+                    "description": "Locale is the locale emails to the user are rendered in, empty for the default one",
+                    "type": "string"
+                },
+                "mustChangePassword": {
+                    "description": "MustChangePassword restricts the user to changing their password until they do",
+                    "type": "boolean"
+                },
+                "passwordExpires": {
+                    "description": "PasswordExpires is set on the profile when passwords expire, PasswordExpiresSoon once the warning is due",
+                    "type": "string"
+                },
+                "passwordExpiresSoon": {
+                    "type": "boolean"
+                },
+                "phoneNumber": {
+                    "type": "string"
+                },
+                "role": {
+                    "description": "Role grants the user the admin Permissions, IsAdmin tells it is the admin role",
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_userConfig.User": {
+            "type": "object",
+            "required": [
+                "email",
+                "login",
+                "password",
+                "username"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "login": {
+                    "type": "string",
+                    "maxLength": 60,
+                    "minLength": 2
+                },
+                "password": {
+                    "type": "string",
+                    "maxLength": 60
+                },
+                "phoneNumber": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string",
+                    "maxLength": 60,
+                    "minLength": 1
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_userConfig.VerificationResend": {
+            "type": "object",
+            "required": [
+                "login"
+            ],
+            "properties": {
+                "login": {
+                    "type": "string",
+                    "maxLength": 254
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_workflow.Status": {
+            "type": "object",
+            "required": [
+                "key",
+                "next"
+            ],
+            "properties": {
+                "done": {
+                    "description": "Done statuses complete the task, isDone is true in them",
+                    "type": "boolean"
+                },
+                "key": {
+                    "description": "Key is stored on the todos, lower case letters, digits and underscores",
+                    "type": "string",
+                    "maxLength": 40
+                },
+                "label": {
+                    "type": "string",
+                    "maxLength": 100
+                },
+                "next": {
+                    "description": "Next lists the statuses a task may move to, empty allows every status",
+                    "type": "array",
+                    "maxItems": 50,
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "github_com_sabbatD_srest-api_internal_lib_workflow.Workflow": {
+            "type": "object",
+            "required": [
+                "statuses"
+            ],
+            "properties": {
+                "statuses": {
+                    "type": "array",
+                    "maxItems": 50,
+                    "items": {
+                        "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_workflow.Status"
+                    }
+                }
+            }
+        },
+        "internal_http-server_handlers_admin.QueryRequest": {
+            "type": "object",
+            "required": [
+                "query"
+            ],
+            "properties": {
+                "format": {
+                    "description": "Format of the result, json by default",
+                    "type": "string",
+                    "enum": [
+                        "json",
+                        "csv"
+                    ]
+                },
+                "params": {
+                    "type": "object",
+                    "additionalProperties": {}
+                },
+                "query": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_http-server_handlers_admin.UpdateRequest": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string"
+                },
+                "value": {}
+            }
+        },
+        "internal_http-server_handlers_batch.BatchRequest": {
+            "type": "object",
+            "required": [
+                "requests"
+            ],
+            "properties": {
+                "requests": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "$ref": "#/definitions/internal_http-server_handlers_batch.Request"
+                    }
+                }
+            }
+        },
+        "internal_http-server_handlers_batch.Request": {
+            "type": "object",
+            "required": [
+                "method",
+                "path"
+            ],
+            "properties": {
+                "body": {
+                    "type": "object"
+                },
+                "headers": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "method": {
+                    "type": "string",
+                    "enum": [
+                        "GET",
+                        "POST",
+                        "PUT",
+                        "PATCH",
+                        "DELETE"
+                    ]
+                },
+                "path": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_http-server_handlers_batch.Response": {
+            "type": "object",
+            "properties": {
+                "body": {
+                    "type": "object"
+                },
+                "headers": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "status": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_http-server_handlers_meta.Health": {
+            "type": "object",
+            "properties": {
+                "commit": {
+                    "type": "string"
+                },
+                "started": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "string"
+                }
             }
         },
-        "github_com_sabbatD_srest-api_internal_lib_todoConfig.MetaResponse": {
+        "internal_http-server_handlers_oidc.ConsentPrompt": {
             "type": "object",
             "properties": {
-                "data": {
+                "clientId": {
+                    "type": "string"
+                },
+                "granted": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_todoConfig.Todo"
+                        "type": "string"
                     }
                 },
-                "info": {
-                    "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_todoConfig.TodoInfo"
+                "name": {
+                    "type": "string"
                 },
-                "meta": {
-                    "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_todoConfig.Meta"
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 }
             }
         },
-        "github_com_sabbatD_srest-api_internal_lib_todoConfig.Todo": {
+        "internal_http-server_handlers_oidc.ConsentResult": {
             "type": "object",
             "properties": {
-                "created": {
+                "redirectTo": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_http-server_handlers_oidc.Discovery": {
+            "type": "object",
+            "properties": {
+                "authorization_endpoint": {
                     "type": "string"
                 },
-                "id": {
-                    "type": "integer"
+                "claims_supported": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 },
-                "isDone": {
-                    "type": "boolean"
+                "code_challenge_methods_supported": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 },
-                "title": {
+                "grant_types_supported": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id_token_signing_alg_values_supported": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "issuer": {
+                    "type": "string"
+                },
+                "jwks_uri": {
+                    "type": "string"
+                },
+                "response_types_supported": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "scopes_supported": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "subject_types_supported": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "token_endpoint": {
+                    "type": "string"
+                },
+                "token_endpoint_auth_methods_supported": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "userinfo_endpoint": {
                     "type": "string"
                 }
             }
         },
-        "github_com_sabbatD_srest-api_internal_lib_todoConfig.TodoInfo": {
+        "internal_http-server_handlers_oidc.TokenError": {
             "type": "object",
             "properties": {
-                "all": {
-                    "type": "integer"
-                },
-                "completed": {
-                    "type": "integer"
+                "error": {
+                    "type": "string"
                 },
-                "inWork": {
-                    "type": "integer"
+                "error_description": {
+                    "type": "string"
                 }
             }
         },
-        "github_com_sabbatD_srest-api_internal_lib_todoConfig.TodoRequest": {
+        "internal_http-server_handlers_oidc.TokenResponse": {
             "type": "object",
             "properties": {
-                "isDone": {
-                    "type": "boolean"
+                "access_token": {
+                    "type": "string"
                 },
-                "title": {
+                "expires_in": {
+                    "type": "integer"
+                },
+                "id_token": {
+                    "type": "string"
+                },
+                "scope": {
+                    "type": "string"
+                },
+                "token_type": {
                     "type": "string"
                 }
             }
         },
-        "github_com_sabbatD_srest-api_internal_lib_userConfig.AuthData": {
+        "internal_http-server_handlers_oidc.UserInfo": {
             "type": "object",
-            "required": [
-                "login",
-                "password"
-            ],
             "properties": {
-                "login": {
+                "email": {
                     "type": "string"
                 },
-                "password": {
+                "email_verified": {
+                    "type": "boolean"
+                },
+                "preferred_username": {
+                    "type": "string"
+                },
+                "sub": {
                     "type": "string"
                 }
             }
         },
-        "github_com_sabbatD_srest-api_internal_lib_userConfig.Meta": {
+        "internal_http-server_handlers_scim.Error": {
             "type": "object",
             "properties": {
-                "sortBy": {
+                "detail": {
                     "type": "string"
                 },
-                "sortOrder": {
+                "schemas": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "scimType": {
                     "type": "string"
                 },
-                "totalAmount": {
-                    "type": "integer"
+                "status": {
+                    "type": "string"
                 }
             }
         },
-        "github_com_sabbatD_srest-api_internal_lib_userConfig.MetaResponse": {
+        "internal_http-server_handlers_scim.ListResponse": {
             "type": "object",
             "properties": {
-                "data": {
+                "Resources": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_userConfig.TableUser"
+                        "$ref": "#/definitions/internal_http-server_handlers_scim.User"
                     }
                 },
-                "meta": {
-                    "$ref": "#/definitions/github_com_sabbatD_srest-api_internal_lib_userConfig.Meta"
+                "itemsPerPage": {
+                    "type": "integer"
+                },
+                "schemas": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "startIndex": {
+                    "type": "integer"
+                },
+                "totalResults": {
+                    "type": "integer"
                 }
             }
         },
-        "github_com_sabbatD_srest-api_internal_lib_userConfig.PutUser": {
+        "internal_http-server_handlers_scim.Meta": {
             "type": "object",
             "properties": {
-                "email": {
-                    "type": "string",
-                    "maxLength": 60,
-                    "minLength": 6
+                "created": {
+                    "type": "string"
                 },
-                "phoneNumber": {
+                "location": {
                     "type": "string"
                 },
-                "username": {
-                    "type": "string",
-                    "maxLength": 60,
-                    "minLength": 1
+                "resourceType": {
+                    "type": "string"
                 }
             }
         },
-        "github_com_sabbatD_srest-api_internal_lib_userConfig.Pwd": {
+        "internal_http-server_handlers_scim.MultiValue": {
             "type": "object",
-            "required": [
-                "password"
-            ],
             "properties": {
-                "password": {
-                    "type": "string",
-                    "maxLength": 60,
-                    "minLength": 6
+                "primary": {
+                    "type": "boolean"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "value": {
+                    "type": "string"
                 }
             }
         },
-        "github_com_sabbatD_srest-api_internal_lib_userConfig.TableUser": {
+        "internal_http-server_handlers_scim.Name": {
             "type": "object",
             "properties": {
-                "date": {
+                "familyName": {
                     "type": "string"
                 },
-                "email": {
+                "formatted": {
+                    "type": "string"
+                },
+                "givenName": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_http-server_handlers_scim.PatchOp": {
+            "type": "object",
+            "properties": {
+                "op": {
+                    "type": "string"
+                },
+                "path": {
+                    "type": "string"
+                },
+                "value": {
+                    "type": "object"
+                }
+            }
+        },
+        "internal_http-server_handlers_scim.PatchRequest": {
+            "type": "object",
+            "properties": {
+                "Operations": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_http-server_handlers_scim.PatchOp"
+                    }
+                },
+                "schemas": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "internal_http-server_handlers_scim.User": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean",
+                    "x-nullable": true
+                },
+                "displayName": {
+                    "type": "string"
+                },
+                "emails": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_http-server_handlers_scim.MultiValue"
+                    }
+                },
+                "externalId": {
                     "type": "string"
                 },
                 "id": {
-                    "type": "integer"
+                    "type": "string"
                 },
-                "isAdmin": {
-                    "type": "boolean"
+                "meta": {
+                    "$ref": "#/definitions/internal_http-server_handlers_scim.Meta"
                 },
-                "isBlocked": {
-                    "type": "boolean"
+                "name": {
+                    "$ref": "#/definitions/internal_http-server_handlers_scim.Name"
                 },
-                "phoneNumber": {
+                "password": {
+                    "description": "Password is write only",
                     "type": "string"
                 },
-                "username": {
+                "phoneNumbers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_http-server_handlers_scim.MultiValue"
+                    }
+                },
+                "schemas": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "userName": {
                     "type": "string"
                 }
             }
         },
-        "github_com_sabbatD_srest-api_internal_lib_userConfig.User": {
+        "internal_http-server_handlers_user.GuestSession": {
             "type": "object",
-            "required": [
-                "email",
-                "login",
-                "password",
-                "username"
-            ],
             "properties": {
-                "email": {
+                "accessToken": {
                     "type": "string"
                 },
-                "login": {
-                    "type": "string",
-                    "maxLength": 60,
-                    "minLength": 2
-                },
-                "password": {
-                    "type": "string",
-                    "maxLength": 60,
-                    "minLength": 6
-                },
-                "phoneNumber": {
+                "expiresAt": {
                     "type": "string"
                 },
-                "username": {
-                    "type": "string",
-                    "maxLength": 60,
-                    "minLength": 1
+                "maxTodos": {
+                    "type": "integer"
                 }
             }
         },
-        "internal_http-server_handlers_admin.UpdateRequest": {
+        "internal_http-server_handlers_user.PendingSession": {
+            "type": "object",
+            "required": [
+                "pendingSession"
+            ],
+            "properties": {
+                "pendingSession": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_http-server_handlers_user.PendingSignIn": {
             "type": "object",
             "properties": {
-                "field": {
+                "expiresAt": {
                     "type": "string"
                 },
-                "value": {}
+                "pendingSession": {
+                    "description": "Session asks for the tokens at POST /auth/signin/pending, it is only returned by the sign in",
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string",
+                    "enum": [
+                        "new_country",
+                        "new_device"
+                    ]
+                }
             }
         },
         "internal_http-server_handlers_user.RefreshToken": {
@@ -1222,6 +10072,14 @@ const docTemplate = `{
                 "accessToken": {
                     "type": "string"
                 },
+                "deviceId": {
+                    "description": "DeviceID is the public id of the remembered device the refresh token belongs to",
+                    "type": "string"
+                },
+                "mustChangePassword": {
+                    "description": "MustChangePassword tells the access token only allows changing the password",
+                    "type": "boolean"
+                },
                 "refreshToken": {
                     "type": "string"
                 }
@@ -6,20 +6,202 @@ import (
 	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
+	"github.com/sabbatD/srest-api/internal/lib/alerting"
+	"github.com/sabbatD/srest-api/internal/lib/api/chaos"
+	"github.com/sabbatD/srest-api/internal/lib/backup"
+	"github.com/sabbatD/srest-api/internal/lib/cache"
+	"github.com/sabbatD/srest-api/internal/lib/clients"
+	"github.com/sabbatD/srest-api/internal/lib/clock"
+	"github.com/sabbatD/srest-api/internal/lib/expiry"
+	"github.com/sabbatD/srest-api/internal/lib/geoip"
+	"github.com/sabbatD/srest-api/internal/lib/jobs"
+	"github.com/sabbatD/srest-api/internal/lib/ldap"
+	"github.com/sabbatD/srest-api/internal/lib/lockout"
+	"github.com/sabbatD/srest-api/internal/lib/mail"
+	"github.com/sabbatD/srest-api/internal/lib/meta"
+	"github.com/sabbatD/srest-api/internal/lib/metrics"
+	"github.com/sabbatD/srest-api/internal/lib/moderation"
+	"github.com/sabbatD/srest-api/internal/lib/oauth"
+	"github.com/sabbatD/srest-api/internal/lib/queries"
+	"github.com/sabbatD/srest-api/internal/lib/retention"
+	"github.com/sabbatD/srest-api/internal/lib/scan"
+	"github.com/sabbatD/srest-api/internal/lib/secrets"
+	"github.com/sabbatD/srest-api/internal/password"
+	"github.com/sabbatD/srest-api/internal/storage/blob"
 )
 
 type Config struct {
-	Env        string `yaml:"env" env-default:"local"`
-	DbString   string `yaml:"dbstring" env-required:"true"`
-	HTTPServer `yaml:"http_server"`
+	Env            string        `yaml:"env" env-default:"local"`
+	DbString       string        `yaml:"dbstring" env-required:"true" redact:"true"`
+	SlowQuery      time.Duration `yaml:"slow_query" env-default:"200ms"`
+	HTTPServer     `yaml:"http_server"`
+	Deadlines      `yaml:"deadlines"`
+	RateLimits     `yaml:"rate_limits"`
+	Logins         `yaml:"logins"`
+	Guests         `yaml:"guests"`
+	Sessions       `yaml:"sessions"`
+	PasswordResets `yaml:"password_resets"`
+	Blob           blob.Config       `yaml:"blob"`
+	Uploads        scan.Config       `yaml:"uploads"`
+	Moderation     moderation.Config `yaml:"moderation"`
+	Backups        backup.Config     `yaml:"backups"`
+	Retention      retention.Config  `yaml:"retention"`
+	PasswordExpiry expiry.Config     `yaml:"password_expiry"`
+	PasswordPolicy password.Config   `yaml:"password_policy"`
+	Lockout        lockout.Config    `yaml:"lockout"`
+	Queries        queries.Config    `yaml:"queries"`
+	Metrics        metrics.Config    `yaml:"metrics"`
+	Cache          cache.Config      `yaml:"cache"`
+	Alerting       alerting.Config   `yaml:"alerting"`
+	Jobs           jobs.Config       `yaml:"jobs"`
+	Clients        clients.Config    `yaml:"clients"`
+	SMTP           mail.Config       `yaml:"smtp"`
+	// EmailVerification of new accounts needs SMTP
+	EmailVerification EmailVerification `yaml:"email_verification"`
+	// SignInConfirmation of sign ins from a new country or device needs SMTP
+	SignInConfirmation SignInConfirmation `yaml:"signin_confirmation"`
+	// JWT locates the token signing key, it is required outside local
+	JWT JWT `yaml:"jwt"`
+	// OIDC makes sAPI an OpenID Connect provider, it needs an RS256 or ES256 JWT key
+	OIDC OIDC `yaml:"oidc"`
+	// OAuth lets users sign in with Google or GitHub
+	OAuth oauth.Config `yaml:"oauth"`
+	// Secrets locates the master key integration secrets set by admins are encrypted with
+	Secrets secrets.Config `yaml:"secrets"`
+	// GeoIP locates sign ins by country and city, see geoip.Config
+	GeoIP geoip.Config `yaml:"geoip"`
+	// ReadOnly mode rejects the routes that write, see readonly.Switch
+	ReadOnly ReadOnly `yaml:"read_only"`
+	// Branding is returned to clients by GET /meta
+	Branding meta.Branding `yaml:"branding"`
+	// Chaos injects faults for client resilience testing, it is ignored in prod
+	Chaos chaos.Config `yaml:"chaos"`
+	// Clock can be frozen for reproducible time-dependent behavior, it is ignored in prod
+	Clock clock.Config `yaml:"clock"`
+	// LDAP is configured by environment, see ldap.Config
+	LDAP ldap.Config `yaml:"-"`
+	SCIM SCIM        `yaml:"-"`
 }
 
 type HTTPServer struct {
-	Address     string        `yaml:"address" env-default:"0.0.0.0:8082"`
+	// Address without a host, e.g. ":8082", listens on IPv4 and IPv6
+	Address     string        `yaml:"address" env-default:":8082"`
 	Timeout     time.Duration `yaml:"timeout" env-default:"4s"`
 	IdleTimeout time.Duration `yaml:"idleTimeout" env-default:"30s"`
 }
 
+// Deadlines bound the time a request of each route class may take, see deadline.New
+type Deadlines struct {
+	Default  time.Duration `yaml:"default" env-default:"3s"`
+	Auth     time.Duration `yaml:"auth" env-default:"3s"`
+	Admin    time.Duration `yaml:"admin" env-default:"3s"`
+	LongPoll time.Duration `yaml:"long_poll" env-default:"35s"`
+}
+
+// RateLimits are per user request limits, a zero limit disables it
+type RateLimits struct {
+	TodoWrites int `yaml:"todo_writes" env-default:"60"`
+	Reports    int `yaml:"reports" env-default:"10"`
+	// Guests limits guest sessions started per client address
+	Guests int `yaml:"guests" env-default:"10"`
+	// PasswordForgot limits password reset emails requested per client address
+	PasswordForgot int `yaml:"password_forgot" env-default:"5"`
+	// VerifyEmail limits verification emails resent per client address
+	VerifyEmail int           `yaml:"verify_email" env-default:"5"`
+	Window      time.Duration `yaml:"window" env-default:"1m"`
+}
+
+// Logins limit login changes: one per ChangeCooldown, a released login stays reserved
+// for its former owner for ReleaseHold
+type Logins struct {
+	ChangeCooldown time.Duration `yaml:"change_cooldown" env-default:"720h"`
+	ReleaseHold    time.Duration `yaml:"release_hold" env-default:"2160h"`
+}
+
+// Guests limit guest sessions: their todo count and how long their token is valid.
+// Guests are deleted by the retention policy, see retention.Policy.GuestDays.
+type Guests struct {
+	MaxTodos int           `yaml:"max_todos" env-default:"20"`
+	TokenTTL time.Duration `yaml:"token_ttl" env-default:"168h"`
+}
+
+// Sessions bound the lifetime of refresh tokens: RefreshTTL of a sign in, RememberTTL of a remembered device
+type Sessions struct {
+	RefreshTTL  time.Duration `yaml:"refresh_ttl" env-default:"12h"`
+	RememberTTL time.Duration `yaml:"remember_ttl" env-default:"720h"`
+	// Cookies switch the token transport from the Authorization header to HttpOnly cookies
+	Cookies Cookies `yaml:"cookies"`
+}
+
+// Cookies of the cookie token transport, see access.Cookies. Insecure drops the Secure attribute,
+// for local development over plain http.
+type Cookies struct {
+	Enabled  bool   `yaml:"enabled" env:"AUTH_COOKIES" env-default:"false"`
+	Path     string `yaml:"path" env-default:"/api/v1"`
+	Domain   string `yaml:"domain"`
+	SameSite string `yaml:"same_site" env-default:"strict"`
+	Insecure bool   `yaml:"insecure" env-default:"false"`
+}
+
+// PasswordResets bound the validity of password reset tokens: TokenTTL of an admin reset, ForgotTTL of one
+// users request themselves. The emailed link is Link followed by the token.
+type PasswordResets struct {
+	TokenTTL  time.Duration `yaml:"token_ttl" env-default:"24h"`
+	ForgotTTL time.Duration `yaml:"forgot_ttl" env-default:"1h"`
+	Link      string        `yaml:"link" env:"PASSWORD_RESET_LINK" env-default:"https://easydev.club/reset-password?token="`
+}
+
+// EmailVerification of new accounts: the emailed link is Link followed by a token valid for TTL.
+// With Required unverified accounts cannot sign in. Accounts are only verified while SMTP is configured.
+type EmailVerification struct {
+	Required bool          `yaml:"required" env-default:"false"`
+	TTL      time.Duration `yaml:"ttl" env-default:"48h"`
+	Link     string        `yaml:"link" env:"EMAIL_VERIFICATION_LINK" env-default:"https://easydev.club/api/v1/verify-email?token="`
+}
+
+// SignInConfirmation holds back the tokens of a sign in from a country (NewCountry) or a User-Agent (NewDevice)
+// missing from the user's recent sign ins until the user follows the emailed link, Link followed by a token valid for TTL.
+// Only users with an email are asked, and only while SMTP is configured.
+type SignInConfirmation struct {
+	Enabled    bool          `yaml:"enabled" env:"SIGNIN_CONFIRMATION" env-default:"false"`
+	NewCountry bool          `yaml:"new_country" env-default:"true"`
+	NewDevice  bool          `yaml:"new_device" env-default:"true"`
+	TTL        time.Duration `yaml:"ttl" env-default:"15m"`
+	Link       string        `yaml:"link" env:"SIGNIN_CONFIRMATION_LINK" env-default:"https://easydev.club/api/v1/auth/signin/confirm?token="`
+}
+
+// ReadOnly mode is switched by admins in the settings, every instance reloads the setting each Refresh.
+// Enabled forces it whatever the setting, e.g. for instances running against a replica.
+type ReadOnly struct {
+	Enabled bool          `yaml:"enabled" env:"READ_ONLY" env-default:"false"`
+	Refresh time.Duration `yaml:"refresh" env-default:"10s"`
+}
+
+// JWT signing key: Key or the content of KeyFile, e.g. a mounted secret. The key itself is only taken from the environment.
+// It is the secret for HS256 and a PEM private key for RS256 and ES256, see access.LoadKey.
+type JWT struct {
+	Algorithm string `yaml:"algorithm" env:"JWT_ALGORITHM" env-default:"HS256"`
+	Key       string `yaml:"-" env:"JWT_KEY" redact:"true"`
+	KeyFile   string `yaml:"key_file" env:"JWT_KEY_FILE"`
+	// ReloadInterval is how often keys rotated by another instance are picked up
+	ReloadInterval time.Duration `yaml:"reload_interval" env-default:"1m"`
+}
+
+// OIDC provider: it is enabled by setting Issuer, the public base URL of sAPI (without /api/v1).
+// Signed out users are sent to LoginURL to sign in, users who have not granted a client the scopes it requests
+// to ConsentURL. Authorization codes are valid for CodeTTL.
+type OIDC struct {
+	Issuer     string        `yaml:"issuer" env:"OIDC_ISSUER"`
+	LoginURL   string        `yaml:"login_url" env:"OIDC_LOGIN_URL"`
+	ConsentURL string        `yaml:"consent_url" env:"OIDC_CONSENT_URL"`
+	CodeTTL    time.Duration `yaml:"code_ttl" env-default:"1m"`
+}
+
+// SCIM provisioning is enabled by setting the bearer token shared with the identity provider
+type SCIM struct {
+	Token string `env:"SCIM_TOKEN" redact:"true"`
+}
+
 func MustLoad() *Config {
 	configPath := os.Getenv("CONFIG_PATH")
 	if configPath == "" {
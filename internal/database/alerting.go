@@ -0,0 +1,67 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/sabbatD/srest-api/internal/lib/alerting"
+)
+
+const settingAlerting = "alerting"
+
+// AlertRules returns the alert rules set by an admin, false when there are none
+func (s *Storage) AlertRules(ctx context.Context) (alerting.Rules, bool, error) {
+	const op = "database.postgres.AlertRules"
+
+	var rules alerting.Rules
+	var data []byte
+	err := s.db.QueryRowContext(ctx, `SELECT value FROM public.settings WHERE key = $1`, settingAlerting).Scan(&data)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return rules, false, nil
+		}
+		return rules, false, fmt.Errorf("%s: %v", op, err)
+	}
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return rules, false, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return rules, true, nil
+}
+
+// SetAlertRules stores the alert rules and records the change by actor in the audit log
+func (s *Storage) SetAlertRules(ctx context.Context, actor int, rules alerting.Rules) error {
+	const op = "database.postgres.SetAlertRules"
+
+	data, err := json.Marshal(rules)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO public.settings (key, value, updated_by) VALUES ($1, $2, $3)
+		ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, updated_by = EXCLUDED.updated_by, updated = NOW()
+	`, settingAlerting, data, actor)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := audit(ctx, tx, actor, AuditSetAlerting, nil, rules); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
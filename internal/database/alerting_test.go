@@ -0,0 +1,26 @@
+package database
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/sabbatD/srest-api/internal/lib/alerting"
+)
+
+func TestAlertRules(t *testing.T) {
+	s := testStorage(t)
+	ctx := context.Background()
+
+	admin := testUser(t, s, "alertingadmin")
+	t.Cleanup(func() { s.db.Exec(`DELETE FROM public.settings WHERE key = $1`, settingAlerting) })
+
+	want := alerting.Rules{ErrorRate: 0.1, MinRequests: 20, FailedLogins: 100, WindowMinutes: 10, Emails: []string{"ops@example.com"}}
+	if err := s.SetAlertRules(ctx, admin, want); err != nil {
+		t.Fatal(err)
+	}
+	got, ok, err := s.AlertRules(ctx)
+	if err != nil || !ok || !reflect.DeepEqual(got, want) {
+		t.Errorf("AlertRules() = %+v, %v, %v", got, ok, err)
+	}
+}
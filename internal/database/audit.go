@@ -0,0 +1,76 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+)
+
+// Audit actions
+const (
+	AuditMergeUsers    = "users.merge"
+	AuditReportReview  = "reports.review"
+	AuditUpdateProfile = "users.update_profile"
+	AuditUpdateUser    = "users.update"
+	AuditUpdateRights  = "users.update_rights"
+	AuditResetCreds    = "users.reset_credentials"
+	AuditRestoreTodos  = "todos.restore"
+	AuditSetRetention  = "settings.retention"
+	AuditSetUserFields = "settings.user_fields"
+	AuditSetAlerting   = "settings.alerting"
+	AuditSetPwdExpiry  = "settings.password_expiry"
+	AuditSetTemplate   = "settings.email_template"
+	AuditSetReadOnly   = "settings.read_only"
+	AuditResetTemplate = "settings.email_template_reset"
+	AuditProvisionUser = "users.provision"
+	AuditSCIMCreate    = "scim.create"
+	AuditSCIMUpdate    = "scim.update"
+	AuditSCIMDelete    = "scim.delete"
+	AuditCreateBanner  = "banners.create"
+	AuditUpdateBanner  = "banners.update"
+	AuditDeleteBanner  = "banners.delete"
+	AuditRotateJWTKey  = "settings.jwt_key_rotate"
+	AuditSetUserLimits = "users.limits"
+	AuditRunQuery      = "admin.query"
+	AuditCreateClient  = "oidc.client_create"
+	AuditDeleteClient  = "oidc.client_delete"
+	AuditSetSecret     = "secrets.set"
+	AuditDeleteSecret  = "secrets.delete"
+	AuditLinkIdentity  = "users.link_identity"
+	AuditCreateRole    = "roles.create"
+	AuditUpdateRole    = "roles.update"
+	AuditDeleteRole    = "roles.delete"
+	AuditSetUserRole   = "users.role"
+	AuditRequeueJob    = "jobs.requeue"
+	AuditDeleteJob     = "jobs.delete"
+)
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// Audit records an action of actor on the target user, details are stored as JSON
+func (s *Storage) Audit(ctx context.Context, actor int, action string, target int, details any) error {
+	const op = "database.postgres.Audit"
+
+	if err := audit(ctx, s.db, actor, action, target, details); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	return nil
+}
+
+// audit records an action of the actor user id (nil for a provisioning client) on the target
+// user id (nil for none) within tx or db
+func audit(ctx context.Context, exec execer, actor any, action string, target any, details any) error {
+	data, err := json.Marshal(details)
+	if err != nil {
+		return err
+	}
+
+	_, err = exec.ExecContext(ctx, `
+		INSERT INTO public.audit_log (actor_id, action, target_id, details)
+		VALUES ($1, $2, $3, $4)
+	`, actor, action, target, data)
+	return err
+}
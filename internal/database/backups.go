@@ -0,0 +1,113 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/sabbatD/srest-api/internal/lib/backup"
+)
+
+const backupColumns = `id, key, status, size, error, started, finished`
+
+func scanBackup(row scanner) (b backup.Backup, err error) {
+	err = row.Scan(&b.ID, &b.Key, &b.Status, &b.Size, &b.Error, &b.Started, &b.Finished)
+	return b, err
+}
+
+func (s *Storage) StartBackup(ctx context.Context, key string) (backup.Backup, error) {
+	const op = "database.postgres.StartBackup"
+
+	b, err := scanBackup(s.db.QueryRowContext(ctx, `
+		INSERT INTO public.backups (key) VALUES ($1)
+		RETURNING `+backupColumns, key))
+	if err != nil {
+		return b, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return b, nil
+}
+
+// FinishBackup marks the backup succeeded, or failed with errMsg when it is not empty
+func (s *Storage) FinishBackup(ctx context.Context, id int, size int64, errMsg string) (backup.Backup, error) {
+	const op = "database.postgres.FinishBackup"
+
+	status := backup.StatusSucceeded
+	if errMsg != "" {
+		status = backup.StatusFailed
+	}
+
+	b, err := scanBackup(s.db.QueryRowContext(ctx, `
+		UPDATE public.backups SET status = $1, size = $2, error = $3, finished = NOW()
+		WHERE id = $4
+		RETURNING `+backupColumns, status, size, errMsg, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return b, fmt.Errorf("%s: no backup with id %v: %w", op, id, ErrNotFound)
+		}
+		return b, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return b, nil
+}
+
+// Backups returns the newest backups first
+func (s *Storage) Backups(ctx context.Context, limit int) ([]backup.Backup, error) {
+	const op = "database.postgres.Backups"
+
+	rows, err := s.db.QueryContext(ctx, `SELECT `+backupColumns+` FROM public.backups ORDER BY id DESC LIMIT $1`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return scanBackups(op, rows)
+}
+
+// ExpiredBackups returns finished backups older than the keep newest succeeded ones
+func (s *Storage) ExpiredBackups(ctx context.Context, keep int) ([]backup.Backup, error) {
+	const op = "database.postgres.ExpiredBackups"
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+backupColumns+` FROM public.backups
+		WHERE status <> 'running' AND id < (
+			SELECT id FROM public.backups WHERE status = 'succeeded'
+			ORDER BY id DESC
+			OFFSET $1 - 1 LIMIT 1
+		)
+		ORDER BY id
+	`, keep)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return scanBackups(op, rows)
+}
+
+func (s *Storage) DeleteBackup(ctx context.Context, id int) error {
+	const op = "database.postgres.DeleteBackup"
+
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM public.backups WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+func scanBackups(op string, rows *sql.Rows) ([]backup.Backup, error) {
+	defer rows.Close()
+
+	var backups []backup.Backup
+	for rows.Next() {
+		b, err := scanBackup(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+		backups = append(backups, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return backups, nil
+}
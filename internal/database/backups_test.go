@@ -0,0 +1,52 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sabbatD/srest-api/internal/lib/backup"
+)
+
+func TestBackups(t *testing.T) {
+	s := testStorage(t)
+	ctx := context.Background()
+
+	s.db.Exec(`DELETE FROM public.backups`)
+	t.Cleanup(func() { s.db.Exec(`DELETE FROM public.backups`) })
+
+	var ids []int
+	for i, errMsg := range []string{"", "pg_dump: connection refused", "", ""} {
+		b, err := s.StartBackup(ctx, "backups/test.dump")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b.Status != backup.StatusRunning {
+			t.Errorf("started backup = %+v", b)
+		}
+		b, err = s.FinishBackup(ctx, b.ID, int64(i), errMsg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (errMsg == "") != (b.Status == backup.StatusSucceeded) || b.Finished == nil {
+			t.Errorf("finished backup = %+v", b)
+		}
+		ids = append(ids, b.ID)
+	}
+
+	list, err := s.Backups(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 4 || list[0].ID != ids[3] {
+		t.Errorf("backups = %+v", list)
+	}
+
+	// Keeping two succeeded backups expires the first one and the failed one.
+	expired, err := s.ExpiredBackups(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(expired) != 2 || expired[0].ID != ids[0] || expired[1].ID != ids[1] {
+		t.Errorf("expired = %+v", expired)
+	}
+}
@@ -0,0 +1,186 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	bc "github.com/sabbatD/srest-api/internal/lib/bannerConfig"
+)
+
+const bannerColumns = `id, public_id, message, severity, audience, starts, ends, created`
+
+func scanBanner(row scanner) (b bc.Banner, err error) {
+	err = row.Scan(&b.ID, &b.PublicID, &b.Message, &b.Severity, &b.Audience, &b.Starts, &b.Ends, &b.Created)
+	return b, err
+}
+
+// CreateBanner stores a banner and records its creation by actor in the audit log.
+// Starts must be set, an empty audience is all.
+func (s *Storage) CreateBanner(ctx context.Context, actor int, b bc.BannerRequest) (bc.Banner, error) {
+	const op = "database.postgres.CreateBanner"
+
+	if b.Audience == "" {
+		b.Audience = bc.AudienceAll
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return bc.Banner{}, fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	banner, err := scanBanner(tx.QueryRowContext(ctx, `
+		INSERT INTO public.banners (message, severity, audience, starts, ends, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING `+bannerColumns,
+		b.Message, b.Severity, b.Audience, b.Starts, b.Ends, actor))
+	if err != nil {
+		return bc.Banner{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := audit(ctx, tx, actor, AuditCreateBanner, nil, banner); err != nil {
+		return bc.Banner{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return bc.Banner{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return banner, nil
+}
+
+// UpdateBanner replaces the banner and records the change by actor in the audit log
+func (s *Storage) UpdateBanner(ctx context.Context, id, actor int, b bc.BannerRequest) (bc.Banner, error) {
+	const op = "database.postgres.UpdateBanner"
+
+	if b.Audience == "" {
+		b.Audience = bc.AudienceAll
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return bc.Banner{}, fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	banner, err := scanBanner(tx.QueryRowContext(ctx, `
+		UPDATE public.banners SET message = $1, severity = $2, audience = $3, starts = $4, ends = $5
+		WHERE id = $6
+		RETURNING `+bannerColumns,
+		b.Message, b.Severity, b.Audience, b.Starts, b.Ends, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return bc.Banner{}, fmt.Errorf("%s: no banners with id %v: %w", op, id, ErrNotFound)
+		}
+		return bc.Banner{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := audit(ctx, tx, actor, AuditUpdateBanner, nil, banner); err != nil {
+		return bc.Banner{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return bc.Banner{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return banner, nil
+}
+
+// DeleteBanner deletes the banner and records the deletion by actor in the audit log
+func (s *Storage) DeleteBanner(ctx context.Context, id, actor int) error {
+	const op = "database.postgres.DeleteBanner"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	var publicID string
+	err = tx.QueryRowContext(ctx, `DELETE FROM public.banners WHERE id = $1 RETURNING public_id`, id).Scan(&publicID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s: no banners with id %v: %w", op, id, ErrNotFound)
+		}
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := audit(ctx, tx, actor, AuditDeleteBanner, nil, map[string]string{"banner": publicID}); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) BannerID(ctx context.Context, publicID string) (int, error) {
+	const op = "database.postgres.BannerID"
+
+	var id int
+	err := s.db.QueryRowContext(ctx, `SELECT id FROM public.banners WHERE public_id = $1`, publicID).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("%s: no banners with id %v: %w", op, publicID, ErrNotFound)
+		}
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return id, nil
+}
+
+// Banners returns every banner, past and scheduled ones included, latest start first
+func (s *Storage) Banners(ctx context.Context) ([]bc.Banner, error) {
+	const op = "database.postgres.Banners"
+
+	rows, err := s.db.QueryContext(ctx, `SELECT `+bannerColumns+` FROM public.banners ORDER BY starts DESC, id DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	banners, err := scanBanners(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+	return banners, nil
+}
+
+// ActiveBanners returns the banners showing at now to the given audiences, most severe first
+func (s *Storage) ActiveBanners(ctx context.Context, audiences []string, now time.Time) ([]bc.Banner, error) {
+	const op = "database.postgres.ActiveBanners"
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+bannerColumns+` FROM public.banners
+		WHERE audience = ANY($1) AND starts <= $2 AND (ends IS NULL OR ends > $2)
+		ORDER BY CASE severity WHEN 'critical' THEN 0 WHEN 'warning' THEN 1 ELSE 2 END, starts DESC, id DESC
+	`, pq.Array(audiences), now)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	banners, err := scanBanners(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+	return banners, nil
+}
+
+func scanBanners(rows *sql.Rows) ([]bc.Banner, error) {
+	defer rows.Close()
+
+	banners := []bc.Banner{}
+	for rows.Next() {
+		b, err := scanBanner(rows)
+		if err != nil {
+			return nil, err
+		}
+		banners = append(banners, b)
+	}
+	return banners, rows.Err()
+}
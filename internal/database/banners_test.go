@@ -0,0 +1,95 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	bc "github.com/sabbatD/srest-api/internal/lib/bannerConfig"
+)
+
+func TestBanners(t *testing.T) {
+	s := testStorage(t)
+	ctx := context.Background()
+
+	admin := testUser(t, s, "banneradmin")
+	now := time.Now().UTC().Truncate(time.Second)
+	ended := now.Add(-time.Hour)
+	later := now.Add(time.Hour)
+
+	create := func(b bc.BannerRequest) bc.Banner {
+		t.Helper()
+		banner, err := s.CreateBanner(ctx, admin, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { s.db.Exec(`DELETE FROM public.banners WHERE id = $1`, banner.ID) })
+		return banner
+	}
+
+	start := now.Add(-2 * time.Hour)
+	info := create(bc.BannerRequest{Message: "New release", Severity: bc.SeverityInfo, Starts: &start})
+	critical := create(bc.BannerRequest{Message: "Maintenance", Severity: bc.SeverityCritical, Audience: bc.AudienceUsers, Starts: &start})
+	create(bc.BannerRequest{Message: "Ended", Severity: bc.SeverityWarning, Starts: &start, Ends: &ended})
+	create(bc.BannerRequest{Message: "Scheduled", Severity: bc.SeverityWarning, Starts: &later})
+	create(bc.BannerRequest{Message: "Admins only", Severity: bc.SeverityInfo, Audience: bc.AudienceAdmins, Starts: &start})
+
+	if info.Audience != bc.AudienceAll || info.Ends != nil {
+		t.Errorf("banner = %+v", info)
+	}
+
+	active, err := s.ActiveBanners(ctx, bc.Audiences(true, false, false), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, b := range active {
+		if b.ID == info.ID || b.ID == critical.ID {
+			ids = append(ids, b.PublicID)
+		} else if b.Message == "Ended" || b.Message == "Scheduled" || b.Message == "Admins only" {
+			t.Errorf("%q is shown to users", b.Message)
+		}
+	}
+	if len(ids) != 2 || ids[0] != critical.PublicID {
+		t.Errorf("active = %v, want the critical banner first", ids)
+	}
+
+	signedOut, err := s.ActiveBanners(ctx, bc.Audiences(false, false, false), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range signedOut {
+		if b.ID == critical.ID {
+			t.Error("a banner for users is shown to signed out clients")
+		}
+	}
+
+	id, err := s.BannerID(ctx, info.PublicID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated, err := s.UpdateBanner(ctx, id, admin, bc.BannerRequest{Message: "Release notes", Severity: bc.SeverityInfo, Starts: &start, Ends: &later})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Message != "Release notes" || updated.Ends == nil || !updated.Ends.Equal(later) {
+		t.Errorf("updated = %+v", updated)
+	}
+
+	if err := s.DeleteBanner(ctx, id, admin); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteBanner(ctx, id, admin); !errors.Is(err, ErrNotFound) {
+		t.Errorf("deleting twice: err = %v, want ErrNotFound", err)
+	}
+	if _, err := s.UpdateBanner(ctx, id, admin, bc.BannerRequest{Message: "m", Severity: bc.SeverityInfo, Starts: &start}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("updating a deleted banner: err = %v, want ErrNotFound", err)
+	}
+
+	var audited int
+	s.db.QueryRow(`SELECT COUNT(*) FROM public.audit_log WHERE actor_id = $1 AND action LIKE 'banners.%'`, admin).Scan(&audited)
+	if audited != 7 {
+		t.Errorf("audit entries = %d, want 7", audited)
+	}
+}
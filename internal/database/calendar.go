@@ -0,0 +1,54 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	t "github.com/sabbatD/srest-api/internal/lib/todoConfig"
+)
+
+// TodoCalendar returns the user's tasks due from from to to, both inclusive, grouped by due date.
+// Days without tasks are left out. Tasks and counts are read with a single query.
+func (s *Storage) TodoCalendar(ctx context.Context, userID int, from, to time.Time) ([]t.CalendarDay, error) {
+	const op = "database.postgres.TodoCalendar"
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, public_id, title, created, `+dueColumn+`, status, is_done, custom, pinned_at IS NOT NULL, `+blockedCondition+`
+		FROM public.todos
+		WHERE user_id = $1 AND due BETWEEN $2::date AND $3::date
+		ORDER BY due, id
+	`, userID, from.Format(t.DateFormat), to.Format(t.DateFormat))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+	defer rows.Close()
+
+	days := []t.CalendarDay{}
+	for rows.Next() {
+		var todo t.Todo
+		var custom []byte
+		if err := rows.Scan(&todo.ID, &todo.PublicID, &todo.Title, &todo.Created, &todo.Due, &todo.Status, &todo.IsDone, &custom, &todo.Pinned, &todo.Blocked); err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+		if err := json.Unmarshal(custom, &todo.Custom); err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+
+		if len(days) == 0 || days[len(days)-1].Date != todo.Due {
+			days = append(days, t.CalendarDay{Date: todo.Due, Todos: []t.Todo{}})
+		}
+		day := &days[len(days)-1]
+		day.Total++
+		if todo.IsDone {
+			day.Completed++
+		}
+		day.Todos = append(day.Todos, todo)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return days, nil
+}
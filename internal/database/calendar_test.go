@@ -0,0 +1,60 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	todoconfig "github.com/sabbatD/srest-api/internal/lib/todoConfig"
+)
+
+func TestTodoCalendar(t *testing.T) {
+	s := testStorage(t)
+	ctx := context.Background()
+
+	user := testUser(t, s, "calendaruser")
+
+	date := func(s string) *string { return &s }
+	done := true
+	for _, req := range []todoconfig.TodoRequest{
+		{Title: "report", Due: date("2024-10-21")},
+		{Title: "review", Due: date("2024-10-21"), IsDone: &done},
+		{Title: "release", Due: date("2024-10-25")},
+		{Title: "later", Due: date("2024-11-30")},
+		{Title: "someday"},
+	} {
+		if _, err := s.Create(ctx, req, user); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	from, _ := time.Parse(todoconfig.DateFormat, "2024-10-21")
+	to, _ := time.Parse(todoconfig.DateFormat, "2024-10-25")
+	days, err := s.TodoCalendar(ctx, user, from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(days) != 2 {
+		t.Fatalf("days = %+v, want 2", days)
+	}
+	if d := days[0]; d.Date != "2024-10-21" || d.Total != 2 || d.Completed != 1 || d.Todos[0].Due != "2024-10-21" {
+		t.Errorf("first day = %+v", d)
+	}
+	if d := days[1]; d.Date != "2024-10-25" || d.Total != 1 || d.Todos[0].Title != "release" {
+		t.Errorf("last day = %+v, the range includes to", d)
+	}
+
+	id := int(days[1].Todos[0].ID)
+	if _, err := s.Update(ctx, id, user, todoconfig.TodoRequest{Title: "release v2"}); err != nil {
+		t.Fatal(err)
+	}
+	if todo, _ := s.GetTodo(ctx, id, user); todo.Due != "2024-10-25" {
+		t.Errorf("update without due changed it to %q", todo.Due)
+	}
+	if _, err := s.Update(ctx, id, user, todoconfig.TodoRequest{Due: date("")}); err != nil {
+		t.Fatal(err)
+	}
+	if todo, _ := s.GetTodo(ctx, id, user); todo.Due != "" {
+		t.Errorf("removed due = %q", todo.Due)
+	}
+}
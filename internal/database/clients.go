@@ -0,0 +1,74 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sabbatD/srest-api/internal/lib/clients"
+)
+
+// RecordClientUsage adds the counts to the stored ones of the same day, client and route
+func (s *Storage) RecordClientUsage(ctx context.Context, usage []clients.Usage) error {
+	const op = "database.postgres.RecordClientUsage"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO public.client_usage (day, client, route, requests, last_seen) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (day, client, route)
+		DO UPDATE SET requests = client_usage.requests + EXCLUDED.requests,
+			last_seen = GREATEST(client_usage.last_seen, EXCLUDED.last_seen)
+	`)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	defer stmt.Close()
+
+	for _, u := range usage {
+		if _, err := stmt.ExecContext(ctx, u.Day, u.Client, u.Route, u.Requests, u.LastSeen); err != nil {
+			return fmt.Errorf("%s: %v", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// ClientUsage sums the requests by client and route since the day of from, of the routes containing route
+// when it is not empty, the latest seen first
+func (s *Storage) ClientUsage(ctx context.Context, from time.Time, route string) ([]clients.Usage, error) {
+	const op = "database.postgres.ClientUsage"
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT client, route, SUM(requests), MAX(last_seen) FROM public.client_usage
+		WHERE day >= $1::date AND ($2 = '' OR strpos(route, $2) > 0)
+		GROUP BY client, route
+		ORDER BY MAX(last_seen) DESC, client, route
+	`, from.UTC(), route)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+	defer rows.Close()
+
+	usage := []clients.Usage{}
+	for rows.Next() {
+		var u clients.Usage
+		if err := rows.Scan(&u.Client, &u.Route, &u.Requests, &u.LastSeen); err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+		usage = append(usage, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return usage, nil
+}
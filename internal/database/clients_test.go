@@ -0,0 +1,45 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sabbatD/srest-api/internal/lib/clients"
+)
+
+func TestClientUsage(t *testing.T) {
+	s := testStorage(t)
+	ctx := context.Background()
+
+	const route = "GET /api/v1/test-clients/{id}"
+	t.Cleanup(func() { s.db.Exec(`DELETE FROM public.client_usage WHERE route = $1`, route) })
+
+	now := time.Now().UTC()
+	today := now.Truncate(24 * time.Hour)
+	old := today.AddDate(0, 0, -40)
+	usage := []clients.Usage{
+		{Day: today, Client: "ios/2.3.1", Route: route, Requests: 2, LastSeen: now.Add(-time.Hour)},
+		{Day: today.AddDate(0, 0, -1), Client: "ios/2.3.1", Route: route, Requests: 3, LastSeen: now.Add(-25 * time.Hour)},
+		{Day: old, Client: "android/1.0", Route: route, Requests: 7, LastSeen: old},
+	}
+	if err := s.RecordClientUsage(ctx, usage); err != nil {
+		t.Fatal(err)
+	}
+	// Counts of the same day add up
+	if err := s.RecordClientUsage(ctx, usage[:1]); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.ClientUsage(ctx, now.AddDate(0, 0, -30), "test-clients")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Client != "ios/2.3.1" || got[0].Requests != 7 {
+		t.Fatalf("ClientUsage() = %+v, want ios/2.3.1 with 7 requests", got)
+	}
+
+	if got, _ := s.ClientUsage(ctx, now.AddDate(0, 0, -60), "test-clients"); len(got) != 2 {
+		t.Errorf("ClientUsage() over 60 days = %+v, want 2 clients", got)
+	}
+}
@@ -0,0 +1,94 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/sabbatD/srest-api/internal/lib/clock"
+	u "github.com/sabbatD/srest-api/internal/lib/userConfig"
+)
+
+// CreatePendingSignIn stores a sign in waiting for confirmation until in.Expires: the client asks for it with the session
+// token, the emailed link confirms it with the other token. Expired pending sign ins are deleted along the way.
+func (s *Storage) CreatePendingSignIn(ctx context.Context, in u.PendingSignIn, sessionHash, tokenHash string) error {
+	const op = "database.postgres.CreatePendingSignIn"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM public.pending_sign_ins WHERE expires <= $1`, clock.Now()); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO public.pending_sign_ins (user_id, session_hash, token_hash, method, remember_me, ip, country, city, user_agent, expires)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`, in.UserID, sessionHash, tokenHash, in.Method, in.RememberMe, in.IP, in.Country, in.City, in.UserAgent, in.Expires)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	return nil
+}
+
+// ConfirmSignIn confirms the unexpired pending sign in with the confirmation token hash and returns its user.
+// Returns ErrNotFound for an unknown or expired token.
+func (s *Storage) ConfirmSignIn(ctx context.Context, tokenHash string) (int, error) {
+	const op = "database.postgres.ConfirmSignIn"
+
+	var id int
+	err := s.db.QueryRowContext(ctx, `
+		UPDATE public.pending_sign_ins SET confirmed = TRUE WHERE token_hash = $1 AND expires > $2 RETURNING user_id
+	`, tokenHash, clock.Now()).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("%s: pending sign in %w", op, ErrNotFound)
+		}
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return id, nil
+}
+
+// ClaimSignIn returns the unexpired pending sign in with the session token hash. A confirmed one is deleted,
+// so its tokens are issued once; an unconfirmed one is kept and returned with Confirmed false.
+// Returns ErrNotFound for an unknown, claimed or expired session.
+func (s *Storage) ClaimSignIn(ctx context.Context, sessionHash string) (u.PendingSignIn, error) {
+	const op = "database.postgres.ClaimSignIn"
+
+	const columns = `user_id, remember_me, confirmed, expires, method, ip, country, city, user_agent`
+	scan := func(row *sql.Row) (in u.PendingSignIn, err error) {
+		err = row.Scan(&in.UserID, &in.RememberMe, &in.Confirmed, &in.Expires, &in.Method, &in.IP, &in.Country, &in.City, &in.UserAgent)
+		return in, err
+	}
+
+	now := clock.Now()
+	in, err := scan(s.db.QueryRowContext(ctx, `
+		DELETE FROM public.pending_sign_ins WHERE session_hash = $1 AND confirmed AND expires > $2 RETURNING `+columns,
+		sessionHash, now))
+	if err == nil {
+		return in, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return u.PendingSignIn{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	in, err = scan(s.db.QueryRowContext(ctx, `
+		SELECT `+columns+` FROM public.pending_sign_ins WHERE session_hash = $1 AND expires > $2`, sessionHash, now))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return u.PendingSignIn{}, fmt.Errorf("%s: pending sign in %w", op, ErrNotFound)
+		}
+		return u.PendingSignIn{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return in, nil
+}
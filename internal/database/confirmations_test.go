@@ -0,0 +1,65 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	u "github.com/sabbatD/srest-api/internal/lib/userConfig"
+	"github.com/sabbatD/srest-api/internal/password"
+)
+
+func TestPendingSignIns(t *testing.T) {
+	s := testStorage(t)
+	ctx := context.Background()
+
+	id := testUser(t, s, "pendinguser")
+	_, sessionHash, err := password.NewToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, tokenHash, err := password.NewToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := u.PendingSignIn{UserID: id, RememberMe: true, Expires: time.Now().Add(time.Hour),
+		SignIn: u.SignIn{Method: "password", IP: "81.2.69.142", Country: "GB", City: "London", UserAgent: "curl/8.0"}}
+	if err := s.CreatePendingSignIn(ctx, in, sessionHash, tokenHash); err != nil {
+		t.Fatal(err)
+	}
+
+	// Unconfirmed sign ins stay pending
+	got, err := s.ClaimSignIn(ctx, sessionHash)
+	if err != nil || got.Confirmed || got.UserID != id || !got.RememberMe || got.UserAgent != "curl/8.0" {
+		t.Fatalf("ClaimSignIn() before confirmation = %+v, %v", got, err)
+	}
+	if _, err := s.ConfirmSignIn(ctx, sessionHash); !errors.Is(err, ErrNotFound) {
+		t.Errorf("ConfirmSignIn() with the session token = %v", err)
+	}
+	if got, err := s.ConfirmSignIn(ctx, password.HashToken(token)); err != nil || got != id {
+		t.Fatalf("ConfirmSignIn() = %d, %v", got, err)
+	}
+
+	// A confirmed sign in is claimed once
+	if got, err := s.ClaimSignIn(ctx, sessionHash); err != nil || !got.Confirmed || got.Country != "GB" {
+		t.Fatalf("ClaimSignIn() after confirmation = %+v, %v", got, err)
+	}
+	if _, err := s.ClaimSignIn(ctx, sessionHash); !errors.Is(err, ErrNotFound) {
+		t.Errorf("ClaimSignIn() of a claimed sign in = %v", err)
+	}
+
+	// Expired sign ins are neither confirmed nor claimed
+	in.Expires = time.Now().Add(-time.Minute)
+	if err := s.CreatePendingSignIn(ctx, in, "expired-session", "expired-token"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ConfirmSignIn(ctx, "expired-token"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("ConfirmSignIn() of an expired sign in = %v", err)
+	}
+	if _, err := s.ClaimSignIn(ctx, "expired-session"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("ClaimSignIn() of an expired sign in = %v", err)
+	}
+	s.db.Exec(`DELETE FROM public.pending_sign_ins WHERE user_id = $1`, id)
+}
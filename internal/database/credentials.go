@@ -0,0 +1,210 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sabbatD/srest-api/internal/lib/clock"
+	u "github.com/sabbatD/srest-api/internal/lib/userConfig"
+	"github.com/sabbatD/srest-api/internal/password"
+)
+
+// ResetCredentials makes the user's password unusable, revokes their refresh token and remembered devices and stores the hash
+// of a password reset token valid until expires, replacing an earlier one. The reset is recorded by actor
+// in the audit log. Returns the user, e.g. to email them the reset link.
+func (s *Storage) ResetCredentials(ctx context.Context, id, actor int, tokenHash string, expires time.Time) (u.TableUser, error) {
+	const op = "database.postgres.ResetCredentials"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return u.TableUser{}, fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	// An empty hash matches no password.
+	var user u.TableUser
+	err = tx.QueryRowContext(ctx, `
+		UPDATE public.users SET password = ''
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING id, public_id, username, COALESCE(email, ''), locale
+	`, id).Scan(&user.ID, &user.PublicID, &user.Username, &user.Email, &user.Locale)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return u.TableUser{}, fmt.Errorf("%s: no such user: %w", op, ErrNotFound)
+		}
+		return u.TableUser{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	if _, err := revokeSessions(ctx, tx, id); err != nil {
+		return u.TableUser{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO public.password_resets (user_id, token_hash, expires) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE SET token_hash = EXCLUDED.token_hash, expires = EXCLUDED.expires, created = NOW()
+	`, id, tokenHash, expires)
+	if err != nil {
+		return u.TableUser{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := audit(ctx, tx, actor, AuditResetCreds, id, map[string]any{"expires": expires}); err != nil {
+		return u.TableUser{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return u.TableUser{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return user, nil
+}
+
+// RequestPasswordReset stores the hash of a password reset token valid until expires for the user with the login or
+// email login, replacing an earlier one. Unlike ResetCredentials the password keeps working until the token is used.
+// Returns the user to email the reset link to, ErrNotFound when no active user with an email has that login or email.
+func (s *Storage) RequestPasswordReset(ctx context.Context, login, tokenHash string, expires time.Time) (u.TableUser, error) {
+	const op = "database.postgres.RequestPasswordReset"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return u.TableUser{}, fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	// A login wins over another user's email that happens to be the same
+	var user u.TableUser
+	err = tx.QueryRowContext(ctx, `
+		SELECT id, public_id, username, email, locale FROM public.users
+		WHERE (login = $1 OR email = $1) AND deleted_at IS NULL AND NOT is_blocked AND NOT is_guest
+			AND COALESCE(email, '') <> ''
+		ORDER BY login = $1 DESC
+		LIMIT 1
+	`, login).Scan(&user.ID, &user.PublicID, &user.Username, &user.Email, &user.Locale)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return u.TableUser{}, fmt.Errorf("%s: no such user: %w", op, ErrNotFound)
+		}
+		return u.TableUser{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO public.password_resets (user_id, token_hash, expires) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE SET token_hash = EXCLUDED.token_hash, expires = EXCLUDED.expires, created = NOW()
+	`, user.ID, tokenHash, expires)
+	if err != nil {
+		return u.TableUser{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return u.TableUser{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return user, nil
+}
+
+// ResetPassword consumes the unexpired reset token with the given hash and sets the password of its user,
+// the user's refresh token and remembered devices are revoked. Returns ErrNotFound for an unknown, used or expired token.
+func (s *Storage) ResetPassword(ctx context.Context, tokenHash, pwd string) (int, error) {
+	const op = "database.postgres.ResetPassword"
+
+	hash, err := password.HashPassword(pwd)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	var id int
+	err = tx.QueryRowContext(ctx, `
+		DELETE FROM public.password_resets WHERE token_hash = $1 AND expires > $2 RETURNING user_id
+	`, tokenHash, clock.Now()).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("%s: reset token %w", op, ErrNotFound)
+		}
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE public.users SET password = $1, must_change_password = FALSE, password_changed = $2, password_warned = NULL
+		WHERE id = $3 AND deleted_at IS NULL
+	`, hash, clock.Now(), id)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+	if n == 0 {
+		return 0, fmt.Errorf("%s: no such user: %w", op, ErrNotFound)
+	}
+
+	if _, err := revokeSessions(ctx, tx, id); err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return id, nil
+}
+
+// UpdatePassword sets the password of the user to pwd if current matches the stored one, and revokes the user's
+// refresh tokens and remembered devices. Returns the number revoked, ErrWrongPassword when current does not match,
+// also for users without a local password.
+func (s *Storage) UpdatePassword(ctx context.Context, id int, current, pwd string) (int64, error) {
+	const op = "database.postgres.UpdatePassword"
+
+	hash, err := password.HashPassword(pwd)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	// Locked, so two concurrent changes cannot both check the same current password
+	var stored string
+	err = tx.QueryRowContext(ctx, `
+		SELECT password FROM public.users WHERE id = $1 AND deleted_at IS NULL FOR UPDATE
+	`, id).Scan(&stored)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("%s: no such user: %w", op, ErrNotFound)
+		}
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+	if err := password.CheckPassword([]byte(stored), current); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, ErrWrongPassword)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE public.users SET password = $1, must_change_password = FALSE, password_changed = $2, password_warned = NULL
+		WHERE id = $3
+	`, hash, clock.Now(), id)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	revoked, err := revokeSessions(ctx, tx, id)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return revoked, nil
+}
@@ -0,0 +1,134 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sabbatD/srest-api/internal/lib/userConfig"
+	"github.com/sabbatD/srest-api/internal/password"
+)
+
+func TestResetCredentials(t *testing.T) {
+	s := testStorage(t)
+	ctx := context.Background()
+
+	admin := testUser(t, s, "credsadmin")
+	id := testUser(t, s, "credsuser")
+	if err := s.SaveRefreshToken(ctx, "credsrefresh", id, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	token, hash, err := password.NewToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := s.ResetCredentials(ctx, id, admin, hash, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Email != "credsuser@example.com" {
+		t.Errorf("user = %+v", user)
+	}
+
+	if _, err := s.Auth(ctx, userConfig.AuthData{Login: "credsuser", Password: "password"}); err == nil {
+		t.Error("old password still works")
+	}
+	if _, refreshed, _ := s.RefreshToken(ctx, "credsrefresh"); refreshed != 0 {
+		t.Error("refresh token not revoked")
+	}
+
+	if _, err := s.ResetPassword(ctx, password.HashToken("wrong"), "newpassword"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("ResetPassword() with a wrong token = %v", err)
+	}
+	if got, err := s.ResetPassword(ctx, password.HashToken(token), "newpassword"); err != nil || got != id {
+		t.Fatalf("ResetPassword() = %d, %v", got, err)
+	}
+	if _, err := s.Auth(ctx, userConfig.AuthData{Login: "credsuser", Password: "newpassword"}); err != nil {
+		t.Errorf("new password: %v", err)
+	}
+	if _, err := s.ResetPassword(ctx, password.HashToken(token), "another"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("ResetPassword() with a used token = %v", err)
+	}
+
+	if _, err := s.ResetCredentials(ctx, -1, admin, hash, time.Now()); !errors.Is(err, ErrNotFound) {
+		t.Errorf("ResetCredentials() of a missing user = %v", err)
+	}
+}
+
+func TestRequestPasswordReset(t *testing.T) {
+	s := testStorage(t)
+	ctx := context.Background()
+
+	id := testUser(t, s, "forgotuser")
+
+	if _, err := s.RequestPasswordReset(ctx, "nosuchforgotuser", "hash", time.Now().Add(time.Hour)); !errors.Is(err, ErrNotFound) {
+		t.Errorf("RequestPasswordReset() of a missing user = %v", err)
+	}
+
+	// By login, then by email: the second token replaces the first
+	first, firstHash, err := password.NewToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user, err := s.RequestPasswordReset(ctx, "forgotuser", firstHash, time.Now().Add(time.Hour)); err != nil || user.ID != id {
+		t.Fatalf("RequestPasswordReset() by login = %+v, %v", user, err)
+	}
+	token, hash, err := password.NewToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user, err := s.RequestPasswordReset(ctx, "forgotuser@example.com", hash, time.Now().Add(time.Hour)); err != nil || user.ID != id {
+		t.Fatalf("RequestPasswordReset() by email = %+v, %v", user, err)
+	}
+
+	// The password keeps working until the token is used
+	if _, err := s.Auth(ctx, userConfig.AuthData{Login: "forgotuser", Password: "password"}); err != nil {
+		t.Errorf("password stopped working: %v", err)
+	}
+	if _, err := s.ResetPassword(ctx, password.HashToken(first), "newpassword"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("ResetPassword() with a replaced token = %v", err)
+	}
+	if got, err := s.ResetPassword(ctx, password.HashToken(token), "newpassword"); err != nil || got != id {
+		t.Errorf("ResetPassword() = %d, %v", got, err)
+	}
+}
+
+func TestUpdatePassword(t *testing.T) {
+	s := testStorage(t)
+	ctx := context.Background()
+
+	id := testUser(t, s, "changepwduser")
+	if err := s.SaveRefreshToken(ctx, "changepwdrefresh", id, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.RememberDevice(ctx, id, "laptop", "changepwdtoken", "changepwddevice", time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.UpdatePassword(ctx, id, "wrong", "newpassword"); !errors.Is(err, ErrWrongPassword) {
+		t.Errorf("UpdatePassword() with a wrong password = %v", err)
+	}
+	if _, refreshed, _ := s.RefreshToken(ctx, "changepwdrefresh"); refreshed != id {
+		t.Error("refresh token revoked by a failed change")
+	}
+
+	revoked, err := s.UpdatePassword(ctx, id, "password", "newpassword")
+	if err != nil || revoked != 2 {
+		t.Fatalf("UpdatePassword() = %d, %v", revoked, err)
+	}
+	if _, err := s.Auth(ctx, userConfig.AuthData{Login: "changepwduser", Password: "password"}); err == nil {
+		t.Error("old password still works")
+	}
+	if _, err := s.Auth(ctx, userConfig.AuthData{Login: "changepwduser", Password: "newpassword"}); err != nil {
+		t.Errorf("new password: %v", err)
+	}
+	if _, refreshed, _ := s.RefreshToken(ctx, "changepwdrefresh"); refreshed != 0 {
+		t.Error("refresh token not revoked")
+	}
+
+	if _, err := s.UpdatePassword(ctx, -1, "password", "newpassword"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("UpdatePassword() of a missing user = %v", err)
+	}
+}
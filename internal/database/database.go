@@ -2,37 +2,60 @@ package database
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"log/slog"
+	"time"
 
 	"github.com/pressly/goose/v3"
 )
 
+var (
+	// ErrNotFound is returned when the requested row does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrAlreadyExists is returned when a unique value is already taken
+	ErrAlreadyExists = errors.New("already exists")
+	// ErrCooldown is returned when an action was repeated before its cooldown period ended
+	ErrCooldown = errors.New("cooldown in effect")
+	// ErrConflict is returned when the row is not in a state allowing the change
+	ErrConflict = errors.New("conflicting state")
+	// ErrNoHistory is returned when a point in time predates the recorded history
+	ErrNoHistory = errors.New("no history recorded")
+	// ErrLimitReached is returned when a row would exceed a per user limit
+	ErrLimitReached = errors.New("limit reached")
+	// ErrWrongPassword is returned when a given password does not match the stored one
+	ErrWrongPassword = errors.New("wrong password")
+)
+
+// MigrationsDir is where the migrations are read from, relative to the working directory of the server
+const MigrationsDir = "./internal/database/migrations"
+
 type Storage struct {
-	db *sql.DB
+	db *db
 }
 
-func SetupDataBase(dbStr, env string) (*Storage, error) {
+// SetupDataBase connects to postgres, statements slower than slowQuery are logged to log.
+func SetupDataBase(dbStr, env string, log *slog.Logger, slowQuery time.Duration) (*Storage, error) {
 	const op = "database.postgres.New"
 
-	db, err := sql.Open("postgres", dbStr)
+	conn, err := sql.Open("postgres", dbStr)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %v", op, err)
 	}
 
-	if err := db.Ping(); err != nil {
+	if err := conn.Ping(); err != nil {
 		return nil, fmt.Errorf("%s: %v", op, err)
 	}
 
 	if env == "local" {
-		migrationsDir := "./internal/database/migrations"
-		fmt.Println("Migrations directory:", migrationsDir) // Проверить путь
+		fmt.Println("Migrations directory:", MigrationsDir) // Проверить путь
 
-		if err := runMigrations(db, migrationsDir); err != nil {
+		if err := runMigrations(conn, MigrationsDir); err != nil {
 			return nil, fmt.Errorf("%s: %v", op, err)
 		}
 	}
 
-	return &Storage{db: db}, nil
+	return &Storage{db: &db{DB: conn, log: log, slow: slowQuery}}, nil
 }
 
 func runMigrations(db *sql.DB, migrationsDir string) error {
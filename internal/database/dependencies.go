@@ -0,0 +1,99 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// AddBlocker records that the user's todo id is blocked by the todo blocker.
+// ErrConflict is returned when blocker already depends on id, directly or through other todos,
+// as the dependency would create a cycle. Adding a recorded dependency again changes nothing.
+func (s *Storage) AddBlocker(ctx context.Context, id, blocker, userID int) error {
+	const op = "database.postgres.AddBlocker"
+
+	if id == blocker {
+		return fmt.Errorf("%s: a task cannot block itself: %w", op, ErrConflict)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	// Locking the user serializes the cycle check with concurrent changes of the user's graph.
+	var locked int
+	if err := tx.QueryRowContext(ctx, `SELECT id FROM public.users WHERE id = $1 FOR UPDATE`, userID).Scan(&locked); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s: no user with id %v: %w", op, userID, ErrNotFound)
+		}
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	var cycle bool
+	err = tx.QueryRowContext(ctx, `
+		WITH RECURSIVE chain (id) AS (
+			SELECT blocked_by FROM public.todo_dependencies WHERE todo_id = $1
+			UNION
+			SELECT d.blocked_by FROM public.todo_dependencies d JOIN chain c ON d.todo_id = c.id
+		)
+		SELECT EXISTS (SELECT 1 FROM chain WHERE id = $2)
+	`, blocker, id).Scan(&cycle)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	if cycle {
+		return fmt.Errorf("%s: task %v already depends on %v: %w", op, blocker, id, ErrConflict)
+	}
+
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO public.todo_dependencies (todo_id, blocked_by)
+		SELECT $1, $2 WHERE (SELECT COUNT(*) FROM public.todos WHERE id IN ($1, $2) AND user_id = $3) = 2
+		ON CONFLICT DO NOTHING
+	`, id, blocker, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	} else if n == 0 {
+		var exists bool
+		err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM public.todo_dependencies WHERE todo_id = $1 AND blocked_by = $2)`, id, blocker).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("%s: %v", op, err)
+		}
+		if !exists {
+			return fmt.Errorf("%s: no tasks %v and %v: %w", op, id, blocker, ErrNotFound)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// RemoveBlocker deletes the dependency of the user's todo id on blocker
+func (s *Storage) RemoveBlocker(ctx context.Context, id, blocker, userID int) error {
+	const op = "database.postgres.RemoveBlocker"
+
+	res, err := s.db.ExecContext(ctx, `
+		DELETE FROM public.todo_dependencies d USING public.todos t
+		WHERE d.todo_id = $1 AND d.blocked_by = $2 AND t.id = d.todo_id AND t.user_id = $3
+	`, id, blocker, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%s: task %v is not blocked by %v: %w", op, id, blocker, ErrNotFound)
+	}
+
+	return nil
+}
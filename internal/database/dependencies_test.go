@@ -0,0 +1,75 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	todoconfig "github.com/sabbatD/srest-api/internal/lib/todoConfig"
+)
+
+func TestBlockers(t *testing.T) {
+	s := testStorage(t)
+	ctx := context.Background()
+
+	user := testUser(t, s, "blockersuser")
+	stranger := testUser(t, s, "blockersstranger")
+
+	var ids []int
+	for _, title := range []string{"design", "build", "ship"} {
+		id, err := s.Create(ctx, todoconfig.TodoRequest{Title: title}, user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, int(id))
+	}
+	design, build, ship := ids[0], ids[1], ids[2]
+
+	if err := s.AddBlocker(ctx, build, design, user); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddBlocker(ctx, ship, build, user); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddBlocker(ctx, ship, build, user); err != nil {
+		t.Errorf("adding a dependency again: %v", err)
+	}
+	if err := s.AddBlocker(ctx, design, ship, user); !errors.Is(err, ErrConflict) {
+		t.Errorf("indirect cycle: err = %v, want ErrConflict", err)
+	}
+	if err := s.AddBlocker(ctx, ship, design, stranger); !errors.Is(err, ErrNotFound) {
+		t.Errorf("tasks of another user: err = %v, want ErrNotFound", err)
+	}
+
+	todo, err := s.GetTodo(ctx, build, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !todo.Blocked || len(todo.BlockedBy) != 1 || len(todo.Blocks) != 1 {
+		t.Errorf("build = %+v, want blocked by design and blocking ship", todo)
+	}
+
+	done := true
+	if _, err := s.Update(ctx, design, user, todoconfig.TodoRequest{IsDone: &done}); err != nil {
+		t.Fatal(err)
+	}
+	blocked := true
+	var matched []string
+	_, err = s.EachTodo(ctx, todoconfig.TodoQuery{Blocked: &blocked}, user, func(todo todoconfig.Todo) error {
+		matched = append(matched, todo.Title)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matched) != 1 || matched[0] != "ship" {
+		t.Errorf("blocked tasks = %v, want ship only", matched)
+	}
+
+	if err := s.RemoveBlocker(ctx, ship, build, user); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RemoveBlocker(ctx, ship, build, user); !errors.Is(err, ErrNotFound) {
+		t.Errorf("removing a missing dependency: err = %v, want ErrNotFound", err)
+	}
+}
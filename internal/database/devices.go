@@ -0,0 +1,131 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	u "github.com/sabbatD/srest-api/internal/lib/userConfig"
+)
+
+// RememberDevice stores a remembered device of the user by the hashes of its refresh token and device cookie,
+// the refresh token is valid until expires. A device the user remembered before with the same cookie is replaced.
+// Returns the public id of the device.
+func (s *Storage) RememberDevice(ctx context.Context, id int, name, tokenHash, deviceHash string, expires time.Time) (string, error) {
+	const op = "database.postgres.RememberDevice"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM public.devices WHERE user_id = $1 AND device_hash = $2`, id, deviceHash); err != nil {
+		return "", fmt.Errorf("%s: %v", op, err)
+	}
+
+	var publicID string
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO public.devices (user_id, name, token_hash, device_hash, expires) VALUES ($1, $2, $3, $4, $5)
+		RETURNING public_id
+	`, id, name, tokenHash, deviceHash, expires).Scan(&publicID)
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("%s: %v", op, err)
+	}
+
+	return publicID, nil
+}
+
+// RefreshDevice replaces the refresh token of the remembered device holding both hashes and extends it until expires.
+// Returns the user and the public id of the device, ErrNotFound for an unknown or expired token or a wrong device.
+func (s *Storage) RefreshDevice(ctx context.Context, tokenHash, deviceHash, newTokenHash string, expires time.Time) (int, string, error) {
+	const op = "database.postgres.RefreshDevice"
+
+	var id int
+	var publicID string
+	err := s.db.QueryRowContext(ctx, `
+		UPDATE public.devices SET token_hash = $3, expires = $4, last_used = NOW()
+		WHERE token_hash = $1 AND device_hash = $2 AND expires > NOW()
+		RETURNING user_id, public_id
+	`, tokenHash, deviceHash, newTokenHash, expires).Scan(&id, &publicID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, "", fmt.Errorf("%s: no such device: %w", op, ErrNotFound)
+		}
+		return 0, "", fmt.Errorf("%s: %v", op, err)
+	}
+
+	return id, publicID, nil
+}
+
+// Devices returns the user's remembered devices that did not expire, the most recently used first
+func (s *Storage) Devices(ctx context.Context, id int) ([]u.Device, error) {
+	const op = "database.postgres.Devices"
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, public_id, name, last_used, expires, created FROM public.devices
+		WHERE user_id = $1 AND expires > NOW()
+		ORDER BY last_used DESC
+	`, id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+	defer rows.Close()
+
+	devices := []u.Device{}
+	for rows.Next() {
+		var d u.Device
+		if err := rows.Scan(&d.ID, &d.PublicID, &d.Name, &d.LastUsed, &d.Expires, &d.Created); err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+		devices = append(devices, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return devices, nil
+}
+
+// ForgetDevice revokes the user's remembered device by its public id
+func (s *Storage) ForgetDevice(ctx context.Context, publicID string, id int) error {
+	const op = "database.postgres.ForgetDevice"
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM public.devices WHERE public_id = $1 AND user_id = $2`, publicID, id)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	} else if n == 0 {
+		return fmt.Errorf("%s: no such device: %w", op, ErrNotFound)
+	}
+
+	return nil
+}
+
+// revokeSessions deletes the user's refresh token and remembered devices within tx or db, returning how many
+func revokeSessions(ctx context.Context, exec execer, id int) (int64, error) {
+	var n int64
+	for _, query := range []string{
+		`DELETE FROM public.tokens WHERE user_id = $1`,
+		`DELETE FROM public.devices WHERE user_id = $1`,
+	} {
+		res, err := exec.ExecContext(ctx, query, id)
+		if err != nil {
+			return n, err
+		}
+		deleted, err := res.RowsAffected()
+		if err != nil {
+			return n, err
+		}
+		n += deleted
+	}
+	return n, nil
+}
@@ -0,0 +1,61 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDevices(t *testing.T) {
+	s := testStorage(t)
+	ctx := context.Background()
+
+	id := testUser(t, s, "deviceuser")
+	other := testUser(t, s, "deviceother")
+	expires := time.Now().Add(time.Hour)
+
+	first, err := s.RememberDevice(ctx, id, "Firefox", "devicetoken1", "devicecookie1", expires)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.RememberDevice(ctx, id, "Safari", "devicetoken2", "devicecookie2", expires); err != nil {
+		t.Fatal(err)
+	}
+	// Signing in again on the same device replaces it.
+	if _, err := s.RememberDevice(ctx, id, "Firefox", "devicetoken3", "devicecookie1", expires); err != nil {
+		t.Fatal(err)
+	}
+	devices, err := s.Devices(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(devices) != 2 {
+		t.Fatalf("devices = %+v", devices)
+	}
+
+	// The token only works with its device cookie and is replaced on use.
+	if _, _, err := s.RefreshDevice(ctx, "devicetoken3", "devicecookie2", "devicetoken4", expires); !errors.Is(err, ErrNotFound) {
+		t.Errorf("refresh with the wrong cookie: %v", err)
+	}
+	got, device, err := s.RefreshDevice(ctx, "devicetoken3", "devicecookie1", "devicetoken4", expires)
+	if err != nil || got != id || device == first {
+		t.Errorf("RefreshDevice() = %d, %s, %v", got, device, err)
+	}
+	if _, _, err := s.RefreshDevice(ctx, "devicetoken3", "devicecookie1", "devicetoken5", expires); !errors.Is(err, ErrNotFound) {
+		t.Errorf("refresh with a used token: %v", err)
+	}
+
+	if err := s.ForgetDevice(ctx, device, other); !errors.Is(err, ErrNotFound) {
+		t.Errorf("another user's device revoked: %v", err)
+	}
+	if err := s.ForgetDevice(ctx, device, id); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.RefreshDevice(ctx, "devicetoken4", "devicecookie1", "devicetoken5", expires); !errors.Is(err, ErrNotFound) {
+		t.Errorf("refresh of a revoked device: %v", err)
+	}
+	if devices, err := s.Devices(ctx, id); err != nil || len(devices) != 1 {
+		t.Errorf("devices after revoke = %+v, %v", devices, err)
+	}
+}
@@ -0,0 +1,70 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/sabbatD/srest-api/internal/lib/clock"
+)
+
+// DuplicateTodo copies the user's todo into a new todo and returns its id. The copy gets the title, status,
+// custom field values, due date and the todos blocking the original, it is not pinned and nothing depends on it.
+// The user's todo limit applies to copies as to created todos, ErrLimitReached is returned when it is reached.
+func (s *Storage) DuplicateTodo(ctx context.Context, id, userID int) (int64, error) {
+	const op = "database.postgres.DuplicateTodo"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	// Locking the user serializes the limit check with concurrent copies.
+	var count int
+	var limit sql.NullInt64
+	err = tx.QueryRowContext(ctx, `
+		SELECT (SELECT COUNT(*) FROM public.todos WHERE user_id = u.id),
+			COALESCE((SELECT max_todos FROM public.user_limits WHERE user_id = u.id), u.todo_limit)
+		FROM public.users u JOIN public.todos t ON t.user_id = u.id
+		WHERE u.id = $1 AND t.id = $2
+		FOR UPDATE OF u
+	`, userID, id).Scan(&count, &limit)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("%s: no such task: %w", op, ErrNotFound)
+		}
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+	if limit.Valid && int64(count) >= limit.Int64 {
+		return 0, fmt.Errorf("%s: todo %w", op, ErrLimitReached)
+	}
+
+	var copyID int64
+	err = tx.QueryRowContext(ctx, `
+		WITH v AS (SELECT nextval('public.todos_version_seq') AS version)
+		INSERT INTO public.todos (public_id, title, is_done, status, user_id, version, created_version, custom, due, created)
+		SELECT gen_random_uuid(), t.title, t.is_done, t.status, t.user_id, v.version, v.version, t.custom, t.due, $3
+		FROM public.todos t, v
+		WHERE t.id = $1 AND t.user_id = $2
+		RETURNING id
+	`, id, userID, clock.Now()).Scan(&copyID)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO public.todo_dependencies (todo_id, blocked_by)
+		SELECT $1, blocked_by FROM public.todo_dependencies WHERE todo_id = $2
+	`, copyID, id)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return copyID, nil
+}
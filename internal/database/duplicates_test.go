@@ -0,0 +1,63 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	todoconfig "github.com/sabbatD/srest-api/internal/lib/todoConfig"
+)
+
+func TestDuplicateTodo(t *testing.T) {
+	s := testStorage(t)
+	ctx := context.Background()
+
+	user := testUser(t, s, "duplicateuser")
+	stranger := testUser(t, s, "duplicatestranger")
+
+	due := "2024-11-01"
+	design, err := s.Create(ctx, todoconfig.TodoRequest{Title: "design"}, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	build, err := s.Create(ctx, todoconfig.TodoRequest{Title: "build", Due: &due, Custom: map[string]any{"priority": "high"}}, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddBlocker(ctx, int(build), int(design), user); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.PinTodo(ctx, int(build), user, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.DuplicateTodo(ctx, int(build), stranger); !errors.Is(err, ErrNotFound) {
+		t.Errorf("task of another user: err = %v, want ErrNotFound", err)
+	}
+
+	id, err := s.DuplicateTodo(ctx, int(build), user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	original, err := s.GetTodo(ctx, int(build), user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	copied, err := s.GetTodo(ctx, int(id), user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if copied.PublicID == original.PublicID || copied.Title != "build" || copied.Due != due || copied.Custom["priority"] != "high" {
+		t.Errorf("copy = %+v", copied)
+	}
+	if copied.Pinned || len(copied.BlockedBy) != 1 || copied.BlockedBy[0] != original.BlockedBy[0] {
+		t.Errorf("copy pin and blockers = %v %v, want unpinned and blocked by design", copied.Pinned, copied.BlockedBy)
+	}
+
+	if _, err := s.db.ExecContext(ctx, `UPDATE public.users SET todo_limit = 3 WHERE id = $1`, user); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.DuplicateTodo(ctx, int(build), user); !errors.Is(err, ErrLimitReached) {
+		t.Errorf("over the limit: err = %v, want ErrLimitReached", err)
+	}
+}
@@ -0,0 +1,133 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sabbatD/srest-api/internal/lib/expiry"
+)
+
+const settingPasswordExpiry = "password_expiry"
+
+// PasswordExpiry returns the policy set by an admin, false when there is none
+func (s *Storage) PasswordExpiry(ctx context.Context) (expiry.Policy, bool, error) {
+	const op = "database.postgres.PasswordExpiry"
+
+	var p expiry.Policy
+	var data []byte
+	err := s.db.QueryRowContext(ctx, `SELECT value FROM public.settings WHERE key = $1`, settingPasswordExpiry).Scan(&data)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return p, false, nil
+		}
+		return p, false, fmt.Errorf("%s: %v", op, err)
+	}
+	if err := json.Unmarshal(data, &p); err != nil {
+		return p, false, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return p, true, nil
+}
+
+// SetPasswordExpiry stores the policy and records the change by actor in the audit log
+func (s *Storage) SetPasswordExpiry(ctx context.Context, actor int, p expiry.Policy) error {
+	const op = "database.postgres.SetPasswordExpiry"
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO public.settings (key, value, updated_by) VALUES ($1, $2, $3)
+		ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, updated_by = EXCLUDED.updated_by, updated = NOW()
+	`, settingPasswordExpiry, data, actor)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := audit(ctx, tx, actor, AuditSetPwdExpiry, nil, p); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// expirable selects the users the policy applies to: local accounts with a password.
+// Guests, directory and SSO users sign in without one.
+const expirable = `deleted_at IS NULL AND NOT is_guest AND auth_source = 'local' AND password <> ''`
+
+// ExpirePasswords makes users with a password changed before the given time change it on their next sign in
+func (s *Storage) ExpirePasswords(ctx context.Context, before time.Time) (int64, error) {
+	const op = "database.postgres.ExpirePasswords"
+
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE public.users SET must_change_password = TRUE
+		WHERE `+expirable+` AND NOT must_change_password AND password_changed < $1
+	`, before)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return n, nil
+}
+
+// PasswordWarnings returns the users not warned yet whose password was changed before the given time,
+// Expires is left for the caller to fill in
+func (s *Storage) PasswordWarnings(ctx context.Context, before time.Time) ([]expiry.Warning, error) {
+	const op = "database.postgres.PasswordWarnings"
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, username, COALESCE(email, ''), locale, password_changed FROM public.users
+		WHERE `+expirable+` AND NOT must_change_password AND password_warned IS NULL AND password_changed < $1
+		ORDER BY id
+	`, before)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+	defer rows.Close()
+
+	var warnings []expiry.Warning
+	for rows.Next() {
+		var w expiry.Warning
+		if err := rows.Scan(&w.UserID, &w.Username, &w.Email, &w.Locale, &w.Changed); err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+		warnings = append(warnings, w)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return warnings, nil
+}
+
+// MarkPasswordWarned records the user was warned, they are not warned again until the password changes
+func (s *Storage) MarkPasswordWarned(ctx context.Context, id int) error {
+	const op = "database.postgres.MarkPasswordWarned"
+
+	if _, err := s.db.ExecContext(ctx, `UPDATE public.users SET password_warned = NOW() WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sabbatD/srest-api/internal/lib/expiry"
+	u "github.com/sabbatD/srest-api/internal/lib/userConfig"
+)
+
+func TestPasswordExpiry(t *testing.T) {
+	s := testStorage(t)
+	ctx := context.Background()
+
+	admin := testUser(t, s, "expiryadmin")
+	old := testUser(t, s, "expiryold")
+	t.Cleanup(func() { s.db.Exec(`DELETE FROM public.settings WHERE key = $1`, settingPasswordExpiry) })
+
+	want := expiry.Policy{Days: 90, WarnDays: 14}
+	if err := s.SetPasswordExpiry(ctx, admin, want); err != nil {
+		t.Fatal(err)
+	}
+	got, ok, err := s.PasswordExpiry(ctx)
+	if err != nil || !ok || got != want {
+		t.Errorf("PasswordExpiry() = %+v, %v, %v", got, ok, err)
+	}
+
+	s.db.Exec(`UPDATE public.users SET password_changed = NOW() - INTERVAL '80 days' WHERE id = $1`, old)
+
+	warnings, err := s.PasswordWarnings(ctx, time.Now().AddDate(0, 0, -76))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found bool
+	for _, w := range warnings {
+		if w.UserID == admin {
+			t.Error("recently changed password warned")
+		}
+		found = found || w.UserID == old && w.Email == "expiryold@example.com"
+	}
+	if !found {
+		t.Errorf("warnings = %+v", warnings)
+	}
+	if err := s.MarkPasswordWarned(ctx, old); err != nil {
+		t.Fatal(err)
+	}
+	warnings, err = s.PasswordWarnings(ctx, time.Now().AddDate(0, 0, -76))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, w := range warnings {
+		if w.UserID == old {
+			t.Error("user warned twice")
+		}
+	}
+
+	s.db.Exec(`UPDATE public.users SET password_changed = NOW() - INTERVAL '100 days' WHERE id = $1`, old)
+	if _, err := s.ExpirePasswords(ctx, time.Now().AddDate(0, 0, -90)); err != nil {
+		t.Fatal(err)
+	}
+	user, err := s.Get(ctx, old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !user.MustChangePassword || user.PasswordChanged == nil {
+		t.Errorf("expired user = %+v", user)
+	}
+	if user, _ := s.Get(ctx, admin); user.MustChangePassword {
+		t.Error("recently changed password expired")
+	}
+
+	// Changing the password starts a new period.
+	if _, err := s.ChangePassword(ctx, u.Pwd{Password: "newpassword"}, old); err != nil {
+		t.Fatal(err)
+	}
+	user, err = s.Get(ctx, old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.MustChangePassword || time.Since(*user.PasswordChanged) > time.Minute {
+		t.Errorf("changed password = %+v", user)
+	}
+}
@@ -0,0 +1,136 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/sabbatD/srest-api/internal/lib/fields"
+)
+
+const settingUserFields = "user_fields"
+
+// UserFields returns the custom profile fields defined by admins, none until they define some
+func (s *Storage) UserFields(ctx context.Context) (fields.Schema, error) {
+	const op = "database.postgres.UserFields"
+
+	schema := fields.Schema{Fields: []fields.Field{}}
+	var data []byte
+	err := s.db.QueryRowContext(ctx, `SELECT value FROM public.settings WHERE key = $1`, settingUserFields).Scan(&data)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return schema, nil
+		}
+		return schema, fmt.Errorf("%s: %v", op, err)
+	}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return schema, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return schema, nil
+}
+
+// SetUserFields replaces the custom profile fields and records the change by actor in the audit log.
+// Values of removed fields stay stored but are no longer returned, defining the field again brings them back.
+func (s *Storage) SetUserFields(ctx context.Context, actor int, schema fields.Schema) error {
+	const op = "database.postgres.SetUserFields"
+
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO public.settings (key, value, updated_by) VALUES ($1, $2, $3)
+		ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, updated_by = EXCLUDED.updated_by, updated = NOW()
+	`, settingUserFields, data, actor)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := audit(ctx, tx, actor, AuditSetUserFields, nil, schema); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// TodoFields returns the custom todo fields the user defined
+func (s *Storage) TodoFields(ctx context.Context, userID int) (fields.Schema, error) {
+	const op = "database.postgres.TodoFields"
+
+	schema := fields.Schema{Fields: []fields.Field{}}
+	var data []byte
+	err := s.db.QueryRowContext(ctx, `SELECT todo_fields FROM public.users WHERE id = $1`, userID).Scan(&data)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return schema, fmt.Errorf("%s: no user with id %v: %w", op, userID, ErrNotFound)
+		}
+		return schema, fmt.Errorf("%s: %v", op, err)
+	}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return schema, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return schema, nil
+}
+
+// SetTodoFields replaces the user's custom todo fields. Values of removed fields are deleted from the todos,
+// which count as changed for sync clients.
+func (s *Storage) SetTodoFields(ctx context.Context, userID int, schema fields.Schema) error {
+	const op = "database.postgres.SetTodoFields"
+
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	keys := make([]string, 0, len(schema.Fields))
+	for _, f := range schema.Fields {
+		keys = append(keys, f.Key)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `UPDATE public.users SET todo_fields = $1 WHERE id = $2`, data, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	} else if n == 0 {
+		return fmt.Errorf("%s: no user with id %v: %w", op, userID, ErrNotFound)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE public.todos
+		SET custom = (SELECT COALESCE(jsonb_object_agg(key, value), '{}') FROM jsonb_each(custom) WHERE key = ANY($2)),
+			version = nextval('public.todos_version_seq')
+		WHERE user_id = $1 AND EXISTS (SELECT 1 FROM jsonb_object_keys(custom) AS k WHERE k <> ALL($2))
+	`, userID, pq.Array(keys))
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
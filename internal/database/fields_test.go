@@ -0,0 +1,103 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sabbatD/srest-api/internal/lib/fields"
+	todoconfig "github.com/sabbatD/srest-api/internal/lib/todoConfig"
+	"github.com/sabbatD/srest-api/internal/lib/userConfig"
+)
+
+func TestUserFields(t *testing.T) {
+	s := testStorage(t)
+	ctx := context.Background()
+
+	admin := testUser(t, s, "fieldsadmin")
+	id := testUser(t, s, "fieldsuser")
+	t.Cleanup(func() { s.db.Exec(`DELETE FROM public.settings WHERE key = $1`, settingUserFields) })
+
+	schema := fields.Schema{Fields: []fields.Field{{Key: "department", Type: fields.TypeText}}}
+	if err := s.SetUserFields(ctx, admin, schema); err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.UserFields(ctx)
+	if err != nil || len(got.Fields) != 1 || got.Fields[0].Key != "department" {
+		t.Errorf("UserFields() = %+v, %v", got, err)
+	}
+
+	if _, err := s.UpdateUser(ctx, userConfig.PutUser{Custom: map[string]any{"department": "sales", "floor": 3.0}}, id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.UpdateUser(ctx, userConfig.PutUser{Custom: map[string]any{"floor": nil}}, id); err != nil {
+		t.Fatal(err)
+	}
+	user, err := s.Get(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(user.Custom) != 1 || user.Custom["department"] != "sales" {
+		t.Errorf("custom = %v, want the merged values without the removed one", user.Custom)
+	}
+
+	var found bool
+	q := userConfig.GetAllQuery{SortBy: "id", SortOrder: "ASC", Custom: map[string]any{"department": "sales"}, Limit: 100}
+	_, err = s.EachUser(ctx, q, func(u userConfig.TableUser) error {
+		found = found || u.ID == id
+		return nil
+	})
+	if err != nil || !found {
+		t.Errorf("filter by custom value: found = %v, err = %v", found, err)
+	}
+}
+
+func TestTodoFields(t *testing.T) {
+	s := testStorage(t)
+	ctx := context.Background()
+
+	user := testUser(t, s, "todofieldsuser")
+
+	schema := fields.Schema{Fields: []fields.Field{{Key: "priority", Type: fields.TypeText}, {Key: "estimate", Type: fields.TypeNumber}}}
+	if err := s.SetTodoFields(ctx, user, schema); err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.TodoFields(ctx, user)
+	if err != nil || len(got.Fields) != 2 {
+		t.Errorf("TodoFields() = %+v, %v", got, err)
+	}
+
+	id, err := s.Create(ctx, todoconfig.TodoRequest{Title: "custom", Custom: map[string]any{"priority": "high", "estimate": 2.0}}, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Create(ctx, todoconfig.TodoRequest{Title: "plain"}, user); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Update(ctx, int(id), user, todoconfig.TodoRequest{Custom: map[string]any{"estimate": nil}}); err != nil {
+		t.Fatal(err)
+	}
+
+	var matched []todoconfig.Todo
+	_, err = s.EachTodo(ctx, todoconfig.TodoQuery{Custom: map[string]any{"priority": "high"}}, user, func(todo todoconfig.Todo) error {
+		matched = append(matched, todo)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matched) != 1 || len(matched[0].Custom) != 1 {
+		t.Errorf("filtered todos = %+v, want the one without the removed estimate", matched)
+	}
+
+	// Removing a field deletes its values.
+	if err := s.SetTodoFields(ctx, user, fields.Schema{Fields: []fields.Field{{Key: "estimate", Type: fields.TypeNumber}}}); err != nil {
+		t.Fatal(err)
+	}
+	todo, err := s.GetTodo(ctx, int(id), user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(todo.Custom) != 0 {
+		t.Errorf("custom = %v after the field was removed", todo.Custom)
+	}
+}
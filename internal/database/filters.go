@@ -0,0 +1,139 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	t "github.com/sabbatD/srest-api/internal/lib/todoConfig"
+)
+
+// MaxTodoFilters is the number of saved filters a user may keep
+const MaxTodoFilters = 50
+
+// CreateTodoFilter saves a named filter of the user. Names are unique per user regardless of case,
+// a taken name returns ErrAlreadyExists and a user at MaxTodoFilters gets ErrLimitReached.
+func (s *Storage) CreateTodoFilter(ctx context.Context, userID int, f t.FilterRequest) (t.SavedFilter, error) {
+	const op = "database.postgres.CreateTodoFilter"
+
+	query, err := json.Marshal(f.Query)
+	if err != nil {
+		return t.SavedFilter{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return t.SavedFilter{}, fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	// Locking the user serializes the limit check with concurrent inserts.
+	var count int
+	err = tx.QueryRowContext(ctx, `
+		SELECT (SELECT COUNT(*) FROM public.todo_filters WHERE user_id = u.id)
+		FROM public.users u WHERE u.id = $1 FOR UPDATE
+	`, userID).Scan(&count)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return t.SavedFilter{}, fmt.Errorf("%s: no user with id %v: %w", op, userID, ErrNotFound)
+		}
+		return t.SavedFilter{}, fmt.Errorf("%s: %v", op, err)
+	}
+	if count >= MaxTodoFilters {
+		return t.SavedFilter{}, fmt.Errorf("%s: filter %w", op, ErrLimitReached)
+	}
+
+	var saved t.SavedFilter
+	var raw []byte
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO public.todo_filters (user_id, name, query) VALUES ($1, $2, $3)
+		RETURNING `+filterColumns,
+		userID, f.Name, query).Scan(&saved.ID, &saved.PublicID, &saved.Name, &raw, &saved.Created)
+	if err != nil {
+		if pgErr, ok := err.(*pq.Error); ok && pgErr.Code == "23505" {
+			return t.SavedFilter{}, fmt.Errorf("%s: filter name %w", op, ErrAlreadyExists)
+		}
+		return t.SavedFilter{}, fmt.Errorf("%s: %v", op, err)
+	}
+	if err := json.Unmarshal(raw, &saved.Query); err != nil {
+		return t.SavedFilter{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return t.SavedFilter{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return saved, nil
+}
+
+// TodoFilters returns the saved filters of the user in the order they were created
+func (s *Storage) TodoFilters(ctx context.Context, userID int) ([]t.SavedFilter, error) {
+	const op = "database.postgres.TodoFilters"
+
+	rows, err := s.db.QueryContext(ctx, `SELECT `+filterColumns+` FROM public.todo_filters WHERE user_id = $1 ORDER BY id`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+	defer rows.Close()
+
+	filters := []t.SavedFilter{}
+	for rows.Next() {
+		var f t.SavedFilter
+		var raw []byte
+		if err := rows.Scan(&f.ID, &f.PublicID, &f.Name, &raw, &f.Created); err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+		if err := json.Unmarshal(raw, &f.Query); err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+		filters = append(filters, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return filters, nil
+}
+
+// TodoFilter returns the user's saved filter by its public id
+func (s *Storage) TodoFilter(ctx context.Context, publicID string, userID int) (t.SavedFilter, error) {
+	const op = "database.postgres.TodoFilter"
+
+	var f t.SavedFilter
+	var raw []byte
+	err := s.db.QueryRowContext(ctx, `SELECT `+filterColumns+` FROM public.todo_filters WHERE public_id = $1 AND user_id = $2`,
+		publicID, userID).Scan(&f.ID, &f.PublicID, &f.Name, &raw, &f.Created)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return t.SavedFilter{}, fmt.Errorf("%s: no such filter: %w", op, ErrNotFound)
+		}
+		return t.SavedFilter{}, fmt.Errorf("%s: %v", op, err)
+	}
+	if err := json.Unmarshal(raw, &f.Query); err != nil {
+		return t.SavedFilter{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return f, nil
+}
+
+// DeleteTodoFilter deletes the user's saved filter by its public id
+func (s *Storage) DeleteTodoFilter(ctx context.Context, publicID string, userID int) error {
+	const op = "database.postgres.DeleteTodoFilter"
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM public.todo_filters WHERE public_id = $1 AND user_id = $2`, publicID, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	} else if n == 0 {
+		return fmt.Errorf("%s: no such filter: %w", op, ErrNotFound)
+	}
+
+	return nil
+}
+
+const filterColumns = `id, public_id, name, query, created`
@@ -0,0 +1,88 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	todoconfig "github.com/sabbatD/srest-api/internal/lib/todoConfig"
+)
+
+func TestTodoFilters(t *testing.T) {
+	s := testStorage(t)
+	ctx := context.Background()
+
+	user := testUser(t, s, "filtersuser")
+	stranger := testUser(t, s, "filtersstranger")
+
+	saved, err := s.CreateTodoFilter(ctx, user, todoconfig.FilterRequest{Name: "Today", Query: map[string]string{"due": "today"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.PublicID == "" || saved.Query["due"] != "today" {
+		t.Errorf("saved filter = %+v", saved)
+	}
+	if _, err := s.CreateTodoFilter(ctx, user, todoconfig.FilterRequest{Name: "TODAY"}); !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("taken name: err = %v, want ErrAlreadyExists", err)
+	}
+	if _, err := s.CreateTodoFilter(ctx, stranger, todoconfig.FilterRequest{Name: "Today"}); err != nil {
+		t.Errorf("names are per user: %v", err)
+	}
+
+	if _, err := s.TodoFilter(ctx, saved.PublicID, stranger); !errors.Is(err, ErrNotFound) {
+		t.Errorf("filter of another user: err = %v, want ErrNotFound", err)
+	}
+	filters, err := s.TodoFilters(ctx, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filters) != 1 || filters[0].PublicID != saved.PublicID {
+		t.Errorf("filters = %+v", filters)
+	}
+
+	for i := len(filters); i < MaxTodoFilters; i++ {
+		if _, err := s.CreateTodoFilter(ctx, user, todoconfig.FilterRequest{Name: string(rune('a'+i%26)) + string(rune('a'+i/26))}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.CreateTodoFilter(ctx, user, todoconfig.FilterRequest{Name: "one too many"}); !errors.Is(err, ErrLimitReached) {
+		t.Errorf("over the limit: err = %v, want ErrLimitReached", err)
+	}
+
+	if err := s.DeleteTodoFilter(ctx, saved.PublicID, stranger); !errors.Is(err, ErrNotFound) {
+		t.Errorf("delete a filter of another user: err = %v, want ErrNotFound", err)
+	}
+	if err := s.DeleteTodoFilter(ctx, saved.PublicID, user); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEachTodoDue(t *testing.T) {
+	s := testStorage(t)
+	ctx := context.Background()
+
+	user := testUser(t, s, "dueuser")
+
+	date := func(s string) *string { return &s }
+	for _, req := range []todoconfig.TodoRequest{
+		{Title: "past", Due: date("2024-10-01")},
+		{Title: "now", Due: date("2024-10-21")},
+		{Title: "someday"},
+	} {
+		if _, err := s.Create(ctx, req, user); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var matched []string
+	_, err := s.EachTodo(ctx, todoconfig.TodoQuery{DueTo: "2024-10-20"}, user, func(todo todoconfig.Todo) error {
+		matched = append(matched, todo.Title)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matched) != 1 || matched[0] != "past" {
+		t.Errorf("due until 2024-10-20 = %v, want [past]", matched)
+	}
+}
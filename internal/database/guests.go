@@ -0,0 +1,102 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// AddGuest creates a guest user without credentials that may own up to maxTodos todos
+func (s *Storage) AddGuest(ctx context.Context, maxTodos int) (int, error) {
+	const op = "database.postgres.AddGuest"
+
+	var id int
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO public.users (username, is_guest, todo_limit) VALUES ('Guest', TRUE, $1)
+		RETURNING id
+	`, maxTodos).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return id, nil
+}
+
+// UpgradeGuest moves the guest's todos to the user and deletes the guest, it returns the number of moved todos.
+// A user without custom todo fields or workflow takes over the guest's ones.
+func (s *Storage) UpgradeGuest(ctx context.Context, guest, user int) (int64, error) {
+	const op = "database.postgres.UpgradeGuest"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	var id int
+	err = tx.QueryRowContext(ctx, `SELECT id FROM public.users WHERE id = $1 AND is_guest FOR UPDATE`, guest).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("%s: no guest with id %v: %w", op, guest, ErrNotFound)
+		}
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	// Moved todos take a new version so the user's sync clients pick them up.
+	res, err := tx.ExecContext(ctx, `
+		UPDATE public.todos SET user_id = $1, version = nextval('public.todos_version_seq')
+		WHERE user_id = $2
+	`, user, guest)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+	moved, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	// The custom values and statuses of the todos come with the guest's definitions, unless the user has their own.
+	_, err = tx.ExecContext(ctx, `
+		UPDATE public.users SET todo_fields = (SELECT todo_fields FROM public.users WHERE id = $2)
+		WHERE id = $1 AND todo_fields = '{"fields": []}'
+	`, user, guest)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE public.users SET todo_workflow = (SELECT todo_workflow FROM public.users WHERE id = $2)
+		WHERE id = $1 AND todo_workflow IS NULL
+	`, user, guest)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM public.users WHERE id = $1`, guest); err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return moved, nil
+}
+
+// PurgeGuests deletes guests created before the given time along with their todos
+func (s *Storage) PurgeGuests(ctx context.Context, before time.Time) (int64, error) {
+	const op = "database.postgres.PurgeGuests"
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM public.users WHERE is_guest AND date < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return n, nil
+}
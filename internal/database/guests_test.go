@@ -0,0 +1,50 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	todoconfig "github.com/sabbatD/srest-api/internal/lib/todoConfig"
+)
+
+func TestGuests(t *testing.T) {
+	s := testStorage(t)
+	ctx := context.Background()
+
+	guest, err := s.AddGuest(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := testUser(t, s, "upgradedguest")
+
+	for _, title := range []string{"one", "two"} {
+		if _, err := s.Create(ctx, todoconfig.TodoRequest{Title: title}, guest); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.Create(ctx, todoconfig.TodoRequest{Title: "three"}, guest); !errors.Is(err, ErrLimitReached) {
+		t.Errorf("todo over the guest limit: err = %v, want ErrLimitReached", err)
+	}
+
+	moved, err := s.UpgradeGuest(ctx, guest, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved != 2 {
+		t.Errorf("moved %d todos, want 2", moved)
+	}
+	if _, info, _, _ := s.OutputAll(ctx, "all", user); info.All != 2 {
+		t.Errorf("user has %d todos, want 2", info.All)
+	}
+	if _, err := s.Create(ctx, todoconfig.TodoRequest{Title: "three"}, user); err != nil {
+		t.Errorf("the user inherited the guest limit: %v", err)
+	}
+
+	if _, err := s.UpgradeGuest(ctx, guest, user); !errors.Is(err, ErrNotFound) {
+		t.Errorf("upgrading a deleted guest: err = %v, want ErrNotFound", err)
+	}
+	if _, err := s.UpgradeGuest(ctx, user, guest); !errors.Is(err, ErrNotFound) {
+		t.Errorf("upgrading a user: err = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,48 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	t "github.com/sabbatD/srest-api/internal/lib/todoConfig"
+)
+
+// TodoCompletions counts the completions of the user's todos per day (UTC) from from until before to,
+// days without completions are left out. A completion is a change from open to done in the todo history,
+// a todo reopened and completed again counts twice. Todos created done count on their creation day.
+func (s *Storage) TodoCompletions(ctx context.Context, userID int, from, to time.Time) ([]t.HeatmapDay, error) {
+	const op = "database.postgres.TodoCompletions"
+
+	// The previous state of a todo may be older than from, the window sees the whole history until to.
+	// Rows without a previous state other than inserts are snapshots or todos merged from another user.
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT to_char(changed_at AT TIME ZONE 'UTC', 'YYYY-MM-DD'), COUNT(*)
+		FROM (
+			SELECT changed_at, op, is_done, LAG(is_done) OVER (PARTITION BY public_id ORDER BY id) AS was_done
+			FROM public.todo_history
+			WHERE user_id = $1 AND changed_at < $3
+		) h
+		WHERE changed_at >= $2 AND is_done AND (NOT was_done OR (was_done IS NULL AND op = 'insert'))
+		GROUP BY 1
+		ORDER BY 1
+	`, userID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+	defer rows.Close()
+
+	days := []t.HeatmapDay{}
+	for rows.Next() {
+		var day t.HeatmapDay
+		if err := rows.Scan(&day.Date, &day.Count); err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+		days = append(days, day)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return days, nil
+}
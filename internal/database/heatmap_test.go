@@ -0,0 +1,52 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	todoconfig "github.com/sabbatD/srest-api/internal/lib/todoConfig"
+)
+
+func TestTodoCompletions(t *testing.T) {
+	s := testStorage(t)
+	ctx := context.Background()
+
+	user := testUser(t, s, "heatmapuser")
+
+	done, open := true, false
+	if _, err := s.Create(ctx, todoconfig.TodoRequest{Title: "created done", IsDone: &done}, user); err != nil {
+		t.Fatal(err)
+	}
+	id, err := s.Create(ctx, todoconfig.TodoRequest{Title: "reopened"}, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, isDone := range []*bool{&done, &open, &done} {
+		if _, err := s.Update(ctx, int(id), user, todoconfig.TodoRequest{IsDone: isDone}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Renaming a done task is no completion.
+	if _, err := s.Update(ctx, int(id), user, todoconfig.TodoRequest{Title: "renamed"}); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now().UTC()
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	days, err := s.TodoCompletions(ctx, user, from, from.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(days) != 1 || days[0].Date != from.Format(todoconfig.DateFormat) || days[0].Count != 3 {
+		t.Errorf("completions = %+v, want 3 today", days)
+	}
+
+	days, err = s.TodoCompletions(ctx, user, from.AddDate(0, 0, -7), from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(days) != 0 {
+		t.Errorf("completions last week = %+v, want none", days)
+	}
+}
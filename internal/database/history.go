@@ -0,0 +1,253 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	t "github.com/sabbatD/srest-api/internal/lib/todoConfig"
+)
+
+// todo_history is written by triggers on todos and todo_dependencies, see the create_todo_history
+// and extend_todo_history migrations.
+
+type historyTodo struct {
+	title   sql.NullString
+	isDone  bool
+	status  string
+	created sql.NullTime
+	// custom is NULL in rows recorded before history kept it, the parts below are then unknown
+	custom    sql.NullString
+	due       sql.NullString
+	pinnedAt  sql.NullTime
+	blockedBy []string
+}
+
+// RestoreTodos sets the user's todos back to their state at asOf: todos created later are deleted,
+// deleted ones are recreated with their old ids and changed ones get their old title, status, custom field values,
+// due date, pin and dependencies. Dependencies on todos that are not back are left out. Parts history did not
+// record yet at asOf are left as they are.
+// A restored status may no longer be part of the user's workflow, the task can then move to any status.
+// Every touched todo takes a new version so sync clients pick the restore up, and the restore is
+// itself recorded in the history, so it can be undone by restoring to a time before it.
+// ErrNoHistory is returned when asOf predates the history. A dry run rolls the restore back.
+func (s *Storage) RestoreTodos(ctx context.Context, userID, actor int, asOf time.Time, dryRun bool) (t.RestoreResult, error) {
+	const op = "database.postgres.RestoreTodos"
+
+	result := t.RestoreResult{AsOf: asOf, DryRun: dryRun}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return result, fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	// Locking the user keeps a concurrent merge from moving todos in or out while restoring.
+	var id int
+	err = tx.QueryRowContext(ctx, `SELECT id FROM public.users WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, userID).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return result, fmt.Errorf("%s: no users with id %v: %w", op, userID, ErrNotFound)
+		}
+		return result, fmt.Errorf("%s: %v", op, err)
+	}
+
+	var start sql.NullTime
+	err = tx.QueryRowContext(ctx, `SELECT changed_at FROM public.todo_history ORDER BY id LIMIT 1`).Scan(&start)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return result, fmt.Errorf("%s: %v", op, err)
+	}
+	if !start.Valid || asOf.Before(start.Time) {
+		return result, fmt.Errorf("%s: history starts at %v: %w", op, start.Time, ErrNoHistory)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT public_id, title, is_done, status, created, custom::text, due::text, pinned_at, blocked_by FROM (
+			SELECT DISTINCT ON (public_id) public_id, op, title, is_done, status, created, custom, due, pinned_at, blocked_by
+			FROM public.todo_history
+			WHERE user_id = $1 AND changed_at <= $2
+			ORDER BY public_id, id DESC
+		) h
+		WHERE op <> 'delete'
+	`, userID, asOf)
+	if err != nil {
+		return result, fmt.Errorf("%s: %v", op, err)
+	}
+	target := make(map[string]historyTodo)
+	// Dependencies are restored once every todo is back, for the todos history has them of
+	blockers := make(map[string][]string)
+	for rows.Next() {
+		var publicID string
+		var h historyTodo
+		if err := rows.Scan(&publicID, &h.title, &h.isDone, &h.status, &h.created, &h.custom, &h.due, &h.pinnedAt, pq.Array(&h.blockedBy)); err != nil {
+			rows.Close()
+			return result, fmt.Errorf("%s: %v", op, err)
+		}
+		target[publicID] = h
+		if h.custom.Valid {
+			blockers[publicID] = h.blockedBy
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return result, fmt.Errorf("%s: %v", op, err)
+	}
+
+	rows, err = tx.QueryContext(ctx, `SELECT id, public_id FROM public.todos WHERE user_id = $1 FOR UPDATE`, userID)
+	if err != nil {
+		return result, fmt.Errorf("%s: %v", op, err)
+	}
+	type currentTodo struct {
+		id       int
+		publicID string
+	}
+	var current []currentTodo
+	for rows.Next() {
+		var c currentTodo
+		if err := rows.Scan(&c.id, &c.publicID); err != nil {
+			rows.Close()
+			return result, fmt.Errorf("%s: %v", op, err)
+		}
+		current = append(current, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return result, fmt.Errorf("%s: %v", op, err)
+	}
+
+	touched := make(map[string]bool)
+	for _, c := range current {
+		h, ok := target[c.publicID]
+		delete(target, c.publicID)
+
+		if !ok {
+			if _, err := tx.ExecContext(ctx, `DELETE FROM public.todos WHERE id = $1`, c.id); err != nil {
+				return result, fmt.Errorf("%s: %v", op, err)
+			}
+			_, err := tx.ExecContext(ctx, `
+				INSERT INTO public.todo_tombstones (public_id, user_id, version)
+				VALUES ($1, $2, nextval('public.todos_version_seq'))
+				ON CONFLICT (public_id) DO UPDATE SET user_id = EXCLUDED.user_id, version = EXCLUDED.version, deleted_at = NOW()
+			`, c.publicID, userID)
+			if err != nil {
+				return result, fmt.Errorf("%s: %v", op, err)
+			}
+			result.Deleted++
+			continue
+		}
+
+		// Unknown parts (NULL custom) keep their current values, unchanged todos are not touched.
+		res, err := tx.ExecContext(ctx, `
+			UPDATE public.todos t
+			SET title = r.title, is_done = r.is_done, status = r.status, custom = r.custom, due = r.due, pinned_at = r.pinned_at,
+				version = nextval('public.todos_version_seq')
+			FROM (
+				SELECT $1::text AS title, $2::boolean AS is_done, $3::text AS status,
+					COALESCE($4::jsonb, c.custom) AS custom,
+					CASE WHEN $4::jsonb IS NULL THEN c.due ELSE $5::date END AS due,
+					CASE WHEN $4::jsonb IS NULL THEN c.pinned_at ELSE $6::timestamptz END AS pinned_at
+				FROM public.todos c WHERE c.id = $7
+			) r
+			WHERE t.id = $7
+				AND (t.title, t.is_done, t.status, t.custom, t.due, t.pinned_at)
+					IS DISTINCT FROM (r.title, r.is_done, r.status, r.custom, r.due, r.pinned_at)
+		`, h.title, h.isDone, h.status, h.custom, h.due, h.pinnedAt, c.id)
+		if err != nil {
+			return result, fmt.Errorf("%s: %v", op, err)
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return result, fmt.Errorf("%s: %v", op, err)
+		} else if n > 0 {
+			touched[c.publicID] = true
+			result.Reverted++
+		}
+	}
+
+	for publicID, h := range target {
+		var taken bool
+		if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM public.todos WHERE public_id = $1)`, publicID).Scan(&taken); err != nil {
+			return result, fmt.Errorf("%s: %v", op, err)
+		}
+		if taken {
+			result.Skipped = append(result.Skipped, publicID)
+			delete(blockers, publicID)
+			continue
+		}
+
+		_, err := tx.ExecContext(ctx, `
+			WITH v AS (SELECT nextval('public.todos_version_seq') AS version)
+			INSERT INTO public.todos (public_id, title, is_done, status, created, user_id, version, created_version, custom, due, pinned_at)
+			SELECT $1, $2, $3, $4, COALESCE($5, NOW()), $6, v.version, v.version, COALESCE($7::jsonb, '{}'), $8::date, $9 FROM v
+		`, publicID, h.title, h.isDone, h.status, h.created, userID, h.custom, h.due, h.pinnedAt)
+		if err != nil {
+			return result, fmt.Errorf("%s: %v", op, err)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM public.todo_tombstones WHERE public_id = $1`, publicID); err != nil {
+			return result, fmt.Errorf("%s: %v", op, err)
+		}
+		touched[publicID] = true
+		result.Restored++
+	}
+
+	for publicID, ids := range blockers {
+		changed, err := restoreBlockers(ctx, tx, userID, publicID, ids)
+		if err != nil {
+			return result, fmt.Errorf("%s: %v", op, err)
+		}
+		if changed && !touched[publicID] {
+			result.Reverted++
+		}
+	}
+
+	if err := audit(ctx, tx, actor, AuditRestoreTodos, userID, result); err != nil {
+		return result, fmt.Errorf("%s: %v", op, err)
+	}
+
+	if dryRun {
+		return result, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return result, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return result, nil
+}
+
+// restoreBlockers sets the todos blocking the user's todo to those of ids that are the user's,
+// reporting whether that changed anything. All blockers come from one point of the history, they cannot form a cycle.
+func restoreBlockers(ctx context.Context, exec execer, userID int, publicID string, ids []string) (bool, error) {
+	res, err := exec.ExecContext(ctx, `
+		DELETE FROM public.todo_dependencies d
+		USING public.todos t, public.todos b
+		WHERE d.todo_id = t.id AND d.blocked_by = b.id AND t.public_id = $1 AND t.user_id = $2
+			AND NOT b.public_id = ANY($3::uuid[])
+	`, publicID, userID, pq.Array(ids))
+	if err != nil {
+		return false, err
+	}
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	res, err = exec.ExecContext(ctx, `
+		INSERT INTO public.todo_dependencies (todo_id, blocked_by)
+		SELECT t.id, b.id FROM public.todos t
+		JOIN public.todos b ON b.user_id = t.user_id AND b.public_id = ANY($3::uuid[])
+		WHERE t.public_id = $1 AND t.user_id = $2
+		ON CONFLICT DO NOTHING
+	`, publicID, userID, pq.Array(ids))
+	if err != nil {
+		return false, err
+	}
+	inserted, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return deleted+inserted > 0, nil
+}
@@ -0,0 +1,138 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	todoconfig "github.com/sabbatD/srest-api/internal/lib/todoConfig"
+)
+
+func TestRestoreTodos(t *testing.T) {
+	s := testStorage(t)
+	ctx := context.Background()
+
+	admin := testUser(t, s, "restoreadmin")
+	user := testUser(t, s, "restoreuser")
+
+	ids := map[string]int64{}
+	for _, title := range []string{"keep", "change", "gone"} {
+		id, err := s.Create(ctx, todoconfig.TodoRequest{Title: title}, user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[title] = id
+	}
+
+	var asOf time.Time
+	if err := s.db.QueryRow(`SELECT clock_timestamp()`).Scan(&asOf); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Update(ctx, int(ids["change"]), user, todoconfig.TodoRequest{Title: "changed"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Delete(ctx, int(ids["gone"]), user); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Create(ctx, todoconfig.TodoRequest{Title: "new"}, user); err != nil {
+		t.Fatal(err)
+	}
+
+	dry, err := s.RestoreTodos(ctx, user, admin, asOf, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dry.Restored != 1 || dry.Reverted != 1 || dry.Deleted != 1 || !dry.DryRun {
+		t.Errorf("dry run = %+v", dry)
+	}
+	if _, info, _, _ := s.OutputAll(ctx, "all", user); info.All != 3 {
+		t.Errorf("dry run changed todos: user has %d", info.All)
+	}
+
+	res, err := s.RestoreTodos(ctx, user, admin, asOf, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Restored != 1 || res.Reverted != 1 || res.Deleted != 1 || res.DryRun {
+		t.Errorf("restore = %+v", res)
+	}
+
+	todos, _, _, err := s.OutputAll(ctx, "all", user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]bool{}
+	for _, todo := range todos {
+		got[todo.Title] = true
+	}
+	if len(todos) != 3 || !got["keep"] || !got["change"] || !got["gone"] {
+		t.Errorf("todos after restore = %+v", todos)
+	}
+	if _, err := s.SyncState(ctx, todos[0].PublicID, user); err != nil {
+		t.Errorf("restored todo has no sync state: %v", err)
+	}
+
+	if _, err := s.RestoreTodos(ctx, user, admin, time.Unix(0, 0), false); !errors.Is(err, ErrNoHistory) {
+		t.Errorf("restoring before the history: err = %v, want ErrNoHistory", err)
+	}
+}
+
+func TestRestoreTodosFields(t *testing.T) {
+	s := testStorage(t)
+	ctx := context.Background()
+
+	admin := testUser(t, s, "restorefieldsadmin")
+	user := testUser(t, s, "restorefieldsuser")
+
+	blocker, err := s.Create(ctx, todoconfig.TodoRequest{Title: "blocker"}, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	due := "2024-12-31"
+	id, err := s.Create(ctx, todoconfig.TodoRequest{Title: "full", Due: &due, Custom: map[string]any{"priority": "high"}}, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.PinTodo(ctx, int(id), user, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddBlocker(ctx, int(id), int(blocker), user); err != nil {
+		t.Fatal(err)
+	}
+	before, err := s.GetTodo(ctx, int(id), user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var asOf time.Time
+	if err := s.db.QueryRow(`SELECT clock_timestamp()`).Scan(&asOf); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Delete(ctx, int(id), user); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := s.RestoreTodos(ctx, user, admin, asOf, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Restored != 1 {
+		t.Errorf("restore = %+v", res)
+	}
+
+	var restored int
+	if err := s.db.QueryRow(`SELECT id FROM public.todos WHERE public_id = $1`, before.PublicID).Scan(&restored); err != nil {
+		t.Fatal(err)
+	}
+	after, err := s.GetTodo(ctx, restored, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.Due != due || after.Custom["priority"] != "high" || !after.Pinned ||
+		len(after.BlockedBy) != 1 || after.BlockedBy[0] != before.BlockedBy[0] {
+		t.Errorf("restored todo = %+v, want %+v", after, before)
+	}
+}
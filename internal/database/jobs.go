@@ -0,0 +1,120 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/sabbatD/srest-api/internal/lib/jobs"
+)
+
+const deadJobColumns = `id, queue, kind, payload, attempts, error, failed_at`
+
+func scanDeadJob(row scanner) (d jobs.DeadJob, err error) {
+	err = row.Scan(&d.ID, &d.Queue, &d.Kind, &d.Payload, &d.Attempts, &d.Error, &d.FailedAt)
+	return d, err
+}
+
+func (s *Storage) AddDeadJob(ctx context.Context, d jobs.DeadJob) error {
+	const op = "database.postgres.AddDeadJob"
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO public.dead_jobs (queue, kind, payload, attempts, error, failed_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, d.Queue, d.Kind, []byte(d.Payload), d.Attempts, d.Error, d.FailedAt)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// DeadJobs returns the newest dead letters first
+func (s *Storage) DeadJobs(ctx context.Context, limit int) ([]jobs.DeadJob, error) {
+	const op = "database.postgres.DeadJobs"
+
+	rows, err := s.db.QueryContext(ctx, `SELECT `+deadJobColumns+` FROM public.dead_jobs ORDER BY id DESC LIMIT $1`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+	defer rows.Close()
+
+	var dead []jobs.DeadJob
+	for rows.Next() {
+		d, err := scanDeadJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+		dead = append(dead, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return dead, nil
+}
+
+// RequeueDeadJob deletes the dead letter with id and passes it to enqueue, the deletion is rolled back when enqueue fails.
+// The requeue by actor is recorded in the audit log.
+func (s *Storage) RequeueDeadJob(ctx context.Context, actor int, id int64, enqueue func(jobs.DeadJob) error) error {
+	const op = "database.postgres.RequeueDeadJob"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	d, err := scanDeadJob(tx.QueryRowContext(ctx, `DELETE FROM public.dead_jobs WHERE id = $1 RETURNING `+deadJobColumns, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s: no dead job with id %v: %w", op, id, ErrNotFound)
+		}
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := audit(ctx, tx, actor, AuditRequeueJob, nil, map[string]any{"id": d.ID, "queue": d.Queue, "kind": d.Kind}); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	// The job is queued before the commit, a failed commit at worst runs a job that stays a dead letter
+	if err := enqueue(d); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// DeleteDeadJob deletes the dead letter with id and records the deletion by actor in the audit log
+func (s *Storage) DeleteDeadJob(ctx context.Context, actor int, id int64) error {
+	const op = "database.postgres.DeleteDeadJob"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	d, err := scanDeadJob(tx.QueryRowContext(ctx, `DELETE FROM public.dead_jobs WHERE id = $1 RETURNING `+deadJobColumns, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s: no dead job with id %v: %w", op, id, ErrNotFound)
+		}
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := audit(ctx, tx, actor, AuditDeleteJob, nil, map[string]any{"id": d.ID, "queue": d.Queue, "kind": d.Kind}); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sabbatD/srest-api/internal/lib/jobs"
+)
+
+func TestDeadJobs(t *testing.T) {
+	s := testStorage(t)
+	ctx := context.Background()
+
+	s.db.Exec(`DELETE FROM public.dead_jobs`)
+	t.Cleanup(func() { s.db.Exec(`DELETE FROM public.dead_jobs`) })
+
+	admin := testUser(t, s, "deadjobsadmin")
+	for _, kind := range []string{"alert.webhook", "backup.alert"} {
+		d := jobs.DeadJob{Queue: jobs.QueueWebhooks, Kind: kind, Payload: json.RawMessage(`{"kind":"error_rate"}`),
+			Attempts: 5, Error: "unexpected status 502", FailedAt: time.Now()}
+		if err := s.AddDeadJob(ctx, d); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dead, err := s.DeadJobs(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dead) != 2 || dead[0].Kind != "backup.alert" || dead[0].Attempts != 5 {
+		t.Fatalf("DeadJobs() = %+v", dead)
+	}
+
+	// A job that cannot be queued stays a dead letter
+	full := errors.New("full")
+	if err := s.RequeueDeadJob(ctx, admin, dead[0].ID, func(jobs.DeadJob) error { return full }); !errors.Is(err, full) {
+		t.Errorf("RequeueDeadJob() with a failing enqueue error = %v, want it", err)
+	}
+	var queued jobs.DeadJob
+	if err := s.RequeueDeadJob(ctx, admin, dead[0].ID, func(d jobs.DeadJob) error { queued = d; return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if queued.Kind != "backup.alert" || string(queued.Payload) != `{"kind": "error_rate"}` {
+		t.Errorf("requeued job = %+v", queued)
+	}
+	if err := s.RequeueDeadJob(ctx, admin, dead[0].ID, func(jobs.DeadJob) error { return nil }); !errors.Is(err, ErrNotFound) {
+		t.Errorf("RequeueDeadJob() of a requeued job error = %v, want ErrNotFound", err)
+	}
+
+	if err := s.DeleteDeadJob(ctx, admin, dead[1].ID); err != nil {
+		t.Fatal(err)
+	}
+	if dead, _ := s.DeadJobs(ctx, 10); len(dead) != 0 {
+		t.Errorf("DeadJobs() after requeue and delete = %+v", dead)
+	}
+}
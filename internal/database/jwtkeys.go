@@ -0,0 +1,65 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	kc "github.com/sabbatD/srest-api/internal/lib/keyConfig"
+)
+
+// SigningKeys returns the JWT signing keys created by rotations, the newest first
+func (s *Storage) SigningKeys(ctx context.Context) ([]kc.SigningKey, error) {
+	const op = "database.postgres.SigningKeys"
+
+	rows, err := s.db.QueryContext(ctx, `SELECT kid, algorithm, key, created, retired FROM public.jwt_keys ORDER BY created DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+	defer rows.Close()
+
+	keys := []kc.SigningKey{}
+	for rows.Next() {
+		var key kc.SigningKey
+		if err := rows.Scan(&key.ID, &key.Algorithm, &key.Key, &key.Created, &key.Retired); err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return keys, nil
+}
+
+// RotateSigningKey retires the active signing key and adds key as the active one, audited as done by actor
+func (s *Storage) RotateSigningKey(ctx context.Context, actor int, key kc.SigningKey) error {
+	const op = "database.postgres.RotateSigningKey"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `UPDATE public.jwt_keys SET retired = $1 WHERE retired IS NULL`, key.Created); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO public.jwt_keys (kid, algorithm, key, created) VALUES ($1, $2, $3, $4)
+	`, key.ID, key.Algorithm, key.Key, key.Created)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := audit(ctx, tx, actor, AuditRotateJWTKey, nil, map[string]string{"kid": key.ID, "algorithm": key.Algorithm}); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
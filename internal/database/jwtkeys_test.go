@@ -0,0 +1,48 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	kc "github.com/sabbatD/srest-api/internal/lib/keyConfig"
+)
+
+func TestRotateSigningKey(t *testing.T) {
+	s := testStorage(t)
+	ctx := context.Background()
+
+	actor := testUser(t, s, "keyadmin")
+	s.db.Exec(`DELETE FROM public.jwt_keys`)
+	t.Cleanup(func() { s.db.Exec(`DELETE FROM public.jwt_keys`) })
+
+	first := time.Now().Add(-time.Hour).Truncate(time.Second)
+	second := time.Now().Truncate(time.Second)
+	if err := s.RotateSigningKey(ctx, actor, kc.SigningKey{ID: "kid-1", Algorithm: "HS256", Key: "secret-1", Created: first}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RotateSigningKey(ctx, actor, kc.SigningKey{ID: "kid-2", Algorithm: "HS256", Key: "secret-2", Created: second}); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := s.SigningKeys(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || keys[0].ID != "kid-2" || keys[1].ID != "kid-1" {
+		t.Fatalf("SigningKeys() = %+v, want kid-2 then kid-1", keys)
+	}
+	if keys[0].Retired != nil {
+		t.Errorf("active key retired at %v", keys[0].Retired)
+	}
+	// The replaced key is retired when the new one was created
+	if keys[1].Retired == nil || !keys[1].Retired.Equal(second) {
+		t.Errorf("replaced key retired at %v, want %v", keys[1].Retired, second)
+	}
+
+	var audited int
+	s.db.QueryRow(`SELECT COUNT(*) FROM public.audit_log WHERE actor_id = $1 AND action = $2`, actor, AuditRotateJWTKey).Scan(&audited)
+	if audited != 2 {
+		t.Errorf("audited %d rotations, want 2", audited)
+	}
+}
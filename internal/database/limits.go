@@ -0,0 +1,93 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	u "github.com/sabbatD/srest-api/internal/lib/userConfig"
+)
+
+// UserLimits returns the limit overrides of the user, empty when none are set
+func (s *Storage) UserLimits(ctx context.Context, id int) (u.Limits, error) {
+	const op = "database.postgres.UserLimits"
+
+	var l u.Limits
+	err := s.db.QueryRowContext(ctx, `
+		SELECT todo_writes, reports, max_todos FROM public.user_limits WHERE user_id = $1
+	`, id).Scan(&l.TodoWrites, &l.Reports, &l.MaxTodos)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return u.Limits{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return l, nil
+}
+
+// AllUserLimits returns the limit overrides of every user that has some, keyed by user id
+func (s *Storage) AllUserLimits(ctx context.Context) (map[int]u.Limits, error) {
+	const op = "database.postgres.AllUserLimits"
+
+	rows, err := s.db.QueryContext(ctx, `SELECT user_id, todo_writes, reports, max_todos FROM public.user_limits`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+	defer rows.Close()
+
+	limits := make(map[int]u.Limits)
+	for rows.Next() {
+		var id int
+		var l u.Limits
+		if err := rows.Scan(&id, &l.TodoWrites, &l.Reports, &l.MaxTodos); err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+		limits[id] = l
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return limits, nil
+}
+
+// SetUserLimits replaces the limit overrides of the user, audited as done by actor.
+// Limits without any override remove the user's row, so every default applies again.
+func (s *Storage) SetUserLimits(ctx context.Context, actor, id int, l u.Limits) error {
+	const op = "database.postgres.SetUserLimits"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM public.users WHERE id = $1)`, id).Scan(&exists); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	if !exists {
+		return fmt.Errorf("%s: no users with id %v: %w", op, id, ErrNotFound)
+	}
+
+	if l.TodoWrites == nil && l.Reports == nil && l.MaxTodos == nil {
+		_, err = tx.ExecContext(ctx, `DELETE FROM public.user_limits WHERE user_id = $1`, id)
+	} else {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO public.user_limits (user_id, todo_writes, reports, max_todos) VALUES ($1, $2, $3, $4)
+			ON CONFLICT (user_id) DO UPDATE SET todo_writes = EXCLUDED.todo_writes, reports = EXCLUDED.reports, max_todos = EXCLUDED.max_todos
+		`, id, l.TodoWrites, l.Reports, l.MaxTodos)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := audit(ctx, tx, actor, AuditSetUserLimits, id, l); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
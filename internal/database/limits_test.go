@@ -0,0 +1,63 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	todoconfig "github.com/sabbatD/srest-api/internal/lib/todoConfig"
+	u "github.com/sabbatD/srest-api/internal/lib/userConfig"
+)
+
+func TestUserLimits(t *testing.T) {
+	s := testStorage(t)
+	ctx := context.Background()
+
+	actor := testUser(t, s, "limitsadmin")
+	user := testUser(t, s, "limiteduser")
+
+	one, zero := 1, 0
+	if err := s.SetUserLimits(ctx, actor, user, u.Limits{MaxTodos: &one, TodoWrites: &zero}); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := s.UserLimits(ctx, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.MaxTodos == nil || *l.MaxTodos != 1 || l.TodoWrites == nil || *l.TodoWrites != 0 || l.Reports != nil {
+		t.Errorf("UserLimits() = %+v", l)
+	}
+	all, err := s.AllUserLimits(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, found := all[user]; !found {
+		t.Errorf("AllUserLimits() misses the user")
+	}
+
+	if _, err := s.Create(ctx, todoconfig.TodoRequest{Title: "one"}, user); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Create(ctx, todoconfig.TodoRequest{Title: "two"}, user); !errors.Is(err, ErrLimitReached) {
+		t.Errorf("todo over the override: err = %v, want ErrLimitReached", err)
+	}
+
+	// Clearing every override restores the defaults
+	if err := s.SetUserLimits(ctx, actor, user, u.Limits{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Create(ctx, todoconfig.TodoRequest{Title: "two"}, user); err != nil {
+		t.Errorf("todo after clearing the override: %v", err)
+	}
+
+	if err := s.SetUserLimits(ctx, actor, -1, u.Limits{}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("unknown user: err = %v, want ErrNotFound", err)
+	}
+
+	var audited int
+	s.db.QueryRow(`SELECT COUNT(*) FROM public.audit_log WHERE actor_id = $1 AND action = $2`, actor, AuditSetUserLimits).Scan(&audited)
+	if audited != 2 {
+		t.Errorf("audited %d changes, want 2", audited)
+	}
+}
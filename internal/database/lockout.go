@@ -0,0 +1,71 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// RecordFailedLogin records a failed sign in of login from ip at the given time and deletes the failures before expired
+func (s *Storage) RecordFailedLogin(ctx context.Context, login, ip string, at, expired time.Time) error {
+	const op = "database.postgres.RecordFailedLogin"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `INSERT INTO public.failed_logins (login, ip, failed_at) VALUES ($1, $2, $3)`, login, ip, at); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM public.failed_logins WHERE failed_at < $1`, expired); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// FailedLogins returns up to limit times of failed sign ins of login from ip since the given time, the latest first
+func (s *Storage) FailedLogins(ctx context.Context, login, ip string, since time.Time, limit int) ([]time.Time, error) {
+	const op = "database.postgres.FailedLogins"
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT failed_at FROM public.failed_logins
+		WHERE login = $1 AND ip = $2 AND failed_at > $3
+		ORDER BY failed_at DESC LIMIT $4
+	`, login, ip, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+	defer rows.Close()
+
+	var failures []time.Time
+	for rows.Next() {
+		var at time.Time
+		if err := rows.Scan(&at); err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+		failures = append(failures, at)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return failures, nil
+}
+
+// ClearFailedLogins forgets the failed sign ins of login from ip, after it signed in
+func (s *Storage) ClearFailedLogins(ctx context.Context, login, ip string) error {
+	const op = "database.postgres.ClearFailedLogins"
+
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM public.failed_logins WHERE login = $1 AND ip = $2`, login, ip); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,50 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestFailedLogins(t *testing.T) {
+	s := testStorage(t)
+	ctx := context.Background()
+
+	s.db.Exec(`DELETE FROM public.failed_logins WHERE login = 'bruteforced'`)
+	t.Cleanup(func() { s.db.Exec(`DELETE FROM public.failed_logins WHERE login = 'bruteforced'`) })
+
+	now := time.Now().Truncate(time.Second)
+	for _, at := range []time.Time{now.Add(-time.Hour), now.Add(-2 * time.Minute), now.Add(-time.Minute), now} {
+		if err := s.RecordFailedLogin(ctx, "bruteforced", "192.0.2.1", at, now.Add(-30*time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.RecordFailedLogin(ctx, "bruteforced", "192.0.2.2", now, now.Add(-30*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	failures, err := s.FailedLogins(ctx, "bruteforced", "192.0.2.1", now.Add(-15*time.Minute), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failures) != 2 || !failures[0].Equal(now) || !failures[1].Equal(now.Add(-time.Minute)) {
+		t.Errorf("FailedLogins() = %v, want the latest two", failures)
+	}
+
+	// The failure an hour ago was deleted by the later ones
+	var kept int
+	s.db.QueryRow(`SELECT COUNT(*) FROM public.failed_logins WHERE login = 'bruteforced' AND ip = '192.0.2.1'`).Scan(&kept)
+	if kept != 3 {
+		t.Errorf("kept %d failures, want 3", kept)
+	}
+
+	if err := s.ClearFailedLogins(ctx, "bruteforced", "192.0.2.1"); err != nil {
+		t.Fatal(err)
+	}
+	if failures, _ := s.FailedLogins(ctx, "bruteforced", "192.0.2.1", now.Add(-time.Hour), 5); len(failures) != 0 {
+		t.Errorf("failures after clearing = %v", failures)
+	}
+	if failures, _ := s.FailedLogins(ctx, "bruteforced", "192.0.2.2", now.Add(-time.Hour), 5); len(failures) != 1 {
+		t.Errorf("failures of the other address = %v, want 1", failures)
+	}
+}
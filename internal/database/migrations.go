@@ -0,0 +1,69 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/pressly/goose/v3"
+	"github.com/sabbatD/srest-api/internal/lib/support"
+)
+
+// MigrationStatus lists the migrations recorded by goose and those in MigrationsDir not applied yet.
+// Migrations are only run on start in the local environment, elsewhere pending ones are applied by hand.
+// A server started outside of the repository has no MigrationsDir, only the recorded ones are listed then.
+func (s *Storage) MigrationStatus(ctx context.Context) (support.MigrationStatus, error) {
+	const op = "database.postgres.MigrationStatus"
+
+	var status support.MigrationStatus
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT DISTINCT ON (version_id) version_id, is_applied, tstamp
+		FROM `+goose.TableName()+`
+		WHERE version_id > 0
+		ORDER BY version_id, id DESC
+	`)
+	if err != nil {
+		return status, fmt.Errorf("%s: %v", op, err)
+	}
+	defer rows.Close()
+
+	byVersion := make(map[int64]int)
+	for rows.Next() {
+		var mig support.Migration
+		var at sql.NullTime
+		if err := rows.Scan(&mig.Version, &mig.Applied, &at); err != nil {
+			return status, fmt.Errorf("%s: %v", op, err)
+		}
+		if mig.Applied {
+			mig.AppliedAt = &at.Time
+			status.Current = max(status.Current, mig.Version)
+		}
+		byVersion[mig.Version] = len(status.Migrations)
+		status.Migrations = append(status.Migrations, mig)
+	}
+	if err := rows.Err(); err != nil {
+		return status, fmt.Errorf("%s: %v", op, err)
+	}
+
+	if _, err := os.Stat(MigrationsDir); err == nil {
+		known, err := goose.CollectMigrations(MigrationsDir, 0, goose.MaxVersion)
+		if err != nil {
+			return status, fmt.Errorf("%s: %v", op, err)
+		}
+		for _, k := range known {
+			if i, ok := byVersion[k.Version]; ok {
+				status.Migrations[i].Name = filepath.Base(k.Source)
+				continue
+			}
+			status.Migrations = append(status.Migrations, support.Migration{Version: k.Version, Name: filepath.Base(k.Source)})
+		}
+	}
+
+	sort.Slice(status.Migrations, func(i, j int) bool { return status.Migrations[i].Version < status.Migrations[j].Version })
+
+	return status, nil
+}
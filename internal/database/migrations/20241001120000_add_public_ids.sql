@@ -0,0 +1,9 @@
+-- +goose Up
+CREATE EXTENSION IF NOT EXISTS "pgcrypto";
+
+ALTER TABLE public.users ADD COLUMN IF NOT EXISTS public_id UUID NOT NULL UNIQUE DEFAULT gen_random_uuid();
+ALTER TABLE public.todos ADD COLUMN IF NOT EXISTS public_id UUID NOT NULL UNIQUE DEFAULT gen_random_uuid();
+
+-- +goose Down
+ALTER TABLE public.todos DROP COLUMN IF EXISTS public_id;
+ALTER TABLE public.users DROP COLUMN IF EXISTS public_id;
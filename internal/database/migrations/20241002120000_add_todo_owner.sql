@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE public.todos ADD COLUMN IF NOT EXISTS user_id INT REFERENCES public.users (id) ON DELETE CASCADE;
+CREATE INDEX IF NOT EXISTS todos_user_id_idx ON public.todos (user_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS todos_user_id_idx;
+ALTER TABLE public.todos DROP COLUMN IF EXISTS user_id;
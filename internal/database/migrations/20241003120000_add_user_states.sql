@@ -0,0 +1,8 @@
+-- +goose Up
+-- is_verified defaults to TRUE so existing accounts stay active.
+ALTER TABLE public.users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+ALTER TABLE public.users ADD COLUMN IF NOT EXISTS is_verified BOOLEAN NOT NULL DEFAULT TRUE;
+
+-- +goose Down
+ALTER TABLE public.users DROP COLUMN IF EXISTS is_verified;
+ALTER TABLE public.users DROP COLUMN IF EXISTS deleted_at;
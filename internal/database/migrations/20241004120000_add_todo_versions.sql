@@ -0,0 +1,24 @@
+-- +goose Up
+-- Every write to a todo takes the next value of todos_version_seq,
+-- deletions leave a tombstone so clients can sync incrementally.
+CREATE SEQUENCE IF NOT EXISTS public.todos_version_seq;
+
+ALTER TABLE public.todos ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT nextval('public.todos_version_seq');
+ALTER TABLE public.todos ADD COLUMN IF NOT EXISTS created_version BIGINT;
+UPDATE public.todos SET created_version = version WHERE created_version IS NULL;
+CREATE INDEX IF NOT EXISTS todos_user_version_idx ON public.todos (user_id, version);
+
+CREATE TABLE IF NOT EXISTS public.todo_tombstones (
+    public_id UUID PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES public.users (id) ON DELETE CASCADE,
+    version BIGINT NOT NULL,
+    deleted_at TIMESTAMPTZ DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS todo_tombstones_user_version_idx ON public.todo_tombstones (user_id, version);
+
+-- +goose Down
+DROP TABLE IF EXISTS public.todo_tombstones;
+DROP INDEX IF EXISTS todos_user_version_idx;
+ALTER TABLE public.todos DROP COLUMN IF EXISTS created_version;
+ALTER TABLE public.todos DROP COLUMN IF EXISTS version;
+DROP SEQUENCE IF EXISTS public.todos_version_seq;
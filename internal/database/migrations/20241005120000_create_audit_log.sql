@@ -0,0 +1,14 @@
+-- +goose Up
+-- Admin actions on accounts, details hold the action specific payload.
+CREATE TABLE IF NOT EXISTS public.audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    actor_id INT REFERENCES public.users (id) ON DELETE SET NULL,
+    action TEXT NOT NULL,
+    target_id INT REFERENCES public.users (id) ON DELETE SET NULL,
+    details JSONB NOT NULL DEFAULT '{}',
+    created TIMESTAMPTZ DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS audit_log_target_idx ON public.audit_log (target_id, created);
+
+-- +goose Down
+DROP TABLE IF EXISTS public.audit_log;
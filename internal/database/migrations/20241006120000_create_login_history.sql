@@ -0,0 +1,14 @@
+-- +goose Up
+-- Previous logins of each user. A released login stays reserved for its former owner until released_until.
+CREATE TABLE IF NOT EXISTS public.login_history (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES public.users (id) ON DELETE CASCADE,
+    login TEXT NOT NULL,
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    released_until TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS login_history_user_idx ON public.login_history (user_id, changed_at);
+CREATE INDEX IF NOT EXISTS login_history_login_idx ON public.login_history (login);
+
+-- +goose Down
+DROP TABLE IF EXISTS public.login_history;
@@ -0,0 +1,22 @@
+-- +goose Up
+-- Abuse reports; target_id refers to the row of target_type, currently always a user.
+CREATE TABLE IF NOT EXISTS public.reports (
+    id SERIAL PRIMARY KEY,
+    public_id UUID NOT NULL UNIQUE DEFAULT gen_random_uuid(),
+    reporter_id INT REFERENCES public.users (id) ON DELETE SET NULL,
+    target_type TEXT NOT NULL,
+    target_id INT NOT NULL,
+    reason TEXT NOT NULL,
+    details TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'open',
+    resolution TEXT NOT NULL DEFAULT '',
+    resolved_by INT REFERENCES public.users (id) ON DELETE SET NULL,
+    created TIMESTAMPTZ DEFAULT NOW(),
+    resolved_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS reports_status_created_idx ON public.reports (status, created);
+-- One open report per reporter and target.
+CREATE UNIQUE INDEX IF NOT EXISTS reports_open_uniq ON public.reports (reporter_id, target_type, target_id) WHERE status = 'open';
+
+-- +goose Down
+DROP TABLE IF EXISTS public.reports;
@@ -0,0 +1,16 @@
+-- +goose Up
+-- Content accepted under the moderation flag action, for admin review.
+CREATE TABLE IF NOT EXISTS public.flagged_content (
+    id SERIAL PRIMARY KEY,
+    public_id UUID NOT NULL UNIQUE DEFAULT gen_random_uuid(),
+    kind TEXT NOT NULL,
+    user_id INT REFERENCES public.users (id) ON DELETE CASCADE,
+    ref UUID NOT NULL,
+    text TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    created TIMESTAMPTZ DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS flagged_content_kind_created_idx ON public.flagged_content (kind, created);
+
+-- +goose Down
+DROP TABLE IF EXISTS public.flagged_content;
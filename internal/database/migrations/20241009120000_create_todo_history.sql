@@ -0,0 +1,45 @@
+-- +goose Up
+-- Every state of every todo, written by a trigger so no write path can skip it.
+-- Existing todos are recorded as a snapshot, the history of a user starts there.
+CREATE TABLE IF NOT EXISTS public.todo_history (
+    id BIGSERIAL PRIMARY KEY,
+    public_id UUID NOT NULL,
+    user_id INT NOT NULL REFERENCES public.users (id) ON DELETE CASCADE,
+    op TEXT NOT NULL,
+    title TEXT,
+    is_done BOOLEAN NOT NULL,
+    created TIMESTAMPTZ,
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT clock_timestamp()
+);
+CREATE INDEX IF NOT EXISTS todo_history_user_changed_idx ON public.todo_history (user_id, changed_at);
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION public.record_todo_history() RETURNS trigger AS $$
+BEGIN
+    -- A todo moved to another user (account merge) is gone for the old owner.
+    IF TG_OP = 'DELETE' OR (TG_OP = 'UPDATE' AND OLD.user_id IS DISTINCT FROM NEW.user_id) THEN
+        IF OLD.user_id IS NOT NULL THEN
+            INSERT INTO public.todo_history (public_id, user_id, op, title, is_done, created)
+            VALUES (OLD.public_id, OLD.user_id, 'delete', OLD.title, OLD.is_done, OLD.created);
+        END IF;
+    END IF;
+    IF TG_OP <> 'DELETE' AND NEW.user_id IS NOT NULL THEN
+        INSERT INTO public.todo_history (public_id, user_id, op, title, is_done, created)
+        VALUES (NEW.public_id, NEW.user_id, lower(TG_OP), NEW.title, NEW.is_done, NEW.created);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+DROP TRIGGER IF EXISTS todos_history ON public.todos;
+CREATE TRIGGER todos_history AFTER INSERT OR UPDATE OR DELETE ON public.todos
+    FOR EACH ROW EXECUTE FUNCTION public.record_todo_history();
+
+INSERT INTO public.todo_history (public_id, user_id, op, title, is_done, created)
+SELECT public_id, user_id, 'snapshot', title, is_done, created FROM public.todos WHERE user_id IS NOT NULL;
+
+-- +goose Down
+DROP TRIGGER IF EXISTS todos_history ON public.todos;
+DROP FUNCTION IF EXISTS public.record_todo_history();
+DROP TABLE IF EXISTS public.todo_history;
@@ -0,0 +1,14 @@
+-- +goose Up
+-- Database backups, the dumps themselves are kept in the blob store under key.
+CREATE TABLE IF NOT EXISTS public.backups (
+    id SERIAL PRIMARY KEY,
+    key TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'running',
+    size BIGINT NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    started TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    finished TIMESTAMPTZ
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS public.backups;
@@ -0,0 +1,15 @@
+-- +goose Up
+-- Settings changed by admins at runtime, they override the configuration file.
+CREATE TABLE IF NOT EXISTS public.settings (
+    key TEXT PRIMARY KEY,
+    value JSONB NOT NULL,
+    updated_by INT REFERENCES public.users (id) ON DELETE SET NULL,
+    updated TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS audit_log_created_idx ON public.audit_log (created);
+CREATE INDEX IF NOT EXISTS users_deleted_at_idx ON public.users (deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS users_deleted_at_idx;
+DROP INDEX IF EXISTS audit_log_created_idx;
+DROP TABLE IF EXISTS public.settings;
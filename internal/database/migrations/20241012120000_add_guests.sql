@@ -0,0 +1,10 @@
+-- +goose Up
+-- Guests are users without credentials, todo_limit caps their todos (NULL is unlimited).
+ALTER TABLE public.users ADD COLUMN IF NOT EXISTS is_guest BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE public.users ADD COLUMN IF NOT EXISTS todo_limit INT;
+CREATE INDEX IF NOT EXISTS users_guest_date_idx ON public.users (date) WHERE is_guest;
+
+-- +goose Down
+DROP INDEX IF EXISTS users_guest_date_idx;
+ALTER TABLE public.users DROP COLUMN IF EXISTS todo_limit;
+ALTER TABLE public.users DROP COLUMN IF EXISTS is_guest;
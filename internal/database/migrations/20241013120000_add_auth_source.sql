@@ -0,0 +1,6 @@
+-- +goose Up
+-- Accounts provisioned by an external source such as LDAP have no local password.
+ALTER TABLE public.users ADD COLUMN IF NOT EXISTS auth_source TEXT NOT NULL DEFAULT 'local';
+
+-- +goose Down
+ALTER TABLE public.users DROP COLUMN IF EXISTS auth_source;
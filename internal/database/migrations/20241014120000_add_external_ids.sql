@@ -0,0 +1,8 @@
+-- +goose Up
+-- external_id is the identity provider's id of a user provisioned over SCIM.
+ALTER TABLE public.users ADD COLUMN IF NOT EXISTS external_id TEXT;
+CREATE UNIQUE INDEX IF NOT EXISTS users_external_id_idx ON public.users (external_id) WHERE external_id IS NOT NULL AND deleted_at IS NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS users_external_id_idx;
+ALTER TABLE public.users DROP COLUMN IF EXISTS external_id;
@@ -0,0 +1,9 @@
+-- +goose Up
+-- Values of the custom profile fields defined by admins, keyed by field key.
+-- The definitions are kept in settings under user_fields.
+ALTER TABLE public.users ADD COLUMN IF NOT EXISTS custom JSONB NOT NULL DEFAULT '{}';
+CREATE INDEX IF NOT EXISTS users_custom_idx ON public.users USING GIN (custom jsonb_path_ops);
+
+-- +goose Down
+DROP INDEX IF EXISTS users_custom_idx;
+ALTER TABLE public.users DROP COLUMN IF EXISTS custom;
@@ -0,0 +1,10 @@
+-- +goose Up
+-- Every user defines their own todo fields, todos hold the values keyed by field key.
+ALTER TABLE public.users ADD COLUMN IF NOT EXISTS todo_fields JSONB NOT NULL DEFAULT '{"fields": []}';
+ALTER TABLE public.todos ADD COLUMN IF NOT EXISTS custom JSONB NOT NULL DEFAULT '{}';
+CREATE INDEX IF NOT EXISTS todos_custom_idx ON public.todos USING GIN (custom jsonb_path_ops);
+
+-- +goose Down
+DROP INDEX IF EXISTS todos_custom_idx;
+ALTER TABLE public.todos DROP COLUMN IF EXISTS custom;
+ALTER TABLE public.users DROP COLUMN IF EXISTS todo_fields;
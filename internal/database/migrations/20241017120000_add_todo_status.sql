@@ -0,0 +1,60 @@
+-- +goose Up
+-- Todos move through the statuses of their owner's workflow, NULL is the default workflow.
+-- is_done is kept in step with the status for old clients and the counters.
+ALTER TABLE public.users ADD COLUMN IF NOT EXISTS todo_workflow JSONB;
+ALTER TABLE public.todos ADD COLUMN IF NOT EXISTS status TEXT;
+ALTER TABLE public.todo_history ADD COLUMN IF NOT EXISTS status TEXT;
+
+-- The backfill is not a change of the todos, it stays out of their history.
+ALTER TABLE public.todos DISABLE TRIGGER todos_history;
+UPDATE public.todos SET status = CASE WHEN is_done THEN 'done' ELSE 'backlog' END WHERE status IS NULL;
+ALTER TABLE public.todos ENABLE TRIGGER todos_history;
+UPDATE public.todo_history SET status = CASE WHEN is_done THEN 'done' ELSE 'backlog' END WHERE status IS NULL;
+
+ALTER TABLE public.todos ALTER COLUMN status SET DEFAULT 'backlog';
+ALTER TABLE public.todos ALTER COLUMN status SET NOT NULL;
+CREATE INDEX IF NOT EXISTS todos_user_status_idx ON public.todos (user_id, status);
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION public.record_todo_history() RETURNS trigger AS $$
+BEGIN
+    -- A todo moved to another user (account merge) is gone for the old owner.
+    IF TG_OP = 'DELETE' OR (TG_OP = 'UPDATE' AND OLD.user_id IS DISTINCT FROM NEW.user_id) THEN
+        IF OLD.user_id IS NOT NULL THEN
+            INSERT INTO public.todo_history (public_id, user_id, op, title, is_done, status, created)
+            VALUES (OLD.public_id, OLD.user_id, 'delete', OLD.title, OLD.is_done, OLD.status, OLD.created);
+        END IF;
+    END IF;
+    IF TG_OP <> 'DELETE' AND NEW.user_id IS NOT NULL THEN
+        INSERT INTO public.todo_history (public_id, user_id, op, title, is_done, status, created)
+        VALUES (NEW.public_id, NEW.user_id, lower(TG_OP), NEW.title, NEW.is_done, NEW.status, NEW.created);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION public.record_todo_history() RETURNS trigger AS $$
+BEGIN
+    -- A todo moved to another user (account merge) is gone for the old owner.
+    IF TG_OP = 'DELETE' OR (TG_OP = 'UPDATE' AND OLD.user_id IS DISTINCT FROM NEW.user_id) THEN
+        IF OLD.user_id IS NOT NULL THEN
+            INSERT INTO public.todo_history (public_id, user_id, op, title, is_done, created)
+            VALUES (OLD.public_id, OLD.user_id, 'delete', OLD.title, OLD.is_done, OLD.created);
+        END IF;
+    END IF;
+    IF TG_OP <> 'DELETE' AND NEW.user_id IS NOT NULL THEN
+        INSERT INTO public.todo_history (public_id, user_id, op, title, is_done, created)
+        VALUES (NEW.public_id, NEW.user_id, lower(TG_OP), NEW.title, NEW.is_done, NEW.created);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+DROP INDEX IF EXISTS todos_user_status_idx;
+ALTER TABLE public.todo_history DROP COLUMN IF EXISTS status;
+ALTER TABLE public.todos DROP COLUMN IF EXISTS status;
+ALTER TABLE public.users DROP COLUMN IF EXISTS todo_workflow;
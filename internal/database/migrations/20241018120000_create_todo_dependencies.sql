@@ -0,0 +1,13 @@
+-- +goose Up
+-- todo_id is blocked by blocked_by, both belong to the same user. The graph is kept acyclic by the storage.
+CREATE TABLE IF NOT EXISTS public.todo_dependencies (
+    todo_id INT NOT NULL REFERENCES public.todos (id) ON DELETE CASCADE,
+    blocked_by INT NOT NULL REFERENCES public.todos (id) ON DELETE CASCADE,
+    created TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (todo_id, blocked_by),
+    CHECK (todo_id <> blocked_by)
+);
+CREATE INDEX IF NOT EXISTS todo_dependencies_blocked_by_idx ON public.todo_dependencies (blocked_by);
+
+-- +goose Down
+DROP TABLE IF EXISTS public.todo_dependencies;
@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE public.todos ADD COLUMN IF NOT EXISTS due DATE;
+CREATE INDEX IF NOT EXISTS todos_user_due_idx ON public.todos (user_id, due) WHERE due IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS todos_user_due_idx;
+ALTER TABLE public.todos DROP COLUMN IF EXISTS due;
@@ -0,0 +1,14 @@
+-- +goose Up
+-- query is a snapshot of the GET /todos query parameters, validated by the handler when saved.
+CREATE TABLE IF NOT EXISTS public.todo_filters (
+    id SERIAL PRIMARY KEY,
+    public_id UUID NOT NULL UNIQUE DEFAULT gen_random_uuid(),
+    user_id INT NOT NULL REFERENCES public.users (id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    query JSONB NOT NULL DEFAULT '{}',
+    created TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS todo_filters_user_name_idx ON public.todo_filters (user_id, lower(name));
+
+-- +goose Down
+DROP TABLE IF EXISTS public.todo_filters;
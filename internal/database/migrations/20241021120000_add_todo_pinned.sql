@@ -0,0 +1,8 @@
+-- +goose Up
+-- A task is pinned while pinned_at is set, pinned tasks are listed first.
+ALTER TABLE public.todos ADD COLUMN IF NOT EXISTS pinned_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS todos_user_pinned_idx ON public.todos (user_id) WHERE pinned_at IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS todos_user_pinned_idx;
+ALTER TABLE public.todos DROP COLUMN IF EXISTS pinned_at;
@@ -0,0 +1,11 @@
+-- +goose Up
+-- Single-use password reset tokens, only their SHA-256 hash is stored. A user has at most one.
+CREATE TABLE IF NOT EXISTS public.password_resets (
+    user_id INT PRIMARY KEY REFERENCES public.users (id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL UNIQUE,
+    expires TIMESTAMPTZ NOT NULL,
+    created TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS public.password_resets;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- Users flagged by an admin or the password expiry policy may only change their password until they do.
+ALTER TABLE public.users ADD COLUMN IF NOT EXISTS must_change_password BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +goose Down
+ALTER TABLE public.users DROP COLUMN IF EXISTS must_change_password;
@@ -0,0 +1,9 @@
+-- +goose Up
+-- The password expiry policy counts from password_changed, existing passwords count from the migration.
+-- password_warned is set once the user was emailed about the coming expiry.
+ALTER TABLE public.users ADD COLUMN IF NOT EXISTS password_changed TIMESTAMPTZ NOT NULL DEFAULT NOW();
+ALTER TABLE public.users ADD COLUMN IF NOT EXISTS password_warned TIMESTAMPTZ;
+
+-- +goose Down
+ALTER TABLE public.users DROP COLUMN IF EXISTS password_warned;
+ALTER TABLE public.users DROP COLUMN IF EXISTS password_changed;
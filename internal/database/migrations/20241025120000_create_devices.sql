@@ -0,0 +1,18 @@
+-- +goose Up
+-- Remembered devices keep a longer-lived refresh token, usable only together with the device cookie.
+-- Only the SHA-256 hashes of both are stored, the refresh token rotates on every use.
+CREATE TABLE IF NOT EXISTS public.devices (
+    id SERIAL PRIMARY KEY,
+    public_id UUID NOT NULL UNIQUE DEFAULT gen_random_uuid(),
+    user_id INT NOT NULL REFERENCES public.users (id) ON DELETE CASCADE,
+    name TEXT NOT NULL DEFAULT '',
+    token_hash TEXT NOT NULL UNIQUE,
+    device_hash TEXT NOT NULL,
+    expires TIMESTAMPTZ NOT NULL,
+    last_used TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    created TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS devices_user_idx ON public.devices (user_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS public.devices;
@@ -0,0 +1,32 @@
+-- +goose Up
+-- Versions of a user's todos are taken under a lock on the user held until commit, so they grow in commit order:
+-- a transaction committing late cannot hold a lower version than one a reader of the changes feed already got past.
+-- The version written by a statement is replaced with one taken under the lock.
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION public.assign_todo_version() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'UPDATE' AND NEW.version IS NOT DISTINCT FROM OLD.version THEN
+        RETURN NEW;
+    END IF;
+    PERFORM pg_advisory_xact_lock('public.todos'::regclass::oid::int, NEW.user_id);
+    NEW.version := nextval('public.todos_version_seq');
+    IF TG_TABLE_NAME = 'todos' AND TG_OP = 'INSERT' THEN
+        NEW.created_version := NEW.version;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+DROP TRIGGER IF EXISTS todos_version ON public.todos;
+CREATE TRIGGER todos_version BEFORE INSERT OR UPDATE ON public.todos
+    FOR EACH ROW WHEN (NEW.user_id IS NOT NULL) EXECUTE FUNCTION public.assign_todo_version();
+
+DROP TRIGGER IF EXISTS todo_tombstones_version ON public.todo_tombstones;
+CREATE TRIGGER todo_tombstones_version BEFORE INSERT OR UPDATE ON public.todo_tombstones
+    FOR EACH ROW EXECUTE FUNCTION public.assign_todo_version();
+
+-- +goose Down
+DROP TRIGGER IF EXISTS todo_tombstones_version ON public.todo_tombstones;
+DROP TRIGGER IF EXISTS todos_version ON public.todos;
+DROP FUNCTION IF EXISTS public.assign_todo_version();
@@ -0,0 +1,89 @@
+-- +goose Up
+-- History keeps every restorable part of a todo: custom field values, the due date, the pin and the ids of
+-- the todos blocking it. Dependencies live in their own table, changing them records the todo's new state.
+-- Rows recorded before have NULL custom, restores leave those parts of such todos as they are.
+ALTER TABLE public.todo_history ADD COLUMN IF NOT EXISTS custom JSONB;
+ALTER TABLE public.todo_history ADD COLUMN IF NOT EXISTS due DATE;
+ALTER TABLE public.todo_history ADD COLUMN IF NOT EXISTS pinned_at TIMESTAMPTZ;
+ALTER TABLE public.todo_history ADD COLUMN IF NOT EXISTS blocked_by UUID[];
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION public.todo_blockers(todo INT) RETURNS UUID[] AS $$
+    SELECT COALESCE(array_agg(b.public_id ORDER BY b.public_id), '{}')
+    FROM public.todo_dependencies d JOIN public.todos b ON b.id = d.blocked_by
+    WHERE d.todo_id = todo;
+$$ LANGUAGE sql STABLE;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION public.record_todo_history() RETURNS trigger AS $$
+BEGIN
+    -- A todo moved to another user (account merge) is gone for the old owner.
+    IF TG_OP = 'DELETE' OR (TG_OP = 'UPDATE' AND OLD.user_id IS DISTINCT FROM NEW.user_id) THEN
+        IF OLD.user_id IS NOT NULL THEN
+            INSERT INTO public.todo_history (public_id, user_id, op, title, is_done, status, created, custom, due, pinned_at, blocked_by)
+            VALUES (OLD.public_id, OLD.user_id, 'delete', OLD.title, OLD.is_done, OLD.status, OLD.created,
+                OLD.custom, OLD.due, OLD.pinned_at, '{}');
+        END IF;
+    END IF;
+    IF TG_OP <> 'DELETE' AND NEW.user_id IS NOT NULL THEN
+        INSERT INTO public.todo_history (public_id, user_id, op, title, is_done, status, created, custom, due, pinned_at, blocked_by)
+        VALUES (NEW.public_id, NEW.user_id, lower(TG_OP), NEW.title, NEW.is_done, NEW.status, NEW.created,
+            NEW.custom, NEW.due, NEW.pinned_at, public.todo_blockers(NEW.id));
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION public.record_dependency_history() RETURNS trigger AS $$
+DECLARE
+    t public.todos%ROWTYPE;
+BEGIN
+    -- A dependency deleted along with its todo leaves no state to record.
+    SELECT * INTO t FROM public.todos
+    WHERE id = CASE WHEN TG_OP = 'DELETE' THEN OLD.todo_id ELSE NEW.todo_id END;
+    IF NOT FOUND OR t.user_id IS NULL THEN
+        RETURN NULL;
+    END IF;
+    INSERT INTO public.todo_history (public_id, user_id, op, title, is_done, status, created, custom, due, pinned_at, blocked_by)
+    VALUES (t.public_id, t.user_id, 'update', t.title, t.is_done, t.status, t.created,
+        t.custom, t.due, t.pinned_at, public.todo_blockers(t.id));
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+DROP TRIGGER IF EXISTS todo_dependencies_history ON public.todo_dependencies;
+CREATE TRIGGER todo_dependencies_history AFTER INSERT OR DELETE ON public.todo_dependencies
+    FOR EACH ROW EXECUTE FUNCTION public.record_dependency_history();
+
+-- +goose Down
+DROP TRIGGER IF EXISTS todo_dependencies_history ON public.todo_dependencies;
+DROP FUNCTION IF EXISTS public.record_dependency_history();
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION public.record_todo_history() RETURNS trigger AS $$
+BEGIN
+    -- A todo moved to another user (account merge) is gone for the old owner.
+    IF TG_OP = 'DELETE' OR (TG_OP = 'UPDATE' AND OLD.user_id IS DISTINCT FROM NEW.user_id) THEN
+        IF OLD.user_id IS NOT NULL THEN
+            INSERT INTO public.todo_history (public_id, user_id, op, title, is_done, status, created)
+            VALUES (OLD.public_id, OLD.user_id, 'delete', OLD.title, OLD.is_done, OLD.status, OLD.created);
+        END IF;
+    END IF;
+    IF TG_OP <> 'DELETE' AND NEW.user_id IS NOT NULL THEN
+        INSERT INTO public.todo_history (public_id, user_id, op, title, is_done, status, created)
+        VALUES (NEW.public_id, NEW.user_id, lower(TG_OP), NEW.title, NEW.is_done, NEW.status, NEW.created);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+DROP FUNCTION IF EXISTS public.todo_blockers(INT);
+ALTER TABLE public.todo_history DROP COLUMN IF EXISTS blocked_by;
+ALTER TABLE public.todo_history DROP COLUMN IF EXISTS pinned_at;
+ALTER TABLE public.todo_history DROP COLUMN IF EXISTS due;
+ALTER TABLE public.todo_history DROP COLUMN IF EXISTS custom;
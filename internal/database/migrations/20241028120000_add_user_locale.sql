@@ -0,0 +1,6 @@
+-- +goose Up
+-- The locale emails to the user are rendered in, a BCP 47 language tag. Empty is the default locale.
+ALTER TABLE public.users ADD COLUMN IF NOT EXISTS locale TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE public.users DROP COLUMN IF EXISTS locale;
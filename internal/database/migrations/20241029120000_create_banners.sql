@@ -0,0 +1,18 @@
+-- +goose Up
+-- Broadcast banners clients show, e.g. maintenance warnings. A banner shows from starts until ends,
+-- without ends until it is deleted. audience is all, users, guests or admins.
+CREATE TABLE IF NOT EXISTS public.banners (
+    id SERIAL PRIMARY KEY,
+    public_id UUID NOT NULL UNIQUE DEFAULT gen_random_uuid(),
+    message TEXT NOT NULL,
+    severity TEXT NOT NULL,
+    audience TEXT NOT NULL DEFAULT 'all',
+    starts TIMESTAMPTZ NOT NULL,
+    ends TIMESTAMPTZ,
+    created_by INT REFERENCES public.users (id) ON DELETE SET NULL,
+    created TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS banners_starts_idx ON public.banners (starts);
+
+-- +goose Down
+DROP TABLE IF EXISTS public.banners;
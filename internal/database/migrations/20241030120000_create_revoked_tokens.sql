@@ -0,0 +1,10 @@
+-- +goose Up
+-- Access tokens revoked before they expire, by JTI. Rows are only needed until the token expires,
+-- logouts delete the expired ones.
+CREATE TABLE IF NOT EXISTS public.revoked_tokens (
+    jti TEXT PRIMARY KEY,
+    expires TIMESTAMPTZ NOT NULL
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS public.revoked_tokens;
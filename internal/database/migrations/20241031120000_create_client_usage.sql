@@ -0,0 +1,15 @@
+-- +goose Up
+-- Requests by day (UTC), client and route, see lib/clients
+CREATE TABLE IF NOT EXISTS public.client_usage (
+    day DATE NOT NULL,
+    client TEXT NOT NULL,
+    route TEXT NOT NULL,
+    requests BIGINT NOT NULL,
+    last_seen TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (day, client, route)
+);
+
+CREATE INDEX IF NOT EXISTS client_usage_route_idx ON public.client_usage (route, day);
+
+-- +goose Down
+DROP TABLE IF EXISTS public.client_usage;
@@ -0,0 +1,12 @@
+-- +goose Up
+-- JWT signing keys created by rotations, see access.Rotator. The active key has no retired time.
+CREATE TABLE IF NOT EXISTS public.jwt_keys (
+    kid TEXT PRIMARY KEY,
+    algorithm TEXT NOT NULL,
+    key TEXT NOT NULL,
+    created TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    retired TIMESTAMPTZ
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS public.jwt_keys;
@@ -0,0 +1,12 @@
+-- +goose Up
+-- Per-user overrides of the configured limits, a NULL column keeps the default.
+-- max_todos takes precedence over users.todo_limit.
+CREATE TABLE IF NOT EXISTS public.user_limits (
+    user_id INT PRIMARY KEY REFERENCES public.users (id) ON DELETE CASCADE,
+    todo_writes INT,
+    reports INT,
+    max_todos INT
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS public.user_limits;
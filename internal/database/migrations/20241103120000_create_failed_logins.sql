@@ -0,0 +1,13 @@
+-- +goose Up
+-- Failed sign ins by login and client address, see lockout.Guard. Rows are only needed for the lockout
+-- window, recording a failure deletes the older ones.
+CREATE TABLE IF NOT EXISTS public.failed_logins (
+    login TEXT NOT NULL,
+    ip TEXT NOT NULL,
+    failed_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS failed_logins_login_ip_idx ON public.failed_logins (login, ip, failed_at);
+CREATE INDEX IF NOT EXISTS failed_logins_failed_at_idx ON public.failed_logins (failed_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS public.failed_logins;
@@ -0,0 +1,12 @@
+-- +goose Up
+-- Email verification tokens by user, only their SHA-256 hash is stored. Accounts signed up while email
+-- is configured stay unverified (is_verified FALSE) until the token is used.
+CREATE TABLE IF NOT EXISTS public.email_verifications (
+    user_id INT PRIMARY KEY REFERENCES public.users (id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL UNIQUE,
+    expires TIMESTAMPTZ NOT NULL,
+    created TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS public.email_verifications;
@@ -0,0 +1,28 @@
+-- +goose Up
+-- Clients (relying parties) of the OpenID Connect provider, registered by admins. Only the hash of the client secret
+-- is kept, codes are only sent to one of redirect_uris.
+CREATE TABLE IF NOT EXISTS public.oidc_clients (
+    id SERIAL PRIMARY KEY,
+    client_id UUID NOT NULL UNIQUE DEFAULT gen_random_uuid(),
+    name TEXT NOT NULL,
+    secret_hash TEXT NOT NULL,
+    redirect_uris TEXT[] NOT NULL,
+    created_by INT REFERENCES public.users (id) ON DELETE SET NULL,
+    created TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Single-use authorization codes by the hash of the code
+CREATE TABLE IF NOT EXISTS public.oidc_codes (
+    code_hash TEXT PRIMARY KEY,
+    client_id INT NOT NULL REFERENCES public.oidc_clients (id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES public.users (id) ON DELETE CASCADE,
+    redirect_uri TEXT NOT NULL,
+    scope TEXT NOT NULL,
+    nonce TEXT NOT NULL DEFAULT '',
+    code_challenge TEXT NOT NULL DEFAULT '',
+    expires TIMESTAMPTZ NOT NULL
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS public.oidc_codes;
+DROP TABLE IF EXISTS public.oidc_clients;
@@ -0,0 +1,13 @@
+-- +goose Up
+-- Scopes each user granted each OpenID Connect client on the consent screen. Client tokens are only honored
+-- while the grant covers their scope, deleting it revokes the client's access.
+CREATE TABLE IF NOT EXISTS public.oidc_consents (
+    user_id INT NOT NULL REFERENCES public.users (id) ON DELETE CASCADE,
+    client_id INT NOT NULL REFERENCES public.oidc_clients (id) ON DELETE CASCADE,
+    scopes TEXT[] NOT NULL,
+    granted TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, client_id)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS public.oidc_consents;
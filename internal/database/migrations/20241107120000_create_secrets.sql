@@ -0,0 +1,12 @@
+-- +goose Up
+-- Integration credentials set by admins, sealed with the master key of the process (see package secrets).
+-- The database only ever holds the ciphertext.
+CREATE TABLE IF NOT EXISTS public.secrets (
+    name TEXT PRIMARY KEY,
+    sealed BYTEA NOT NULL,
+    updated_by INT REFERENCES public.users (id) ON DELETE SET NULL,
+    updated TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS public.secrets;
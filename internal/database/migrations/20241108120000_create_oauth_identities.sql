@@ -0,0 +1,15 @@
+-- +goose Up
+-- Accounts at Google or GitHub users sign in with, linked to their local account.
+CREATE TABLE IF NOT EXISTS public.oauth_identities (
+    provider TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    user_id INT NOT NULL REFERENCES public.users (id) ON DELETE CASCADE,
+    email TEXT NOT NULL DEFAULT '',
+    linked TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_used TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (provider, subject)
+);
+CREATE INDEX IF NOT EXISTS oauth_identities_user_idx ON public.oauth_identities (user_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS public.oauth_identities;
@@ -0,0 +1,15 @@
+-- +goose Up
+-- Successful sign ins of users, located by the GeoIP database when one is configured.
+CREATE TABLE IF NOT EXISTS public.sign_ins (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES public.users (id) ON DELETE CASCADE,
+    method TEXT NOT NULL,
+    ip TEXT NOT NULL DEFAULT '',
+    country TEXT NOT NULL DEFAULT '',
+    city TEXT NOT NULL DEFAULT '',
+    at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS sign_ins_user_idx ON public.sign_ins (user_id, at DESC);
+
+-- +goose Down
+DROP TABLE IF EXISTS public.sign_ins;
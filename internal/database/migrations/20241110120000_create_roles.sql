@@ -0,0 +1,38 @@
+-- +goose Up
+-- Roles replace the admin flag of users. The admin role has every permission, the others the ones mapped to them.
+CREATE TABLE IF NOT EXISTS public.roles (
+    name TEXT PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT '',
+    builtin BOOLEAN NOT NULL DEFAULT FALSE,
+    created TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE TABLE IF NOT EXISTS public.role_permissions (
+    role TEXT NOT NULL REFERENCES public.roles (name) ON DELETE CASCADE,
+    permission TEXT NOT NULL,
+    PRIMARY KEY (role, permission)
+);
+
+INSERT INTO public.roles (name, description, builtin) VALUES
+    ('admin', 'Every permission', TRUE),
+    ('moderator', 'Reviews reports and flagged content, blocks users', TRUE),
+    ('user', 'No admin permissions', TRUE)
+ON CONFLICT (name) DO NOTHING;
+INSERT INTO public.role_permissions (role, permission) VALUES
+    ('moderator', 'users:read'),
+    ('moderator', 'users:block'),
+    ('moderator', 'moderation:read'),
+    ('moderator', 'moderation:write')
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE public.users ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'user' REFERENCES public.roles (name);
+UPDATE public.users SET role = 'admin' WHERE is_admin;
+ALTER TABLE public.users DROP COLUMN IF EXISTS is_admin;
+CREATE INDEX IF NOT EXISTS users_role_idx ON public.users (role);
+
+-- +goose Down
+ALTER TABLE public.users ADD COLUMN IF NOT EXISTS is_admin BOOLEAN NOT NULL DEFAULT FALSE;
+UPDATE public.users SET is_admin = TRUE WHERE role = 'admin';
+DROP INDEX IF EXISTS users_role_idx;
+ALTER TABLE public.users DROP COLUMN IF EXISTS role;
+DROP TABLE IF EXISTS public.role_permissions;
+DROP TABLE IF EXISTS public.roles;
@@ -0,0 +1,26 @@
+-- +goose Up
+-- The User-Agent of sign ins tells new devices apart; sign ins recorded before it are left empty.
+ALTER TABLE public.sign_ins ADD COLUMN IF NOT EXISTS user_agent TEXT NOT NULL DEFAULT '';
+
+-- Sign ins from a new country or device waiting for the user to confirm them by email.
+-- The client polls with the session token, the emailed link carries the confirmation token.
+CREATE TABLE IF NOT EXISTS public.pending_sign_ins (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES public.users (id) ON DELETE CASCADE,
+    session_hash TEXT NOT NULL UNIQUE,
+    token_hash TEXT NOT NULL UNIQUE,
+    method TEXT NOT NULL,
+    remember_me BOOLEAN NOT NULL DEFAULT FALSE,
+    ip TEXT NOT NULL DEFAULT '',
+    country TEXT NOT NULL DEFAULT '',
+    city TEXT NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    confirmed BOOLEAN NOT NULL DEFAULT FALSE,
+    expires TIMESTAMPTZ NOT NULL,
+    created TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS pending_sign_ins_expires_idx ON public.pending_sign_ins (expires);
+
+-- +goose Down
+DROP TABLE IF EXISTS public.pending_sign_ins;
+ALTER TABLE public.sign_ins DROP COLUMN IF EXISTS user_agent;
@@ -0,0 +1,15 @@
+-- +goose Up
+-- Background jobs that failed every attempt, kept until an admin requeues or deletes them.
+-- Jobs are queued in memory, only the dead letters are stored so every instance lists them.
+CREATE TABLE IF NOT EXISTS public.dead_jobs (
+    id BIGSERIAL PRIMARY KEY,
+    queue TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    attempts INT NOT NULL,
+    error TEXT NOT NULL DEFAULT '',
+    failed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS public.dead_jobs;
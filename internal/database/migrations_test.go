@@ -0,0 +1,23 @@
+package database
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMigrationStatus(tt *testing.T) {
+	s := testStorage(tt)
+
+	status, err := s.MigrationStatus(context.Background())
+	if err != nil {
+		tt.Fatal(err)
+	}
+	if len(status.Migrations) == 0 {
+		tt.Fatal("no migrations recorded")
+	}
+
+	last := status.Migrations[len(status.Migrations)-1]
+	if !last.Applied || last.AppliedAt == nil || status.Current != last.Version {
+		tt.Errorf("current = %d, last = %+v", status.Current, last)
+	}
+}
@@ -0,0 +1,60 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sabbatD/srest-api/internal/lib/moderation"
+)
+
+func (s *Storage) FlagContent(ctx context.Context, f moderation.Flag) error {
+	const op = "database.postgres.FlagContent"
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO public.flagged_content (kind, user_id, ref, text, reason)
+		VALUES ($1, $2, $3, $4, $5)
+	`, f.Kind, f.UserID, f.Ref, f.Text, f.Reason)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// FlaggedContent lists flagged content of the kind, every kind for an empty one, newest first
+func (s *Storage) FlaggedContent(ctx context.Context, kind string, limit, offset int) (moderation.FlagsResponse, error) {
+	const op = "database.postgres.FlaggedContent"
+
+	result := moderation.FlagsResponse{Data: []moderation.Flag{}, Meta: moderation.FlagsMeta{Kind: kind}}
+
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM public.flagged_content WHERE $1 = '' OR kind = $1`, kind).Scan(&result.Meta.TotalAmount)
+	if err != nil {
+		return result, fmt.Errorf("%s: %v", op, err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT f.public_id, f.kind, u.public_id, f.ref, f.text, f.reason, f.created
+		FROM public.flagged_content f
+		JOIN public.users u ON u.id = f.user_id
+		WHERE $1 = '' OR f.kind = $1
+		ORDER BY f.created DESC, f.id DESC
+		LIMIT $2 OFFSET $3
+	`, kind, limit, offset)
+	if err != nil {
+		return result, fmt.Errorf("%s: %v", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var f moderation.Flag
+		if err := rows.Scan(&f.PublicID, &f.Kind, &f.User, &f.Ref, &f.Text, &f.Reason, &f.Created); err != nil {
+			return result, fmt.Errorf("%s: %v", op, err)
+		}
+		result.Data = append(result.Data, f)
+	}
+	if err := rows.Err(); err != nil {
+		return result, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,87 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/sabbatD/srest-api/internal/lib/oauth"
+	u "github.com/sabbatD/srest-api/internal/lib/userConfig"
+)
+
+// OAuthUser returns the local account of a user signed in with a provider. On the first sign in the identity is linked
+// to the account with its email when the provider and sAPI both verified it, otherwise an account without a password
+// is created with the login <provider>-<subject>. An email of an account it cannot be linked to is ErrAlreadyExists.
+func (s *Storage) OAuthUser(ctx context.Context, id oauth.Identity) (user u.TableUser, err error) {
+	const op = "database.postgres.OAuthUser"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return user, fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	var userID int
+	err = tx.QueryRowContext(ctx, `
+		SELECT i.user_id FROM public.oauth_identities i JOIN public.users u ON u.id = i.user_id
+		WHERE i.provider = $1 AND i.subject = $2 AND u.deleted_at IS NULL
+	`, id.Provider, id.Subject).Scan(&userID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return user, fmt.Errorf("%s: %v", op, err)
+	}
+
+	if userID == 0 && id.EmailVerified {
+		err = tx.QueryRowContext(ctx, `
+			SELECT id FROM public.users WHERE email = $1 AND is_verified AND NOT is_guest AND deleted_at IS NULL
+		`, id.Email).Scan(&userID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return user, fmt.Errorf("%s: %v", op, err)
+		}
+		if userID != 0 {
+			if err := audit(ctx, tx, userID, AuditLinkIdentity, userID, map[string]string{"provider": id.Provider}); err != nil {
+				return user, fmt.Errorf("%s: %v", op, err)
+			}
+		}
+	}
+
+	if userID == 0 {
+		err = tx.QueryRowContext(ctx, `
+			INSERT INTO public.users (login, username, email, is_verified, auth_source) VALUES ($1, $2, $3, $4, $5)
+			RETURNING id
+		`, id.Provider+"-"+id.Subject, id.Username, id.Email, id.EmailVerified, id.Provider).Scan(&userID)
+		if err != nil {
+			if pgErr, ok := err.(*pq.Error); ok && pgErr.Code == "23505" {
+				return user, fmt.Errorf("%s: email %w", op, ErrAlreadyExists)
+			}
+			return user, fmt.Errorf("%s: %v", op, err)
+		}
+		if err := audit(ctx, tx, userID, AuditProvisionUser, userID, map[string]any{"source": id.Provider}); err != nil {
+			return user, fmt.Errorf("%s: %v", op, err)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO public.oauth_identities (provider, subject, user_id, email) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (provider, subject) DO UPDATE SET user_id = EXCLUDED.user_id, email = EXCLUDED.email, last_used = NOW()
+	`, id.Provider, id.Subject, userID, id.Email)
+	if err != nil {
+		return user, fmt.Errorf("%s: %v", op, err)
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		SELECT id, public_id, username, email, locale, date, is_blocked, must_change_password, is_verified, `+roleColumns+` FROM public.users WHERE id = $1
+	`, userID).Scan(&user.ID, &user.PublicID, &user.Username, &user.Email, &user.Locale, &user.Date, &user.IsBlocked, &user.MustChangePassword, &user.IsVerified, &user.Role, pq.Array(&user.Permissions))
+	if err != nil {
+		return user, fmt.Errorf("%s: %v", op, err)
+	}
+	grant(&user)
+	disarm(&user)
+
+	if err := tx.Commit(); err != nil {
+		return u.TableUser{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return user, nil
+}
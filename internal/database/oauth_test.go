@@ -0,0 +1,49 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sabbatD/srest-api/internal/lib/oauth"
+)
+
+func TestOAuthUser(t *testing.T) {
+	s := testStorage(t)
+	ctx := context.Background()
+
+	s.db.Exec(`DELETE FROM public.users WHERE login = 'github-4242'`)
+	id := oauth.Identity{Provider: oauth.GitHub, Subject: "4242", Username: "octocat", Email: "octocat@example.com", EmailVerified: true}
+
+	user, err := s.OAuthUser(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.db.Exec(`DELETE FROM public.users WHERE id = $1`, user.ID) })
+	if user.Username != "octocat" || !user.IsVerified {
+		t.Errorf("created user = %+v", user)
+	}
+
+	// The identity stays linked when the email changes at the provider.
+	id.Email = "octocat@other.example.com"
+	again, err := s.OAuthUser(ctx, id)
+	if err != nil || again.ID != user.ID {
+		t.Errorf("second sign in = %+v, %v, want user %d", again, err, user.ID)
+	}
+
+	// A verified local account is linked by its email.
+	local := testUser(t, s, "oauthlocal")
+	s.db.Exec(`UPDATE public.users SET is_verified = TRUE WHERE id = $1`, local)
+	google := oauth.Identity{Provider: oauth.Google, Subject: "g-1", Username: "Local", Email: "oauthlocal@example.com", EmailVerified: true}
+	linked, err := s.OAuthUser(ctx, google)
+	if err != nil || linked.ID != local {
+		t.Errorf("verified email = %+v, %v, want the local account %d", linked, err, local)
+	}
+
+	// An email the provider did not verify is not enough to take over an account.
+	testUser(t, s, "oauthunverified")
+	google = oauth.Identity{Provider: oauth.Google, Subject: "g-2", Username: "Other", Email: "oauthunverified@example.com"}
+	if _, err := s.OAuthUser(ctx, google); !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("unverified email of an account: err = %v, want ErrAlreadyExists", err)
+	}
+}
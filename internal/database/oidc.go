@@ -0,0 +1,266 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/sabbatD/srest-api/internal/lib/clock"
+	oc "github.com/sabbatD/srest-api/internal/lib/oidcConfig"
+)
+
+const clientColumns = `id, client_id, name, redirect_uris, created, secret_hash`
+
+func scanClient(row scanner) (c oc.Client, err error) {
+	err = row.Scan(&c.ID, &c.ClientID, &c.Name, pq.Array(&c.RedirectURIs), &c.Created, &c.SecretHash)
+	return c, err
+}
+
+// CreateOIDCClient registers an OpenID Connect client with the hash of its secret and records the registration
+// by actor in the audit log
+func (s *Storage) CreateOIDCClient(ctx context.Context, actor int, c oc.ClientRequest, secretHash string) (oc.Client, error) {
+	const op = "database.postgres.CreateOIDCClient"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return oc.Client{}, fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	client, err := scanClient(tx.QueryRowContext(ctx, `
+		INSERT INTO public.oidc_clients (name, secret_hash, redirect_uris, created_by) VALUES ($1, $2, $3, $4)
+		RETURNING `+clientColumns,
+		c.Name, secretHash, pq.Array(c.RedirectURIs), actor))
+	if err != nil {
+		return oc.Client{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := audit(ctx, tx, actor, AuditCreateClient, nil, map[string]any{"client": client.ClientID, "name": client.Name}); err != nil {
+		return oc.Client{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return oc.Client{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return client, nil
+}
+
+// OIDCClients returns the registered OpenID Connect clients, latest first
+func (s *Storage) OIDCClients(ctx context.Context) ([]oc.Client, error) {
+	const op = "database.postgres.OIDCClients"
+
+	rows, err := s.db.QueryContext(ctx, `SELECT `+clientColumns+` FROM public.oidc_clients ORDER BY created DESC, id DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+	defer rows.Close()
+
+	clients := []oc.Client{}
+	for rows.Next() {
+		c, err := scanClient(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+		clients = append(clients, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return clients, nil
+}
+
+// OIDCClient returns the OpenID Connect client with the client id, ErrNotFound for an unknown one
+func (s *Storage) OIDCClient(ctx context.Context, clientID string) (oc.Client, error) {
+	const op = "database.postgres.OIDCClient"
+
+	client, err := scanClient(s.db.QueryRowContext(ctx, `SELECT `+clientColumns+` FROM public.oidc_clients WHERE client_id = $1`, clientID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return oc.Client{}, fmt.Errorf("%s: no clients with id %v: %w", op, clientID, ErrNotFound)
+		}
+		return oc.Client{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return client, nil
+}
+
+// DeleteOIDCClient deletes the OpenID Connect client with its unused codes and records the deletion by actor
+// in the audit log. Tokens issued to the client stay valid until they expire.
+func (s *Storage) DeleteOIDCClient(ctx context.Context, actor int, clientID string) error {
+	const op = "database.postgres.DeleteOIDCClient"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	var name string
+	err = tx.QueryRowContext(ctx, `DELETE FROM public.oidc_clients WHERE client_id = $1 RETURNING name`, clientID).Scan(&name)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s: no clients with id %v: %w", op, clientID, ErrNotFound)
+		}
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := audit(ctx, tx, actor, AuditDeleteClient, nil, map[string]string{"client": clientID, "name": name}); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// SaveOIDCCode stores the grant of an authorization code by the hash of the code, valid until expires.
+// Expired codes are deleted on the way.
+func (s *Storage) SaveOIDCCode(ctx context.Context, codeHash string, g oc.Grant, expires time.Time) error {
+	const op = "database.postgres.SaveOIDCCode"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM public.oidc_codes WHERE expires <= $1`, clock.Now()); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO public.oidc_codes (code_hash, client_id, user_id, redirect_uri, scope, nonce, code_challenge, expires)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, codeHash, g.ClientID, g.UserID, g.RedirectURI, g.Scope, g.Nonce, g.CodeChallenge, expires)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// UseOIDCCode consumes the unexpired authorization code with the given hash and returns its grant.
+// Returns ErrNotFound for an unknown, used or expired code.
+func (s *Storage) UseOIDCCode(ctx context.Context, codeHash string) (oc.Grant, error) {
+	const op = "database.postgres.UseOIDCCode"
+
+	var g oc.Grant
+	err := s.db.QueryRowContext(ctx, `
+		DELETE FROM public.oidc_codes WHERE code_hash = $1 AND expires > $2
+		RETURNING client_id, user_id, redirect_uri, scope, nonce, code_challenge
+	`, codeHash, clock.Now()).Scan(&g.ClientID, &g.UserID, &g.RedirectURI, &g.Scope, &g.Nonce, &g.CodeChallenge)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return oc.Grant{}, fmt.Errorf("%s: authorization code %w", op, ErrNotFound)
+		}
+		return oc.Grant{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return g, nil
+}
+
+// OIDCConsent returns the scopes the user granted the OpenID Connect client with the client id, none without a grant
+func (s *Storage) OIDCConsent(ctx context.Context, userID int, clientID string) ([]string, error) {
+	const op = "database.postgres.OIDCConsent"
+
+	var scopes []string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT c.scopes FROM public.oidc_consents c JOIN public.oidc_clients cl ON cl.id = c.client_id
+		WHERE c.user_id = $1 AND cl.client_id = $2
+	`, userID, clientID).Scan(pq.Array(&scopes))
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return scopes, nil
+}
+
+// SaveOIDCConsent replaces the scopes the user granted the OpenID Connect client
+func (s *Storage) SaveOIDCConsent(ctx context.Context, userID, clientID int, scopes []string) error {
+	const op = "database.postgres.SaveOIDCConsent"
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO public.oidc_consents (user_id, client_id, scopes, granted) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, client_id) DO UPDATE SET scopes = EXCLUDED.scopes, granted = EXCLUDED.granted
+	`, userID, clientID, pq.Array(scopes), clock.Now())
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// AuthorizedApps returns the OpenID Connect clients the user granted scopes to, the last granted first
+func (s *Storage) AuthorizedApps(ctx context.Context, userID int) ([]oc.AuthorizedApp, error) {
+	const op = "database.postgres.AuthorizedApps"
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT cl.client_id, cl.name, c.scopes, c.granted
+		FROM public.oidc_consents c JOIN public.oidc_clients cl ON cl.id = c.client_id
+		WHERE c.user_id = $1 ORDER BY c.granted DESC, cl.id DESC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+	defer rows.Close()
+
+	apps := []oc.AuthorizedApp{}
+	for rows.Next() {
+		var a oc.AuthorizedApp
+		if err := rows.Scan(&a.ClientID, &a.Name, pq.Array(&a.Scopes), &a.Granted); err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+		apps = append(apps, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return apps, nil
+}
+
+// RevokeOIDCConsent deletes the user's grant to the OpenID Connect client with the client id and the client's unused
+// codes of the user. Returns ErrNotFound when the user granted the client nothing.
+func (s *Storage) RevokeOIDCConsent(ctx context.Context, userID int, clientID string) error {
+	const op = "database.postgres.RevokeOIDCConsent"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	var id int
+	err = tx.QueryRowContext(ctx, `
+		DELETE FROM public.oidc_consents c USING public.oidc_clients cl
+		WHERE cl.id = c.client_id AND c.user_id = $1 AND cl.client_id = $2
+		RETURNING cl.id
+	`, userID, clientID).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s: no grants to client %v: %w", op, clientID, ErrNotFound)
+		}
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM public.oidc_codes WHERE user_id = $1 AND client_id = $2`, userID, id); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	oc "github.com/sabbatD/srest-api/internal/lib/oidcConfig"
+)
+
+func TestOIDCClients(t *testing.T) {
+	s := testStorage(t)
+	ctx := context.Background()
+
+	admin := testUser(t, s, "oidcadmin")
+	user := testUser(t, s, "oidcuser")
+
+	client, err := s.CreateOIDCClient(ctx, admin, oc.ClientRequest{Name: "wiki", RedirectURIs: []string{"https://wiki.example.com/callback"}}, "secret-hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.DeleteOIDCClient(ctx, admin, client.ClientID) })
+
+	got, err := s.OIDCClient(ctx, client.ClientID)
+	if err != nil || got.ID != client.ID || got.SecretHash != "secret-hash" || len(got.RedirectURIs) != 1 {
+		t.Fatalf("OIDCClient() = %+v, %v", got, err)
+	}
+
+	grant := oc.Grant{ClientID: client.ID, UserID: user, RedirectURI: "https://wiki.example.com/callback", Scope: "openid email", Nonce: "n"}
+	if err := s.SaveOIDCCode(ctx, "code-hash", grant, time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := s.UseOIDCCode(ctx, "code-hash"); err != nil || got != grant {
+		t.Fatalf("UseOIDCCode() = %+v, %v, want %+v", got, err, grant)
+	}
+	if _, err := s.UseOIDCCode(ctx, "code-hash"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("UseOIDCCode() of a used code = %v", err)
+	}
+
+	if err := s.SaveOIDCCode(ctx, "expired-hash", grant, time.Now().Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.UseOIDCCode(ctx, "expired-hash"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("UseOIDCCode() of an expired code = %v", err)
+	}
+
+	if err := s.DeleteOIDCClient(ctx, admin, client.ClientID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.OIDCClient(ctx, client.ClientID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("OIDCClient() after delete = %v", err)
+	}
+}
+
+func TestOIDCConsents(t *testing.T) {
+	s := testStorage(t)
+	ctx := context.Background()
+
+	admin := testUser(t, s, "consentadmin")
+	user := testUser(t, s, "consentuser")
+
+	client, err := s.CreateOIDCClient(ctx, admin, oc.ClientRequest{Name: "wiki", RedirectURIs: []string{"https://wiki.example.com/callback"}}, "secret-hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.DeleteOIDCClient(ctx, admin, client.ClientID) })
+
+	if got, err := s.OIDCConsent(ctx, user, client.ClientID); err != nil || got != nil {
+		t.Fatalf("OIDCConsent() without a grant = %v, %v", got, err)
+	}
+
+	for _, scopes := range [][]string{{"openid"}, {"openid", "email"}} {
+		if err := s.SaveOIDCConsent(ctx, user, client.ID, scopes); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, err := s.OIDCConsent(ctx, user, client.ClientID); err != nil || len(got) != 2 || got[1] != "email" {
+		t.Errorf("OIDCConsent() = %v, %v", got, err)
+	}
+	apps, err := s.AuthorizedApps(ctx, user)
+	if err != nil || len(apps) != 1 || apps[0].ClientID != client.ClientID || apps[0].Name != "wiki" {
+		t.Fatalf("AuthorizedApps() = %+v, %v", apps, err)
+	}
+
+	grant := oc.Grant{ClientID: client.ID, UserID: user, RedirectURI: "https://wiki.example.com/callback", Scope: "openid"}
+	if err := s.SaveOIDCCode(ctx, "consent-code-hash", grant, time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RevokeOIDCConsent(ctx, user, client.ClientID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.UseOIDCCode(ctx, "consent-code-hash"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("UseOIDCCode() after the revocation = %v", err)
+	}
+	if err := s.RevokeOIDCConsent(ctx, user, client.ClientID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("RevokeOIDCConsent() twice = %v", err)
+	}
+	if apps, err := s.AuthorizedApps(ctx, user); err != nil || len(apps) != 0 {
+		t.Errorf("AuthorizedApps() after the revocation = %+v, %v", apps, err)
+	}
+}
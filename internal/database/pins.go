@@ -0,0 +1,62 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/sabbatD/srest-api/internal/lib/clock"
+)
+
+// MaxPinnedTodos is the number of tasks a user may keep pinned
+const MaxPinnedTodos = 10
+
+// PinTodo pins or unpins the user's todo. Pinning a pinned task or unpinning an unpinned one changes nothing,
+// pinning more than MaxPinnedTodos tasks returns ErrLimitReached.
+func (s *Storage) PinTodo(ctx context.Context, id, userID int, pinned bool) error {
+	const op = "database.postgres.PinTodo"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	// Locking the user serializes the limit check with concurrent pins.
+	var count int
+	var current bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT (SELECT COUNT(*) FROM public.todos WHERE user_id = u.id AND pinned_at IS NOT NULL), t.pinned_at IS NOT NULL
+		FROM public.users u JOIN public.todos t ON t.user_id = u.id
+		WHERE u.id = $1 AND t.id = $2
+		FOR UPDATE OF u
+	`, userID, id).Scan(&count, &current)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s: no such task: %w", op, ErrNotFound)
+		}
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	if current == pinned {
+		return nil
+	}
+	if pinned && count >= MaxPinnedTodos {
+		return fmt.Errorf("%s: pinned todo %w", op, ErrLimitReached)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE public.todos
+		SET pinned_at = CASE WHEN $3 THEN $4::timestamptz END, version = nextval('public.todos_version_seq')
+		WHERE id = $1 AND user_id = $2
+	`, id, userID, pinned, clock.Now())
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
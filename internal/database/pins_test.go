@@ -0,0 +1,70 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	todoconfig "github.com/sabbatD/srest-api/internal/lib/todoConfig"
+)
+
+func TestPinTodo(t *testing.T) {
+	s := testStorage(t)
+	ctx := context.Background()
+
+	user := testUser(t, s, "pinsuser")
+	stranger := testUser(t, s, "pinsstranger")
+
+	var ids []int
+	for i := 0; i <= MaxPinnedTodos; i++ {
+		id, err := s.Create(ctx, todoconfig.TodoRequest{Title: fmt.Sprintf("task %d", i)}, user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, int(id))
+	}
+	last := ids[len(ids)-1]
+
+	if err := s.PinTodo(ctx, last, stranger, true); !errors.Is(err, ErrNotFound) {
+		t.Errorf("task of another user: err = %v, want ErrNotFound", err)
+	}
+	if err := s.PinTodo(ctx, last, user, true); err != nil {
+		t.Fatal(err)
+	}
+
+	var first []string
+	_, err := s.EachTodo(ctx, todoconfig.TodoQuery{}, user, func(todo todoconfig.Todo) error {
+		first = append(first, todo.Title)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first[0] != fmt.Sprintf("task %d", MaxPinnedTodos) {
+		t.Errorf("listing starts with %q, want the pinned task", first[0])
+	}
+
+	for _, id := range ids[:MaxPinnedTodos-1] {
+		if err := s.PinTodo(ctx, id, user, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.PinTodo(ctx, last, user, true); err != nil {
+		t.Errorf("pinning a pinned task at the limit: %v", err)
+	}
+	if err := s.PinTodo(ctx, ids[MaxPinnedTodos-1], user, true); !errors.Is(err, ErrLimitReached) {
+		t.Errorf("over the limit: err = %v, want ErrLimitReached", err)
+	}
+
+	if err := s.PinTodo(ctx, last, user, false); err != nil {
+		t.Fatal(err)
+	}
+	todo, err := s.GetTodo(ctx, last, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if todo.Pinned {
+		t.Error("unpinned task is pinned")
+	}
+}
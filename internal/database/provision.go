@@ -0,0 +1,93 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	u "github.com/sabbatD/srest-api/internal/lib/userConfig"
+)
+
+// ProvisionUser returns the local account of a user authenticated by an external source,
+// creating it on the first sign in. The account has no password and its admin rights
+// follow the source, a source not granting them keeps other roles. A login taken by a local account or one of another source,
+// a deleted account or a reserved login is ErrConflict.
+func (s *Storage) ProvisionUser(ctx context.Context, ext u.ExternalUser) (user u.TableUser, err error) {
+	const op = "database.postgres.ProvisionUser"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return user, fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	var id int
+	var source string
+	var deleted, isAdmin bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT id, auth_source, deleted_at IS NOT NULL, role = 'admin' FROM public.users WHERE login = $1 FOR UPDATE
+	`, ext.Login).Scan(&id, &source, &deleted, &isAdmin)
+
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		err = tx.QueryRowContext(ctx, `
+			INSERT INTO public.users (login, username, email, role, auth_source)
+			SELECT $1, $2, $3, CASE WHEN $4 THEN 'admin' ELSE 'user' END, $5
+			WHERE NOT EXISTS (SELECT 1 FROM public.login_history WHERE login = $1 AND released_until > NOW())
+			RETURNING id
+		`, ext.Login, ext.Username, ext.Email, ext.IsAdmin, ext.Source).Scan(&id)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return user, fmt.Errorf("%s: login is reserved: %w", op, ErrConflict)
+			}
+			if pgErr, ok := err.(*pq.Error); ok && pgErr.Code == "23505" {
+				return user, fmt.Errorf("%s: email %w", op, ErrAlreadyExists)
+			}
+			return user, fmt.Errorf("%s: %v", op, err)
+		}
+		if err := audit(ctx, tx, id, AuditProvisionUser, id, map[string]any{"source": ext.Source, "isAdmin": ext.IsAdmin}); err != nil {
+			return user, fmt.Errorf("%s: %v", op, err)
+		}
+
+	case err != nil:
+		return user, fmt.Errorf("%s: %v", op, err)
+
+	case source != ext.Source || deleted:
+		return user, fmt.Errorf("%s: login %q belongs to a %s account: %w", op, ext.Login, source, ErrConflict)
+
+	default:
+		_, err := tx.ExecContext(ctx, `
+			UPDATE public.users SET username = $1, email = $2,
+				role = CASE WHEN $3 THEN 'admin' WHEN role = 'admin' THEN 'user' ELSE role END
+			WHERE id = $4
+		`, ext.Username, ext.Email, ext.IsAdmin, id)
+		if err != nil {
+			if pgErr, ok := err.(*pq.Error); ok && pgErr.Code == "23505" {
+				return user, fmt.Errorf("%s: email %w", op, ErrAlreadyExists)
+			}
+			return user, fmt.Errorf("%s: %v", op, err)
+		}
+		if isAdmin != ext.IsAdmin {
+			if err := audit(ctx, tx, id, AuditUpdateRights, id, map[string]any{"source": ext.Source, "isAdmin": ext.IsAdmin}); err != nil {
+				return user, fmt.Errorf("%s: %v", op, err)
+			}
+		}
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		SELECT id, public_id, username, email, date, is_blocked, is_verified, `+roleColumns+` FROM public.users WHERE id = $1
+	`, id).Scan(&user.ID, &user.PublicID, &user.Username, &user.Email, &user.Date, &user.IsBlocked, &user.IsVerified, &user.Role, pq.Array(&user.Permissions))
+	if err != nil {
+		return user, fmt.Errorf("%s: %v", op, err)
+	}
+	grant(&user)
+	disarm(&user)
+
+	if err := tx.Commit(); err != nil {
+		return u.TableUser{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return user, nil
+}
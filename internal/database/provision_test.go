@@ -0,0 +1,45 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sabbatD/srest-api/internal/lib/userConfig"
+)
+
+func TestProvisionUser(t *testing.T) {
+	s := testStorage(t)
+	ctx := context.Background()
+
+	s.db.Exec(`DELETE FROM public.users WHERE login = 'directoryuser'`)
+	ext := userConfig.ExternalUser{Source: "ldap", Login: "directoryuser", Username: "Directory User", Email: "directoryuser@example.com", IsAdmin: true}
+
+	user, err := s.ProvisionUser(ctx, ext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.db.Exec(`DELETE FROM public.users WHERE id = $1`, user.ID) })
+	if !user.IsAdmin || user.Username != ext.Username {
+		t.Errorf("provisioned user = %+v, want an admin named %q", user, ext.Username)
+	}
+
+	ext.IsAdmin = false
+	again, err := s.ProvisionUser(ctx, ext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.ID != user.ID || again.IsAdmin {
+		t.Errorf("second sign in = %+v, want the same account without admin rights", again)
+	}
+
+	if _, err := s.Auth(ctx, userConfig.AuthData{Login: ext.Login, Password: "password"}); err == nil {
+		t.Error("provisioned account signed in with a local password")
+	}
+
+	testUser(t, s, "localuser")
+	ext.Login, ext.Email = "localuser", "other@example.com"
+	if _, err := s.ProvisionUser(ctx, ext); !errors.Is(err, ErrConflict) {
+		t.Errorf("provisioning over a local account: err = %v, want ErrConflict", err)
+	}
+}
@@ -0,0 +1,72 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// RunQuery runs a report query with args in a read-only transaction, cancelling its statement after timeout,
+// and returns its columns and up to maxRows rows. Text and numeric values are returned as strings.
+// The run of the query name is recorded by actor in the audit log.
+func (s *Storage) RunQuery(ctx context.Context, actor int, name, query string, args []any, maxRows int, timeout time.Duration) ([]string, [][]any, bool, error) {
+	const op = "database.postgres.RunQuery"
+
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	if timeout > 0 {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("SET LOCAL statement_timeout = %d", timeout.Milliseconds())); err != nil {
+			return nil, nil, false, fmt.Errorf("%s: %v", op, err)
+		}
+	}
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("%s: %v", op, err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("%s: %v", op, err)
+	}
+
+	var result [][]any
+	truncated := false
+	for rows.Next() {
+		if len(result) == maxRows {
+			truncated = true
+			break
+		}
+
+		values := make([]any, len(columns))
+		ptrs := make([]any, len(columns))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, nil, false, fmt.Errorf("%s: %v", op, err)
+		}
+		for i, v := range values {
+			if b, ok := v.([]byte); ok {
+				values[i] = string(b)
+			}
+		}
+		result = append(result, values)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, false, fmt.Errorf("%s: %v", op, err)
+	}
+	rows.Close()
+
+	if err := audit(ctx, s.db, actor, AuditRunQuery, nil, map[string]any{"query": name, "params": args, "rows": len(result)}); err != nil {
+		return nil, nil, false, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return columns, result, truncated, nil
+}
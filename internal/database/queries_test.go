@@ -0,0 +1,57 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sabbatD/srest-api/internal/lib/queries"
+)
+
+func TestRunQuery(t *testing.T) {
+	s := testStorage(t)
+	ctx := context.Background()
+
+	actor := testUser(t, s, "queryadmin")
+	testUser(t, s, "queryuser")
+
+	// Every built-in query is valid against the schema
+	runner, err := queries.New(s, queries.Config{MaxRows: 100, Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, q := range runner.List() {
+		if _, err := runner.Run(ctx, actor, q.Name, nil); err != nil {
+			t.Errorf("Run(%s) = %v", q.Name, err)
+		}
+	}
+
+	columns, rows, truncated, err := s.RunQuery(ctx, actor, "logins", "SELECT login, NULL AS nothing FROM public.users WHERE login LIKE $1 ORDER BY login", []any{"query%"}, 1, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(columns, ",") != "login,nothing" || len(rows) != 1 || !truncated {
+		t.Fatalf("RunQuery() = %v, %v, %v", columns, rows, truncated)
+	}
+	if rows[0][0] != "queryadmin" || rows[0][1] != nil {
+		t.Errorf("row = %v", rows[0])
+	}
+
+	// Writes fail in the read-only transaction
+	if _, _, _, err := s.RunQuery(ctx, actor, "write", "WITH d AS (DELETE FROM public.users WHERE login = 'queryuser' RETURNING id) SELECT * FROM d", nil, 10, time.Second); err == nil {
+		t.Error("RunQuery() of a write succeeded")
+	}
+
+	if _, _, _, err := s.RunQuery(ctx, actor, "slow", "SELECT pg_sleep(1)", nil, 10, 50*time.Millisecond); err == nil {
+		t.Error("RunQuery() past its timeout succeeded")
+	}
+
+	var runs int
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM public.audit_log WHERE actor_id = $1 AND action = $2 AND details->>'query' = 'logins'", actor, AuditRunQuery).Scan(&runs); err != nil {
+		t.Fatal(err)
+	}
+	if runs != 1 {
+		t.Errorf("%d audited runs, want 1", runs)
+	}
+}
@@ -0,0 +1,124 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/sabbatD/srest-api/internal/lib/logger/sl"
+	"github.com/sabbatD/srest-api/internal/lib/metrics"
+)
+
+// db wraps *sql.DB to time every statement and log the ones slower than slow.
+// Arguments are never logged, only their types.
+type db struct {
+	*sql.DB
+	log  *slog.Logger
+	slow time.Duration
+}
+
+type stmt struct {
+	*sql.Stmt
+	db    *db
+	query string
+}
+
+type tx struct {
+	*sql.Tx
+	db *db
+}
+
+func (d *db) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	defer d.observe(ctx, time.Now(), query, args)
+	return d.DB.QueryContext(ctx, query, args...)
+}
+
+func (d *db) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	defer d.observe(ctx, time.Now(), query, args)
+	return d.DB.QueryRowContext(ctx, query, args...)
+}
+
+func (d *db) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	defer d.observe(ctx, time.Now(), query, args)
+	return d.DB.ExecContext(ctx, query, args...)
+}
+
+func (d *db) PrepareContext(ctx context.Context, query string) (*stmt, error) {
+	s, err := d.DB.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return &stmt{Stmt: s, db: d, query: query}, nil
+}
+
+func (d *db) BeginTx(ctx context.Context, opts *sql.TxOptions) (*tx, error) {
+	t, err := d.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &tx{Tx: t, db: d}, nil
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args ...any) (*sql.Rows, error) {
+	defer s.db.observe(ctx, time.Now(), s.query, args)
+	return s.Stmt.QueryContext(ctx, args...)
+}
+
+func (s *stmt) QueryRowContext(ctx context.Context, args ...any) *sql.Row {
+	defer s.db.observe(ctx, time.Now(), s.query, args)
+	return s.Stmt.QueryRowContext(ctx, args...)
+}
+
+func (s *stmt) ExecContext(ctx context.Context, args ...any) (sql.Result, error) {
+	defer s.db.observe(ctx, time.Now(), s.query, args)
+	return s.Stmt.ExecContext(ctx, args...)
+}
+
+func (t *tx) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	defer t.db.observe(ctx, time.Now(), query, args)
+	return t.Tx.QueryContext(ctx, query, args...)
+}
+
+func (t *tx) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	defer t.db.observe(ctx, time.Now(), query, args)
+	return t.Tx.QueryRowContext(ctx, query, args...)
+}
+
+func (t *tx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	defer t.db.observe(ctx, time.Now(), query, args)
+	return t.Tx.ExecContext(ctx, query, args...)
+}
+
+// observe must be deferred directly by the wrapper methods:
+// it attributes the statement to the storage method two frames up.
+// The statement is logged to the request logger of ctx, or to d.log outside of requests.
+func (d *db) observe(ctx context.Context, start time.Time, query string, args []any) {
+	elapsed := time.Since(start)
+	if d.slow <= 0 || elapsed < d.slow {
+		return
+	}
+
+	method := "unknown"
+	if pc, _, _, ok := runtime.Caller(2); ok {
+		name := runtime.FuncForPC(pc).Name()
+		method = name[strings.LastIndex(name, ".")+1:]
+	}
+
+	metrics.SlowQueries.Add(method, 1)
+
+	types := make([]string, len(args))
+	for i, arg := range args {
+		types[i] = fmt.Sprintf("%T", arg)
+	}
+
+	sl.FromContext(sl.Ensure(ctx, d.log)).Warn("slow query",
+		slog.String("method", method),
+		slog.Duration("elapsed", elapsed),
+		slog.String("query", strings.Join(strings.Fields(query), " ")),
+		slog.Any("args", types),
+	)
+}
@@ -0,0 +1,72 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+const settingReadOnly = "read_only"
+
+// readOnlySetting is the stored read-only mode, see readonly.Mode
+type readOnlySetting struct {
+	Enabled bool   `json:"enabled"`
+	Message string `json:"message,omitempty"`
+}
+
+// ReadOnly returns whether an admin switched the API to read-only mode and the message for clients, off when there is no setting
+func (s *Storage) ReadOnly(ctx context.Context) (bool, string, error) {
+	const op = "database.postgres.ReadOnly"
+
+	var m readOnlySetting
+	var data []byte
+	err := s.db.QueryRowContext(ctx, `SELECT value FROM public.settings WHERE key = $1`, settingReadOnly).Scan(&data)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, "", nil
+		}
+		return false, "", fmt.Errorf("%s: %v", op, err)
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return false, "", fmt.Errorf("%s: %v", op, err)
+	}
+
+	return m.Enabled, m.Message, nil
+}
+
+// SetReadOnly stores the read-only mode and records the change by actor in the audit log
+func (s *Storage) SetReadOnly(ctx context.Context, actor int, enabled bool, message string) error {
+	const op = "database.postgres.SetReadOnly"
+
+	m := readOnlySetting{Enabled: enabled, Message: message}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO public.settings (key, value, updated_by) VALUES ($1, $2, $3)
+		ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, updated_by = EXCLUDED.updated_by, updated = NOW()
+	`, settingReadOnly, data, actor)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := audit(ctx, tx, actor, AuditSetReadOnly, nil, m); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
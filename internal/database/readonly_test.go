@@ -0,0 +1,26 @@
+package database
+
+import (
+	"context"
+	"testing"
+)
+
+func TestReadOnly(t *testing.T) {
+	s := testStorage(t)
+	ctx := context.Background()
+
+	s.db.Exec(`DELETE FROM public.settings WHERE key = $1`, settingReadOnly)
+	t.Cleanup(func() { s.db.Exec(`DELETE FROM public.settings WHERE key = $1`, settingReadOnly) })
+
+	if enabled, _, err := s.ReadOnly(ctx); err != nil || enabled {
+		t.Fatalf("ReadOnly() without a setting = %v, %v", enabled, err)
+	}
+
+	admin := testUser(t, s, "readonlyadmin")
+	if err := s.SetReadOnly(ctx, admin, true, "failover"); err != nil {
+		t.Fatal(err)
+	}
+	if enabled, message, err := s.ReadOnly(ctx); err != nil || !enabled || message != "failover" {
+		t.Errorf("ReadOnly() = %v, %q, %v", enabled, message, err)
+	}
+}
@@ -0,0 +1,161 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	rc "github.com/sabbatD/srest-api/internal/lib/reportConfig"
+)
+
+const reportColumns = `
+	r.id, r.public_id, reporter.public_id, r.target_type, COALESCE(target.public_id::text, ''),
+	r.reason, r.details, r.status, r.resolution, r.created, r.resolved_at
+`
+
+const reportJoins = `
+	LEFT JOIN public.users reporter ON reporter.id = r.reporter_id
+	LEFT JOIN public.users target ON r.target_type = 'user' AND target.id = r.target_id
+`
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanReport(row scanner) (report rc.Report, err error) {
+	err = row.Scan(&report.ID, &report.PublicID, &report.Reporter, &report.TargetType, &report.Target,
+		&report.Reason, &report.Details, &report.Status, &report.Resolution, &report.Created, &report.ResolvedAt)
+	return report, err
+}
+
+// CreateReport files a report by reporter against the target row, a reporter has one open report per target
+func (s *Storage) CreateReport(ctx context.Context, reporter int, r rc.ReportRequest, target int) (rc.Report, error) {
+	const op = "database.postgres.CreateReport"
+
+	var id int
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO public.reports (reporter_id, target_type, target_id, reason, details)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`, reporter, r.TargetType, target, r.Reason, r.Details).Scan(&id)
+	if err != nil {
+		if pgErr, ok := err.(*pq.Error); ok && pgErr.Code == "23505" {
+			return rc.Report{}, fmt.Errorf("%s: open report %w", op, ErrAlreadyExists)
+		}
+		return rc.Report{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	report, err := scanReport(s.db.QueryRowContext(ctx, `SELECT `+reportColumns+` FROM public.reports r `+reportJoins+` WHERE r.id = $1`, id))
+	if err != nil {
+		return rc.Report{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return report, nil
+}
+
+// Reports returns reports with the given status, all of them for an empty one, oldest first
+func (s *Storage) Reports(ctx context.Context, status string, limit, offset int) (rc.MetaResponse, error) {
+	const op = "database.postgres.Reports"
+
+	result := rc.MetaResponse{Data: []rc.Report{}, Meta: rc.Meta{Status: status}}
+
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM public.reports WHERE $1 = '' OR status = $1`, status).Scan(&result.Meta.TotalAmount)
+	if err != nil {
+		return result, fmt.Errorf("%s: %v", op, err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+reportColumns+` FROM public.reports r `+reportJoins+`
+		WHERE $1 = '' OR r.status = $1
+		ORDER BY r.created, r.id
+		LIMIT $2 OFFSET $3
+	`, status, limit, offset)
+	if err != nil {
+		return result, fmt.Errorf("%s: %v", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		report, err := scanReport(rows)
+		if err != nil {
+			return result, fmt.Errorf("%s: %v", op, err)
+		}
+		result.Data = append(result.Data, report)
+	}
+	if err := rows.Err(); err != nil {
+		return result, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return result, nil
+}
+
+func (s *Storage) ReportID(ctx context.Context, publicID string) (int, error) {
+	const op = "database.postgres.ReportID"
+
+	var id int
+	err := s.db.QueryRowContext(ctx, `SELECT id FROM public.reports WHERE public_id = $1`, publicID).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("%s: no reports with id %v: %w", op, publicID, ErrNotFound)
+		}
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return id, nil
+}
+
+// ReviewReport closes an open report with status resolved or dismissed and records the review by actor
+// in the audit log. Closed reports cannot be reviewed again, that is ErrConflict.
+func (s *Storage) ReviewReport(ctx context.Context, id, actor int, status, resolution string) (rc.Report, error) {
+	const op = "database.postgres.ReviewReport"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return rc.Report{}, fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	var current, targetType string
+	var target int
+	err = tx.QueryRowContext(ctx, `SELECT status, target_type, target_id FROM public.reports WHERE id = $1 FOR UPDATE`, id).Scan(&current, &targetType, &target)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return rc.Report{}, fmt.Errorf("%s: no reports with id %v: %w", op, id, ErrNotFound)
+		}
+		return rc.Report{}, fmt.Errorf("%s: %v", op, err)
+	}
+	if current != rc.StatusOpen {
+		return rc.Report{}, fmt.Errorf("%s: report is %s: %w", op, current, ErrConflict)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE public.reports SET status = $1, resolution = $2, resolved_by = $3, resolved_at = NOW()
+		WHERE id = $4
+	`, status, resolution, actor, id)
+	if err != nil {
+		return rc.Report{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	report, err := scanReport(tx.QueryRowContext(ctx, `SELECT `+reportColumns+` FROM public.reports r `+reportJoins+` WHERE r.id = $1`, id))
+	if err != nil {
+		return rc.Report{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	// The audit log targets users, reports on other content are logged without a target.
+	var auditTarget any
+	if targetType == rc.TargetUser {
+		auditTarget = target
+	}
+	details := map[string]string{"report": report.PublicID, "status": status, "resolution": resolution}
+	if err := audit(ctx, tx, actor, AuditReportReview, auditTarget, details); err != nil {
+		return rc.Report{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return rc.Report{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return report, nil
+}
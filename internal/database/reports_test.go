@@ -0,0 +1,57 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	rc "github.com/sabbatD/srest-api/internal/lib/reportConfig"
+)
+
+func TestReports(t *testing.T) {
+	s := testStorage(t)
+	ctx := context.Background()
+
+	reporter := testUser(t, s, "reporter")
+	target := testUser(t, s, "reported")
+	admin := testUser(t, s, "reportadmin")
+	t.Cleanup(func() { s.db.Exec(`DELETE FROM public.reports WHERE target_id = $1`, target) })
+
+	req := rc.ReportRequest{TargetType: rc.TargetUser, Reason: "spam"}
+	report, err := s.CreateReport(ctx, reporter, req, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Status != rc.StatusOpen || report.Target == "" || report.Reporter == nil {
+		t.Errorf("report = %+v", report)
+	}
+	if _, err := s.CreateReport(ctx, reporter, req, target); !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("second open report: err = %v, want ErrAlreadyExists", err)
+	}
+
+	id, err := s.ReportID(ctx, report.PublicID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err = s.ReviewReport(ctx, id, admin, rc.StatusDismissed, "not spam")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Status != rc.StatusDismissed || report.Resolution != "not spam" || report.ResolvedAt == nil {
+		t.Errorf("reviewed report = %+v", report)
+	}
+	if _, err := s.ReviewReport(ctx, id, admin, rc.StatusResolved, ""); !errors.Is(err, ErrConflict) {
+		t.Errorf("reviewing a closed report: err = %v, want ErrConflict", err)
+	}
+
+	var audited int
+	s.db.QueryRow(`SELECT COUNT(*) FROM public.audit_log WHERE action = $1 AND details->>'report' = $2`, AuditReportReview, report.PublicID).Scan(&audited)
+	if audited != 1 {
+		t.Errorf("audit entries = %d, want 1", audited)
+	}
+
+	// A closed report no longer blocks a new one.
+	if _, err := s.CreateReport(ctx, reporter, req, target); err != nil {
+		t.Errorf("report after review: %v", err)
+	}
+}
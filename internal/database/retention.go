@@ -0,0 +1,139 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sabbatD/srest-api/internal/lib/retention"
+)
+
+const settingRetention = "retention"
+
+// RetentionPolicy returns the policy set by an admin, false when there is none
+func (s *Storage) RetentionPolicy(ctx context.Context) (retention.Policy, bool, error) {
+	const op = "database.postgres.RetentionPolicy"
+
+	var p retention.Policy
+	var data []byte
+	err := s.db.QueryRowContext(ctx, `SELECT value FROM public.settings WHERE key = $1`, settingRetention).Scan(&data)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return p, false, nil
+		}
+		return p, false, fmt.Errorf("%s: %v", op, err)
+	}
+	if err := json.Unmarshal(data, &p); err != nil {
+		return p, false, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return p, true, nil
+}
+
+// SetRetentionPolicy stores the policy and records the change by actor in the audit log
+func (s *Storage) SetRetentionPolicy(ctx context.Context, actor int, p retention.Policy) error {
+	const op = "database.postgres.SetRetentionPolicy"
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO public.settings (key, value, updated_by) VALUES ($1, $2, $3)
+		ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, updated_by = EXCLUDED.updated_by, updated = NOW()
+	`, settingRetention, data, actor)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := audit(ctx, tx, actor, AuditSetRetention, nil, p); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// PurgeLoginHistory deletes former logins changed before the given time unless they are still reserved
+func (s *Storage) PurgeLoginHistory(ctx context.Context, before time.Time) (int64, error) {
+	const op = "database.postgres.PurgeLoginHistory"
+
+	res, err := s.db.ExecContext(ctx, `
+		DELETE FROM public.login_history WHERE changed_at < $1 AND released_until < NOW()
+	`, before)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return n, nil
+}
+
+// PurgeDeletedUsers deletes users soft-deleted before the given time for good, along with their
+// todos and history. Audit entries and reports about them stay, without the reference.
+func (s *Storage) PurgeDeletedUsers(ctx context.Context, before time.Time) (int64, error) {
+	const op = "database.postgres.PurgeDeletedUsers"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	// tokens has no foreign key to users.
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM public.tokens WHERE user_id IN (SELECT id FROM public.users WHERE deleted_at < $1)
+	`, before)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM public.users WHERE deleted_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return n, nil
+}
+
+// PurgeAuditLog deletes audit entries recorded before the given time
+func (s *Storage) PurgeAuditLog(ctx context.Context, before time.Time) (int64, error) {
+	const op = "database.postgres.PurgeAuditLog"
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM public.audit_log WHERE created < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return n, nil
+}
@@ -0,0 +1,46 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sabbatD/srest-api/internal/lib/retention"
+)
+
+func TestRetention(t *testing.T) {
+	s := testStorage(t)
+	ctx := context.Background()
+
+	admin := testUser(t, s, "retentionadmin")
+	removed := testUser(t, s, "retentionremoved")
+	t.Cleanup(func() { s.db.Exec(`DELETE FROM public.settings WHERE key = $1`, settingRetention) })
+
+	want := retention.Policy{LoginHistoryDays: 30, DeletedUsersDays: 7, AuditLogDays: 365}
+	if err := s.SetRetentionPolicy(ctx, admin, want); err != nil {
+		t.Fatal(err)
+	}
+	got, ok, err := s.RetentionPolicy(ctx)
+	if err != nil || !ok || got != want {
+		t.Errorf("RetentionPolicy() = %+v, %v, %v", got, ok, err)
+	}
+
+	if _, err := s.Remove(ctx, removed); err != nil {
+		t.Fatal(err)
+	}
+	s.db.Exec(`UPDATE public.users SET deleted_at = NOW() - INTERVAL '10 days' WHERE id = $1`, removed)
+
+	n, err := s.PurgeDeletedUsers(ctx, time.Now().AddDate(0, 0, -7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n < 1 {
+		t.Errorf("purged %d users", n)
+	}
+	if _, err := s.Get(ctx, removed); err == nil {
+		t.Error("purged user still exists")
+	}
+	if _, err := s.Get(ctx, admin); err != nil {
+		t.Errorf("active user purged: %v", err)
+	}
+}
@@ -0,0 +1,305 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	rc "github.com/sabbatD/srest-api/internal/lib/roleConfig"
+	u "github.com/sabbatD/srest-api/internal/lib/userConfig"
+)
+
+// roleColumns selects the role of a user and the permissions stored for it, see grant
+const roleColumns = `role, ARRAY(SELECT permission FROM public.role_permissions p WHERE p.role = users.role)`
+
+// grant sets the admin flag and the permissions of the user from the role scanned with roleColumns
+func grant(user *u.TableUser) {
+	user.IsAdmin = user.Role == rc.Admin
+	user.Permissions = rc.Granted(user.Role, user.Permissions)
+}
+
+// disarm drops the rights of a blocked user
+func disarm(user *u.TableUser) {
+	if user.IsBlocked {
+		user.Role, user.IsAdmin, user.Permissions = rc.User, false, nil
+	}
+}
+
+const roleQuery = `
+	SELECT r.name, r.description, r.builtin,
+		ARRAY(SELECT permission FROM public.role_permissions p WHERE p.role = r.name),
+		(SELECT COUNT(*) FROM public.users WHERE role = r.name AND deleted_at IS NULL)
+	FROM public.roles r
+`
+
+func scanRole(row scanner) (r rc.Role, err error) {
+	if err = row.Scan(&r.Name, &r.Description, &r.Builtin, pq.Array(&r.Permissions), &r.Users); err != nil {
+		return r, err
+	}
+	r.Permissions = rc.Granted(r.Name, r.Permissions)
+	return r, nil
+}
+
+// Roles returns every role with the number of its users, built-in ones first
+func (s *Storage) Roles(ctx context.Context) ([]rc.Role, error) {
+	const op = "database.postgres.Roles"
+
+	rows, err := s.db.QueryContext(ctx, roleQuery+` ORDER BY r.builtin DESC, r.name`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+	defer rows.Close()
+
+	roles := []rc.Role{}
+	for rows.Next() {
+		r, err := scanRole(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+		roles = append(roles, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return roles, nil
+}
+
+// CreateRole stores a role granting the permissions, checked with rc.Check, and records its creation
+// by actor in the audit log. A taken name is ErrAlreadyExists.
+func (s *Storage) CreateRole(ctx context.Context, actor int, role rc.RoleRequest) (rc.Role, error) {
+	const op = "database.postgres.CreateRole"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return rc.Role{}, fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO public.roles (name, description) VALUES ($1, $2)`, role.Name, role.Description)
+	if err != nil {
+		if pgErr, ok := err.(*pq.Error); ok && pgErr.Code == "23505" {
+			return rc.Role{}, fmt.Errorf("%s: role %q %w", op, role.Name, ErrAlreadyExists)
+		}
+		return rc.Role{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	r, err := setPermissions(ctx, tx, role.Name, role.Permissions)
+	if err != nil {
+		return rc.Role{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := audit(ctx, tx, actor, AuditCreateRole, nil, r); err != nil {
+		return rc.Role{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return rc.Role{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return r, nil
+}
+
+// UpdateRole replaces the description and permissions of the role and records the change by actor
+// in the audit log. The admin and user roles cannot be changed, they are ErrConflict.
+func (s *Storage) UpdateRole(ctx context.Context, name string, actor int, role rc.RoleRequest) (rc.Role, error) {
+	const op = "database.postgres.UpdateRole"
+
+	if name == rc.Admin || name == rc.User {
+		return rc.Role{}, fmt.Errorf("%s: the %s role cannot be changed: %w", op, name, ErrConflict)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return rc.Role{}, fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `UPDATE public.roles SET description = $1 WHERE name = $2`, role.Description, name)
+	if err != nil {
+		return rc.Role{}, fmt.Errorf("%s: %v", op, err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return rc.Role{}, fmt.Errorf("%s: %v", op, err)
+	} else if n == 0 {
+		return rc.Role{}, fmt.Errorf("%s: no role %q: %w", op, name, ErrNotFound)
+	}
+
+	r, err := setPermissions(ctx, tx, name, role.Permissions)
+	if err != nil {
+		return rc.Role{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := audit(ctx, tx, actor, AuditUpdateRole, nil, r); err != nil {
+		return rc.Role{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return rc.Role{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return r, nil
+}
+
+// setPermissions replaces the permissions of the role within tx and returns the role
+func setPermissions(ctx context.Context, tx *tx, name string, permissions []string) (rc.Role, error) {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM public.role_permissions WHERE role = $1`, name); err != nil {
+		return rc.Role{}, err
+	}
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO public.role_permissions (role, permission) SELECT $1, unnest($2::text[])
+	`, name, pq.Array(permissions))
+	if err != nil {
+		return rc.Role{}, err
+	}
+
+	return scanRole(tx.QueryRowContext(ctx, roleQuery+` WHERE r.name = $1`, name))
+}
+
+// DeleteRole deletes the role and records the deletion by actor in the audit log.
+// Built-in roles and roles of users, deleted ones aside, are ErrConflict.
+func (s *Storage) DeleteRole(ctx context.Context, name string, actor int) error {
+	const op = "database.postgres.DeleteRole"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	var builtin bool
+	err = tx.QueryRowContext(ctx, `SELECT builtin FROM public.roles WHERE name = $1 FOR UPDATE`, name).Scan(&builtin)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s: no role %q: %w", op, name, ErrNotFound)
+		}
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	if builtin {
+		return fmt.Errorf("%s: the %s role is built in: %w", op, name, ErrConflict)
+	}
+
+	var users int
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM public.users WHERE role = $1 AND deleted_at IS NULL`, name).Scan(&users); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	if users > 0 {
+		return fmt.Errorf("%s: the %s role has %d users: %w", op, name, users, ErrConflict)
+	}
+
+	// Deleted users keep no role of their own
+	if _, err := tx.ExecContext(ctx, `UPDATE public.users SET role = $1 WHERE role = $2`, rc.User, name); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM public.roles WHERE name = $1`, name); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := audit(ctx, tx, actor, AuditDeleteRole, nil, map[string]string{"role": name}); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// SetUserRole assigns the role to the user and records the change by actor in the audit log.
+// An unknown role or user is ErrNotFound, taking the role of the last admin is ErrConflict.
+// The user's access tokens get the permissions of the new role at once, see UserRole.
+func (s *Storage) SetUserRole(ctx context.Context, id, actor int, role string) error {
+	const op = "database.postgres.SetUserRole"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM public.roles WHERE name = $1)`, role).Scan(&exists); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	if !exists {
+		return fmt.Errorf("%s: no role %q: %w", op, role, ErrNotFound)
+	}
+
+	if err := lockAdmins(ctx, tx); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	var old string
+	err = tx.QueryRowContext(ctx, `SELECT role FROM public.users WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, id).Scan(&old)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s: no users with id %v: %w", op, id, ErrNotFound)
+		}
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	if old == role {
+		return tx.Commit()
+	}
+
+	if old == rc.Admin {
+		if last, err := lastAdmin(ctx, tx, id); err != nil {
+			return fmt.Errorf("%s: %v", op, err)
+		} else if last {
+			return fmt.Errorf("%s: user %v is the last admin: %w", op, id, ErrConflict)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE public.users SET role = $1 WHERE id = $2`, role, id); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := audit(ctx, tx, actor, AuditSetUserRole, id, map[string]string{"old": old, "new": role}); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// lockAdmins locks the admins within tx, so that two admins cannot demote or block each other at once
+func lockAdmins(ctx context.Context, tx *tx) error {
+	_, err := tx.ExecContext(ctx, `SELECT id FROM public.users WHERE role = $1 FOR UPDATE`, rc.Admin)
+	return err
+}
+
+// lastAdmin reports whether no admin but the user with the id is left neither blocked nor deleted,
+// call it after lockAdmins
+func lastAdmin(ctx context.Context, tx *tx, id int) (bool, error) {
+	var admins int
+	err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM public.users WHERE role = $1 AND id <> $2 AND deleted_at IS NULL AND NOT is_blocked
+	`, rc.Admin, id).Scan(&admins)
+	return admins == 0, err
+}
+
+// UserRole returns the current role of the user and the permissions it grants, see access.Roles.
+// Blocked and deleted users have the user role without permissions.
+func (s *Storage) UserRole(ctx context.Context, id int) (string, []string, error) {
+	const op = "database.postgres.UserRole"
+
+	var user u.TableUser
+	err := s.db.QueryRowContext(ctx, `
+		SELECT is_blocked, `+roleColumns+` FROM public.users WHERE id = $1 AND deleted_at IS NULL
+	`, id).Scan(&user.IsBlocked, &user.Role, pq.Array(&user.Permissions))
+	if errors.Is(err, sql.ErrNoRows) {
+		return rc.User, nil, nil
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("%s: %v", op, err)
+	}
+	grant(&user)
+	disarm(&user)
+
+	return user.Role, user.Permissions, nil
+}
@@ -0,0 +1,122 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/lib/pq"
+	rc "github.com/sabbatD/srest-api/internal/lib/roleConfig"
+)
+
+func TestRoles(t *testing.T) {
+	s := testStorage(t)
+	ctx := context.Background()
+
+	const name = "test-support"
+	s.db.Exec(`UPDATE public.users SET role = 'user' WHERE role = $1`, name)
+	s.db.Exec(`DELETE FROM public.roles WHERE name = $1`, name)
+	t.Cleanup(func() { s.db.Exec(`DELETE FROM public.roles WHERE name = $1`, name) })
+
+	actor := testUser(t, s, "roleactor")
+	id := testUser(t, s, "roleuser")
+
+	role, err := s.CreateRole(ctx, actor, rc.RoleRequest{Name: name, Permissions: []string{rc.UsersRead}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(role.Permissions, []string{rc.UsersRead}) || role.Builtin {
+		t.Fatalf("created role = %+v", role)
+	}
+	if _, err := s.CreateRole(ctx, actor, rc.RoleRequest{Name: name}); !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("CreateRole() of a taken name error = %v, want ErrAlreadyExists", err)
+	}
+
+	role, err = s.UpdateRole(ctx, name, actor, rc.RoleRequest{Description: "Support", Permissions: []string{rc.UsersRead, rc.UsersBlock}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(role.Permissions, []string{rc.UsersRead, rc.UsersBlock}) || role.Description != "Support" {
+		t.Fatalf("updated role = %+v", role)
+	}
+
+	if err := s.SetUserRole(ctx, id, actor, name); err != nil {
+		t.Fatal(err)
+	}
+	user, err := s.Get(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Role != name || user.IsAdmin || !slices.Equal(user.Permissions, []string{rc.UsersRead, rc.UsersBlock}) {
+		t.Errorf("user with the role = %+v", user)
+	}
+	if err := s.DeleteRole(ctx, name, actor); !errors.Is(err, ErrConflict) {
+		t.Errorf("DeleteRole() of a role with users error = %v, want ErrConflict", err)
+	}
+
+	if err := s.SetUserRole(ctx, id, actor, rc.Admin); err != nil {
+		t.Fatal(err)
+	}
+	if user, _ := s.Get(ctx, id); !user.IsAdmin || !slices.Equal(user.Permissions, rc.Permissions) {
+		t.Errorf("admin = %+v, want every permission", user)
+	}
+
+	if err := s.SetUserRole(ctx, id, actor, rc.User); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteRole(ctx, name, actor); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.SetUserRole(ctx, id, actor, name); !errors.Is(err, ErrNotFound) {
+		t.Errorf("SetUserRole() of a deleted role error = %v, want ErrNotFound", err)
+	}
+	if err := s.DeleteRole(ctx, rc.Moderator, actor); !errors.Is(err, ErrConflict) {
+		t.Errorf("DeleteRole() of a built-in role error = %v, want ErrConflict", err)
+	}
+	if _, err := s.UpdateRole(ctx, rc.Admin, actor, rc.RoleRequest{}); !errors.Is(err, ErrConflict) {
+		t.Errorf("UpdateRole() of the admin role error = %v, want ErrConflict", err)
+	}
+}
+
+func TestBlockAdmin(t *testing.T) {
+	s := testStorage(t)
+	ctx := context.Background()
+
+	actor := testUser(t, s, "blockactor")
+	admin := testUser(t, s, "blockadmin")
+	other := testUser(t, s, "blockother")
+	for _, id := range []int{admin, other} {
+		if err := s.SetUserRole(ctx, id, actor, rc.Admin); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := s.UpdateField(ctx, "admin", admin, false); err == nil {
+		t.Error("UpdateField() of the admin flag succeeded, want roles set with SetUserRole")
+	}
+	if _, err := s.UpdateField(ctx, "block", admin, true); err != nil {
+		t.Fatal(err)
+	}
+	if role, permissions, err := s.UserRole(ctx, admin); err != nil || role != rc.User || len(permissions) != 0 {
+		t.Errorf("UserRole() of a blocked admin = %q, %v, %v, want the user role", role, permissions, err)
+	}
+	if role, permissions, err := s.UserRole(ctx, other); err != nil || role != rc.Admin || !slices.Equal(permissions, rc.Permissions) {
+		t.Errorf("UserRole() of an admin = %q, %v, %v, want every permission", role, permissions, err)
+	}
+
+	// Every other admin blocked, the last one cannot be
+	var blocked []int64
+	err := s.db.QueryRow(`
+		WITH b AS (UPDATE public.users SET is_blocked = TRUE WHERE role = $1 AND id <> $2 AND NOT is_blocked RETURNING id)
+		SELECT ARRAY(SELECT id FROM b)
+	`, rc.Admin, other).Scan(pq.Array(&blocked))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.db.Exec(`UPDATE public.users SET is_blocked = FALSE WHERE id = ANY($1)`, pq.Array(blocked)) })
+	if _, err := s.UpdateField(ctx, "block", other, true); !errors.Is(err, ErrConflict) {
+		t.Errorf("UpdateField() blocking the last admin error = %v, want ErrConflict", err)
+	}
+}
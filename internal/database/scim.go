@@ -0,0 +1,235 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	u "github.com/sabbatD/srest-api/internal/lib/userConfig"
+	"github.com/sabbatD/srest-api/internal/password"
+)
+
+// Accounts created over SCIM have the auth source scim, they sign in with the password
+// set by the identity provider, if any. Guests and deleted accounts are invisible to SCIM.
+
+const directoryColumns = `id, public_id, COALESCE(external_id, ''), login, COALESCE(username, ''), COALESCE(email, ''),
+	COALESCE(phone_number, ''), NOT is_blocked, date`
+
+func scanDirectoryUser(row scanner) (d u.DirectoryUser, err error) {
+	err = row.Scan(&d.ID, &d.PublicID, &d.ExternalID, &d.Login, &d.Username, &d.Email, &d.PhoneNumber, &d.Active, &d.Created)
+	return d, err
+}
+
+// SCIMUsers returns a page of users ordered by id and the number of all matching users
+func (s *Storage) SCIMUsers(ctx context.Context, q u.DirectoryQuery) ([]u.DirectoryUser, int, error) {
+	const op = "database.postgres.SCIMUsers"
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+directoryColumns+`, COUNT(*) OVER()
+		FROM public.users
+		WHERE deleted_at IS NULL AND NOT is_guest
+			AND ($1 = '' OR lower(login) = lower($1))
+			AND ($2 = '' OR external_id = $2)
+		ORDER BY id
+		LIMIT $3 OFFSET $4
+	`, q.Login, q.ExternalID, q.Limit, q.Offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: %v", op, err)
+	}
+	defer rows.Close()
+
+	users := []u.DirectoryUser{}
+	total := 0
+	for rows.Next() {
+		var d u.DirectoryUser
+		err := rows.Scan(&d.ID, &d.PublicID, &d.ExternalID, &d.Login, &d.Username, &d.Email, &d.PhoneNumber, &d.Active, &d.Created, &total)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%s: %v", op, err)
+		}
+		users = append(users, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	// A page past the end has no rows to carry the count.
+	if len(users) == 0 && q.Offset > 0 {
+		err := s.db.QueryRowContext(ctx, `
+			SELECT COUNT(*) FROM public.users
+			WHERE deleted_at IS NULL AND NOT is_guest
+				AND ($1 = '' OR lower(login) = lower($1))
+				AND ($2 = '' OR external_id = $2)
+		`, q.Login, q.ExternalID).Scan(&total)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%s: %v", op, err)
+		}
+	}
+
+	return users, total, nil
+}
+
+// SCIMUser returns the user with the public id
+func (s *Storage) SCIMUser(ctx context.Context, publicID string) (u.DirectoryUser, error) {
+	const op = "database.postgres.SCIMUser"
+
+	d, err := scanDirectoryUser(s.db.QueryRowContext(ctx, `
+		SELECT `+directoryColumns+` FROM public.users WHERE public_id = $1 AND deleted_at IS NULL AND NOT is_guest
+	`, publicID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return d, fmt.Errorf("%s: no users with id %v: %w", op, publicID, ErrNotFound)
+		}
+		return d, fmt.Errorf("%s: %v", op, err)
+	}
+	return d, nil
+}
+
+// SCIMCreateUser creates the user, without a password it can only sign in once one is set
+func (s *Storage) SCIMCreateUser(ctx context.Context, d u.DirectoryUser, pwd string) (u.DirectoryUser, error) {
+	const op = "database.postgres.SCIMCreateUser"
+
+	hash, err := hashOptional(pwd)
+	if err != nil {
+		return d, fmt.Errorf("%s: %v", op, err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return d, fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	created, err := scanDirectoryUser(tx.QueryRowContext(ctx, `
+		INSERT INTO public.users (login, username, email, phone_number, external_id, is_blocked, password, auth_source)
+		SELECT $1, $2, $3, $4, NULLIF($5, ''), NOT $6, $7, 'scim'
+		WHERE NOT EXISTS (SELECT 1 FROM public.login_history WHERE login = $1 AND released_until > NOW())
+		RETURNING `+directoryColumns,
+		d.Login, d.Username, d.Email, d.PhoneNumber, d.ExternalID, d.Active, hash))
+	if err != nil {
+		return d, fmt.Errorf("%s: %w", op, uniqueness(err))
+	}
+
+	if err := audit(ctx, tx, nil, AuditSCIMCreate, created.ID, created); err != nil {
+		return d, fmt.Errorf("%s: %v", op, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return d, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return created, nil
+}
+
+// SCIMUpdateUser replaces the user's attributes, the password only when pwd is not empty.
+// A deactivated user is blocked and signed out. Login changes made by the identity provider
+// skip the cooldown and the reservation of the old login.
+func (s *Storage) SCIMUpdateUser(ctx context.Context, d u.DirectoryUser, pwd string) (u.DirectoryUser, error) {
+	const op = "database.postgres.SCIMUpdateUser"
+
+	hash, err := hashOptional(pwd)
+	if err != nil {
+		return d, fmt.Errorf("%s: %v", op, err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return d, fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	before, err := scanDirectoryUser(tx.QueryRowContext(ctx, `
+		SELECT `+directoryColumns+` FROM public.users WHERE public_id = $1 AND deleted_at IS NULL AND NOT is_guest FOR UPDATE
+	`, d.PublicID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return d, fmt.Errorf("%s: no users with id %v: %w", op, d.PublicID, ErrNotFound)
+		}
+		return d, fmt.Errorf("%s: %v", op, err)
+	}
+
+	after, err := scanDirectoryUser(tx.QueryRowContext(ctx, `
+		UPDATE public.users SET login = $1, username = $2, email = $3, phone_number = $4, external_id = NULLIF($5, ''),
+			is_blocked = NOT $6, password = COALESCE($7, password)
+		WHERE id = $8
+		RETURNING `+directoryColumns,
+		d.Login, d.Username, d.Email, d.PhoneNumber, d.ExternalID, d.Active, hash, before.ID))
+	if err != nil {
+		return d, fmt.Errorf("%s: %w", op, uniqueness(err))
+	}
+
+	if !after.Active {
+		if _, err := revokeSessions(ctx, tx, after.ID); err != nil {
+			return d, fmt.Errorf("%s: %v", op, err)
+		}
+	}
+
+	details := map[string]any{"before": before, "after": after, "passwordChanged": hash != nil}
+	if err := audit(ctx, tx, nil, AuditSCIMUpdate, after.ID, details); err != nil {
+		return d, fmt.Errorf("%s: %v", op, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return d, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return after, nil
+}
+
+// SCIMDeleteUser deletes the user like an admin would and signs it out
+func (s *Storage) SCIMDeleteUser(ctx context.Context, publicID string) error {
+	const op = "database.postgres.SCIMDeleteUser"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	var id int
+	err = tx.QueryRowContext(ctx, `
+		UPDATE public.users SET deleted_at = NOW()
+		WHERE public_id = $1 AND deleted_at IS NULL AND NOT is_guest
+		RETURNING id
+	`, publicID).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s: no users with id %v: %w", op, publicID, ErrNotFound)
+		}
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if _, err := revokeSessions(ctx, tx, id); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	if err := audit(ctx, tx, nil, AuditSCIMDelete, id, map[string]any{"id": publicID}); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	return nil
+}
+
+// hashOptional hashes pwd, an empty one is NULL
+func hashOptional(pwd string) (any, error) {
+	if pwd == "" {
+		return nil, nil
+	}
+	hash, err := password.HashPassword(pwd)
+	if err != nil {
+		return nil, err
+	}
+	return string(hash), nil
+}
+
+// uniqueness maps a taken login, email or external id, and a reserved login, to ErrAlreadyExists
+func uniqueness(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("login is reserved: %w", ErrAlreadyExists)
+	}
+	if pgErr, ok := err.(*pq.Error); ok && pgErr.Code == "23505" {
+		return fmt.Errorf("%s: %w", pgErr.Constraint, ErrAlreadyExists)
+	}
+	return err
+}
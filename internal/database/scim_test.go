@@ -0,0 +1,62 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sabbatD/srest-api/internal/lib/userConfig"
+)
+
+func TestSCIMUsers(t *testing.T) {
+	s := testStorage(t)
+	ctx := context.Background()
+
+	s.db.Exec(`DELETE FROM public.users WHERE login = 'scimuser'`)
+	created, err := s.SCIMCreateUser(ctx, userConfig.DirectoryUser{Login: "scimuser", Username: "Scim User", Email: "scimuser@example.com", ExternalID: "ext-1", Active: true}, "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.db.Exec(`DELETE FROM public.users WHERE id = $1`, created.ID) })
+
+	if _, err := s.Auth(ctx, userConfig.AuthData{Login: "scimuser", Password: "password"}); err != nil {
+		t.Errorf("provisioned password: %v", err)
+	}
+	if _, err := s.SCIMCreateUser(ctx, userConfig.DirectoryUser{Login: "scimuser2", Email: "x@example.com", ExternalID: "ext-1"}, ""); !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("taken external id: err = %v, want ErrAlreadyExists", err)
+	}
+
+	users, total, err := s.SCIMUsers(ctx, userConfig.DirectoryQuery{ExternalID: "ext-1", Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 || len(users) != 1 || users[0].PublicID != created.PublicID {
+		t.Errorf("users by external id = %+v (%d)", users, total)
+	}
+
+	if err := s.SaveRefreshToken(ctx, "scim-token", created.ID, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	created.Active = false
+	updated, err := s.SCIMUpdateUser(ctx, created, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Active {
+		t.Error("deactivated user is active")
+	}
+	if _, id, _ := s.RefreshToken(ctx, "scim-token"); id != 0 {
+		t.Error("deactivated user kept the refresh token")
+	}
+	if _, err := s.Auth(ctx, userConfig.AuthData{Login: "scimuser", Password: "password"}); err != nil {
+		t.Errorf("an update without a password removed it: %v", err)
+	}
+
+	if err := s.SCIMDeleteUser(ctx, created.PublicID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.SCIMUser(ctx, created.PublicID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("deleted user: err = %v, want ErrNotFound", err)
+	}
+}
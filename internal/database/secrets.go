@@ -0,0 +1,142 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/sabbatD/srest-api/internal/lib/secrets"
+)
+
+// Secret returns the sealed secret, false when it is not set
+func (s *Storage) Secret(ctx context.Context, name string) ([]byte, bool, error) {
+	const op = "database.postgres.Secret"
+
+	var sealed []byte
+	err := s.db.QueryRowContext(ctx, `SELECT sealed FROM public.secrets WHERE name = $1`, name).Scan(&sealed)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return sealed, true, nil
+}
+
+// SealedSecrets returns every sealed secret by name
+func (s *Storage) SealedSecrets(ctx context.Context) (map[string][]byte, error) {
+	const op = "database.postgres.SealedSecrets"
+
+	rows, err := s.db.QueryContext(ctx, `SELECT name, sealed FROM public.secrets`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+	defer rows.Close()
+
+	all := map[string][]byte{}
+	for rows.Next() {
+		var name string
+		var sealed []byte
+		if err := rows.Scan(&name, &sealed); err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+		all[name] = sealed
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return all, nil
+}
+
+// SecretInfos returns the names of the stored secrets and when they were set, by name
+func (s *Storage) SecretInfos(ctx context.Context) ([]secrets.Info, error) {
+	const op = "database.postgres.SecretInfos"
+
+	rows, err := s.db.QueryContext(ctx, `SELECT name, updated FROM public.secrets ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+	defer rows.Close()
+
+	infos := []secrets.Info{}
+	for rows.Next() {
+		i := secrets.Info{Set: true}
+		if err := rows.Scan(&i.Name, &i.Updated); err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+		infos = append(infos, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return infos, nil
+}
+
+// SetSecret stores the sealed secret and records the change by actor in the audit log, without one for 0,
+// e.g. when secrets are resealed with a new master key. The audit log only gets the name.
+func (s *Storage) SetSecret(ctx context.Context, actor int, name string, sealed []byte) error {
+	const op = "database.postgres.SetSecret"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	var by any
+	if actor != 0 {
+		by = actor
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO public.secrets (name, sealed, updated_by, updated) VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (name) DO UPDATE SET sealed = EXCLUDED.sealed, updated_by = EXCLUDED.updated_by, updated = NOW()
+	`, name, sealed, by)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := audit(ctx, tx, by, AuditSetSecret, nil, map[string]string{"name": name}); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// DeleteSecret deletes the secret and records the deletion by actor in the audit log, ErrNotFound when it is not set
+func (s *Storage) DeleteSecret(ctx context.Context, actor int, name string) error {
+	const op = "database.postgres.DeleteSecret"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM public.secrets WHERE name = $1`, name)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	} else if n == 0 {
+		return fmt.Errorf("%s: no secret %s: %w", op, name, ErrNotFound)
+	}
+
+	if err := audit(ctx, tx, actor, AuditDeleteSecret, nil, map[string]string{"name": name}); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestSecrets(t *testing.T) {
+	s := testStorage(t)
+	ctx := context.Background()
+
+	admin := testUser(t, s, "secretsadmin")
+	const name = "test.secret"
+	t.Cleanup(func() { s.db.Exec(`DELETE FROM public.secrets WHERE name = $1`, name) })
+
+	if _, ok, err := s.Secret(ctx, name); ok || err != nil {
+		t.Errorf("Secret() before set = %v, %v", ok, err)
+	}
+
+	if err := s.SetSecret(ctx, admin, name, []byte("sealed-1")); err != nil {
+		t.Fatal(err)
+	}
+	// Resealing has no actor.
+	if err := s.SetSecret(ctx, 0, name, []byte("sealed-2")); err != nil {
+		t.Fatal(err)
+	}
+	got, ok, err := s.Secret(ctx, name)
+	if err != nil || !ok || !bytes.Equal(got, []byte("sealed-2")) {
+		t.Errorf("Secret() = %q, %v, %v", got, ok, err)
+	}
+
+	all, err := s.SealedSecrets(ctx)
+	if err != nil || !bytes.Equal(all[name], []byte("sealed-2")) {
+		t.Errorf("SealedSecrets() = %v, %v", all, err)
+	}
+	infos, err := s.SecretInfos(ctx)
+	found := false
+	for _, i := range infos {
+		found = found || i.Name == name && i.Set && i.Updated != nil
+	}
+	if err != nil || !found {
+		t.Errorf("SecretInfos() = %+v, %v", infos, err)
+	}
+
+	if err := s.DeleteSecret(ctx, admin, name); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteSecret(ctx, admin, name); !errors.Is(err, ErrNotFound) {
+		t.Errorf("DeleteSecret() twice = %v, want ErrNotFound", err)
+	}
+}
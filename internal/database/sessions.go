@@ -0,0 +1,61 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Logout ends a session of the user: their refresh token is deleted, the remembered device with deviceHash
+// when it is not empty, and the access token jti is revoked until it expires. A token without a JTI is not revoked.
+func (s *Storage) Logout(ctx context.Context, id int, deviceHash, jti string, expires time.Time) error {
+	const op = "database.postgres.Logout"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM public.tokens WHERE user_id = $1`, id); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if deviceHash != "" {
+		_, err := tx.ExecContext(ctx, `DELETE FROM public.devices WHERE user_id = $1 AND device_hash = $2`, id, deviceHash)
+		if err != nil {
+			return fmt.Errorf("%s: %v", op, err)
+		}
+	}
+
+	if jti != "" {
+		_, err := tx.ExecContext(ctx, `INSERT INTO public.revoked_tokens (jti, expires) VALUES ($1, $2) ON CONFLICT DO NOTHING`, jti, expires)
+		if err != nil {
+			return fmt.Errorf("%s: %v", op, err)
+		}
+	}
+
+	// Expired tokens fail their own check, their rows are no longer needed.
+	if _, err := tx.ExecContext(ctx, `DELETE FROM public.revoked_tokens WHERE expires < NOW()`); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// TokenRevoked reports whether the access token jti was revoked, see access.Denylist
+func (s *Storage) TokenRevoked(ctx context.Context, jti string) (bool, error) {
+	const op = "database.postgres.TokenRevoked"
+
+	var revoked bool
+	err := s.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM public.revoked_tokens WHERE jti = $1)`, jti).Scan(&revoked)
+	if err != nil {
+		return false, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return revoked, nil
+}
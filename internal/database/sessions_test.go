@@ -0,0 +1,53 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLogout(t *testing.T) {
+	s := testStorage(t)
+	ctx := context.Background()
+
+	id := testUser(t, s, "logoutuser")
+	expires := time.Now().Add(time.Hour)
+	t.Cleanup(func() { s.db.Exec(`DELETE FROM public.revoked_tokens WHERE jti IN ('logout-jti', 'logout-old')`) })
+
+	if err := s.SaveRefreshToken(ctx, "logoutrefresh", id, expires); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.RememberDevice(ctx, id, "Firefox", "logouttoken1", "logoutcookie1", expires); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.RememberDevice(ctx, id, "Safari", "logouttoken2", "logoutcookie2", expires); err != nil {
+		t.Fatal(err)
+	}
+	s.db.Exec(`INSERT INTO public.revoked_tokens (jti, expires) VALUES ('logout-old', NOW() - INTERVAL '1 minute')`)
+
+	if err := s.Logout(ctx, id, "logoutcookie1", "logout-jti", expires); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, refreshed, _ := s.RefreshToken(ctx, "logoutrefresh"); refreshed != 0 {
+		t.Error("the refresh token still works after logout")
+	}
+	// Only the device logged out from is forgotten
+	if devices, err := s.Devices(ctx, id); err != nil || len(devices) != 1 || devices[0].Name != "Safari" {
+		t.Errorf("devices after logout = %+v, %v", devices, err)
+	}
+
+	if revoked, err := s.TokenRevoked(ctx, "logout-jti"); err != nil || !revoked {
+		t.Errorf("TokenRevoked() = %v, %v, want true", revoked, err)
+	}
+	if revoked, _ := s.TokenRevoked(ctx, "logout-other"); revoked {
+		t.Error("a token that was not revoked is")
+	}
+	if revoked, _ := s.TokenRevoked(ctx, "logout-old"); revoked {
+		t.Error("an expired revoked token was not deleted")
+	}
+	// Logging out twice changes nothing
+	if err := s.Logout(ctx, id, "", "logout-jti", expires); err != nil {
+		t.Error(err)
+	}
+}
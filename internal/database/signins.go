@@ -0,0 +1,71 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	u "github.com/sabbatD/srest-api/internal/lib/userConfig"
+)
+
+// MaxSignIns is the number of sign ins kept per user, older ones are deleted as new ones are recorded
+const MaxSignIns = 100
+
+// RecordSignIn stores a successful sign in of the user
+func (s *Storage) RecordSignIn(ctx context.Context, id int, in u.SignIn) error {
+	const op = "database.postgres.RecordSignIn"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO public.sign_ins (user_id, method, ip, country, city, user_agent) VALUES ($1, $2, $3, $4, $5, $6)
+	`, id, in.Method, in.IP, in.Country, in.City, in.UserAgent)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM public.sign_ins WHERE user_id = $1 AND id NOT IN (
+			SELECT id FROM public.sign_ins WHERE user_id = $1 ORDER BY at DESC, id DESC LIMIT $2
+		)
+	`, id, MaxSignIns)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	return nil
+}
+
+// SignIns returns the user's latest sign ins, the most recent first
+func (s *Storage) SignIns(ctx context.Context, id, limit int) ([]u.SignIn, error) {
+	const op = "database.postgres.SignIns"
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT method, ip, country, city, user_agent, at FROM public.sign_ins WHERE user_id = $1
+		ORDER BY at DESC, id DESC LIMIT $2
+	`, id, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+	defer rows.Close()
+
+	signIns := []u.SignIn{}
+	for rows.Next() {
+		var in u.SignIn
+		if err := rows.Scan(&in.Method, &in.IP, &in.Country, &in.City, &in.UserAgent, &in.At); err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+		signIns = append(signIns, in)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return signIns, nil
+}
@@ -0,0 +1,38 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	u "github.com/sabbatD/srest-api/internal/lib/userConfig"
+)
+
+func TestSignIns(t *testing.T) {
+	s := testStorage(t)
+	ctx := context.Background()
+
+	id := testUser(t, s, "signins")
+	for i := 0; i < MaxSignIns+2; i++ {
+		if err := s.RecordSignIn(ctx, id, u.SignIn{Method: "password", IP: "192.0.2.1"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.RecordSignIn(ctx, id, u.SignIn{Method: "github", IP: "81.2.69.142", Country: "GB", City: "London", UserAgent: "curl/8.0"}); err != nil {
+		t.Fatal(err)
+	}
+
+	signIns, err := s.SignIns(ctx, id, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(signIns) != 5 || signIns[0].Method != "github" || signIns[0].Country != "GB" || signIns[0].City != "London" || signIns[0].UserAgent != "curl/8.0" {
+		t.Fatalf("sign ins = %+v", signIns)
+	}
+
+	// Only the latest sign ins are kept.
+	var n int
+	s.db.QueryRow(`SELECT COUNT(*) FROM public.sign_ins WHERE user_id = $1`, id).Scan(&n)
+	if n != MaxSignIns {
+		t.Errorf("kept %d sign ins, want %d", n, MaxSignIns)
+	}
+}
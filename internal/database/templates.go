@@ -0,0 +1,108 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/sabbatD/srest-api/internal/lib/mail"
+)
+
+// Email templates set by admins are settings keyed by the template name after this prefix,
+// followed by the locale for the ones not in the default locale
+const settingEmailTemplate = "email_template."
+
+func emailTemplateKey(name, locale string) string {
+	if locale == "" {
+		return settingEmailTemplate + name
+	}
+	return settingEmailTemplate + name + "." + locale
+}
+
+// EmailTemplate returns the template an admin set for name in locale, false when there is none
+func (s *Storage) EmailTemplate(ctx context.Context, name, locale string) (mail.Template, bool, error) {
+	const op = "database.postgres.EmailTemplate"
+
+	var t mail.Template
+	var data []byte
+	err := s.db.QueryRowContext(ctx, `SELECT value FROM public.settings WHERE key = $1`, emailTemplateKey(name, locale)).Scan(&data)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return t, false, nil
+		}
+		return t, false, fmt.Errorf("%s: %v", op, err)
+	}
+	if err := json.Unmarshal(data, &t); err != nil {
+		return t, false, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return t, true, nil
+}
+
+// SetEmailTemplate stores the template for name in locale and records the change by actor in the audit log
+func (s *Storage) SetEmailTemplate(ctx context.Context, actor int, name, locale string, t mail.Template) error {
+	const op = "database.postgres.SetEmailTemplate"
+
+	data, err := json.Marshal(t)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO public.settings (key, value, updated_by) VALUES ($1, $2, $3)
+		ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, updated_by = EXCLUDED.updated_by, updated = NOW()
+	`, emailTemplateKey(name, locale), data, actor)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := audit(ctx, tx, actor, AuditSetTemplate, nil, map[string]any{"name": name, "locale": locale, "template": t}); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// DeleteEmailTemplate deletes the template set for name in locale and records the change by actor in the audit log,
+// deleting a template that is not set changes nothing
+func (s *Storage) DeleteEmailTemplate(ctx context.Context, actor int, name, locale string) error {
+	const op = "database.postgres.DeleteEmailTemplate"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM public.settings WHERE key = $1`, emailTemplateKey(name, locale))
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	} else if n == 0 {
+		return nil
+	}
+
+	if err := audit(ctx, tx, actor, AuditResetTemplate, nil, map[string]any{"name": name, "locale": locale}); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,56 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sabbatD/srest-api/internal/lib/mail"
+)
+
+func TestEmailTemplates(t *testing.T) {
+	s := testStorage(t)
+	ctx := context.Background()
+
+	admin := testUser(t, s, "templatesadmin")
+	if err := s.DeleteEmailTemplate(ctx, admin, mail.TemplateAlert, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok, err := s.EmailTemplate(ctx, mail.TemplateAlert, ""); err != nil || ok {
+		t.Fatalf("before set: ok = %v, err = %v", ok, err)
+	}
+
+	want := mail.Template{Subject: "Alert {{.Kind}}", Body: "{{.Message}}"}
+	if err := s.SetEmailTemplate(ctx, admin, mail.TemplateAlert, "", want); err != nil {
+		t.Fatal(err)
+	}
+	got, ok, err := s.EmailTemplate(ctx, mail.TemplateAlert, "")
+	if err != nil || !ok || got != want {
+		t.Errorf("template = %+v, %v, %v", got, ok, err)
+	}
+
+	// Locales are kept apart from the default template
+	if err := s.DeleteEmailTemplate(ctx, admin, mail.TemplateAlert, "pt-BR"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := s.EmailTemplate(ctx, mail.TemplateAlert, "pt-BR"); ok {
+		t.Error("the default template is returned for pt-BR")
+	}
+	translated := mail.Template{Subject: "Alerta {{.Kind}}", Body: "{{.Message}}"}
+	if err := s.SetEmailTemplate(ctx, admin, mail.TemplateAlert, "pt-BR", translated); err != nil {
+		t.Fatal(err)
+	}
+	if got, _, _ := s.EmailTemplate(ctx, mail.TemplateAlert, "pt-BR"); got != translated {
+		t.Errorf("pt-BR template = %+v", got)
+	}
+	if err := s.DeleteEmailTemplate(ctx, admin, mail.TemplateAlert, "pt-BR"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.DeleteEmailTemplate(ctx, admin, mail.TemplateAlert, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := s.EmailTemplate(ctx, mail.TemplateAlert, ""); ok {
+		t.Error("template is still set after delete")
+	}
+}
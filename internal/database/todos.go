@@ -1,83 +1,117 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 
+	"github.com/lib/pq"
+	"github.com/sabbatD/srest-api/internal/lib/clock"
 	t "github.com/sabbatD/srest-api/internal/lib/todoConfig"
 )
 
-func (s *Storage) Create(t t.TodoRequest) (int64, error) {
+// All todo queries are scoped by user_id: a todo owned by another user
+// is indistinguishable from a missing one.
+
+// dueColumn selects the due date as YYYY-MM-DD, empty without one
+const dueColumn = `COALESCE(to_char(due, 'YYYY-MM-DD'), '')`
+
+// blockedCondition holds for todos with an open blocker, see AddBlocker
+const blockedCondition = `EXISTS (
+	SELECT 1 FROM public.todo_dependencies d JOIN public.todos b ON b.id = d.blocked_by
+	WHERE d.todo_id = todos.id AND NOT b.is_done
+)`
+
+func (s *Storage) Create(ctx context.Context, t t.TodoRequest, userID int) (int64, error) {
 	const op = "database.postgres.CreateTodo"
 
+	id, err := s.createTodo(ctx, nil, t, userID)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+// CreateWithID creates a todo with a client generated public id, used by offline sync.
+func (s *Storage) CreateWithID(ctx context.Context, publicID string, t t.TodoRequest, userID int) (int64, error) {
+	const op = "database.postgres.CreateTodoWithID"
+
+	id, err := s.createTodo(ctx, &publicID, t, userID)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) createTodo(ctx context.Context, publicID *string, t t.TodoRequest, userID int) (int64, error) {
 	query := `
-		INSERT INTO public.todos (title, is_done)
-		VALUES ($1, $2)
+		WITH v AS (SELECT nextval('public.todos_version_seq') AS version)
+		INSERT INTO public.todos (public_id, title, is_done, status, user_id, version, created_version, custom, due, created)
+		SELECT COALESCE($1::uuid, gen_random_uuid()), $2, $3, COALESCE(NULLIF($6, ''), CASE WHEN $3 THEN 'done' ELSE 'backlog' END),
+			$4, v.version, v.version, jsonb_strip_nulls($5), NULLIF($7, '')::date, $8 FROM v
+		WHERE NOT EXISTS (
+			SELECT 1 FROM public.users u LEFT JOIN public.user_limits l ON l.user_id = u.id
+			WHERE u.id = $4 AND COALESCE(l.max_todos, u.todo_limit) <= (SELECT COUNT(*) FROM public.todos WHERE user_id = $4)
+		)
 		RETURNING id
 	`
-	stmt, err := s.db.Prepare(query)
+	stmt, err := s.db.PrepareContext(ctx, query)
 	if err != nil {
-		return 0, fmt.Errorf("%s: %v", op, err)
+		return 0, err
 	}
 	defer stmt.Close()
 
-	var id int64
+	isDone := false
 	if t.IsDone != nil {
-		err = stmt.QueryRow(t.Title, *t.IsDone).Scan(&id)
-	} else {
-		err = stmt.QueryRow(t.Title, false).Scan(&id)
+		isDone = *t.IsDone
 	}
+	custom, err := customJSON(t.Custom)
 	if err != nil {
-		return 0, fmt.Errorf("%s: %v", op, err)
+		return 0, err
+	}
+
+	var id int64
+	if err := stmt.QueryRowContext(ctx, publicID, t.Title, isDone, userID, custom, t.Status, t.Due, clock.Now()).Scan(&id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("todo %w", ErrLimitReached)
+		}
+		if pgErr, ok := err.(*pq.Error); ok && pgErr.Code == "23505" {
+			return 0, fmt.Errorf("task id is taken: %w", ErrAlreadyExists)
+		}
+		return 0, err
 	}
 
 	return id, nil
 }
 
-func (s *Storage) Update(id int, t t.TodoRequest) (int64, error) {
+func (s *Storage) Update(ctx context.Context, id, userID int, t t.TodoRequest) (int64, error) {
 	const op = "database.postgres.UpdateTodo"
 
-	var stmt *sql.Stmt
-	var err error
-	var res sql.Result
-
-	if t.Title == "" {
-		stmt, err = s.db.Prepare(`UPDATE public.todos SET is_done = $1 WHERE id = $2`)
-		if err != nil {
-			return -1, fmt.Errorf("%s: %v", op, err)
-		}
-
-		res, err = stmt.Exec(*t.IsDone, id)
-		if err != nil {
-			return -1, fmt.Errorf("%s: %v", op, err)
-		}
-
-		stmt.Close()
+	stmt, err := s.db.PrepareContext(ctx, `
+		UPDATE public.todos
+		SET title = COALESCE(NULLIF($1, ''), title), is_done = COALESCE($2, is_done), status = COALESCE(NULLIF($6, ''), status),
+			due = CASE WHEN $7::text IS NULL THEN due ELSE NULLIF($7, '')::date END,
+			custom = jsonb_strip_nulls(custom || $5), version = nextval('public.todos_version_seq')
+		WHERE id = $3 AND user_id = $4
+	`)
+	if err != nil {
+		return -1, fmt.Errorf("%s: %v", op, err)
 	}
+	defer stmt.Close()
 
-	if t.IsDone == nil {
-		stmt, err = s.db.Prepare(`UPDATE public.todos SET title = $1 WHERE id = $2`)
-		if err != nil {
-			return -1, fmt.Errorf("%s: %v", op, err)
-		}
-		res, err = stmt.Exec(t.Title, id)
-		if err != nil {
-			return -1, fmt.Errorf("%s: %v", op, err)
-		}
-
-		stmt.Close()
+	// Custom changes are merged into the stored values, nulls remove them.
+	custom, err := customJSON(t.Custom)
+	if err != nil {
+		return -1, fmt.Errorf("%s: %v", op, err)
 	}
 
-	if t.Title != "" && t.IsDone != nil {
-		stmt, err = s.db.Prepare(`UPDATE public.todos SET title = $1, is_done = $2 WHERE id = $3`)
-		if err != nil {
-			return -1, fmt.Errorf("%s: %v", op, err)
-		}
-		res, err = stmt.Exec(t.Title, t.IsDone, id)
-		if err != nil {
-			return -1, fmt.Errorf("%s: %v", op, err)
-		}
-		stmt.Close()
+	res, err := stmt.ExecContext(ctx, t.Title, t.IsDone, id, userID, custom, t.Status, t.Due)
+	if err != nil {
+		return -1, fmt.Errorf("%s: %v", op, err)
 	}
 
 	n, err := res.RowsAffected()
@@ -86,117 +120,265 @@ func (s *Storage) Update(id int, t t.TodoRequest) (int64, error) {
 	}
 
 	if n == 0 {
-		return n, fmt.Errorf("%s: no task with id: %v", op, id)
+		return n, fmt.Errorf("%s: no task with id %v: %w", op, id, ErrNotFound)
 	}
 
 	return n, nil
 }
 
-func (s *Storage) Delete(id int) (int64, error) {
+func (s *Storage) Delete(ctx context.Context, id, userID int) (int64, error) {
 	const op = "database.postgres.DeleteTodo"
 
-	stmt, err := s.db.Prepare(`
-	DELETE FROM public.todos 
-		WHERE id = $1
-	`)
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return -1, fmt.Errorf("%s: %v", op, err)
 	}
-	defer stmt.Close()
-
-	res, err := stmt.Exec(id)
+	defer tx.Rollback()
+
+	var publicID string
+	err = tx.QueryRowContext(ctx, `
+	DELETE FROM public.todos
+		WHERE id = $1 AND user_id = $2
+		RETURNING public_id
+	`, id, userID).Scan(&publicID)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("%s: no task with id %v: %w", op, id, ErrNotFound)
+		}
 		return -1, fmt.Errorf("%s: %v", op, err)
 	}
 
-	n, err := res.RowsAffected()
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO public.todo_tombstones (public_id, user_id, version)
+		VALUES ($1, $2, nextval('public.todos_version_seq'))
+	`, publicID, userID)
 	if err != nil {
 		return -1, fmt.Errorf("%s: %v", op, err)
 	}
 
-	if n == 0 {
-		return n, fmt.Errorf("%s: no task with id: %v", op, id)
+	if err := tx.Commit(); err != nil {
+		return -1, fmt.Errorf("%s: %v", op, err)
 	}
 
-	return n, nil
+	return 1, nil
 }
 
-func (s *Storage) GetTodo(id int) (t.Todo, error) {
+func (s *Storage) GetTodo(ctx context.Context, id, userID int) (t.Todo, error) {
 	const op = "database.postgres.GetTodo"
 
-	rows, err := s.db.Query(`SELECT * FROM public.todos WHERE id = $1`, id)
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, public_id, title, created, `+dueColumn+`, status, is_done, custom, pinned_at IS NOT NULL, `+blockedCondition+`,
+			ARRAY(SELECT b.public_id FROM public.todo_dependencies d JOIN public.todos b ON b.id = d.blocked_by WHERE d.todo_id = todos.id ORDER BY b.id),
+			ARRAY(SELECT b.public_id FROM public.todo_dependencies d JOIN public.todos b ON b.id = d.todo_id WHERE d.blocked_by = todos.id ORDER BY b.id)
+		FROM public.todos WHERE id = $1 AND user_id = $2
+	`, id, userID)
 	if err != nil {
 		return t.Todo{}, fmt.Errorf("%s: %v", op, err)
 	}
 	defer rows.Close()
 
 	var todo t.Todo
+	var custom []byte
 
 	if rows.Next() {
-		if err := rows.Scan(&todo.ID, &todo.Title, &todo.Created, &todo.IsDone); err != nil {
+		err := rows.Scan(&todo.ID, &todo.PublicID, &todo.Title, &todo.Created, &todo.Due, &todo.Status, &todo.IsDone, &custom, &todo.Pinned, &todo.Blocked,
+			pq.Array(&todo.BlockedBy), pq.Array(&todo.Blocks))
+		if err != nil {
+			return t.Todo{}, fmt.Errorf("%s: %v", op, err)
+		}
+		if err := json.Unmarshal(custom, &todo.Custom); err != nil {
 			return t.Todo{}, fmt.Errorf("%s: %v", op, err)
 		}
 	} else {
-		return t.Todo{}, fmt.Errorf("%s: no such task", op)
+		return t.Todo{}, fmt.Errorf("%s: no such task: %w", op, ErrNotFound)
 	}
 
 	return todo, nil
 }
 
-func (s *Storage) OutputAll(filter string) ([]t.Todo, t.TodoInfo, int, error) {
+func (s *Storage) OutputAll(ctx context.Context, filter string, userID int) ([]t.Todo, t.TodoInfo, int, error) {
 	const op = "database.postgres.OutputAllTodos"
 
-	query := ``
-	switch filter {
-	case "all":
-		query = `SELECT * FROM public.todos ORDER BY id ASC`
+	var result []t.Todo
+	info, err := s.EachTodo(ctx, t.TodoQuery{Filter: filter}, userID, func(todo t.Todo) error {
+		result = append(result, todo)
+		return nil
+	})
+	if err != nil {
+		return nil, t.TodoInfo{}, 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return result, info, info.All, nil
+}
+
+// EachTodo calls fn for every task of the user matching q without keeping them in memory
+// and returns the counters over all of the user's tasks. Iteration stops at the first error returned by fn.
+func (s *Storage) EachTodo(ctx context.Context, q t.TodoQuery, userID int, fn func(t.Todo) error) (t.TodoInfo, error) {
+	const op = "database.postgres.EachTodo"
+
+	var info t.TodoInfo
+
+	query := `
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE is_done), COUNT(*) FILTER (WHERE NOT is_done)
+		FROM public.todos WHERE user_id = $1
+	`
+	if err := s.db.QueryRowContext(ctx, query, userID).Scan(&info.All, &info.Completed, &info.InWork); err != nil {
+		return info, fmt.Errorf("%s: %v", op, err)
+	}
+
+	// An empty object matches every task.
+	custom, err := customJSON(q.Custom)
+	if err != nil {
+		return info, fmt.Errorf("%s: %v", op, err)
+	}
+
+	query = `
+		SELECT id, public_id, title, created, ` + dueColumn + `, status, is_done, custom, pinned_at IS NOT NULL, ` + blockedCondition + ` FROM public.todos
+		WHERE user_id = $1 AND custom @> $2 AND ($3 = '' OR status = $3)
+	`
+	switch q.Filter {
 	case "completed":
-		query = `SELECT * FROM public.todos WHERE is_done = true ORDER BY id ASC`
+		query += ` AND is_done = true`
 	case "inWork":
-		query = `SELECT * FROM public.todos WHERE is_done = false ORDER BY id ASC`
-	default:
-		query = `SELECT * FROM public.todos ORDER BY id ASC`
+		query += ` AND is_done = false`
 	}
+	if q.Blocked != nil {
+		if *q.Blocked {
+			query += ` AND ` + blockedCondition
+		} else {
+			query += ` AND NOT ` + blockedCondition
+		}
+	}
+	args := []any{userID, custom, q.Status}
+	if q.DueFrom != "" {
+		args = append(args, q.DueFrom)
+		query += fmt.Sprintf(` AND due >= $%d::date`, len(args))
+	}
+	if q.DueTo != "" {
+		args = append(args, q.DueTo)
+		query += fmt.Sprintf(` AND due <= $%d::date`, len(args))
+	}
+	// Pinned tasks come first.
+	query += ` ORDER BY pinned_at IS NULL, id ASC`
 
-	rows, err := s.db.Query(query)
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, t.TodoInfo{}, 0, fmt.Errorf("%s: %v", op, err)
+		return info, fmt.Errorf("%s: %v", op, err)
 	}
 	defer rows.Close()
 
-	var result []t.Todo
-	var todo t.Todo
-
 	for rows.Next() {
-		if err := rows.Scan(&todo.ID, &todo.Title, &todo.Created, &todo.IsDone); err != nil {
-			return nil, t.TodoInfo{}, 0, fmt.Errorf("%s: %v", op, err)
+		var todo t.Todo
+		var custom []byte
+		if err := rows.Scan(&todo.ID, &todo.PublicID, &todo.Title, &todo.Created, &todo.Due, &todo.Status, &todo.IsDone, &custom, &todo.Pinned, &todo.Blocked); err != nil {
+			return info, fmt.Errorf("%s: %v", op, err)
+		}
+		if err := json.Unmarshal(custom, &todo.Custom); err != nil {
+			return info, fmt.Errorf("%s: %v", op, err)
 		}
 
-		result = append(result, todo)
+		if err := fn(todo); err != nil {
+			return info, err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return info, fmt.Errorf("%s: %v", op, err)
 	}
 
-	var info t.TodoInfo
+	return info, nil
+}
 
-	query = `SELECT is_done FROM public.todos`
+func (s *Storage) TodoID(ctx context.Context, publicID string, userID int) (int, error) {
+	const op = "database.postgres.TodoID"
 
-	rows, err = s.db.Query(query)
+	var id int
+	err := s.db.QueryRowContext(ctx, `SELECT id FROM public.todos WHERE public_id = $1 AND user_id = $2`, publicID, userID).Scan(&id)
 	if err != nil {
-		return nil, t.TodoInfo{}, 0, fmt.Errorf("%s: %v", op, err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("%s: no task with id %v: %w", op, publicID, ErrNotFound)
+		}
+		return 0, fmt.Errorf("%s: %v", op, err)
 	}
 
-	var done bool
+	return id, nil
+}
+
+// Changes returns up to limit changes of the user's todos with a version above since,
+// ordered by version. The returned cursor is the version of the last change,
+// or since when there are none.
+// Versions of a user's todos are assigned by a trigger under a per-user lock held until commit,
+// so they grow in commit order and a write cannot commit behind the returned cursor.
+func (s *Storage) Changes(ctx context.Context, userID int, since int64, limit int) ([]t.Change, int64, error) {
+	const op = "database.postgres.TodoChanges"
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT version, created_version > $2, public_id, title, created, `+dueColumn+`, status, is_done, custom, pinned_at IS NOT NULL, FALSE
+		FROM public.todos WHERE user_id = $1 AND version > $2
+		UNION ALL
+		SELECT version, FALSE, public_id, '', deleted_at, '', '', FALSE, '{}', FALSE, TRUE
+		FROM public.todo_tombstones WHERE user_id = $1 AND version > $2
+		ORDER BY 1 ASC
+		LIMIT $3
+	`, userID, since, limit)
+	if err != nil {
+		return nil, since, fmt.Errorf("%s: %v", op, err)
+	}
+	defer rows.Close()
+
+	cursor := since
+	changes := []t.Change{}
 	for rows.Next() {
-		if err := rows.Scan(&done); err != nil {
-			return nil, t.TodoInfo{}, 0, fmt.Errorf("%s: %v", op, err)
+		var todo t.Todo
+		var custom []byte
+		var created, deleted bool
+		if err := rows.Scan(&cursor, &created, &todo.PublicID, &todo.Title, &todo.Created, &todo.Due, &todo.Status, &todo.IsDone, &custom, &todo.Pinned, &deleted); err != nil {
+			return nil, since, fmt.Errorf("%s: %v", op, err)
+		}
+		if err := json.Unmarshal(custom, &todo.Custom); err != nil {
+			return nil, since, fmt.Errorf("%s: %v", op, err)
 		}
 
-		if done {
-			info.Completed++
-		} else {
-			info.InWork++
+		switch {
+		case deleted:
+			changes = append(changes, t.Change{Type: t.ChangeDeleted, ID: todo.PublicID})
+		case created:
+			changes = append(changes, t.Change{Type: t.ChangeCreated, ID: todo.PublicID, Todo: &todo})
+		default:
+			changes = append(changes, t.Change{Type: t.ChangeUpdated, ID: todo.PublicID, Todo: &todo})
 		}
-		info.All++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, since, fmt.Errorf("%s: %v", op, err)
 	}
 
-	return result, info, info.All, nil
+	return changes, cursor, nil
+}
+
+// SyncState returns the internal id and version of the user's todo,
+// or the tombstone version when it was deleted.
+func (s *Storage) SyncState(ctx context.Context, publicID string, userID int) (t.SyncState, error) {
+	const op = "database.postgres.TodoSyncState"
+
+	var state t.SyncState
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, version, FALSE FROM public.todos WHERE public_id = $1 AND user_id = $2
+		UNION ALL
+		SELECT 0, version, TRUE FROM public.todo_tombstones WHERE public_id = $1 AND user_id = $2
+	`, publicID, userID).Scan(&state.ID, &state.Version, &state.Deleted)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return state, fmt.Errorf("%s: no task with id %v: %w", op, publicID, ErrNotFound)
+		}
+		return state, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return state, nil
+}
+
+// customJSON encodes custom field values for a JSONB parameter, nil as an empty object
+func customJSON(values map[string]any) ([]byte, error) {
+	if values == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(values)
 }
@@ -0,0 +1,132 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	todoconfig "github.com/sabbatD/srest-api/internal/lib/todoConfig"
+	"github.com/sabbatD/srest-api/internal/lib/userConfig"
+)
+
+// testStorage connects to the database from SAPI_TEST_DB and applies migrations.
+// Tests using it are skipped when the variable is not set.
+func testStorage(t *testing.T) *Storage {
+	t.Helper()
+
+	dbStr := os.Getenv("SAPI_TEST_DB")
+	if dbStr == "" {
+		t.Skip("SAPI_TEST_DB is not set")
+	}
+
+	s, err := SetupDataBase(dbStr, "test", slog.Default(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := runMigrations(s.db.DB, "migrations"); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func testUser(t *testing.T, s *Storage, login string) int {
+	t.Helper()
+	ctx := context.Background()
+
+	s.db.Exec(`DELETE FROM public.users WHERE login = $1`, login)
+
+	id, err := s.Add(ctx, userConfig.User{Login: login, Username: login, Password: "password", Email: login + "@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.db.Exec(`DELETE FROM public.users WHERE id = $1`, id) })
+	return id
+}
+
+func TestTodoOwnership(t *testing.T) {
+	s := testStorage(t)
+	ctx := context.Background()
+
+	owner := testUser(t, s, "ownera")
+	stranger := testUser(t, s, "strangerb")
+
+	id, err := s.Create(ctx, todoconfig.TodoRequest{Title: "secret"}, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	todo, err := s.GetTodo(ctx, int(id), owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := true
+	checks := map[string]error{}
+	_, checks["GetTodo"] = s.GetTodo(ctx, int(id), stranger)
+	_, checks["TodoID"] = s.TodoID(ctx, todo.PublicID, stranger)
+	_, checks["Update"] = s.Update(ctx, int(id), stranger, todoconfig.TodoRequest{Title: "pwned", IsDone: &done})
+	_, checks["Delete"] = s.Delete(ctx, int(id), stranger)
+
+	for name, err := range checks {
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s by stranger: err = %v, want ErrNotFound", name, err)
+		}
+	}
+
+	todos, _, _, err := s.OutputAll(ctx, "all", stranger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(todos) != 0 {
+		t.Errorf("stranger sees %d tasks, want 0", len(todos))
+	}
+
+	if got, _ := s.GetTodo(ctx, int(id), owner); got.Title != "secret" || got.IsDone {
+		t.Errorf("task = %+v, stranger must not modify it", got)
+	}
+}
+
+func TestChangesCommitOrder(t *testing.T) {
+	s := testStorage(t)
+	ctx := context.Background()
+
+	id := testUser(t, s, "changesorder")
+
+	// A write in progress holds the user's versions, a later write waits for it to commit.
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `INSERT INTO public.todos (title, user_id) VALUES ('first', $1)`, id); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := s.Create(ctx, todoconfig.TodoRequest{Title: "second"}, id)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		t.Fatalf("second write committed before the first, err = %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	changes, _, err := s.Changes(ctx, id, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 || changes[0].Todo.Title != "first" || changes[1].Todo.Title != "second" {
+		t.Errorf("changes = %+v, want first then second", changes)
+	}
+}
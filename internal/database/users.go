@@ -1,14 +1,21 @@
 package database
 
 import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/lib/pq"
+	"github.com/sabbatD/srest-api/internal/lib/clock"
+	rc "github.com/sabbatD/srest-api/internal/lib/roleConfig"
 	u "github.com/sabbatD/srest-api/internal/lib/userConfig"
 	"github.com/sabbatD/srest-api/internal/password"
 )
 
-func (s *Storage) Add(u u.User) (int, error) {
+func (s *Storage) Add(ctx context.Context, u u.User) (int, error) {
 	const op = "database.postgres.Add"
 
 	pwd, err := password.HashPassword(u.Password)
@@ -16,16 +23,21 @@ func (s *Storage) Add(u u.User) (int, error) {
 		return 0, fmt.Errorf("%s: %v", op, err)
 	}
 
+	// Logins released by a rename are reserved for a while, nobody else may register them.
 	var id int
-	err = s.db.QueryRow(`
+	err = s.db.QueryRowContext(ctx, `
 		INSERT INTO public.users (login, username, email, password, phone_number)
-		VALUES ($1, $2, $3, $4, $5)
+		SELECT $1, $2, $3, $4, $5
+		WHERE NOT EXISTS (SELECT 1 FROM public.login_history WHERE login = $1 AND released_until > NOW())
 		RETURNING id
 	`, u.Login, u.Username, u.Email, string(pwd), u.PhoneNumber).Scan(&id)
 
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("%s: login %w", op, ErrAlreadyExists)
+		}
 		if pgErr, ok := err.(*pq.Error); ok && pgErr.Code == "23505" { // Код ошибки 23505 означает нарушение уникальности
-			return 0, fmt.Errorf("%s: user already exists", op)
+			return 0, fmt.Errorf("%s: user %w", op, ErrAlreadyExists)
 		}
 		return 0, fmt.Errorf("%s: %v", op, err)
 	}
@@ -33,61 +45,65 @@ func (s *Storage) Add(u u.User) (int, error) {
 	return id, nil
 }
 
-func (s *Storage) Auth(u u.AuthData) (user u.TableUser, err error) {
+func (s *Storage) Auth(ctx context.Context, u u.AuthData) (user u.TableUser, err error) {
 	const op = "database.postgres.Auth"
 
-	stmt, err := s.db.Prepare(`SELECT password FROM public.users WHERE login = $1`)
+	stmt, err := s.db.PrepareContext(ctx, `SELECT password FROM public.users WHERE login = $1 AND deleted_at IS NULL`)
 	if err != nil {
-		return user, fmt.Errorf("%s.s.db.Prepare(`SELECT password FROM public.users WHERE login = $1`): %v", op, err)
+		return user, fmt.Errorf("%s.s.db.PrepareContext(ctx, `SELECT password FROM public.users WHERE login = $1 AND deleted_at IS NULL`): %v", op, err)
 	}
 	defer stmt.Close()
 
 	var pwd string
 
-	if err = stmt.QueryRow(u.Login).Scan(&pwd); err != nil {
-		return user, fmt.Errorf("%s.stmt.QueryRow(u.Login): %v", op, err)
+	if err = stmt.QueryRowContext(ctx, u.Login).Scan(&pwd); err != nil {
+		return user, fmt.Errorf("%s.stmt.QueryRowContext(ctx, u.Login): %v", op, err)
 	}
 
 	if err := password.CheckPassword([]byte(pwd), u.Password); err != nil {
 		return user, fmt.Errorf("%s.password.CheckPassword: %v", op, err)
 	}
 
-	stmt, err = s.db.Prepare(`SELECT id, username, email, date, is_blocked, is_admin FROM public.users WHERE login = $1`)
+	stmt, err = s.db.PrepareContext(ctx, `SELECT id, public_id, username, email, locale, date, is_blocked, must_change_password, is_verified, `+roleColumns+` FROM public.users WHERE login = $1`)
 	if err != nil {
-		return user, fmt.Errorf("%s.s.db.Prepare(`SELECT id, username, email, date, is_blocked, is_admin FROM public.users WHERE login = $1`): %v", op, err)
+		return user, fmt.Errorf("%s.s.db.PrepareContext(ctx, `SELECT id, public_id, username, email, locale, date, is_blocked, must_change_password, is_verified, role, permissions FROM public.users WHERE login = $1`): %v", op, err)
 	}
 
-	err = stmt.QueryRow(u.Login).Scan(&user.ID, &user.Username, &user.Email, &user.Date, &user.IsBlocked, &user.IsAdmin)
+	err = stmt.QueryRowContext(ctx, u.Login).Scan(&user.ID, &user.PublicID, &user.Username, &user.Email, &user.Locale, &user.Date, &user.IsBlocked, &user.MustChangePassword, &user.IsVerified, &user.Role, pq.Array(&user.Permissions))
 	if err != nil {
-		return user, fmt.Errorf("%s.stmt.QueryRow(u.Login).Scan(user): %v", op, err)
-	}
-	if user.IsBlocked {
-		user.IsAdmin = false
+		return user, fmt.Errorf("%s.stmt.QueryRowContext(ctx, u.Login).Scan(user): %v", op, err)
 	}
+	grant(&user)
+	disarm(&user)
 
 	return user, nil
 }
 
-func (s *Storage) UpdateField(field string, id int, val any) (int64, error) {
+// UpdateField sets the block or must_change_password flag of the user, other fields are -2.
+// Blocking the last admin is ErrConflict, roles are assigned with SetUserRole.
+func (s *Storage) UpdateField(ctx context.Context, field string, id int, val any) (int64, error) {
 	const op = "database.postgres.UpdateUserField"
 
+	var query string
 	switch field {
-	case "admin":
-		field = "is_admin"
 	case "block":
-		field = "is_blocked"
+		if val == true {
+			return s.block(ctx, id)
+		}
+		query = `UPDATE public.users SET is_blocked = $1 WHERE id = $2`
+	case "must_change_password":
+		query = `UPDATE public.users SET must_change_password = $1 WHERE id = $2`
 	default:
 		return -2, fmt.Errorf("%s: no such field: %v", op, field)
 	}
-	query := fmt.Sprintf(`UPDATE public.users SET %s = $1 WHERE id = $2`, field)
 
-	stmt, err := s.db.Prepare(query)
+	stmt, err := s.db.PrepareContext(ctx, query)
 	if err != nil {
 		return -1, fmt.Errorf("%s: %v with parameters:%v, %v, %v", op, err, field, id, val)
 	}
 	defer stmt.Close()
 
-	res, err := stmt.Exec(val, id)
+	res, err := stmt.ExecContext(ctx, val, id)
 	if err != nil {
 		return -1, fmt.Errorf("%s: %v with parameters:%v, %v, %v", op, err, field, id, val)
 	}
@@ -98,25 +114,67 @@ func (s *Storage) UpdateField(field string, id int, val any) (int64, error) {
 	}
 
 	if n == 0 {
-		return n, fmt.Errorf("%s: no users with id: %v", op, id)
+		return n, fmt.Errorf("%s: no users with id %v: %w", op, id, ErrNotFound)
 	}
 
 	return n, nil
 }
 
-func (s *Storage) Remove(id int) (int64, error) {
+// block blocks the user, the last admin is ErrConflict
+func (s *Storage) block(ctx context.Context, id int) (int64, error) {
+	const op = "database.postgres.BlockUser"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return -1, fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	if err := lockAdmins(ctx, tx); err != nil {
+		return -1, fmt.Errorf("%s: %v", op, err)
+	}
+
+	var role string
+	err = tx.QueryRowContext(ctx, `SELECT role FROM public.users WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, id).Scan(&role)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("%s: no users with id %v: %w", op, id, ErrNotFound)
+		}
+		return -1, fmt.Errorf("%s: %v", op, err)
+	}
+	if role == rc.Admin {
+		if last, err := lastAdmin(ctx, tx, id); err != nil {
+			return -1, fmt.Errorf("%s: %v", op, err)
+		} else if last {
+			return -1, fmt.Errorf("%s: user %v is the last admin: %w", op, id, ErrConflict)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE public.users SET is_blocked = TRUE WHERE id = $1`, id); err != nil {
+		return -1, fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return -1, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return 1, nil
+}
+
+// Remove soft deletes the user and signs them out
+func (s *Storage) Remove(ctx context.Context, id int) (int64, error) {
 	const op = "database.postgres.RemoveUser"
 
-	stmt, err := s.db.Prepare(`
-	DELETE FROM public.users 
-		WHERE id = $1
-	`)
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return -1, fmt.Errorf("%s: %v", op, err)
 	}
-	defer stmt.Close()
+	defer tx.Rollback()
 
-	res, err := stmt.Exec(id)
+	res, err := tx.ExecContext(ctx, `
+	UPDATE public.users SET deleted_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL
+	`, id)
 	if err != nil {
 		return -1, fmt.Errorf("%s: %v", op, err)
 	}
@@ -127,97 +185,186 @@ func (s *Storage) Remove(id int) (int64, error) {
 	}
 
 	if n == 0 {
-		return n, fmt.Errorf("%s: no users with id: %v", op, id)
+		return n, fmt.Errorf("%s: no users with id %v: %w", op, id, ErrNotFound)
+	}
+
+	if _, err := revokeSessions(ctx, tx, id); err != nil {
+		return -1, fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return -1, fmt.Errorf("%s: %v", op, err)
 	}
 
 	return n, nil
 }
 
-func (s *Storage) All(q u.GetAllQuery) (result u.MetaResponse, E error) {
+// stateCondition maps a user state to its WHERE condition.
+// States are exclusive: deleted wins over blocked, blocked wins over pending.
+var stateCondition = map[string]string{
+	u.StateActive:  `deleted_at IS NULL AND NOT is_blocked AND is_verified`,
+	u.StateBlocked: `deleted_at IS NULL AND is_blocked`,
+	u.StateDeleted: `deleted_at IS NOT NULL`,
+	u.StatePending: `deleted_at IS NULL AND NOT is_blocked AND NOT is_verified`,
+}
+
+func (s *Storage) All(ctx context.Context, q u.GetAllQuery) (result u.MetaResponse, E error) {
 	const op = "database.postgres.GetAllUsers"
 
-	query := `SELECT 1 FROM public.users`
+	result.Meta, E = s.EachUser(ctx, q, func(user u.TableUser) error {
+		result.Data = append(result.Data, user)
+		return nil
+	})
+	if E != nil {
+		return result, fmt.Errorf("%s: %v", op, E)
+	}
 
-	rows, err := s.db.Query(query)
-	if err != nil {
-		return result, fmt.Errorf("%s: %v", op, err)
+	return result, nil
+}
+
+// EachUser calls fn for every user matching q without keeping them in memory, guests are left out.
+// Iteration stops at the first error returned by fn.
+func (s *Storage) EachUser(ctx context.Context, q u.GetAllQuery, fn func(u.TableUser) error) (meta u.Meta, E error) {
+	const op = "database.postgres.EachUser"
+
+	query := `
+		SELECT COUNT(*),
+			COUNT(*) FILTER (WHERE ` + stateCondition[u.StateActive] + `),
+			COUNT(*) FILTER (WHERE ` + stateCondition[u.StateBlocked] + `),
+			COUNT(*) FILTER (WHERE ` + stateCondition[u.StateDeleted] + `),
+			COUNT(*) FILTER (WHERE ` + stateCondition[u.StatePending] + `)
+		FROM public.users
+		WHERE NOT is_guest
+	`
+
+	sum := &meta.Summary
+	if err := s.db.QueryRowContext(ctx, query).Scan(&meta.TotalAmount, &sum.Active, &sum.Blocked, &sum.Deleted, &sum.Pending); err != nil {
+		return meta, fmt.Errorf("%s: %v", op, err)
 	}
-	defer rows.Close()
+	meta.SortBy, meta.SortOrder, meta.State = q.SortBy, q.SortOrder, q.State
 
-	for rows.Next() {
-		result.Meta.TotalAmount++
+	// Without an explicit state the legacy isBlocked filter applies to not deleted users.
+	args := []any{q.SearchTerm, q.Limit, q.Offset}
+	filter, ok := stateCondition[q.State]
+	if !ok {
+		filter = `deleted_at IS NULL AND is_blocked = $4`
+		args = append(args, q.IsBlocked)
+	}
+	if len(q.Custom) > 0 {
+		data, err := json.Marshal(q.Custom)
+		if err != nil {
+			return meta, fmt.Errorf("%s: %v", op, err)
+		}
+		args = append(args, data)
+		filter += fmt.Sprintf(` AND custom @> $%d`, len(args))
+	}
+	if q.Role != "" {
+		args = append(args, q.Role)
+		filter += fmt.Sprintf(` AND role = $%d`, len(args))
 	}
-	result.Meta.SortBy, result.Meta.SortOrder = q.SortBy, q.SortOrder
 
 	query = `
-		SELECT id, username, email, date, is_blocked, is_admin
+		SELECT id, public_id, username, email, date, is_blocked, must_change_password, is_verified, custom, ` + roleColumns + `
 		FROM public.users
-		WHERE ($1 = '' OR username ILIKE '%' || $1 || '%' OR email ILIKE '%' || $1 || '%')
-		AND is_blocked = $2
+		WHERE ($1 = '' OR username ILIKE '%' || $1 || '%' OR email ILIKE '%' || $1 || '%' OR login ILIKE '%' || $1 || '%'
+			OR id IN (SELECT user_id FROM public.login_history WHERE login ILIKE '%' || $1 || '%'))
+		AND NOT is_guest AND ` + filter + `
 		ORDER BY ` + q.SortBy + ` ` + q.SortOrder + `
-		LIMIT $3 OFFSET $4;
+		LIMIT $2 OFFSET $3;
 	`
 
-	rows, err = s.db.Query(query, q.SearchTerm, q.IsBlocked, q.Limit, q.Offset)
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return result, fmt.Errorf("%s: %v", op, err)
+		return meta, fmt.Errorf("%s: %v", op, err)
 	}
+	defer rows.Close()
 
-	var user u.TableUser
-	var users []u.TableUser
 	for rows.Next() {
-		if err := rows.Scan(&user.ID, &user.Username, &user.Email, &user.Date, &user.IsBlocked, &user.IsAdmin); err != nil {
-			return result, fmt.Errorf("%s: %v", op, err)
+		var user u.TableUser
+		var custom []byte
+		if err := rows.Scan(&user.ID, &user.PublicID, &user.Username, &user.Email, &user.Date, &user.IsBlocked, &user.MustChangePassword, &user.IsVerified, &custom, &user.Role, pq.Array(&user.Permissions)); err != nil {
+			return meta, fmt.Errorf("%s: %v", op, err)
+		}
+		grant(&user)
+		if err := json.Unmarshal(custom, &user.Custom); err != nil {
+			return meta, fmt.Errorf("%s: %v", op, err)
 		}
 
-		users = append(users, user)
+		if err := fn(user); err != nil {
+			return meta, err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return meta, fmt.Errorf("%s: %v", op, err)
 	}
 
-	result.Data = users
-
-	return result, nil
+	return meta, nil
 }
 
-func (s *Storage) Get(id int) (u.TableUser, error) {
+func (s *Storage) Get(ctx context.Context, id int) (u.TableUser, error) {
 	const op = "database.postgres.GetUser"
 
-	rows, err := s.db.Query(`SELECT id, username, email, date, is_blocked, is_admin, phone_number FROM public.users WHERE id = $1`, id)
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, public_id, username, email, date, is_blocked, must_change_password, is_verified,
+			CASE WHEN auth_source = 'local' AND password <> '' THEN password_changed END, phone_number, locale, custom, `+roleColumns+`
+		FROM public.users WHERE id = $1 AND deleted_at IS NULL
+	`, id)
 	if err != nil {
 		return u.TableUser{}, fmt.Errorf("%s: %v", op, err)
 	}
 	defer rows.Close()
 
 	var user u.TableUser
+	var custom []byte
 
 	if rows.Next() {
-		if err := rows.Scan(&user.ID, &user.Username, &user.Email, &user.Date, &user.IsBlocked, &user.IsAdmin, &user.PhoneNumber); err != nil {
+		if err := rows.Scan(&user.ID, &user.PublicID, &user.Username, &user.Email, &user.Date, &user.IsBlocked, &user.MustChangePassword, &user.IsVerified, &user.PasswordChanged, &user.PhoneNumber, &user.Locale, &custom, &user.Role, pq.Array(&user.Permissions)); err != nil {
+			return u.TableUser{}, fmt.Errorf("%s: %v", op, err)
+		}
+		grant(&user)
+		if err := json.Unmarshal(custom, &user.Custom); err != nil {
 			return u.TableUser{}, fmt.Errorf("%s: %v", op, err)
 		}
 	} else {
-		return u.TableUser{}, fmt.Errorf("%s: no such user", op)
+		return u.TableUser{}, fmt.Errorf("%s: no such user: %w", op, ErrNotFound)
 	}
 
 	return user, nil
 }
 
-func (s *Storage) UpdateUser(u u.PutUser, id int) (int64, error) {
+func (s *Storage) UserID(ctx context.Context, publicID string) (int, error) {
+	const op = "database.postgres.UserID"
+
+	var id int
+	err := s.db.QueryRowContext(ctx, `SELECT id FROM public.users WHERE public_id = $1`, publicID).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("%s: no users with id %v: %w", op, publicID, ErrNotFound)
+		}
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) UpdateUser(ctx context.Context, u u.PutUser, id int) (int64, error) {
 	const op = "database.postgres.UpdateUser"
 
 	var exists bool
-	stmt, err := s.db.Prepare(`SELECT EXISTS (SELECT 1 FROM public.users WHERE email = $1)`)
+	stmt, err := s.db.PrepareContext(ctx, `SELECT EXISTS (SELECT 1 FROM public.users WHERE email = $1)`)
 	if err != nil {
 		return -1, fmt.Errorf("%s: %v", op, err)
 	}
 	defer stmt.Close()
 
-	if err = stmt.QueryRow(u.Email).Scan(&exists); err != nil {
+	if err = stmt.QueryRowContext(ctx, u.Email).Scan(&exists); err != nil {
 		return -1, fmt.Errorf("%s: %v", op, err)
 	}
 	if exists {
 		return -2, fmt.Errorf("%s: email already used", op)
 	}
 
-	tx, err := s.db.Begin()
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return -1, fmt.Errorf("%s: %v", op, err)
 	}
@@ -225,21 +372,40 @@ func (s *Storage) UpdateUser(u u.PutUser, id int) (int64, error) {
 	defer tx.Rollback()
 
 	if u.Username != "" {
-		_, err = tx.Exec(`UPDATE public.users SET username = $1 WHERE id = $2`, u.Username, id)
+		_, err = tx.ExecContext(ctx, `UPDATE public.users SET username = $1 WHERE id = $2`, u.Username, id)
 		if err != nil {
 			return -1, fmt.Errorf("%s: %v", op, err)
 		}
 	}
 
 	if u.Email != "" {
-		_, err = tx.Exec(`UPDATE public.users SET email = $1 WHERE id = $2`, u.Email, id)
+		_, err = tx.ExecContext(ctx, `UPDATE public.users SET email = $1 WHERE id = $2`, u.Email, id)
 		if err != nil {
 			return -1, fmt.Errorf("%s: %v", op, err)
 		}
 	}
 
 	if u.PhoneNumber != "" {
-		_, err = tx.Exec(`UPDATE public.users SET phone_number = $1 WHERE id = $2`, u.PhoneNumber, id)
+		_, err = tx.ExecContext(ctx, `UPDATE public.users SET phone_number = $1 WHERE id = $2`, u.PhoneNumber, id)
+		if err != nil {
+			return -1, fmt.Errorf("%s: %v", op, err)
+		}
+	}
+
+	if u.Locale != "" {
+		_, err = tx.ExecContext(ctx, `UPDATE public.users SET locale = $1 WHERE id = $2`, u.Locale, id)
+		if err != nil {
+			return -1, fmt.Errorf("%s: %v", op, err)
+		}
+	}
+
+	// Changes are merged into the stored values, nulls remove them.
+	if len(u.Custom) > 0 {
+		data, err := json.Marshal(u.Custom)
+		if err != nil {
+			return -1, fmt.Errorf("%s: %v", op, err)
+		}
+		_, err = tx.ExecContext(ctx, `UPDATE public.users SET custom = jsonb_strip_nulls(custom || $1) WHERE id = $2`, data, id)
 		if err != nil {
 			return -1, fmt.Errorf("%s: %v", op, err)
 		}
@@ -252,21 +418,22 @@ func (s *Storage) UpdateUser(u u.PutUser, id int) (int64, error) {
 	return 1, nil
 }
 
-func (s *Storage) SaveRefreshToken(token string, id int) error {
+// SaveRefreshToken replaces the user's refresh token with one valid until expires
+func (s *Storage) SaveRefreshToken(ctx context.Context, token string, id int, expires time.Time) error {
 	const op = "database.postgres.SaveRefreshToken"
 
-	stmt, err := s.db.Prepare(`
+	stmt, err := s.db.PrepareContext(ctx, `
 		INSERT INTO public.tokens (user_id, token, date) 
-		VALUES ($1, $2, NOW() + INTERVAL '12 hours') 
+		VALUES ($1, $2, $3) 
 		ON CONFLICT (user_id) 
-		DO UPDATE SET token = EXCLUDED.token, date = NOW() + INTERVAL '12 hours'
+		DO UPDATE SET token = EXCLUDED.token, date = EXCLUDED.date
 	`)
 	if err != nil {
 		return fmt.Errorf("%s: %v", op, err)
 	}
 	defer stmt.Close()
 
-	_, err = stmt.Exec(id, token)
+	_, err = stmt.ExecContext(ctx, id, token, expires)
 	if err != nil {
 		return fmt.Errorf("%s: %v", op, err)
 	}
@@ -274,16 +441,20 @@ func (s *Storage) SaveRefreshToken(token string, id int) error {
 	return nil
 }
 
-func (s *Storage) RefreshToken(token string) (string, int, error) {
+func (s *Storage) RefreshToken(ctx context.Context, token string) (string, int, error) {
 	const op = "database.postgres.RefreshToken"
 
-	stmt, err := s.db.Prepare(`SELECT user_id, token FROM public.tokens WHERE token = $1 and date > NOW()`)
+	stmt, err := s.db.PrepareContext(ctx, `
+		SELECT t.user_id, t.token FROM public.tokens t
+		JOIN public.users u ON u.id = t.user_id
+		WHERE t.token = $1 AND t.date > NOW() AND u.deleted_at IS NULL
+	`)
 	if err != nil {
 		return "", 0, fmt.Errorf("%s: %v", op, err)
 	}
 	defer stmt.Close()
 
-	rows, err := stmt.Query(token)
+	rows, err := stmt.QueryContext(ctx, token)
 	if err != nil {
 		return "", 0, fmt.Errorf("%s: %v", op, err)
 	}
@@ -301,24 +472,24 @@ func (s *Storage) RefreshToken(token string) (string, int, error) {
 	return "expired", 0, nil
 }
 
-func (s *Storage) ChangePassword(u u.Pwd, id int) (int64, error) {
+func (s *Storage) ChangePassword(ctx context.Context, u u.Pwd, id int) (int64, error) {
 	const op = "database.postgres.ChangePassword"
 
 	var exists bool
-	stmt, err := s.db.Prepare(`SELECT EXISTS (SELECT 1 FROM public.users WHERE id = $1)`)
+	stmt, err := s.db.PrepareContext(ctx, `SELECT EXISTS (SELECT 1 FROM public.users WHERE id = $1)`)
 	if err != nil {
 		return -1, fmt.Errorf("%s: %v", op, err)
 	}
 	defer stmt.Close()
 
-	if err = stmt.QueryRow(id).Scan(&exists); err != nil {
+	if err = stmt.QueryRowContext(ctx, id).Scan(&exists); err != nil {
 		return -1, fmt.Errorf("%s: %v", op, err)
 	}
 	if !exists {
-		return -2, fmt.Errorf("%s: no such user", op)
+		return 0, fmt.Errorf("%s: no such user: %w", op, ErrNotFound)
 	}
 
-	tx, err := s.db.Begin()
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return -1, fmt.Errorf("%s: %v", op, err)
 	}
@@ -331,7 +502,9 @@ func (s *Storage) ChangePassword(u u.Pwd, id int) (int64, error) {
 			return 0, fmt.Errorf("%s: %v", op, err)
 		}
 
-		_, err = tx.Exec(`UPDATE public.users SET password = $1 WHERE id = $2`, pwd, id)
+		_, err = tx.ExecContext(ctx, `
+			UPDATE public.users SET password = $1, must_change_password = FALSE, password_changed = $2, password_warned = NULL WHERE id = $3
+		`, pwd, clock.Now(), id)
 		if err != nil {
 			return -1, fmt.Errorf("%s: %v", op, err)
 		}
@@ -343,3 +516,158 @@ func (s *Storage) ChangePassword(u u.Pwd, id int) (int64, error) {
 
 	return 1, nil
 }
+
+// MergeUsers moves the duplicate's todos to the primary user, revokes the duplicate's refresh token and remembered devices,
+// soft-deletes the duplicate and records the merge by actor in the audit log.
+// Both users must exist and not be deleted. A dry run performs the merge and rolls it back,
+// so the result is exactly what a real merge would do at that moment.
+func (s *Storage) MergeUsers(ctx context.Context, primary, duplicate, actor int, dryRun bool) (u.MergeResult, error) {
+	const op = "database.postgres.MergeUsers"
+
+	result := u.MergeResult{DryRun: dryRun}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return result, fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	// Locking both rows keeps concurrent merges and deletions of the same accounts out.
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, public_id FROM public.users
+		WHERE id IN ($1, $2) AND deleted_at IS NULL
+		ORDER BY id
+		FOR UPDATE
+	`, primary, duplicate)
+	if err != nil {
+		return result, fmt.Errorf("%s: %v", op, err)
+	}
+	found := 0
+	for rows.Next() {
+		var id int
+		var publicID string
+		if err := rows.Scan(&id, &publicID); err != nil {
+			rows.Close()
+			return result, fmt.Errorf("%s: %v", op, err)
+		}
+		if id == primary {
+			result.Primary = publicID
+		} else {
+			result.Duplicate = publicID
+		}
+		found++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return result, fmt.Errorf("%s: %v", op, err)
+	}
+	if found != 2 {
+		return result, fmt.Errorf("%s: no users with ids %v, %v: %w", op, primary, duplicate, ErrNotFound)
+	}
+
+	// Moved todos take a new version so the primary user's sync clients pick them up.
+	res, err := tx.ExecContext(ctx, `
+		UPDATE public.todos SET user_id = $1, version = nextval('public.todos_version_seq')
+		WHERE user_id = $2
+	`, primary, duplicate)
+	if err != nil {
+		return result, fmt.Errorf("%s: %v", op, err)
+	}
+	if result.TodosMoved, err = res.RowsAffected(); err != nil {
+		return result, fmt.Errorf("%s: %v", op, err)
+	}
+
+	if result.SessionsRevoked, err = revokeSessions(ctx, tx, duplicate); err != nil {
+		return result, fmt.Errorf("%s: %v", op, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE public.users SET deleted_at = NOW() WHERE id = $1`, duplicate); err != nil {
+		return result, fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := audit(ctx, tx, actor, AuditMergeUsers, primary, result); err != nil {
+		return result, fmt.Errorf("%s: %v", op, err)
+	}
+
+	if dryRun {
+		return result, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return result, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return result, nil
+}
+
+// ChangeLogin renames the user's login and keeps the old one in the login history, where it stays
+// findable by admins and reserved for the user for hold. Renames are allowed once per cooldown,
+// ErrCooldown comes with the time of the next allowed change.
+func (s *Storage) ChangeLogin(ctx context.Context, id int, login string, cooldown, hold time.Duration) (time.Time, error) {
+	const op = "database.postgres.ChangeLogin"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	var current string
+	err = tx.QueryRowContext(ctx, `SELECT login FROM public.users WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, id).Scan(&current)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return time.Time{}, fmt.Errorf("%s: no users with id %v: %w", op, id, ErrNotFound)
+		}
+		return time.Time{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	var last sql.NullTime
+	var now time.Time
+	err = tx.QueryRowContext(ctx, `SELECT MAX(changed_at), NOW() FROM public.login_history WHERE user_id = $1`, id).Scan(&last, &now)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s: %v", op, err)
+	}
+	next := now
+	if last.Valid && last.Time.Add(cooldown).After(now) {
+		next = last.Time.Add(cooldown)
+	}
+
+	if login == current {
+		return next, nil
+	}
+	if next.After(now) {
+		return next, fmt.Errorf("%s: login changed at %v: %w", op, last.Time, ErrCooldown)
+	}
+
+	var held bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM public.login_history WHERE login = $1 AND user_id <> $2 AND released_until > NOW())
+	`, login, id).Scan(&held)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s: %v", op, err)
+	}
+	if held {
+		return time.Time{}, fmt.Errorf("%s: login is reserved: %w", op, ErrAlreadyExists)
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE public.users SET login = $1 WHERE id = $2`, login, id); err != nil {
+		if pgErr, ok := err.(*pq.Error); ok && pgErr.Code == "23505" {
+			return time.Time{}, fmt.Errorf("%s: login %w", op, ErrAlreadyExists)
+		}
+		return time.Time{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO public.login_history (user_id, login, changed_at, released_until)
+		VALUES ($1, $2, $3, $4)
+	`, id, current, now, now.Add(hold))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return time.Time{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return now.Add(cooldown), nil
+}
@@ -0,0 +1,146 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	todoconfig "github.com/sabbatD/srest-api/internal/lib/todoConfig"
+	"github.com/sabbatD/srest-api/internal/lib/userConfig"
+)
+
+func TestMergeUsers(t *testing.T) {
+	s := testStorage(t)
+	ctx := context.Background()
+
+	admin := testUser(t, s, "mergeadmin")
+	primary := testUser(t, s, "mergeprimary")
+	duplicate := testUser(t, s, "mergeduplicate")
+
+	for _, title := range []string{"one", "two"} {
+		if _, err := s.Create(ctx, todoconfig.TodoRequest{Title: title}, duplicate); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.SaveRefreshToken(ctx, "merge-refresh", duplicate, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	dry, err := s.MergeUsers(ctx, primary, duplicate, admin, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dry.TodosMoved != 2 || dry.SessionsRevoked != 1 || !dry.DryRun {
+		t.Errorf("dry run = %+v", dry)
+	}
+	if _, info, _, _ := s.OutputAll(ctx, "all", duplicate); info.All != 2 {
+		t.Errorf("dry run moved todos: duplicate has %d", info.All)
+	}
+
+	res, err := s.MergeUsers(ctx, primary, duplicate, admin, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.TodosMoved != 2 || res.SessionsRevoked != 1 || res.DryRun {
+		t.Errorf("merge = %+v", res)
+	}
+	if _, info, _, _ := s.OutputAll(ctx, "all", primary); info.All != 2 {
+		t.Errorf("primary has %d todos, want 2", info.All)
+	}
+	if _, id, _ := s.RefreshToken(ctx, "merge-refresh"); id != 0 {
+		t.Error("duplicate's refresh token still valid")
+	}
+
+	var audited int
+	s.db.QueryRow(`SELECT COUNT(*) FROM public.audit_log WHERE action = $1 AND target_id = $2`, AuditMergeUsers, primary).Scan(&audited)
+	if audited != 1 {
+		t.Errorf("audit entries = %d, want 1", audited)
+	}
+
+	if _, err := s.MergeUsers(ctx, primary, duplicate, admin, false); !errors.Is(err, ErrNotFound) {
+		t.Errorf("merging a deleted duplicate: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	s := testStorage(t)
+	ctx := context.Background()
+
+	id := testUser(t, s, "removed")
+	if err := s.SaveRefreshToken(ctx, "removed-refresh", id, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Remove(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(ctx, id); !errors.Is(err, ErrNotFound) {
+		t.Errorf("get of a deleted user: err = %v, want ErrNotFound", err)
+	}
+	if _, user, _ := s.RefreshToken(ctx, "removed-refresh"); user != 0 {
+		t.Error("deleted user's refresh token still valid")
+	}
+	if _, err := s.Remove(ctx, id); !errors.Is(err, ErrNotFound) {
+		t.Errorf("removing twice: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestChangeLogin(t *testing.T) {
+	s := testStorage(t)
+	ctx := context.Background()
+
+	s.db.Exec(`DELETE FROM public.users WHERE login IN ('renamednew', 'renamedlater')`)
+	id := testUser(t, s, "renamedold")
+	other := testUser(t, s, "renamedother")
+
+	if _, err := s.ChangeLogin(ctx, id, "renamednew", time.Hour, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	next, err := s.ChangeLogin(ctx, id, "renamedlater", time.Hour, time.Hour)
+	if !errors.Is(err, ErrCooldown) || time.Until(next) < 59*time.Minute {
+		t.Errorf("second change: next = %v, err = %v, want ErrCooldown", next, err)
+	}
+
+	if _, err := s.ChangeLogin(ctx, other, "renamedold", 0, time.Hour); !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("taking a reserved login: err = %v, want ErrAlreadyExists", err)
+	}
+	if _, err := s.Add(ctx, userConfig.User{Login: "renamedold", Username: "x", Password: "password", Email: "renamedold2@example.com"}); !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("registering a reserved login: err = %v, want ErrAlreadyExists", err)
+	}
+
+	found := false
+	_, err = s.EachUser(ctx, userConfig.GetAllQuery{SearchTerm: "renamedold", SortBy: "id", SortOrder: "ASC", Limit: 20}, func(user userConfig.TableUser) error {
+		found = found || user.ID == id
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found {
+		t.Error("user not found by the old login")
+	}
+}
+
+func TestMustChangePassword(t *testing.T) {
+	s := testStorage(t)
+	ctx := context.Background()
+
+	id := testUser(t, s, "mustchange")
+	if _, err := s.UpdateField(ctx, "must_change_password", id, true); err != nil {
+		t.Fatal(err)
+	}
+
+	user, err := s.Auth(ctx, userConfig.AuthData{Login: "mustchange", Password: "password"})
+	if err != nil || !user.MustChangePassword {
+		t.Fatalf("Auth() = %+v, %v, want the flag set", user, err)
+	}
+
+	if _, err := s.ChangePassword(ctx, userConfig.Pwd{Password: "changed"}, id); err != nil {
+		t.Fatal(err)
+	}
+	if user, err := s.Get(ctx, id); err != nil || user.MustChangePassword {
+		t.Errorf("Get() after the change = %+v, %v, want the flag cleared", user, err)
+	}
+}
@@ -0,0 +1,135 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sabbatD/srest-api/internal/lib/clock"
+	u "github.com/sabbatD/srest-api/internal/lib/userConfig"
+)
+
+// StartEmailVerification marks the user unverified and stores the hash of an email verification token valid until expires,
+// replacing an earlier one. Returns the user to email the verification link to.
+func (s *Storage) StartEmailVerification(ctx context.Context, id int, tokenHash string, expires time.Time) (u.TableUser, error) {
+	const op = "database.postgres.StartEmailVerification"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return u.TableUser{}, fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	var user u.TableUser
+	err = tx.QueryRowContext(ctx, `
+		UPDATE public.users SET is_verified = FALSE
+		WHERE id = $1 AND deleted_at IS NULL AND COALESCE(email, '') <> ''
+		RETURNING id, public_id, username, email, locale
+	`, id).Scan(&user.ID, &user.PublicID, &user.Username, &user.Email, &user.Locale)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return u.TableUser{}, fmt.Errorf("%s: no such user: %w", op, ErrNotFound)
+		}
+		return u.TableUser{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := storeVerification(ctx, tx, id, tokenHash, expires); err != nil {
+		return u.TableUser{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return u.TableUser{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return user, nil
+}
+
+// RequestEmailVerification stores the hash of a new email verification token valid until expires for the unverified user
+// with the login or email login, replacing an earlier one. Returns the user to email the verification link to,
+// ErrNotFound when no unverified active user has that login or email.
+func (s *Storage) RequestEmailVerification(ctx context.Context, login, tokenHash string, expires time.Time) (u.TableUser, error) {
+	const op = "database.postgres.RequestEmailVerification"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return u.TableUser{}, fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	// A login wins over another user's email that happens to be the same
+	var user u.TableUser
+	err = tx.QueryRowContext(ctx, `
+		SELECT id, public_id, username, email, locale FROM public.users
+		WHERE (login = $1 OR email = $1) AND deleted_at IS NULL AND NOT is_blocked AND NOT is_verified
+			AND COALESCE(email, '') <> ''
+		ORDER BY login = $1 DESC
+		LIMIT 1
+	`, login).Scan(&user.ID, &user.PublicID, &user.Username, &user.Email, &user.Locale)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return u.TableUser{}, fmt.Errorf("%s: no such user: %w", op, ErrNotFound)
+		}
+		return u.TableUser{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := storeVerification(ctx, tx, user.ID, tokenHash, expires); err != nil {
+		return u.TableUser{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return u.TableUser{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return user, nil
+}
+
+func storeVerification(ctx context.Context, exec execer, id int, tokenHash string, expires time.Time) error {
+	_, err := exec.ExecContext(ctx, `
+		INSERT INTO public.email_verifications (user_id, token_hash, expires) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE SET token_hash = EXCLUDED.token_hash, expires = EXCLUDED.expires, created = NOW()
+	`, id, tokenHash, expires)
+	return err
+}
+
+// VerifyEmail consumes the unexpired verification token with the given hash and marks its user verified.
+// Returns ErrNotFound for an unknown, used or expired token.
+func (s *Storage) VerifyEmail(ctx context.Context, tokenHash string) (int, error) {
+	const op = "database.postgres.VerifyEmail"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	var id int
+	err = tx.QueryRowContext(ctx, `
+		DELETE FROM public.email_verifications WHERE token_hash = $1 AND expires > $2 RETURNING user_id
+	`, tokenHash, clock.Now()).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("%s: verification token %w", op, ErrNotFound)
+		}
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	res, err := tx.ExecContext(ctx, `UPDATE public.users SET is_verified = TRUE WHERE id = $1 AND deleted_at IS NULL`, id)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+	if n == 0 {
+		return 0, fmt.Errorf("%s: no such user: %w", op, ErrNotFound)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return id, nil
+}
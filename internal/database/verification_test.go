@@ -0,0 +1,67 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sabbatD/srest-api/internal/lib/userConfig"
+	"github.com/sabbatD/srest-api/internal/password"
+)
+
+func TestEmailVerification(t *testing.T) {
+	s := testStorage(t)
+	ctx := context.Background()
+
+	id := testUser(t, s, "verifyuser")
+	if user, err := s.Get(ctx, id); err != nil || !user.IsVerified {
+		t.Fatalf("new account before verification = %+v, %v", user, err)
+	}
+
+	// Verified accounts get no new link
+	if _, err := s.RequestEmailVerification(ctx, "verifyuser", "hash", time.Now().Add(time.Hour)); !errors.Is(err, ErrNotFound) {
+		t.Errorf("RequestEmailVerification() of a verified user = %v", err)
+	}
+
+	first, firstHash, err := password.NewToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user, err := s.StartEmailVerification(ctx, id, firstHash, time.Now().Add(time.Hour)); err != nil || user.Email != "verifyuser@example.com" {
+		t.Fatalf("StartEmailVerification() = %+v, %v", user, err)
+	}
+	user, err := s.Auth(ctx, userConfig.AuthData{Login: "verifyuser", Password: "password"})
+	if err != nil || user.IsVerified {
+		t.Fatalf("Auth() of an unverified user = %+v, %v", user, err)
+	}
+
+	// A resent link replaces the first one
+	token, hash, err := password.NewToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user, err := s.RequestEmailVerification(ctx, "verifyuser@example.com", hash, time.Now().Add(time.Hour)); err != nil || user.ID != id {
+		t.Fatalf("RequestEmailVerification() by email = %+v, %v", user, err)
+	}
+	if _, err := s.VerifyEmail(ctx, password.HashToken(first)); !errors.Is(err, ErrNotFound) {
+		t.Errorf("VerifyEmail() with a replaced token = %v", err)
+	}
+	if got, err := s.VerifyEmail(ctx, password.HashToken(token)); err != nil || got != id {
+		t.Fatalf("VerifyEmail() = %d, %v", got, err)
+	}
+	if _, err := s.VerifyEmail(ctx, password.HashToken(token)); !errors.Is(err, ErrNotFound) {
+		t.Errorf("VerifyEmail() with a used token = %v", err)
+	}
+	if user, err := s.Get(ctx, id); err != nil || !user.IsVerified {
+		t.Errorf("account after verification = %+v, %v", user, err)
+	}
+
+	// Expired tokens do not verify
+	if _, err := s.StartEmailVerification(ctx, id, "expiredhash", time.Now().Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.VerifyEmail(ctx, "expiredhash"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("VerifyEmail() with an expired token = %v", err)
+	}
+}
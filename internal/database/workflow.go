@@ -0,0 +1,82 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/sabbatD/srest-api/internal/lib/workflow"
+)
+
+// TodoWorkflow returns the user's todo workflow, the default one until they define their own
+func (s *Storage) TodoWorkflow(ctx context.Context, userID int) (workflow.Workflow, error) {
+	const op = "database.postgres.TodoWorkflow"
+
+	var data []byte
+	err := s.db.QueryRowContext(ctx, `SELECT todo_workflow FROM public.users WHERE id = $1`, userID).Scan(&data)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return workflow.Workflow{}, fmt.Errorf("%s: no user with id %v: %w", op, userID, ErrNotFound)
+		}
+		return workflow.Workflow{}, fmt.Errorf("%s: %v", op, err)
+	}
+	if data == nil {
+		return workflow.Default(), nil
+	}
+
+	var w workflow.Workflow
+	if err := json.Unmarshal(data, &w); err != nil {
+		return w, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return w, nil
+}
+
+// SetTodoWorkflow replaces the user's todo workflow. Todos in a removed status move to the initial
+// or the completed status, todos whose status changed between open and done follow it with is_done.
+// Moved todos count as changed for sync clients.
+func (s *Storage) SetTodoWorkflow(ctx context.Context, userID int, w workflow.Workflow) error {
+	const op = "database.postgres.SetTodoWorkflow"
+
+	data, err := json.Marshal(w)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	keys, done := w.Keys()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `UPDATE public.users SET todo_workflow = $1 WHERE id = $2`, data, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	} else if n == 0 {
+		return fmt.Errorf("%s: no user with id %v: %w", op, userID, ErrNotFound)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE public.todos SET
+			status = CASE WHEN status = ANY($2) THEN status WHEN is_done THEN $4 ELSE $5 END,
+			is_done = CASE WHEN status = ANY($2) THEN status = ANY($3) ELSE is_done END,
+			version = nextval('public.todos_version_seq')
+		WHERE user_id = $1 AND (status <> ALL($2) OR is_done <> (status = ANY($3)))
+	`, userID, pq.Array(keys), pq.Array(done), w.Completed(), w.Initial())
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	todoconfig "github.com/sabbatD/srest-api/internal/lib/todoConfig"
+	"github.com/sabbatD/srest-api/internal/lib/workflow"
+)
+
+func TestTodoWorkflow(t *testing.T) {
+	s := testStorage(t)
+	ctx := context.Background()
+
+	user := testUser(t, s, "workflowuser")
+
+	if w, err := s.TodoWorkflow(ctx, user); err != nil || w.Initial() != workflow.StatusBacklog {
+		t.Fatalf("default workflow = %+v, %v", w, err)
+	}
+
+	done := true
+	legacy, err := s.Create(ctx, todoconfig.TodoRequest{Title: "legacy", IsDone: &done}, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doing, err := s.Create(ctx, todoconfig.TodoRequest{Title: "doing", Status: workflow.StatusInProgress}, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if todo, _ := s.GetTodo(ctx, int(legacy), user); todo.Status != workflow.StatusDone {
+		t.Errorf("status of a done task created without one = %q", todo.Status)
+	}
+
+	// in_progress is removed and done no longer completes tasks.
+	w := workflow.Workflow{Statuses: []workflow.Status{
+		{Key: workflow.StatusBacklog},
+		{Key: workflow.StatusDone},
+		{Key: "shipped", Done: true},
+	}}
+	if err := s.SetTodoWorkflow(ctx, user, w); err != nil {
+		t.Fatal(err)
+	}
+
+	if todo, _ := s.GetTodo(ctx, int(doing), user); todo.Status != workflow.StatusBacklog || todo.IsDone {
+		t.Errorf("task in a removed status = %q, isDone = %v", todo.Status, todo.IsDone)
+	}
+	if todo, _ := s.GetTodo(ctx, int(legacy), user); todo.Status != workflow.StatusDone || todo.IsDone {
+		t.Errorf("task in a status that no longer completes = %q, isDone = %v", todo.Status, todo.IsDone)
+	}
+
+	var matched int
+	_, err = s.EachTodo(ctx, todoconfig.TodoQuery{Status: workflow.StatusBacklog}, user, func(todoconfig.Todo) error {
+		matched++
+		return nil
+	})
+	if err != nil || matched != 1 {
+		t.Errorf("tasks in backlog = %d, %v", matched, err)
+	}
+}
@@ -0,0 +1,212 @@
+package handleutil
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	sdb "github.com/sabbatD/srest-api/internal/database"
+	"github.com/sabbatD/srest-api/internal/lib/api/validation"
+	"github.com/sabbatD/srest-api/internal/lib/logger/sl"
+	"github.com/sabbatD/srest-api/internal/lib/metrics"
+)
+
+// Error codes returned in Problem.Code
+const (
+	CodeBadRequest   = "BAD_REQUEST"
+	CodeInvalidInput = "INVALID_INPUT"
+	CodeInvalidID    = "INVALID_ID"
+	CodeUnauthorized = "UNAUTHORIZED"
+	CodeForbidden    = "FORBIDDEN"
+	CodeNotFound     = "NOT_FOUND"
+	CodeConflict     = "CONFLICT"
+	CodeTimeout      = "TIMEOUT"
+	CodeCooldown     = "COOLDOWN"
+	CodeRejected     = "CONTENT_REJECTED"
+	CodeLimit        = "LIMIT_REACHED"
+	CodeInternal     = "INTERNAL"
+
+	// CodePasswordChange refuses every request but the password change of a user who must change it
+	CodePasswordChange = "PASSWORD_CHANGE_REQUIRED"
+	// CodeRateLimited answers requests over a rate limit, see Retry-After
+	CodeRateLimited = "RATE_LIMITED"
+	// CodeLocked answers sign ins of a login locked after repeated failures, see Retry-After
+	CodeLocked = "LOCKED"
+	// CodeUnknownFields answers a strictly decoded body with unknown fields, listed in Problem.Fields
+	CodeUnknownFields = "UNKNOWN_FIELDS"
+	// CodeGone answers requests to a deprecated route after its sunset, see deprecation.New
+	CodeGone = "GONE"
+	// CodeWeakPassword answers a password violating the password policy, the rules are listed in Problem.Violations
+	CodeWeakPassword = "WEAK_PASSWORD"
+	// CodeUnavailable answers requests for a feature this deployment is not configured for
+	CodeUnavailable = "UNAVAILABLE"
+	// CodeUnverified refuses sign ins of accounts whose email is not verified while verification is required
+	CodeUnverified = "EMAIL_NOT_VERIFIED"
+	// CodeReadOnly answers writes while the API is in read-only mode, see readonly.Switch
+	CodeReadOnly = "READ_ONLY"
+)
+
+// Problem is an RFC 7807 error body, sent as application/problem+json
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	Code     string `json:"code"`
+	// Fields lists the unknown fields of a strictly decoded body, see StrictJSON
+	Fields []string `json:"fields,omitempty"`
+	// Violations lists the password policy rules a password violates, see password.Policy
+	Violations []string `json:"violations,omitempty"`
+}
+
+// HTTPError is an error with the response it maps to.
+// The wrapped cause is logged but never sent to the client.
+type HTTPError struct {
+	Status  int
+	Code    string
+	Message string
+	Err     error
+	// Fields is sent as Problem.Fields
+	Fields []string
+	// Violations is sent as Problem.Violations
+	Violations []string
+}
+
+func (e *HTTPError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", e.Message, e.Err)
+	}
+	return e.Message
+}
+
+func (e *HTTPError) Unwrap() error { return e.Err }
+
+// NewError returns an error answered with status, code and msg
+func NewError(status int, code, msg string) *HTTPError {
+	return &HTTPError{Status: status, Code: code, Message: msg}
+}
+
+// WrapError is NewError keeping err as the cause
+func WrapError(err error, status int, code, msg string) *HTTPError {
+	return &HTTPError{Status: status, Code: code, Message: msg, Err: err}
+}
+
+// HandlerFunc returns the response body or an error instead of writing them.
+// A non-nil body is rendered as JSON, render.Status sets a status other than 200.
+// Returning nil, nil leaves the response to the handler, e.g. an empty 200.
+type HandlerFunc func(w http.ResponseWriter, r *http.Request) (any, error)
+
+// Handle adapts h to http.HandlerFunc, errors are answered by WriteError.
+// The request logger, or log when the request carries none, gets the op and the route pattern,
+// h and everything it calls with the request context read it with sl.FromContext.
+func Handle(log *slog.Logger, op string, h HandlerFunc) http.HandlerFunc {
+	return HandleWith(log, op, h, WriteError)
+}
+
+// HandleWith is Handle answering errors with writeErr instead, for protocols with their own error format
+func HandleWith(log *slog.Logger, op string, h HandlerFunc, writeErr func(w http.ResponseWriter, r *http.Request, err error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := sl.Ensure(r.Context(), log)
+
+		route := ""
+		if rctx := chi.RouteContext(ctx); rctx != nil {
+			route = rctx.RoutePattern()
+		}
+		r = r.WithContext(sl.With(ctx, slog.String("op", op), slog.String("route", route)))
+
+		data, err := h(w, r)
+		if err != nil {
+			writeErr(w, r, err)
+			return
+		}
+		if data != nil {
+			render.JSON(w, r, data)
+		}
+	}
+}
+
+// WriteError maps err to a problem response, logs and counts it:
+//   - *HTTPError answers with its status and code
+//   - sdb.ErrNotFound with 404, sdb.ErrAlreadyExists with 409
+//   - anything else with 500, or 504 when the request deadline has passed
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	e := LogError(r, err)
+
+	body, _ := json.Marshal(Problem{
+		Type:       "about:blank",
+		Title:      http.StatusText(e.Status),
+		Status:     e.Status,
+		Detail:     e.Message,
+		Instance:   r.URL.Path,
+		Code:       e.Code,
+		Fields:     e.Fields,
+		Violations: e.Violations,
+	})
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(e.Status)
+	w.Write(body)
+}
+
+// LogError maps err to its response like WriteError, logs and counts it
+func LogError(r *http.Request, err error) *HTTPError {
+	var e *HTTPError
+	switch {
+	case errors.As(err, &e):
+	case errors.Is(r.Context().Err(), context.DeadlineExceeded):
+		e = WrapError(err, http.StatusGatewayTimeout, CodeTimeout, "Request took too long")
+	case errors.Is(err, sdb.ErrNotFound):
+		e = WrapError(err, http.StatusNotFound, CodeNotFound, "Not found")
+	case errors.Is(err, sdb.ErrAlreadyExists):
+		e = WrapError(err, http.StatusConflict, CodeConflict, "Already exists")
+	default:
+		e = WrapError(err, http.StatusInternalServerError, CodeInternal, "Internal Server Error")
+	}
+
+	log := sl.FromContext(r.Context())
+	if e.Status >= http.StatusInternalServerError {
+		log.Error(e.Message, sl.Err(err))
+	} else {
+		log.Info(e.Message, sl.Err(err))
+	}
+	metrics.Errors.Add(e.Code, 1)
+
+	return e
+}
+
+// NotFound maps sdb.ErrNotFound to a 404 with msg, other errors are returned as is
+func NotFound(err error, msg string) error {
+	if errors.Is(err, sdb.ErrNotFound) {
+		return WrapError(err, http.StatusNotFound, CodeNotFound, msg)
+	}
+	return err
+}
+
+// Shortcut for DecodeJSON
+// Decodes the request body into v, a malformed body is a 400.
+// Decoded strictly, see StrictJSON, a body with unknown fields is a 400 too.
+func DecodeJSON(r *http.Request, v any) error {
+	if strict(r) {
+		return decodeStrict(r, v)
+	}
+	if err := render.DecodeJSON(r.Body, v); err != nil {
+		return WrapError(err, http.StatusBadRequest, CodeBadRequest, "failed to deserialize json request")
+	}
+	return nil
+}
+
+// Shortcut for Validate
+// Validates v by its validate tags, a failed check is a 400.
+func Validate(v any) error {
+	validation.InitValidator()
+	if err := validation.ValidateStruct(v); err != nil {
+		return WrapError(err, http.StatusBadRequest, CodeInvalidInput, fmt.Sprintf("Invalid input: %v", err.Error()))
+	}
+	return nil
+}
@@ -1,35 +1,82 @@
 package handleutil
 
 import (
-	"log/slog"
+	"context"
+	"fmt"
 	"net/http"
-	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
 )
 
-// Shortcut for logging
-func SlogWith(op string, r *http.Request) []any {
-	return []any{
-		slog.String("op", op),
-		slog.String("request_id", middleware.GetReqID(r.Context())),
-		slog.String("\n", ""),
+// Shortcut for ResolveID
+// Parses the {id} path param as a public UUID and maps it to the internal id with resolve.
+// Malformed ids are a 400, unknown ones a 404 with notFound.
+func ResolveID(r *http.Request, resolve func(ctx context.Context, publicID string) (int, error), notFound string) (int, error) {
+	publicID := chi.URLParam(r, "id")
+	if !IsUUID(publicID) {
+		return 0, NewError(http.StatusBadRequest, CodeInvalidID, "Missing or wrong id")
 	}
+
+	id, err := resolve(r.Context(), publicID)
+	if err != nil {
+		return 0, NotFound(err, notFound)
+	}
+	return id, nil
 }
 
-// Shortcut for InternalError
-func InternalError(w http.ResponseWriter, r *http.Request, log *slog.Logger, err error) {
-	log.Debug(err.Error())
+// IsUUID reports whether s is a canonical textual UUID
+func IsUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i, c := range s {
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+		default:
+			if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+				return false
+			}
+		}
+	}
+	return true
+}
 
-	http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+// Shortcut for Cache
+// Marks responses cacheable for maxAge with Last-Modified set to modified,
+// answers 304 when the client's copy is not older. Private responses are not stored by shared caches.
+func Cache(maxAge time.Duration, modified time.Time, private bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if setCacheHeaders(w, r, maxAge, modified, private) {
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func setCacheHeaders(w http.ResponseWriter, r *http.Request, maxAge time.Duration, modified time.Time, private bool) (notModified bool) {
+	modified = modified.UTC().Truncate(time.Second)
+
+	w.Header().Set("Cache-Control", cacheControl(maxAge, private))
+	w.Header().Set("Last-Modified", modified.Format(http.TimeFormat))
+
+	if since, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil && !modified.After(since) {
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	}
+	return false
 }
 
-// Shortcut for GetUrlParam
-func GetUrlParam(w http.ResponseWriter, r *http.Request, log *slog.Logger) int {
-	id, err := strconv.Atoi(chi.URLParam(r, "id"))
-	if err != nil || id < 1 {
-		return 0
+func cacheControl(maxAge time.Duration, private bool) string {
+	scope := "public"
+	if private {
+		scope = "private"
 	}
-	return id
+	return fmt.Sprintf("%s, max-age=%d", scope, int(maxAge.Seconds()))
 }
@@ -0,0 +1,130 @@
+package handleutil
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	sdb "github.com/sabbatD/srest-api/internal/database"
+)
+
+func TestHandleErrors(tt *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		name     string
+		err      error
+		deadline bool
+		status   int
+		code     string
+		detail   string
+	}{
+		{name: "http error", err: NewError(http.StatusForbidden, CodeForbidden, "Not enough rights"), status: http.StatusForbidden, code: CodeForbidden, detail: "Not enough rights"},
+		{name: "wrapped not found", err: NotFound(fmt.Errorf("op: %w", sdb.ErrNotFound), "No such task"), status: http.StatusNotFound, code: CodeNotFound, detail: "No such task"},
+		{name: "storage not found", err: fmt.Errorf("op: %w", sdb.ErrNotFound), status: http.StatusNotFound, code: CodeNotFound},
+		{name: "already exists", err: fmt.Errorf("op: %w", sdb.ErrAlreadyExists), status: http.StatusConflict, code: CodeConflict},
+		{name: "deadline", err: errors.New("pq: canceling statement"), deadline: true, status: http.StatusGatewayTimeout, code: CodeTimeout},
+		{name: "internal", err: errors.New("pq: connection refused"), status: http.StatusInternalServerError, code: CodeInternal, detail: "Internal Server Error"},
+	}
+	for _, tc := range tests {
+		tt.Run(tc.name, func(tt *testing.T) {
+			h := Handle(log, "test", func(w http.ResponseWriter, r *http.Request) (any, error) {
+				return nil, tc.err
+			})
+
+			r := httptest.NewRequest(http.MethodGet, "/todos/1", nil)
+			if tc.deadline {
+				ctx, cancel := context.WithTimeout(r.Context(), 0)
+				defer cancel()
+				r = r.WithContext(ctx)
+			}
+			w := httptest.NewRecorder()
+			h(w, r)
+
+			if w.Code != tc.status {
+				tt.Errorf("status = %d, want %d", w.Code, tc.status)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != "application/problem+json" {
+				tt.Errorf("content type = %q", ct)
+			}
+
+			var p Problem
+			if err := json.NewDecoder(w.Body).Decode(&p); err != nil {
+				tt.Fatal(err)
+			}
+			if p.Status != tc.status || p.Code != tc.code || p.Instance != "/todos/1" {
+				tt.Errorf("problem = %+v", p)
+			}
+			if tc.detail != "" && p.Detail != tc.detail {
+				tt.Errorf("detail = %q, want %q", p.Detail, tc.detail)
+			}
+		})
+	}
+}
+
+func TestStrictJSON(tt *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	type item struct {
+		Title string `json:"title"`
+	}
+	type base struct {
+		ID string `json:"id"`
+	}
+	type request struct {
+		base
+		Title  string         `json:"title"`
+		Items  []item         `json:"items"`
+		Extra  map[string]any `json:"extra"`
+		Ignore string         `json:"-"`
+	}
+
+	tests := []struct {
+		name   string
+		strict bool
+		header string
+		body   string
+		fields []string
+	}{
+		{name: "lenient", body: `{"tittle":"a"}`},
+		{name: "known fields", strict: true, body: `{"id":"1","TITLE":"a","items":[{"title":"b"}],"extra":{"any":1}}`},
+		{name: "unknown fields", strict: true, body: `{"tittle":"a","Ignore":"x","items":[{"title":"b"},{"titel":"c"}]}`, fields: []string{"Ignore", "items[1].titel", "tittle"}},
+		{name: "header", header: "true", body: `{"tittle":"a"}`, fields: []string{"tittle"}},
+	}
+	for _, tc := range tests {
+		tt.Run(tc.name, func(tt *testing.T) {
+			var h http.Handler = Handle(log, "test", func(w http.ResponseWriter, r *http.Request) (any, error) {
+				var req request
+				return nil, DecodeJSON(r, &req)
+			})
+			if tc.strict {
+				h = StrictJSON(h)
+			}
+
+			r := httptest.NewRequest(http.MethodPost, "/todos", strings.NewReader(tc.body))
+			r.Header.Set(StrictHeader, tc.header)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if tc.fields == nil {
+				if w.Code != http.StatusOK {
+					tt.Errorf("status = %d, want 200, body = %s", w.Code, w.Body)
+				}
+				return
+			}
+			var p Problem
+			json.NewDecoder(w.Body).Decode(&p)
+			if w.Code != http.StatusBadRequest || p.Code != CodeUnknownFields || !slices.Equal(p.Fields, tc.fields) {
+				tt.Errorf("status = %d, problem = %+v, want 400 with fields %v", w.Code, p, tc.fields)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package handleutil
+
+import (
+	"bytes"
+	"context"
+	"encoding"
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// StrictHeader asks for strict decoding of the request body, e.g. "X-Strict-JSON: true"
+const StrictHeader = "X-Strict-JSON"
+
+type strictKey struct{}
+
+// StrictJSON makes DecodeJSON reject request bodies with unknown fields on the routes it wraps,
+// clients can ask for it on any route with StrictHeader.
+func StrictJSON(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), strictKey{}, true)))
+	})
+}
+
+// strict reports whether the body of r is decoded strictly
+func strict(r *http.Request) bool {
+	if on, _ := r.Context().Value(strictKey{}).(bool); on {
+		return true
+	}
+	on, _ := strconv.ParseBool(r.Header.Get(StrictHeader))
+	return on
+}
+
+// decodeStrict decodes the body of r into v like DecodeJSON, a body with fields v has no place for is a 400 listing them
+func decodeStrict(r *http.Request, v any) error {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return WrapError(err, http.StatusBadRequest, CodeBadRequest, "failed to deserialize json request")
+	}
+	if err := json.NewDecoder(bytes.NewReader(body)).Decode(v); err != nil {
+		return WrapError(err, http.StatusBadRequest, CodeBadRequest, "failed to deserialize json request")
+	}
+
+	var raw any
+	if err := json.NewDecoder(bytes.NewReader(body)).Decode(&raw); err != nil {
+		return WrapError(err, http.StatusBadRequest, CodeBadRequest, "failed to deserialize json request")
+	}
+	unknown := unknownFields(raw, reflect.TypeOf(v), "", nil)
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		e := NewError(http.StatusBadRequest, CodeUnknownFields, "Unknown fields: "+strings.Join(unknown, ", "))
+		e.Fields = unknown
+		return e
+	}
+	return nil
+}
+
+var (
+	unmarshalerType     = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// unknownFields appends the paths of the object keys in raw that t does not decode, e.g. "items[0].tittle".
+// Keys match fields case-insensitively like encoding/json. Types decoding themselves accept anything.
+func unknownFields(raw any, t reflect.Type, path string, unknown []string) []string {
+	for t.Kind() == reflect.Pointer {
+		if t.Implements(unmarshalerType) || t.Implements(textUnmarshalerType) {
+			return unknown
+		}
+		t = t.Elem()
+	}
+	if reflect.PointerTo(t).Implements(unmarshalerType) || reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return unknown
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, ok := raw.(map[string]any)
+		if !ok {
+			return unknown
+		}
+		fields := jsonFields(t)
+		for key, value := range obj {
+			f, ok := fields[key]
+			if !ok {
+				for name, field := range fields {
+					if strings.EqualFold(name, key) {
+						f, ok = field, true
+						break
+					}
+				}
+			}
+			if !ok {
+				unknown = append(unknown, join(path, key))
+				continue
+			}
+			unknown = unknownFields(value, f.Type, join(path, key), unknown)
+		}
+	case reflect.Map:
+		obj, ok := raw.(map[string]any)
+		if !ok {
+			return unknown
+		}
+		for key, value := range obj {
+			unknown = unknownFields(value, t.Elem(), join(path, key), unknown)
+		}
+	case reflect.Slice, reflect.Array:
+		items, ok := raw.([]any)
+		if !ok {
+			return unknown
+		}
+		for i, item := range items {
+			unknown = unknownFields(item, t.Elem(), path+"["+strconv.Itoa(i)+"]", unknown)
+		}
+	}
+	return unknown
+}
+
+// jsonFields returns the fields of struct t by their JSON name, with the fields of embedded structs promoted
+func jsonFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+
+		ft := f.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			for n, promoted := range jsonFields(ft) {
+				if _, ok := fields[n]; !ok {
+					fields[n] = promoted
+				}
+			}
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f
+	}
+	return fields
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -1,59 +1,90 @@
 package admin
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
-	"github.com/go-chi/render"
+	sdb "github.com/sabbatD/srest-api/internal/database"
 	util "github.com/sabbatD/srest-api/internal/http-server/handleUtil"
 	"github.com/sabbatD/srest-api/internal/lib/api/access"
-	"github.com/sabbatD/srest-api/internal/lib/api/validation"
+	"github.com/sabbatD/srest-api/internal/lib/api/stream"
+	"github.com/sabbatD/srest-api/internal/lib/cache"
+	"github.com/sabbatD/srest-api/internal/lib/clock"
+	"github.com/sabbatD/srest-api/internal/lib/fields"
+	"github.com/sabbatD/srest-api/internal/lib/flight"
 	"github.com/sabbatD/srest-api/internal/lib/logger/sl"
+	"github.com/sabbatD/srest-api/internal/lib/mail"
+	"github.com/sabbatD/srest-api/internal/lib/metrics"
+	"github.com/sabbatD/srest-api/internal/lib/moderation"
+	t "github.com/sabbatD/srest-api/internal/lib/todoConfig"
 	u "github.com/sabbatD/srest-api/internal/lib/userConfig"
 )
 
+// maxSharedUsers is the longest page of users All reads once for concurrent requests
+const maxSharedUsers = 500
+
+// errLongPage stops reading a page longer than maxSharedUsers
+var errLongPage = errors.New("page too long to share")
+
 type UpdateRequest struct {
 	Field string
 	Value any
 }
 
 type AdminHandler interface {
-	UpdateField(field string, id int, val any) (int64, error)
-	All(q u.GetAllQuery) (result u.MetaResponse, E error)
-	Remove(id int) (int64, error)
-	Get(id int) (u.TableUser, error)
-	UpdateUser(u u.PutUser, id int) (int64, error)
+	UpdateField(ctx context.Context, field string, id int, val any) (int64, error)
+	EachUser(ctx context.Context, q u.GetAllQuery, fn func(u.TableUser) error) (meta u.Meta, E error)
+	Remove(ctx context.Context, id int) (int64, error)
+	Get(ctx context.Context, id int) (u.TableUser, error)
+	UpdateUser(ctx context.Context, u u.PutUser, id int) (int64, error)
+	UserID(ctx context.Context, publicID string) (int, error)
+	MergeUsers(ctx context.Context, primary, duplicate, actor int, dryRun bool) (u.MergeResult, error)
+	FlaggedContent(ctx context.Context, kind string, limit, offset int) (moderation.FlagsResponse, error)
+	Audit(ctx context.Context, actor int, action string, target int, details any) error
+	RestoreTodos(ctx context.Context, userID, actor int, asOf time.Time, dryRun bool) (t.RestoreResult, error)
+	UserFields(ctx context.Context) (fields.Schema, error)
+	SetUserFields(ctx context.Context, actor int, schema fields.Schema) error
 }
 
 // All godoc
 // @Summary Get all users
+// @ID listUsers
 // @Description Fetches a list of users based on optional query parameters such as filters and sorting.
 // Requires Authorization header with Bearer token for authentication.
 // @Tags admin
 // @Produce json
-// @Param search query string false "Filter users by username or email"
+// @Param search query string false "Filter users by username, email, login or a former login"
 // @Param sortBy query string false "Sort by 'email', 'username', or 'id'. Default is 'id'."
 // @Param sortOrder query string false "Sort order: 'asc', 'desc', or 'none'. Default is 'asc'."
-// @Param isBlocked query bool false "Filter by block status (true/false)"
+// @Param state query string false "Filter by state: 'active', 'blocked', 'deleted' or 'pending'. Overrides isBlocked."
+// @Param isBlocked query bool false "Filter by block status (true/false), ignored when state is set"
+// @Param role query string false "Filter by role, e.g. 'moderator'"
+// @Param custom.key query string false "Filter by the value of the custom field 'key', e.g. custom.department=sales; several may be given"
 // @Param limit query int false "Limit the number of users returned (default is 20)"
 // @Param offset query int false "Offset for pagination (default is 0)"
 // @Security BearerAuth
 // @Success 200 {object} u.MetaResponse "Successful retrieval of users."
+// @Failure 400 {object} util.Problem "Unknown state or custom field."
 // @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
-// @Failure 403 {object} string "Insufficient permissions."
-// @Failure 500 {object} string "Internal server error."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 500 {object} util.Problem "Internal server error."
 // @Router /admin/users [get]
-func All(log *slog.Logger, Users AdminHandler) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		const op = "http-server.handlers.admin.GetAll"
+func All(log *slog.Logger, Users AdminHandler, reads *flight.Group) http.HandlerFunc {
+	const op = "http-server.handlers.admin.GetAll"
 
-		log.With(util.SlogWith(op, r)...)
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
 
-		if !AdmCheck(w, r, log) {
-			return
+		if err := AdmCheck(r); err != nil {
+			return nil, err
 		}
 
 		var q u.GetAllQuery
@@ -75,6 +106,19 @@ func All(log *slog.Logger, Users AdminHandler) http.HandlerFunc {
 			q.SortOrder = "ASC"
 		}
 
+		q.State = strings.ToLower(r.URL.Query().Get("state"))
+		switch q.State {
+		case "", u.StateActive, u.StateBlocked, u.StateDeleted, u.StatePending:
+		default:
+			return nil, util.NewError(http.StatusBadRequest, util.CodeBadRequest, "Unknown state: must be one of active, blocked, deleted, pending")
+		}
+
+		q.Role = r.URL.Query().Get("role")
+
+		if q.Custom, E = customFilter(r, Users); E != nil {
+			return nil, E
+		}
+
 		isblockedStr := r.URL.Query().Get("isBlocked")
 		q.IsBlocked, E = strconv.ParseBool(isblockedStr)
 		if E != nil {
@@ -93,389 +137,758 @@ func All(log *slog.Logger, Users AdminHandler) http.HandlerFunc {
 			q.Offset = 0
 		}
 
-		metaResponse, err := Users.All(q)
+		// Concurrent requests for the page share one read. Pages too long to hold are streamed by each request,
+		// large limits do not build the whole page in memory.
+		key, err := json.Marshal(q)
+		if err != nil {
+			return nil, err
+		}
+		v, err := reads.Do(r.Context(), string(key), func(ctx context.Context) (any, error) {
+			res := u.MetaResponse{Data: []u.TableUser{}}
+			meta, err := Users.EachUser(ctx, q, func(user u.TableUser) error {
+				if len(res.Data) == maxSharedUsers {
+					return errLongPage
+				}
+				res.Data = append(res.Data, user)
+				return nil
+			})
+			res.Meta = meta
+			return res, err
+		})
+		switch {
+		case errors.Is(err, errLongPage):
+			err = streamUsers(w, r, Users, q)
+		case err == nil:
+			res := v.(u.MetaResponse)
+			err = stream.List(w, r, func(emit stream.Emit) error {
+				for _, user := range res.Data {
+					if err := emit(user); err != nil {
+						return err
+					}
+				}
+				return nil
+			}, func() stream.Field {
+				return stream.Field{Key: "meta", Value: res.Meta}
+			})
+		}
 		if err != nil {
-			util.InternalError(w, r, log, err)
-			return
+			return nil, err
 		}
 
 		log.Info("users successfully retrieved")
 		log.Debug(fmt.Sprintf("query: %v", q))
 
-		render.JSON(w, r, metaResponse)
-	}
+		return nil, nil
+	})
+}
+
+// streamUsers writes the page of users matching q
+func streamUsers(w http.ResponseWriter, r *http.Request, Users AdminHandler, q u.GetAllQuery) error {
+	var meta u.Meta
+	return stream.List(w, r, func(emit stream.Emit) (err error) {
+		meta, err = Users.EachUser(r.Context(), q, func(user u.TableUser) error {
+			return emit(user)
+		})
+		return err
+	}, func() stream.Field {
+		return stream.Field{Key: "meta", Value: meta}
+	})
 }
 
 // Profile godoc
 // @Summary Retrieve user's profile
+// @ID getUser
 // @Description Retrieves a user's profile by their ID.
 // Requires Authorization header with Bearer token for authentication.
 // @Tags admin
 // @Produce json
 // @Security BearerAuth
-// @Param id path int true "ID of the user"
+// @Param id path string true "Public ID (UUID) of the user"
 // @Success 200 {object} u.TableUser "Successful retrieval of user profile."
-// @Failure 400 {object} string "Invalid or missing user ID."
+// @Failure 400 {object} util.Problem "Invalid or missing user ID."
 // @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
-// @Failure 403 {object} string "Insufficient permissions."
-// @Failure 404 {object} string "User not found."
-// @Failure 500 {object} string "Internal server error."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 404 {object} util.Problem "User not found."
+// @Failure 500 {object} util.Problem "Internal server error."
 // @Router /admin/users/{id} [get]
 func Profile(log *slog.Logger, User AdminHandler) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		const op = "http-server.handlers.user.Profile"
+	const op = "http-server.handlers.admin.Profile"
 
-		log.With(util.SlogWith(op, r)...)
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
 
-		if !AdmCheck(w, r, log) {
-			return
+		if err := AdmCheck(r); err != nil {
+			return nil, err
 		}
 
-		id := util.GetUrlParam(w, r, log)
-		if id == 0 {
-			log.Info("missing or wrong id")
-			http.Error(w, "Missing or wrong id", http.StatusBadRequest)
-			return
+		id, err := util.ResolveID(r, User.UserID, "No such user")
+		if err != nil {
+			return nil, err
 		}
 
-		user, err := User.Get(id)
+		user, err := User.Get(r.Context(), id)
 		if err != nil {
-			if err.Error() == "database.postgres.Get: no such user" {
-				log.Info(err.Error())
-
-				http.Error(w, "No such user", http.StatusNotFound)
+			return nil, util.NotFound(err, "No such user")
+		}
 
-				return
-			}
-			util.InternalError(w, r, log, err)
-			return
+		schema, err := User.UserFields(r.Context())
+		if err != nil {
+			return nil, err
 		}
+		user.Custom = schema.Filter(user.Custom, fields.All)
 
 		log.Info("user successfully retrieved")
 		log.Debug(fmt.Sprintf("user: %v", user))
 
-		render.JSON(w, r, user)
-	}
+		return user, nil
+	})
 }
 
 // UpdateUser godoc
 // @Summary Update user's profile
+// @ID updateUser
 // @Description Updates the details of a user by accepting a JSON payload.
+// Custom holds the changed custom field values (null removes one), admins may change every field.
+// The response lists the changed fields with their old and new values, the change is recorded in the audit log.
 // Requires Authorization header with Bearer token for authentication.
 // @Tags admin
 // @Accept json
 // @Produce json
-// @Param id path int true "ID of the user"
+// @Param id path string true "Public ID (UUID) of the user"
 // @Param UserData body u.PutUser true "User data payload"
 // @Security BearerAuth
-// @Success 200 {object} u.TableUser "User profile updated successfully."
-// @Failure 400 {object} string "Invalid request payload or ID."
-// @Failure 400 {object} string "Duplicate login or email."
+// @Success 200 {object} u.UpdatedUser "User profile updated successfully."
+// @Failure 400 {object} util.Problem "Invalid request payload or ID."
+// @Failure 400 {object} util.Problem "Duplicate login or email."
+// @Failure 400 {object} util.Problem "Invalid custom field value or locale."
 // @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
-// @Failure 403 {object} string "Insufficient permissions."
-// @Failure 404 {object} string "User not found."
-// @Failure 500 {object} string "Internal server error."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 404 {object} util.Problem "User not found."
+// @Failure 422 {object} util.Problem "Username rejected by moderation."
+// @Failure 500 {object} util.Problem "Internal server error."
 // @Router /admin/users/{id} [put]
-func UpdateUser(log *slog.Logger, User AdminHandler) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		const op = "http-server.handlers.admin.UpdateUser"
+func UpdateUser(log *slog.Logger, User AdminHandler, mod *moderation.Moderator) http.HandlerFunc {
+	const op = "http-server.handlers.admin.UpdateUser"
 
-		log.With(util.SlogWith(op, r)...)
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
 
-		if !AdmCheck(w, r, log) {
-			return
+		if err := AdmCheck(r); err != nil {
+			return nil, err
 		}
 
 		var req u.PutUser
-		if err := render.DecodeJSON(r.Body, &req); err != nil {
-			log.Error("failed to decode request", sl.Err(err))
-
-			http.Error(w, "failed to deserialize json request", http.StatusBadRequest)
-
-			return
+		if err := util.DecodeJSON(r, &req); err != nil {
+			return nil, err
 		}
 
 		log.Info("request body decoded")
 		log.Debug("req: ", slog.Any("request", req))
 
-		validation.InitValidator()
-		if err := validation.ValidateStruct(req); err != nil {
-			log.Debug(fmt.Sprintf("validation failed: %v", err.Error()))
-
-			http.Error(w, fmt.Sprintf("Invalid input: %v", err.Error()), http.StatusBadRequest)
-
-			return
+		if err := util.Validate(req); err != nil {
+			return nil, err
 		}
 
 		log.Info("input validated")
 
-		id := util.GetUrlParam(w, r, log)
-		if id == 0 {
-			log.Info("missing or wrong id")
-			http.Error(w, "Missing or wrong id", http.StatusBadRequest)
-			return
+		locale, err := mail.ParseLocale(req.Locale)
+		if err != nil {
+			return nil, util.WrapError(err, http.StatusBadRequest, util.CodeInvalidInput, fmt.Sprintf("Invalid input: %v", err))
 		}
+		req.Locale = locale
 
-		n, err := User.UpdateUser(req, id)
+		id, err := util.ResolveID(r, User.UserID, "No such user")
 		if err != nil {
-			if n == 0 {
-				log.Info(err.Error())
-
-				http.Error(w, "No such user", http.StatusNotFound)
+			return nil, err
+		}
 
-				return
-			} else if n == -2 {
+		verdict, err := mod.Check(r.Context(), req.Username)
+		if err != nil {
+			return nil, util.WrapError(err, http.StatusUnprocessableEntity, util.CodeRejected, "Username was rejected by moderation")
+		}
 
-				log.Info(err.Error())
+		before, err := User.Get(r.Context(), id)
+		if err != nil {
+			return nil, util.NotFound(err, "No such user")
+		}
 
-				http.Error(w, "Login or email already used", http.StatusBadRequest)
+		schema, err := User.UserFields(r.Context())
+		if err != nil {
+			return nil, err
+		}
+		if _, err := schema.Apply(before.Custom, req.Custom, fields.All); err != nil {
+			return nil, util.WrapError(err, http.StatusBadRequest, util.CodeInvalidInput, fmt.Sprintf("Invalid input: %v", err))
+		}
 
-				return
+		n, err := User.UpdateUser(r.Context(), req, id)
+		if err != nil {
+			if n == -2 {
+				return nil, util.WrapError(err, http.StatusBadRequest, util.CodeConflict, "Login or email already used")
 			}
-			util.InternalError(w, r, log, err)
-			return
+			return nil, util.NotFound(err, "No such user")
 		}
 
-		user, err := User.Get(id)
+		user, err := User.Get(r.Context(), id)
 		if err != nil {
-			util.InternalError(w, r, log, err)
+			return nil, util.NotFound(err, "No such user")
 		}
 
+		mod.Record(r.Context(), verdict, moderation.Flag{Kind: moderation.KindUsername, UserID: user.ID, Ref: user.PublicID, Text: req.Username})
+
+		before.Custom = schema.Filter(before.Custom, fields.All)
+		user.Custom = schema.Filter(user.Custom, fields.All)
+		changes := audit(r, User, sdb.AuditUpdateUser, before, user)
+
 		log.Info("Successfully updated user")
 		log.Debug(fmt.Sprintf("user: %v", user))
 
-		render.JSON(w, r, user)
-	}
+		return u.UpdatedUser{TableUser: user, Changes: changes}, nil
+	})
 }
 
 // Remove godoc
 // @Summary Remove user
-// @Description Deletes a user by their ID.
+// @ID removeUser
+// @Description Soft-deletes a user by their ID. The user can no longer sign in but stays visible to admins with state=deleted.
 // Requires Authorization header with Bearer token for authentication.
 // @Tags admin
 // @Produce json
 // @Security BearerAuth
-// @Param id path int true "ID of the user"
+// @Param id path string true "Public ID (UUID) of the user"
 // @Success 200 {object} string "User successfully removed."
-// @Failure 400 {object} string "Invalid or missing user ID."
+// @Failure 400 {object} util.Problem "Invalid or missing user ID."
 // @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
-// @Failure 403 {object} string "Insufficient permissions."
-// @Failure 404 {object} string "User not found."
-// @Failure 500 {object} string "Internal server error."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 404 {object} util.Problem "User not found."
+// @Failure 500 {object} util.Problem "Internal server error."
 // @Router /admin/users/{id} [delete]
 func Remove(log *slog.Logger, User AdminHandler) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		const op = "http-server.handlers.admin.Remove"
-
-		log.With(util.SlogWith(op, r)...)
+	const op = "http-server.handlers.admin.Remove"
 
-		if !AdmCheck(w, r, log) {
-			return
-		}
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
 
-		id := util.GetUrlParam(w, r, log)
-		if id == 0 {
-			log.Info("missing or wrong id")
-			http.Error(w, "Missing or wrong id", http.StatusBadRequest)
-			return
+		if err := AdmCheck(r); err != nil {
+			return nil, err
 		}
 
-		n, err := User.Remove(id)
+		id, err := util.ResolveID(r, User.UserID, "No such user")
 		if err != nil {
-			if n == 0 {
-				log.Info(err.Error())
-
-				http.Error(w, "No such user", http.StatusNotFound)
+			return nil, err
+		}
 
-				return
-			}
-			util.InternalError(w, r, log, err)
-			return
+		if _, err := User.Remove(r.Context(), id); err != nil {
+			return nil, util.NotFound(err, "No such user")
 		}
 
 		log.Info("user successfully removed")
-	}
+
+		return nil, nil
+	})
 }
 
 // Block godoc
 // @Summary Block user
-// @Description Blocks a user by their ID, disabling their account.
+// @ID blockUser
+// @Description Blocks a user by their ID, disabling their account. The user's access tokens lose their permissions at once.
+// Users whose role grants permissions the requesting user lacks cannot be blocked, nor can the last admin.
 // Requires Authorization header with Bearer token for authentication.
 // @Tags admin
 // @Produce json
 // @Security BearerAuth
-// @Param id path int true "ID of the user"
-// @Success 200 {object} u.TableUser "User successfully blocked."
-// @Failure 400 {object} string "Invalid or missing user ID."
-// @Failure 404 {object} string "User not found."
-// @Failure 500 {object} string "Internal server error."
+// @Param id path string true "Public ID (UUID) of the user"
+// @Success 200 {object} u.UpdatedUser "User successfully blocked."
+// @Failure 400 {object} util.Problem "Invalid or missing user ID."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 404 {object} util.Problem "User not found."
+// @Failure 409 {object} util.Problem "The user is the last admin."
+// @Failure 500 {object} util.Problem "Internal server error."
 // @Router /admin/users/{id}/block [post]
 func Block(log *slog.Logger, User AdminHandler) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		const op = "http-server.handlers.admin.Block"
+	const op = "http-server.handlers.admin.Block"
 
-		changeField(w, r, log, User, op, "block", true)
-	}
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		if err := AdmCheck(r); err != nil {
+			return nil, err
+		}
+
+		return changeField(r, User, "block", true)
+	})
 }
 
-// Unlock godoc
-// @Summary Unlock user
-// @Description Unblocks a user by their ID, re-enabling their account.
+// Unblock godoc
+// @Summary Unblock user
+// @ID unblockUser
+// @Description Unblocks a user by their ID, re-enabling their account. Users whose role grants permissions
+// the requesting user lacks cannot be unblocked.
 // Requires Authorization header with Bearer token for authentication.
 // @Tags admin
 // @Produce json
 // @Security BearerAuth
-// @Param id path int true "ID of the user"
-// @Success 200 {object} u.TableUser "User successfully unblocked."
-// @Failure 400 {object} string "Invalid or missing user ID."
-// @Failure 404 {object} string "User not found."
-// @Failure 500 {object} string "Internal server error."
-// @Router /admin/users/{id}/unlock [post]
+// @Param id path string true "Public ID (UUID) of the user"
+// @Success 200 {object} u.UpdatedUser "User successfully unblocked."
+// @Failure 400 {object} util.Problem "Invalid or missing user ID."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 404 {object} util.Problem "User not found."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /admin/users/{id}/unblock [post]
 func Unblock(log *slog.Logger, User AdminHandler) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		const op = "http-server.handlers.admin.Unblock"
+	const op = "http-server.handlers.admin.Unblock"
 
-		changeField(w, r, log, User, op, "block", false)
-	}
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		if err := AdmCheck(r); err != nil {
+			return nil, err
+		}
+
+		return changeField(r, User, "block", false)
+	})
+}
+
+// RequirePasswordChange godoc
+// @Summary Require password change
+// @ID requirePasswordChange
+// @Description Flags a user to change their password: from their next sign in or token refresh their access token
+// only allows the password change, other routes answer 403 until they change it.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Public ID (UUID) of the user"
+// @Success 200 {object} u.UpdatedUser "User flagged."
+// @Failure 400 {object} util.Problem "Invalid or missing user ID."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 404 {object} util.Problem "User not found."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /admin/users/{id}/require-password-change [post]
+func RequirePasswordChange(log *slog.Logger, User AdminHandler) http.HandlerFunc {
+	const op = "http-server.handlers.admin.RequirePasswordChange"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		if err := AdmCheck(r); err != nil {
+			return nil, err
+		}
+
+		return changeField(r, User, "must_change_password", true)
+	})
+}
+
+// Merge godoc
+// @Summary Merge duplicate account
+// @ID mergeUsers
+// @Description Merges a duplicate account into the primary one: the duplicate's todos are moved to the primary user,
+// its refresh token is revoked and it is soft-deleted. The merge is recorded in the audit log.
+// With dryRun nothing is changed and the response tells what would change.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param MergeRequest body u.MergeRequest true "Public IDs of the primary and the duplicate account"
+// @Security BearerAuth
+// @Success 200 {object} u.MergeResult "Accounts merged, or the dry run result."
+// @Failure 400 {object} util.Problem "Invalid request payload or both IDs are the same."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 404 {object} util.Problem "User not found."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /admin/users/merge [post]
+func Merge(log *slog.Logger, User AdminHandler, versions *cache.Versions) http.HandlerFunc {
+	const op = "http-server.handlers.admin.Merge"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		if err := AdmCheck(r); err != nil {
+			return nil, err
+		}
+		actor, err := contextUser(r)
+		if err != nil {
+			return nil, err
+		}
+
+		var req u.MergeRequest
+		if err := util.DecodeJSON(r, &req); err != nil {
+			return nil, err
+		}
+
+		log.Info("request body decoded")
+		log.Debug("req: ", slog.Any("request", req))
+
+		if err := util.Validate(req); err != nil {
+			return nil, err
+		}
+		if strings.EqualFold(req.Primary, req.Duplicate) {
+			return nil, util.NewError(http.StatusBadRequest, util.CodeInvalidInput, "Cannot merge an account into itself")
+		}
+
+		primary, err := User.UserID(r.Context(), req.Primary)
+		if err != nil {
+			return nil, util.NotFound(err, "No such primary user")
+		}
+		duplicate, err := User.UserID(r.Context(), req.Duplicate)
+		if err != nil {
+			return nil, util.NotFound(err, "No such duplicate user")
+		}
+
+		result, err := User.MergeUsers(r.Context(), primary, duplicate, actor, req.DryRun)
+		if err != nil {
+			return nil, util.NotFound(err, "No such user")
+		}
+
+		// Both users' cached profiles and todo lists are out of date now
+		if !result.DryRun {
+			versions.Bump(primary)
+			versions.Bump(duplicate)
+		}
+
+		log.Info("users merged", slog.Bool("dry_run", result.DryRun), slog.Int64("todos_moved", result.TodosMoved))
+
+		return result, nil
+	})
+}
+
+// RestoreTodos godoc
+// @Summary Restore user's todos to a point in time
+// @ID restoreUserTodos
+// @Description Sets the user's todos back to their state at as_of: todos created later are deleted,
+// deleted ones are recreated with their old IDs and changed ones get their old title and status.
+// Todos moved to another account since then are skipped. The restore is recorded in the audit log
+// and in the todo history, so it can be undone by restoring to a time before it.
+// With dry_run nothing is changed and the response tells what would change.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags admin
+// @Produce json
+// @Param id path string true "Public ID (UUID) of the user"
+// @Param as_of query string true "Point in time to restore, RFC 3339, e.g. 2024-10-09T12:00:00Z"
+// @Param dry_run query bool false "Only report what would change"
+// @Security BearerAuth
+// @Success 200 {object} t.RestoreResult "Todos restored, or the dry run result."
+// @Failure 400 {object} util.Problem "Missing, malformed or future as_of, or invalid ID."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 404 {object} util.Problem "User not found."
+// @Failure 422 {object} util.Problem "as_of predates the recorded todo history."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /admin/users/{id}/todos/restore [post]
+func RestoreTodos(log *slog.Logger, User AdminHandler) http.HandlerFunc {
+	const op = "http-server.handlers.admin.RestoreTodos"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		if err := AdmCheck(r); err != nil {
+			return nil, err
+		}
+		actor, err := contextUser(r)
+		if err != nil {
+			return nil, err
+		}
+
+		id, err := util.ResolveID(r, User.UserID, "No such user")
+		if err != nil {
+			return nil, err
+		}
+
+		asOf, err := time.Parse(time.RFC3339, r.URL.Query().Get("as_of"))
+		if err != nil {
+			return nil, util.NewError(http.StatusBadRequest, util.CodeInvalidInput, "as_of must be an RFC 3339 time")
+		}
+		if asOf.After(clock.Now()) {
+			return nil, util.NewError(http.StatusBadRequest, util.CodeInvalidInput, "as_of is in the future")
+		}
+		dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+
+		result, err := User.RestoreTodos(r.Context(), id, actor, asOf, dryRun)
+		if err != nil {
+			if errors.Is(err, sdb.ErrNoHistory) {
+				return nil, util.WrapError(err, http.StatusUnprocessableEntity, util.CodeInvalidInput, "No todo history recorded that far back")
+			}
+			return nil, util.NotFound(err, "No such user")
+		}
+
+		log.Info("todos restored", slog.Time("as_of", asOf), slog.Bool("dry_run", result.DryRun),
+			slog.Int("restored", result.Restored), slog.Int("reverted", result.Reverted), slog.Int("deleted", result.Deleted))
+
+		return result, nil
+	})
 }
 
 // Update godoc
 // @Summary Update user's rights
-// @Description Updates specific fields related to user's rights by accepting a JSON payload.
+// @ID updateUserRights
+// @Description Sets the "block" or "must_change_password" flag of a user, roles are assigned with setUserRole.
+// Users whose role grants permissions the requesting user lacks cannot be changed, nor can the last admin be blocked.
 // Requires Authorization header with Bearer token for authentication.
 // @Tags admin
 // @Accept json
 // @Produce json
-// @Param id path int true "ID of the user"
+// @Param id path string true "Public ID (UUID) of the user"
 // @Param UserData body UpdateRequest true "User data for updating rights"
-// @Success 200 {object} u.TableUser "Rights successfully updated."
-// @Failure 400 {object} string "Invalid request payload or missing ID."
-// @Failure 400 {object} string "No such field."
-// @Failure 404 {object} string "User not found."
-// @Failure 500 {object} string "Internal server error."
+// @Success 200 {object} u.UpdatedUser "Rights successfully updated."
+// @Failure 400 {object} util.Problem "Invalid request payload or missing ID."
+// @Failure 400 {object} util.Problem "No such field."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 404 {object} util.Problem "User not found."
+// @Failure 409 {object} util.Problem "The user is the last admin."
+// @Failure 500 {object} util.Problem "Internal server error."
 // @Router /admin/users/{id}/rights [post]
 func Update(log *slog.Logger, User AdminHandler) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		const op = "http-server.handlers.admin.Update"
+	const op = "http-server.handlers.admin.Update"
 
-		log.With(util.SlogWith(op, r)...)
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
 
-		// if !AdmCheck(w, r, log) {
-		// 	return
-		// }
+		if err := AdmCheck(r); err != nil {
+			return nil, err
+		}
 
 		var req UpdateRequest
-		if err := render.DecodeJSON(r.Body, &req); err != nil {
-			log.Error("failed to decode request", sl.Err(err))
-
-			http.Error(w, "failed to deserialize json request", http.StatusBadRequest)
-
-			return
+		if err := util.DecodeJSON(r, &req); err != nil {
+			return nil, err
 		}
 
 		log.Info("request body decoded")
 		log.Debug("req: ", slog.Any("request", req))
 
-		id := util.GetUrlParam(w, r, log)
-		if id == 0 {
-			log.Info("missing or wrong id")
-			http.Error(w, "Missing or wrong id", http.StatusBadRequest)
-			return
+		return changeField(r, User, req.Field, req.Value)
+	})
+}
+
+// Metrics godoc
+// @Summary Get process metrics
+// @ID getMetrics
+// @Description Returns process counters (e.g. db_slow_queries by storage method) and runtime memory stats as JSON.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]any "Metrics retrieved."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Router /admin/metrics [get]
+func Metrics(log *slog.Logger) http.HandlerFunc {
+	const op = "http-server.handlers.admin.Metrics"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		if err := AdmCheck(r); err != nil {
+			return nil, err
 		}
 
-		if n, err := User.UpdateField(req.Field, id, req.Value); err != nil {
-			if n == 0 {
-				log.Info(err.Error())
+		expvar.Handler().ServeHTTP(w, r)
 
-				http.Error(w, "No such user", http.StatusNotFound)
+		return nil, nil
+	})
+}
 
-				return
-			} else if n == -2 {
-				log.Info(err.Error())
+// summaryWindow is the time the metrics summary covers
+const summaryWindow = time.Hour
 
-				http.Error(w, "No such field", http.StatusBadRequest)
+type MetricsHandler interface {
+	Summary(now time.Time, window time.Duration) metrics.Summary
+}
 
-				return
-			}
-			util.InternalError(w, r, log, err)
-			return
+// MetricsSummary godoc
+// @Summary Get latency and error rates by route
+// @ID getMetricsSummary
+// @Description Returns p50/p95 latency and error rates by route over the last hour, busiest routes first.
+// The requests are kept in process in a bounded buffer, under heavy traffic from is later than an hour ago.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} metrics.Summary "Summary retrieved."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Router /admin/metrics/summary [get]
+func MetricsSummary(log *slog.Logger, Metrics MetricsHandler) http.HandlerFunc {
+	const op = "http-server.handlers.admin.MetricsSummary"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		if err := AdmCheck(r); err != nil {
+			return nil, err
 		}
 
-		user, err := User.Get(id)
-		if err != nil {
-			util.InternalError(w, r, log, err)
-			return
+		return Metrics.Summary(clock.Now(), summaryWindow), nil
+	})
+}
+
+// Flagged godoc
+// @Summary Get flagged content
+// @ID listFlaggedContent
+// @Description Lists content that moderation flagged but accepted (moderation action 'flag'), newest first.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags admin
+// @Produce json
+// @Param kind query string false "Filter by kind: 'todo_title' or 'username'"
+// @Param limit query int false "Limit the number of entries returned (default is 20)"
+// @Param offset query int false "Offset for pagination (default is 0)"
+// @Security BearerAuth
+// @Success 200 {object} moderation.FlagsResponse "Flagged content retrieved."
+// @Failure 400 {object} util.Problem "Unknown kind."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /admin/moderation/flagged [get]
+func Flagged(log *slog.Logger, Content AdminHandler) http.HandlerFunc {
+	const op = "http-server.handlers.admin.Flagged"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		if err := AdmCheck(r); err != nil {
+			return nil, err
 		}
 
-		log.Info("Successfully updated user's rights")
-		log.Debug(fmt.Sprintf("user: %v", user))
+		kind := r.URL.Query().Get("kind")
+		switch kind {
+		case "", moderation.KindTodoTitle, moderation.KindUsername:
+		default:
+			return nil, util.NewError(http.StatusBadRequest, util.CodeBadRequest, "Unknown kind: must be todo_title or username")
+		}
 
-		render.JSON(w, r, user)
-	}
+		limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+		if err != nil || limit <= 0 || limit > 100 {
+			limit = 20
+		}
+		offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+		if err != nil || offset < 0 {
+			offset = 0
+		}
+
+		return Content.FlaggedContent(r.Context(), kind, limit, offset)
+	})
 }
 
-func contextAdmin(r *http.Request) (bool, error) {
+// AdmCheck returns a 401 error without a user context and a 403 one for users without admin permissions,
+// the routes check the permissions they need, see access.RequireScope
+func AdmCheck(r *http.Request) error {
 	userContext, ok := r.Context().Value(access.CxtKey("userContext")).(access.UserContext)
 	if !ok {
-		return false, fmt.Errorf("Unauthorized")
+		return util.NewError(http.StatusUnauthorized, util.CodeUnauthorized, "User context not found")
+	}
+	if !userContext.Staff() {
+		return util.NewError(http.StatusForbidden, util.CodeForbidden, "Not enough rights")
 	}
-	return userContext.IsAdmin, nil
+	return nil
 }
 
-func AdmCheck(w http.ResponseWriter, r *http.Request, log *slog.Logger) bool {
-	ok, err := contextAdmin(r)
-	if !ok {
-		if err != nil {
-			http.Error(w, "User context not found", http.StatusUnauthorized)
-
-			return false
+// customFilter reads the custom.<key>=<value> query parameters as values of the custom fields
+func customFilter(r *http.Request, Users AdminHandler) (map[string]any, error) {
+	var filter map[string]any
+	var schema fields.Schema
+	for param, values := range r.URL.Query() {
+		key, ok := strings.CutPrefix(param, "custom.")
+		if !ok {
+			continue
+		}
+		if filter == nil {
+			var err error
+			if schema, err = Users.UserFields(r.Context()); err != nil {
+				return nil, err
+			}
+			filter = make(map[string]any)
 		}
 
-		log.Info("Not enough rights")
+		f, ok := schema.Field(key)
+		if !ok {
+			return nil, util.NewError(http.StatusBadRequest, util.CodeBadRequest, fmt.Sprintf("Unknown custom field %q", key))
+		}
+		value, err := f.Parse(values[0])
+		if err != nil {
+			return nil, util.WrapError(err, http.StatusBadRequest, util.CodeInvalidInput, fmt.Sprintf("Invalid input: %v", err))
+		}
+		filter[key] = value
+	}
+	return filter, nil
+}
 
-		http.Error(w, "Not enough rights", http.StatusForbidden)
+func changeField(r *http.Request, User AdminHandler, field string, value any) (any, error) {
+	log := sl.FromContext(r.Context())
 
-		return false
+	id, err := util.ResolveID(r, User.UserID, "No such user")
+	if err != nil {
+		return nil, err
 	}
-	return true
-}
 
-func changeField(w http.ResponseWriter, r *http.Request, log *slog.Logger, User AdminHandler, op, field string, value bool) {
-	log.With(util.SlogWith(op, r)...)
+	before, err := User.Get(r.Context(), id)
+	if err != nil {
+		return nil, util.NotFound(err, "No such user")
+	}
+	if err := outranks(r, before); err != nil {
+		return nil, err
+	}
 
-	if !AdmCheck(w, r, log) {
-		return
+	if n, err := User.UpdateField(r.Context(), field, id, value); err != nil {
+		switch {
+		case n == -2:
+			return nil, util.WrapError(err, http.StatusBadRequest, util.CodeBadRequest, "No such field")
+		case errors.Is(err, sdb.ErrConflict):
+			return nil, util.WrapError(err, http.StatusConflict, util.CodeConflict, "The last admin cannot be blocked")
+		}
+		return nil, util.NotFound(err, "No such user")
 	}
 
-	id := util.GetUrlParam(w, r, log)
-	if id == 0 {
-		log.Info("missing or wrong id")
-		http.Error(w, "Missing or wrong id", http.StatusBadRequest)
-		return
+	user, err := User.Get(r.Context(), id)
+	if err != nil {
+		return nil, util.NotFound(err, "No such user")
 	}
 
-	if n, err := User.UpdateField(field, id, value); err != nil {
-		if n == 0 {
-			log.Info(err.Error())
+	changes := audit(r, User, sdb.AuditUpdateRights, before, user)
+
+	log.Info(fmt.Sprintf("Successfully updated field: %v to %v", field, value))
+	log.Debug(fmt.Sprintf("user: %v", user))
 
-			http.Error(w, "No such user", http.StatusNotFound)
-		} else if n == -2 {
-			log.Info(err.Error())
+	return u.UpdatedUser{TableUser: user, Changes: changes}, nil
+}
 
-			http.Error(w, "No such field", http.StatusBadRequest)
+// outranks refuses with 403 the changes to a user whose role grants permissions the requesting user lacks,
+// e.g. moderators cannot block admins
+func outranks(r *http.Request, target u.TableUser) error {
+	userContext, ok := r.Context().Value(access.CxtKey("userContext")).(access.UserContext)
+	if !ok {
+		return util.NewError(http.StatusUnauthorized, util.CodeUnauthorized, "User context not found")
+	}
+	for _, p := range target.Permissions {
+		if !userContext.Can(p) {
+			return util.NewError(http.StatusForbidden, util.CodeForbidden, "Not enough rights to change the user")
 		}
-		util.InternalError(w, r, log, err)
-		return
 	}
+	return nil
+}
+
+// audit records the fields changed between before and after by the requesting admin and returns them.
+// The update is already done, a failure to record it is only logged.
+func audit(r *http.Request, User AdminHandler, action string, before, after u.TableUser) []u.FieldChange {
+	log := sl.FromContext(r.Context())
 
-	user, err := User.Get(id)
+	changes := u.Diff(before, after)
+	if len(changes) == 0 {
+		return changes
+	}
+
+	actor, err := contextUser(r)
 	if err != nil {
-		util.InternalError(w, r, log, err)
-		return
+		log.Error("failed to record user changes", sl.Err(err))
+		return changes
+	}
+	if err := User.Audit(r.Context(), actor, action, after.ID, changes); err != nil {
+		log.Error("failed to record user changes", sl.Err(err))
 	}
 
-	log.Info(fmt.Sprintf("Successfully updated field: %v to %v", field, value))
-	log.Debug(fmt.Sprintf("user: %v", user))
+	return changes
+}
 
-	render.JSON(w, r, user)
+// TargetUser returns the internal id of the user in the {id} path param, so that writes of admins to a user
+// change the user's cache version, see cache.Versions.Middleware
+func TargetUser(User AdminHandler) func(r *http.Request) (int, bool) {
+	return func(r *http.Request) (int, bool) {
+		id, err := util.ResolveID(r, User.UserID, "No such user")
+		return id, err == nil
+	}
+}
+
+func contextUser(r *http.Request) (int, error) {
+	userContext, ok := r.Context().Value(access.CxtKey("userContext")).(access.UserContext)
+	if !ok {
+		return 0, util.NewError(http.StatusUnauthorized, util.CodeUnauthorized, "User context not found")
+	}
+	return userContext.UserId, nil
 }
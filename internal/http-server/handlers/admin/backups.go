@@ -0,0 +1,91 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/render"
+	util "github.com/sabbatD/srest-api/internal/http-server/handleUtil"
+	"github.com/sabbatD/srest-api/internal/lib/backup"
+	"github.com/sabbatD/srest-api/internal/lib/logger/sl"
+)
+
+// BackupHandler runs and lists database backups, see backup.Manager
+type BackupHandler interface {
+	Trigger(ctx context.Context) (backup.Backup, error)
+	List(ctx context.Context, limit int) (backup.ListResponse, error)
+}
+
+// StartBackup godoc
+// @Summary Start a database backup
+// @ID startBackup
+// @Description Starts a database backup in the background and returns its record with status 'running'.
+// The dump is stored in the blob store, its progress is visible in the backup list.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 202 {object} backup.Backup "Backup started."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 409 {object} util.Problem "A backup is already running."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /admin/backups [post]
+func StartBackup(log *slog.Logger, Backups BackupHandler) http.HandlerFunc {
+	const op = "http-server.handlers.admin.StartBackup"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		if err := AdmCheck(r); err != nil {
+			return nil, err
+		}
+
+		b, err := Backups.Trigger(r.Context())
+		if err != nil {
+			if errors.Is(err, backup.ErrRunning) {
+				return nil, util.WrapError(err, http.StatusConflict, util.CodeConflict, "A backup is already running")
+			}
+			return nil, err
+		}
+
+		log.Info("backup started", slog.Int("backup", b.ID))
+
+		render.Status(r, http.StatusAccepted)
+		return b, nil
+	})
+}
+
+// ListBackups godoc
+// @Summary Get recent backups
+// @ID listBackups
+// @Description Lists recent database backups, newest first, with their size and status: 'running', 'succeeded' or 'failed'.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags admin
+// @Produce json
+// @Param limit query int false "Limit the number of backups returned (default is 20)"
+// @Security BearerAuth
+// @Success 200 {object} backup.ListResponse "Backups retrieved."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /admin/backups [get]
+func ListBackups(log *slog.Logger, Backups BackupHandler) http.HandlerFunc {
+	const op = "http-server.handlers.admin.ListBackups"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		if err := AdmCheck(r); err != nil {
+			return nil, err
+		}
+
+		limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+		if err != nil || limit <= 0 || limit > 100 {
+			limit = 20
+		}
+
+		return Backups.List(r.Context(), limit)
+	})
+}
@@ -0,0 +1,105 @@
+package admin
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	util "github.com/sabbatD/srest-api/internal/http-server/handleUtil"
+	"github.com/sabbatD/srest-api/internal/lib/cache"
+	"github.com/sabbatD/srest-api/internal/lib/logger/sl"
+)
+
+// CacheHandler invalidates cached responses, see cache.Group
+type CacheHandler interface {
+	Invalidate(name, pattern string) (map[string]int, error)
+	Stats() []cache.Stats
+}
+
+// InvalidateCache godoc
+// @Summary Invalidate cached responses
+// @ID invalidateCache
+// @Description Deletes cached responses so the next request reads fresh data, e.g. on a stale-data complaint.
+// Either everything cached for a user (userId) or the entries whose key matches a glob pattern, e.g. "user:42:*" or "*" for all.
+// Keys of a user's entries start with "user:<internal id>:". Without cache every cache is searched.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param Invalidation body cache.Invalidation true "Cache, key pattern or user public ID"
+// @Security BearerAuth
+// @Success 200 {object} cache.Invalidated "Entries deleted per cache."
+// @Failure 400 {object} util.Problem "Invalid request payload or pattern."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 404 {object} util.Problem "No such cache or user."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /admin/cache/invalidate [post]
+func InvalidateCache(log *slog.Logger, Caches CacheHandler, User AdminHandler) http.HandlerFunc {
+	const op = "http-server.handlers.admin.InvalidateCache"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		if err := AdmCheck(r); err != nil {
+			return nil, err
+		}
+
+		var req cache.Invalidation
+		if err := util.DecodeJSON(r, &req); err != nil {
+			return nil, err
+		}
+
+		log.Info("request body decoded")
+		log.Debug("req: ", slog.Any("request", req))
+
+		if err := util.Validate(req); err != nil {
+			return nil, err
+		}
+
+		pattern := req.Pattern
+		if req.UserID != "" {
+			id, err := User.UserID(r.Context(), req.UserID)
+			if err != nil {
+				return nil, util.NotFound(err, "No such user")
+			}
+			pattern = cache.UserPattern(id)
+		}
+
+		deleted, err := Caches.Invalidate(req.Cache, pattern)
+		if err != nil {
+			if errors.Is(err, cache.ErrNoCache) {
+				return nil, util.WrapError(err, http.StatusNotFound, util.CodeNotFound, "No such cache")
+			}
+			return nil, util.WrapError(err, http.StatusBadRequest, util.CodeInvalidInput, "Invalid pattern")
+		}
+
+		log.Info("cache invalidated", slog.String("cache", req.Cache), slog.String("pattern", pattern), slog.Any("deleted", deleted))
+
+		return cache.Invalidated{Deleted: deleted}, nil
+	})
+}
+
+// CacheStats godoc
+// @Summary Get cache statistics
+// @ID getCacheStats
+// @Description Returns the size, limits, hits, misses, invalidations and evictions of each cache since the start.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} cache.Stats "Statistics per cache."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Router /admin/cache/stats [get]
+func CacheStats(log *slog.Logger, Caches CacheHandler) http.HandlerFunc {
+	const op = "http-server.handlers.admin.CacheStats"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		if err := AdmCheck(r); err != nil {
+			return nil, err
+		}
+
+		return Caches.Stats(), nil
+	})
+}
@@ -0,0 +1,65 @@
+package admin
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	util "github.com/sabbatD/srest-api/internal/http-server/handleUtil"
+	"github.com/sabbatD/srest-api/internal/lib/clients"
+	"github.com/sabbatD/srest-api/internal/lib/clock"
+)
+
+// maxClientDays bounds the days the client report covers
+const maxClientDays = 365
+
+type ClientsHandler interface {
+	ClientUsage(ctx context.Context, from time.Time, route string) ([]clients.Usage, error)
+}
+
+// Clients godoc
+// @Summary Get requests by client
+// @ID getClientUsage
+// @Description Returns the requests by client and route over the last days, the latest seen first.
+// The client is the X-Client header of the request, e.g. "ios/2.3.1", else its user agent.
+// Counts are written every clients.interval, the latest requests may be missing.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags admin
+// @Produce json
+// @Param days query int false "Days covered, today included (default is 30, at most 365)"
+// @Param route query string false "Only routes containing it, e.g. '/api/v1/todos'"
+// @Security BearerAuth
+// @Success 200 {object} clients.Report "Report retrieved."
+// @Failure 400 {object} util.Problem "Invalid days."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /admin/reports/clients [get]
+func Clients(log *slog.Logger, Clients ClientsHandler) http.HandlerFunc {
+	const op = "http-server.handlers.admin.Clients"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		if err := AdmCheck(r); err != nil {
+			return nil, err
+		}
+
+		days := 30
+		if v := r.URL.Query().Get("days"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > maxClientDays {
+				return nil, util.NewError(http.StatusBadRequest, util.CodeBadRequest, "Invalid days: must be 1 to 365")
+			}
+			days = n
+		}
+
+		from := clock.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1-days)
+		usage, err := Clients.ClientUsage(r.Context(), from, r.URL.Query().Get("route"))
+		if err != nil {
+			return nil, err
+		}
+
+		return clients.Report{From: from, Clients: usage}, nil
+	})
+}
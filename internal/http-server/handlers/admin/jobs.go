@@ -0,0 +1,183 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	util "github.com/sabbatD/srest-api/internal/http-server/handleUtil"
+	"github.com/sabbatD/srest-api/internal/lib/jobs"
+	"github.com/sabbatD/srest-api/internal/lib/logger/sl"
+)
+
+// JobsHandler reports the background job queues and handles their dead letters, see jobs.Worker
+type JobsHandler interface {
+	Stats() jobs.Stats
+	DeadJobs(ctx context.Context, limit int) (jobs.DeadList, error)
+	Requeue(ctx context.Context, actor int, id int64) error
+	Discard(ctx context.Context, actor int, id int64) error
+}
+
+// JobQueues godoc
+// @Summary Get background job queues
+// @ID getJobQueues
+// @Description Returns the background job queues of the instance by priority, highest first, with their limits and the jobs queued and running.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} jobs.Stats "Queues retrieved."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Router /admin/jobs [get]
+func JobQueues(log *slog.Logger, Jobs JobsHandler) http.HandlerFunc {
+	const op = "http-server.handlers.admin.JobQueues"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		if err := AdmCheck(r); err != nil {
+			return nil, err
+		}
+
+		return Jobs.Stats(), nil
+	})
+}
+
+// DeadJobs godoc
+// @Summary Get dead background jobs
+// @ID listDeadJobs
+// @Description Lists the background jobs that failed every attempt, newest first, with the error of the last attempt.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags admin
+// @Produce json
+// @Param limit query int false "Limit the number of jobs returned (default is 20)"
+// @Security BearerAuth
+// @Success 200 {object} jobs.DeadList "Dead jobs retrieved."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /admin/jobs/dead [get]
+func DeadJobs(log *slog.Logger, Jobs JobsHandler) http.HandlerFunc {
+	const op = "http-server.handlers.admin.DeadJobs"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		if err := AdmCheck(r); err != nil {
+			return nil, err
+		}
+
+		limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+		if err != nil || limit <= 0 || limit > 100 {
+			limit = 20
+		}
+
+		return Jobs.DeadJobs(r.Context(), limit)
+	})
+}
+
+// RequeueDeadJob godoc
+// @Summary Requeue a dead background job
+// @ID requeueDeadJob
+// @Description Queues the dead job again with every attempt and removes it from the dead jobs. The requeue is recorded in the audit log.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags admin
+// @Produce json
+// @Param id path int true "ID of the dead job"
+// @Security BearerAuth
+// @Success 200 {object} string "Job queued."
+// @Failure 400 {object} util.Problem "Invalid ID."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 404 {object} util.Problem "Dead job not found."
+// @Failure 409 {object} util.Problem "The queue of the job is no longer configured."
+// @Failure 503 {object} util.Problem "The queue is full, or no job workers are configured."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /admin/jobs/dead/{id}/requeue [post]
+func RequeueDeadJob(log *slog.Logger, Jobs JobsHandler) http.HandlerFunc {
+	const op = "http-server.handlers.admin.RequeueDeadJob"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		if err := AdmCheck(r); err != nil {
+			return nil, err
+		}
+		actor, err := contextUser(r)
+		if err != nil {
+			return nil, err
+		}
+		id, err := deadJobID(r)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := Jobs.Requeue(r.Context(), actor, id); err != nil {
+			switch {
+			case errors.Is(err, jobs.ErrFull):
+				return nil, util.WrapError(err, http.StatusServiceUnavailable, util.CodeLimit, "The job queue is full, try again later")
+			case errors.Is(err, jobs.ErrDisabled):
+				return nil, util.WrapError(err, http.StatusServiceUnavailable, util.CodeUnavailable, "No job workers are configured")
+			case errors.Is(err, jobs.ErrUnknownQueue):
+				return nil, util.WrapError(err, http.StatusConflict, util.CodeConflict, "The queue of the job is no longer configured")
+			}
+			return nil, util.NotFound(err, "No such dead job")
+		}
+
+		log.Info("dead job requeued", slog.Int64("job", id))
+
+		return nil, nil
+	})
+}
+
+// DeleteDeadJob godoc
+// @Summary Delete a dead background job
+// @ID deleteDeadJob
+// @Description Deletes the dead job without running it again. The deletion is recorded in the audit log.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags admin
+// @Produce json
+// @Param id path int true "ID of the dead job"
+// @Security BearerAuth
+// @Success 200 {object} string "Job deleted."
+// @Failure 400 {object} util.Problem "Invalid ID."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 404 {object} util.Problem "Dead job not found."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /admin/jobs/dead/{id} [delete]
+func DeleteDeadJob(log *slog.Logger, Jobs JobsHandler) http.HandlerFunc {
+	const op = "http-server.handlers.admin.DeleteDeadJob"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		if err := AdmCheck(r); err != nil {
+			return nil, err
+		}
+		actor, err := contextUser(r)
+		if err != nil {
+			return nil, err
+		}
+		id, err := deadJobID(r)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := Jobs.Discard(r.Context(), actor, id); err != nil {
+			return nil, util.NotFound(err, "No such dead job")
+		}
+
+		log.Info("dead job deleted", slog.Int64("job", id))
+
+		return nil, nil
+	})
+}
+
+func deadJobID(r *http.Request) (int64, error) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, util.NewError(http.StatusBadRequest, util.CodeInvalidID, "Missing or wrong id")
+	}
+	return id, nil
+}
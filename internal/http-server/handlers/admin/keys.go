@@ -0,0 +1,56 @@
+package admin
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	util "github.com/sabbatD/srest-api/internal/http-server/handleUtil"
+	kc "github.com/sabbatD/srest-api/internal/lib/keyConfig"
+	"github.com/sabbatD/srest-api/internal/lib/logger/sl"
+)
+
+// KeysHandler rotates the JWT signing key, see access.Rotator
+type KeysHandler interface {
+	Rotate(ctx context.Context, actor int) (kc.Rotation, error)
+}
+
+// RotateKey godoc
+// @Summary Rotate the JWT signing key
+// @ID rotateSigningKey
+// @Description Creates a signing key of the configured algorithm that signs tokens from now on, e.g. when the key may have leaked.
+// Tokens of the replaced key keep verifying until verifiesUntil, the lifetime of the longest lived token, so no one is signed out.
+// Other instances pick the key up within jwt.reload_interval. The rotation is recorded in the audit log.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} kc.Rotation "Key rotated."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /admin/jwt/rotate [post]
+func RotateKey(log *slog.Logger, Keys KeysHandler) http.HandlerFunc {
+	const op = "http-server.handlers.admin.RotateKey"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		if err := AdmCheck(r); err != nil {
+			return nil, err
+		}
+		actor, err := contextUser(r)
+		if err != nil {
+			return nil, err
+		}
+
+		rotation, err := Keys.Rotate(r.Context(), actor)
+		if err != nil {
+			return nil, err
+		}
+
+		log.Info("JWT signing key rotated", slog.String("kid", rotation.Kid), slog.String("retired", rotation.RetiredKid))
+
+		return rotation, nil
+	})
+}
@@ -0,0 +1,139 @@
+package admin
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	util "github.com/sabbatD/srest-api/internal/http-server/handleUtil"
+	"github.com/sabbatD/srest-api/internal/lib/logger/sl"
+	u "github.com/sabbatD/srest-api/internal/lib/userConfig"
+)
+
+// LimitsHandler reads and changes the limit overrides of a user
+type LimitsHandler interface {
+	UserID(ctx context.Context, publicID string) (int, error)
+	UserLimits(ctx context.Context, id int) (u.Limits, error)
+	SetUserLimits(ctx context.Context, actor, id int, l u.Limits) error
+}
+
+// RateLimiter takes per-user limits, see ratelimit.Limiter
+type RateLimiter interface {
+	SetLimit(key string, limit int)
+}
+
+// RateLimits are the limiters user limit overrides apply to
+type RateLimits struct {
+	TodoWrites RateLimiter
+	Reports    RateLimiter
+}
+
+// Apply sets the rate limits of the user from l, a missing override restores the default
+func (rl RateLimits) Apply(id int, l u.Limits) {
+	key := strconv.Itoa(id)
+	rl.TodoWrites.SetLimit(key, override(l.TodoWrites))
+	rl.Reports.SetLimit(key, override(l.Reports))
+}
+
+func override(limit *int) int {
+	if limit == nil {
+		return -1
+	}
+	return *limit
+}
+
+// UserLimits godoc
+// @Summary Get user limits
+// @ID getUserLimits
+// @Description Returns the limits set for the user that override the configured ones: todo writes and abuse reports
+// per rate limit window and the maximum number of todos. Missing fields use the defaults.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Public ID (UUID) of the user"
+// @Success 200 {object} u.Limits "Limit overrides retrieved."
+// @Failure 400 {object} util.Problem "Invalid or missing user ID."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 404 {object} util.Problem "User not found."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /admin/users/{id}/limits [get]
+func UserLimits(log *slog.Logger, Limits LimitsHandler) http.HandlerFunc {
+	const op = "http-server.handlers.admin.UserLimits"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		if err := AdmCheck(r); err != nil {
+			return nil, err
+		}
+
+		id, err := util.ResolveID(r, Limits.UserID, "No such user")
+		if err != nil {
+			return nil, err
+		}
+
+		return Limits.UserLimits(r.Context(), id)
+	})
+}
+
+// SetUserLimits godoc
+// @Summary Set user limits
+// @ID setUserLimits
+// @Description Replaces the limit overrides of the user, they apply from the next request. Zero lifts a rate limit,
+// missing fields restore the defaults and an empty object removes every override. The change is recorded in the audit log.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Public ID (UUID) of the user"
+// @Param Limits body u.Limits true "Limits that override the defaults"
+// @Success 200 {object} u.Limits "Limit overrides set."
+// @Failure 400 {object} util.Problem "Invalid request payload or user ID."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 404 {object} util.Problem "User not found."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /admin/users/{id}/limits [put]
+func SetUserLimits(log *slog.Logger, Limits LimitsHandler, rates RateLimits) http.HandlerFunc {
+	const op = "http-server.handlers.admin.SetUserLimits"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		if err := AdmCheck(r); err != nil {
+			return nil, err
+		}
+		actor, err := contextUser(r)
+		if err != nil {
+			return nil, err
+		}
+
+		id, err := util.ResolveID(r, Limits.UserID, "No such user")
+		if err != nil {
+			return nil, err
+		}
+
+		var l u.Limits
+		if err := util.DecodeJSON(r, &l); err != nil {
+			return nil, err
+		}
+
+		log.Info("request body decoded")
+		log.Debug("req: ", slog.Any("request", l))
+
+		if err := util.Validate(l); err != nil {
+			return nil, err
+		}
+
+		if err := Limits.SetUserLimits(r.Context(), actor, id, l); err != nil {
+			return nil, util.NotFound(err, "No such user")
+		}
+		rates.Apply(id, l)
+
+		log.Info("user limits set", slog.Int("id", id))
+
+		return l, nil
+	})
+}
@@ -0,0 +1,133 @@
+package admin
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	util "github.com/sabbatD/srest-api/internal/http-server/handleUtil"
+	"github.com/sabbatD/srest-api/internal/lib/clock"
+	"github.com/sabbatD/srest-api/internal/lib/logger/sl"
+	"github.com/sabbatD/srest-api/internal/lib/queries"
+)
+
+// QueryRunner runs the report queries, see queries.Runner
+type QueryRunner interface {
+	List() []queries.Query
+	Run(ctx context.Context, actor int, name string, params map[string]any) (queries.Result, error)
+}
+
+type QueryRequest struct {
+	Query  string         `json:"query" validate:"required"`
+	Params map[string]any `json:"params"`
+	// Format of the result, json by default
+	Format string `json:"format" validate:"omitempty,oneof=json csv"`
+}
+
+// Queries godoc
+// @Summary List report queries
+// @ID listQueries
+// @Description Returns the read-only report queries that can be run with POST /admin/query, with their parameters.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} queries.Query "Report queries retrieved."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /admin/queries [get]
+func Queries(log *slog.Logger, Runner QueryRunner) http.HandlerFunc {
+	const op = "http-server.handlers.admin.Queries"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		if err := AdmCheck(r); err != nil {
+			return nil, err
+		}
+
+		return Runner.List(), nil
+	})
+}
+
+// RunQuery godoc
+// @Summary Run a report query
+// @ID runQuery
+// @Description Runs one of the report queries listed by GET /admin/queries with the given parameters, missing ones take their defaults.
+// The query runs read-only with a statement timeout and returns at most the configured number of rows, truncated tells more matched.
+// The result is JSON, or CSV with a header row for format csv or an Accept: text/csv header. Every run is recorded in the audit log.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Produce text/csv
+// @Security BearerAuth
+// @Param Query body QueryRequest true "Query name, parameters and result format"
+// @Success 200 {object} queries.Result "Query result."
+// @Failure 400 {object} util.Problem "Invalid request payload or query parameters."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 404 {object} util.Problem "Unknown query."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /admin/query [post]
+func RunQuery(log *slog.Logger, Runner QueryRunner) http.HandlerFunc {
+	const op = "http-server.handlers.admin.RunQuery"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		if err := AdmCheck(r); err != nil {
+			return nil, err
+		}
+		actor, err := contextUser(r)
+		if err != nil {
+			return nil, err
+		}
+
+		var req QueryRequest
+		if err := util.DecodeJSON(r, &req); err != nil {
+			return nil, err
+		}
+
+		log.Info("request body decoded")
+		log.Debug("req: ", slog.Any("request", req))
+
+		if err := util.Validate(req); err != nil {
+			return nil, err
+		}
+
+		res, err := Runner.Run(r.Context(), actor, req.Query, req.Params)
+		switch {
+		case errors.Is(err, queries.ErrUnknownQuery):
+			return nil, util.WrapError(err, http.StatusNotFound, util.CodeNotFound, "No such query")
+		case errors.Is(err, queries.ErrInvalidParams):
+			return nil, util.WrapError(err, http.StatusBadRequest, util.CodeInvalidInput, fmt.Sprintf("Invalid input: %v", errors.Unwrap(err)))
+		case err != nil:
+			return nil, err
+		}
+
+		log.Info("query run", slog.String("query", req.Query), slog.Int("rows", len(res.Rows)))
+
+		if req.Format != "csv" && (req.Format != "" || !strings.Contains(r.Header.Get("Accept"), "text/csv")) {
+			return res, nil
+		}
+
+		var buf bytes.Buffer
+		if err := queries.WriteCSV(&buf, res); err != nil {
+			return nil, err
+		}
+
+		name := fmt.Sprintf("%s-%s.csv", req.Query, clock.Now().UTC().Format("20060102-150405"))
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+		w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+		w.Header().Set("X-Truncated", strconv.FormatBool(res.Truncated))
+		buf.WriteTo(w)
+
+		return nil, nil
+	})
+}
@@ -0,0 +1,279 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	sdb "github.com/sabbatD/srest-api/internal/database"
+	util "github.com/sabbatD/srest-api/internal/http-server/handleUtil"
+	"github.com/sabbatD/srest-api/internal/lib/logger/sl"
+	rc "github.com/sabbatD/srest-api/internal/lib/roleConfig"
+)
+
+// RoleHandler manages the roles and the roles of users
+type RoleHandler interface {
+	Roles(ctx context.Context) ([]rc.Role, error)
+	CreateRole(ctx context.Context, actor int, role rc.RoleRequest) (rc.Role, error)
+	UpdateRole(ctx context.Context, name string, actor int, role rc.RoleRequest) (rc.Role, error)
+	DeleteRole(ctx context.Context, name string, actor int) error
+	UserID(ctx context.Context, publicID string) (int, error)
+	SetUserRole(ctx context.Context, id, actor int, role string) error
+}
+
+// Roles godoc
+// @Summary Get roles
+// @ID listRoles
+// @Description Lists the roles with the permissions they grant and the number of their users, built-in roles first.
+// The admin role grants every permission.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} rc.Role "Roles retrieved."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /admin/roles [get]
+func Roles(log *slog.Logger, Roles RoleHandler) http.HandlerFunc {
+	const op = "http-server.handlers.admin.Roles"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		if err := AdmCheck(r); err != nil {
+			return nil, err
+		}
+
+		return Roles.Roles(r.Context())
+	})
+}
+
+// CreateRole godoc
+// @Summary Create a role
+// @ID createRole
+// @Description Creates a role granting the permissions, e.g. 'users:read' and 'moderation:write'. The creation is recorded in the audit log.
+// Role names are 2 to 50 lowercase letters, digits, '-' and '_', starting with a letter.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param Role body rc.RoleRequest true "Role"
+// @Security BearerAuth
+// @Success 201 {object} rc.Role "Role created."
+// @Failure 400 {object} util.Problem "Invalid name or unknown permission."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 409 {object} util.Problem "A role with the name exists."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /admin/roles [post]
+func CreateRole(log *slog.Logger, Roles RoleHandler) http.HandlerFunc {
+	const op = "http-server.handlers.admin.CreateRole"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		if err := AdmCheck(r); err != nil {
+			return nil, err
+		}
+		actor, err := contextUser(r)
+		if err != nil {
+			return nil, err
+		}
+
+		req, err := decodeRole(r)
+		if err != nil {
+			return nil, err
+		}
+		if err := rc.CheckName(req.Name); err != nil {
+			return nil, util.WrapError(err, http.StatusBadRequest, util.CodeInvalidInput, "Invalid role name: "+err.Error())
+		}
+
+		role, err := Roles.CreateRole(r.Context(), actor, req)
+		if err != nil {
+			return nil, err
+		}
+
+		log.Info("role created", slog.String("role", role.Name), slog.Any("permissions", role.Permissions))
+
+		render.Status(r, http.StatusCreated)
+		return role, nil
+	})
+}
+
+// UpdateRole godoc
+// @Summary Replace a role
+// @ID replaceRole
+// @Description Replaces the description and permissions of the role, the name in the body is ignored. The change is recorded in the audit log.
+// The admin and user roles cannot be changed. Users get the new permissions when their access token is refreshed.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param name path string true "Name of the role"
+// @Param Role body rc.RoleRequest true "Role"
+// @Security BearerAuth
+// @Success 200 {object} rc.Role "Role replaced."
+// @Failure 400 {object} util.Problem "Invalid input or unknown permission."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 404 {object} util.Problem "Role not found."
+// @Failure 409 {object} util.Problem "The role cannot be changed."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /admin/roles/{name} [put]
+func UpdateRole(log *slog.Logger, Roles RoleHandler) http.HandlerFunc {
+	const op = "http-server.handlers.admin.UpdateRole"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		if err := AdmCheck(r); err != nil {
+			return nil, err
+		}
+		actor, err := contextUser(r)
+		if err != nil {
+			return nil, err
+		}
+
+		req, err := decodeRole(r)
+		if err != nil {
+			return nil, err
+		}
+
+		name := chi.URLParam(r, "name")
+		role, err := Roles.UpdateRole(r.Context(), name, actor, req)
+		if err != nil {
+			if errors.Is(err, sdb.ErrConflict) {
+				return nil, util.WrapError(err, http.StatusConflict, util.CodeConflict, "The admin and user roles cannot be changed")
+			}
+			return nil, util.NotFound(err, "No such role")
+		}
+
+		log.Info("role updated", slog.String("role", role.Name), slog.Any("permissions", role.Permissions))
+
+		return role, nil
+	})
+}
+
+// DeleteRole godoc
+// @Summary Delete a role
+// @ID deleteRole
+// @Description Deletes the role, built-in roles and roles assigned to users cannot be deleted. The deletion is recorded in the audit log.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags admin
+// @Produce json
+// @Param name path string true "Name of the role"
+// @Security BearerAuth
+// @Success 200 {object} string "Role deleted."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 404 {object} util.Problem "Role not found."
+// @Failure 409 {object} util.Problem "The role is built in or assigned to users."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /admin/roles/{name} [delete]
+func DeleteRole(log *slog.Logger, Roles RoleHandler) http.HandlerFunc {
+	const op = "http-server.handlers.admin.DeleteRole"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		if err := AdmCheck(r); err != nil {
+			return nil, err
+		}
+		actor, err := contextUser(r)
+		if err != nil {
+			return nil, err
+		}
+
+		name := chi.URLParam(r, "name")
+		if err := Roles.DeleteRole(r.Context(), name, actor); err != nil {
+			if errors.Is(err, sdb.ErrConflict) {
+				return nil, util.WrapError(err, http.StatusConflict, util.CodeConflict, "Built-in roles and roles assigned to users cannot be deleted")
+			}
+			return nil, util.NotFound(err, "No such role")
+		}
+
+		log.Info("role deleted", slog.String("role", name))
+
+		return nil, nil
+	})
+}
+
+// SetUserRole godoc
+// @Summary Set the role of a user
+// @ID setUserRole
+// @Description Assigns the role to the user, the last admin keeps the admin role. The change is recorded in the audit log.
+// The user gets the permissions of the role when their access token is refreshed.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path string true "Public ID (UUID) of the user"
+// @Param Role body rc.UserRole true "Role"
+// @Security BearerAuth
+// @Success 200 {object} string "Role set."
+// @Failure 400 {object} util.Problem "Invalid input or user ID."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 404 {object} util.Problem "User or role not found."
+// @Failure 409 {object} util.Problem "The user is the last admin."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /admin/users/{id}/role [put]
+func SetUserRole(log *slog.Logger, Roles RoleHandler) http.HandlerFunc {
+	const op = "http-server.handlers.admin.SetUserRole"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		if err := AdmCheck(r); err != nil {
+			return nil, err
+		}
+		actor, err := contextUser(r)
+		if err != nil {
+			return nil, err
+		}
+
+		var req rc.UserRole
+		if err := util.DecodeJSON(r, &req); err != nil {
+			return nil, err
+		}
+		if err := util.Validate(req); err != nil {
+			return nil, err
+		}
+
+		id, err := util.ResolveID(r, Roles.UserID, "No such user")
+		if err != nil {
+			return nil, err
+		}
+
+		if err := Roles.SetUserRole(r.Context(), id, actor, req.Role); err != nil {
+			if errors.Is(err, sdb.ErrConflict) {
+				return nil, util.WrapError(err, http.StatusConflict, util.CodeConflict, "The last admin cannot be given another role")
+			}
+			return nil, util.NotFound(err, "No such user or role")
+		}
+
+		log.Info("user role set", slog.Int("id", id), slog.String("role", req.Role))
+
+		return nil, nil
+	})
+}
+
+// decodeRole reads a role request and checks its permissions
+func decodeRole(r *http.Request) (rc.RoleRequest, error) {
+	var req rc.RoleRequest
+	if err := util.DecodeJSON(r, &req); err != nil {
+		return req, err
+	}
+	if err := util.Validate(req); err != nil {
+		return req, err
+	}
+
+	permissions, err := rc.Check(req.Permissions)
+	if err != nil {
+		return req, util.WrapError(err, http.StatusBadRequest, util.CodeInvalidInput, "Unknown permission: "+err.Error())
+	}
+	req.Permissions = permissions
+	return req, nil
+}
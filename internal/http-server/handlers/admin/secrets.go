@@ -0,0 +1,156 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	util "github.com/sabbatD/srest-api/internal/http-server/handleUtil"
+	"github.com/sabbatD/srest-api/internal/lib/logger/sl"
+	"github.com/sabbatD/srest-api/internal/lib/secrets"
+)
+
+// SecretsHandler reads and changes the integration secrets, see secrets.Secrets
+type SecretsHandler interface {
+	Enabled() bool
+	List(ctx context.Context) ([]secrets.Info, error)
+	Set(ctx context.Context, actor int, name, value string) error
+	Delete(ctx context.Context, actor int, name string) error
+}
+
+var errNoSecrets = util.NewError(http.StatusServiceUnavailable, util.CodeUnavailable, "Secrets are not available: no master key is configured")
+
+// Secrets godoc
+// @Summary Get integration secrets
+// @ID listSecrets
+// @Description Lists the integration secrets admins can set, whether each is set and when it was last changed. Values are never returned.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} secrets.Info "Secrets retrieved."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Failure 503 {object} util.Problem "No secrets master key is configured."
+// @Router /admin/secrets [get]
+func Secrets(log *slog.Logger, Secrets SecretsHandler) http.HandlerFunc {
+	const op = "http-server.handlers.admin.Secrets"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		if err := AdmCheck(r); err != nil {
+			return nil, err
+		}
+		if !Secrets.Enabled() {
+			return nil, errNoSecrets
+		}
+
+		return Secrets.List(r.Context())
+	})
+}
+
+// SetSecret godoc
+// @Summary Set an integration secret
+// @ID setSecret
+// @Description Encrypts the value with the master key and stores it, the integration uses it instead of the configured one from then on.
+// The change is recorded in the audit log without the value.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param name path string true "Secret name, e.g. smtp.password"
+// @Param Value body secrets.Value true "Secret value"
+// @Security BearerAuth
+// @Success 200 {object} string "Secret set."
+// @Failure 400 {object} util.Problem "Invalid request payload."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 404 {object} util.Problem "Unknown secret."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Failure 503 {object} util.Problem "No secrets master key is configured."
+// @Router /admin/secrets/{name} [put]
+func SetSecret(log *slog.Logger, Secrets SecretsHandler) http.HandlerFunc {
+	const op = "http-server.handlers.admin.SetSecret"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		if err := AdmCheck(r); err != nil {
+			return nil, err
+		}
+		actor, err := contextUser(r)
+		if err != nil {
+			return nil, err
+		}
+		if !Secrets.Enabled() {
+			return nil, errNoSecrets
+		}
+
+		var req secrets.Value
+		if err := util.DecodeJSON(r, &req); err != nil {
+			return nil, err
+		}
+		if err := util.Validate(req); err != nil {
+			return nil, err
+		}
+
+		name := chi.URLParam(r, "name")
+		if err := Secrets.Set(r.Context(), actor, name, req.Value); err != nil {
+			if errors.Is(err, secrets.ErrUnknownName) {
+				return nil, util.WrapError(err, http.StatusNotFound, util.CodeNotFound, "No such secret")
+			}
+			return nil, err
+		}
+
+		log.Info("secret set", slog.String("name", name))
+
+		return nil, nil
+	})
+}
+
+// DeleteSecret godoc
+// @Summary Delete an integration secret
+// @ID deleteSecret
+// @Description Deletes the secret, the integration falls back to its configured value. The deletion is recorded in the audit log.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags admin
+// @Produce json
+// @Param name path string true "Secret name, e.g. smtp.password"
+// @Security BearerAuth
+// @Success 200 {object} string "Secret deleted."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 404 {object} util.Problem "Secret not set."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Failure 503 {object} util.Problem "No secrets master key is configured."
+// @Router /admin/secrets/{name} [delete]
+func DeleteSecret(log *slog.Logger, Secrets SecretsHandler) http.HandlerFunc {
+	const op = "http-server.handlers.admin.DeleteSecret"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		if err := AdmCheck(r); err != nil {
+			return nil, err
+		}
+		actor, err := contextUser(r)
+		if err != nil {
+			return nil, err
+		}
+		if !Secrets.Enabled() {
+			return nil, errNoSecrets
+		}
+
+		name := chi.URLParam(r, "name")
+		if err := Secrets.Delete(r.Context(), actor, name); err != nil {
+			return nil, util.NotFound(err, "Secret not set")
+		}
+
+		log.Info("secret deleted", slog.String("name", name))
+
+		return nil, nil
+	})
+}
@@ -0,0 +1,459 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	util "github.com/sabbatD/srest-api/internal/http-server/handleUtil"
+	"github.com/sabbatD/srest-api/internal/lib/alerting"
+	"github.com/sabbatD/srest-api/internal/lib/api/readonly"
+	"github.com/sabbatD/srest-api/internal/lib/expiry"
+	"github.com/sabbatD/srest-api/internal/lib/fields"
+	"github.com/sabbatD/srest-api/internal/lib/logger/sl"
+	"github.com/sabbatD/srest-api/internal/lib/retention"
+)
+
+// RetentionHandler reads and changes the retention policy, see retention.Runner
+type RetentionHandler interface {
+	Policy(ctx context.Context) (retention.Policy, error)
+	SetPolicy(ctx context.Context, actor int, p retention.Policy) error
+}
+
+// Retention godoc
+// @Summary Get retention policy
+// @ID getRetention
+// @Description Returns the retention policy in effect: how many days former logins, soft-deleted users and
+// audit entries are kept before the scheduled purge deletes them. Zero keeps the data forever.
+// Until an admin sets a policy the configured default applies.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} retention.Policy "Retention policy retrieved."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /admin/settings/retention [get]
+func Retention(log *slog.Logger, Settings RetentionHandler) http.HandlerFunc {
+	const op = "http-server.handlers.admin.Retention"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		if err := AdmCheck(r); err != nil {
+			return nil, err
+		}
+
+		return Settings.Policy(r.Context())
+	})
+}
+
+// SetRetention godoc
+// @Summary Set retention policy
+// @ID setRetention
+// @Description Replaces the retention policy, it applies from the next scheduled purge. The change is recorded in the audit log.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param Policy body retention.Policy true "Days to keep each kind of data, zero keeps it forever"
+// @Security BearerAuth
+// @Success 200 {object} retention.Policy "Retention policy set."
+// @Failure 400 {object} util.Problem "Invalid request payload."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /admin/settings/retention [put]
+func SetRetention(log *slog.Logger, Settings RetentionHandler) http.HandlerFunc {
+	const op = "http-server.handlers.admin.SetRetention"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		if err := AdmCheck(r); err != nil {
+			return nil, err
+		}
+		actor, err := contextUser(r)
+		if err != nil {
+			return nil, err
+		}
+
+		var p retention.Policy
+		if err := util.DecodeJSON(r, &p); err != nil {
+			return nil, err
+		}
+
+		log.Info("request body decoded")
+		log.Debug("req: ", slog.Any("request", p))
+
+		if err := util.Validate(p); err != nil {
+			return nil, err
+		}
+
+		if err := Settings.SetPolicy(r.Context(), actor, p); err != nil {
+			return nil, err
+		}
+
+		log.Info("retention policy set")
+
+		return p, nil
+	})
+}
+
+// UserFieldsHandler reads and changes the custom profile fields
+type UserFieldsHandler interface {
+	UserFields(ctx context.Context) (fields.Schema, error)
+	SetUserFields(ctx context.Context, actor int, schema fields.Schema) error
+}
+
+// UserFields godoc
+// @Summary Get custom profile fields
+// @ID getUserFieldSchema
+// @Description Returns the custom fields admins defined for user profiles, with their type, whether they are required and who sees them.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} fields.Schema "Custom fields retrieved."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /admin/settings/user-fields [get]
+func UserFields(log *slog.Logger, Settings UserFieldsHandler) http.HandlerFunc {
+	const op = "http-server.handlers.admin.UserFields"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		if err := AdmCheck(r); err != nil {
+			return nil, err
+		}
+
+		return Settings.UserFields(r.Context())
+	})
+}
+
+// SetUserFields godoc
+// @Summary Set custom profile fields
+// @ID setUserFieldSchema
+// @Description Replaces the custom profile fields. Values of removed fields are kept but no longer returned. The change is recorded in the audit log.
+// Keys hold lower case letters, digits and underscores. Types are text, number, boolean, date (YYYY-MM-DD) and select, which needs options.
+// Visibility is editable (default, set by the user), visible (shown to the user, set by admins) or admin (admins only).
+// Requires Authorization header with Bearer token for authentication.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param Schema body fields.Schema true "Custom field definitions"
+// @Security BearerAuth
+// @Success 200 {object} fields.Schema "Custom fields set."
+// @Failure 400 {object} util.Problem "Invalid request payload."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /admin/settings/user-fields [put]
+func SetUserFields(log *slog.Logger, Settings UserFieldsHandler) http.HandlerFunc {
+	const op = "http-server.handlers.admin.SetUserFields"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		if err := AdmCheck(r); err != nil {
+			return nil, err
+		}
+		actor, err := contextUser(r)
+		if err != nil {
+			return nil, err
+		}
+
+		var schema fields.Schema
+		if err := util.DecodeJSON(r, &schema); err != nil {
+			return nil, err
+		}
+
+		log.Info("request body decoded")
+		log.Debug("req: ", slog.Any("request", schema))
+
+		if err := util.Validate(schema); err != nil {
+			return nil, err
+		}
+		if err := schema.Check(); err != nil {
+			if errors.Is(err, fields.ErrInvalid) {
+				return nil, util.WrapError(err, http.StatusBadRequest, util.CodeInvalidInput, fmt.Sprintf("Invalid input: %v", err))
+			}
+			return nil, err
+		}
+		if schema.Fields == nil {
+			schema.Fields = []fields.Field{}
+		}
+
+		if err := Settings.SetUserFields(r.Context(), actor, schema); err != nil {
+			return nil, err
+		}
+
+		log.Info("custom profile fields set")
+
+		return schema, nil
+	})
+}
+
+// AlertingHandler reads and changes the alert rules, see alerting.Evaluator
+type AlertingHandler interface {
+	Rules(ctx context.Context) (alerting.Rules, error)
+	SetRules(ctx context.Context, actor int, rules alerting.Rules) error
+}
+
+// Alerting godoc
+// @Summary Get alert rules
+// @ID getAlertRules
+// @Description Returns the alert rules in effect: thresholds for the share of 5xx responses, failed logins and failed background jobs
+// over a sliding window, the cooldown between alerts of a kind and the webhook and emails alerts are sent to. A zero threshold disables its alert.
+// Until an admin sets rules the configured default applies.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} alerting.Rules "Alert rules retrieved."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /admin/settings/alerting [get]
+func Alerting(log *slog.Logger, Settings AlertingHandler) http.HandlerFunc {
+	const op = "http-server.handlers.admin.Alerting"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		if err := AdmCheck(r); err != nil {
+			return nil, err
+		}
+
+		return Settings.Rules(r.Context())
+	})
+}
+
+// SetAlerting godoc
+// @Summary Set alert rules
+// @ID setAlertRules
+// @Description Replaces the alert rules, they apply from the next check. The change is recorded in the audit log.
+// Emails are only sent when SMTP is configured. With a secrets master key the webhook URL is stored encrypted and left out
+// of the rules returned, rules without one keep the stored URL.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param Rules body alerting.Rules true "Thresholds, window in minutes and notification targets"
+// @Security BearerAuth
+// @Success 200 {object} alerting.Rules "Alert rules set."
+// @Failure 400 {object} util.Problem "Invalid request payload."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /admin/settings/alerting [put]
+func SetAlerting(log *slog.Logger, Settings AlertingHandler) http.HandlerFunc {
+	const op = "http-server.handlers.admin.SetAlerting"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		if err := AdmCheck(r); err != nil {
+			return nil, err
+		}
+		actor, err := contextUser(r)
+		if err != nil {
+			return nil, err
+		}
+
+		var rules alerting.Rules
+		if err := util.DecodeJSON(r, &rules); err != nil {
+			return nil, err
+		}
+
+		log.Info("request body decoded")
+		log.Debug("req: ", slog.Any("request", rules))
+
+		if err := util.Validate(rules); err != nil {
+			return nil, err
+		}
+
+		if err := Settings.SetRules(r.Context(), actor, rules); err != nil {
+			return nil, err
+		}
+
+		log.Info("alert rules set")
+
+		return rules, nil
+	})
+}
+
+// PasswordExpiryHandler reads and changes the password expiry policy, see expiry.Runner
+type PasswordExpiryHandler interface {
+	Policy(ctx context.Context) (expiry.Policy, error)
+	SetPolicy(ctx context.Context, actor int, p expiry.Policy) error
+}
+
+// PasswordExpiry godoc
+// @Summary Get password expiry policy
+// @ID getPasswordExpiry
+// @Description Returns the password expiry policy in effect: after how many days passwords expire and how many days ahead users are warned.
+// Zero days disables expiry. It applies to local accounts only, directory and SSO users sign in without a password.
+// Until an admin sets a policy the configured default applies.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} expiry.Policy "Password expiry policy retrieved."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /admin/settings/password-expiry [get]
+func PasswordExpiry(log *slog.Logger, Settings PasswordExpiryHandler) http.HandlerFunc {
+	const op = "http-server.handlers.admin.PasswordExpiry"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		if err := AdmCheck(r); err != nil {
+			return nil, err
+		}
+
+		return Settings.Policy(r.Context())
+	})
+}
+
+// SetPasswordExpiry godoc
+// @Summary Set password expiry policy
+// @ID setPasswordExpiry
+// @Description Replaces the password expiry policy, it applies from the next scheduled run: users are emailed once they are within
+// the warning period and must change their password on the next sign in once it expired. The change is recorded in the audit log.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param Policy body expiry.Policy true "Days a password is valid and days to warn ahead, zero disables either"
+// @Security BearerAuth
+// @Success 200 {object} expiry.Policy "Password expiry policy set."
+// @Failure 400 {object} util.Problem "Invalid request payload."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /admin/settings/password-expiry [put]
+func SetPasswordExpiry(log *slog.Logger, Settings PasswordExpiryHandler) http.HandlerFunc {
+	const op = "http-server.handlers.admin.SetPasswordExpiry"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		if err := AdmCheck(r); err != nil {
+			return nil, err
+		}
+		actor, err := contextUser(r)
+		if err != nil {
+			return nil, err
+		}
+
+		var p expiry.Policy
+		if err := util.DecodeJSON(r, &p); err != nil {
+			return nil, err
+		}
+
+		log.Info("request body decoded")
+		log.Debug("req: ", slog.Any("request", p))
+
+		if err := util.Validate(p); err != nil {
+			return nil, err
+		}
+
+		if err := Settings.SetPolicy(r.Context(), actor, p); err != nil {
+			return nil, err
+		}
+
+		log.Info("password expiry policy set")
+
+		return p, nil
+	})
+}
+
+// ReadOnlyHandler reads and switches read-only mode, see readonly.Switch
+type ReadOnlyHandler interface {
+	Mode(ctx context.Context) (readonly.Mode, error)
+	SetMode(ctx context.Context, actor int, m readonly.Mode) error
+}
+
+// ReadOnly godoc
+// @Summary Get read-only mode
+// @ID getReadOnly
+// @Description Returns whether the API is in read-only mode on this instance, with the message clients get. While it is on,
+// routes that write answer 503 READ_ONLY and reads go on. Forced is set when the configuration enables it.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} readonly.Mode "Read-only mode retrieved."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /admin/settings/read-only [get]
+func ReadOnly(log *slog.Logger, Settings ReadOnlyHandler) http.HandlerFunc {
+	const op = "http-server.handlers.admin.ReadOnly"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		if err := AdmCheck(r); err != nil {
+			return nil, err
+		}
+
+		return Settings.Mode(r.Context())
+	})
+}
+
+// SetReadOnly godoc
+// @Summary Switch read-only mode
+// @ID setReadOnly
+// @Description Switches read-only mode on or off, e.g. for migrations and failovers of the primary database. The change is recorded in the audit log.
+// It applies on this instance at once and on the others within the configured refresh interval. This route, signing in and
+// refreshing tokens stay open in read-only mode, so admins can switch it off again.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param Mode body readonly.Mode true "Whether writes are rejected and the message clients get"
+// @Security BearerAuth
+// @Success 200 {object} readonly.Mode "Read-only mode set."
+// @Failure 400 {object} util.Problem "Invalid request payload."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 409 {object} util.Problem "Read-only mode is forced by the configuration."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /admin/settings/read-only [put]
+func SetReadOnly(log *slog.Logger, Settings ReadOnlyHandler) http.HandlerFunc {
+	const op = "http-server.handlers.admin.SetReadOnly"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		if err := AdmCheck(r); err != nil {
+			return nil, err
+		}
+		actor, err := contextUser(r)
+		if err != nil {
+			return nil, err
+		}
+
+		var m readonly.Mode
+		if err := util.DecodeJSON(r, &m); err != nil {
+			return nil, err
+		}
+
+		log.Info("request body decoded")
+		log.Debug("req: ", slog.Any("request", m))
+
+		if err := util.Validate(m); err != nil {
+			return nil, err
+		}
+
+		if err := Settings.SetMode(r.Context(), actor, m); err != nil {
+			if errors.Is(err, readonly.ErrForced) {
+				return nil, util.WrapError(err, http.StatusConflict, util.CodeConflict, "Read-only mode is forced by the configuration")
+			}
+			return nil, err
+		}
+
+		log.Warn("read-only mode set", slog.Bool("enabled", m.Enabled))
+
+		return Settings.Mode(r.Context())
+	})
+}
@@ -0,0 +1,57 @@
+package admin
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	util "github.com/sabbatD/srest-api/internal/http-server/handleUtil"
+	u "github.com/sabbatD/srest-api/internal/lib/userConfig"
+)
+
+// SignInsHandler reads the sign in history of users
+type SignInsHandler interface {
+	UserID(ctx context.Context, publicID string) (int, error)
+	SignIns(ctx context.Context, id, limit int) ([]u.SignIn, error)
+}
+
+// SignIns godoc
+// @Summary Get sign ins of a user
+// @ID listUserSignIns
+// @Description Lists the latest successful sign ins of the user, newest first, with the method, address and, when a GeoIP database is configured, the country and city.
+// The last 100 sign ins of each user are kept.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Public ID (UUID) of the user"
+// @Param limit query int false "Limit the number of sign ins returned (default is 20, at most 100)"
+// @Success 200 {array} u.SignIn "Sign ins retrieved."
+// @Failure 400 {object} util.Problem "Invalid or missing user ID."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 404 {object} util.Problem "User not found."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /admin/users/{id}/sign-ins [get]
+func SignIns(log *slog.Logger, SignIns SignInsHandler) http.HandlerFunc {
+	const op = "http-server.handlers.admin.SignIns"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		if err := AdmCheck(r); err != nil {
+			return nil, err
+		}
+
+		id, err := util.ResolveID(r, SignIns.UserID, "No such user")
+		if err != nil {
+			return nil, err
+		}
+
+		limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+		if err != nil || limit <= 0 || limit > 100 {
+			limit = 20
+		}
+
+		return SignIns.SignIns(r.Context(), id, limit)
+	})
+}
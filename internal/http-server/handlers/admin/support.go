@@ -0,0 +1,65 @@
+package admin
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	util "github.com/sabbatD/srest-api/internal/http-server/handleUtil"
+	"github.com/sabbatD/srest-api/internal/lib/clock"
+	"github.com/sabbatD/srest-api/internal/lib/logger/sl"
+)
+
+// SupportHandler writes support bundles, see support.Bundle
+type SupportHandler interface {
+	Write(ctx context.Context, w io.Writer) error
+}
+
+// SupportBundle godoc
+// @Summary Download a support bundle
+// @ID getSupportBundle
+// @Description Returns a zip archive to attach to bug reports: the configuration with secrets redacted (about.json, config.json),
+// the recent error logs (errors.json), the migration status (migrations.json) and a snapshot of the metrics (metrics.json, latency.json).
+// manifest.json lists the files with their SHA-256 checksums. Parts that cannot be read, e.g. the migration status while
+// the database is down, hold the error instead.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags admin
+// @Produce application/zip
+// @Security BearerAuth
+// @Success 200 {file} file "Support bundle."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /admin/support-bundle [get]
+func SupportBundle(log *slog.Logger, Bundle SupportHandler) http.HandlerFunc {
+	const op = "http-server.handlers.admin.SupportBundle"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		if err := AdmCheck(r); err != nil {
+			return nil, err
+		}
+
+		// The bundle is small, building it first lets a failure still get an error response
+		var buf bytes.Buffer
+		if err := Bundle.Write(r.Context(), &buf); err != nil {
+			return nil, err
+		}
+
+		log.Info("support bundle created", slog.Int("size", buf.Len()))
+
+		name := fmt.Sprintf("sapi-support-%s.zip", clock.Now().UTC().Format("20060102-150405"))
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+		w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+		w.Header().Set("Cache-Control", "no-store")
+		buf.WriteTo(w)
+
+		return nil, nil
+	})
+}
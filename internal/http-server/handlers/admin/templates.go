@@ -0,0 +1,279 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	util "github.com/sabbatD/srest-api/internal/http-server/handleUtil"
+	"github.com/sabbatD/srest-api/internal/lib/logger/sl"
+	"github.com/sabbatD/srest-api/internal/lib/mail"
+)
+
+// TemplatesHandler reads and changes the email templates, see mail.Templates
+type TemplatesHandler interface {
+	List(ctx context.Context, locale string) ([]mail.TemplateInfo, error)
+	Get(ctx context.Context, name, locale string) (mail.TemplateInfo, error)
+	Set(ctx context.Context, actor int, name, locale string, t mail.Template) error
+	Reset(ctx context.Context, actor int, name, locale string) error
+	Preview(ctx context.Context, name, locale string, t *mail.Template) (mail.Rendered, error)
+}
+
+// Templates godoc
+// @Summary Get email templates
+// @ID listTemplates
+// @Description Returns the template in effect for every email the server sends in the locale, with the variables it may use.
+// A locale without its own template falls back to its parent locale, e.g. pt-BR to pt, then to the default one.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags admin
+// @Produce json
+// @Param locale query string false "BCP 47 language tag, the default locale when empty"
+// @Security BearerAuth
+// @Success 200 {array} mail.TemplateInfo "Templates retrieved."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 400 {object} util.Problem "Invalid locale."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /admin/templates [get]
+func Templates(log *slog.Logger, Templates TemplatesHandler) http.HandlerFunc {
+	const op = "http-server.handlers.admin.Templates"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		if err := AdmCheck(r); err != nil {
+			return nil, err
+		}
+
+		locale, err := templateLocale(r)
+		if err != nil {
+			return nil, err
+		}
+
+		return Templates.List(r.Context(), locale)
+	})
+}
+
+// Template godoc
+// @Summary Get an email template
+// @ID getTemplate
+// @Description Returns the template in effect for the email in the locale: the one an admin set (custom) or the built-in one,
+// with the locale it was found for, the variables it may use and the sample values previews use.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags admin
+// @Produce json
+// @Param name path string true "Template name: password_expiry, credentials_reset or alert"
+// @Param locale query string false "BCP 47 language tag, the default locale when empty"
+// @Security BearerAuth
+// @Success 200 {object} mail.TemplateInfo "Template retrieved."
+// @Failure 400 {object} util.Problem "Invalid locale."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 404 {object} util.Problem "No such template."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /admin/templates/{name} [get]
+func Template(log *slog.Logger, Templates TemplatesHandler) http.HandlerFunc {
+	const op = "http-server.handlers.admin.Template"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		if err := AdmCheck(r); err != nil {
+			return nil, err
+		}
+
+		locale, err := templateLocale(r)
+		if err != nil {
+			return nil, err
+		}
+
+		info, err := Templates.Get(r.Context(), chi.URLParam(r, "name"), locale)
+		if err != nil {
+			return nil, templateError(err)
+		}
+		return info, nil
+	})
+}
+
+// SetTemplate godoc
+// @Summary Set an email template
+// @ID setTemplate
+// @Description Replaces the subject and body of the email in the locale, they apply to the next email sent. Both use text/template syntax,
+// variables are written as {{.Name}}. A template that does not parse or uses a variable the email does not have is rejected.
+// The change is recorded in the audit log.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param name path string true "Template name: password_expiry, credentials_reset or alert"
+// @Param locale query string false "BCP 47 language tag, the default locale when empty"
+// @Param Template body mail.Template true "Subject and body"
+// @Security BearerAuth
+// @Success 200 {object} mail.TemplateInfo "Template set."
+// @Failure 400 {object} util.Problem "Invalid request payload, template or locale."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 404 {object} util.Problem "No such template."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /admin/templates/{name} [put]
+func SetTemplate(log *slog.Logger, Templates TemplatesHandler) http.HandlerFunc {
+	const op = "http-server.handlers.admin.SetTemplate"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		if err := AdmCheck(r); err != nil {
+			return nil, err
+		}
+		actor, err := contextUser(r)
+		if err != nil {
+			return nil, err
+		}
+
+		var t mail.Template
+		if err := util.DecodeJSON(r, &t); err != nil {
+			return nil, err
+		}
+
+		log.Info("request body decoded")
+		log.Debug("req: ", slog.Any("request", t))
+
+		if err := util.Validate(t); err != nil {
+			return nil, err
+		}
+
+		locale, err := templateLocale(r)
+		if err != nil {
+			return nil, err
+		}
+
+		name := chi.URLParam(r, "name")
+		if err := Templates.Set(r.Context(), actor, name, locale, t); err != nil {
+			return nil, templateError(err)
+		}
+
+		log.Info("email template set", slog.String("name", name), slog.String("locale", locale))
+
+		return Templates.Get(r.Context(), name, locale)
+	})
+}
+
+// ResetTemplate godoc
+// @Summary Reset an email template
+// @ID resetTemplate
+// @Description Deletes the template an admin set for the email in the locale, the built-in one or the one of the parent locale applies again. The change is recorded in the audit log.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags admin
+// @Produce json
+// @Param name path string true "Template name: password_expiry, credentials_reset or alert"
+// @Param locale query string false "BCP 47 language tag, the default locale when empty"
+// @Security BearerAuth
+// @Success 200 {object} mail.TemplateInfo "Template reset, returns the one in effect now."
+// @Failure 400 {object} util.Problem "Invalid locale."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 404 {object} util.Problem "No such template."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /admin/templates/{name} [delete]
+func ResetTemplate(log *slog.Logger, Templates TemplatesHandler) http.HandlerFunc {
+	const op = "http-server.handlers.admin.ResetTemplate"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		if err := AdmCheck(r); err != nil {
+			return nil, err
+		}
+		actor, err := contextUser(r)
+		if err != nil {
+			return nil, err
+		}
+
+		locale, err := templateLocale(r)
+		if err != nil {
+			return nil, err
+		}
+
+		name := chi.URLParam(r, "name")
+		if err := Templates.Reset(r.Context(), actor, name, locale); err != nil {
+			return nil, templateError(err)
+		}
+
+		log.Info("email template reset", slog.String("name", name), slog.String("locale", locale))
+
+		return Templates.Get(r.Context(), name, locale)
+	})
+}
+
+// PreviewTemplate godoc
+// @Summary Preview an email template
+// @ID previewTemplate
+// @Description Renders the template in the body, or the template in effect in the locale when the body is empty, with the sample values of the variables.
+// Nothing is stored, the preview shows a draft before it is set.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param name path string true "Template name: password_expiry, credentials_reset or alert"
+// @Param locale query string false "BCP 47 language tag, the default locale when empty"
+// @Param Template body mail.Template false "Draft subject and body"
+// @Security BearerAuth
+// @Success 200 {object} mail.Rendered "Rendered email."
+// @Failure 400 {object} util.Problem "Invalid request payload, template or locale."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 404 {object} util.Problem "No such template."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /admin/templates/{name}/preview [post]
+func PreviewTemplate(log *slog.Logger, Templates TemplatesHandler) http.HandlerFunc {
+	const op = "http-server.handlers.admin.PreviewTemplate"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		if err := AdmCheck(r); err != nil {
+			return nil, err
+		}
+
+		locale, err := templateLocale(r)
+		if err != nil {
+			return nil, err
+		}
+
+		var draft *mail.Template
+		if r.ContentLength != 0 {
+			var t mail.Template
+			if err := util.DecodeJSON(r, &t); err != nil {
+				return nil, err
+			}
+			if err := util.Validate(t); err != nil {
+				return nil, err
+			}
+			draft = &t
+		}
+
+		out, err := Templates.Preview(r.Context(), chi.URLParam(r, "name"), locale, draft)
+		if err != nil {
+			return nil, templateError(err)
+		}
+		return out, nil
+	})
+}
+
+// templateLocale returns the canonical locale of the locale query parameter, empty for the default one
+func templateLocale(r *http.Request) (string, error) {
+	locale, err := mail.ParseLocale(r.URL.Query().Get("locale"))
+	if err != nil {
+		return "", templateError(err)
+	}
+	return locale, nil
+}
+
+func templateError(err error) error {
+	switch {
+	case errors.Is(err, mail.ErrUnknownTemplate):
+		return util.WrapError(err, http.StatusNotFound, util.CodeNotFound, "No such template")
+	case errors.Is(err, mail.ErrInvalidTemplate):
+		return util.WrapError(err, http.StatusBadRequest, util.CodeInvalidInput, fmt.Sprintf("Invalid template: %v", err))
+	case errors.Is(err, mail.ErrInvalidLocale):
+		return util.WrapError(err, http.StatusBadRequest, util.CodeInvalidInput, fmt.Sprintf("Invalid locale: %v", err))
+	}
+	return err
+}
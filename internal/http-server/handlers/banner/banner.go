@@ -0,0 +1,254 @@
+// Package banner provides handlers for broadcast banners: admins manage them, clients show the active ones.
+package banner
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/render"
+
+	util "github.com/sabbatD/srest-api/internal/http-server/handleUtil"
+	"github.com/sabbatD/srest-api/internal/http-server/handlers/admin"
+	"github.com/sabbatD/srest-api/internal/lib/api/access"
+	bc "github.com/sabbatD/srest-api/internal/lib/bannerConfig"
+	"github.com/sabbatD/srest-api/internal/lib/clock"
+	"github.com/sabbatD/srest-api/internal/lib/logger/sl"
+)
+
+type BannerHandler interface {
+	CreateBanner(ctx context.Context, actor int, b bc.BannerRequest) (bc.Banner, error)
+	UpdateBanner(ctx context.Context, id, actor int, b bc.BannerRequest) (bc.Banner, error)
+	DeleteBanner(ctx context.Context, id, actor int) error
+	BannerID(ctx context.Context, publicID string) (int, error)
+	Banners(ctx context.Context) ([]bc.Banner, error)
+	ActiveBanners(ctx context.Context, audiences []string, now time.Time) ([]bc.Banner, error)
+}
+
+// Active godoc
+// @Summary Get active banners
+// @ID listActiveBanners
+// @Description Returns the banners to show now, most severe first. Authentication is optional: without a valid bearer token
+// only banners for all are returned, with one also the banners for the caller's audience (users, guests or admins).
+// @Tags banners
+// @Produce json
+// @Success 200 {array} bc.Banner "Active banners."
+// @Failure 500 {object} util.Problem "Internal error."
+// @Router /banners [get]
+func Active(log *slog.Logger, Banners BannerHandler) http.HandlerFunc {
+	const op = "http-server.handlers.banner.Active"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		// An expired token still gets the banners for all, e.g. a maintenance warning on the sign in page
+		user, signedIn := access.TokenUser(r)
+		audiences := bc.Audiences(signedIn, user.IsGuest, user.IsAdmin)
+
+		// The answer depends on the token, shared caches must not store it
+		w.Header().Set("Cache-Control", "private, no-cache")
+		w.Header().Add("Vary", "Authorization")
+
+		return Banners.ActiveBanners(r.Context(), audiences, clock.Now())
+	})
+}
+
+// All godoc
+// @Summary Get all banners
+// @ID listBanners
+// @Description Returns every banner, past and scheduled ones included, latest start first.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} bc.Banner "Banners retrieved."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /admin/banners [get]
+func All(log *slog.Logger, Banners BannerHandler) http.HandlerFunc {
+	const op = "http-server.handlers.banner.All"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		if err := admin.AdmCheck(r); err != nil {
+			return nil, err
+		}
+
+		return Banners.Banners(r.Context())
+	})
+}
+
+// Create godoc
+// @Summary Create a banner
+// @ID createBanner
+// @Description Creates a banner clients show from starts (default now) until ends, or until it is deleted without ends.
+// Severities are 'info', 'warning' and 'critical', audiences 'all' (default), 'users', 'guests' and 'admins'.
+// The creation is recorded in the audit log.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param Banner body bc.BannerRequest true "Banner"
+// @Security BearerAuth
+// @Success 201 {object} bc.Banner "Banner created."
+// @Failure 400 {object} util.Problem "Invalid input or ends not after starts."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /admin/banners [post]
+func Create(log *slog.Logger, Banners BannerHandler) http.HandlerFunc {
+	const op = "http-server.handlers.banner.Create"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		if err := admin.AdmCheck(r); err != nil {
+			return nil, err
+		}
+		actor, err := contextUser(r)
+		if err != nil {
+			return nil, err
+		}
+
+		req, err := decode(r)
+		if err != nil {
+			return nil, err
+		}
+
+		banner, err := Banners.CreateBanner(r.Context(), actor, req)
+		if err != nil {
+			return nil, err
+		}
+
+		log.Info("banner created", slog.String("banner", banner.PublicID), slog.String("severity", banner.Severity))
+
+		render.Status(r, http.StatusCreated)
+		return banner, nil
+	})
+}
+
+// Update godoc
+// @Summary Replace a banner
+// @ID replaceBanner
+// @Description Replaces the banner, starts defaults to now. The change is recorded in the audit log.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path string true "Public ID (UUID) of the banner"
+// @Param Banner body bc.BannerRequest true "Banner"
+// @Security BearerAuth
+// @Success 200 {object} bc.Banner "Banner replaced."
+// @Failure 400 {object} util.Problem "Invalid ID or input, or ends not after starts."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 404 {object} util.Problem "Banner not found."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /admin/banners/{id} [put]
+func Update(log *slog.Logger, Banners BannerHandler) http.HandlerFunc {
+	const op = "http-server.handlers.banner.Update"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		if err := admin.AdmCheck(r); err != nil {
+			return nil, err
+		}
+		actor, err := contextUser(r)
+		if err != nil {
+			return nil, err
+		}
+
+		id, err := util.ResolveID(r, Banners.BannerID, "No such banner")
+		if err != nil {
+			return nil, err
+		}
+
+		req, err := decode(r)
+		if err != nil {
+			return nil, err
+		}
+
+		banner, err := Banners.UpdateBanner(r.Context(), id, actor, req)
+		if err != nil {
+			return nil, util.NotFound(err, "No such banner")
+		}
+
+		log.Info("banner updated", slog.String("banner", banner.PublicID))
+
+		return banner, nil
+	})
+}
+
+// Delete godoc
+// @Summary Delete a banner
+// @ID deleteBanner
+// @Description Deletes the banner, clients stop showing it. The deletion is recorded in the audit log.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags admin
+// @Produce json
+// @Param id path string true "Public ID (UUID) of the banner"
+// @Security BearerAuth
+// @Success 200 {object} string "Banner deleted."
+// @Failure 400 {object} util.Problem "Invalid ID."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 404 {object} util.Problem "Banner not found."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /admin/banners/{id} [delete]
+func Delete(log *slog.Logger, Banners BannerHandler) http.HandlerFunc {
+	const op = "http-server.handlers.banner.Delete"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		if err := admin.AdmCheck(r); err != nil {
+			return nil, err
+		}
+		actor, err := contextUser(r)
+		if err != nil {
+			return nil, err
+		}
+
+		id, err := util.ResolveID(r, Banners.BannerID, "No such banner")
+		if err != nil {
+			return nil, err
+		}
+
+		if err := Banners.DeleteBanner(r.Context(), id, actor); err != nil {
+			return nil, util.NotFound(err, "No such banner")
+		}
+
+		log.Info("banner deleted", slog.Int("banner_id", id))
+
+		return nil, nil
+	})
+}
+
+// decode reads and validates a banner request, a missing start is now
+func decode(r *http.Request) (bc.BannerRequest, error) {
+	var req bc.BannerRequest
+	if err := util.DecodeJSON(r, &req); err != nil {
+		return req, err
+	}
+	if err := util.Validate(req); err != nil {
+		return req, err
+	}
+
+	if req.Starts == nil {
+		now := clock.Now()
+		req.Starts = &now
+	}
+	if req.Ends != nil && !req.Ends.After(*req.Starts) {
+		return req, util.NewError(http.StatusBadRequest, util.CodeInvalidInput, "Invalid input: ends must be after starts")
+	}
+
+	return req, nil
+}
+
+func contextUser(r *http.Request) (int, error) {
+	userContext, ok := r.Context().Value(access.CxtKey("userContext")).(access.UserContext)
+	if !ok {
+		return 0, util.NewError(http.StatusUnauthorized, util.CodeUnauthorized, "User context not found")
+	}
+	return userContext.UserId, nil
+}
@@ -0,0 +1,205 @@
+// Package batch runs several API requests in one round trip.
+// Sub-requests are replayed through the API router, so authentication, rate limits and deadlines apply to each.
+package batch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+
+	util "github.com/sabbatD/srest-api/internal/http-server/handleUtil"
+	"github.com/sabbatD/srest-api/internal/lib/logger/sl"
+)
+
+const maxRequests = 20
+
+// Request is a sub-request, Path is relative to the API base path, e.g. "/todos?filter=completed"
+type Request struct {
+	Method  string            `json:"method" validate:"required,oneof=GET POST PUT PATCH DELETE"`
+	Path    string            `json:"path" validate:"required,startswith=/"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty" swaggertype:"object"`
+}
+
+type BatchRequest struct {
+	Requests []Request `json:"requests" validate:"required,min=1,dive"`
+}
+
+// Response is a sub-response, JSON bodies are embedded as is, other bodies as a string
+type Response struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty" swaggertype:"object"`
+}
+
+// Handle godoc
+// @Summary Run several requests at once
+// @ID batch
+// @Description Executes up to 20 sub-requests and returns their responses in the same order.
+// Each sub-request gets the caller's Authorization header unless it sets its own.
+// Writes run one at a time in order, consecutive GET requests run in parallel; a read always sees the writes before it.
+// A failing sub-request does not stop the batch, its error is returned as its response.
+// @Tags batch
+// @Accept json
+// @Produce json
+// @Param Batch body BatchRequest true "Sub-requests, paths relative to /api/v1"
+// @Success 200 {array} Response "Responses of the sub-requests."
+// @Failure 400 {object} util.Problem "Invalid batch, too many or nested sub-requests."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /batch [post]
+func Handle(log *slog.Logger, api http.Handler, base string) http.HandlerFunc {
+	const op = "http-server.handlers.batch.Handle"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		var req BatchRequest
+		if err := util.DecodeJSON(r, &req); err != nil {
+			return nil, err
+		}
+		for i := range req.Requests {
+			req.Requests[i].Method = strings.ToUpper(req.Requests[i].Method)
+		}
+		if len(req.Requests) > maxRequests {
+			return nil, util.NewError(http.StatusBadRequest, util.CodeBadRequest, fmt.Sprintf("Too many requests: at most %d per batch", maxRequests))
+		}
+		if err := util.Validate(req); err != nil {
+			return nil, err
+		}
+		for _, sub := range req.Requests {
+			if sub.Path == "/batch" || strings.HasPrefix(sub.Path, "/batch/") || strings.HasPrefix(sub.Path, "/batch?") {
+				return nil, util.NewError(http.StatusBadRequest, util.CodeBadRequest, "Batches cannot be nested")
+			}
+		}
+
+		responses := make([]Response, len(req.Requests))
+		for i := 0; i < len(req.Requests); {
+			// A run of reads goes in parallel, anything else alone.
+			j := i + 1
+			if req.Requests[i].Method == http.MethodGet {
+				for j < len(req.Requests) && req.Requests[j].Method == http.MethodGet {
+					j++
+				}
+			}
+
+			var wg sync.WaitGroup
+			for k := i; k < j; k++ {
+				wg.Add(1)
+				go func(k int) {
+					defer wg.Done()
+					responses[k] = serve(r, api, base, k, req.Requests[k])
+				}(k)
+			}
+			wg.Wait()
+			i = j
+		}
+
+		log.Info("batch executed", slog.Int("requests", len(req.Requests)))
+
+		return responses, nil
+	})
+}
+
+// serve runs sub-request i of the outer request r through api
+func serve(r *http.Request, api http.Handler, base string, i int, sub Request) (resp Response) {
+	// The sub-request gets its own routing state, everything else (cancellation, deadline) is inherited.
+	ctx := context.WithValue(r.Context(), chi.RouteCtxKey, nil)
+
+	sr, err := http.NewRequestWithContext(ctx, sub.Method, base+sub.Path, bytes.NewReader(sub.Body))
+	if err != nil {
+		return errorResponse(http.StatusBadRequest, util.CodeBadRequest, "Invalid sub-request: "+err.Error())
+	}
+	sr.RemoteAddr = r.RemoteAddr
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		sr.Header.Set("Authorization", auth)
+	}
+	if len(sub.Body) > 0 {
+		sr.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range sub.Headers {
+		sr.Header.Set(k, v)
+	}
+	sr.Header.Set(middleware.RequestIDHeader, fmt.Sprintf("%s-%d", middleware.GetReqID(r.Context()), i))
+
+	rec := &recorder{header: http.Header{}}
+	defer func() {
+		// Streaming handlers abort when failing after the first byte, that fails only this sub-request.
+		if v := recover(); v != nil {
+			if v != http.ErrAbortHandler {
+				panic(v)
+			}
+			resp = errorResponse(http.StatusInternalServerError, util.CodeInternal, "Response aborted")
+		}
+	}()
+	api.ServeHTTP(rec, sr)
+
+	return rec.response()
+}
+
+func errorResponse(status int, code, detail string) Response {
+	body, _ := json.Marshal(util.Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	})
+	return Response{Status: status, Headers: map[string]string{"Content-Type": "application/problem+json"}, Body: body}
+}
+
+// recorder buffers a sub-response
+type recorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *recorder) Header() http.Header { return rec.header }
+
+func (rec *recorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+}
+
+func (rec *recorder) Write(p []byte) (int, error) {
+	rec.WriteHeader(http.StatusOK)
+	return rec.body.Write(p)
+}
+
+// response keeps the headers a client acts on and embeds the body
+func (rec *recorder) response() Response {
+	resp := Response{Status: rec.status, Headers: map[string]string{}}
+	if resp.Status == 0 {
+		resp.Status = http.StatusOK
+	}
+	for _, k := range []string{"Content-Type", "Location", "Retry-After", "ETag", "Last-Modified",
+		"X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset"} {
+		if v := rec.header.Get(k); v != "" {
+			resp.Headers[k] = v
+		}
+	}
+	for k := range rec.header {
+		if strings.HasPrefix(k, "X-Ratelimit-") {
+			resp.Headers[k] = rec.header.Get(k)
+		}
+	}
+
+	data := bytes.TrimSpace(rec.body.Bytes())
+	switch {
+	case len(data) == 0:
+	case json.Valid(data) && strings.Contains(rec.header.Get("Content-Type"), "json"):
+		resp.Body = data
+	default:
+		resp.Body, _ = json.Marshal(string(data))
+	}
+	return resp
+}
@@ -0,0 +1,118 @@
+package batch
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+)
+
+func newAPI() http.Handler {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	var mu sync.Mutex
+	var items []string
+
+	route := chi.NewRouter()
+	route.Route("/api/v1", func(r chi.Router) {
+		r.Use(middleware.RequestID)
+		r.Use(middleware.Recoverer)
+
+		r.Post("/batch", Handle(log, route, "/api/v1"))
+		r.Get("/whoami", func(w http.ResponseWriter, r *http.Request) {
+			render.JSON(w, r, map[string]string{"auth": r.Header.Get("Authorization")})
+		})
+		r.Post("/items", func(w http.ResponseWriter, r *http.Request) {
+			var item struct{ Name string }
+			render.DecodeJSON(r.Body, &item)
+			mu.Lock()
+			items = append(items, item.Name)
+			mu.Unlock()
+			w.WriteHeader(http.StatusCreated)
+		})
+		r.Get("/items", func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+			render.JSON(w, r, items)
+		})
+		r.Get("/abort", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("partial"))
+			panic(http.ErrAbortHandler)
+		})
+	})
+	return route
+}
+
+func post(tt *testing.T, h http.Handler, body string) *httptest.ResponseRecorder {
+	tt.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/batch", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer token")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestBatch(tt *testing.T) {
+	api := newAPI()
+
+	rec := post(tt, api, `{"requests": [
+		{"method": "get", "path": "/whoami"},
+		{"method": "POST", "path": "/items", "body": {"name": "a"}},
+		{"method": "POST", "path": "/items", "body": {"name": "b"}},
+		{"method": "GET", "path": "/items"},
+		{"method": "GET", "path": "/missing"},
+		{"method": "GET", "path": "/abort"}
+	]}`)
+	if rec.Code != http.StatusOK {
+		tt.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+
+	var got []Response
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		tt.Fatal(err)
+	}
+
+	want := []struct {
+		status int
+		body   string
+	}{
+		{200, `{"auth":"Bearer token"}`},
+		{201, ``},
+		{201, ``},
+		{200, `["a","b"]`},
+		{404, `"404 page not found"`},
+		{500, ``},
+	}
+	if len(got) != len(want) {
+		tt.Fatalf("got %d responses, want %d", len(got), len(want))
+	}
+	for i, w := range want {
+		if got[i].Status != w.status || (w.body != "" && string(got[i].Body) != w.body) {
+			tt.Errorf("response %d = %d %s, want %d %s", i, got[i].Status, got[i].Body, w.status, w.body)
+		}
+	}
+}
+
+func TestBatchInvalid(tt *testing.T) {
+	api := newAPI()
+
+	for name, body := range map[string]string{
+		"nested":   `{"requests": [{"method": "POST", "path": "/batch"}]}`,
+		"empty":    `{"requests": []}`,
+		"method":   `{"requests": [{"method": "TRACE", "path": "/items"}]}`,
+		"relative": `{"requests": [{"method": "GET", "path": "items"}]}`,
+		"too many": `{"requests": [` + strings.Repeat(`{"method": "GET", "path": "/items"},`, maxRequests) + `{"method": "GET", "path": "/items"}]}`,
+	} {
+		if rec := post(tt, api, body); rec.Code != http.StatusBadRequest {
+			tt.Errorf("%s: status = %d, want 400", name, rec.Code)
+		}
+	}
+}
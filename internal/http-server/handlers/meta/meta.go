@@ -0,0 +1,133 @@
+// Package meta tells clients which deployment they talk to.
+package meta
+
+import (
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	util "github.com/sabbatD/srest-api/internal/http-server/handleUtil"
+	"github.com/sabbatD/srest-api/internal/lib/api/access"
+	"github.com/sabbatD/srest-api/internal/lib/api/routes"
+	"github.com/sabbatD/srest-api/internal/lib/api/spec"
+	m "github.com/sabbatD/srest-api/internal/lib/meta"
+	"github.com/swaggo/swag"
+)
+
+// Get godoc
+// @Summary Get deployment metadata
+// @ID getMeta
+// @Description Returns the server version and build commit, the enabled features, the supported sign in methods
+// and the branding strings of this deployment, so clients can adapt their UI to it. No authentication required.
+// @Tags meta
+// @Produce json
+// @Success 200 {object} m.Info "Deployment metadata."
+// @Router /meta [get]
+func Get(log *slog.Logger, info m.Info) http.HandlerFunc {
+	const op = "http-server.handlers.meta.Get"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		return info, nil
+	})
+}
+
+// Health is the liveness report of the running build
+type Health struct {
+	Status  string    `json:"status"`
+	Version string    `json:"version"`
+	Commit  string    `json:"commit"`
+	Started time.Time `json:"started"`
+}
+
+// Healthz godoc
+// @Summary Health check
+// @ID healthz
+// @Description Reports the server is up, with the version and commit of the running build and when it started,
+// e.g. to tell which build runs on which host. Served outside the API base path, no authentication required.
+// @Tags meta
+// @Produce json
+// @Success 200 {object} Health "Server is up."
+// @x-outside-base true
+// @Router /healthz [get]
+func Healthz(log *slog.Logger, started time.Time) http.HandlerFunc {
+	const op = "http-server.handlers.meta.Healthz"
+
+	health := Health{Status: "ok", Version: m.Version, Commit: m.BuildCommit(), Started: started}
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		return health, nil
+	})
+}
+
+// JWKS godoc
+// @Summary Get the token verification keys
+// @ID getJWKS
+// @Description Returns the public keys verifying access tokens as a JSON Web Key Set (RFC 7517), tokens name their key
+// in the kid header. Other services and API gateways can verify tokens offline with them. The set is empty when tokens
+// are signed with HS256. Served outside the API base path at /.well-known/jwks.json, no authentication required.
+// @Tags meta
+// @Produce json
+// @Success 200 {object} access.JWKSet "Public keys."
+// @x-outside-base true
+// @Router /.well-known/jwks.json [get]
+func JWKS(log *slog.Logger) http.HandlerFunc {
+	const op = "http-server.handlers.meta.JWKS"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		return access.JWKS(), nil
+	})
+}
+
+// OpenAPI godoc
+// @Summary Get the API spec
+// @ID getOpenAPI
+// @Description Returns the Swagger 2.0 (OpenAPI 2) spec of this API version as the running build serves it: the documented
+// operations of the registered routes, registered routes without docs tagged undocumented, and info.version and
+// info.x-build-commit naming the build. Each operation's security, x-auth, x-rate-limit, deprecation and x-writes come from
+// the route declarations. Generate clients from it to match the deployment. No authentication required.
+// Summaries and descriptions are in the language of lang or else of the Accept-Language header, English (en) or Russian (ru),
+// operations without a translation stay in English.
+// @Tags meta
+// @Produce json
+// @Param lang query string false "Language of the descriptions" Enums(en, ru)
+// @Success 200 {object} object "The spec."
+// @Router /openapi.json [get]
+func OpenAPI(log *slog.Logger, mux chi.Routes, declared func() []routes.Route, base string) http.HandlerFunc {
+	const op = "http-server.handlers.meta.OpenAPI"
+
+	// Built in every language on the first request, once every route is registered
+	build := sync.OnceValues(func() (map[string][]byte, error) {
+		doc, err := swag.ReadDoc()
+		if err != nil {
+			return nil, err
+		}
+		data, err := spec.Build(doc, mux, declared(), base, m.Version, m.BuildCommit())
+		if err != nil {
+			return nil, err
+		}
+
+		specs := make(map[string][]byte)
+		for _, lang := range spec.Languages() {
+			if specs[lang], err = spec.Localize(data, lang); err != nil {
+				return nil, err
+			}
+		}
+		return specs, nil
+	})
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		specs, err := build()
+		if err != nil {
+			return nil, err
+		}
+		lang := spec.Language(r.URL.Query().Get("lang"), r.Header.Get("Accept-Language"))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Language", lang)
+		w.Header().Add("Vary", "Accept-Language")
+		w.Write(specs[lang])
+		return nil, nil
+	})
+}
@@ -0,0 +1,159 @@
+package oidc
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+
+	util "github.com/sabbatD/srest-api/internal/http-server/handleUtil"
+	"github.com/sabbatD/srest-api/internal/http-server/handlers/admin"
+	"github.com/sabbatD/srest-api/internal/lib/api/access"
+	"github.com/sabbatD/srest-api/internal/lib/logger/sl"
+	oc "github.com/sabbatD/srest-api/internal/lib/oidcConfig"
+	"github.com/sabbatD/srest-api/internal/password"
+)
+
+type ClientHandler interface {
+	CreateOIDCClient(ctx context.Context, actor int, c oc.ClientRequest, secretHash string) (oc.Client, error)
+	OIDCClients(ctx context.Context) ([]oc.Client, error)
+	DeleteOIDCClient(ctx context.Context, actor int, clientID string) error
+}
+
+// Clients godoc
+// @Summary Get OpenID Connect clients
+// @ID listOIDCClients
+// @Description Returns the registered OpenID Connect clients, latest first. Their secrets are never returned.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} oc.Client "Clients retrieved."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /admin/oidc/clients [get]
+func Clients(log *slog.Logger, Clients ClientHandler) http.HandlerFunc {
+	const op = "http-server.handlers.oidc.Clients"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		if err := admin.AdmCheck(r); err != nil {
+			return nil, err
+		}
+
+		return Clients.OIDCClients(r.Context())
+	})
+}
+
+// CreateClient godoc
+// @Summary Register an OpenID Connect client
+// @ID createOIDCClient
+// @Description Registers a client allowed to sign its users in with sAPI accounts, redirecting only to the given URIs.
+// The response holds the client secret, it is not shown again. The registration is recorded in the audit log.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param Client body oc.ClientRequest true "Client"
+// @Security BearerAuth
+// @Success 201 {object} oc.Client "Client registered, with its secret."
+// @Failure 400 {object} util.Problem "Invalid input."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /admin/oidc/clients [post]
+func CreateClient(log *slog.Logger, Clients ClientHandler) http.HandlerFunc {
+	const op = "http-server.handlers.oidc.CreateClient"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		if err := admin.AdmCheck(r); err != nil {
+			return nil, err
+		}
+		actor, err := contextUser(r)
+		if err != nil {
+			return nil, err
+		}
+
+		var req oc.ClientRequest
+		if err := util.DecodeJSON(r, &req); err != nil {
+			return nil, err
+		}
+		if err := util.Validate(req); err != nil {
+			return nil, err
+		}
+
+		secret, hash, err := password.NewToken()
+		if err != nil {
+			return nil, err
+		}
+
+		client, err := Clients.CreateOIDCClient(r.Context(), actor, req, hash)
+		if err != nil {
+			return nil, err
+		}
+		client.Secret = secret
+
+		log.Info("OIDC client registered", slog.String("client", client.ClientID))
+
+		render.Status(r, http.StatusCreated)
+		return client, nil
+	})
+}
+
+// DeleteClient godoc
+// @Summary Delete an OpenID Connect client
+// @ID deleteOIDCClient
+// @Description Deletes the client, its users can no longer sign in with it. Issued tokens stay valid until they expire.
+// The deletion is recorded in the audit log.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags admin
+// @Produce json
+// @Param id path string true "Client ID (UUID)"
+// @Security BearerAuth
+// @Success 200 {object} string "Client deleted."
+// @Failure 400 {object} util.Problem "Invalid ID."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 404 {object} util.Problem "Client not found."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /admin/oidc/clients/{id} [delete]
+func DeleteClient(log *slog.Logger, Clients ClientHandler) http.HandlerFunc {
+	const op = "http-server.handlers.oidc.DeleteClient"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		if err := admin.AdmCheck(r); err != nil {
+			return nil, err
+		}
+		actor, err := contextUser(r)
+		if err != nil {
+			return nil, err
+		}
+
+		clientID := chi.URLParam(r, "id")
+		if !util.IsUUID(clientID) {
+			return nil, util.NewError(http.StatusBadRequest, util.CodeInvalidID, "Invalid client ID")
+		}
+
+		if err := Clients.DeleteOIDCClient(r.Context(), actor, clientID); err != nil {
+			return nil, util.NotFound(err, "No such client")
+		}
+
+		log.Info("OIDC client deleted", slog.String("client", clientID))
+
+		return nil, nil
+	})
+}
+
+func contextUser(r *http.Request) (int, error) {
+	userContext, ok := r.Context().Value(access.CxtKey("userContext")).(access.UserContext)
+	if !ok {
+		return 0, util.NewError(http.StatusUnauthorized, util.CodeUnauthorized, "User context not found")
+	}
+	return userContext.UserId, nil
+}
@@ -0,0 +1,349 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	sdb "github.com/sabbatD/srest-api/internal/database"
+	util "github.com/sabbatD/srest-api/internal/http-server/handleUtil"
+	"github.com/sabbatD/srest-api/internal/lib/clock"
+	"github.com/sabbatD/srest-api/internal/lib/logger/sl"
+	oc "github.com/sabbatD/srest-api/internal/lib/oidcConfig"
+	"github.com/sabbatD/srest-api/internal/password"
+)
+
+type AppHandler interface {
+	AuthorizedApps(ctx context.Context, userID int) ([]oc.AuthorizedApp, error)
+	RevokeOIDCConsent(ctx context.Context, userID int, clientID string) error
+}
+
+// ConsentPrompt is what the consent screen shows: the client, the scopes it requests and those the user already
+// granted it
+type ConsentPrompt struct {
+	ClientID string   `json:"clientId"`
+	Name     string   `json:"name"`
+	Scopes   []string `json:"scopes"`
+	Granted  []string `json:"granted"`
+}
+
+// ConsentResult is where the consent screen sends the user: back to the client with a code or an error
+type ConsentResult struct {
+	RedirectTo string `json:"redirectTo"`
+}
+
+// authRequest is a validated authorization request
+type authRequest struct {
+	client      oc.Client
+	redirectURI string
+	state       string
+	nonce       string
+	challenge   string
+	scopes      []string
+}
+
+// parseRequest validates the authorization request q. An unknown client or redirect URI is an *util.HTTPError,
+// the other errors are OAuth errors to send back to the client's redirect URI, see redirectError.
+func parseRequest(ctx context.Context, store ProviderHandler, q url.Values) (authRequest, error) {
+	clientID := q.Get("client_id")
+	if !util.IsUUID(clientID) {
+		return authRequest{}, util.NewError(http.StatusBadRequest, util.CodeInvalidInput, "Missing or unknown client_id")
+	}
+	client, err := store.OIDCClient(ctx, clientID)
+	if errors.Is(err, sdb.ErrNotFound) {
+		return authRequest{}, util.WrapError(err, http.StatusBadRequest, util.CodeInvalidInput, "Missing or unknown client_id")
+	}
+	if err != nil {
+		return authRequest{}, err
+	}
+	redirectURI := q.Get("redirect_uri")
+	if !slices.Contains(client.RedirectURIs, redirectURI) {
+		return authRequest{}, util.NewError(http.StatusBadRequest, util.CodeInvalidInput, "redirect_uri is not registered for the client")
+	}
+
+	req := authRequest{
+		client:      client,
+		redirectURI: redirectURI,
+		state:       q.Get("state"),
+		nonce:       q.Get("nonce"),
+		challenge:   q.Get("code_challenge"),
+		scopes:      oc.ParseScope(q.Get("scope")),
+	}
+	if q.Get("response_type") != "code" {
+		return req, oauthError(http.StatusBadRequest, "unsupported_response_type", "only the code response type is supported")
+	}
+	if !slices.Contains(req.scopes, oc.ScopeOpenID) {
+		return req, oauthError(http.StatusBadRequest, "invalid_scope", "the openid scope is required")
+	}
+	if req.challenge != "" && q.Get("code_challenge_method") != "S256" {
+		return req, oauthError(http.StatusBadRequest, "invalid_request", "only the S256 code challenge method is supported")
+	}
+	return req, nil
+}
+
+// errorURL returns the client's redirect URI with the OAuth error of err, ok is false for other errors
+// or before the redirect URI is validated
+func (req authRequest) errorURL(r *http.Request, err error) (target string, ok bool, _ error) {
+	var te *tokenError
+	if req.redirectURI == "" || !errors.As(err, &te) {
+		return "", false, nil
+	}
+
+	sl.FromContext(r.Context()).Info("authorization refused", slog.String("client", req.client.ClientID), slog.String("error", te.code))
+
+	target, err = location(req.redirectURI, url.Values{"error": {te.code}, "error_description": {te.Message}, "state": {req.state}})
+	return target, true, err
+}
+
+// redirectError sends OAuth errors back to the client, other errors are returned to answer the request with
+func (req authRequest) redirectError(w http.ResponseWriter, r *http.Request, err error) error {
+	target, ok, lerr := req.errorURL(r, err)
+	if !ok {
+		return err
+	}
+	if lerr != nil {
+		return lerr
+	}
+	http.Redirect(w, r, target, http.StatusFound)
+	return nil
+}
+
+// issue saves a code granting the client scopes of the user and returns the client's redirect URI with it
+func (p *Provider) issue(ctx context.Context, req authRequest, userID int, scopes []string) (string, error) {
+	code, hash, err := password.NewToken()
+	if err != nil {
+		return "", err
+	}
+	grant := oc.Grant{
+		ClientID:      req.client.ID,
+		UserID:        userID,
+		RedirectURI:   req.redirectURI,
+		Scope:         strings.Join(scopes, " "),
+		Nonce:         req.nonce,
+		CodeChallenge: req.challenge,
+	}
+	if err := p.Store.SaveOIDCCode(ctx, hash, grant, clock.Now().Add(p.CodeTTL)); err != nil {
+		return "", err
+	}
+
+	return location(req.redirectURI, url.Values{"code": {code}, "state": {req.state}})
+}
+
+// covers tells granted includes every scope of scopes
+func covers(granted, scopes []string) bool {
+	for _, s := range scopes {
+		if !slices.Contains(granted, s) {
+			return false
+		}
+	}
+	return true
+}
+
+// Consent godoc
+// @Summary Get the consent prompt of an OpenID Connect authorization request
+// @ID getOIDCConsent
+// @Description Returns the client of the authorization request, the scopes it requests and those the user already granted it, for the consent screen.
+// The query is the authorization request the consent screen was sent with in request.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags oidc
+// @Produce json
+// @Param client_id query string true "Client ID"
+// @Param redirect_uri query string true "A registered redirect URI of the client"
+// @Param scope query string true "Space separated scopes, openid is required"
+// @Security BearerAuth
+// @Success 200 {object} ConsentPrompt "Consent prompt."
+// @Failure 400 {object} util.Problem "Invalid authorization request."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 500 {object} util.Problem "Internal error."
+// @Router /oidc/consent [get]
+func Consent(log *slog.Logger, p *Provider) http.HandlerFunc {
+	const op = "http-server.handlers.oidc.Consent"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		userID, err := contextUser(r)
+		if err != nil {
+			return nil, err
+		}
+
+		req, err := parseRequest(r.Context(), p.Store, r.URL.Query())
+		var te *tokenError
+		if errors.As(err, &te) {
+			return nil, util.WrapError(err, http.StatusBadRequest, util.CodeInvalidInput, "Invalid authorization request: "+te.Message)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		granted, err := p.Store.OIDCConsent(r.Context(), userID, req.client.ClientID)
+		if err != nil {
+			return nil, err
+		}
+		if granted == nil {
+			granted = []string{}
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		return ConsentPrompt{ClientID: req.client.ClientID, Name: req.client.Name, Scopes: req.scopes, Granted: granted}, nil
+	})
+}
+
+// Decide godoc
+// @Summary Grant or deny an OpenID Connect authorization request
+// @ID decideOIDCConsent
+// @Description Records the scopes the user grants the client of the authorization request and returns where to send the user: back to the client with a code or, when denied, with access_denied.
+// Scopes the client requested but the user does not grant are left out of the code, openid has to be granted.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags oidc
+// @Accept json
+// @Produce json
+// @Param Decision body oc.ConsentDecision true "Consent decision"
+// @Security BearerAuth
+// @Success 200 {object} ConsentResult "Where to send the user."
+// @Failure 400 {object} util.Problem "Invalid authorization request or openid not granted."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 500 {object} util.Problem "Internal error."
+// @Router /oidc/consent [post]
+func Decide(log *slog.Logger, p *Provider) http.HandlerFunc {
+	const op = "http-server.handlers.oidc.Decide"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		userID, err := contextUser(r)
+		if err != nil {
+			return nil, err
+		}
+
+		var d oc.ConsentDecision
+		if err := util.DecodeJSON(r, &d); err != nil {
+			return nil, err
+		}
+		if err := util.Validate(d); err != nil {
+			return nil, err
+		}
+		q, err := url.ParseQuery(d.Request)
+		if err != nil {
+			return nil, util.WrapError(err, http.StatusBadRequest, util.CodeInvalidInput, "Invalid input: malformed request")
+		}
+
+		req, err := parseRequest(r.Context(), p.Store, q)
+		if d.Deny && err == nil {
+			err = oauthError(http.StatusBadRequest, "access_denied", "the user denied the request")
+		}
+		if err != nil {
+			target, ok, lerr := req.errorURL(r, err)
+			if !ok {
+				return nil, err
+			}
+			if lerr != nil {
+				return nil, lerr
+			}
+			return ConsentResult{RedirectTo: target}, nil
+		}
+
+		// The grant keeps the scopes granted before and not asked for again
+		var scopes []string
+		for _, s := range req.scopes {
+			if slices.Contains(d.Scopes, s) {
+				scopes = append(scopes, s)
+			}
+		}
+		if !slices.Contains(scopes, oc.ScopeOpenID) {
+			return nil, util.NewError(http.StatusBadRequest, util.CodeInvalidInput, "Invalid input: the openid scope has to be granted")
+		}
+		previous, err := p.Store.OIDCConsent(r.Context(), userID, req.client.ClientID)
+		if err != nil {
+			return nil, err
+		}
+		var granted []string
+		for _, s := range oc.Scopes {
+			if slices.Contains(scopes, s) || slices.Contains(previous, s) && !slices.Contains(req.scopes, s) {
+				granted = append(granted, s)
+			}
+		}
+		if err := p.Store.SaveOIDCConsent(r.Context(), userID, req.client.ID, granted); err != nil {
+			return nil, err
+		}
+
+		target, err := p.issue(r.Context(), req, userID, scopes)
+		if err != nil {
+			return nil, err
+		}
+
+		log.Info("client authorized", slog.String("client", req.client.ClientID), slog.Int("user_id", userID),
+			slog.String("scope", strings.Join(scopes, " ")))
+
+		return ConsentResult{RedirectTo: target}, nil
+	})
+}
+
+// AuthorizedApps godoc
+// @Summary List authorized apps
+// @ID listAuthorizedApps
+// @Description Returns the OpenID Connect clients the user granted scopes to on the consent screen, the last granted first.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags user
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} oc.AuthorizedApp "Authorized apps."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 500 {object} util.Problem "Internal error."
+// @Router /user/authorized-apps [get]
+func AuthorizedApps(log *slog.Logger, Apps AppHandler) http.HandlerFunc {
+	const op = "http-server.handlers.oidc.AuthorizedApps"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		userID, err := contextUser(r)
+		if err != nil {
+			return nil, err
+		}
+
+		return Apps.AuthorizedApps(r.Context(), userID)
+	})
+}
+
+// RevokeApp godoc
+// @Summary Revoke an authorized app
+// @ID revokeAuthorizedApp
+// @Description Revokes the scopes the user granted the client, its tokens of the user are refused from now on and it has to ask for consent again.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags user
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Client ID (UUID)"
+// @Success 200 {object} string "App revoked."
+// @Failure 400 {object} util.Problem "Invalid client ID."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 404 {object} util.Problem "No such authorized app."
+// @Failure 500 {object} util.Problem "Internal error."
+// @Router /user/authorized-apps/{id} [delete]
+func RevokeApp(log *slog.Logger, Apps AppHandler) http.HandlerFunc {
+	const op = "http-server.handlers.oidc.RevokeApp"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		userID, err := contextUser(r)
+		if err != nil {
+			return nil, err
+		}
+		clientID := chi.URLParam(r, "id")
+		if !util.IsUUID(clientID) {
+			return nil, util.NewError(http.StatusBadRequest, util.CodeInvalidID, "Missing or wrong client id")
+		}
+
+		if err := Apps.RevokeOIDCConsent(r.Context(), userID, clientID); err != nil {
+			return nil, util.NotFound(err, "No such authorized app")
+		}
+
+		log.Info("app revoked", slog.String("client", clientID))
+
+		return nil, nil
+	})
+}
@@ -0,0 +1,465 @@
+// Package oidc makes sAPI an OpenID Connect provider for other tools (authorization code flow): admins register
+// the clients, users signed in to sAPI authorize them and the clients get ID tokens of the users' accounts.
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	sdb "github.com/sabbatD/srest-api/internal/database"
+	util "github.com/sabbatD/srest-api/internal/http-server/handleUtil"
+	"github.com/sabbatD/srest-api/internal/lib/api/access"
+	"github.com/sabbatD/srest-api/internal/lib/clock"
+	"github.com/sabbatD/srest-api/internal/lib/logger/sl"
+	oc "github.com/sabbatD/srest-api/internal/lib/oidcConfig"
+	u "github.com/sabbatD/srest-api/internal/lib/userConfig"
+	"github.com/sabbatD/srest-api/internal/password"
+)
+
+type ProviderHandler interface {
+	OIDCClient(ctx context.Context, clientID string) (oc.Client, error)
+	SaveOIDCCode(ctx context.Context, codeHash string, g oc.Grant, expires time.Time) error
+	UseOIDCCode(ctx context.Context, codeHash string) (oc.Grant, error)
+	Get(ctx context.Context, id int) (u.TableUser, error)
+	OIDCConsent(ctx context.Context, userID int, clientID string) ([]string, error)
+	SaveOIDCConsent(ctx context.Context, userID, clientID int, scopes []string) error
+}
+
+// Provider issues the codes and tokens of Issuer, its endpoints are under API, the base URL of the API.
+// ID tokens are signed with the JWT signing key of Algorithm, codes are valid for CodeTTL.
+// Signed out users are sent to LoginURL with the authorization request to return to in return_to,
+// users who have not granted the requested scopes to ConsentURL with the authorization request in request.
+type Provider struct {
+	Store      ProviderHandler
+	Issuer     string
+	API        string
+	Algorithm  string
+	LoginURL   string
+	ConsentURL string
+	CodeTTL    time.Duration
+}
+
+// Discovery is the OpenID Provider Metadata (OpenID Connect Discovery 1.0, section 3)
+type Discovery struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserinfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
+
+// TokenResponse is the answer of the token endpoint, the access token is only accepted at the userinfo endpoint
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+	IDToken     string `json:"id_token"`
+	Scope       string `json:"scope"`
+}
+
+// UserInfo holds the claims about the user: sub always, preferred_username with the profile scope,
+// email and email_verified with the email scope
+type UserInfo struct {
+	Subject           string `json:"sub"`
+	PreferredUsername string `json:"preferred_username,omitempty"`
+	Email             string `json:"email,omitempty"`
+	EmailVerified     *bool  `json:"email_verified,omitempty"`
+}
+
+// TokenError is an OAuth 2.0 error response (RFC 6749, section 5.2)
+type TokenError struct {
+	Error       string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+// Discover godoc
+// @Summary Get the OpenID Connect provider metadata
+// @ID getOpenIDConfiguration
+// @Description Returns the issuer, endpoints, scopes, flows and signing algorithm of sAPI as an OpenID Connect provider.
+// Served outside the API base path at /.well-known/openid-configuration.
+// @Tags oidc
+// @Produce json
+// @Success 200 {object} Discovery "Provider metadata."
+// @x-outside-base true
+// @Router /.well-known/openid-configuration [get]
+func Discover(log *slog.Logger, p *Provider) http.HandlerFunc {
+	const op = "http-server.handlers.oidc.Discover"
+
+	d := Discovery{
+		Issuer:                            p.Issuer,
+		AuthorizationEndpoint:             p.API + "/oidc/authorize",
+		TokenEndpoint:                     p.API + "/oidc/token",
+		UserinfoEndpoint:                  p.API + "/oidc/userinfo",
+		JWKSURI:                           p.Issuer + "/.well-known/jwks.json",
+		ScopesSupported:                   oc.Scopes,
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{"authorization_code"},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{p.Algorithm},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post"},
+		CodeChallengeMethodsSupported:     []string{"S256"},
+		ClaimsSupported:                   []string{"sub", "iss", "aud", "exp", "iat", "nonce", "preferred_username", "email", "email_verified"},
+	}
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+		return d, nil
+	})
+}
+
+// Authorize godoc
+// @Summary Authorize an OpenID Connect client
+// @ID oidcAuthorize
+// @Description Redirects the signed in user back to the client's redirect_uri with a single-use code or an OAuth error.
+// The user signs in to sAPI with the access token, in the Authorization header or the cookie transport.
+// Signed out users are sent to the login page with the request to return to, or get login_required back without one
+// or with prompt=none. Users who have not granted the client the requested scopes are sent to the consent screen
+// with the request, or get consent_required back without one or with prompt=none. PKCE with S256 is supported. Unknown clients and redirect URIs that are not registered
+// are answered with 400 instead of a redirect.
+// @Tags oidc
+// @Produce json
+// @Param response_type query string true "code" Enums(code)
+// @Param client_id query string true "Client ID"
+// @Param redirect_uri query string true "A registered redirect URI of the client"
+// @Param scope query string true "Space separated scopes, openid is required"
+// @Param state query string false "Returned to the client as it is"
+// @Param nonce query string false "Set in the ID token"
+// @Param code_challenge query string false "PKCE code challenge"
+// @Param code_challenge_method query string false "PKCE method" Enums(S256)
+// @Param prompt query string false "none to fail instead of sending the user to sign in or consent, consent to ask for consent again" Enums(none, consent)
+// @Success 302 {string} string "Redirect to the client with a code or an error."
+// @Failure 400 {object} util.Problem "Unknown client or redirect URI."
+// @Failure 500 {object} util.Problem "Internal error."
+// @Router /oidc/authorize [get]
+func Authorize(log *slog.Logger, p *Provider) http.HandlerFunc {
+	const op = "http-server.handlers.oidc.Authorize"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+		q := r.URL.Query()
+
+		req, err := parseRequest(r.Context(), p.Store, q)
+		if err != nil {
+			return nil, req.redirectError(w, r, err)
+		}
+
+		// From here on errors go back to the client
+		fail := func(code, desc string) (any, error) {
+			return nil, req.redirectError(w, r, oauthError(http.StatusBadRequest, code, desc))
+		}
+
+		user, ok := access.TokenUser(r)
+		if !ok || user.IsGuest {
+			if q.Get("prompt") == "none" || p.LoginURL == "" {
+				return fail("login_required", "the user is not signed in")
+			}
+			return nil, redirect(w, r, p.LoginURL, url.Values{"return_to": {p.API + "/oidc/authorize?" + r.URL.RawQuery}})
+		}
+
+		// Scopes the user has not granted the client yet are asked for on the consent screen
+		granted, err := p.Store.OIDCConsent(r.Context(), user.UserId, req.client.ClientID)
+		if err != nil {
+			return nil, err
+		}
+		if q.Get("prompt") == "consent" || !covers(granted, req.scopes) {
+			if q.Get("prompt") == "none" || p.ConsentURL == "" {
+				return fail("consent_required", "the user has not granted the requested scopes")
+			}
+			return nil, redirect(w, r, p.ConsentURL, url.Values{"request": {r.URL.RawQuery}})
+		}
+
+		target, err := p.issue(r.Context(), req, user.UserId, req.scopes)
+		if err != nil {
+			return nil, err
+		}
+
+		log.Info("client authorized", slog.String("client", req.client.ClientID), slog.Int("user_id", user.UserId))
+
+		http.Redirect(w, r, target, http.StatusFound)
+		return nil, nil
+	})
+}
+
+// Token godoc
+// @Summary Redeem an OpenID Connect authorization code
+// @ID oidcToken
+// @Description Exchanges an authorization code for an ID token and an access token of the userinfo endpoint.
+// The client authenticates with HTTP Basic or client_id and client_secret in the form, the redirect_uri has to be
+// the one the code was sent to and with PKCE the code_verifier has to match.
+// Errors are OAuth 2.0 error responses (RFC 6749, 5.2).
+// @Tags oidc
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param grant_type formData string true "authorization_code" Enums(authorization_code)
+// @Param code formData string true "Authorization code"
+// @Param redirect_uri formData string true "Redirect URI the code was sent to"
+// @Param client_id formData string false "Client ID, unless sent with HTTP Basic"
+// @Param client_secret formData string false "Client secret, unless sent with HTTP Basic"
+// @Param code_verifier formData string false "PKCE code verifier"
+// @Success 200 {object} TokenResponse "Tokens issued."
+// @Failure 400 {object} TokenError "Invalid request or grant."
+// @Failure 401 {object} TokenError "Invalid client credentials."
+// @Failure 500 {object} TokenError "Internal error."
+// @Router /oidc/token [post]
+func Token(log *slog.Logger, p *Provider) http.HandlerFunc {
+	const op = "http-server.handlers.oidc.Token"
+
+	return util.HandleWith(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		if err := r.ParseForm(); err != nil {
+			return nil, oauthError(http.StatusBadRequest, "invalid_request", "malformed form body")
+		}
+		if r.PostForm.Get("grant_type") != "authorization_code" {
+			return nil, oauthError(http.StatusBadRequest, "unsupported_grant_type", "only the authorization_code grant is supported")
+		}
+
+		client, err := authenticate(r, p.Store)
+		if err != nil {
+			return nil, err
+		}
+
+		grant, err := p.Store.UseOIDCCode(r.Context(), password.HashToken(r.PostForm.Get("code")))
+		if errors.Is(err, sdb.ErrNotFound) {
+			return nil, oauthError(http.StatusBadRequest, "invalid_grant", "unknown, used or expired code")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if grant.ClientID != client.ID || grant.RedirectURI != r.PostForm.Get("redirect_uri") {
+			return nil, oauthError(http.StatusBadRequest, "invalid_grant", "the code was not issued to this client and redirect_uri")
+		}
+		if grant.CodeChallenge != "" && !verifyChallenge(grant.CodeChallenge, r.PostForm.Get("code_verifier")) {
+			return nil, oauthError(http.StatusBadRequest, "invalid_grant", "code_verifier does not match the code challenge")
+		}
+
+		user, err := p.Store.Get(r.Context(), grant.UserID)
+		if errors.Is(err, sdb.ErrNotFound) || err == nil && user.IsBlocked {
+			return nil, oauthError(http.StatusBadRequest, "invalid_grant", "the user is no longer active")
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		accessToken, expires, err := access.NewOIDCAccessToken(user.ID, client.ClientID, grant.Scope)
+		if err != nil {
+			return nil, err
+		}
+
+		now := clock.Now()
+		claims := map[string]any{
+			"iss": p.Issuer,
+			"aud": client.ClientID,
+			"iat": now.Unix(),
+			"exp": expires.Unix(),
+		}
+		info := userInfo(user, grant.Scope)
+		data, _ := json.Marshal(info)
+		json.Unmarshal(data, &claims)
+		if grant.Nonce != "" {
+			claims["nonce"] = grant.Nonce
+		}
+		idToken, err := access.SignIDToken(claims)
+		if err != nil {
+			return nil, err
+		}
+
+		log.Info("ID token issued", slog.String("client", client.ClientID), slog.Int("user_id", user.ID))
+
+		w.Header().Set("Cache-Control", "no-store")
+		return TokenResponse{
+			AccessToken: accessToken,
+			TokenType:   "Bearer",
+			ExpiresIn:   int(expires.Sub(now).Seconds()),
+			IDToken:     idToken,
+			Scope:       grant.Scope,
+		}, nil
+	}, writeTokenError)
+}
+
+type ctxKey struct{}
+
+// Auth admits requests bearing an access token issued to an OpenID Connect client by Token.
+// Other API tokens are refused, as the API refuses the client tokens.
+func Auth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := access.OIDCToken(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			util.WriteError(w, r, util.NewError(http.StatusUnauthorized, util.CodeUnauthorized, "Missing or invalid access token"))
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxKey{}, token)))
+	})
+}
+
+// UserInfo godoc
+// @Summary Get claims about the user of an OpenID Connect access token
+// @ID oidcUserInfo
+// @Description Returns the claims about the user of the access token by its scopes: sub always, preferred_username with profile, email and email_verified with email.
+// Other API tokens are refused, as are the tokens of apps the user revoked.
+// @Tags oidc
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} UserInfo "Claims about the user."
+// @Failure 401 {object} util.Problem "Missing or invalid access token."
+// @Failure 500 {object} util.Problem "Internal error."
+// @Router /oidc/userinfo [get]
+func GetUserInfo(log *slog.Logger, p *Provider) http.HandlerFunc {
+	const op = "http-server.handlers.oidc.GetUserInfo"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		token, ok := r.Context().Value(ctxKey{}).(access.ClientToken)
+		if !ok {
+			return nil, util.NewError(http.StatusUnauthorized, util.CodeUnauthorized, "Missing or invalid access token")
+		}
+
+		user, err := p.Store.Get(r.Context(), token.UserId)
+		if errors.Is(err, sdb.ErrNotFound) || err == nil && user.IsBlocked {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			return nil, util.NewError(http.StatusUnauthorized, util.CodeUnauthorized, "The user is no longer active")
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// Revoking the app on /user/authorized-apps ends its access before the token expires
+		granted, err := p.Store.OIDCConsent(r.Context(), token.UserId, token.ClientID)
+		if err != nil {
+			return nil, err
+		}
+		if !covers(granted, strings.Fields(token.Scope)) {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			return nil, util.NewError(http.StatusUnauthorized, util.CodeUnauthorized, "The user revoked the access of the client")
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		return userInfo(user, token.Scope), nil
+	})
+}
+
+// userInfo returns the claims about user the space separated scope grants
+func userInfo(user u.TableUser, scope string) UserInfo {
+	scopes := strings.Fields(scope)
+
+	info := UserInfo{Subject: user.PublicID}
+	if slices.Contains(scopes, oc.ScopeProfile) {
+		info.PreferredUsername = user.Username
+	}
+	if slices.Contains(scopes, oc.ScopeEmail) && user.Email != "" {
+		info.Email = user.Email
+		info.EmailVerified = &user.IsVerified
+	}
+	return info
+}
+
+// authenticate returns the client of the HTTP Basic or form credentials of r
+func authenticate(r *http.Request, store ProviderHandler) (oc.Client, error) {
+	id, secret, ok := r.BasicAuth()
+	if ok {
+		// The credentials are form-urlencoded before they are put in the header (RFC 6749, 2.3.1)
+		id, _ = url.QueryUnescape(id)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		id, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+
+	invalid := oauthError(http.StatusUnauthorized, "invalid_client", "unknown client or wrong secret")
+	if !util.IsUUID(id) || secret == "" {
+		return oc.Client{}, invalid
+	}
+	client, err := store.OIDCClient(r.Context(), id)
+	if errors.Is(err, sdb.ErrNotFound) {
+		return oc.Client{}, invalid
+	}
+	if err != nil {
+		return oc.Client{}, err
+	}
+	if subtle.ConstantTimeCompare([]byte(password.HashToken(secret)), []byte(client.SecretHash)) != 1 {
+		return oc.Client{}, invalid
+	}
+	return client, nil
+}
+
+// verifyChallenge checks the PKCE verifier against its S256 challenge (RFC 7636, 4.6)
+func verifyChallenge(challenge, verifier string) bool {
+	sum := sha256.Sum256([]byte(verifier))
+	return subtle.ConstantTimeCompare([]byte(base64.RawURLEncoding.EncodeToString(sum[:])), []byte(challenge)) == 1
+}
+
+// location returns uri with params added to its query, empty params are left out
+func location(uri string, params url.Values) (string, error) {
+	target, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+
+	q := target.Query()
+	for k, v := range params {
+		if v[0] != "" {
+			q.Set(k, v[0])
+		}
+	}
+	target.RawQuery = q.Encode()
+
+	return target.String(), nil
+}
+
+// redirect sends the client to uri with params added to its query, see location
+func redirect(w http.ResponseWriter, r *http.Request, uri string, params url.Values) error {
+	target, err := location(uri, params)
+	if err != nil {
+		return err
+	}
+	http.Redirect(w, r, target, http.StatusFound)
+	return nil
+}
+
+// tokenError carries the OAuth error code of the response along with it
+type tokenError struct {
+	*util.HTTPError
+	code string
+}
+
+func (e *tokenError) Unwrap() error { return e.HTTPError }
+
+func oauthError(status int, code, desc string) error {
+	return &tokenError{HTTPError: util.NewError(status, util.CodeBadRequest, desc), code: code}
+}
+
+func writeTokenError(w http.ResponseWriter, r *http.Request, err error) {
+	e := util.LogError(r, err)
+
+	body := TokenError{Error: "server_error"}
+	var te *tokenError
+	if errors.As(err, &te) {
+		body = TokenError{Error: te.code, Description: te.Message}
+	}
+	if e.Status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Basic realm="oidc"`)
+	}
+
+	data, _ := json.Marshal(body)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(e.Status)
+	w.Write(data)
+}
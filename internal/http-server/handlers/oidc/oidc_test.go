@@ -0,0 +1,309 @@
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/go-chi/chi/v5"
+	sdb "github.com/sabbatD/srest-api/internal/database"
+	"github.com/sabbatD/srest-api/internal/lib/api/access"
+	oc "github.com/sabbatD/srest-api/internal/lib/oidcConfig"
+	u "github.com/sabbatD/srest-api/internal/lib/userConfig"
+	"github.com/sabbatD/srest-api/internal/password"
+)
+
+type memProvider struct {
+	client oc.Client
+	user   u.TableUser
+	codes  map[string]oc.Grant
+	// granted is the user's consent to the client
+	granted []string
+}
+
+func (m *memProvider) OIDCClient(ctx context.Context, clientID string) (oc.Client, error) {
+	if clientID != m.client.ClientID {
+		return oc.Client{}, sdb.ErrNotFound
+	}
+	return m.client, nil
+}
+
+func (m *memProvider) SaveOIDCCode(ctx context.Context, codeHash string, g oc.Grant, expires time.Time) error {
+	m.codes[codeHash] = g
+	return nil
+}
+
+func (m *memProvider) UseOIDCCode(ctx context.Context, codeHash string) (oc.Grant, error) {
+	g, ok := m.codes[codeHash]
+	if !ok {
+		return oc.Grant{}, sdb.ErrNotFound
+	}
+	delete(m.codes, codeHash)
+	return g, nil
+}
+
+func (m *memProvider) Get(ctx context.Context, id int) (u.TableUser, error) {
+	if id != m.user.ID {
+		return u.TableUser{}, sdb.ErrNotFound
+	}
+	return m.user, nil
+}
+
+func (m *memProvider) OIDCConsent(ctx context.Context, userID int, clientID string) ([]string, error) {
+	return m.granted, nil
+}
+
+func (m *memProvider) SaveOIDCConsent(ctx context.Context, userID, clientID int, scopes []string) error {
+	m.granted = scopes
+	return nil
+}
+
+func (m *memProvider) AuthorizedApps(ctx context.Context, userID int) ([]oc.AuthorizedApp, error) {
+	if m.granted == nil {
+		return []oc.AuthorizedApp{}, nil
+	}
+	return []oc.AuthorizedApp{{ClientID: m.client.ClientID, Name: m.client.Name, Scopes: m.granted}}, nil
+}
+
+func (m *memProvider) RevokeOIDCConsent(ctx context.Context, userID int, clientID string) error {
+	if m.granted == nil || clientID != m.client.ClientID {
+		return sdb.ErrNotFound
+	}
+	m.granted = nil
+	return nil
+}
+
+// signedIn returns r as the user of JWTAuthMiddleware
+func signedIn(r *http.Request, id int) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), access.CxtKey("userContext"), access.UserContext{UserId: id}))
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	key, err := access.GenerateKey(access.ES256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	access.SetKey(key)
+
+	const (
+		clientID = "5f0c6f4e-7d1a-4c59-9a57-0f3e2b8c1d2a"
+		callback = "https://wiki.example.com/callback"
+	)
+	store := &memProvider{
+		client: oc.Client{ID: 1, ClientID: clientID, RedirectURIs: []string{callback}, SecretHash: password.HashToken("s3cret")},
+		user:   u.TableUser{ID: 7, PublicID: "0b9a3c1e-2f4d-4e6a-8b7c-9d0e1f2a3b4c", Username: "alice", Email: "alice@example.com", IsVerified: true},
+		codes:  map[string]oc.Grant{},
+	}
+	p := &Provider{
+		Store:      store,
+		Issuer:     "https://sapi.example.com",
+		API:        "https://sapi.example.com/api/v1",
+		Algorithm:  access.ES256,
+		LoginURL:   "https://sapi.example.com/login",
+		ConsentURL: "https://sapi.example.com/consent",
+		CodeTTL:    time.Minute,
+	}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	verifier := "a-code-verifier-long-enough-to-be-a-real-one-0123456789"
+	sum := sha256.Sum256([]byte(verifier))
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {clientID},
+		"redirect_uri":          {callback},
+		"scope":                 {"openid email profile"},
+		"state":                 {"xyz"},
+		"nonce":                 {"n-0S6"},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(sum[:])},
+		"code_challenge_method": {"S256"},
+	}
+
+	authorize := func(q url.Values, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/oidc/authorize?"+q.Encode(), nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		Authorize(log, p).ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Signed out users are sent to sign in and back
+	rec := authorize(query, "")
+	if loc := rec.Header().Get("Location"); rec.Code != http.StatusFound || !strings.HasPrefix(loc, p.LoginURL+"?return_to=") {
+		t.Fatalf("signed out: status %d, Location %q", rec.Code, loc)
+	}
+
+	bad := url.Values{}
+	for k, v := range query {
+		bad[k] = v
+	}
+	bad.Set("redirect_uri", "https://evil.example.com/callback")
+	if rec := authorize(bad, ""); rec.Code != http.StatusBadRequest || rec.Header().Get("Location") != "" {
+		t.Errorf("unregistered redirect_uri: status %d, Location %q", rec.Code, rec.Header().Get("Location"))
+	}
+
+	userToken, err := access.NewAccessToken(7, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The scopes are granted on the consent screen, the user leaves out profile
+	rec = authorize(query, userToken)
+	loc, err := url.Parse(rec.Header().Get("Location"))
+	if rec.Code != http.StatusFound || err != nil || !strings.HasPrefix(loc.String(), p.ConsentURL) {
+		t.Fatalf("authorize without consent: status %d, Location %q", rec.Code, rec.Header().Get("Location"))
+	}
+	request := loc.Query().Get("request")
+
+	rec = httptest.NewRecorder()
+	Consent(log, p).ServeHTTP(rec, signedIn(httptest.NewRequest(http.MethodGet, "/oidc/consent?"+request, nil), 7))
+	var prompt ConsentPrompt
+	if err := json.Unmarshal(rec.Body.Bytes(), &prompt); rec.Code != http.StatusOK || err != nil {
+		t.Fatalf("consent prompt: status %d, body %s", rec.Code, rec.Body)
+	}
+	if prompt.ClientID != clientID || strings.Join(prompt.Scopes, " ") != "openid profile email" || len(prompt.Granted) != 0 {
+		t.Errorf("consent prompt = %+v", prompt)
+	}
+
+	decide := func(body string) ConsentResult {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		Decide(log, p).ServeHTTP(rec, signedIn(httptest.NewRequest(http.MethodPost, "/oidc/consent", strings.NewReader(body)), 7))
+		var res ConsentResult
+		if err := json.Unmarshal(rec.Body.Bytes(), &res); rec.Code != http.StatusOK || err != nil {
+			t.Fatalf("consent: status %d, body %s", rec.Code, rec.Body)
+		}
+		return res
+	}
+	encoded, _ := json.Marshal(request)
+	if res := decide(`{"request": ` + string(encoded) + `, "deny": true}`); !strings.Contains(res.RedirectTo, "error=access_denied") {
+		t.Errorf("denied: redirect to %s", res.RedirectTo)
+	}
+	loc, err = url.Parse(decide(`{"request": ` + string(encoded) + `, "scopes": ["openid", "email"]}`).RedirectTo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := loc.Query().Get("code")
+	if !strings.HasPrefix(loc.String(), callback) || loc.Query().Get("state") != "xyz" || code == "" {
+		t.Fatalf("redirect = %s", loc)
+	}
+	if strings.Join(store.granted, " ") != "openid email" {
+		t.Errorf("granted %v", store.granted)
+	}
+
+	// Granted scopes need no consent again, others do
+	granted := url.Values{}
+	for k, v := range query {
+		granted[k] = v
+	}
+	granted.Set("scope", "openid email")
+	if rec := authorize(granted, userToken); !strings.HasPrefix(rec.Header().Get("Location"), callback+"?code=") {
+		t.Errorf("granted scopes: Location %q", rec.Header().Get("Location"))
+	}
+	query.Set("prompt", "none")
+	if rec := authorize(query, userToken); !strings.Contains(rec.Header().Get("Location"), "error=consent_required") {
+		t.Errorf("ungranted scope with prompt=none: Location %q", rec.Header().Get("Location"))
+	}
+
+	token := func(form url.Values, secret string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/oidc/token", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth(clientID, secret)
+		rec := httptest.NewRecorder()
+		Token(log, p).ServeHTTP(rec, req)
+		return rec
+	}
+	form := url.Values{"grant_type": {"authorization_code"}, "code": {code}, "redirect_uri": {callback}, "code_verifier": {verifier}}
+
+	if rec := token(form, "wrong"); rec.Code != http.StatusUnauthorized || !strings.Contains(rec.Body.String(), `"invalid_client"`) {
+		t.Errorf("wrong secret: status %d, body %s", rec.Code, rec.Body)
+	}
+
+	rec = token(form, "s3cret")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("token: status %d, body %s", rec.Code, rec.Body)
+	}
+	var tokens TokenResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &tokens); err != nil {
+		t.Fatal(err)
+	}
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(tokens.IDToken, claims, func(*jwt.Token) (any, error) { return key.PublicKey(), nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims["iss"] != p.Issuer || claims["aud"] != clientID || claims["sub"] != store.user.PublicID ||
+		claims["nonce"] != "n-0S6" || claims["email"] != "alice@example.com" || claims["preferred_username"] != nil {
+		t.Errorf("ID token claims = %v", claims)
+	}
+
+	// Codes are single-use
+	if rec := token(form, "s3cret"); rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), `"invalid_grant"`) {
+		t.Errorf("used code: status %d, body %s", rec.Code, rec.Body)
+	}
+
+	userinfo := func(bearer string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/oidc/userinfo", nil)
+		req.Header.Set("Authorization", "Bearer "+bearer)
+		rec := httptest.NewRecorder()
+		Auth(GetUserInfo(log, p)).ServeHTTP(rec, req)
+		return rec
+	}
+	rec = userinfo(tokens.AccessToken)
+	var info UserInfo
+	if err := json.Unmarshal(rec.Body.Bytes(), &info); rec.Code != http.StatusOK || err != nil {
+		t.Fatalf("userinfo: status %d, body %s", rec.Code, rec.Body)
+	}
+	if info.Subject != store.user.PublicID || info.Email != "alice@example.com" || info.EmailVerified == nil || !*info.EmailVerified {
+		t.Errorf("userinfo = %+v", info)
+	}
+
+	// Revoked apps lose access at once
+	rec = httptest.NewRecorder()
+	apps := httptest.NewRequest(http.MethodGet, "/user/authorized-apps", nil)
+	AuthorizedApps(log, store).ServeHTTP(rec, signedIn(apps, 7))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), clientID) {
+		t.Errorf("authorized apps: status %d, body %s", rec.Code, rec.Body)
+	}
+	for _, want := range []int{http.StatusOK, http.StatusNotFound} {
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", clientID)
+		revoke := httptest.NewRequest(http.MethodDelete, "/user/authorized-apps/"+clientID, nil)
+		revoke = revoke.WithContext(context.WithValue(revoke.Context(), chi.RouteCtxKey, rctx))
+		rec = httptest.NewRecorder()
+		RevokeApp(log, store).ServeHTTP(rec, signedIn(revoke, 7))
+		if rec.Code != want {
+			t.Errorf("revoke: status %d, want %d", rec.Code, want)
+		}
+	}
+	if rec := userinfo(tokens.AccessToken); rec.Code != http.StatusUnauthorized {
+		t.Errorf("userinfo of a revoked app: status %d, want 401", rec.Code)
+	}
+
+	// Client and API tokens do not mix
+	for name, bearer := range map[string]string{"API token": userToken, "ID token": tokens.IDToken} {
+		if rec := userinfo(bearer); rec.Code != http.StatusUnauthorized {
+			t.Errorf("userinfo with the %s: status %d, want 401", name, rec.Code)
+		}
+	}
+	api := access.JWTAuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	req := httptest.NewRequest(http.MethodGet, "/user/profile", nil)
+	req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+	rec = httptest.NewRecorder()
+	api.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("API with the client token: status %d, want 401", rec.Code)
+	}
+}
@@ -0,0 +1,233 @@
+// Package report provides handlers for abuse reports: users file them, admins review them.
+package report
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/render"
+
+	sdb "github.com/sabbatD/srest-api/internal/database"
+	util "github.com/sabbatD/srest-api/internal/http-server/handleUtil"
+	"github.com/sabbatD/srest-api/internal/http-server/handlers/admin"
+	"github.com/sabbatD/srest-api/internal/lib/api/access"
+	"github.com/sabbatD/srest-api/internal/lib/logger/sl"
+	rc "github.com/sabbatD/srest-api/internal/lib/reportConfig"
+)
+
+type ReportHandler interface {
+	CreateReport(ctx context.Context, reporter int, r rc.ReportRequest, target int) (rc.Report, error)
+	Reports(ctx context.Context, status string, limit, offset int) (rc.MetaResponse, error)
+	ReportID(ctx context.Context, publicID string) (int, error)
+	ReviewReport(ctx context.Context, id, actor int, status, resolution string) (rc.Report, error)
+	UserID(ctx context.Context, publicID string) (int, error)
+}
+
+// Create godoc
+// @Summary Report abuse
+// @ID reportAbuse
+// @Description Files a report against another user. Reasons are 'spam', 'abuse', 'impersonation' or 'other'.
+// A user can have one open report per target.
+// The user must be authenticated and provide a valid JWT token.
+// @Tags reports
+// @Accept json
+// @Produce json
+// @Param Report body rc.ReportRequest true "Reported target and reason"
+// @Security BearerAuth
+// @Success 201 {object} rc.Report "Report filed."
+// @Failure 400 {object} util.Problem "Invalid input or reporting yourself."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 404 {object} util.Problem "Reported user not found."
+// @Failure 409 {object} util.Problem "An open report for this target already exists."
+// @Failure 500 {object} util.Problem "Internal error."
+// @Router /reports [post]
+func Create(log *slog.Logger, Reports ReportHandler) http.HandlerFunc {
+	const op = "http-server.handlers.report.Create"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		userID, err := contextUser(r)
+		if err != nil {
+			return nil, err
+		}
+
+		var req rc.ReportRequest
+		if err := util.DecodeJSON(r, &req); err != nil {
+			return nil, err
+		}
+		if err := util.Validate(req); err != nil {
+			return nil, err
+		}
+
+		target, err := Reports.UserID(r.Context(), req.TargetID)
+		if err != nil {
+			return nil, util.NotFound(err, "No such user")
+		}
+		if target == userID {
+			return nil, util.NewError(http.StatusBadRequest, util.CodeInvalidInput, "Cannot report yourself")
+		}
+
+		report, err := Reports.CreateReport(r.Context(), userID, req, target)
+		if err != nil {
+			if errors.Is(err, sdb.ErrAlreadyExists) {
+				return nil, util.WrapError(err, http.StatusConflict, util.CodeConflict, "You already reported this")
+			}
+			return nil, err
+		}
+
+		log.Info("report filed", slog.String("report", report.PublicID), slog.String("reason", report.Reason))
+
+		render.Status(r, http.StatusCreated)
+		return report, nil
+	})
+}
+
+// All godoc
+// @Summary Get abuse reports
+// @ID listReports
+// @Description Returns the review queue, oldest reports first.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags admin
+// @Produce json
+// @Param status query string false "Filter by status: 'open' (default), 'resolved', 'dismissed' or 'all'"
+// @Param limit query int false "Limit the number of reports returned (default is 20)"
+// @Param offset query int false "Offset for pagination (default is 0)"
+// @Security BearerAuth
+// @Success 200 {object} rc.MetaResponse "Reports retrieved."
+// @Failure 400 {object} util.Problem "Unknown status."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /admin/reports [get]
+func All(log *slog.Logger, Reports ReportHandler) http.HandlerFunc {
+	const op = "http-server.handlers.report.All"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		if err := admin.AdmCheck(r); err != nil {
+			return nil, err
+		}
+
+		status := strings.ToLower(r.URL.Query().Get("status"))
+		switch status {
+		case "":
+			status = rc.StatusOpen
+		case "all":
+			status = ""
+		case rc.StatusOpen, rc.StatusResolved, rc.StatusDismissed:
+		default:
+			return nil, util.NewError(http.StatusBadRequest, util.CodeBadRequest, "Unknown status: must be one of open, resolved, dismissed, all")
+		}
+
+		limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+		if err != nil || limit <= 0 || limit > 100 {
+			limit = 20
+		}
+		offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+		if err != nil || offset < 0 {
+			offset = 0
+		}
+
+		return Reports.Reports(r.Context(), status, limit, offset)
+	})
+}
+
+// Resolve godoc
+// @Summary Resolve abuse report
+// @ID resolveReport
+// @Description Closes an open report as acted upon. The review is recorded in the audit log.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path string true "Public ID (UUID) of the report"
+// @Param Review body rc.ReviewRequest false "Resolution note"
+// @Security BearerAuth
+// @Success 200 {object} rc.Report "Report resolved."
+// @Failure 400 {object} util.Problem "Invalid ID or input."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 404 {object} util.Problem "Report not found."
+// @Failure 409 {object} util.Problem "Report is already closed."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /admin/reports/{id}/resolve [post]
+func Resolve(log *slog.Logger, Reports ReportHandler) http.HandlerFunc {
+	return review(log, Reports, "http-server.handlers.report.Resolve", rc.StatusResolved)
+}
+
+// Dismiss godoc
+// @Summary Dismiss abuse report
+// @ID dismissReport
+// @Description Closes an open report without action. The review is recorded in the audit log.
+// Requires Authorization header with Bearer token for authentication.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path string true "Public ID (UUID) of the report"
+// @Param Review body rc.ReviewRequest false "Resolution note"
+// @Security BearerAuth
+// @Success 200 {object} rc.Report "Report dismissed."
+// @Failure 400 {object} util.Problem "Invalid ID or input."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Insufficient permissions."
+// @Failure 404 {object} util.Problem "Report not found."
+// @Failure 409 {object} util.Problem "Report is already closed."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /admin/reports/{id}/dismiss [post]
+func Dismiss(log *slog.Logger, Reports ReportHandler) http.HandlerFunc {
+	return review(log, Reports, "http-server.handlers.report.Dismiss", rc.StatusDismissed)
+}
+
+func review(log *slog.Logger, Reports ReportHandler, op, status string) http.HandlerFunc {
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		if err := admin.AdmCheck(r); err != nil {
+			return nil, err
+		}
+		actor, err := contextUser(r)
+		if err != nil {
+			return nil, err
+		}
+
+		// The resolution note is optional, so is the body.
+		var req rc.ReviewRequest
+		if r.ContentLength != 0 {
+			if err := util.DecodeJSON(r, &req); err != nil {
+				return nil, err
+			}
+			if err := util.Validate(req); err != nil {
+				return nil, err
+			}
+		}
+
+		id, err := util.ResolveID(r, Reports.ReportID, "No such report")
+		if err != nil {
+			return nil, err
+		}
+
+		report, err := Reports.ReviewReport(r.Context(), id, actor, status, req.Resolution)
+		if err != nil {
+			if errors.Is(err, sdb.ErrConflict) {
+				return nil, util.WrapError(err, http.StatusConflict, util.CodeConflict, "Report is already closed")
+			}
+			return nil, util.NotFound(err, "No such report")
+		}
+
+		log.Info("report reviewed", slog.String("report", report.PublicID), slog.String("status", status))
+
+		return report, nil
+	})
+}
+
+func contextUser(r *http.Request) (int, error) {
+	userContext, ok := r.Context().Value(access.CxtKey("userContext")).(access.UserContext)
+	if !ok {
+		return 0, util.NewError(http.StatusUnauthorized, util.CodeUnauthorized, "User context not found")
+	}
+	return userContext.UserId, nil
+}
@@ -0,0 +1,153 @@
+package scim
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+type PatchRequest struct {
+	Schemas    []string  `json:"schemas"`
+	Operations []PatchOp `json:"Operations"`
+}
+
+// PatchOp is one operation, without a path the value is an object of attributes to set
+type PatchOp struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path,omitempty"`
+	Value json.RawMessage `json:"value,omitempty" swaggertype:"object"`
+}
+
+func (p PatchOp) apply(user *User) error {
+	switch strings.ToLower(p.Op) {
+	case "add", "replace":
+		if p.Path != "" {
+			return set(user, p.Path, p.Value)
+		}
+		var attrs map[string]json.RawMessage
+		if err := json.Unmarshal(p.Value, &attrs); err != nil {
+			return invalid("invalidValue", "A patch without a path needs an object value")
+		}
+		for path, value := range attrs {
+			if err := set(user, path, value); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "remove":
+		if p.Path == "" {
+			return invalid("noTarget", "remove needs a path")
+		}
+		return set(user, p.Path, nil)
+	default:
+		return invalid("invalidSyntax", "Unsupported op "+strconv.Quote(p.Op))
+	}
+}
+
+// set sets the attribute at path to value, a nil value removes it.
+// Filtered paths such as emails[type eq "work"].value address the single kept value.
+func set(user *User, path string, value json.RawMessage) error {
+	path = strings.ToLower(strings.TrimPrefix(path, SchemaUser+":"))
+	if i := strings.Index(path, "["); i >= 0 {
+		if j := strings.Index(path, "]"); j > i {
+			path = path[:i] + path[j+1:]
+		}
+	}
+
+	switch path {
+	case "active":
+		active := true
+		if value != nil {
+			v, err := parseBool(value)
+			if err != nil {
+				return err
+			}
+			active = v
+		}
+		user.Active = &active
+	case "username":
+		return setString(&user.UserName, value)
+	case "displayname":
+		return setString(&user.DisplayName, value)
+	case "externalid":
+		return setString(&user.ExternalID, value)
+	case "name":
+		var name Name
+		if value != nil {
+			if err := json.Unmarshal(value, &name); err != nil {
+				return invalid("invalidValue", "name must be an object")
+			}
+		}
+		user.Name, user.DisplayName = &name, ""
+	case "name.formatted", "name.givenname", "name.familyname":
+		if user.Name == nil {
+			user.Name = &Name{}
+		}
+		field := map[string]*string{
+			"name.formatted":  &user.Name.Formatted,
+			"name.givenname":  &user.Name.GivenName,
+			"name.familyname": &user.Name.FamilyName,
+		}[path]
+		if err := setString(field, value); err != nil {
+			return err
+		}
+		// The username is one field, a changed name replaces the displayName it was shown as.
+		if path != "name.formatted" {
+			user.Name.Formatted = ""
+		}
+		user.DisplayName = ""
+	case "emails", "phonenumbers", "emails.value", "phonenumbers.value":
+		target := &user.Emails
+		if strings.HasPrefix(path, "phonenumbers") {
+			target = &user.PhoneNumbers
+		}
+		if value == nil {
+			*target = nil
+			return nil
+		}
+		if strings.HasSuffix(path, ".value") {
+			var v string
+			if err := setString(&v, value); err != nil {
+				return err
+			}
+			*target = []MultiValue{{Value: v, Type: "work", Primary: true}}
+			return nil
+		}
+		var values []MultiValue
+		if err := json.Unmarshal(value, &values); err != nil {
+			return invalid("invalidValue", path+" must be a list")
+		}
+		*target = values
+	case "password":
+		return setString(&user.Password, value)
+	default:
+		return invalid("invalidPath", "Unsupported path "+strconv.Quote(path))
+	}
+	return nil
+}
+
+func setString(field *string, value json.RawMessage) error {
+	if value == nil {
+		*field = ""
+		return nil
+	}
+	if err := json.Unmarshal(value, field); err != nil {
+		return invalid("invalidValue", "Expected a string value")
+	}
+	return nil
+}
+
+// parseBool accepts a JSON boolean or, as some identity providers send, a string
+func parseBool(value json.RawMessage) (bool, error) {
+	var b bool
+	if err := json.Unmarshal(value, &b); err == nil {
+		return b, nil
+	}
+	var s string
+	if err := json.Unmarshal(value, &s); err == nil {
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b, nil
+		}
+	}
+	return false, invalid("invalidValue", "Expected a boolean value")
+}
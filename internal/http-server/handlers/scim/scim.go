@@ -0,0 +1,512 @@
+// This package provides SCIM 2.0 (RFC 7643, RFC 7644) user provisioning for identity providers
+package scim
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	util "github.com/sabbatD/srest-api/internal/http-server/handleUtil"
+	"github.com/sabbatD/srest-api/internal/lib/logger/sl"
+	u "github.com/sabbatD/srest-api/internal/lib/userConfig"
+)
+
+// SCIM schema URNs
+const (
+	SchemaUser  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SchemaList  = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SchemaPatch = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SchemaError = "urn:ietf:params:scim:api:messages:2.0:Error"
+)
+
+const contentType = "application/scim+json"
+
+type UserHandler interface {
+	SCIMUsers(ctx context.Context, q u.DirectoryQuery) ([]u.DirectoryUser, int, error)
+	SCIMUser(ctx context.Context, publicID string) (u.DirectoryUser, error)
+	SCIMCreateUser(ctx context.Context, d u.DirectoryUser, pwd string) (u.DirectoryUser, error)
+	SCIMUpdateUser(ctx context.Context, d u.DirectoryUser, pwd string) (u.DirectoryUser, error)
+	SCIMDeleteUser(ctx context.Context, publicID string) error
+}
+
+// User is the SCIM core user. userName is the login, displayName (or name) the username,
+// the primary email and phone number are kept, active=false blocks the user.
+type User struct {
+	Schemas      []string     `json:"schemas"`
+	ID           string       `json:"id,omitempty"`
+	ExternalID   string       `json:"externalId,omitempty"`
+	UserName     string       `json:"userName"`
+	Name         *Name        `json:"name,omitempty"`
+	DisplayName  string       `json:"displayName,omitempty"`
+	Emails       []MultiValue `json:"emails,omitempty"`
+	PhoneNumbers []MultiValue `json:"phoneNumbers,omitempty"`
+	Active       *bool        `json:"active,omitempty" extensions:"x-nullable"`
+	// Password is write only
+	Password string `json:"password,omitempty"`
+	Meta     *Meta  `json:"meta,omitempty"`
+}
+
+type Name struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+type MultiValue struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+type Meta struct {
+	ResourceType string    `json:"resourceType"`
+	Created      time.Time `json:"created"`
+	Location     string    `json:"location"`
+}
+
+type ListResponse struct {
+	Schemas      []string `json:"schemas"`
+	TotalResults int      `json:"totalResults"`
+	StartIndex   int      `json:"startIndex"`
+	ItemsPerPage int      `json:"itemsPerPage"`
+	Resources    []User   `json:"Resources"`
+}
+
+// Error is the SCIM error body, status is a string by the spec
+type Error struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+}
+
+// scimError carries the scimType of a 400 along with the response
+type scimError struct {
+	*util.HTTPError
+	scimType string
+}
+
+func (e *scimError) Unwrap() error { return e.HTTPError }
+
+func invalid(scimType, msg string) error {
+	return &scimError{HTTPError: util.NewError(http.StatusBadRequest, util.CodeInvalidInput, msg), scimType: scimType}
+}
+
+// Auth admits requests bearing the provisioning token. SCIM clients are not users,
+// they authenticate with a static token shared with the identity provider.
+func Auth(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				writeError(w, r, util.NewError(http.StatusUnauthorized, util.CodeUnauthorized, "Missing or invalid provisioning token"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// List godoc
+// @Summary List users for provisioning
+// @ID scimListUsers
+// @Description Lists users in SCIM format ordered by creation. Supports the filters userName eq "login" and externalId eq "id".
+// Requires the SCIM provisioning token as Bearer token.
+// @Tags scim
+// @Produce json
+// @Param filter query string false "userName eq \"login\" or externalId eq \"id\""
+// @Param startIndex query int false "1-based index of the first result (default is 1)"
+// @Param count query int false "Page size (default and maximum is 100)"
+// @Security BearerAuth
+// @Success 200 {object} ListResponse "Users retrieved."
+// @Failure 400 {object} Error "Unsupported filter."
+// @Failure 401 {object} Error "Missing or invalid provisioning token."
+// @Failure 500 {object} Error "Internal error."
+// @Router /scim/v2/Users [get]
+func List(log *slog.Logger, Users UserHandler) http.HandlerFunc {
+	const op = "http-server.handlers.scim.List"
+
+	return handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		query := r.URL.Query()
+
+		q := u.DirectoryQuery{}
+		if filter := query.Get("filter"); filter != "" {
+			attr, value, err := parseFilter(filter)
+			if err != nil {
+				return nil, err
+			}
+			switch attr {
+			case "username":
+				q.Login = value
+			case "externalid":
+				q.ExternalID = value
+			}
+		}
+
+		start, err := strconv.Atoi(query.Get("startIndex"))
+		if err != nil || start < 1 {
+			start = 1
+		}
+		q.Offset = start - 1
+		q.Limit, err = strconv.Atoi(query.Get("count"))
+		if err != nil || q.Limit < 0 || q.Limit > 100 {
+			q.Limit = 100
+		}
+
+		users, total, err := Users.SCIMUsers(r.Context(), q)
+		if err != nil {
+			return nil, err
+		}
+
+		resp := ListResponse{Schemas: []string{SchemaList}, TotalResults: total, StartIndex: start, ItemsPerPage: len(users), Resources: []User{}}
+		for _, d := range users {
+			resp.Resources = append(resp.Resources, toSCIM(r, d))
+		}
+		return write(w, http.StatusOK, resp)
+	})
+}
+
+// Get godoc
+// @Summary Get a user for provisioning
+// @ID scimGetUser
+// @Description Returns the user in SCIM format.
+// Requires the SCIM provisioning token as Bearer token.
+// @Tags scim
+// @Produce json
+// @Param id path string true "User ID (UUID)"
+// @Security BearerAuth
+// @Success 200 {object} User "User retrieved."
+// @Failure 401 {object} Error "Missing or invalid provisioning token."
+// @Failure 404 {object} Error "User not found."
+// @Failure 500 {object} Error "Internal error."
+// @Router /scim/v2/Users/{id} [get]
+func Get(log *slog.Logger, Users UserHandler) http.HandlerFunc {
+	const op = "http-server.handlers.scim.Get"
+
+	return handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		d, err := Users.SCIMUser(r.Context(), userID(r))
+		if err != nil {
+			return nil, util.NotFound(err, "User not found")
+		}
+		return write(w, http.StatusOK, toSCIM(r, d))
+	})
+}
+
+// Create godoc
+// @Summary Provision a user
+// @ID scimCreateUser
+// @Description Creates a user from a SCIM user. An email is required, without a password the user cannot sign in until one is set.
+// Requires the SCIM provisioning token as Bearer token.
+// @Tags scim
+// @Accept json
+// @Produce json
+// @Param User body User true "SCIM user"
+// @Security BearerAuth
+// @Success 201 {object} User "User created."
+// @Failure 400 {object} Error "Invalid user."
+// @Failure 401 {object} Error "Missing or invalid provisioning token."
+// @Failure 409 {object} Error "Login, email or externalId is taken."
+// @Failure 500 {object} Error "Internal error."
+// @Router /scim/v2/Users [post]
+func Create(log *slog.Logger, Users UserHandler) http.HandlerFunc {
+	const op = "http-server.handlers.scim.Create"
+
+	return handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		var req User
+		if err := decode(r, &req); err != nil {
+			return nil, err
+		}
+		d, err := fromSCIM(req)
+		if err != nil {
+			return nil, err
+		}
+
+		created, err := Users.SCIMCreateUser(r.Context(), d, req.Password)
+		if err != nil {
+			return nil, err
+		}
+
+		log.Info("user provisioned", slog.String("id", created.PublicID))
+
+		resp := toSCIM(r, created)
+		w.Header().Set("Location", resp.Meta.Location)
+		return write(w, http.StatusCreated, resp)
+	})
+}
+
+// Replace godoc
+// @Summary Replace a provisioned user
+// @ID scimReplaceUser
+// @Description Replaces all attributes of the user. Setting active to false blocks the user and revokes their sessions.
+// Requires the SCIM provisioning token as Bearer token.
+// @Tags scim
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID (UUID)"
+// @Param User body User true "SCIM user"
+// @Security BearerAuth
+// @Success 200 {object} User "User replaced."
+// @Failure 400 {object} Error "Invalid user."
+// @Failure 401 {object} Error "Missing or invalid provisioning token."
+// @Failure 404 {object} Error "User not found."
+// @Failure 409 {object} Error "Login, email or externalId is taken."
+// @Failure 500 {object} Error "Internal error."
+// @Router /scim/v2/Users/{id} [put]
+func Replace(log *slog.Logger, Users UserHandler) http.HandlerFunc {
+	const op = "http-server.handlers.scim.Replace"
+
+	return handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		var req User
+		if err := decode(r, &req); err != nil {
+			return nil, err
+		}
+		return update(w, r, Users, req)
+	})
+}
+
+// Patch godoc
+// @Summary Update a provisioned user
+// @ID scimPatchUser
+// @Description Applies SCIM PatchOp operations (add, replace, remove) to the user, e.g. {"op": "replace", "path": "active", "value": false} deactivates it.
+// Supported paths are active, userName, displayName, externalId, name.*, emails, phoneNumbers and their value sub-attributes.
+// Requires the SCIM provisioning token as Bearer token.
+// @Tags scim
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID (UUID)"
+// @Param Patch body PatchRequest true "SCIM patch"
+// @Security BearerAuth
+// @Success 200 {object} User "User updated."
+// @Failure 400 {object} Error "Invalid operation."
+// @Failure 401 {object} Error "Missing or invalid provisioning token."
+// @Failure 404 {object} Error "User not found."
+// @Failure 409 {object} Error "Login, email or externalId is taken."
+// @Failure 500 {object} Error "Internal error."
+// @Router /scim/v2/Users/{id} [patch]
+func Patch(log *slog.Logger, Users UserHandler) http.HandlerFunc {
+	const op = "http-server.handlers.scim.Patch"
+
+	return handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		var req PatchRequest
+		if err := decode(r, &req); err != nil {
+			return nil, err
+		}
+
+		d, err := Users.SCIMUser(r.Context(), userID(r))
+		if err != nil {
+			return nil, util.NotFound(err, "User not found")
+		}
+
+		user := toSCIM(r, d)
+		for _, operation := range req.Operations {
+			if err := operation.apply(&user); err != nil {
+				return nil, err
+			}
+		}
+		return update(w, r, Users, user)
+	})
+}
+
+// Delete godoc
+// @Summary Deprovision a user
+// @ID scimDeleteUser
+// @Description Deletes the user like an admin would and revokes their sessions.
+// Requires the SCIM provisioning token as Bearer token.
+// @Tags scim
+// @Param id path string true "User ID (UUID)"
+// @Security BearerAuth
+// @Success 204 "User deleted."
+// @Failure 401 {object} Error "Missing or invalid provisioning token."
+// @Failure 404 {object} Error "User not found."
+// @Failure 500 {object} Error "Internal error."
+// @Router /scim/v2/Users/{id} [delete]
+func Delete(log *slog.Logger, Users UserHandler) http.HandlerFunc {
+	const op = "http-server.handlers.scim.Delete"
+
+	return handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		if err := Users.SCIMDeleteUser(r.Context(), userID(r)); err != nil {
+			return nil, util.NotFound(err, "User not found")
+		}
+
+		log.Info("user deprovisioned", slog.String("id", userID(r)))
+
+		w.WriteHeader(http.StatusNoContent)
+		return nil, nil
+	})
+}
+
+// update stores req as the user of the {id} path param
+func update(w http.ResponseWriter, r *http.Request, Users UserHandler, req User) (any, error) {
+	log := sl.FromContext(r.Context())
+
+	d, err := fromSCIM(req)
+	if err != nil {
+		return nil, err
+	}
+	d.PublicID = userID(r)
+
+	updated, err := Users.SCIMUpdateUser(r.Context(), d, req.Password)
+	if err != nil {
+		return nil, util.NotFound(err, "User not found")
+	}
+
+	log.Info("provisioned user updated", slog.String("id", updated.PublicID), slog.Bool("active", updated.Active))
+
+	return write(w, http.StatusOK, toSCIM(r, updated))
+}
+
+// Ids that are not UUIDs cannot match a user, they are answered with 404 as SCIM expects
+func userID(r *http.Request) string {
+	id := chi.URLParam(r, "id")
+	if !util.IsUUID(id) {
+		return "00000000-0000-0000-0000-000000000000"
+	}
+	return id
+}
+
+func toSCIM(r *http.Request, d u.DirectoryUser) User {
+	active := d.Active
+	user := User{
+		Schemas:     []string{SchemaUser},
+		ID:          d.PublicID,
+		ExternalID:  d.ExternalID,
+		UserName:    d.Login,
+		Name:        &Name{Formatted: d.Username},
+		DisplayName: d.Username,
+		Active:      &active,
+		Meta:        &Meta{ResourceType: "User", Created: d.Created, Location: location(r, d.PublicID)},
+	}
+	if d.Email != "" {
+		user.Emails = []MultiValue{{Value: d.Email, Type: "work", Primary: true}}
+	}
+	if d.PhoneNumber != "" {
+		user.PhoneNumbers = []MultiValue{{Value: d.PhoneNumber, Type: "work", Primary: true}}
+	}
+	return user
+}
+
+func fromSCIM(user User) (u.DirectoryUser, error) {
+	d := u.DirectoryUser{
+		ExternalID:  user.ExternalID,
+		Login:       strings.TrimSpace(user.UserName),
+		Username:    user.DisplayName,
+		Email:       primary(user.Emails),
+		PhoneNumber: primary(user.PhoneNumbers),
+		Active:      user.Active == nil || *user.Active,
+	}
+	if d.Username == "" && user.Name != nil {
+		d.Username = user.Name.Formatted
+		if d.Username == "" {
+			d.Username = strings.TrimSpace(user.Name.GivenName + " " + user.Name.FamilyName)
+		}
+	}
+	if d.Username == "" {
+		d.Username = d.Login
+	}
+
+	switch {
+	case d.Login == "" || len(d.Login) > 254:
+		return d, invalid("invalidValue", "userName is required and at most 254 characters long")
+	case d.Email == "":
+		return d, invalid("invalidValue", "An email is required")
+	case len(d.Username) > 254:
+		return d, invalid("invalidValue", "displayName is at most 254 characters long")
+	}
+	return d, nil
+}
+
+// primary returns the primary value, or the first one when none is marked primary
+func primary(values []MultiValue) string {
+	for _, v := range values {
+		if v.Primary {
+			return v.Value
+		}
+	}
+	if len(values) > 0 {
+		return values[0].Value
+	}
+	return ""
+}
+
+// location is the URL of the user resource, the users route path with the id
+func location(r *http.Request, id string) string {
+	scheme := "https"
+	if r.TLS == nil && r.Header.Get("X-Forwarded-Proto") != "https" {
+		scheme = "http"
+	}
+	path := r.URL.Path
+	if i := strings.Index(path, "/Users"); i >= 0 {
+		path = path[:i+len("/Users")]
+	}
+	return scheme + "://" + r.Host + path + "/" + id
+}
+
+// parseFilter parses the supported filters: <attr> eq "<value>" on userName and externalId
+func parseFilter(filter string) (attr, value string, err error) {
+	fields := strings.SplitN(strings.TrimSpace(filter), " ", 3)
+	if len(fields) != 3 || !strings.EqualFold(fields[1], "eq") {
+		return "", "", invalid("invalidFilter", "Only eq filters are supported")
+	}
+
+	attr = strings.ToLower(fields[0])
+	if attr != "username" && attr != "externalid" {
+		return "", "", invalid("invalidFilter", "Only userName and externalId can be filtered")
+	}
+	if err := json.Unmarshal([]byte(fields[2]), &value); err != nil {
+		return "", "", invalid("invalidFilter", "The filter value must be a quoted string")
+	}
+	return attr, value, nil
+}
+
+// decode reads a SCIM request, clients send application/scim+json which render.DecodeJSON would not check anyway
+func decode(r *http.Request, v any) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return &scimError{HTTPError: util.WrapError(err, http.StatusBadRequest, util.CodeBadRequest, "failed to deserialize json request"), scimType: "invalidSyntax"}
+	}
+	return nil
+}
+
+func handle(log *slog.Logger, op string, h util.HandlerFunc) http.HandlerFunc {
+	return util.HandleWith(log, op, h, writeError)
+}
+
+// write answers with v as application/scim+json, render.JSON would set application/json
+func write(w http.ResponseWriter, status int, v any) (any, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	w.Write(body)
+	return nil, nil
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	e := util.LogError(r, err)
+
+	body := Error{Schemas: []string{SchemaError}, Status: strconv.Itoa(e.Status), Detail: e.Message}
+	var se *scimError
+	if errors.As(err, &se) {
+		body.ScimType = se.scimType
+	} else if e.Status == http.StatusConflict {
+		body.ScimType = "uniqueness"
+	}
+
+	data, _ := json.Marshal(body)
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(e.Status)
+	w.Write(data)
+}
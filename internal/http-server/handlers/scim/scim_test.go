@@ -0,0 +1,223 @@
+package scim
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	sdb "github.com/sabbatD/srest-api/internal/database"
+	u "github.com/sabbatD/srest-api/internal/lib/userConfig"
+)
+
+// memUsers mirrors the uniqueness rules of the postgres storage
+type memUsers struct {
+	users     []u.DirectoryUser
+	passwords map[string]string
+}
+
+func (m *memUsers) SCIMUsers(ctx context.Context, q u.DirectoryQuery) ([]u.DirectoryUser, int, error) {
+	var match []u.DirectoryUser
+	for _, d := range m.users {
+		if (q.Login == "" || strings.EqualFold(d.Login, q.Login)) && (q.ExternalID == "" || d.ExternalID == q.ExternalID) {
+			match = append(match, d)
+		}
+	}
+	page := []u.DirectoryUser{}
+	for i := q.Offset; i < len(match) && i < q.Offset+q.Limit; i++ {
+		page = append(page, match[i])
+	}
+	return page, len(match), nil
+}
+
+func (m *memUsers) SCIMUser(ctx context.Context, publicID string) (u.DirectoryUser, error) {
+	for _, d := range m.users {
+		if d.PublicID == publicID {
+			return d, nil
+		}
+	}
+	return u.DirectoryUser{}, sdb.ErrNotFound
+}
+
+func (m *memUsers) taken(d u.DirectoryUser) bool {
+	for _, o := range m.users {
+		if o.PublicID != d.PublicID && (o.Login == d.Login || o.Email == d.Email) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *memUsers) SCIMCreateUser(ctx context.Context, d u.DirectoryUser, pwd string) (u.DirectoryUser, error) {
+	if m.taken(d) {
+		return d, fmt.Errorf("user %w", sdb.ErrAlreadyExists)
+	}
+	d.ID = len(m.users) + 1
+	d.PublicID = fmt.Sprintf("00000000-0000-0000-0000-%012d", d.ID)
+	d.Created = time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)
+	m.users = append(m.users, d)
+	m.passwords[d.PublicID] = pwd
+	return d, nil
+}
+
+func (m *memUsers) SCIMUpdateUser(ctx context.Context, d u.DirectoryUser, pwd string) (u.DirectoryUser, error) {
+	for i, o := range m.users {
+		if o.PublicID == d.PublicID {
+			if m.taken(d) {
+				return d, fmt.Errorf("user %w", sdb.ErrAlreadyExists)
+			}
+			d.ID, d.Created = o.ID, o.Created
+			m.users[i] = d
+			if pwd != "" {
+				m.passwords[d.PublicID] = pwd
+			}
+			return d, nil
+		}
+	}
+	return d, sdb.ErrNotFound
+}
+
+func (m *memUsers) SCIMDeleteUser(ctx context.Context, publicID string) error {
+	for i, d := range m.users {
+		if d.PublicID == publicID {
+			m.users = append(m.users[:i], m.users[i+1:]...)
+			return nil
+		}
+	}
+	return sdb.ErrNotFound
+}
+
+func newRouter(m *memUsers) http.Handler {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	r := chi.NewRouter()
+	r.Route("/scim/v2/Users", func(r chi.Router) {
+		r.Use(Auth("secret"))
+		r.Get("/", List(log, m))
+		r.Post("/", Create(log, m))
+		r.Get("/{id}", Get(log, m))
+		r.Put("/{id}", Replace(log, m))
+		r.Patch("/{id}", Patch(log, m))
+		r.Delete("/{id}", Delete(log, m))
+	})
+	return r
+}
+
+func do(t *testing.T, h http.Handler, method, path, token, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", contentType)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func decodeBody[T any](t *testing.T, rec *httptest.ResponseRecorder) T {
+	t.Helper()
+	var v T
+	if err := json.Unmarshal(rec.Body.Bytes(), &v); err != nil {
+		t.Fatalf("decode %q: %v", rec.Body.String(), err)
+	}
+	return v
+}
+
+func TestProvisioning(t *testing.T) {
+	m := &memUsers{passwords: map[string]string{}}
+	h := newRouter(m)
+
+	rec := do(t, h, http.MethodGet, "/scim/v2/Users", "wrong", "")
+	if rec.Code != http.StatusUnauthorized || rec.Header().Get("Content-Type") != contentType {
+		t.Fatalf("wrong token: %d %s, want a SCIM 401", rec.Code, rec.Header().Get("Content-Type"))
+	}
+
+	rec = do(t, h, http.MethodPost, "/scim/v2/Users", "secret", `{
+		"schemas": ["`+SchemaUser+`"],
+		"userName": "alice@example.com",
+		"externalId": "00u1",
+		"name": {"givenName": "Alice", "familyName": "Smith"},
+		"emails": [{"value": "home@example.com"}, {"value": "alice@example.com", "primary": true}],
+		"password": "s3cret"
+	}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", rec.Code, rec.Body)
+	}
+	alice := decodeBody[User](t, rec)
+	if alice.DisplayName != "Alice Smith" || alice.Emails[0].Value != "alice@example.com" || !*alice.Active {
+		t.Errorf("created user = %+v", alice)
+	}
+	if rec.Header().Get("Location") != alice.Meta.Location || !strings.HasSuffix(alice.Meta.Location, "/scim/v2/Users/"+alice.ID) {
+		t.Errorf("location = %q, meta = %q", rec.Header().Get("Location"), alice.Meta.Location)
+	}
+	if alice.Password != "" || m.passwords[alice.ID] != "s3cret" {
+		t.Error("password was not stored write only")
+	}
+
+	rec = do(t, h, http.MethodPost, "/scim/v2/Users", "secret", `{"userName": "alice@example.com", "emails": [{"value": "other@example.com"}]}`)
+	if e := decodeBody[Error](t, rec); rec.Code != http.StatusConflict || e.ScimType != "uniqueness" || e.Status != "409" {
+		t.Errorf("duplicate: %d %+v, want 409 uniqueness", rec.Code, e)
+	}
+	rec = do(t, h, http.MethodPost, "/scim/v2/Users", "secret", `{"userName": "bob"}`)
+	if e := decodeBody[Error](t, rec); rec.Code != http.StatusBadRequest || e.ScimType != "invalidValue" {
+		t.Errorf("no email: %d %+v, want 400 invalidValue", rec.Code, e)
+	}
+
+	rec = do(t, h, http.MethodGet, `/scim/v2/Users?filter=userName+eq+%22ALICE@example.com%22`, "secret", "")
+	list := decodeBody[ListResponse](t, rec)
+	if list.TotalResults != 1 || len(list.Resources) != 1 || list.Resources[0].ID != alice.ID {
+		t.Errorf("filtered list = %+v", list)
+	}
+	rec = do(t, h, http.MethodGet, `/scim/v2/Users?filter=emails+co+%22x%22`, "secret", "")
+	if e := decodeBody[Error](t, rec); rec.Code != http.StatusBadRequest || e.ScimType != "invalidFilter" {
+		t.Errorf("unsupported filter: %d %+v, want 400 invalidFilter", rec.Code, e)
+	}
+
+	// Azure AD sends booleans as strings and capitalized ops.
+	rec = do(t, h, http.MethodPatch, "/scim/v2/Users/"+alice.ID, "secret", `{
+		"schemas": ["`+SchemaPatch+`"],
+		"Operations": [
+			{"op": "Replace", "path": "active", "value": "False"},
+			{"op": "replace", "path": "emails[type eq \"work\"].value", "value": "a.smith@example.com"},
+			{"op": "replace", "value": {"displayName": "A. Smith"}}
+		]
+	}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("patch: %d %s", rec.Code, rec.Body)
+	}
+	patched := decodeBody[User](t, rec)
+	if *patched.Active || patched.Emails[0].Value != "a.smith@example.com" || patched.DisplayName != "A. Smith" || patched.UserName != alice.UserName {
+		t.Errorf("patched user = %+v", patched)
+	}
+	if m.passwords[alice.ID] != "s3cret" {
+		t.Error("patch without a password changed it")
+	}
+
+	rec = do(t, h, http.MethodPatch, "/scim/v2/Users/"+alice.ID, "secret", `{"Operations": [{"op": "replace", "path": "groups", "value": []}]}`)
+	if e := decodeBody[Error](t, rec); rec.Code != http.StatusBadRequest || e.ScimType != "invalidPath" {
+		t.Errorf("unsupported path: %d %+v, want 400 invalidPath", rec.Code, e)
+	}
+
+	rec = do(t, h, http.MethodPut, "/scim/v2/Users/"+alice.ID, "secret", `{"userName": "asmith", "emails": [{"value": "a.smith@example.com"}]}`)
+	if replaced := decodeBody[User](t, rec); rec.Code != http.StatusOK || replaced.UserName != "asmith" || !*replaced.Active || replaced.ExternalID != "" {
+		t.Errorf("replace: %d %+v", rec.Code, replaced)
+	}
+
+	if rec = do(t, h, http.MethodDelete, "/scim/v2/Users/"+alice.ID, "secret", ""); rec.Code != http.StatusNoContent {
+		t.Errorf("delete: %d %s", rec.Code, rec.Body)
+	}
+	if rec = do(t, h, http.MethodGet, "/scim/v2/Users/"+alice.ID, "secret", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("deleted user: %d, want 404", rec.Code)
+	}
+	if rec = do(t, h, http.MethodGet, "/scim/v2/Users/not-a-uuid", "secret", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("malformed id: %d, want 404", rec.Code)
+	}
+}
@@ -0,0 +1,77 @@
+package todo
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	util "github.com/sabbatD/srest-api/internal/http-server/handleUtil"
+	"github.com/sabbatD/srest-api/internal/lib/logger/sl"
+	t "github.com/sabbatD/srest-api/internal/lib/todoConfig"
+)
+
+// calendarMaxDays limits the range of a calendar request, a year of a leap year
+const calendarMaxDays = 366
+
+// Calendar godoc
+// @Summary Retrieve tasks by due date
+// @ID getCalendar
+// @Description Returns the tasks due from one date to another, both inclusive, grouped by day in date order.
+// Every day holds the number of its tasks and of the completed ones. Days without tasks are left out.
+// The range may span up to 366 days.
+// @Tags todo
+// @Security BearerAuth
+// @Produce json
+// @Param from query string true "First day as YYYY-MM-DD"
+// @Param to query string true "Last day as YYYY-MM-DD"
+// @Success 200 {object} t.CalendarResponse "Tasks retrieved successfully."
+// @Failure 400 {object} util.Problem "Missing or invalid from or to, or a range that is reversed or too long."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /todos/calendar [get]
+func Calendar(log *slog.Logger, todo TodoHandler) http.HandlerFunc {
+	const op = "http-server.hanlders.todo.Calendar"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		userID, err := contextUser(r)
+		if err != nil {
+			return nil, err
+		}
+
+		from, err := time.Parse(t.DateFormat, r.URL.Query().Get("from"))
+		if err != nil {
+			return nil, util.NewError(http.StatusBadRequest, util.CodeBadRequest, "Invalid from: must be a date as YYYY-MM-DD")
+		}
+		to, err := time.Parse(t.DateFormat, r.URL.Query().Get("to"))
+		if err != nil {
+			return nil, util.NewError(http.StatusBadRequest, util.CodeBadRequest, "Invalid to: must be a date as YYYY-MM-DD")
+		}
+		if to.Before(from) {
+			return nil, util.NewError(http.StatusBadRequest, util.CodeBadRequest, "Invalid range: to is before from")
+		}
+		if to.Sub(from) >= calendarMaxDays*24*time.Hour {
+			return nil, util.NewError(http.StatusBadRequest, util.CodeBadRequest, fmt.Sprintf("Invalid range: at most %d days", calendarMaxDays))
+		}
+
+		days, err := todo.TodoCalendar(r.Context(), userID, from, to)
+		if err != nil {
+			return nil, err
+		}
+
+		log.Info("successfully retrieved calendar")
+
+		return t.CalendarResponse{From: from.Format(t.DateFormat), To: to.Format(t.DateFormat), Days: days}, nil
+	})
+}
+
+// validDue tells whether a due date change is a date as YYYY-MM-DD or empty to remove the date
+func validDue(due *string) bool {
+	if due == nil || *due == "" {
+		return true
+	}
+	_, err := time.Parse(t.DateFormat, *due)
+	return err == nil
+}
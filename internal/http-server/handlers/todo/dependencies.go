@@ -0,0 +1,130 @@
+package todo
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	sdb "github.com/sabbatD/srest-api/internal/database"
+	util "github.com/sabbatD/srest-api/internal/http-server/handleUtil"
+	"github.com/sabbatD/srest-api/internal/lib/logger/sl"
+	t "github.com/sabbatD/srest-api/internal/lib/todoConfig"
+)
+
+// AddBlocker godoc
+// @Summary Mark a task as blocked by another
+// @ID addBlocker
+// @Description Records that the task cannot be completed while the blocking task is open. Dependencies that would create
+// a cycle are refused. Adding a recorded dependency again changes nothing.
+// @Tags todo
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Public ID (UUID) of the blocked task"
+// @Param blocker path string true "Public ID (UUID) of the blocking task"
+// @Success 200 {object} t.Todo "Dependency recorded, returns the blocked task."
+// @Failure 400 {object} util.Problem "Invalid task ID."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 404 {object} util.Problem "Task not found."
+// @Failure 409 {object} util.Problem "The dependency would create a cycle."
+// @Failure 429 {object} string "Too many requests, see Retry-After."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /todos/{id}/blocked-by/{blocker} [put]
+func AddBlocker(log *slog.Logger, todo TodoHandler) http.HandlerFunc {
+	const op = "http-server.hanlders.todo.AddBlocker"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		userID, err := contextUser(r)
+		if err != nil {
+			return nil, err
+		}
+		id := contextTodo(r)
+		blocker, err := blockerID(r, todo, userID)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := todo.AddBlocker(r.Context(), id, blocker, userID); err != nil {
+			if errors.Is(err, sdb.ErrConflict) {
+				return nil, util.WrapError(err, http.StatusConflict, util.CodeConflict, "The dependency would create a cycle")
+			}
+			return nil, util.NotFound(err, "No such task")
+		}
+
+		task, err := todo.GetTodo(r.Context(), id, userID)
+		if err != nil {
+			return nil, util.NotFound(err, "No such task")
+		}
+
+		log.Info("successfully added blocker")
+
+		return task, nil
+	})
+}
+
+// RemoveBlocker godoc
+// @Summary Remove a dependency between tasks
+// @ID removeBlocker
+// @Description Deletes the record that the task is blocked by the other one.
+// @Tags todo
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Public ID (UUID) of the blocked task"
+// @Param blocker path string true "Public ID (UUID) of the blocking task"
+// @Success 200 {object} t.Todo "Dependency removed, returns the formerly blocked task."
+// @Failure 400 {object} util.Problem "Invalid task ID."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 404 {object} util.Problem "Task or dependency not found."
+// @Failure 429 {object} string "Too many requests, see Retry-After."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /todos/{id}/blocked-by/{blocker} [delete]
+func RemoveBlocker(log *slog.Logger, todo TodoHandler) http.HandlerFunc {
+	const op = "http-server.hanlders.todo.RemoveBlocker"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		userID, err := contextUser(r)
+		if err != nil {
+			return nil, err
+		}
+		id := contextTodo(r)
+		blocker, err := blockerID(r, todo, userID)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := todo.RemoveBlocker(r.Context(), id, blocker, userID); err != nil {
+			return nil, util.NotFound(err, "No such dependency")
+		}
+
+		task, err := todo.GetTodo(r.Context(), id, userID)
+		if err != nil {
+			return nil, util.NotFound(err, "No such task")
+		}
+
+		log.Info("successfully removed blocker")
+
+		return task, nil
+	})
+}
+
+// blockerID resolves the {blocker} path param to a task of the user
+func blockerID(r *http.Request, todo TodoHandler, userID int) (int, error) {
+	publicID := chi.URLParam(r, "blocker")
+	if !util.IsUUID(publicID) {
+		return 0, util.NewError(http.StatusBadRequest, util.CodeInvalidID, "Missing or wrong blocker id")
+	}
+	id, err := todo.TodoID(r.Context(), publicID, userID)
+	if err != nil {
+		return 0, util.NotFound(err, "No such blocking task")
+	}
+	return id, nil
+}
+
+// completesBlocked tells whether req completes the open task before while a task it depends on is open
+func completesBlocked(before t.Todo, req t.TodoRequest) bool {
+	return req.IsDone != nil && *req.IsDone && !before.IsDone && before.Blocked
+}
@@ -0,0 +1,137 @@
+package todo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	util "github.com/sabbatD/srest-api/internal/http-server/handleUtil"
+	"github.com/sabbatD/srest-api/internal/lib/fields"
+	"github.com/sabbatD/srest-api/internal/lib/logger/sl"
+	"github.com/sabbatD/srest-api/internal/lib/workflow"
+)
+
+// Fields godoc
+// @Summary Get custom todo fields
+// @ID getTodoFieldSchema
+// @Description Returns the custom fields the user defined for their tasks.
+// @Tags todo
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} fields.Schema "Custom fields retrieved."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /todos/fields [get]
+func Fields(log *slog.Logger, todo TodoHandler) http.HandlerFunc {
+	const op = "http-server.hanlders.todo.Fields"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		userID, err := contextUser(r)
+		if err != nil {
+			return nil, err
+		}
+
+		return todo.TodoFields(r.Context(), userID)
+	})
+}
+
+// SetFields godoc
+// @Summary Set custom todo fields
+// @ID setTodoFieldSchema
+// @Description Replaces the custom fields of the user's tasks. Values of removed fields are deleted from the tasks.
+// Keys hold lower case letters, digits and underscores. Types are text, number, boolean, date (YYYY-MM-DD) and select, which needs options.
+// @Tags todo
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param Schema body fields.Schema true "Custom field definitions"
+// @Success 200 {object} fields.Schema "Custom fields set."
+// @Failure 400 {object} util.Problem "Invalid request payload."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 429 {object} string "Too many requests, see Retry-After."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /todos/fields [put]
+func SetFields(log *slog.Logger, todo TodoHandler) http.HandlerFunc {
+	const op = "http-server.hanlders.todo.SetFields"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		userID, err := contextUser(r)
+		if err != nil {
+			return nil, err
+		}
+
+		var schema fields.Schema
+		if err := util.DecodeJSON(r, &schema); err != nil {
+			return nil, err
+		}
+
+		log.Info("request body decoded")
+		log.Debug("req: ", slog.Any("request", schema))
+
+		if err := util.Validate(schema); err != nil {
+			return nil, err
+		}
+		if err := schema.Check(); err != nil {
+			return nil, invalidInput(err)
+		}
+		// Visibility is about user profiles, todos are only seen by their owner.
+		for i := range schema.Fields {
+			schema.Fields[i].Visibility = ""
+		}
+		if schema.Fields == nil {
+			schema.Fields = []fields.Field{}
+		}
+
+		if err := todo.SetTodoFields(r.Context(), userID, schema); err != nil {
+			return nil, err
+		}
+
+		log.Info("custom todo fields set")
+
+		return schema, nil
+	})
+}
+
+// customFilter reads the custom.<key>=<value> query parameters as values of the user's custom fields
+func customFilter(ctx context.Context, params url.Values, todo TodoHandler, userID int) (map[string]any, error) {
+	var filter map[string]any
+	var schema fields.Schema
+	for param, values := range params {
+		key, ok := strings.CutPrefix(param, "custom.")
+		if !ok {
+			continue
+		}
+		if filter == nil {
+			var err error
+			if schema, err = todo.TodoFields(ctx, userID); err != nil {
+				return nil, err
+			}
+			filter = make(map[string]any)
+		}
+
+		f, ok := schema.Field(key)
+		if !ok {
+			return nil, util.NewError(http.StatusBadRequest, util.CodeBadRequest, fmt.Sprintf("Unknown custom field %q", key))
+		}
+		value, err := f.Parse(values[0])
+		if err != nil {
+			return nil, invalidInput(err)
+		}
+		filter[key] = value
+	}
+	return filter, nil
+}
+
+// invalidInput reports invalid custom fields, statuses and their values as bad input, other errors are passed on
+func invalidInput(err error) error {
+	if errors.Is(err, fields.ErrInvalid) || errors.Is(err, workflow.ErrInvalid) {
+		return util.WrapError(err, http.StatusBadRequest, util.CodeInvalidInput, fmt.Sprintf("Invalid input: %v", err))
+	}
+	return err
+}
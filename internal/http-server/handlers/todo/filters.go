@@ -0,0 +1,222 @@
+package todo
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	sdb "github.com/sabbatD/srest-api/internal/database"
+	util "github.com/sabbatD/srest-api/internal/http-server/handleUtil"
+	"github.com/sabbatD/srest-api/internal/lib/clock"
+	"github.com/sabbatD/srest-api/internal/lib/logger/sl"
+	t "github.com/sabbatD/srest-api/internal/lib/todoConfig"
+)
+
+// filterParams are the GET /todos query parameters a saved filter may hold, besides custom.<key>
+var filterParams = map[string]bool{"filter": true, "status": true, "blocked": true, "due": true}
+
+// Filters godoc
+// @Summary List saved filters
+// @ID listFilters
+// @Description Returns the user's saved filters in the order they were created.
+// @Tags todo
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} t.SavedFilter "Saved filters retrieved."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /todos/filters [get]
+func Filters(log *slog.Logger, todo TodoHandler) http.HandlerFunc {
+	const op = "http-server.hanlders.todo.Filters"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		userID, err := contextUser(r)
+		if err != nil {
+			return nil, err
+		}
+
+		return todo.TodoFilters(r.Context(), userID)
+	})
+}
+
+// CreateFilter godoc
+// @Summary Save a filter
+// @ID saveFilter
+// @Description Saves a named snapshot of GET /todos query parameters: filter, status, blocked, due and custom.<key>.
+// The parameters are checked as a listing would check them. Relative due dates (today, overdue) are resolved when
+// the filter is applied. Names are unique per user regardless of case, a user keeps up to 50 filters.
+// @Tags todo
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param Filter body t.FilterRequest true "Name and query parameters of the filter"
+// @Success 200 {object} t.SavedFilter "Filter saved."
+// @Failure 400 {object} util.Problem "Invalid request payload or query parameters."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Filter limit reached."
+// @Failure 409 {object} util.Problem "A filter with the name exists."
+// @Failure 429 {object} string "Too many requests, see Retry-After."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /todos/filters [post]
+func CreateFilter(log *slog.Logger, todo TodoHandler) http.HandlerFunc {
+	const op = "http-server.hanlders.todo.CreateFilter"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		userID, err := contextUser(r)
+		if err != nil {
+			return nil, err
+		}
+
+		var req t.FilterRequest
+		if err := util.DecodeJSON(r, &req); err != nil {
+			return nil, err
+		}
+
+		log.Info("request body decoded")
+		log.Debug("req: ", slog.Any("request", req))
+
+		req.Name = strings.TrimSpace(req.Name)
+		if err := util.Validate(req); err != nil {
+			return nil, err
+		}
+		for param := range req.Query {
+			if !filterParams[param] && !strings.HasPrefix(param, "custom.") {
+				return nil, util.NewError(http.StatusBadRequest, util.CodeInvalidInput, fmt.Sprintf("Unknown query parameter %q", param))
+			}
+		}
+		if req.Query == nil {
+			req.Query = map[string]string{}
+		}
+		if _, err := todoQuery(r.Context(), filterValues(req.Query), todo, userID, clock.Now()); err != nil {
+			return nil, err
+		}
+
+		saved, err := todo.CreateTodoFilter(r.Context(), userID, req)
+		if err != nil {
+			if errors.Is(err, sdb.ErrLimitReached) {
+				return nil, util.WrapError(err, http.StatusForbidden, util.CodeLimit, fmt.Sprintf("Filter limit reached, at most %d", sdb.MaxTodoFilters))
+			}
+			if errors.Is(err, sdb.ErrAlreadyExists) {
+				return nil, util.WrapError(err, http.StatusConflict, util.CodeConflict, "A filter with this name exists")
+			}
+			return nil, err
+		}
+
+		log.Info("filter saved")
+
+		return saved, nil
+	})
+}
+
+// ApplyFilter godoc
+// @Summary Retrieve the tasks of a saved filter
+// @ID listFilterTodos
+// @Description Lists the tasks matching the saved filter, as GET /todos with its query parameters would.
+// A filter referring to a status or custom field removed since it was saved is reported as invalid.
+// @Tags todo
+// @Security BearerAuth
+// @Produce json
+// @Param filter path string true "Public ID (UUID) of the saved filter"
+// @Success 200 {object} t.MetaResponse "Tasks retrieved successfully."
+// @Failure 400 {object} util.Problem "Invalid filter ID, or the filter is no longer valid."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 404 {object} util.Problem "Filter not found."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /todos/filters/{filter}/todos [get]
+func ApplyFilter(log *slog.Logger, todo TodoHandler) http.HandlerFunc {
+	const op = "http-server.hanlders.todo.ApplyFilter"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		userID, err := contextUser(r)
+		if err != nil {
+			return nil, err
+		}
+		publicID, err := filterID(r)
+		if err != nil {
+			return nil, err
+		}
+
+		saved, err := todo.TodoFilter(r.Context(), publicID, userID)
+		if err != nil {
+			return nil, util.NotFound(err, "No such filter")
+		}
+
+		q, err := todoQuery(r.Context(), filterValues(saved.Query), todo, userID, clock.Now())
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := streamTodos(w, r, todo, q, userID); err != nil {
+			return nil, err
+		}
+
+		log.Info("successfully applied filter")
+
+		return nil, nil
+	})
+}
+
+// DeleteFilter godoc
+// @Summary Delete a saved filter
+// @ID deleteFilter
+// @Tags todo
+// @Security BearerAuth
+// @Produce json
+// @Param filter path string true "Public ID (UUID) of the saved filter"
+// @Success 200 {object} string "Filter deleted."
+// @Failure 400 {object} util.Problem "Invalid filter ID."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 404 {object} util.Problem "Filter not found."
+// @Failure 429 {object} string "Too many requests, see Retry-After."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /todos/filters/{filter} [delete]
+func DeleteFilter(log *slog.Logger, todo TodoHandler) http.HandlerFunc {
+	const op = "http-server.hanlders.todo.DeleteFilter"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		userID, err := contextUser(r)
+		if err != nil {
+			return nil, err
+		}
+		publicID, err := filterID(r)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := todo.DeleteTodoFilter(r.Context(), publicID, userID); err != nil {
+			return nil, util.NotFound(err, "No such filter")
+		}
+
+		log.Info("filter deleted")
+
+		return nil, nil
+	})
+}
+
+// filterID reads the {filter} path param
+func filterID(r *http.Request) (string, error) {
+	publicID := chi.URLParam(r, "filter")
+	if !util.IsUUID(publicID) {
+		return "", util.NewError(http.StatusBadRequest, util.CodeInvalidID, "Missing or wrong filter id")
+	}
+	return publicID, nil
+}
+
+// filterValues converts saved query parameters to the form a request carries them in
+func filterValues(query map[string]string) url.Values {
+	params := make(url.Values, len(query))
+	for k, v := range query {
+		params.Set(k, v)
+	}
+	return params
+}
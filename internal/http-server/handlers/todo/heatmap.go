@@ -0,0 +1,80 @@
+package todo
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	util "github.com/sabbatD/srest-api/internal/http-server/handleUtil"
+	"github.com/sabbatD/srest-api/internal/lib/cache"
+	"github.com/sabbatD/srest-api/internal/lib/clock"
+	"github.com/sabbatD/srest-api/internal/lib/logger/sl"
+	t "github.com/sabbatD/srest-api/internal/lib/todoConfig"
+)
+
+const (
+	// HeatmapTTL is how long a computed heatmap is served, completions show up at most this late
+	HeatmapTTL = 5 * time.Minute
+	// HeatmapMaxEntries bounds the heatmap cache
+	HeatmapMaxEntries = 10000
+)
+
+// Heatmap godoc
+// @Summary Retrieve completions per day
+// @ID getHeatmap
+// @Description Returns how many tasks the user completed on each day of the year (UTC), for a GitHub-style heatmap.
+// Days without completions are left out. A task reopened and completed again counts again.
+// Results are cached for up to 5 minutes.
+// @Tags todo
+// @Security BearerAuth
+// @Produce json
+// @Param year query int false "Year, the current one by default"
+// @Success 200 {object} t.HeatmapResponse "Completions retrieved successfully."
+// @Failure 400 {object} util.Problem "Invalid year."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /todos/heatmap [get]
+func Heatmap(log *slog.Logger, todo TodoHandler, heatmaps *cache.Cache) http.HandlerFunc {
+	const op = "http-server.hanlders.todo.Heatmap"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		userID, err := contextUser(r)
+		if err != nil {
+			return nil, err
+		}
+
+		now := clock.Now().UTC()
+		year := now.Year()
+		if str := r.URL.Query().Get("year"); str != "" {
+			year, err = strconv.Atoi(str)
+			if err != nil || year < 1970 || year > now.Year() {
+				return nil, util.NewError(http.StatusBadRequest, util.CodeBadRequest, "Invalid year")
+			}
+		}
+
+		key := cache.UserKey(userID, year)
+		if res, ok := heatmaps.Get(key); ok {
+			log.Info("heatmap served from cache")
+			return res, nil
+		}
+
+		from := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+		days, err := todo.TodoCompletions(r.Context(), userID, from, from.AddDate(1, 0, 0))
+		if err != nil {
+			return nil, err
+		}
+
+		res := t.HeatmapResponse{Year: year, Days: days}
+		for _, d := range days {
+			res.Total += d.Count
+		}
+		heatmaps.Set(key, res)
+
+		log.Info("successfully computed heatmap")
+
+		return res, nil
+	})
+}
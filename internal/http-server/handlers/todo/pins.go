@@ -0,0 +1,80 @@
+package todo
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	sdb "github.com/sabbatD/srest-api/internal/database"
+	util "github.com/sabbatD/srest-api/internal/http-server/handleUtil"
+	"github.com/sabbatD/srest-api/internal/lib/logger/sl"
+	t "github.com/sabbatD/srest-api/internal/lib/todoConfig"
+)
+
+// Pin godoc
+// @Summary Pin a task
+// @ID pinTodo
+// @Description Pins the task, pinned tasks are listed first. A user may pin up to 10 tasks. Pinning a pinned task changes nothing.
+// @Tags todo
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Public ID (UUID) of the task to pin"
+// @Success 200 {object} t.Todo "Task pinned, returns the task."
+// @Failure 400 {object} util.Problem "Invalid task ID."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Pinned task limit reached."
+// @Failure 404 {object} util.Problem "Task not found."
+// @Failure 429 {object} string "Too many requests, see Retry-After."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /todos/{id}/pin [post]
+func Pin(log *slog.Logger, todo TodoHandler) http.HandlerFunc {
+	return pin(log, todo, "http-server.hanlders.todo.Pin", true)
+}
+
+// Unpin godoc
+// @Summary Unpin a task
+// @ID unpinTodo
+// @Description Unpins the task. Unpinning a task that is not pinned changes nothing.
+// @Tags todo
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Public ID (UUID) of the task to unpin"
+// @Success 200 {object} t.Todo "Task unpinned, returns the task."
+// @Failure 400 {object} util.Problem "Invalid task ID."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 404 {object} util.Problem "Task not found."
+// @Failure 429 {object} string "Too many requests, see Retry-After."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /todos/{id}/unpin [post]
+func Unpin(log *slog.Logger, todo TodoHandler) http.HandlerFunc {
+	return pin(log, todo, "http-server.hanlders.todo.Unpin", false)
+}
+
+func pin(log *slog.Logger, todo TodoHandler, op string, pinned bool) http.HandlerFunc {
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		userID, err := contextUser(r)
+		if err != nil {
+			return nil, err
+		}
+		id := contextTodo(r)
+
+		if err := todo.PinTodo(r.Context(), id, userID, pinned); err != nil {
+			if errors.Is(err, sdb.ErrLimitReached) {
+				return nil, util.WrapError(err, http.StatusForbidden, util.CodeLimit, fmt.Sprintf("Pinned task limit reached, at most %d", sdb.MaxPinnedTodos))
+			}
+			return nil, util.NotFound(err, "No such task")
+		}
+
+		var task t.Todo
+		if task, err = todo.GetTodo(r.Context(), id, userID); err != nil {
+			return nil, util.NotFound(err, "No such task")
+		}
+
+		log.Info("successfully changed pin", slog.Bool("pinned", pinned))
+
+		return task, nil
+	})
+}
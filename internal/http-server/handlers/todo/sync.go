@@ -0,0 +1,292 @@
+package todo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	sdb "github.com/sabbatD/srest-api/internal/database"
+	util "github.com/sabbatD/srest-api/internal/http-server/handleUtil"
+	"github.com/sabbatD/srest-api/internal/lib/fields"
+	"github.com/sabbatD/srest-api/internal/lib/logger/sl"
+	"github.com/sabbatD/srest-api/internal/lib/moderation"
+	t "github.com/sabbatD/srest-api/internal/lib/todoConfig"
+	"github.com/sabbatD/srest-api/internal/lib/workflow"
+)
+
+const maxMutations = 500
+
+type SyncHandler interface {
+	TodoHandler
+	CreateWithID(ctx context.Context, publicID string, t t.TodoRequest, userID int) (int64, error)
+	SyncState(ctx context.Context, publicID string, userID int) (t.SyncState, error)
+}
+
+// Sync godoc
+// @Summary Synchronize offline changes
+// @ID syncTodos
+// @Description Applies the client's pending mutations in order and returns the server changes since the client's cursor.
+// A mutation conflicts when the task was changed on the server after the cursor. With strategy "lww" (default)
+// the client's mutation wins and is applied, with "reject" it is not applied and returned as a conflict with the server's task.
+// Creates may carry a client generated UUID, so later mutations and retries can refer to the same task.
+// Titles rejected by moderation are reported as rejected mutations with reason "content rejected",
+// invalid custom field values with reason "invalid custom field" and unknown statuses or transitions not allowed by
+// the workflow with reason "invalid status". A bare isDone maps to a status as in the update of a task.
+// Completing a task blocked by an open task is rejected with reason "blocked", a due date not as YYYY-MM-DD with reason "invalid due".
+// @Tags todo
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param SyncData body t.SyncRequest true "Pending mutations and the last cursor"
+// @Success 200 {object} t.SyncResponse "Per mutation results and the server delta."
+// @Failure 400 {object} util.Problem "Invalid request body, cursor or strategy."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 429 {object} string "Too many requests, see Retry-After."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /todos/sync [post]
+func Sync(log *slog.Logger, todo SyncHandler, mod *moderation.Moderator) http.HandlerFunc {
+	const op = "http-server.hanlders.todo.Sync"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		userID, err := contextUser(r)
+		if err != nil {
+			return nil, err
+		}
+
+		var req t.SyncRequest
+		if err := util.DecodeJSON(r, &req); err != nil {
+			return nil, err
+		}
+
+		log.Info("request body decoded")
+		log.Debug("req: ", slog.Any("request", req))
+
+		var since int64
+		if req.Cursor != "" {
+			since, err = strconv.ParseInt(req.Cursor, 10, 64)
+			if err != nil || since < 0 {
+				return nil, util.NewError(http.StatusBadRequest, util.CodeBadRequest, "Invalid cursor")
+			}
+		}
+
+		switch req.Strategy {
+		case "":
+			req.Strategy = t.StrategyLastWriteWins
+		case t.StrategyLastWriteWins, t.StrategyReject:
+		default:
+			return nil, util.NewError(http.StatusBadRequest, util.CodeBadRequest, "Invalid strategy: must be lww or reject")
+		}
+
+		if len(req.Mutations) > maxMutations {
+			return nil, util.NewError(http.StatusBadRequest, util.CodeBadRequest, fmt.Sprintf("Too many mutations: at most %d per request", maxMutations))
+		}
+
+		schema, err := todo.TodoFields(r.Context(), userID)
+		if err != nil {
+			return nil, err
+		}
+		wf, err := todo.TodoWorkflow(r.Context(), userID)
+		if err != nil {
+			return nil, err
+		}
+
+		results := make([]t.SyncResult, 0, len(req.Mutations))
+		for _, m := range req.Mutations {
+			res, err := applyMutation(r.Context(), todo, mod, schema, wf, userID, since, req.Strategy, m)
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, res)
+		}
+
+		changes, cursor, err := todo.Changes(r.Context(), userID, since, changesLimit)
+		if err != nil {
+			return nil, err
+		}
+
+		log.Info("successfully synchronized tasks", slog.Int("mutations", len(req.Mutations)))
+
+		return t.SyncResponse{
+			Results: results,
+			Changes: changes,
+			Cursor:  strconv.FormatInt(cursor, 10),
+			HasMore: len(changes) == changesLimit,
+		}, nil
+	})
+}
+
+// applyMutation applies a single mutation. Expected outcomes (conflicts, invalid mutations)
+// are reported in the result, the error is for storage failures only.
+func applyMutation(ctx context.Context, todo SyncHandler, mod *moderation.Moderator, schema fields.Schema, wf workflow.Workflow, userID int, since int64, strategy string, m t.Mutation) (t.SyncResult, error) {
+	res := t.SyncResult{ID: m.ID}
+
+	var verdict moderation.Verdict
+	if m.Op == t.OpCreate || m.Op == t.OpUpdate {
+		var err error
+		if verdict, err = mod.Check(ctx, m.Title); err != nil {
+			res.Status, res.Reason = t.SyncRejected, "content rejected"
+			return res, nil
+		}
+		if !validDue(m.Due) {
+			res.Status, res.Reason = t.SyncRejected, "invalid due"
+			return res, nil
+		}
+	}
+	// record flags content that was written
+	record := func(task t.Todo) {
+		mod.Record(ctx, verdict, moderation.Flag{Kind: moderation.KindTodoTitle, UserID: userID, Ref: task.PublicID, Text: m.Title})
+	}
+
+	if m.ID != "" && !util.IsUUID(m.ID) {
+		res.Status, res.Reason = t.SyncRejected, "invalid id"
+		return res, nil
+	}
+	if m.ID == "" && m.Op != t.OpCreate {
+		res.Status, res.Reason = t.SyncRejected, "missing id"
+		return res, nil
+	}
+
+	var state t.SyncState
+	exists := false
+	if m.ID != "" {
+		var err error
+		state, err = todo.SyncState(ctx, m.ID, userID)
+		switch {
+		case err == nil:
+			exists = true
+		case !errors.Is(err, sdb.ErrNotFound):
+			return res, err
+		}
+	}
+
+	// conflict reports the server's side of a conflicting mutation
+	conflict := func(reason string) (t.SyncResult, error) {
+		res.Status, res.Reason = t.SyncConflict, reason
+		if exists && !state.Deleted {
+			task, err := todo.GetTodo(ctx, state.ID, userID)
+			if err != nil {
+				return res, err
+			}
+			res.Todo = &task
+		}
+		return res, nil
+	}
+	modified := exists && state.Version > since
+
+	switch m.Op {
+	case t.OpCreate:
+		if exists {
+			if state.Deleted {
+				return conflict("deleted")
+			}
+			// Retried create, already applied.
+			res.Status = t.SyncApplied
+			return res, nil
+		}
+
+		custom, err := schema.Apply(nil, m.Custom, fields.All)
+		if err != nil {
+			res.Status, res.Reason = t.SyncRejected, "invalid custom field"
+			return res, nil
+		}
+
+		req := t.TodoRequest{Title: m.Title, Status: m.Status, IsDone: m.IsDone, Due: m.Due, Custom: custom}
+		if err := resolveStatus(wf, "", &req); err != nil {
+			res.Status, res.Reason = t.SyncRejected, "invalid status"
+			return res, nil
+		}
+		var id int64
+		if m.ID != "" {
+			id, err = todo.CreateWithID(ctx, m.ID, req, userID)
+		} else {
+			id, err = todo.Create(ctx, req, userID)
+		}
+		if errors.Is(err, sdb.ErrAlreadyExists) {
+			res.Status, res.Reason = t.SyncRejected, "id is taken"
+			return res, nil
+		}
+		if errors.Is(err, sdb.ErrLimitReached) {
+			res.Status, res.Reason = t.SyncRejected, "limit reached"
+			return res, nil
+		}
+		if err != nil {
+			return res, err
+		}
+
+		task, err := todo.GetTodo(ctx, int(id), userID)
+		if err != nil {
+			return res, err
+		}
+		record(task)
+		res.ID, res.Status, res.Todo = task.PublicID, t.SyncApplied, &task
+		return res, nil
+
+	case t.OpUpdate:
+		if !exists {
+			return conflict("not found")
+		}
+		if state.Deleted {
+			return conflict("deleted")
+		}
+		if modified && strategy == t.StrategyReject {
+			return conflict("modified")
+		}
+
+		req := t.TodoRequest{Title: m.Title, Status: m.Status, IsDone: m.IsDone, Due: m.Due, Custom: m.Custom}
+		if len(m.Custom) > 0 || m.Status != "" || m.IsDone != nil {
+			before, err := todo.GetTodo(ctx, state.ID, userID)
+			if err != nil {
+				return res, err
+			}
+			if len(m.Custom) > 0 {
+				if _, err := schema.Apply(before.Custom, m.Custom, fields.All); err != nil {
+					res.Status, res.Reason = t.SyncRejected, "invalid custom field"
+					return res, nil
+				}
+			}
+			if err := resolveStatus(wf, before.Status, &req); err != nil {
+				res.Status, res.Reason = t.SyncRejected, "invalid status"
+				return res, nil
+			}
+			if completesBlocked(before, req) {
+				res.Status, res.Reason = t.SyncRejected, "blocked"
+				return res, nil
+			}
+		}
+
+		if _, err := todo.Update(ctx, state.ID, userID, req); err != nil {
+			return res, err
+		}
+		task, err := todo.GetTodo(ctx, state.ID, userID)
+		if err != nil {
+			return res, err
+		}
+		record(task)
+		res.Status, res.Todo = t.SyncApplied, &task
+		return res, nil
+
+	case t.OpDelete:
+		if !exists || state.Deleted {
+			res.Status = t.SyncApplied
+			return res, nil
+		}
+		if modified && strategy == t.StrategyReject {
+			return conflict("modified")
+		}
+
+		if _, err := todo.Delete(ctx, state.ID, userID); err != nil && !errors.Is(err, sdb.ErrNotFound) {
+			return res, err
+		}
+		res.Status = t.SyncApplied
+		return res, nil
+
+	default:
+		res.Status, res.Reason = t.SyncRejected, "unknown op"
+		return res, nil
+	}
+}
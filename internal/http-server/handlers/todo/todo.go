@@ -4,273 +4,649 @@
 package todo
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 
-	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
+	sdb "github.com/sabbatD/srest-api/internal/database"
 	util "github.com/sabbatD/srest-api/internal/http-server/handleUtil"
-	"github.com/sabbatD/srest-api/internal/lib/api/validation"
+	"github.com/sabbatD/srest-api/internal/lib/api/access"
+	"github.com/sabbatD/srest-api/internal/lib/api/stream"
+	"github.com/sabbatD/srest-api/internal/lib/cache"
+	"github.com/sabbatD/srest-api/internal/lib/clock"
+	"github.com/sabbatD/srest-api/internal/lib/fields"
+	"github.com/sabbatD/srest-api/internal/lib/flight"
 	"github.com/sabbatD/srest-api/internal/lib/logger/sl"
+	"github.com/sabbatD/srest-api/internal/lib/moderation"
 	t "github.com/sabbatD/srest-api/internal/lib/todoConfig"
+	"github.com/sabbatD/srest-api/internal/lib/workflow"
 )
 
 type TodoHandler interface {
-	Create(t t.TodoRequest) (int64, error)
-	Update(id int, t t.TodoRequest) (int64, error)
-	Delete(id int) (int64, error)
-	GetTodo(id int) (t.Todo, error)
-	OutputAll(filter string) ([]t.Todo, t.TodoInfo, int, error)
+	Create(ctx context.Context, t t.TodoRequest, userID int) (int64, error)
+	Update(ctx context.Context, id, userID int, t t.TodoRequest) (int64, error)
+	Delete(ctx context.Context, id, userID int) (int64, error)
+	GetTodo(ctx context.Context, id, userID int) (t.Todo, error)
+	EachTodo(ctx context.Context, q t.TodoQuery, userID int, fn func(t.Todo) error) (t.TodoInfo, error)
+	TodoID(ctx context.Context, publicID string, userID int) (int, error)
+	Changes(ctx context.Context, userID int, since int64, limit int) ([]t.Change, int64, error)
+	TodoFields(ctx context.Context, userID int) (fields.Schema, error)
+	SetTodoFields(ctx context.Context, userID int, schema fields.Schema) error
+	TodoWorkflow(ctx context.Context, userID int) (workflow.Workflow, error)
+	SetTodoWorkflow(ctx context.Context, userID int, w workflow.Workflow) error
+	AddBlocker(ctx context.Context, id, blocker, userID int) error
+	RemoveBlocker(ctx context.Context, id, blocker, userID int) error
+	TodoCalendar(ctx context.Context, userID int, from, to time.Time) ([]t.CalendarDay, error)
+	CreateTodoFilter(ctx context.Context, userID int, f t.FilterRequest) (t.SavedFilter, error)
+	TodoFilters(ctx context.Context, userID int) ([]t.SavedFilter, error)
+	TodoFilter(ctx context.Context, publicID string, userID int) (t.SavedFilter, error)
+	DeleteTodoFilter(ctx context.Context, publicID string, userID int) error
+	PinTodo(ctx context.Context, id, userID int, pinned bool) error
+	DuplicateTodo(ctx context.Context, id, userID int) (int64, error)
+	TodoCompletions(ctx context.Context, userID int, from, to time.Time) ([]t.HeatmapDay, error)
+}
+
+const (
+	changesLimit   = 500
+	changesMaxWait = 30 * time.Second
+	changesPoll    = time.Second
+)
+
+// errLongList stops reading a list longer than GetAll keeps in memory
+var errLongList = errors.New("list too long to keep")
+
+// Ownership resolves the {id} path param to a task owned by the authenticated user
+// and stores its internal id in the request context.
+// Tasks of other users are reported as missing, so their existence is not leaked.
+func Ownership(log *slog.Logger, todo TodoHandler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			const op = "http-server.hanlders.todo.Ownership"
+
+			r = r.WithContext(sl.With(sl.Ensure(r.Context(), log), slog.String("op", op)))
+
+			userID, err := contextUser(r)
+			if err != nil {
+				util.WriteError(w, r, err)
+				return
+			}
+
+			id, err := util.ResolveID(r, func(ctx context.Context, publicID string) (int, error) {
+				return todo.TodoID(ctx, publicID, userID)
+			}, "No such task")
+			if err != nil {
+				util.WriteError(w, r, err)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), access.CxtKey("todoID"), id)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
 }
 
 // Create godoc
 // @Summary Create a new task
+// @ID createTodo
 // @Description Creates a new task by accepting a JSON payload with the task's details.
+// Status is a status of the user's workflow, the initial one by default, old clients may send isDone instead.
+// Custom holds the values of the user's custom fields, required ones must be set. Due is an optional date as YYYY-MM-DD.
 // @Tags todo
+// @Security BearerAuth
 // @Accept json
 // @Produce json
 // @Param UserData body t.TodoRequest true "Task data for creating a new task"
 // @Success 200 {object}  t.Todo "Task successfully created, returns the created task."
-// @Failure 400 {object} string "Invalid request body or missing/incorrect fields."
-// @Failure 500 {object} string "Internal server error."
+// @Failure 400 {object} util.Problem "Invalid request body, missing/incorrect fields, unknown status, invalid due date or custom field values."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Todo limit of the guest session reached."
+// @Failure 422 {object} util.Problem "Title rejected by moderation."
+// @Failure 429 {object} string "Too many requests, see Retry-After."
+// @Failure 500 {object} util.Problem "Internal server error."
 // @Router /todos [post]
-func Create(log *slog.Logger, todo TodoHandler) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		const op = "http-server.hanlders.todo.Create"
+func Create(log *slog.Logger, todo TodoHandler, mod *moderation.Moderator) http.HandlerFunc {
+	const op = "http-server.hanlders.todo.Create"
 
-		log.With(util.SlogWith(op, r)...)
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
 
 		var req t.TodoRequest
-		if err := render.DecodeJSON(r.Body, &req); err != nil {
-			log.Error("failed to decode request", sl.Err(err))
-
-			http.Error(w, "failed to deserialize json request", http.StatusBadRequest)
-
-			return
+		if err := util.DecodeJSON(r, &req); err != nil {
+			return nil, err
 		}
 
 		log.Info("request body decoded")
 		log.Debug("req: ", slog.Any("request", req))
 
-		id, err := todo.Create(req)
+		userID, err := contextUser(r)
 		if err != nil {
-			util.InternalError(w, r, log, err)
-			return
+			return nil, err
 		}
 
-		task, err := todo.GetTodo(int(id))
+		if !validDue(req.Due) {
+			return nil, util.NewError(http.StatusBadRequest, util.CodeInvalidInput, "Invalid due: must be a date as YYYY-MM-DD")
+		}
+		schema, err := todo.TodoFields(r.Context(), userID)
 		if err != nil {
-			util.InternalError(w, r, log, err)
-			return
+			return nil, err
+		}
+		if req.Custom, err = schema.Apply(nil, req.Custom, fields.All); err != nil {
+			return nil, invalidInput(err)
+		}
+		wf, err := todo.TodoWorkflow(r.Context(), userID)
+		if err != nil {
+			return nil, err
+		}
+		if err := resolveStatus(wf, "", &req); err != nil {
+			return nil, invalidInput(err)
+		}
+
+		verdict, err := mod.Check(r.Context(), req.Title)
+		if err != nil {
+			return nil, util.WrapError(err, http.StatusUnprocessableEntity, util.CodeRejected, "Title was rejected by moderation")
+		}
+
+		id, err := todo.Create(r.Context(), req, userID)
+		if err != nil {
+			if errors.Is(err, sdb.ErrLimitReached) {
+				return nil, util.WrapError(err, http.StatusForbidden, util.CodeLimit, "Todo limit reached, sign up to create more")
+			}
+			return nil, err
+		}
+
+		task, err := todo.GetTodo(r.Context(), int(id), userID)
+		if err != nil {
+			return nil, err
 		}
 
+		mod.Record(r.Context(), verdict, moderation.Flag{Kind: moderation.KindTodoTitle, UserID: userID, Ref: task.PublicID, Text: req.Title})
+
 		log.Info("successfully created task")
 
-		render.JSON(w, r, task)
-	}
+		return task, nil
+	})
 }
 
 // Get All godoc
 // @Summary Retrieve all tasks
-// @Description Retrieves all tasks with optional filtering by status (e.g., completed or in-progress).
+// @ID listTodos
+// @Description Retrieves all tasks with optional filtering by status (e.g., completed or in-progress). Pinned tasks come first.
 // @Tags todo
+// @Security BearerAuth
 // @Produce json
-// @Param filter query string false "Filter tasks by status: all, completed, or inWork"
+// @Param filter query string false "Filter tasks by completion: all, completed, or inWork"
+// @Param status query string false "Filter tasks by a status of the workflow"
+// @Param blocked query bool false "Filter tasks by whether a task they depend on is still open"
+// @Param due query string false "Filter tasks by due date: today, overdue (due before today, UTC) or a date as YYYY-MM-DD"
+// @Param custom.key query string false "Filter by the value of the custom field 'key', e.g. custom.priority=high; several may be given"
 // @Success 200 {object} t.MetaResponse "Tasks retrieved successfully."
-// @Failure 500 {object} string "Internal server error."
+// @Failure 400 {object} util.Problem "Unknown status, custom field or invalid value, invalid blocked or due."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 500 {object} util.Problem "Internal server error."
 // @Router /todos [get]
-func GetAll(log *slog.Logger, todo TodoHandler) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		const op = "http-server.hanlders.todo.GetAll"
+func GetAll(log *slog.Logger, todo TodoHandler, lists *cache.UserCache, reads *flight.Group, maxCached int) http.HandlerFunc {
+	const op = "http-server.hanlders.todo.GetAll"
 
-		log.With(util.SlogWith(op, r)...)
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
 
-		filter := r.URL.Query().Get("filter")
+		userID, err := contextUser(r)
+		if err != nil {
+			return nil, err
+		}
 
-		todos, info, n, err := todo.OutputAll(filter)
+		q, err := todoQuery(r.Context(), r.URL.Query(), todo, userID, clock.Now())
 		if err != nil {
-			util.InternalError(w, r, log, err)
-			return
+			return nil, err
 		}
 
-		if todos == nil {
-			todos = []t.Todo{}
+		// The resolved query, relative due dates included, identifies the list.
+		query, err := json.Marshal(q)
+		if err != nil {
+			return nil, err
 		}
+		key := lists.Key(userID, string(query))
+		if v, ok := lists.Get(key); ok {
+			if err := writeTodos(w, r, v.(t.MetaResponse)); err != nil {
+				return nil, err
+			}
+
+			log.Info("tasks served from cache")
 
-		response := t.MetaResponse{
-			Data: todos,
-			Info: info,
-			Meta: t.Meta{
-				TotalAmount: n,
-			},
+			return nil, nil
+		}
+
+		// Concurrent requests for the list share one read, lists too long to hold are streamed by each request.
+		v, err := reads.Do(r.Context(), key, func(ctx context.Context) (any, error) {
+			tasks := []t.Todo{}
+			info, err := todo.EachTodo(ctx, q, userID, func(task t.Todo) error {
+				if len(tasks) == maxCached {
+					return errLongList
+				}
+				tasks = append(tasks, task)
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			res := t.MetaResponse{Data: tasks, Info: info, Meta: t.Meta{TotalAmount: info.All}}
+			lists.Set(key, res)
+			return res, nil
+		})
+		switch {
+		case errors.Is(err, errLongList):
+			if _, err := streamTodos(w, r, todo, q, userID); err != nil {
+				return nil, err
+			}
+		case err != nil:
+			return nil, err
+		default:
+			if err := writeTodos(w, r, v.(t.MetaResponse)); err != nil {
+				return nil, err
+			}
 		}
 
 		log.Info("successfully retrieved tasks")
 
-		render.JSON(w, r, response)
+		return nil, nil
+	})
+}
+
+// todoQuery reads the GET /todos query parameters, today resolves relative due dates
+func todoQuery(ctx context.Context, params url.Values, todo TodoHandler, userID int, today time.Time) (t.TodoQuery, error) {
+	q := t.TodoQuery{Filter: params.Get("filter"), Status: params.Get("status")}
+
+	var err error
+	if q.Custom, err = customFilter(ctx, params, todo, userID); err != nil {
+		return q, err
+	}
+	if str := params.Get("blocked"); str != "" {
+		blocked, err := strconv.ParseBool(str)
+		if err != nil {
+			return q, util.NewError(http.StatusBadRequest, util.CodeBadRequest, "Invalid blocked: must be true or false")
+		}
+		q.Blocked = &blocked
 	}
+	if q.Status != "" {
+		wf, err := todo.TodoWorkflow(ctx, userID)
+		if err != nil {
+			return q, err
+		}
+		if _, ok := wf.Status(q.Status); !ok {
+			return q, util.NewError(http.StatusBadRequest, util.CodeBadRequest, fmt.Sprintf("Unknown status %q", q.Status))
+		}
+	}
+
+	switch due := params.Get("due"); due {
+	case "":
+	case "today":
+		q.DueFrom = today.UTC().Format(t.DateFormat)
+		q.DueTo = q.DueFrom
+	case "overdue":
+		q.DueTo = today.UTC().AddDate(0, 0, -1).Format(t.DateFormat)
+	default:
+		if _, err := time.Parse(t.DateFormat, due); err != nil {
+			return q, util.NewError(http.StatusBadRequest, util.CodeBadRequest, "Invalid due: must be today, overdue or a date as YYYY-MM-DD")
+		}
+		q.DueFrom, q.DueTo = due, due
+	}
+
+	return q, nil
+}
+
+// streamTodos writes the tasks matching q with the counters of the user's tasks and returns the counters.
+// Tasks are streamed, long lists do not build the whole response in memory. seen, when set, gets each task.
+func streamTodos(w http.ResponseWriter, r *http.Request, todo TodoHandler, q t.TodoQuery, userID int) (t.TodoInfo, error) {
+	var info t.TodoInfo
+	err := stream.List(w, r, func(emit stream.Emit) (err error) {
+		info, err = todo.EachTodo(r.Context(), q, userID, func(task t.Todo) error {
+			return emit(task)
+		})
+		return err
+	}, func() stream.Field {
+		return stream.Field{Key: "info", Value: info}
+	}, func() stream.Field {
+		return stream.Field{Key: "meta", Value: t.Meta{TotalAmount: info.All}}
+	})
+	return info, err
+}
+
+// writeTodos writes a list read before in the format of streamTodos
+func writeTodos(w http.ResponseWriter, r *http.Request, res t.MetaResponse) error {
+	return stream.List(w, r, func(emit stream.Emit) error {
+		for _, task := range res.Data {
+			if err := emit(task); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, func() stream.Field {
+		return stream.Field{Key: "info", Value: res.Info}
+	}, func() stream.Field {
+		return stream.Field{Key: "meta", Value: res.Meta}
+	})
+}
+
+// Changes godoc
+// @Summary Retrieve task changes since a cursor
+// @ID getChanges
+// @Description Returns tasks created, updated or deleted after the cursor, oldest first. Deleted tasks are reported by id only.
+// An empty cursor returns the whole current state. With wait > 0 the request blocks until there is at least one change
+// or wait seconds pass (long polling). Pass the returned cursor to the next call, repeat immediately while hasMore is true.
+// @Tags todo
+// @Security BearerAuth
+// @Produce json
+// @Param since query string false "Cursor returned by the previous call"
+// @Param wait query int false "Seconds to wait for changes, up to 30 (default is 0)"
+// @Success 200 {object} t.ChangesResponse "Changes retrieved successfully."
+// @Failure 400 {object} util.Problem "Invalid cursor or wait."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /todos/changes [get]
+func Changes(log *slog.Logger, todo TodoHandler) http.HandlerFunc {
+	const op = "http-server.hanlders.todo.Changes"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		userID, err := contextUser(r)
+		if err != nil {
+			return nil, err
+		}
+
+		var since int64
+		if str := r.URL.Query().Get("since"); str != "" {
+			since, err = strconv.ParseInt(str, 10, 64)
+			if err != nil || since < 0 {
+				return nil, util.NewError(http.StatusBadRequest, util.CodeBadRequest, "Invalid cursor")
+			}
+		}
+
+		var wait time.Duration
+		if str := r.URL.Query().Get("wait"); str != "" {
+			seconds, err := strconv.Atoi(str)
+			if err != nil || seconds < 0 {
+				return nil, util.NewError(http.StatusBadRequest, util.CodeBadRequest, "Invalid wait")
+			}
+			wait = min(time.Duration(seconds)*time.Second, changesMaxWait)
+		}
+
+		if wait > 0 {
+			// The server write timeout is shorter than a long poll.
+			if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(wait + changesPoll)); err != nil {
+				log.Debug(fmt.Sprintf("could not extend write deadline: %v", err))
+			}
+		}
+
+		deadline := time.Now().Add(wait)
+		for {
+			changes, cursor, err := todo.Changes(r.Context(), userID, since, changesLimit)
+			if err != nil {
+				return nil, err
+			}
+
+			if len(changes) > 0 || !time.Now().Before(deadline) {
+				log.Info("successfully retrieved changes")
+
+				return t.ChangesResponse{
+					Changes: changes,
+					Cursor:  strconv.FormatInt(cursor, 10),
+					HasMore: len(changes) == changesLimit,
+				}, nil
+			}
+
+			select {
+			case <-r.Context().Done():
+				return nil, nil
+			case <-time.After(changesPoll):
+			}
+		}
+	})
 }
 
 // Get godoc
 // @Summary Retrieve a task by ID
-// @Description Retrieves a specific task by its ID from the URL.
+// @ID getTodo
+// @Description Retrieves a specific task by its ID from the URL, with the ids of the tasks it depends on (blockedBy)
+// and of the tasks depending on it (blocks).
 // @Tags todo
+// @Security BearerAuth
 // @Produce json
-// @Param id path int true "ID of the task to retrieve"
+// @Param id path string true "Public ID (UUID) of the task to retrieve"
 // @Success 200 {object}  t.Todo "Task retrieved successfully."
-// @Failure 400 {object} string "Invalid or missing task ID."
-// @Failure 404 {object} string "Task not found."
-// @Failure 500 {object} string "Internal server error."
+// @Failure 400 {object} util.Problem "Invalid or missing task ID."
+// @Failure 404 {object} util.Problem "Task not found."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 500 {object} util.Problem "Internal server error."
 // @Router /todos/{id} [get]
 func Get(log *slog.Logger, todo TodoHandler) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		const op = "http-server.hanlders.todo.Get"
+	const op = "http-server.hanlders.todo.Get"
 
-		log.With(util.SlogWith(op, r)...)
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
 
-		id := util.GetUrlParam(w, r, log)
-		if id == 0 {
-			log.Info("missing or wrong id")
-			http.Error(w, "Missing or wrong id", http.StatusBadRequest)
-			return
+		userID, err := contextUser(r)
+		if err != nil {
+			return nil, err
 		}
+		id := contextTodo(r)
 
-		task, err := todo.GetTodo(id)
+		task, err := todo.GetTodo(r.Context(), id, userID)
 		if err != nil {
-			if err.Error() == "database.postgres.GetTodo: no such task" {
-				log.Info(err.Error())
-
-				http.Error(w, "No such task", http.StatusNotFound)
-
-				return
-			}
-			util.InternalError(w, r, log, err)
-			return
+			return nil, util.NotFound(err, "No such task")
 		}
 
 		log.Info("successfully retrieved task")
 
-		render.JSON(w, r, task)
-	}
+		return task, nil
+	})
 }
 
 // Update godoc
 // @Summary Update an existing task
+// @ID updateTodo
 // @Description Updates an existing task by accepting a JSON payload with the updated task details.
+// Status moves the task to another status of the workflow, only allowed transitions are accepted.
+// Old clients may send isDone instead, it moves the task to the first done or the initial status.
+// A task cannot be completed while a task it depends on is open.
+// Custom holds the changed custom field values, null removes one. Due sets the due date, an empty string removes it.
 // @Tags todo
+// @Security BearerAuth
 // @Accept json
 // @Produce json
-// @Param id path int true "ID of the task to update"
+// @Param id path string true "Public ID (UUID) of the task to update"
 // @Param UserData body t.TodoRequest true "Updated task data"
 // @Success 200 {object}  t.Todo "Task updated successfully, returns the updated task."
-// @Failure 400 {object} string "Invalid request body, missing/incorrect fields, or invalid ID."
-// @Failure 404 {object} string "Task not found."
-// @Failure 500 {object} string "Internal server error."
+// @Failure 400 {object} util.Problem "Invalid request body, missing/incorrect fields, transition not allowed, invalid due date, custom field values or ID."
+// @Failure 404 {object} util.Problem "Task not found."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 409 {object} util.Problem "The task is blocked by an open task."
+// @Failure 429 {object} string "Too many requests, see Retry-After."
+// @Failure 422 {object} util.Problem "Title rejected by moderation."
+// @Failure 500 {object} util.Problem "Internal server error."
 // @Router /todos/{id} [put]
-func Update(log *slog.Logger, todo TodoHandler) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		const op = "http-server.hanlders.todo.Update"
+func Update(log *slog.Logger, todo TodoHandler, mod *moderation.Moderator) http.HandlerFunc {
+	const op = "http-server.hanlders.todo.Update"
 
-		log.With(
-			slog.String("op", op),
-			slog.String("request_id", middleware.GetReqID(r.Context())),
-		)
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
 
 		var req t.TodoRequest
-		if err := render.DecodeJSON(r.Body, &req); err != nil {
-			log.Error("failed to decode request", sl.Err(err))
-
-			http.Error(w, "failed to deserialize json request", http.StatusBadRequest)
-
-			return
+		if err := util.DecodeJSON(r, &req); err != nil {
+			return nil, err
 		}
 
 		log.Info("request body decoded")
 		log.Debug("req: ", slog.Any("request", req))
 
-		validation.InitValidator()
-		if err := validation.ValidateStruct(req); err != nil {
-			log.Debug(fmt.Sprintf("validation failed: %v", err.Error()))
-
-			http.Error(w, fmt.Sprintf("Invalid input: %v", err.Error()), http.StatusBadRequest)
-
-			return
+		if err := util.Validate(req); err != nil {
+			return nil, err
+		}
+		if !validDue(req.Due) {
+			return nil, util.NewError(http.StatusBadRequest, util.CodeInvalidInput, "Invalid due: must be a date as YYYY-MM-DD")
 		}
 
 		log.Info("input validated")
 
-		id := util.GetUrlParam(w, r, log)
-		if id == 0 {
-			log.Info("missing or wrong id")
-			http.Error(w, "Missing or wrong id", http.StatusBadRequest)
-			return
+		userID, err := contextUser(r)
+		if err != nil {
+			return nil, err
 		}
+		id := contextTodo(r)
 
-		n, err := todo.Update(id, req)
-		if err != nil {
-			if n == 0 {
-				log.Info(err.Error())
+		if len(req.Custom) > 0 || req.Status != "" || req.IsDone != nil {
+			before, err := todo.GetTodo(r.Context(), id, userID)
+			if err != nil {
+				return nil, util.NotFound(err, "No such task")
+			}
+			if len(req.Custom) > 0 {
+				schema, err := todo.TodoFields(r.Context(), userID)
+				if err != nil {
+					return nil, err
+				}
+				if _, err := schema.Apply(before.Custom, req.Custom, fields.All); err != nil {
+					return nil, invalidInput(err)
+				}
+			}
+			if req.Status != "" || req.IsDone != nil {
+				wf, err := todo.TodoWorkflow(r.Context(), userID)
+				if err != nil {
+					return nil, err
+				}
+				if err := resolveStatus(wf, before.Status, &req); err != nil {
+					return nil, invalidInput(err)
+				}
+				if completesBlocked(before, req) {
+					return nil, util.NewError(http.StatusConflict, util.CodeConflict, "The task is blocked by an open task")
+				}
+			}
+		}
 
-				http.Error(w, "No such task", http.StatusNotFound)
+		verdict, err := mod.Check(r.Context(), req.Title)
+		if err != nil {
+			return nil, util.WrapError(err, http.StatusUnprocessableEntity, util.CodeRejected, "Title was rejected by moderation")
+		}
 
-				return
-			}
-			util.InternalError(w, r, log, err)
-			return
+		if _, err := todo.Update(r.Context(), id, userID, req); err != nil {
+			return nil, util.NotFound(err, "No such task")
 		}
 
-		task, err := todo.GetTodo(id)
+		task, err := todo.GetTodo(r.Context(), id, userID)
 		if err != nil {
-			util.InternalError(w, r, log, err)
-			return
+			return nil, util.NotFound(err, "No such task")
 		}
 
+		mod.Record(r.Context(), verdict, moderation.Flag{Kind: moderation.KindTodoTitle, UserID: userID, Ref: task.PublicID, Text: req.Title})
+
 		log.Info("successfully updated task")
 
-		render.JSON(w, r, task)
-	}
+		return task, nil
+	})
 }
 
 // Delete godoc
 // @Summary Delete a task by ID
+// @ID deleteTodo
 // @Description Deletes a task by its ID from the URL.
 // @Tags todo
+// @Security BearerAuth
 // @Produce json
-// @Param id path int true "ID of the task to delete"
+// @Param id path string true "Public ID (UUID) of the task to delete"
 // @Success 200 {object} string "Task deleted successfully."
-// @Failure 400 {object} string "Invalid or missing task ID."
-// @Failure 404 {object} string "Task not found."
-// @Failure 500 {object} string "Internal server error."
+// @Failure 400 {object} util.Problem "Invalid or missing task ID."
+// @Failure 404 {object} util.Problem "Task not found."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 429 {object} string "Too many requests, see Retry-After."
+// @Failure 500 {object} util.Problem "Internal server error."
 // @Router /todos/{id} [delete]
 func Delete(log *slog.Logger, todo TodoHandler) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		const op = "http-server.hanlders.todo.Delete"
-
-		log.With(util.SlogWith(op, r)...)
+	const op = "http-server.hanlders.todo.Delete"
 
-		id := util.GetUrlParam(w, r, log)
-		if id == 0 {
-			log.Info("missing or wrong id")
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
 
-			http.Error(w, "Missing or wrong id", http.StatusBadRequest)
+		userID, err := contextUser(r)
+		if err != nil {
+			return nil, err
+		}
+		id := contextTodo(r)
 
-			return
+		if _, err := todo.Delete(r.Context(), id, userID); err != nil {
+			return nil, util.NotFound(err, "No such task")
 		}
 
-		n, err := todo.Delete(id)
-		if err != nil {
-			if n == 0 {
-				log.Info(err.Error())
+		log.Info("successfully deleted task")
+
+		return nil, nil
+	})
+}
 
-				http.Error(w, "No such task", http.StatusNotFound)
+// Duplicate godoc
+// @Summary Duplicate a task
+// @ID duplicateTodo
+// @Description Creates a copy of the task with a new ID: its title, status, custom field values, due date and the tasks it depends on (blockedBy).
+// The copy is not pinned and no task depends on it. Guests' copies count towards their todo limit.
+// @Tags todo
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Public ID (UUID) of the task to duplicate"
+// @Success 201 {object} t.Todo "Task duplicated, returns the copy."
+// @Failure 400 {object} util.Problem "Invalid task ID."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Todo limit of the guest session reached."
+// @Failure 404 {object} util.Problem "Task not found."
+// @Failure 429 {object} string "Too many requests, see Retry-After."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /todos/{id}/duplicate [post]
+func Duplicate(log *slog.Logger, todo TodoHandler) http.HandlerFunc {
+	const op = "http-server.hanlders.todo.Duplicate"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		userID, err := contextUser(r)
+		if err != nil {
+			return nil, err
+		}
+		id := contextTodo(r)
 
-				return
+		copyID, err := todo.DuplicateTodo(r.Context(), id, userID)
+		if err != nil {
+			if errors.Is(err, sdb.ErrLimitReached) {
+				return nil, util.WrapError(err, http.StatusForbidden, util.CodeLimit, "Todo limit reached, sign up to create more")
 			}
-			util.InternalError(w, r, log, err)
-			return
+			return nil, util.NotFound(err, "No such task")
 		}
 
-		log.Info("successfully retrieved task")
+		task, err := todo.GetTodo(r.Context(), int(copyID), userID)
+		if err != nil {
+			return nil, err
+		}
+
+		log.Info("successfully duplicated task", slog.String("copy", task.PublicID))
+
+		render.Status(r, http.StatusCreated)
+		return task, nil
+	})
+}
+
+func contextUser(r *http.Request) (int, error) {
+	userContext, ok := r.Context().Value(access.CxtKey("userContext")).(access.UserContext)
+	if !ok {
+		return 0, util.NewError(http.StatusUnauthorized, util.CodeUnauthorized, "User context not found")
 	}
+	return userContext.UserId, nil
+}
+
+func contextTodo(r *http.Request) int {
+	id, _ := r.Context().Value(access.CxtKey("todoID")).(int)
+	return id
 }
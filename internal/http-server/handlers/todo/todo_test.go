@@ -0,0 +1,1077 @@
+package todo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	sdb "github.com/sabbatD/srest-api/internal/database"
+	util "github.com/sabbatD/srest-api/internal/http-server/handleUtil"
+	"github.com/sabbatD/srest-api/internal/lib/api/access"
+	"github.com/sabbatD/srest-api/internal/lib/cache"
+	"github.com/sabbatD/srest-api/internal/lib/clock"
+	"github.com/sabbatD/srest-api/internal/lib/fields"
+	"github.com/sabbatD/srest-api/internal/lib/flight"
+	t "github.com/sabbatD/srest-api/internal/lib/todoConfig"
+	"github.com/sabbatD/srest-api/internal/lib/workflow"
+)
+
+// memTodos mirrors the user_id scoping and the todo limits of the postgres storage.
+type memTodos struct {
+	todos     map[int]t.Todo
+	owners    map[int]int
+	limits    map[int]int
+	schemas   map[int]fields.Schema
+	workflows map[int]workflow.Workflow
+	// blockers of each todo
+	blockers map[int]map[int]bool
+	filters  []memFilter
+	// completions are returned by TodoCompletions, calls counts its calls
+	completions []t.HeatmapDay
+	calls       int
+	// lists counts EachTodo calls
+	lists int
+}
+
+type memFilter struct {
+	t.SavedFilter
+	owner int
+}
+
+func newMemTodos() *memTodos {
+	return &memTodos{
+		todos: map[int]t.Todo{}, owners: map[int]int{}, limits: map[int]int{},
+		schemas: map[int]fields.Schema{}, workflows: map[int]workflow.Workflow{}, blockers: map[int]map[int]bool{},
+	}
+}
+
+func (m *memTodos) Create(ctx context.Context, req t.TodoRequest, userID int) (int64, error) {
+	if limit, ok := m.limits[userID]; ok {
+		n := 0
+		for _, owner := range m.owners {
+			if owner == userID {
+				n++
+			}
+		}
+		if n >= limit {
+			return 0, fmt.Errorf("todo %w", sdb.ErrLimitReached)
+		}
+	}
+	id := len(m.todos) + 1
+	todo := t.Todo{ID: uint(id), PublicID: fmt.Sprintf("00000000-0000-0000-0000-%012d", id), Title: req.Title, Status: req.Status, Custom: req.Custom}
+	if req.IsDone != nil {
+		todo.IsDone = *req.IsDone
+	}
+	if req.Due != nil {
+		todo.Due = *req.Due
+	}
+	m.todos[id], m.owners[id] = todo, userID
+	return int64(id), nil
+}
+
+func (m *memTodos) Update(ctx context.Context, id, userID int, req t.TodoRequest) (int64, error) {
+	todo, err := m.GetTodo(ctx, id, userID)
+	if err != nil {
+		return 0, err
+	}
+	if req.Title != "" {
+		todo.Title = req.Title
+	}
+	if req.Status != "" {
+		todo.Status = req.Status
+	}
+	if req.IsDone != nil {
+		todo.IsDone = *req.IsDone
+	}
+	if req.Due != nil {
+		todo.Due = *req.Due
+	}
+	custom := map[string]any{}
+	for k, v := range todo.Custom {
+		custom[k] = v
+	}
+	for k, v := range req.Custom {
+		if v == nil {
+			delete(custom, k)
+		} else {
+			custom[k] = v
+		}
+	}
+	todo.Custom = custom
+	m.todos[id] = todo
+	return 1, nil
+}
+
+func (m *memTodos) Delete(ctx context.Context, id, userID int) (int64, error) {
+	if _, err := m.GetTodo(ctx, id, userID); err != nil {
+		return 0, err
+	}
+	delete(m.todos, id)
+	return 1, nil
+}
+
+func (m *memTodos) GetTodo(ctx context.Context, id, userID int) (t.Todo, error) {
+	todo, ok := m.todos[id]
+	if !ok || m.owners[id] != userID {
+		return t.Todo{}, sdb.ErrNotFound
+	}
+	todo.Blocked = m.blocked(id)
+	for blocker := range m.blockers[id] {
+		todo.BlockedBy = append(todo.BlockedBy, m.todos[blocker].PublicID)
+	}
+	for other, blockers := range m.blockers {
+		if blockers[id] {
+			todo.Blocks = append(todo.Blocks, m.todos[other].PublicID)
+		}
+	}
+	return todo, nil
+}
+
+func (m *memTodos) blocked(id int) bool {
+	for blocker := range m.blockers[id] {
+		if todo, ok := m.todos[blocker]; ok && !todo.IsDone {
+			return true
+		}
+	}
+	return false
+}
+
+// dependsOn tells whether id depends on other, directly or through other todos
+func (m *memTodos) dependsOn(id, other int) bool {
+	for blocker := range m.blockers[id] {
+		if blocker == other || m.dependsOn(blocker, other) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *memTodos) AddBlocker(ctx context.Context, id, blocker, userID int) error {
+	if id == blocker || m.dependsOn(blocker, id) {
+		return sdb.ErrConflict
+	}
+	if m.blockers[id] == nil {
+		m.blockers[id] = map[int]bool{}
+	}
+	m.blockers[id][blocker] = true
+	return nil
+}
+
+func (m *memTodos) RemoveBlocker(ctx context.Context, id, blocker, userID int) error {
+	if !m.blockers[id][blocker] {
+		return sdb.ErrNotFound
+	}
+	delete(m.blockers[id], blocker)
+	return nil
+}
+
+func (m *memTodos) EachTodo(ctx context.Context, q t.TodoQuery, userID int, fn func(t.Todo) error) (t.TodoInfo, error) {
+	m.lists++
+	var info t.TodoInfo
+	for id, todo := range m.todos {
+		if m.owners[id] != userID {
+			continue
+		}
+		info.All++
+		todo.Blocked = m.blocked(id)
+		match := (q.Status == "" || todo.Status == q.Status) && (q.Blocked == nil || *q.Blocked == todo.Blocked)
+		for k, v := range q.Custom {
+			match = match && todo.Custom[k] == v
+		}
+		if q.DueFrom != "" || q.DueTo != "" {
+			match = match && todo.Due != "" && todo.Due >= q.DueFrom && (q.DueTo == "" || todo.Due <= q.DueTo)
+		}
+		if !match {
+			continue
+		}
+		if err := fn(todo); err != nil {
+			return info, err
+		}
+	}
+	return info, nil
+}
+
+func (m *memTodos) TodoID(ctx context.Context, publicID string, userID int) (int, error) {
+	for id, todo := range m.todos {
+		if todo.PublicID == publicID && m.owners[id] == userID {
+			return id, nil
+		}
+	}
+	return 0, sdb.ErrNotFound
+}
+
+func (m *memTodos) Changes(ctx context.Context, userID int, since int64, limit int) ([]t.Change, int64, error) {
+	return nil, since, nil
+}
+
+func (m *memTodos) TodoFields(ctx context.Context, userID int) (fields.Schema, error) {
+	return m.schemas[userID], nil
+}
+
+func (m *memTodos) SetTodoFields(ctx context.Context, userID int, schema fields.Schema) error {
+	m.schemas[userID] = schema
+	return nil
+}
+
+func (m *memTodos) TodoWorkflow(ctx context.Context, userID int) (workflow.Workflow, error) {
+	if w, ok := m.workflows[userID]; ok {
+		return w, nil
+	}
+	return workflow.Default(), nil
+}
+
+func (m *memTodos) SetTodoWorkflow(ctx context.Context, userID int, w workflow.Workflow) error {
+	m.workflows[userID] = w
+	return nil
+}
+
+func (m *memTodos) TodoCalendar(ctx context.Context, userID int, from, to time.Time) ([]t.CalendarDay, error) {
+	days := []t.CalendarDay{}
+	for id := 1; id <= len(m.todos); id++ {
+		todo, ok := m.todos[id]
+		if !ok || m.owners[id] != userID || todo.Due < from.Format(t.DateFormat) || todo.Due > to.Format(t.DateFormat) {
+			continue
+		}
+		i := 0
+		for i < len(days) && days[i].Date < todo.Due {
+			i++
+		}
+		if i == len(days) || days[i].Date != todo.Due {
+			days = append(days[:i], append([]t.CalendarDay{{Date: todo.Due}}, days[i:]...)...)
+		}
+		days[i].Total++
+		if todo.IsDone {
+			days[i].Completed++
+		}
+		days[i].Todos = append(days[i].Todos, todo)
+	}
+	return days, nil
+}
+
+func (m *memTodos) CreateTodoFilter(ctx context.Context, userID int, f t.FilterRequest) (t.SavedFilter, error) {
+	for _, o := range m.filters {
+		if o.owner == userID && strings.EqualFold(o.Name, f.Name) {
+			return t.SavedFilter{}, fmt.Errorf("filter name %w", sdb.ErrAlreadyExists)
+		}
+	}
+	id := len(m.filters) + 1
+	saved := t.SavedFilter{ID: id, PublicID: fmt.Sprintf("10000000-0000-0000-0000-%012d", id), Name: f.Name, Query: f.Query}
+	m.filters = append(m.filters, memFilter{saved, userID})
+	return saved, nil
+}
+
+func (m *memTodos) TodoFilters(ctx context.Context, userID int) ([]t.SavedFilter, error) {
+	filters := []t.SavedFilter{}
+	for _, f := range m.filters {
+		if f.owner == userID {
+			filters = append(filters, f.SavedFilter)
+		}
+	}
+	return filters, nil
+}
+
+func (m *memTodos) TodoFilter(ctx context.Context, publicID string, userID int) (t.SavedFilter, error) {
+	for _, f := range m.filters {
+		if f.owner == userID && f.PublicID == publicID {
+			return f.SavedFilter, nil
+		}
+	}
+	return t.SavedFilter{}, sdb.ErrNotFound
+}
+
+func (m *memTodos) DeleteTodoFilter(ctx context.Context, publicID string, userID int) error {
+	for i, f := range m.filters {
+		if f.owner == userID && f.PublicID == publicID {
+			m.filters = append(m.filters[:i], m.filters[i+1:]...)
+			return nil
+		}
+	}
+	return sdb.ErrNotFound
+}
+
+func (m *memTodos) PinTodo(ctx context.Context, id, userID int, pinned bool) error {
+	todo, err := m.GetTodo(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+	if todo.Pinned == pinned {
+		return nil
+	}
+	n := 0
+	for other, o := range m.todos {
+		if m.owners[other] == userID && o.Pinned {
+			n++
+		}
+	}
+	if pinned && n >= sdb.MaxPinnedTodos {
+		return fmt.Errorf("pinned todo %w", sdb.ErrLimitReached)
+	}
+	todo = m.todos[id]
+	todo.Pinned = pinned
+	m.todos[id] = todo
+	return nil
+}
+
+func (m *memTodos) DuplicateTodo(ctx context.Context, id, userID int) (int64, error) {
+	todo, err := m.GetTodo(ctx, id, userID)
+	if err != nil {
+		return 0, err
+	}
+	copyID, err := m.Create(ctx, t.TodoRequest{Title: todo.Title, Status: todo.Status, IsDone: &todo.IsDone, Due: &todo.Due, Custom: todo.Custom}, userID)
+	if err != nil {
+		return 0, err
+	}
+	for blocker := range m.blockers[id] {
+		m.AddBlocker(ctx, int(copyID), blocker, userID)
+	}
+	return copyID, nil
+}
+
+func (m *memTodos) TodoCompletions(ctx context.Context, userID int, from, to time.Time) ([]t.HeatmapDay, error) {
+	m.calls++
+	days := []t.HeatmapDay{}
+	for _, d := range m.completions {
+		if d.Date >= from.Format(t.DateFormat) && d.Date < to.Format(t.DateFormat) {
+			days = append(days, d)
+		}
+	}
+	return days, nil
+}
+
+func newRouter(storage TodoHandler) http.Handler {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	versions := cache.NewVersions()
+	lists := cache.NewUserCache(cache.New("todos", time.Minute, 100), versions)
+
+	router := chi.NewRouter()
+	router.Route("/todos", func(r chi.Router) {
+		r.Use(access.GuestAuthMiddleware)
+		r.Use(versions.Middleware(access.UserID))
+
+		r.Post("/", Create(log, storage, nil))
+		r.Get("/", GetAll(log, storage, lists, flight.New("todos"), 10))
+		r.Get("/calendar", Calendar(log, storage))
+		r.Get("/heatmap", Heatmap(log, storage, cache.New("heatmap", HeatmapTTL, HeatmapMaxEntries)))
+		r.Get("/filters", Filters(log, storage))
+		r.Post("/filters", CreateFilter(log, storage))
+		r.Get("/filters/{filter}/todos", ApplyFilter(log, storage))
+		r.Delete("/filters/{filter}", DeleteFilter(log, storage))
+		r.Get("/fields", Fields(log, storage))
+		r.Put("/fields", SetFields(log, storage))
+		r.Get("/workflow", Workflow(log, storage))
+		r.Put("/workflow", SetWorkflow(log, storage))
+
+		r.Route("/{id}", func(r chi.Router) {
+			r.Use(Ownership(log, storage))
+
+			r.Get("/", Get(log, storage))
+			r.Put("/", Update(log, storage, nil))
+			r.Delete("/", Delete(log, storage))
+			r.Post("/pin", Pin(log, storage))
+			r.Post("/unpin", Unpin(log, storage))
+			r.Post("/duplicate", Duplicate(log, storage))
+			r.Put("/blocked-by/{blocker}", AddBlocker(log, storage))
+			r.Delete("/blocked-by/{blocker}", RemoveBlocker(log, storage))
+		})
+	})
+	return router
+}
+
+func do(tt *testing.T, h http.Handler, userID int, method, path, body string) *httptest.ResponseRecorder {
+	tt.Helper()
+
+	token, err := access.NewAccessToken(userID, false, false)
+	if err != nil {
+		tt.Fatal(err)
+	}
+	return doWithToken(h, token, method, path, body)
+}
+
+func doWithToken(h http.Handler, token, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCrossUserAccess(tt *testing.T) {
+	const owner, stranger = 1, 2
+
+	storage := newMemTodos()
+	h := newRouter(storage)
+
+	rec := do(tt, h, owner, http.MethodPost, "/todos", `{"title":"secret"}`)
+	if rec.Code != http.StatusOK {
+		tt.Fatalf("create: status = %d, body = %s", rec.Code, rec.Body)
+	}
+	var created t.Todo
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		tt.Fatal(err)
+	}
+	path := "/todos/" + created.PublicID
+
+	tests := []struct {
+		name   string
+		userID int
+		method string
+		body   string
+		want   int
+	}{
+		{name: "stranger get", userID: stranger, method: http.MethodGet, want: http.StatusNotFound},
+		{name: "stranger update", userID: stranger, method: http.MethodPut, body: `{"title":"pwned"}`, want: http.StatusNotFound},
+		{name: "stranger delete", userID: stranger, method: http.MethodDelete, want: http.StatusNotFound},
+		{name: "owner get", userID: owner, method: http.MethodGet, want: http.StatusOK},
+	}
+	for _, tc := range tests {
+		tt.Run(tc.name, func(tt *testing.T) {
+			if rec := do(tt, h, tc.userID, tc.method, path, tc.body); rec.Code != tc.want {
+				tt.Errorf("status = %d, want %d, body = %s", rec.Code, tc.want, rec.Body)
+			}
+		})
+	}
+
+	id, err := storage.TodoID(context.Background(), created.PublicID, owner)
+	if err != nil {
+		tt.Fatal(err)
+	}
+	if got := storage.todos[id].Title; got != "secret" {
+		tt.Errorf("title = %q, stranger must not modify the task", got)
+	}
+
+	rec = do(tt, h, stranger, http.MethodGet, "/todos", "")
+	var list t.MetaResponse
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		tt.Fatal(err)
+	}
+	if len(list.Data) != 0 {
+		tt.Errorf("stranger sees %d tasks, want 0", len(list.Data))
+	}
+}
+
+func TestGuestLimit(tt *testing.T) {
+	const guest = 3
+
+	storage := newMemTodos()
+	storage.limits[guest] = 1
+	h := newRouter(storage)
+
+	token, _, err := access.NewGuestToken(guest, time.Hour)
+	if err != nil {
+		tt.Fatal(err)
+	}
+
+	if rec := doWithToken(h, token, http.MethodPost, "/todos", `{"title":"first"}`); rec.Code != http.StatusOK {
+		tt.Fatalf("first todo: status = %d, body = %s", rec.Code, rec.Body)
+	}
+	rec := doWithToken(h, token, http.MethodPost, "/todos", `{"title":"second"}`)
+	var p util.Problem
+	json.NewDecoder(rec.Body).Decode(&p)
+	if rec.Code != http.StatusForbidden || p.Code != util.CodeLimit {
+		tt.Errorf("todo over the limit: status = %d, code = %q", rec.Code, p.Code)
+	}
+
+	// Routes without guest access refuse the token.
+	users := access.JWTAuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	if rec := doWithToken(users, token, http.MethodGet, "/user/profile", ""); rec.Code != http.StatusForbidden {
+		tt.Errorf("guest on a user route: status = %d, want 403", rec.Code)
+	}
+}
+
+func TestMustChangePassword(tt *testing.T) {
+	const owner = 1
+
+	token, err := access.NewAccessToken(owner, false, true)
+	if err != nil {
+		tt.Fatal(err)
+	}
+
+	rec := doWithToken(newRouter(newMemTodos()), token, http.MethodGet, "/todos", "")
+	var problem util.Problem
+	json.NewDecoder(rec.Body).Decode(&problem)
+	if rec.Code != http.StatusForbidden || problem.Code != util.CodePasswordChange {
+		tt.Errorf("todos: status = %d, code = %q, want 403 %s", rec.Code, problem.Code, util.CodePasswordChange)
+	}
+
+	// Only the password change admits the token.
+	change := access.PasswordChangeMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	if rec := doWithToken(change, token, http.MethodPut, "/user/profile/reset-password", ""); rec.Code != http.StatusOK {
+		tt.Errorf("password change: status = %d, want 200", rec.Code)
+	}
+}
+
+// denylist revokes every token with a JTI, or fails with err
+type denylist struct{ err error }
+
+func (d denylist) TokenRevoked(ctx context.Context, jti string) (bool, error) {
+	return jti != "", d.err
+}
+
+func TestRevokedToken(tt *testing.T) {
+	const owner = 1
+
+	token, err := access.NewAccessToken(owner, false, false)
+	if err != nil {
+		tt.Fatal(err)
+	}
+	h := newRouter(newMemTodos())
+
+	if rec := doWithToken(h, token, http.MethodGet, "/todos", ""); rec.Code != http.StatusOK {
+		tt.Fatalf("before logout: status = %d, want 200", rec.Code)
+	}
+
+	restore := access.SetDenylist(denylist{})
+	tt.Cleanup(restore)
+	if rec := doWithToken(h, token, http.MethodGet, "/todos", ""); rec.Code != http.StatusUnauthorized {
+		tt.Errorf("revoked: status = %d, want 401", rec.Code)
+	}
+
+	// The token is not let through when the denylist cannot be checked
+	access.SetDenylist(denylist{err: errors.New("db is down")})
+	if rec := doWithToken(h, token, http.MethodGet, "/todos", ""); rec.Code != http.StatusInternalServerError {
+		tt.Errorf("denylist error: status = %d, want 500", rec.Code)
+	}
+}
+
+func TestCustomFields(tt *testing.T) {
+	const owner, stranger = 1, 2
+
+	storage := newMemTodos()
+	h := newRouter(storage)
+
+	rec := do(tt, h, owner, http.MethodPut, "/todos/fields", `{"fields":[
+		{"key":"priority","type":"select","options":["low","high"],"required":true},
+		{"key":"estimate","type":"number"}
+	]}`)
+	if rec.Code != http.StatusOK {
+		tt.Fatalf("set fields: status = %d, body = %s", rec.Code, rec.Body)
+	}
+	if rec := do(tt, h, owner, http.MethodPut, "/todos/fields", `{"fields":[{"key":"Bad Key","type":"text"}]}`); rec.Code != http.StatusBadRequest {
+		tt.Errorf("invalid key: status = %d, want 400", rec.Code)
+	}
+
+	for name, body := range map[string]string{
+		"missing required": `{"title":"a"}`,
+		"unknown option":   `{"title":"a","custom":{"priority":"urgent"}}`,
+		"unknown field":    `{"title":"a","custom":{"priority":"low","owner":"me"}}`,
+	} {
+		var p util.Problem
+		rec := do(tt, h, owner, http.MethodPost, "/todos", body)
+		json.NewDecoder(rec.Body).Decode(&p)
+		if rec.Code != http.StatusBadRequest || p.Code != util.CodeInvalidInput {
+			tt.Errorf("%s: status = %d, code = %q, want 400 %s", name, rec.Code, p.Code, util.CodeInvalidInput)
+		}
+	}
+
+	rec = do(tt, h, owner, http.MethodPost, "/todos", `{"title":"urgent","custom":{"priority":"high","estimate":2}}`)
+	if rec.Code != http.StatusOK {
+		tt.Fatalf("create: status = %d, body = %s", rec.Code, rec.Body)
+	}
+	var created t.Todo
+	json.NewDecoder(rec.Body).Decode(&created)
+	if created.Custom["priority"] != "high" || created.Custom["estimate"] != 2.0 {
+		tt.Errorf("custom = %v", created.Custom)
+	}
+	if rec := do(tt, h, owner, http.MethodPost, "/todos", `{"title":"later","custom":{"priority":"low"}}`); rec.Code != http.StatusOK {
+		tt.Fatalf("create: status = %d, body = %s", rec.Code, rec.Body)
+	}
+
+	rec = do(tt, h, owner, http.MethodPut, "/todos/"+created.PublicID, `{"custom":{"estimate":null}}`)
+	var updated t.Todo
+	json.NewDecoder(rec.Body).Decode(&updated)
+	if _, ok := updated.Custom["estimate"]; rec.Code != http.StatusOK || ok {
+		tt.Errorf("remove value: status = %d, custom = %v", rec.Code, updated.Custom)
+	}
+	if rec := do(tt, h, owner, http.MethodPut, "/todos/"+created.PublicID, `{"custom":{"priority":null}}`); rec.Code != http.StatusBadRequest {
+		tt.Errorf("remove required value: status = %d, want 400", rec.Code)
+	}
+
+	rec = do(tt, h, owner, http.MethodGet, "/todos?custom.priority=high", "")
+	var list t.MetaResponse
+	json.NewDecoder(rec.Body).Decode(&list)
+	if len(list.Data) != 1 || list.Data[0].PublicID != created.PublicID {
+		tt.Errorf("filtered list = %+v", list.Data)
+	}
+	if rec := do(tt, h, owner, http.MethodGet, "/todos?custom.color=red", ""); rec.Code != http.StatusBadRequest {
+		tt.Errorf("unknown filter field: status = %d, want 400", rec.Code)
+	}
+	// Fields are per user.
+	if rec := do(tt, h, stranger, http.MethodGet, "/todos?custom.priority=high", ""); rec.Code != http.StatusBadRequest {
+		tt.Errorf("filter by another user's field: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestWorkflow(tt *testing.T) {
+	const owner = 1
+
+	storage := newMemTodos()
+	h := newRouter(storage)
+
+	create := func(body string) t.Todo {
+		tt.Helper()
+		rec := do(tt, h, owner, http.MethodPost, "/todos", body)
+		if rec.Code != http.StatusOK {
+			tt.Fatalf("create: status = %d, body = %s", rec.Code, rec.Body)
+		}
+		var todo t.Todo
+		json.NewDecoder(rec.Body).Decode(&todo)
+		return todo
+	}
+	update := func(todo t.Todo, body string) (t.Todo, int) {
+		tt.Helper()
+		rec := do(tt, h, owner, http.MethodPut, "/todos/"+todo.PublicID, body)
+		var updated t.Todo
+		json.NewDecoder(rec.Body).Decode(&updated)
+		return updated, rec.Code
+	}
+
+	if todo := create(`{"title":"default"}`); todo.Status != workflow.StatusBacklog || todo.IsDone {
+		tt.Errorf("new task: status = %q, isDone = %v", todo.Status, todo.IsDone)
+	}
+	// Old clients complete tasks through isDone.
+	if todo := create(`{"title":"legacy","isDone":true}`); todo.Status != workflow.StatusDone || !todo.IsDone {
+		tt.Errorf("legacy done task: status = %q, isDone = %v", todo.Status, todo.IsDone)
+	}
+
+	rec := do(tt, h, owner, http.MethodPut, "/todos/workflow", `{"statuses":[
+		{"key":"todo","next":["doing"]},
+		{"key":"doing","next":["todo","review"]},
+		{"key":"review","next":["doing","shipped"]},
+		{"key":"shipped","done":true}
+	]}`)
+	if rec.Code != http.StatusOK {
+		tt.Fatalf("set workflow: status = %d, body = %s", rec.Code, rec.Body)
+	}
+	if rec := do(tt, h, owner, http.MethodPut, "/todos/workflow", `{"statuses":[{"key":"todo"}]}`); rec.Code != http.StatusBadRequest {
+		tt.Errorf("workflow without a done status: status = %d, want 400", rec.Code)
+	}
+
+	todo := create(`{"title":"feature"}`)
+	if todo.Status != "todo" {
+		tt.Errorf("new task: status = %q, want the first open status", todo.Status)
+	}
+	if _, code := update(todo, `{"status":"shipped"}`); code != http.StatusBadRequest {
+		tt.Errorf("transition not allowed: status = %d, want 400", code)
+	}
+	if _, code := update(todo, `{"status":"blocked"}`); code != http.StatusBadRequest {
+		tt.Errorf("unknown status: status = %d, want 400", code)
+	}
+	todo, _ = update(todo, `{"status":"doing"}`)
+	todo, _ = update(todo, `{"status":"review"}`)
+	if todo, code := update(todo, `{"status":"shipped"}`); code != http.StatusOK || !todo.IsDone {
+		tt.Errorf("move to a done status: status = %d, task = %+v", code, todo)
+	}
+
+	// isDone skips the transition checks, old clients do not know the workflow.
+	reopened, code := update(todo, `{"isDone":false}`)
+	if code != http.StatusOK || reopened.Status != "todo" || reopened.IsDone {
+		tt.Errorf("legacy reopen: status = %d, task = %+v", code, reopened)
+	}
+
+	rec = do(tt, h, owner, http.MethodGet, "/todos?status=todo", "")
+	var list t.MetaResponse
+	json.NewDecoder(rec.Body).Decode(&list)
+	if len(list.Data) != 1 || list.Data[0].PublicID != todo.PublicID {
+		tt.Errorf("filtered list = %+v", list.Data)
+	}
+	if rec := do(tt, h, owner, http.MethodGet, "/todos?status=backlog", ""); rec.Code != http.StatusBadRequest {
+		tt.Errorf("filter by a status outside the workflow: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestDependencies(tt *testing.T) {
+	const owner, stranger = 1, 2
+
+	storage := newMemTodos()
+	h := newRouter(storage)
+
+	create := func(title string) t.Todo {
+		tt.Helper()
+		rec := do(tt, h, owner, http.MethodPost, "/todos", `{"title":"`+title+`"}`)
+		var todo t.Todo
+		json.NewDecoder(rec.Body).Decode(&todo)
+		return todo
+	}
+	release, deploy := create("release"), create("deploy")
+	blockedBy := func(todo, blocker t.Todo) string {
+		return "/todos/" + todo.PublicID + "/blocked-by/" + blocker.PublicID
+	}
+
+	rec := do(tt, h, owner, http.MethodPut, blockedBy(deploy, release), "")
+	var got t.Todo
+	json.NewDecoder(rec.Body).Decode(&got)
+	if rec.Code != http.StatusOK || !got.Blocked || len(got.BlockedBy) != 1 || got.BlockedBy[0] != release.PublicID {
+		tt.Fatalf("add blocker: status = %d, task = %+v", rec.Code, got)
+	}
+	rec = do(tt, h, owner, http.MethodGet, "/todos/"+release.PublicID, "")
+	json.NewDecoder(rec.Body).Decode(&got)
+	if len(got.Blocks) != 1 || got.Blocks[0] != deploy.PublicID {
+		tt.Errorf("blocking task: blocks = %v", got.Blocks)
+	}
+
+	if rec := do(tt, h, owner, http.MethodPut, blockedBy(release, deploy), ""); rec.Code != http.StatusConflict {
+		tt.Errorf("cycle: status = %d, want 409", rec.Code)
+	}
+	if rec := do(tt, h, stranger, http.MethodPut, blockedBy(deploy, release), ""); rec.Code != http.StatusNotFound {
+		tt.Errorf("stranger: status = %d, want 404", rec.Code)
+	}
+
+	rec = do(tt, h, owner, http.MethodGet, "/todos?blocked=true", "")
+	var list t.MetaResponse
+	json.NewDecoder(rec.Body).Decode(&list)
+	if len(list.Data) != 1 || list.Data[0].PublicID != deploy.PublicID {
+		tt.Errorf("blocked tasks = %+v", list.Data)
+	}
+
+	if rec := do(tt, h, owner, http.MethodPut, "/todos/"+deploy.PublicID, `{"isDone":true}`); rec.Code != http.StatusConflict {
+		tt.Errorf("complete a blocked task: status = %d, want 409", rec.Code)
+	}
+	if rec := do(tt, h, owner, http.MethodPut, "/todos/"+release.PublicID, `{"isDone":true}`); rec.Code != http.StatusOK {
+		tt.Fatalf("complete the blocker: status = %d, body = %s", rec.Code, rec.Body)
+	}
+	if rec := do(tt, h, owner, http.MethodPut, "/todos/"+deploy.PublicID, `{"isDone":true}`); rec.Code != http.StatusOK {
+		tt.Errorf("complete an unblocked task: status = %d, body = %s", rec.Code, rec.Body)
+	}
+
+	if rec := do(tt, h, owner, http.MethodDelete, blockedBy(deploy, release), ""); rec.Code != http.StatusOK {
+		tt.Errorf("remove blocker: status = %d", rec.Code)
+	}
+	if rec := do(tt, h, owner, http.MethodDelete, blockedBy(deploy, release), ""); rec.Code != http.StatusNotFound {
+		tt.Errorf("remove a missing dependency: status = %d, want 404", rec.Code)
+	}
+}
+
+func TestCalendar(tt *testing.T) {
+	const owner, stranger = 1, 2
+
+	storage := newMemTodos()
+	h := newRouter(storage)
+
+	for _, body := range []string{
+		`{"title":"report","due":"2024-10-21"}`,
+		`{"title":"review","due":"2024-10-21","isDone":true}`,
+		`{"title":"release","due":"2024-10-25"}`,
+		`{"title":"later","due":"2024-11-30"}`,
+		`{"title":"someday"}`,
+	} {
+		if rec := do(tt, h, owner, http.MethodPost, "/todos", body); rec.Code != http.StatusOK {
+			tt.Fatalf("create %s: status = %d, body = %s", body, rec.Code, rec.Body)
+		}
+	}
+	if rec := do(tt, h, stranger, http.MethodPost, "/todos", `{"title":"other","due":"2024-10-21"}`); rec.Code != http.StatusOK {
+		tt.Fatalf("create: status = %d, body = %s", rec.Code, rec.Body)
+	}
+
+	rec := do(tt, h, owner, http.MethodGet, "/todos/calendar?from=2024-10-21&to=2024-10-27", "")
+	var got t.CalendarResponse
+	json.NewDecoder(rec.Body).Decode(&got)
+	if rec.Code != http.StatusOK || len(got.Days) != 2 {
+		tt.Fatalf("calendar: status = %d, response = %+v", rec.Code, got)
+	}
+	if d := got.Days[0]; d.Date != "2024-10-21" || d.Total != 2 || d.Completed != 1 || len(d.Todos) != 2 {
+		tt.Errorf("first day = %+v", d)
+	}
+	if d := got.Days[1]; d.Date != "2024-10-25" || d.Total != 1 || d.Todos[0].Title != "release" {
+		tt.Errorf("second day = %+v", d)
+	}
+
+	for _, query := range []string{"", "?from=2024-10-21", "?from=21.10.2024&to=2024-10-27", "?from=2024-10-27&to=2024-10-21", "?from=2024-01-01&to=2025-01-01"} {
+		if rec := do(tt, h, owner, http.MethodGet, "/todos/calendar"+query, ""); rec.Code != http.StatusBadRequest {
+			tt.Errorf("calendar%s: status = %d, want 400", query, rec.Code)
+		}
+	}
+
+	if rec := do(tt, h, owner, http.MethodPost, "/todos", `{"title":"bad","due":"tomorrow"}`); rec.Code != http.StatusBadRequest {
+		tt.Errorf("invalid due: status = %d, want 400", rec.Code)
+	}
+	rec = do(tt, h, owner, http.MethodPut, "/todos/00000000-0000-0000-0000-000000000001", `{"due":""}`)
+	var updated t.Todo
+	json.NewDecoder(rec.Body).Decode(&updated)
+	if rec.Code != http.StatusOK || updated.Due != "" {
+		tt.Errorf("remove due: status = %d, task = %+v", rec.Code, updated)
+	}
+}
+
+func TestSavedFilters(tt *testing.T) {
+	const owner, stranger = 1, 2
+
+	storage := newMemTodos()
+	storage.schemas[owner] = fields.Schema{Fields: []fields.Field{{Key: "priority", Type: fields.TypeSelect, Options: []string{"low", "high"}}}}
+	h := newRouter(storage)
+
+	// The clock is frozen so today cannot change during the test.
+	tt.Cleanup(clock.Set(clock.NewFake(time.Date(2024, 10, 1, 23, 59, 59, 0, time.UTC))))
+	today := "2024-10-01"
+	for _, body := range []string{
+		`{"title":"today high","due":"` + today + `","custom":{"priority":"high"}}`,
+		`{"title":"today low","due":"` + today + `","custom":{"priority":"low"}}`,
+		`{"title":"overdue","due":"2000-01-01"}`,
+		`{"title":"someday","custom":{"priority":"high"}}`,
+	} {
+		if rec := do(tt, h, owner, http.MethodPost, "/todos", body); rec.Code != http.StatusOK {
+			tt.Fatalf("create %s: status = %d, body = %s", body, rec.Code, rec.Body)
+		}
+	}
+
+	rec := do(tt, h, owner, http.MethodPost, "/todos/filters", `{"name":"Today, high","query":{"due":"today","custom.priority":"high"}}`)
+	var saved t.SavedFilter
+	json.NewDecoder(rec.Body).Decode(&saved)
+	if rec.Code != http.StatusOK || saved.PublicID == "" || saved.Query["due"] != "today" {
+		tt.Fatalf("save: status = %d, filter = %+v", rec.Code, saved)
+	}
+
+	for name, body := range map[string]string{
+		"unknown parameter": `{"name":"a","query":{"sort":"title"}}`,
+		"unknown status":    `{"name":"b","query":{"status":"archived"}}`,
+		"unknown field":     `{"name":"c","query":{"custom.size":"xl"}}`,
+		"invalid due":       `{"name":"d","query":{"due":"tomorrow"}}`,
+		"no name":           `{"query":{}}`,
+	} {
+		if rec := do(tt, h, owner, http.MethodPost, "/todos/filters", body); rec.Code != http.StatusBadRequest {
+			tt.Errorf("%s: status = %d, want 400", name, rec.Code)
+		}
+	}
+	if rec := do(tt, h, owner, http.MethodPost, "/todos/filters", `{"name":"today, HIGH","query":{}}`); rec.Code != http.StatusConflict {
+		tt.Errorf("taken name: status = %d, want 409", rec.Code)
+	}
+
+	rec = do(tt, h, owner, http.MethodGet, "/todos/filters", "")
+	var filters []t.SavedFilter
+	json.NewDecoder(rec.Body).Decode(&filters)
+	if len(filters) != 1 || filters[0].Name != "Today, high" {
+		tt.Errorf("filters = %+v", filters)
+	}
+
+	path := "/todos/filters/" + saved.PublicID + "/todos"
+	rec = do(tt, h, owner, http.MethodGet, path, "")
+	var list t.MetaResponse
+	json.NewDecoder(rec.Body).Decode(&list)
+	if rec.Code != http.StatusOK || len(list.Data) != 1 || list.Data[0].Title != "today high" {
+		tt.Errorf("apply: status = %d, tasks = %+v", rec.Code, list.Data)
+	}
+	rec = do(tt, h, owner, http.MethodGet, "/todos?due=overdue", "")
+	json.NewDecoder(rec.Body).Decode(&list)
+	if len(list.Data) != 1 || list.Data[0].Title != "overdue" {
+		tt.Errorf("overdue tasks = %+v", list.Data)
+	}
+
+	if rec := do(tt, h, stranger, http.MethodGet, path, ""); rec.Code != http.StatusNotFound {
+		tt.Errorf("stranger apply: status = %d, want 404", rec.Code)
+	}
+	if rec := do(tt, h, stranger, http.MethodDelete, "/todos/filters/"+saved.PublicID, ""); rec.Code != http.StatusNotFound {
+		tt.Errorf("stranger delete: status = %d, want 404", rec.Code)
+	}
+
+	// A filter on a removed custom field is reported when applied.
+	storage.schemas[owner] = fields.Schema{}
+	if rec := do(tt, h, owner, http.MethodGet, path, ""); rec.Code != http.StatusBadRequest {
+		tt.Errorf("apply a stale filter: status = %d, want 400", rec.Code)
+	}
+
+	if rec := do(tt, h, owner, http.MethodDelete, "/todos/filters/"+saved.PublicID, ""); rec.Code != http.StatusOK {
+		tt.Errorf("delete: status = %d", rec.Code)
+	}
+	if rec := do(tt, h, owner, http.MethodGet, path, ""); rec.Code != http.StatusNotFound {
+		tt.Errorf("deleted filter: status = %d, want 404", rec.Code)
+	}
+}
+
+func TestPins(tt *testing.T) {
+	const owner, stranger = 1, 2
+
+	storage := newMemTodos()
+	h := newRouter(storage)
+
+	var ids []string
+	for i := 0; i <= sdb.MaxPinnedTodos; i++ {
+		rec := do(tt, h, owner, http.MethodPost, "/todos", fmt.Sprintf(`{"title":"task %d"}`, i))
+		var todo t.Todo
+		json.NewDecoder(rec.Body).Decode(&todo)
+		ids = append(ids, todo.PublicID)
+	}
+
+	rec := do(tt, h, owner, http.MethodPost, "/todos/"+ids[0]+"/pin", "")
+	var got t.Todo
+	json.NewDecoder(rec.Body).Decode(&got)
+	if rec.Code != http.StatusOK || !got.Pinned {
+		tt.Fatalf("pin: status = %d, task = %+v", rec.Code, got)
+	}
+	if rec := do(tt, h, owner, http.MethodPost, "/todos/"+ids[0]+"/pin", ""); rec.Code != http.StatusOK {
+		tt.Errorf("pin again: status = %d", rec.Code)
+	}
+	if rec := do(tt, h, stranger, http.MethodPost, "/todos/"+ids[1]+"/pin", ""); rec.Code != http.StatusNotFound {
+		tt.Errorf("stranger: status = %d, want 404", rec.Code)
+	}
+
+	for _, id := range ids[1:sdb.MaxPinnedTodos] {
+		if rec := do(tt, h, owner, http.MethodPost, "/todos/"+id+"/pin", ""); rec.Code != http.StatusOK {
+			tt.Fatalf("pin: status = %d, body = %s", rec.Code, rec.Body)
+		}
+	}
+	last := "/todos/" + ids[sdb.MaxPinnedTodos]
+	if rec := do(tt, h, owner, http.MethodPost, last+"/pin", ""); rec.Code != http.StatusForbidden {
+		tt.Errorf("over the limit: status = %d, want 403", rec.Code)
+	}
+
+	rec = do(tt, h, owner, http.MethodPost, "/todos/"+ids[0]+"/unpin", "")
+	got = t.Todo{}
+	json.NewDecoder(rec.Body).Decode(&got)
+	if rec.Code != http.StatusOK || got.Pinned {
+		tt.Errorf("unpin: status = %d, task = %+v", rec.Code, got)
+	}
+	if rec := do(tt, h, owner, http.MethodPost, last+"/pin", ""); rec.Code != http.StatusOK {
+		tt.Errorf("pin after unpin: status = %d", rec.Code)
+	}
+}
+
+func TestHeatmap(tt *testing.T) {
+	const owner = 1
+
+	storage := newMemTodos()
+	storage.completions = []t.HeatmapDay{{Date: "2023-12-31", Count: 4}, {Date: "2024-01-01", Count: 2}, {Date: "2024-06-15", Count: 3}}
+	h := newRouter(storage)
+
+	rec := do(tt, h, owner, http.MethodGet, "/todos/heatmap?year=2024", "")
+	var got t.HeatmapResponse
+	json.NewDecoder(rec.Body).Decode(&got)
+	if rec.Code != http.StatusOK || got.Year != 2024 || got.Total != 5 || len(got.Days) != 2 {
+		tt.Fatalf("heatmap: status = %d, response = %+v", rec.Code, got)
+	}
+
+	do(tt, h, owner, http.MethodGet, "/todos/heatmap?year=2024", "")
+	if storage.calls != 1 {
+		tt.Errorf("storage calls = %d, want the second request served from cache", storage.calls)
+	}
+	do(tt, h, owner+1, http.MethodGet, "/todos/heatmap?year=2024", "")
+	if storage.calls != 2 {
+		tt.Errorf("storage calls = %d, the cache is per user", storage.calls)
+	}
+
+	rec = do(tt, h, owner, http.MethodGet, "/todos/heatmap", "")
+	json.NewDecoder(rec.Body).Decode(&got)
+	if got.Year != time.Now().UTC().Year() {
+		tt.Errorf("default year = %d", got.Year)
+	}
+
+	for _, year := range []string{"abc", "1969", strconv.Itoa(time.Now().UTC().Year() + 1)} {
+		if rec := do(tt, h, owner, http.MethodGet, "/todos/heatmap?year="+year, ""); rec.Code != http.StatusBadRequest {
+			tt.Errorf("year %s: status = %d, want 400", year, rec.Code)
+		}
+	}
+}
+
+func TestListCache(tt *testing.T) {
+	const owner = 1
+
+	storage := newMemTodos()
+	h := newRouter(storage)
+
+	list := func(userID int) t.MetaResponse {
+		tt.Helper()
+		rec := do(tt, h, userID, http.MethodGet, "/todos", "")
+		var got t.MetaResponse
+		if err := json.NewDecoder(rec.Body).Decode(&got); err != nil || rec.Code != http.StatusOK {
+			tt.Fatalf("list: status = %d, err = %v", rec.Code, err)
+		}
+		return got
+	}
+
+	list(owner)
+	list(owner)
+	if storage.lists != 1 {
+		tt.Errorf("storage lists = %d, want the second request served from cache", storage.lists)
+	}
+	list(owner + 1)
+	if storage.lists != 2 {
+		tt.Errorf("storage lists = %d, the cache is per user", storage.lists)
+	}
+
+	// A read after the user's own write reflects it.
+	rec := do(tt, h, owner, http.MethodPost, "/todos", `{"title":"cached"}`)
+	var created t.Todo
+	json.NewDecoder(rec.Body).Decode(&created)
+	if got := list(owner); len(got.Data) != 1 || got.Data[0].Title != "cached" {
+		tt.Fatalf("list after create = %+v", got.Data)
+	}
+
+	do(tt, h, owner, http.MethodPut, "/todos/"+created.PublicID, `{"title":"renamed"}`)
+	if got := list(owner); len(got.Data) != 1 || got.Data[0].Title != "renamed" {
+		tt.Errorf("list after update = %+v", got.Data)
+	}
+
+	do(tt, h, owner, http.MethodDelete, "/todos/"+created.PublicID, "")
+	if got := list(owner); len(got.Data) != 0 {
+		tt.Errorf("list after delete = %+v", got.Data)
+	}
+
+	// Another user's write leaves the owner's entry in place.
+	lists := storage.lists
+	do(tt, h, owner+1, http.MethodPost, "/todos", `{"title":"other"}`)
+	list(owner)
+	if storage.lists != lists {
+		tt.Errorf("storage lists = %d, want %d", storage.lists, lists)
+	}
+
+	// Lists longer than the router's limit of 10 are streamed whole and not cached.
+	for i := 0; i < 11; i++ {
+		do(tt, h, owner, http.MethodPost, "/todos", fmt.Sprintf(`{"title":"task %d"}`, i))
+	}
+	if got := list(owner); len(got.Data) != 11 {
+		tt.Errorf("long list has %d tasks, want 11", len(got.Data))
+	}
+	lists = storage.lists
+	list(owner)
+	if storage.lists == lists {
+		tt.Error("long list served from cache")
+	}
+}
+
+func TestDuplicate(tt *testing.T) {
+	const owner, stranger = 1, 2
+
+	storage := newMemTodos()
+	h := newRouter(storage)
+
+	var design, build t.Todo
+	json.NewDecoder(do(tt, h, owner, http.MethodPost, "/todos", `{"title":"design"}`).Body).Decode(&design)
+	json.NewDecoder(do(tt, h, owner, http.MethodPost, "/todos", `{"title":"build","due":"2024-11-01"}`).Body).Decode(&build)
+	if rec := do(tt, h, owner, http.MethodPut, "/todos/"+build.PublicID+"/blocked-by/"+design.PublicID, ""); rec.Code != http.StatusOK {
+		tt.Fatalf("add blocker: status = %d, body = %s", rec.Code, rec.Body)
+	}
+
+	if rec := do(tt, h, stranger, http.MethodPost, "/todos/"+build.PublicID+"/duplicate", ""); rec.Code != http.StatusNotFound {
+		tt.Errorf("stranger: status = %d, want 404", rec.Code)
+	}
+
+	rec := do(tt, h, owner, http.MethodPost, "/todos/"+build.PublicID+"/duplicate", "")
+	var copied t.Todo
+	json.NewDecoder(rec.Body).Decode(&copied)
+	if rec.Code != http.StatusCreated {
+		tt.Fatalf("duplicate: status = %d, body = %s", rec.Code, rec.Body)
+	}
+	if copied.PublicID == build.PublicID || copied.Title != "build" || copied.Due != "2024-11-01" {
+		tt.Errorf("copy = %+v", copied)
+	}
+	if len(copied.BlockedBy) != 1 || copied.BlockedBy[0] != design.PublicID {
+		tt.Errorf("copy blocked by %v, want %s", copied.BlockedBy, design.PublicID)
+	}
+
+	storage.limits[owner] = 3
+	if rec := do(tt, h, owner, http.MethodPost, "/todos/"+build.PublicID+"/duplicate", ""); rec.Code != http.StatusForbidden {
+		tt.Errorf("over the limit: status = %d, want 403", rec.Code)
+	}
+}
@@ -0,0 +1,121 @@
+package todo
+
+import (
+	"log/slog"
+	"net/http"
+
+	util "github.com/sabbatD/srest-api/internal/http-server/handleUtil"
+	"github.com/sabbatD/srest-api/internal/lib/logger/sl"
+	t "github.com/sabbatD/srest-api/internal/lib/todoConfig"
+	"github.com/sabbatD/srest-api/internal/lib/workflow"
+)
+
+// Workflow godoc
+// @Summary Get the todo workflow
+// @ID getWorkflow
+// @Description Returns the statuses the user's tasks move through and the allowed transitions, the default workflow
+// (backlog, in_progress, done) until the user defines their own.
+// @Tags todo
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} workflow.Workflow "Workflow retrieved."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /todos/workflow [get]
+func Workflow(log *slog.Logger, todo TodoHandler) http.HandlerFunc {
+	const op = "http-server.hanlders.todo.Workflow"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		userID, err := contextUser(r)
+		if err != nil {
+			return nil, err
+		}
+
+		return todo.TodoWorkflow(r.Context(), userID)
+	})
+}
+
+// SetWorkflow godoc
+// @Summary Set the todo workflow
+// @ID setWorkflow
+// @Description Replaces the workflow of the user's tasks. New tasks start in the first open status. A status without next
+// allows every transition. Tasks in a removed status move to the first open or done status, depending on isDone.
+// @Tags todo
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param Workflow body workflow.Workflow true "Statuses with their transitions"
+// @Success 200 {object} workflow.Workflow "Workflow set."
+// @Failure 400 {object} util.Problem "Invalid request payload."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 429 {object} string "Too many requests, see Retry-After."
+// @Failure 500 {object} util.Problem "Internal server error."
+// @Router /todos/workflow [put]
+func SetWorkflow(log *slog.Logger, todo TodoHandler) http.HandlerFunc {
+	const op = "http-server.hanlders.todo.SetWorkflow"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		userID, err := contextUser(r)
+		if err != nil {
+			return nil, err
+		}
+
+		var wf workflow.Workflow
+		if err := util.DecodeJSON(r, &wf); err != nil {
+			return nil, err
+		}
+
+		log.Info("request body decoded")
+		log.Debug("req: ", slog.Any("request", wf))
+
+		if err := util.Validate(wf); err != nil {
+			return nil, err
+		}
+		if err := wf.Check(); err != nil {
+			return nil, invalidInput(err)
+		}
+
+		if err := todo.SetTodoWorkflow(r.Context(), userID, wf); err != nil {
+			return nil, err
+		}
+
+		log.Info("todo workflow set")
+
+		return wf, nil
+	})
+}
+
+// resolveStatus sets the status and isDone of req in step for a task in status current, empty for a new task.
+// An explicit status must be reachable in the workflow. Old clients only send isDone, it completes the task
+// with the first done status or reopens it in the initial status, without transition checks.
+func resolveStatus(wf workflow.Workflow, current string, req *t.TodoRequest) error {
+	if req.Status != "" {
+		s, err := wf.Move(current, req.Status)
+		if err != nil {
+			return err
+		}
+		req.IsDone = &s.Done
+		return nil
+	}
+
+	if req.IsDone == nil {
+		if current == "" {
+			req.Status = wf.Initial()
+			done := false
+			req.IsDone = &done
+		}
+		return nil
+	}
+
+	if s, ok := wf.Status(current); ok && s.Done == *req.IsDone {
+		return nil
+	}
+	if *req.IsDone {
+		req.Status = wf.Completed()
+	} else {
+		req.Status = wf.Initial()
+	}
+	return nil
+}
@@ -0,0 +1,221 @@
+package user
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/render"
+	sdb "github.com/sabbatD/srest-api/internal/database"
+	util "github.com/sabbatD/srest-api/internal/http-server/handleUtil"
+	"github.com/sabbatD/srest-api/internal/lib/clock"
+	"github.com/sabbatD/srest-api/internal/lib/geoip"
+	"github.com/sabbatD/srest-api/internal/lib/logger/sl"
+	"github.com/sabbatD/srest-api/internal/lib/mail"
+	"github.com/sabbatD/srest-api/internal/lib/metrics"
+	u "github.com/sabbatD/srest-api/internal/lib/userConfig"
+	"github.com/sabbatD/srest-api/internal/password"
+)
+
+// Reasons a sign in is held for confirmation
+const (
+	ReasonNewCountry = "new_country"
+	ReasonNewDevice  = "new_device"
+)
+
+// ConfirmationHandler keeps the sign ins waiting for confirmation, see database.Storage.CreatePendingSignIn
+type ConfirmationHandler interface {
+	SignIns(ctx context.Context, id, limit int) ([]u.SignIn, error)
+	CreatePendingSignIn(ctx context.Context, in u.PendingSignIn, sessionHash, tokenHash string) error
+	ConfirmSignIn(ctx context.Context, tokenHash string) (int, error)
+	ClaimSignIn(ctx context.Context, sessionHash string) (u.PendingSignIn, error)
+}
+
+// Confirmation holds back the tokens of a sign in from a country (NewCountry) or a device (NewDevice) missing from
+// the user's recent sign ins until the user follows the emailed link, Link followed by a token valid for TTL.
+// A device is told apart by its User-Agent. Only users with an email are asked, and only while email is enabled.
+type Confirmation struct {
+	Store      ConfirmationHandler
+	Mail       Mailer
+	NewCountry bool
+	NewDevice  bool
+	TTL        time.Duration
+	Link       string
+}
+
+// PendingSignIn answers a sign in held back until the user confirms it with the emailed link
+type PendingSignIn struct {
+	// Session asks for the tokens at POST /auth/signin/pending, it is only returned by the sign in
+	Session   string    `json:"pendingSession,omitempty"`
+	Reason    string    `json:"reason,omitempty" enums:"new_country,new_device"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// PendingSession asks for the tokens of a held sign in
+type PendingSession struct {
+	Session string `json:"pendingSession" validate:"required"`
+}
+
+func (c *Confirmation) enabled() bool {
+	return c != nil && c.Mail.Enabled()
+}
+
+// suspicious returns why the sign in needs confirming, empty when its country and device are among the recent ones.
+// The first sign in has nothing to compare with, nor have sign ins recorded before GeoIP or devices were known.
+func (c *Confirmation) suspicious(in u.SignIn, recent []u.SignIn) string {
+	seen := func(field func(u.SignIn) string) (known, same bool) {
+		for _, r := range recent {
+			known = known || field(r) != ""
+			same = same || field(r) == field(in)
+		}
+		return known, same
+	}
+
+	if known, same := seen(func(s u.SignIn) string { return s.Country }); c.NewCountry && in.Country != "" && known && !same {
+		return ReasonNewCountry
+	}
+	if known, same := seen(func(s u.SignIn) string { return s.UserAgent }); c.NewDevice && known && !same {
+		return ReasonNewDevice
+	}
+	return ""
+}
+
+// hold stores the sign in of user as pending and emails the confirmation link when it comes from a new country or device.
+// Returns nil when the sign in needs no confirmation.
+func (c *Confirmation) hold(ctx context.Context, user u.TableUser, in u.SignIn, rememberMe bool) (*PendingSignIn, error) {
+	if !c.enabled() || user.Email == "" {
+		return nil, nil
+	}
+
+	recent, err := c.Store.SignIns(ctx, user.ID, sdb.MaxSignIns)
+	if err != nil {
+		return nil, err
+	}
+	reason := c.suspicious(in, recent)
+	if reason == "" {
+		return nil, nil
+	}
+
+	session, sessionHash, err := password.NewToken()
+	if err != nil {
+		return nil, err
+	}
+	token, tokenHash, err := password.NewToken()
+	if err != nil {
+		return nil, err
+	}
+	expires := clock.Now().Add(c.TTL)
+
+	pending := u.PendingSignIn{UserID: user.ID, RememberMe: rememberMe, Expires: expires, SignIn: in}
+	if err := c.Store.CreatePendingSignIn(ctx, pending, sessionHash, tokenHash); err != nil {
+		return nil, err
+	}
+
+	location, device := geoip.Location{Country: in.Country, City: in.City}.String(), in.UserAgent
+	if location == "" {
+		location = "an unknown location"
+	}
+	if device == "" {
+		device = "an unknown device"
+	}
+	vars := map[string]string{"Username": user.Username, "Location": location, "IP": in.IP, "Device": device,
+		"Expires": expires.UTC().Format(time.RFC1123), "Link": c.Link + token}
+	if err := c.Mail.Send(ctx, []string{user.Email}, mail.TemplateConfirmSignIn, user.Locale, vars); err != nil {
+		return nil, err
+	}
+	metrics.HeldLogins.Add(reason, 1)
+
+	return &PendingSignIn{Session: session, Reason: reason, ExpiresAt: expires}, nil
+}
+
+// ConfirmSignIn godoc
+// @Summary Confirm a sign in
+// @ID confirmSignIn
+// @Description Confirms the sign in from a new country or device with the token of the link emailed when it was held.
+// The client that signed in then gets its tokens from POST /auth/signin/pending.
+// @Tags user
+// @Produce json
+// @Param token query string true "Confirmation token"
+// @Success 200 {object} string "Sign in confirmed."
+// @Failure 400 {object} util.Problem "Missing token."
+// @Failure 404 {object} util.Problem "Unknown or expired confirmation token."
+// @Failure 500 {object} util.Problem "Internal error."
+// @Router /auth/signin/confirm [get]
+func ConfirmSignIn(log *slog.Logger, Confirmations ConfirmationHandler) http.HandlerFunc {
+	const op = "http-server.handlers.user.ConfirmSignIn"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		token := r.URL.Query().Get("token")
+		if token == "" {
+			return nil, util.NewError(http.StatusBadRequest, util.CodeBadRequest, "Missing token")
+		}
+
+		userID, err := Confirmations.ConfirmSignIn(r.Context(), password.HashToken(token))
+		if err != nil {
+			return nil, util.NotFound(err, "Invalid or expired confirmation token")
+		}
+
+		log.Info("sign in confirmed", slog.Int("user_id", userID))
+
+		return nil, nil
+	})
+}
+
+// CompleteSignIn godoc
+// @Summary Complete a held sign in
+// @ID completeSignIn
+// @Description Issues the tokens of a sign in held for confirmation once the emailed link is followed, like POST /auth/signin does.
+// Until then the answer is 202, the client polls with the pendingSession of the sign in. The tokens are issued once.
+// In the cookie transport the tokens are set as HttpOnly cookies along with the CSRF cookie and left out of the body.
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param PendingSession body PendingSession true "Session of the held sign in"
+// @Success 200 {object} Tokens "Sign in confirmed. Returns a JWT token."
+// @Success 202 {object} PendingSignIn "Not confirmed yet."
+// @Failure 400 {object} util.Problem "Invalid input."
+// @Failure 404 {object} util.Problem "Unknown, completed or expired session."
+// @Failure 500 {object} util.Problem "Internal error."
+// @Router /auth/signin/pending [post]
+func CompleteSignIn(log *slog.Logger, User UserHandler, Confirmations ConfirmationHandler, signIns *SignIns, refreshTTL, rememberTTL time.Duration) http.HandlerFunc {
+	const op = "http-server.handlers.user.CompleteSignIn"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		var req PendingSession
+		if err := util.DecodeJSON(r, &req); err != nil {
+			return nil, err
+		}
+		if err := util.Validate(req); err != nil {
+			return nil, err
+		}
+
+		in, err := Confirmations.ClaimSignIn(r.Context(), password.HashToken(req.Session))
+		if err != nil {
+			return nil, util.NotFound(err, "No such pending sign in, sign in again")
+		}
+		if !in.Confirmed {
+			render.Status(r, http.StatusAccepted)
+			return PendingSignIn{ExpiresAt: in.Expires}, nil
+		}
+
+		user, err := User.Get(r.Context(), in.UserID)
+		if err != nil {
+			return nil, util.NotFound(err, "No such pending sign in, sign in again")
+		}
+
+		tokens, err := signInTokens(w, r, User, user, in.RememberMe, refreshTTL, rememberTTL)
+		if err != nil {
+			return nil, err
+		}
+		signIns.record(r, user.ID, in.SignIn)
+
+		log.Info("held sign in completed", slog.Int("id", user.ID), slog.Bool("remembered", in.RememberMe))
+
+		return tokens, nil
+	})
+}
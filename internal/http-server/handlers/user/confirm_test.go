@@ -0,0 +1,174 @@
+package user
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	sdb "github.com/sabbatD/srest-api/internal/database"
+	"github.com/sabbatD/srest-api/internal/lib/lockout"
+	"github.com/sabbatD/srest-api/internal/lib/mail"
+	u "github.com/sabbatD/srest-api/internal/lib/userConfig"
+	"github.com/sabbatD/srest-api/internal/password"
+)
+
+func TestSuspiciousSignIn(t *testing.T) {
+	c := &Confirmation{NewCountry: true, NewDevice: true}
+	recent := []u.SignIn{{Country: "GB", UserAgent: "curl/8.0"}, {Country: "DE", UserAgent: "Firefox"}}
+
+	for _, tc := range []struct {
+		name   string
+		in     u.SignIn
+		recent []u.SignIn
+		want   string
+	}{
+		{"known", u.SignIn{Country: "DE", UserAgent: "curl/8.0"}, recent, ""},
+		{"new country", u.SignIn{Country: "FR", UserAgent: "curl/8.0"}, recent, ReasonNewCountry},
+		{"new device", u.SignIn{Country: "GB", UserAgent: "Safari"}, recent, ReasonNewDevice},
+		{"unlocated", u.SignIn{UserAgent: "Firefox"}, recent, ""},
+		{"first sign in", u.SignIn{Country: "FR", UserAgent: "Safari"}, nil, ""},
+		{"recorded before devices", u.SignIn{Country: "GB", UserAgent: "Safari"}, []u.SignIn{{Country: "GB"}}, ""},
+	} {
+		if got := c.suspicious(tc.in, tc.recent); got != tc.want {
+			t.Errorf("%s: reason %q, want %q", tc.name, got, tc.want)
+		}
+	}
+
+	c.NewDevice = false
+	if got := c.suspicious(u.SignIn{Country: "GB", UserAgent: "Safari"}, recent); got != "" {
+		t.Errorf("new device with device checks off: reason %q", got)
+	}
+}
+
+type memConfirmations struct {
+	recent []u.SignIn
+	held   map[string]*u.PendingSignIn
+	tokens map[string]string
+}
+
+func (m *memConfirmations) SignIns(ctx context.Context, id, limit int) ([]u.SignIn, error) {
+	return m.recent, nil
+}
+
+func (m *memConfirmations) CreatePendingSignIn(ctx context.Context, in u.PendingSignIn, sessionHash, tokenHash string) error {
+	m.held[sessionHash], m.tokens[tokenHash] = &in, sessionHash
+	return nil
+}
+
+func (m *memConfirmations) ConfirmSignIn(ctx context.Context, tokenHash string) (int, error) {
+	in, ok := m.held[m.tokens[tokenHash]]
+	if !ok {
+		return 0, sdb.ErrNotFound
+	}
+	in.Confirmed = true
+	return in.UserID, nil
+}
+
+func (m *memConfirmations) ClaimSignIn(ctx context.Context, sessionHash string) (u.PendingSignIn, error) {
+	in, ok := m.held[sessionHash]
+	if !ok {
+		return u.PendingSignIn{}, sdb.ErrNotFound
+	}
+	if in.Confirmed {
+		delete(m.held, sessionHash)
+	}
+	return *in, nil
+}
+
+// memSignInUsers implements the part of UserHandler signing in uses
+type memSignInUsers struct {
+	UserHandler
+	user u.TableUser
+}
+
+func (m *memSignInUsers) Auth(ctx context.Context, data u.AuthData) (u.TableUser, error) {
+	if data.Password != "password" {
+		return u.TableUser{}, sdb.ErrNotFound
+	}
+	return m.user, nil
+}
+
+func (m *memSignInUsers) Get(ctx context.Context, id int) (u.TableUser, error) {
+	return m.user, nil
+}
+
+func (m *memSignInUsers) SaveRefreshToken(ctx context.Context, token string, id int, expires time.Time) error {
+	return nil
+}
+
+func TestConfirmSignIn(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	users := &memSignInUsers{user: u.TableUser{ID: 7, Username: "Alice", Email: "alice@example.com", IsVerified: true}}
+	store := &memConfirmations{recent: []u.SignIn{{UserAgent: "curl/8.0"}}, held: map[string]*u.PendingSignIn{}, tokens: map[string]string{}}
+	mailer := &memMailer{enabled: true}
+	c := &Confirmation{Store: store, Mail: mailer, NewCountry: true, NewDevice: true, TTL: time.Hour, Link: "https://example.com/confirm?token="}
+
+	auth := Auth(log, users, nil, lockout.New(nil, lockout.Config{}), nil, c, time.Hour, 24*time.Hour, true)
+	complete := CompleteSignIn(log, users, store, nil, time.Hour, 24*time.Hour)
+	signIn := func(agent string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/auth/signin", strings.NewReader(`{"login": "alice", "password": "password"}`))
+		req.Header.Set("User-Agent", agent)
+		rec := httptest.NewRecorder()
+		auth.ServeHTTP(rec, req)
+		return rec
+	}
+	poll := func(session string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		complete.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/auth/signin/pending", strings.NewReader(`{"pendingSession": "`+session+`"}`)))
+		return rec
+	}
+
+	if rec := signIn("curl/8.0"); rec.Code != http.StatusOK || len(mailer.sent) != 0 {
+		t.Fatalf("sign in from a known device: status %d, %d emails", rec.Code, len(mailer.sent))
+	}
+
+	rec := signIn("Firefox")
+	var pending PendingSignIn
+	if err := json.NewDecoder(rec.Body).Decode(&pending); err != nil || rec.Code != http.StatusAccepted || pending.Reason != ReasonNewDevice {
+		t.Fatalf("sign in from a new device: status %d, %+v, %v", rec.Code, pending, err)
+	}
+	if len(mailer.sent) != 1 || mailer.sent[0].name != mail.TemplateConfirmSignIn || mailer.sent[0].vars["Device"] != "Firefox" {
+		t.Fatalf("emails = %+v", mailer.sent)
+	}
+	if in := store.held[password.HashToken(pending.Session)]; in == nil || in.UserAgent != "Firefox" || in.Method != MethodPassword {
+		t.Fatalf("held sign in = %+v", in)
+	}
+
+	if rec := poll(pending.Session); rec.Code != http.StatusAccepted {
+		t.Errorf("poll before confirmation: status %d, want 202", rec.Code)
+	}
+
+	confirm := ConfirmSignIn(log, store)
+	token, _ := strings.CutPrefix(mailer.sent[0].vars["Link"], "https://example.com/confirm?token=")
+	rec = httptest.NewRecorder()
+	confirm.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/auth/signin/confirm?token="+pending.Session, nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("confirm with the session: status %d, want 404", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	confirm.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/auth/signin/confirm?token="+token, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("confirm: status %d", rec.Code)
+	}
+
+	rec = poll(pending.Session)
+	var tokens Tokens
+	if err := json.NewDecoder(rec.Body).Decode(&tokens); err != nil || rec.Code != http.StatusOK || tokens.AccessToken.Token == "" {
+		t.Fatalf("poll after confirmation: status %d, %+v, %v", rec.Code, tokens, err)
+	}
+	if rec := poll(pending.Session); rec.Code != http.StatusNotFound {
+		t.Errorf("poll of a completed sign in: status %d, want 404", rec.Code)
+	}
+
+	// Without email nobody can confirm, sign ins go through
+	mailer.enabled = false
+	if rec := signIn("Safari"); rec.Code != http.StatusOK {
+		t.Errorf("sign in with email disabled: status %d, want 200", rec.Code)
+	}
+}
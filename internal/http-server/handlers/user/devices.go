@@ -0,0 +1,197 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	sdb "github.com/sabbatD/srest-api/internal/database"
+	util "github.com/sabbatD/srest-api/internal/http-server/handleUtil"
+	"github.com/sabbatD/srest-api/internal/lib/api/access"
+	"github.com/sabbatD/srest-api/internal/lib/clock"
+	"github.com/sabbatD/srest-api/internal/lib/logger/sl"
+	u "github.com/sabbatD/srest-api/internal/lib/userConfig"
+	"github.com/sabbatD/srest-api/internal/password"
+)
+
+// deviceCookie holds the secret a remembered device's refresh token is bound to, it is only sent to /auth
+const deviceCookie = "sapi_device"
+
+// maxDeviceName bounds the user agent stored as the name of a remembered device
+const maxDeviceName = 200
+
+// DeviceHandler lists and revokes remembered devices
+type DeviceHandler interface {
+	Devices(ctx context.Context, id int) ([]u.Device, error)
+	ForgetDevice(ctx context.Context, publicID string, id int) error
+}
+
+// Devices godoc
+// @Summary List remembered devices
+// @ID listDevices
+// @Description Returns the devices the user signed in on with rememberMe whose refresh token did not expire, the most recently used first.
+// The name is the user agent of the sign in.
+// @Tags user
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} u.Device "Remembered devices."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 500 {object} util.Problem "Internal error."
+// @Router /user/devices [get]
+func Devices(log *slog.Logger, Devices DeviceHandler) http.HandlerFunc {
+	const op = "http-server.handlers.user.Devices"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		userID, err := contextUser(r)
+		if err != nil {
+			return nil, err
+		}
+
+		return Devices.Devices(r.Context(), userID)
+	})
+}
+
+// ForgetDevice godoc
+// @Summary Revoke a remembered device
+// @ID revokeDevice
+// @Description Revokes the refresh token of a remembered device, it has to sign in again once its access token expires.
+// @Tags user
+// @Produce json
+// @Security BearerAuth
+// @Param device path string true "Public ID (UUID) of the device"
+// @Success 200 {object} string "Device revoked."
+// @Failure 400 {object} util.Problem "Invalid device ID."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 404 {object} util.Problem "No such device."
+// @Failure 500 {object} util.Problem "Internal error."
+// @Router /user/devices/{device} [delete]
+func ForgetDevice(log *slog.Logger, Devices DeviceHandler) http.HandlerFunc {
+	const op = "http-server.handlers.user.ForgetDevice"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		userID, err := contextUser(r)
+		if err != nil {
+			return nil, err
+		}
+		publicID := chi.URLParam(r, "device")
+		if !util.IsUUID(publicID) {
+			return nil, util.NewError(http.StatusBadRequest, util.CodeInvalidID, "Missing or wrong device id")
+		}
+
+		if err := Devices.ForgetDevice(r.Context(), publicID, userID); err != nil {
+			return nil, util.NotFound(err, "No such device")
+		}
+
+		log.Info("device revoked", slog.String("device", publicID))
+
+		return nil, nil
+	})
+}
+
+// rememberDevice issues tokens with a refresh token valid for ttl and bound to the device cookie.
+// A device cookie the client already holds is kept, so signing in again replaces the device.
+func rememberDevice(w http.ResponseWriter, r *http.Request, User UserHandler, user u.TableUser, ttl time.Duration) (Tokens, error) {
+	accessToken, err := access.NewRoleToken(user.ID, user.Role, user.Permissions, user.MustChangePassword)
+	if err != nil {
+		return Tokens{}, fmt.Errorf("could not generate JWT accessToken: %w", err)
+	}
+
+	refreshToken, refreshHash, err := password.NewToken()
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	secret := ""
+	if cookie, err := r.Cookie(deviceCookie); err == nil && cookie.Value != "" {
+		secret = cookie.Value
+	} else if secret, _, err = password.NewToken(); err != nil {
+		return Tokens{}, err
+	}
+
+	name := []rune(r.UserAgent())
+	if len(name) > maxDeviceName {
+		name = name[:maxDeviceName]
+	}
+
+	expires := clock.Now().Add(ttl)
+	deviceID, err := User.RememberDevice(r.Context(), user.ID, string(name), refreshHash, password.HashToken(secret), expires)
+	if err != nil {
+		return Tokens{}, err
+	}
+	setDeviceCookie(w, secret, expires)
+
+	return Tokens{
+		AccessToken:        AccessToken{accessToken},
+		RefreshToken:       RefreshToken{refreshToken},
+		MustChangePassword: user.MustChangePassword,
+		DeviceID:           deviceID,
+	}, nil
+}
+
+// refreshDevice replaces the refresh token of the remembered device holding the secret and extends it by ttl
+func refreshDevice(w http.ResponseWriter, r *http.Request, User UserHandler, token, secret string, ttl time.Duration) (Tokens, error) {
+	log := sl.FromContext(r.Context())
+
+	refreshToken, refreshHash, err := password.NewToken()
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	expires := clock.Now().Add(ttl)
+	id, deviceID, err := User.RefreshDevice(r.Context(), password.HashToken(token), password.HashToken(secret), refreshHash, expires)
+	if err != nil {
+		if errors.Is(err, sdb.ErrNotFound) {
+			return Tokens{}, util.WrapError(err, http.StatusUnauthorized, util.CodeUnauthorized, "Invalid credentials: token is expired - must auth again")
+		}
+		return Tokens{}, err
+	}
+
+	user, err := User.Get(r.Context(), id)
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	accessToken, err := access.NewRoleToken(user.ID, user.Role, user.Permissions, user.MustChangePassword)
+	if err != nil {
+		return Tokens{}, fmt.Errorf("could not generate JWT accessToken: %w", err)
+	}
+	setDeviceCookie(w, secret, expires)
+
+	log.Info("successfully refreshed remembered device", slog.String("device", deviceID))
+
+	return Tokens{
+		AccessToken:        AccessToken{accessToken},
+		RefreshToken:       RefreshToken{refreshToken},
+		MustChangePassword: user.MustChangePassword,
+		DeviceID:           deviceID,
+	}, nil
+}
+
+func setDeviceCookie(w http.ResponseWriter, secret string, expires time.Time) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     deviceCookie,
+		Value:    secret,
+		Path:     "/auth",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+func clearDeviceCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     deviceCookie,
+		Path:     "/auth",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
@@ -0,0 +1,173 @@
+package user
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log/slog"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+
+	sdb "github.com/sabbatD/srest-api/internal/database"
+	util "github.com/sabbatD/srest-api/internal/http-server/handleUtil"
+	"github.com/sabbatD/srest-api/internal/lib/logger/sl"
+	"github.com/sabbatD/srest-api/internal/lib/oauth"
+	"github.com/sabbatD/srest-api/internal/password"
+)
+
+// stateCookie holds the state of a sign in with a provider until it redirects back to the callback
+const stateCookie = "sapi_oauth_state"
+
+// stateTTL bounds the time a user has to sign in at the provider
+const stateTTL = 600
+
+// SocialLogin signs users in with Google or GitHub, see oauth.Client
+type SocialLogin interface {
+	AuthURL(provider, state string) (string, error)
+	Exchange(ctx context.Context, provider, code string) (oauth.Identity, error)
+}
+
+// OAuthLogin godoc
+// @Summary Sign in with Google or GitHub
+// @ID oauthLogin
+// @Description Redirects to the sign in page of the provider, which redirects back to GET /auth/{provider}/callback with a code.
+// The state of the sign in is kept in an HttpOnly cookie the callback checks. Providers are enabled by configuring their client,
+// GET /meta lists them in authMethods.
+// @Tags user
+// @Param provider path string true "Provider" Enums(google, github)
+// @Success 302 {string} string "Redirect to the provider."
+// @Failure 404 {object} util.Problem "Provider not configured."
+// @Failure 500 {object} util.Problem "Internal error."
+// @Router /auth/{provider}/login [get]
+func OAuthLogin(log *slog.Logger, Social SocialLogin) http.HandlerFunc {
+	const op = "http-server.handlers.user.OAuthLogin"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		state, _, err := password.NewToken()
+		if err != nil {
+			return nil, err
+		}
+
+		target, err := Social.AuthURL(chi.URLParam(r, "provider"), state)
+		if err != nil {
+			if errors.Is(err, oauth.ErrUnknownProvider) {
+				return nil, util.WrapError(err, http.StatusNotFound, util.CodeNotFound, "Sign in with this provider is not available")
+			}
+			return nil, err
+		}
+
+		http.SetCookie(w, &http.Cookie{
+			Name:     stateCookie,
+			Value:    state,
+			Path:     path.Dir(r.URL.Path),
+			MaxAge:   stateTTL,
+			HttpOnly: true,
+			Secure:   true,
+			// Lax, the provider redirects back from another site
+			SameSite: http.SameSiteLaxMode,
+		})
+		http.Redirect(w, r, target, http.StatusFound)
+		return nil, nil
+	})
+}
+
+// OAuthCallback godoc
+// @Summary Complete a sign in with Google or GitHub
+// @ID oauthCallback
+// @Description Exchanges the code the provider redirected back with for the user's identity and returns the tokens of the local account.
+// On the first sign in the identity is linked to the account with the same email when the provider and sAPI both verified it,
+// otherwise an account without a password is created with the login <provider>-<id>. Later sign ins use the linked account.
+// An email that belongs to an account it cannot be linked to is refused with 409, the user signs in to that account instead.
+// When email verification is required, accounts whose email is not verified are refused with 403 EMAIL_NOT_VERIFIED.
+// A sign in from a new country or device is held with 202 like POST /auth/signin, the tokens come from POST /auth/signin/pending.
+// In the cookie transport the tokens are set as HttpOnly cookies along with the CSRF cookie and left out of the body.
+// @Tags user
+// @Produce json
+// @Param provider path string true "Provider" Enums(google, github)
+// @Param code query string true "Code from the provider"
+// @Param state query string true "State from the provider, it has to match the state cookie"
+// @Success 200 {object} Tokens "Authentication successful."
+// @Success 202 {object} PendingSignIn "Sign in held until it is confirmed by email."
+// @Failure 400 {object} util.Problem "Missing code or state not matching the state cookie."
+// @Failure 401 {object} util.Problem "Sign in refused or failed at the provider."
+// @Failure 403 {object} util.Problem "No email at the provider, or email not verified."
+// @Failure 404 {object} util.Problem "Provider not configured."
+// @Failure 409 {object} util.Problem "Email of an account the identity cannot be linked to."
+// @Failure 500 {object} util.Problem "Internal error."
+// @Router /auth/{provider}/callback [get]
+func OAuthCallback(log *slog.Logger, User UserHandler, Social SocialLogin, signIns *SignIns, confirm *Confirmation, refreshTTL time.Duration, requireVerified bool) http.HandlerFunc {
+	const op = "http-server.handlers.user.OAuthCallback"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		provider := chi.URLParam(r, "provider")
+		q := r.URL.Query()
+
+		cookie, err := r.Cookie(stateCookie)
+		if err != nil || q.Get("state") == "" || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(q.Get("state"))) != 1 {
+			return nil, util.NewError(http.StatusBadRequest, util.CodeBadRequest, "Sign in state does not match, start the sign in again")
+		}
+		http.SetCookie(w, &http.Cookie{Name: stateCookie, Path: path.Dir(r.URL.Path), MaxAge: -1, HttpOnly: true, Secure: true, SameSite: http.SameSiteLaxMode})
+
+		if e := q.Get("error"); e != "" {
+			signIns.failed(r)
+			return nil, util.NewError(http.StatusUnauthorized, util.CodeUnauthorized, "Sign in was refused at the provider: "+e)
+		}
+		if q.Get("code") == "" {
+			return nil, util.NewError(http.StatusBadRequest, util.CodeBadRequest, "Missing code")
+		}
+
+		id, err := Social.Exchange(r.Context(), provider, q.Get("code"))
+		switch {
+		case errors.Is(err, oauth.ErrUnknownProvider):
+			return nil, util.WrapError(err, http.StatusNotFound, util.CodeNotFound, "Sign in with this provider is not available")
+		case errors.Is(err, oauth.ErrNoEmail):
+			return nil, util.WrapError(err, http.StatusForbidden, util.CodeForbidden, "The provider account has no email")
+		case err != nil:
+			signIns.failed(r)
+			log.Warn("sign in with provider failed", slog.String("provider", provider), sl.Err(err))
+			return nil, util.WrapError(err, http.StatusUnauthorized, util.CodeUnauthorized, "Sign in with the provider failed")
+		}
+
+		user, err := User.OAuthUser(r.Context(), id)
+		if err != nil {
+			if errors.Is(err, sdb.ErrAlreadyExists) {
+				return nil, util.WrapError(err, http.StatusConflict, util.CodeConflict, "An account with this email already exists, sign in to it")
+			}
+			return nil, err
+		}
+		if requireVerified && !user.IsVerified {
+			return nil, util.NewError(http.StatusForbidden, util.CodeUnverified, "Email is not verified, verify it at the provider")
+		}
+
+		// The provider vouches for the account, not for the country or device it is used from
+		in := signIns.signIn(r, provider)
+		pending, err := confirm.hold(r.Context(), user, in, false)
+		if err != nil {
+			return nil, err
+		}
+		if pending != nil {
+			log.Info("sign in with provider held for confirmation", slog.String("provider", provider), slog.String("reason", pending.Reason))
+			render.Status(r, http.StatusAccepted)
+			return pending, nil
+		}
+
+		tokens, err := issueTokens(r.Context(), User, user, refreshTTL)
+		if err != nil {
+			return nil, err
+		}
+		if tokens, err = sendTokens(w, tokens, refreshTTL); err != nil {
+			return nil, err
+		}
+		signIns.record(r, user.ID, in)
+
+		log.Info("signed in with provider", slog.String("provider", provider), slog.Int("id", user.ID))
+
+		return tokens, nil
+	})
+}
@@ -0,0 +1,153 @@
+package user
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/sabbatD/srest-api/internal/lib/oauth"
+	u "github.com/sabbatD/srest-api/internal/lib/userConfig"
+	"github.com/sabbatD/srest-api/internal/password"
+)
+
+type fakeSocial struct{}
+
+func (fakeSocial) AuthURL(provider, state string) (string, error) {
+	if provider != oauth.GitHub {
+		return "", oauth.ErrUnknownProvider
+	}
+	return "https://github.example.com/authorize?state=" + state, nil
+}
+
+func (fakeSocial) Exchange(ctx context.Context, provider, code string) (oauth.Identity, error) {
+	return oauth.Identity{Provider: provider, Subject: "42", Username: "octocat", Email: "octo@example.com", EmailVerified: true}, nil
+}
+
+// memOAuthUsers implements the part of UserHandler the callback uses
+type memOAuthUsers struct {
+	UserHandler
+	linked []oauth.Identity
+	saved  int
+}
+
+func (m *memOAuthUsers) OAuthUser(ctx context.Context, id oauth.Identity) (u.TableUser, error) {
+	m.linked = append(m.linked, id)
+	return u.TableUser{ID: 7, Username: id.Username, Email: id.Email, IsVerified: id.EmailVerified}, nil
+}
+
+func (m *memOAuthUsers) SaveRefreshToken(ctx context.Context, token string, id int, expires time.Time) error {
+	m.saved++
+	return nil
+}
+
+func TestOAuthSignIn(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	users := &memOAuthUsers{}
+	r := chi.NewRouter()
+	r.Get("/auth/{provider}/login", OAuthLogin(log, fakeSocial{}))
+	r.Get("/auth/{provider}/callback", OAuthCallback(log, users, fakeSocial{}, nil, nil, time.Hour, true))
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/auth/google/login", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("unknown provider login = %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/auth/github/login", nil))
+	if rec.Code != http.StatusFound {
+		t.Fatalf("login = %d", rec.Code)
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != stateCookie || cookies[0].Path != "/auth/github" || !cookies[0].HttpOnly {
+		t.Fatalf("state cookie = %+v", cookies)
+	}
+	target, _ := url.Parse(rec.Header().Get("Location"))
+	state := target.Query().Get("state")
+	if state != cookies[0].Value {
+		t.Errorf("redirect state %q, cookie %q", state, cookies[0].Value)
+	}
+
+	callback := func(state string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/auth/github/callback?code=c&state="+state, nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// A callback the user did not start, e.g. a login CSRF, has no or another state.
+	if rec := callback(state, nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("callback without the state cookie = %d", rec.Code)
+	}
+	if rec := callback("other", cookies[0]); rec.Code != http.StatusBadRequest {
+		t.Errorf("callback with another state = %d", rec.Code)
+	}
+	if len(users.linked) != 0 {
+		t.Fatalf("users signed in with a bad state: %v", users.linked)
+	}
+
+	rec = callback(state, cookies[0])
+	if rec.Code != http.StatusOK {
+		t.Fatalf("callback = %d %s", rec.Code, rec.Body)
+	}
+	var tokens Tokens
+	if err := json.NewDecoder(rec.Body).Decode(&tokens); err != nil || tokens.AccessToken.Token == "" || tokens.RefreshToken.Token == "" {
+		t.Errorf("tokens = %+v, %v", tokens, err)
+	}
+	if len(users.linked) != 1 || users.linked[0].Subject != "42" {
+		t.Errorf("signed in identities = %+v", users.linked)
+	}
+	if c := rec.Result().Cookies(); len(c) != 1 || c[0].MaxAge >= 0 || c[0].Name != stateCookie {
+		t.Errorf("state cookie not cleared: %+v", c)
+	}
+}
+
+func TestOAuthSignInConfirmation(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	users := &memOAuthUsers{}
+	store := &memConfirmations{recent: []u.SignIn{{UserAgent: "curl/8.0"}}, held: map[string]*u.PendingSignIn{}, tokens: map[string]string{}}
+	mailer := &memMailer{enabled: true}
+	c := &Confirmation{Store: store, Mail: mailer, NewCountry: true, NewDevice: true, TTL: time.Hour, Link: "https://example.com/confirm?token="}
+	callback := OAuthCallback(log, users, fakeSocial{}, nil, c, time.Hour, true)
+
+	signIn := func(agent string) *httptest.ResponseRecorder {
+		r := chi.NewRouter()
+		r.Get("/auth/{provider}/callback", callback)
+		req := httptest.NewRequest(http.MethodGet, "/auth/github/callback?code=c&state=s", nil)
+		req.AddCookie(&http.Cookie{Name: stateCookie, Value: "s"})
+		req.Header.Set("User-Agent", agent)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := signIn("curl/8.0"); rec.Code != http.StatusOK || users.saved != 1 {
+		t.Fatalf("sign in from a known device: status %d, %d refresh tokens", rec.Code, users.saved)
+	}
+
+	rec := signIn("Firefox")
+	var pending PendingSignIn
+	if err := json.NewDecoder(rec.Body).Decode(&pending); err != nil || rec.Code != http.StatusAccepted || pending.Reason != ReasonNewDevice {
+		t.Fatalf("sign in from a new device: status %d, %+v, %v", rec.Code, pending, err)
+	}
+	if users.saved != 1 {
+		t.Errorf("held sign in issued tokens")
+	}
+	if len(mailer.sent) != 1 || mailer.sent[0].vars["Device"] != "Firefox" {
+		t.Errorf("emails = %+v", mailer.sent)
+	}
+	if in := store.held[password.HashToken(pending.Session)]; in == nil || in.Method != oauth.GitHub || in.RememberMe {
+		t.Errorf("held sign in = %+v", in)
+	}
+}
@@ -0,0 +1,219 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	sdb "github.com/sabbatD/srest-api/internal/database"
+	util "github.com/sabbatD/srest-api/internal/http-server/handleUtil"
+	"github.com/sabbatD/srest-api/internal/lib/clock"
+	"github.com/sabbatD/srest-api/internal/lib/logger/sl"
+	"github.com/sabbatD/srest-api/internal/lib/mail"
+	u "github.com/sabbatD/srest-api/internal/lib/userConfig"
+	"github.com/sabbatD/srest-api/internal/password"
+)
+
+// PasswordHandler sets passwords with reset tokens
+type PasswordHandler interface {
+	ResetPassword(ctx context.Context, tokenHash, password string) (int, error)
+}
+
+// PasswordChanger changes passwords given the current one, see database.Storage.UpdatePassword
+type PasswordChanger interface {
+	UpdatePassword(ctx context.Context, id int, current, password string) (int64, error)
+}
+
+// ForgotHandler issues password reset tokens users request themselves, see database.Storage.RequestPasswordReset
+type ForgotHandler interface {
+	RequestPasswordReset(ctx context.Context, login, tokenHash string, expires time.Time) (u.TableUser, error)
+}
+
+// Mailer sends email to users, see mail.Templates
+type Mailer interface {
+	Enabled() bool
+	Send(ctx context.Context, to []string, name, locale string, vars map[string]string) error
+}
+
+// ForgotPassword godoc
+// @Summary Request a password reset link
+// @ID forgotPassword
+// @Description Emails a single-use password reset link to the account with the given login or email, valid for an hour by default.
+// The password keeps working until a new one is set with the token at POST /password/reset. The answer is the same
+// whether the account exists or not. Requests are rate limited per client address.
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param Forgot body u.PasswordForgot true "Login or email of the account"
+// @Success 202 {object} string "Reset link emailed if the account exists."
+// @Failure 400 {object} util.Problem "Invalid input."
+// @Failure 429 {object} util.Problem "Too many requests."
+// @Failure 500 {object} util.Problem "Internal error."
+// @Failure 503 {object} util.Problem "Email is not configured."
+// @Router /password/forgot [post]
+func ForgotPassword(log *slog.Logger, Passwords ForgotHandler, Mail Mailer, ttl time.Duration, link string) http.HandlerFunc {
+	const op = "http-server.handlers.user.ForgotPassword"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		var req u.PasswordForgot
+		if err := util.DecodeJSON(r, &req); err != nil {
+			return nil, err
+		}
+
+		log.Info("request body decoded")
+
+		if err := util.Validate(req); err != nil {
+			return nil, err
+		}
+		if !Mail.Enabled() {
+			return nil, util.NewError(http.StatusServiceUnavailable, util.CodeUnavailable, "Password reset by email is not available")
+		}
+
+		token, hash, err := password.NewToken()
+		if err != nil {
+			return nil, err
+		}
+		expires := clock.Now().Add(ttl)
+
+		// Unknown accounts get the same answer, so the route does not tell which logins exist
+		user, err := Passwords.RequestPasswordReset(r.Context(), req.Login, hash, expires)
+		if errors.Is(err, sdb.ErrNotFound) {
+			log.Info("password reset requested for an unknown account")
+			w.WriteHeader(http.StatusAccepted)
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		vars := map[string]string{"Username": user.Username, "Expires": expires.UTC().Format(time.RFC1123), "Link": link + token}
+		if err := Mail.Send(r.Context(), []string{user.Email}, mail.TemplatePasswordForgot, user.Locale, vars); err != nil {
+			return nil, err
+		}
+
+		log.Info("password reset link emailed", slog.Int("user_id", user.ID))
+
+		w.WriteHeader(http.StatusAccepted)
+		return nil, nil
+	})
+}
+
+// ResetPassword godoc
+// @Summary Reset password with a token
+// @ID resetPassword
+// @Description Sets a new password with a single-use reset token, from the link emailed after POST /password/forgot or after an admin reset the user's credentials.
+// The user's refresh token is revoked, they sign in again with the new password.
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param Reset body u.PasswordReset true "Reset token and new password"
+// @Success 200 {object} string "Password changed."
+// @Failure 400 {object} util.Problem "Invalid input, or a password violating the password policy."
+// @Failure 404 {object} util.Problem "Unknown, used or expired reset token."
+// @Failure 500 {object} util.Problem "Internal error."
+// @Router /password/reset [post]
+func ResetPassword(log *slog.Logger, Passwords PasswordHandler, policy *password.Policy) http.HandlerFunc {
+	const op = "http-server.handlers.user.ResetPassword"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		var req u.PasswordReset
+		if err := util.DecodeJSON(r, &req); err != nil {
+			return nil, err
+		}
+
+		log.Info("request body decoded")
+
+		if err := util.Validate(req); err != nil {
+			return nil, err
+		}
+		if err := checkPassword(policy, req.Password); err != nil {
+			return nil, err
+		}
+
+		userID, err := Passwords.ResetPassword(r.Context(), password.HashToken(req.Token), req.Password)
+		if err != nil {
+			return nil, util.NotFound(err, "Invalid or expired reset token")
+		}
+
+		log.Info("password reset", slog.Int("user_id", userID))
+
+		return nil, nil
+	})
+}
+
+// UpdatePassword godoc
+// @Summary Change password
+// @ID updatePassword
+// @Description Sets a new password after checking the current one. Every refresh token and remembered device of the user is revoked,
+// so the user signs in again everywhere, also on this device once the access token expires.
+// Users who must change their password may call it with their restricted token.
+// The user must be authenticated and provide a valid JWT token.
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param Password body u.PasswordChange true "Current and new password"
+// @Security BearerAuth
+// @Success 200 {object} u.PasswordChanged "Password changed."
+// @Failure 400 {object} util.Problem "Invalid input, or a password violating the password policy."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 403 {object} util.Problem "Wrong current password."
+// @Failure 404 {object} util.Problem "No such user."
+// @Failure 500 {object} util.Problem "Internal error."
+// @Router /user/password [put]
+func UpdatePassword(log *slog.Logger, Passwords PasswordChanger, policy *password.Policy) http.HandlerFunc {
+	const op = "http-server.handlers.user.UpdatePassword"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		userID, err := contextUser(r)
+		if err != nil {
+			return nil, err
+		}
+
+		var req u.PasswordChange
+		if err := util.DecodeJSON(r, &req); err != nil {
+			return nil, err
+		}
+
+		log.Info("request body decoded")
+
+		if err := util.Validate(req); err != nil {
+			return nil, err
+		}
+		if err := checkPassword(policy, req.NewPassword); err != nil {
+			return nil, err
+		}
+
+		revoked, err := Passwords.UpdatePassword(r.Context(), userID, req.CurrentPassword, req.NewPassword)
+		if errors.Is(err, sdb.ErrWrongPassword) {
+			return nil, util.WrapError(err, http.StatusForbidden, util.CodeForbidden, "Current password is wrong")
+		}
+		if err != nil {
+			return nil, util.NotFound(err, "No such user")
+		}
+
+		log.Info("password changed", slog.Int("user_id", userID), slog.Int64("revoked_sessions", revoked))
+
+		return u.PasswordChanged{RevokedSessions: revoked}, nil
+	})
+}
+
+// checkPassword answers a password violating policy with 400 WEAK_PASSWORD, listing the violated rules
+func checkPassword(policy *password.Policy, pwd string) error {
+	violated := policy.Check(pwd)
+	if len(violated) == 0 {
+		return nil
+	}
+
+	e := util.NewError(http.StatusBadRequest, util.CodeWeakPassword, "Password is too weak: "+strings.Join(violated, ", "))
+	e.Violations = violated
+	return e
+}
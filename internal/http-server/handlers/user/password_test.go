@@ -0,0 +1,155 @@
+package user
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	sdb "github.com/sabbatD/srest-api/internal/database"
+	util "github.com/sabbatD/srest-api/internal/http-server/handleUtil"
+	"github.com/sabbatD/srest-api/internal/lib/api/access"
+	"github.com/sabbatD/srest-api/internal/lib/mail"
+	u "github.com/sabbatD/srest-api/internal/lib/userConfig"
+	"github.com/sabbatD/srest-api/internal/password"
+)
+
+type memResets struct {
+	user   u.TableUser
+	hashes map[int]string
+}
+
+func (m *memResets) RequestPasswordReset(ctx context.Context, login, tokenHash string, expires time.Time) (u.TableUser, error) {
+	if login != "alice" && login != m.user.Email {
+		return u.TableUser{}, sdb.ErrNotFound
+	}
+	m.hashes[m.user.ID] = tokenHash
+	return m.user, nil
+}
+
+type sentMail struct {
+	to   []string
+	name string
+	vars map[string]string
+}
+
+type memMailer struct {
+	enabled bool
+	sent    []sentMail
+}
+
+func (m *memMailer) Enabled() bool { return m.enabled }
+
+func (m *memMailer) Send(ctx context.Context, to []string, name, locale string, vars map[string]string) error {
+	m.sent = append(m.sent, sentMail{to: to, name: name, vars: vars})
+	return nil
+}
+
+func TestForgotPassword(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := &memResets{user: u.TableUser{ID: 7, Username: "Alice", Email: "alice@example.com"}, hashes: map[int]string{}}
+	mailer := &memMailer{enabled: true}
+	h := ForgotPassword(log, store, mailer, time.Hour, "https://example.com/reset?token=")
+
+	forgot := func(body string) int {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/password/forgot", strings.NewReader(body)))
+		return rec.Code
+	}
+
+	// Unknown accounts get the same answer and no email
+	if code := forgot(`{"login": "mallory"}`); code != http.StatusAccepted || len(mailer.sent) != 0 {
+		t.Errorf("unknown account: status %d, %d emails", code, len(mailer.sent))
+	}
+
+	if code := forgot(`{"login": "alice@example.com"}`); code != http.StatusAccepted || len(mailer.sent) != 1 {
+		t.Fatalf("known account: status %d, %d emails", code, len(mailer.sent))
+	}
+	sent := mailer.sent[0]
+	token, found := strings.CutPrefix(sent.vars["Link"], "https://example.com/reset?token=")
+	if !found || sent.name != mail.TemplatePasswordForgot || sent.to[0] != "alice@example.com" {
+		t.Errorf("email = %+v", sent)
+	}
+	// Only the hash of the emailed token is stored
+	if store.hashes[7] != password.HashToken(token) {
+		t.Error("stored hash does not match the emailed token")
+	}
+
+	if code := forgot(`{}`); code != http.StatusBadRequest {
+		t.Errorf("missing login: status %d, want 400", code)
+	}
+
+	mailer.enabled = false
+	if code := forgot(`{"login": "alice"}`); code != http.StatusServiceUnavailable {
+		t.Errorf("email disabled: status %d, want 503", code)
+	}
+}
+
+type memPasswords struct {
+	current  string
+	sessions int64
+}
+
+func (m *memPasswords) UpdatePassword(ctx context.Context, id int, current, pwd string) (int64, error) {
+	if id != 7 {
+		return 0, sdb.ErrNotFound
+	}
+	if current != m.current {
+		return 0, sdb.ErrWrongPassword
+	}
+	revoked := m.sessions
+	m.current, m.sessions = pwd, 0
+	return revoked, nil
+}
+
+func TestUpdatePassword(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := &memPasswords{current: "old-password", sessions: 3}
+	policy, err := password.NewPolicy(password.Config{MinLength: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := access.PasswordChangeMiddleware(UpdatePassword(log, store, policy))
+
+	// Users who must change their password may use their restricted token
+	token, err := access.NewAccessToken(7, false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	change := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/user/password", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	var problem util.Problem
+	rec := change(`{"currentPassword": "wrong", "newPassword": "new-password"}`)
+	json.NewDecoder(rec.Body).Decode(&problem)
+	if rec.Code != http.StatusForbidden || problem.Code != util.CodeForbidden || store.current != "old-password" {
+		t.Errorf("wrong current password: status %d, code %s", rec.Code, problem.Code)
+	}
+
+	rec = change(`{"currentPassword": "old-password", "newPassword": "short"}`)
+	json.NewDecoder(rec.Body).Decode(&problem)
+	if rec.Code != http.StatusBadRequest || problem.Code != util.CodeWeakPassword {
+		t.Errorf("weak password: status %d, code %s", rec.Code, problem.Code)
+	}
+
+	if rec := change(`{"newPassword": "new-password"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("missing current password: status %d, want 400", rec.Code)
+	}
+
+	var changed u.PasswordChanged
+	rec = change(`{"currentPassword": "old-password", "newPassword": "new-password"}`)
+	json.NewDecoder(rec.Body).Decode(&changed)
+	if rec.Code != http.StatusOK || changed.RevokedSessions != 3 || store.current != "new-password" {
+		t.Errorf("change: status %d, %+v", rec.Code, changed)
+	}
+}
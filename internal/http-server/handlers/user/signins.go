@@ -0,0 +1,78 @@
+package user
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/sabbatD/srest-api/internal/lib/api/access"
+	"github.com/sabbatD/srest-api/internal/lib/geoip"
+	"github.com/sabbatD/srest-api/internal/lib/logger/sl"
+	"github.com/sabbatD/srest-api/internal/lib/metrics"
+	u "github.com/sabbatD/srest-api/internal/lib/userConfig"
+)
+
+// Methods of sign ins
+const (
+	MethodPassword = "password"
+	MethodLDAP     = "ldap"
+)
+
+// SignInStore keeps the sign in history of users
+type SignInStore interface {
+	RecordSignIn(ctx context.Context, id int, in u.SignIn) error
+}
+
+// SignIns records the sign ins of users, located with the GeoIP database when there is one.
+// A nil SignIns only counts the failed ones.
+type SignIns struct {
+	store SignInStore
+	geo   *geoip.DB
+}
+
+func NewSignIns(store SignInStore, geo *geoip.DB) *SignIns {
+	return &SignIns{store: store, geo: geo}
+}
+
+// locate returns the address of the client and its location, empty when they are unknown
+func (s *SignIns) locate(r *http.Request) (string, geoip.Location) {
+	addr, ok := access.ClientAddr(r)
+	if !ok {
+		return "", geoip.Location{}
+	}
+	if s == nil {
+		return addr.String(), geoip.Location{}
+	}
+	loc, _ := s.geo.Lookup(addr)
+	return addr.String(), loc
+}
+
+// signIn describes the sign in the request makes with method
+func (s *SignIns) signIn(r *http.Request, method string) u.SignIn {
+	ip, loc := s.locate(r)
+	return u.SignIn{Method: method, IP: ip, Country: loc.Country, City: loc.City, UserAgent: r.UserAgent()}
+}
+
+// record stores the sign in of the user, a failure is logged rather than failing the sign in
+func (s *SignIns) record(r *http.Request, id int, in u.SignIn) {
+	if s == nil {
+		return
+	}
+	log := sl.FromContext(r.Context())
+
+	if err := s.store.RecordSignIn(r.Context(), id, in); err != nil {
+		log.Error("failed to record the sign in", sl.Err(err))
+		return
+	}
+	if loc := (geoip.Location{Country: in.Country, City: in.City}); loc != (geoip.Location{}) {
+		log.Info("signed in from", slog.String("location", loc.String()))
+	}
+}
+
+// failed counts a failed sign in, by the country of the client for the failed logins alert
+func (s *SignIns) failed(r *http.Request) {
+	metrics.FailedLogins.Add(1)
+	if _, loc := s.locate(r); loc.Country != "" {
+		metrics.FailedLoginCountries.Add(loc.Country, 1)
+	}
+}
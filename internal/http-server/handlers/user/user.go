@@ -2,429 +2,818 @@
 package user
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/go-chi/render"
 
+	sdb "github.com/sabbatD/srest-api/internal/database"
 	util "github.com/sabbatD/srest-api/internal/http-server/handleUtil"
 	"github.com/sabbatD/srest-api/internal/lib/api/access"
-	"github.com/sabbatD/srest-api/internal/lib/api/validation"
+	"github.com/sabbatD/srest-api/internal/lib/cache"
+	"github.com/sabbatD/srest-api/internal/lib/clock"
+	"github.com/sabbatD/srest-api/internal/lib/expiry"
+	"github.com/sabbatD/srest-api/internal/lib/fields"
+	"github.com/sabbatD/srest-api/internal/lib/ldap"
+	"github.com/sabbatD/srest-api/internal/lib/lockout"
 	"github.com/sabbatD/srest-api/internal/lib/logger/sl"
+	"github.com/sabbatD/srest-api/internal/lib/mail"
+	"github.com/sabbatD/srest-api/internal/lib/metrics"
+	"github.com/sabbatD/srest-api/internal/lib/moderation"
+	"github.com/sabbatD/srest-api/internal/lib/oauth"
 	u "github.com/sabbatD/srest-api/internal/lib/userConfig"
+	"github.com/sabbatD/srest-api/internal/password"
 )
 
+// Tokens are left out of the response bodies when they are sent as cookies, see access.Cookies
 type AccessToken struct {
-	Token string `json:"accessToken"`
+	Token string `json:"accessToken,omitempty"`
 }
 type RefreshToken struct {
-	Token string `json:"refreshToken"`
+	Token string `json:"refreshToken,omitempty"`
 }
 
 type Tokens struct {
 	AccessToken
 	RefreshToken
+	// MustChangePassword tells the access token only allows changing the password
+	MustChangePassword bool `json:"mustChangePassword,omitempty"`
+	// DeviceID is the public id of the remembered device the refresh token belongs to
+	DeviceID string `json:"deviceId,omitempty"`
+}
+
+// GuestSession is a guest's access token, there is no refresh token
+type GuestSession struct {
+	AccessToken
+	ExpiresAt time.Time `json:"expiresAt"`
+	MaxTodos  int       `json:"maxTodos"`
 }
 
 type UserHandler interface {
-	Add(u u.User) (int, error)
-	Auth(u u.AuthData) (user u.TableUser, err error)
-	Get(id int) (u.TableUser, error)
-	UpdateUser(u u.PutUser, id int) (int64, error)
-	RefreshToken(token string) (string, int, error)
-	SaveRefreshToken(token string, id int) error
-	ChangePassword(u u.Pwd, id int) (int64, error)
+	Add(ctx context.Context, u u.User) (int, error)
+	Auth(ctx context.Context, u u.AuthData) (user u.TableUser, err error)
+	Get(ctx context.Context, id int) (u.TableUser, error)
+	UpdateUser(ctx context.Context, u u.PutUser, id int) (int64, error)
+	RefreshToken(ctx context.Context, token string) (string, int, error)
+	SaveRefreshToken(ctx context.Context, token string, id int, expires time.Time) error
+	RememberDevice(ctx context.Context, id int, name, tokenHash, deviceHash string, expires time.Time) (string, error)
+	RefreshDevice(ctx context.Context, tokenHash, deviceHash, newTokenHash string, expires time.Time) (int, string, error)
+	ChangePassword(ctx context.Context, u u.Pwd, id int) (int64, error)
+	ChangeLogin(ctx context.Context, id int, login string, cooldown, hold time.Duration) (time.Time, error)
+	Audit(ctx context.Context, actor int, action string, target int, details any) error
+	AddGuest(ctx context.Context, maxTodos int) (int, error)
+	UpgradeGuest(ctx context.Context, guest, user int) (int64, error)
+	ProvisionUser(ctx context.Context, ext u.ExternalUser) (u.TableUser, error)
+	OAuthUser(ctx context.Context, id oauth.Identity) (u.TableUser, error)
+	UserFields(ctx context.Context) (fields.Schema, error)
+}
+
+// Directory authenticates users against an external directory, see ldap.Directory
+type Directory interface {
+	Authenticate(ctx context.Context, login, password string) (ldap.Identity, error)
 }
 
 // Register godoc
 // @Summary Register a new user
+// @ID signUp
 // @Description Handles the registration of a new user by accepting a JSON payload containing user data.
 // This endpoint will create a new user if the username doesn't already exist in the system.
+// With a guest token in the Authorization header the guest's todos are moved to the new account.
+// While email is configured the account is unverified until the link emailed to it is followed, see GET /verify-email.
 // @Tags user
 // @Accept json
 // @Produce json
 // @Param UserData body u.User true "Complete user data for registration"
 // @Success 201 {object} u.TableUser "Registration successful. Returns user data."
-// @Failure 400 {object} string "failed to deserialize json request."
-// @Failure 400 {object} string "Invalid input."
-// @Failure 409 {object} string "User already exists."
-// @Failure 500 {object} string "Internal error."
+// @Failure 400 {object} util.Problem "failed to deserialize json request."
+// @Failure 400 {object} util.Problem "Invalid input, or a password violating the password policy."
+// @Failure 409 {object} util.Problem "User already exists."
+// @Failure 422 {object} util.Problem "Username rejected by moderation."
+// @Failure 500 {object} util.Problem "Internal error."
 // @Router /auth/signup [post]
-func Register(log *slog.Logger, User UserHandler) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		const op = "http-server.handlers.user.Register"
+func Register(log *slog.Logger, User UserHandler, mod *moderation.Moderator, policy *password.Policy, verify *Verification) http.HandlerFunc {
+	const op = "http-server.handlers.user.Register"
 
-		log.With(util.SlogWith(op, r)...)
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
 
 		var req u.User
-		if err := render.DecodeJSON(r.Body, &req); err != nil {
-			log.Error("failed to decode request", sl.Err(err))
-
-			http.Error(w, "failed to deserialize json request", http.StatusBadRequest)
-
-			return
+		if err := util.DecodeJSON(r, &req); err != nil {
+			return nil, err
 		}
 
 		log.Info("request body decoded")
 		log.Debug("req: ", slog.Any("request", req))
 
-		validation.InitValidator()
-		if err := validation.ValidateStruct(req); err != nil {
-			log.Debug(fmt.Sprintf("validation failed: %v", err.Error()))
-
-			http.Error(w, fmt.Sprintf("Invalid input: %v", err.Error()), http.StatusBadRequest)
-
-			return
+		if err := util.Validate(req); err != nil {
+			return nil, err
+		}
+		if err := checkPassword(policy, req.Password); err != nil {
+			return nil, err
 		}
 
 		log.Info("input validated")
 
-		id, err := User.Add(req)
+		verdict, err := mod.Check(r.Context(), req.Username)
 		if err != nil {
-			if err.Error() == "database.postgres.Add: user already exists" {
-				log.Info(err.Error())
+			return nil, util.WrapError(err, http.StatusUnprocessableEntity, util.CodeRejected, "Username was rejected by moderation")
+		}
 
-				http.Error(w, "user already exists", http.StatusConflict)
+		id, err := User.Add(r.Context(), req)
+		if err != nil {
+			if errors.Is(err, sdb.ErrAlreadyExists) {
+				return nil, util.WrapError(err, http.StatusConflict, util.CodeConflict, "user already exists")
+			}
+			return nil, err
+		}
 
-				return
+		// Like the guest upgrade below, a failed verification email does not undo the signup, the link can be resent.
+		if verify.enabled() {
+			if err := verify.start(r.Context(), id); err != nil {
+				log.Error("failed to start email verification", sl.Err(err))
 			}
-			util.InternalError(w, r, log, err)
-			return
 		}
 
-		user, err := User.Get(id)
+		user, err := User.Get(r.Context(), id)
 		if err != nil {
-			util.InternalError(w, r, log, err)
-			return
+			return nil, err
+		}
+
+		mod.Record(r.Context(), verdict, moderation.Flag{Kind: moderation.KindUsername, UserID: user.ID, Ref: user.PublicID, Text: req.Username})
+
+		// The account exists at this point, a failed upgrade leaves the guest's todos behind but not the signup.
+		if guest, ok := access.GuestID(r); ok {
+			moved, err := User.UpgradeGuest(r.Context(), guest, id)
+			if err != nil {
+				log.Error("failed to upgrade guest", slog.Int("guest", guest), sl.Err(err))
+			} else {
+				log.Info("guest upgraded", slog.Int("guest", guest), slog.Int64("todos_moved", moved))
+			}
 		}
 
 		log.Info("user successfully created")
 		log.Debug(fmt.Sprintf("user: %v", user))
 
-		w.WriteHeader(http.StatusCreated)
-		render.JSON(w, r, user)
-	}
+		render.Status(r, http.StatusCreated)
+		return user, nil
+	})
+}
+
+// Guest godoc
+// @Summary Start a guest session
+// @ID startGuestSession
+// @Description Creates a guest account and returns its access token, with which the todo routes can be used
+// for up to maxTodos todos. Other routes refuse guest tokens. Signing up with the guest token in the
+// Authorization header moves the guest's todos to the new account. Guests are deleted after the retention period.
+// In the cookie transport the access token is set as an HttpOnly cookie instead.
+// @Tags user
+// @Produce json
+// @Success 201 {object} GuestSession "Guest session started."
+// @Failure 429 {object} string "Too many requests, see Retry-After."
+// @Failure 500 {object} util.Problem "Internal error."
+// @Router /guest [post]
+func Guest(log *slog.Logger, User UserHandler, maxTodos int, ttl time.Duration) http.HandlerFunc {
+	const op = "http-server.handlers.user.Guest"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		id, err := User.AddGuest(r.Context(), maxTodos)
+		if err != nil {
+			return nil, err
+		}
+
+		token, expires, err := access.NewGuestToken(id, ttl)
+		if err != nil {
+			return nil, fmt.Errorf("could not generate JWT accessToken: %w", err)
+		}
+
+		log.Info("guest session started", slog.Int("guest", id))
+
+		session := GuestSession{AccessToken: AccessToken{token}, ExpiresAt: expires, MaxTodos: maxTodos}
+		if access.CookiesEnabled() {
+			if err := access.SetTokenCookies(w, token, expires, "", time.Time{}); err != nil {
+				return nil, err
+			}
+			session.AccessToken = AccessToken{}
+		}
+
+		render.Status(r, http.StatusCreated)
+		return session, nil
+	})
 }
 
 // Auth godoc
 // @Summary Authenticate user
+// @ID signIn
 // @Description Authenticates a user by accepting their login credentials (login and password) in JSON format.
 // Upon successful authentication, a JWT token will be generated and returned for subsequent API calls.
+// With an LDAP directory configured the credentials are checked against it first, a directory user gets
+// a local account on the first sign in. Local accounts still sign in with their password.
+// With rememberMe the device is remembered: the refresh token lives longer and is bound to the HttpOnly device cookie
+// set with the response, the refresh has to send both. Remembered devices are listed and revoked at /user/devices.
+// After repeated failed sign ins a login is locked for the client address for a while, the attempts answer 423.
+// When email verification is required, accounts whose email is not verified are refused with 403 EMAIL_NOT_VERIFIED.
+// With sign in confirmation enabled, a sign in from a new country or device answers 202 without tokens and emails the user
+// a confirmation link, the client gets the tokens from POST /auth/signin/pending once it is followed.
+// In the cookie transport the tokens are set as HttpOnly cookies along with the CSRF cookie and left out of the body.
 // @Tags user
 // @Accept json
 // @Produce json
 // @Param AuthData body u.AuthData true "User login credentials"
 // @Success 200 {object} Tokens "Authentication successful. Returns a JWT token."
-// @Failure 400 {object} string "failed to deserialize json request."
-// @Failure 400 {object} string "Invalid input."
-// @Failure 401 {object} string "Invalid credentials."
-// @Failure 500 {object} string "Internal error."
+// @Success 202 {object} PendingSignIn "Sign in held until it is confirmed by email."
+// @Failure 400 {object} util.Problem "failed to deserialize json request."
+// @Failure 400 {object} util.Problem "Invalid input."
+// @Failure 401 {object} util.Problem "Invalid credentials."
+// @Failure 403 {object} util.Problem "Email not verified."
+// @Failure 423 {object} util.Problem "Too many failed sign ins of the login from this address, see Retry-After."
+// @Failure 500 {object} util.Problem "Internal error."
 // @Router /auth/signin [post]
-func Auth(log *slog.Logger, User UserHandler) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		const op = "http-server.handlers.user.Auth"
+func Auth(log *slog.Logger, User UserHandler, dir Directory, guard *lockout.Guard, signIns *SignIns, confirm *Confirmation, refreshTTL, rememberTTL time.Duration, requireVerified bool) http.HandlerFunc {
+	const op = "http-server.handlers.user.Auth"
 
-		log.With(util.SlogWith(op, r)...)
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
 
 		var req u.AuthData
-		if err := render.DecodeJSON(r.Body, &req); err != nil {
-			log.Error("failed to decode request", sl.Err(err))
-
-			http.Error(w, "failed to deserialize json request", http.StatusBadRequest)
-
-			return
+		if err := util.DecodeJSON(r, &req); err != nil {
+			return nil, err
 		}
 
 		log.Info("request body decoded")
 		log.Debug("req: ", slog.Any("request", req))
 
-		validation.InitValidator()
-		if err := validation.ValidateStruct(req); err != nil {
-			log.Debug(fmt.Sprintf("validation failed: %v", err.Error()))
-
-			http.Error(w, fmt.Sprintf("Invalid input: %v", err.Error()), http.StatusBadRequest)
-
-			return
+		if err := util.Validate(req); err != nil {
+			return nil, err
 		}
 
 		log.Info("input validated")
 
-		user, err := User.Auth(req)
-		if user.ID == 0 {
-			log.Info("wrong login or password")
-
-			http.Error(w, "Invalid credentials", http.StatusUnauthorized)
-
-			return
-		}
+		ip, _ := access.IPKey(r)
+		until, err := guard.Locked(r.Context(), req.Login, ip)
 		if err != nil {
-			util.InternalError(w, r, log, err)
-			return
+			return nil, err
+		}
+		if !until.IsZero() {
+			metrics.LockedLogins.Add(1)
+			w.Header().Set("Retry-After", strconv.Itoa(int(until.Sub(clock.Now()).Seconds())+1))
+			return nil, util.NewError(http.StatusLocked, util.CodeLocked,
+				fmt.Sprintf("Too many failed sign ins, try again at %s", until.UTC().Format(time.RFC3339)))
 		}
 
-		accessToken, err := access.NewAccessToken(user.ID, user.IsAdmin)
+		var user u.TableUser
+		method := MethodLDAP
+		if dir != nil {
+			if user, err = directoryAuth(r.Context(), log, User, dir, req); err != nil {
+				return nil, err
+			}
+		}
+		if user.ID == 0 {
+			method = MethodPassword
+			user, err = User.Auth(r.Context(), req)
+		}
+		if user.ID == 0 {
+			signIns.failed(r)
+			if err := guard.Fail(r.Context(), req.Login, ip); err != nil {
+				log.Error("failed to record the failed sign in", sl.Err(err))
+			}
+			return nil, util.WrapError(err, http.StatusUnauthorized, util.CodeUnauthorized, "Invalid credentials")
+		}
 		if err != nil {
-			util.InternalError(w, r, log, err)
-			return
+			return nil, err
+		}
+		if err := guard.Succeed(r.Context(), req.Login, ip); err != nil {
+			log.Error("failed to clear the failed sign ins", sl.Err(err))
+		}
+		if requireVerified && !user.IsVerified {
+			return nil, util.NewError(http.StatusForbidden, util.CodeUnverified, "Email is not verified, follow the link emailed after sign up")
 		}
 
-		refreshToken, err := access.NewRefreshToken()
+		in := signIns.signIn(r, method)
+		pending, err := confirm.hold(r.Context(), user, in, req.RememberMe)
 		if err != nil {
-			util.InternalError(w, r, log, fmt.Errorf("could not generate JWT refreshToken"))
-			return
+			return nil, err
+		}
+		if pending != nil {
+			log.Info("sign in held for confirmation", slog.String("reason", pending.Reason))
+			render.Status(r, http.StatusAccepted)
+			return pending, nil
 		}
 
-		if err := User.SaveRefreshToken(refreshToken, user.ID); err != nil {
-			util.InternalError(w, r, log, err)
-			return
+		tokens, err := signInTokens(w, r, User, user, req.RememberMe, refreshTTL, rememberTTL)
+		if err != nil {
+			return nil, err
 		}
+		signIns.record(r, user.ID, in)
 
-		log.Info("successfully logged in")
+		log.Info("successfully logged in", slog.Bool("remembered", req.RememberMe))
 		log.Debug(fmt.Sprintf("user: %v", req))
 
-		render.JSON(w, r, Tokens{AccessToken{accessToken}, RefreshToken{refreshToken}})
-	}
+		return tokens, nil
+	})
 }
 
 // Refresh godoc
 // @Summary Refresh user's access token
+// @ID refresh
 // @Description Recieve a user's refresh token in JSON format.
 // Upon successful refresh token compare, an access JWT token will be generated and returned for subsequent API calls.
+// The refresh token of a remembered device only works together with its device cookie, it is replaced on every refresh.
+// In the cookie transport the body may be left out, the refresh token cookie is used with the X-CSRF-Token header.
 // @Tags user
 // @Accept json
 // @Produce json
 // @Param RefreshToken body RefreshToken true "User's refresh token"
 // @Success 200 {object} Tokens "Authentication successful. Returns a JWT token."
-// @Failure 400 {object} string "failed to deserialize json request."
-// @Failure 401 {object} string "Invalid credentials: token is expired - must auth again."
-// @Failure 500 {object} string "Internal error."
+// @Failure 400 {object} util.Problem "failed to deserialize json request."
+// @Failure 401 {object} util.Problem "Invalid credentials: token is expired - must auth again."
+// @Failure 403 {object} util.Problem "Missing or invalid CSRF token."
+// @Failure 500 {object} util.Problem "Internal error."
 // @Router /auth/refresh [post]
-func Refresh(log *slog.Logger, User UserHandler) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		const op = "http-server.handlers.user.Refresh"
+func Refresh(log *slog.Logger, User UserHandler, refreshTTL, rememberTTL time.Duration) http.HandlerFunc {
+	const op = "http-server.handlers.user.Refresh"
 
-		log.With(util.SlogWith(op, r)...)
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
 
-		var req RefreshToken
-		if err := render.DecodeJSON(r.Body, &req); err != nil {
-			log.Error("failed to decode request", sl.Err(err))
+		cookie, err := access.RefreshTokenCookie(r)
+		if err != nil {
+			return nil, util.WrapError(err, http.StatusForbidden, util.CodeForbidden, "Missing or invalid CSRF token")
+		}
 
-			http.Error(w, "failed to deserialize json request", http.StatusBadRequest)
+		// In the cookie transport the body may be left out
+		var req RefreshToken
+		if cookie == "" || r.ContentLength != 0 {
+			if err := util.DecodeJSON(r, &req); err != nil {
+				return nil, err
+			}
 
-			return
+			log.Info("request body decoded")
+			log.Debug("req: ", slog.Any("request", req))
+		}
+		if req.Token == "" {
+			req.Token = cookie
 		}
 
-		log.Info("request body decoded")
-		log.Debug("req: ", slog.Any("request", req))
-
-		token, id, err := User.RefreshToken(req.Token)
+		token, id, err := User.RefreshToken(r.Context(), req.Token)
 		if err != nil {
-			util.InternalError(w, r, log, err)
-			return
+			return nil, err
 		}
 		if token == "expired" {
-			log.Info("token is expired")
-
-			http.Error(w, "Invalid credentials: token is expired - must auth again", http.StatusUnauthorized)
-
-			return
+			if cookie, err := r.Cookie(deviceCookie); err == nil {
+				tokens, err := refreshDevice(w, r, User, req.Token, cookie.Value, rememberTTL)
+				if err != nil {
+					return nil, err
+				}
+				return sendTokens(w, tokens, rememberTTL)
+			}
+			return nil, util.NewError(http.StatusUnauthorized, util.CodeUnauthorized, "Invalid credentials: token is expired - must auth again")
 		}
 
-		user, err := User.Get(id)
+		user, err := User.Get(r.Context(), id)
 		if err != nil {
-			util.InternalError(w, r, log, err)
-			return
+			return nil, err
 		}
 
-		accessToken, err := access.NewAccessToken(user.ID, user.IsAdmin)
+		tokens, err := issueTokens(r.Context(), User, user, refreshTTL)
 		if err != nil {
-			util.InternalError(w, r, log, fmt.Errorf("could not generate JWT accessToken"))
-			return
+			return nil, err
 		}
 
-		refreshToken, err := access.NewRefreshToken()
-		if err != nil {
-			util.InternalError(w, r, log, fmt.Errorf("could not generate JWT refreshToken"))
-			return
+		log.Info("successfully refreshed access token")
+		log.Debug(fmt.Sprintf("user: %v", tokens.RefreshToken.Token))
+
+		return sendTokens(w, tokens, refreshTTL)
+	})
+}
+
+// LogoutHandler ends sessions, see access.Denylist for the revoked access tokens
+type LogoutHandler interface {
+	Logout(ctx context.Context, id int, deviceHash, jti string, expires time.Time) error
+}
+
+// Logout godoc
+// @Summary Log out
+// @ID logout
+// @Description Deletes the refresh token of the user and revokes the access token of the request, it gets 401 from then on.
+// A remembered device sending its device cookie is forgotten too and the cookie is cleared. Guests may log out as well.
+// In the cookie transport the token cookies are cleared.
+// @Tags user
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} string "Logged out."
+// @Failure 401 {object} string "Unauthorized access. Bearer token missing or invalid."
+// @Failure 500 {object} util.Problem "Internal error."
+// @Router /auth/logout [post]
+func Logout(log *slog.Logger, Sessions LogoutHandler) http.HandlerFunc {
+	const op = "http-server.handlers.user.Logout"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		userContext, ok := r.Context().Value(access.CxtKey("userContext")).(access.UserContext)
+		if !ok {
+			return nil, util.NewError(http.StatusUnauthorized, util.CodeUnauthorized, "User context not found")
 		}
 
-		if err := User.SaveRefreshToken(refreshToken, user.ID); err != nil {
-			util.InternalError(w, r, log, err)
-			return
+		deviceHash := ""
+		if cookie, err := r.Cookie(deviceCookie); err == nil && cookie.Value != "" {
+			deviceHash = password.HashToken(cookie.Value)
 		}
 
-		log.Info("successfully refreshed access token")
-		log.Debug(fmt.Sprintf("user: %v", refreshToken))
+		if err := Sessions.Logout(r.Context(), userContext.UserId, deviceHash, userContext.TokenID, userContext.TokenExpires); err != nil {
+			return nil, err
+		}
+		if deviceHash != "" {
+			clearDeviceCookie(w)
+		}
+		access.ClearTokenCookies(w)
 
-		render.JSON(w, r, Tokens{AccessToken{accessToken}, RefreshToken{refreshToken}})
-	}
+		log.Info("successfully logged out", slog.Bool("device", deviceHash != ""))
+
+		return nil, nil
+	})
+}
+
+// PasswordExpiryHandler returns the password expiry policy in effect, see expiry.Runner
+type PasswordExpiryHandler interface {
+	Policy(ctx context.Context) (expiry.Policy, error)
 }
 
 // Profile godoc
 // @Summary Get user profile
+// @ID getProfile
 // @Description Retrieves the full profile of the currently authenticated user.
+// Custom fields are included unless admins hid them from the user.
+// When passwords expire, passwordExpires holds when the user's password does and passwordExpiresSoon is set once it is within the warning period.
 // The user must be logged in and provide a valid JWT token for authentication.
 // @Tags user
 // @Produce json
 // @Security BearerAuth
 // @Success 200 {object} u.TableUser "Returns the user profile data."
-// @Failure 400 {object} string "No such user."
-// @Failure 500 {object} string "Internal error."
+// @Failure 404 {object} util.Problem "No such user."
+// @Failure 500 {object} util.Problem "Internal error."
 // @Router /user/profile [get]
-func Profile(log *slog.Logger, User UserHandler) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		const op = "http-server.handlers.user.Profile"
+func Profile(log *slog.Logger, User UserHandler, Expiry PasswordExpiryHandler, profiles *cache.UserCache) http.HandlerFunc {
+	const op = "http-server.handlers.user.Profile"
 
-		log.With(util.SlogWith(op, r)...)
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
 
-		userContext, ok := r.Context().Value(access.CxtKey("userContext")).(access.UserContext)
-		if !ok {
-			http.Error(w, "User context not found", http.StatusUnauthorized)
-			return
+		userID, err := contextUser(r)
+		if err != nil {
+			return nil, err
 		}
 
-		user, err := User.Get(userContext.UserId)
+		key := profiles.Key(userID)
+		if user, ok := profiles.Get(key); ok {
+			log.Info("User served from cache")
+			return user, nil
+		}
+
+		user, err := User.Get(r.Context(), userID)
 		if err != nil {
-			if err.Error() == "database.postgres.Get: no such user" {
-				log.Info(err.Error())
+			return nil, util.NotFound(err, "No such user")
+		}
 
-				http.Error(w, "No such user", http.StatusBadRequest)
+		schema, err := User.UserFields(r.Context())
+		if err != nil {
+			return nil, err
+		}
+		user.Custom = schema.Filter(user.Custom, fields.UserVisible)
 
-				return
+		if user.PasswordChanged != nil {
+			policy, err := Expiry.Policy(r.Context())
+			if err != nil {
+				return nil, err
+			}
+			if expires, ok := policy.Expires(*user.PasswordChanged); ok {
+				user.PasswordExpires = &expires
+				user.PasswordExpiresSoon = policy.Warn(expires, clock.Now())
 			}
-			util.InternalError(w, r, log, err)
-			return
 		}
 
+		profiles.Set(key, user)
+
 		log.Info("User successfully retrieved")
 		log.Debug(fmt.Sprintf("user: %v", user))
 
-		render.JSON(w, r, user)
-	}
+		return user, nil
+	})
+}
+
+// ProfileFields godoc
+// @Summary Get custom profile fields
+// @ID getProfileFields
+// @Description Lists the custom profile fields shown to the user: their type, whether they are required
+// and whether the user may edit them ('editable') or only admins ('visible').
+// The user must be logged in and provide a valid JWT token for authentication.
+// @Tags user
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} fields.Schema "Returns the custom fields."
+// @Failure 500 {object} util.Problem "Internal error."
+// @Router /user/profile/fields [get]
+func ProfileFields(log *slog.Logger, User UserHandler) http.HandlerFunc {
+	const op = "http-server.handlers.user.ProfileFields"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		schema, err := User.UserFields(r.Context())
+		if err != nil {
+			return nil, err
+		}
+		return schema.Only(fields.UserVisible), nil
+	})
 }
 
 // UpdateUser godoc
 // @Summary Update user profile
+// @ID updateProfile
 // @Description Updates the user profile with new data provided in the JSON payload.
+// Custom holds the changed custom field values (null removes one), only fields editable by the user may change.
+// The response lists the changed fields with their old and new values, the change is recorded in the audit log.
 // The user must be authenticated and provide a valid JWT token.
 // @Tags user
 // @Accept json
 // @Produce json
 // @Param Userdata body u.PutUser true "Updated user's any data"
 // @Security BearerAuth
-// @Success 200 {object} u.TableUser "Profile successfully updated."
-// @Failure 400 {object} string "failed to deserialize json request."
-// @Failure 400 {object} string "Login or email already used."
-// @Failure 404 {object} string "No such user."
-// @Failure 500 {object} string "Internal error."
+// @Success 200 {object} u.UpdatedUser "Profile successfully updated."
+// @Failure 400 {object} util.Problem "failed to deserialize json request."
+// @Failure 400 {object} util.Problem "Login or email already used."
+// @Failure 400 {object} util.Problem "Invalid custom field value or locale."
+// @Failure 404 {object} util.Problem "No such user."
+// @Failure 422 {object} util.Problem "Username rejected by moderation."
+// @Failure 500 {object} util.Problem "Internal error."
 // @Router /user/profile [put]
-func UpdateUser(log *slog.Logger, User UserHandler) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		const op = "http-server.handlers.user.UpdateUser"
+func UpdateUser(log *slog.Logger, User UserHandler, mod *moderation.Moderator) http.HandlerFunc {
+	const op = "http-server.handlers.user.UpdateUser"
 
-		log.With(util.SlogWith(op, r)...)
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
 
 		var req u.PutUser
-		if err := render.DecodeJSON(r.Body, &req); err != nil {
-			log.Error("failed to decode request", sl.Err(err))
-
-			http.Error(w, "failed to deserialize json request", http.StatusBadRequest)
-
-			return
+		if err := util.DecodeJSON(r, &req); err != nil {
+			return nil, err
 		}
 
 		log.Info("request body decoded")
 		log.Debug("req: ", slog.Any("request", req))
 
-		userContext, ok := r.Context().Value(access.CxtKey("userContext")).(access.UserContext)
-		if !ok {
-			http.Error(w, "User context not found", http.StatusUnauthorized)
-			return
-		}
-
-		n, err := User.UpdateUser(req, userContext.UserId)
+		userID, err := contextUser(r)
 		if err != nil {
-			if n == 0 {
-				log.Info(err.Error())
+			return nil, err
+		}
 
-				http.Error(w, "No such user", http.StatusNotFound)
+		if req.Locale, err = mail.ParseLocale(req.Locale); err != nil {
+			return nil, util.WrapError(err, http.StatusBadRequest, util.CodeInvalidInput, fmt.Sprintf("Invalid input: %v", err))
+		}
 
-				return
-			} else if n == -2 {
+		verdict, err := mod.Check(r.Context(), req.Username)
+		if err != nil {
+			return nil, util.WrapError(err, http.StatusUnprocessableEntity, util.CodeRejected, "Username was rejected by moderation")
+		}
 
-				log.Info(err.Error())
+		before, err := User.Get(r.Context(), userID)
+		if err != nil {
+			return nil, util.NotFound(err, "No such user")
+		}
 
-				http.Error(w, "Login or email already used", http.StatusBadRequest)
+		schema, err := User.UserFields(r.Context())
+		if err != nil {
+			return nil, err
+		}
+		if _, err := schema.Apply(before.Custom, req.Custom, fields.UserEditable); err != nil {
+			return nil, util.WrapError(err, http.StatusBadRequest, util.CodeInvalidInput, fmt.Sprintf("Invalid input: %v", err))
+		}
 
-				return
+		n, err := User.UpdateUser(r.Context(), req, userID)
+		if err != nil {
+			switch n {
+			case 0:
+				return nil, util.WrapError(err, http.StatusNotFound, util.CodeNotFound, "No such user")
+			case -2:
+				return nil, util.WrapError(err, http.StatusBadRequest, util.CodeConflict, "Login or email already used")
 			}
-			util.InternalError(w, r, log, err)
-			return
+			return nil, err
 		}
 
-		user, err := User.Get(userContext.UserId)
+		user, err := User.Get(r.Context(), userID)
 		if err != nil {
-			util.InternalError(w, r, log, err)
-			return
+			return nil, err
+		}
+
+		mod.Record(r.Context(), verdict, moderation.Flag{Kind: moderation.KindUsername, UserID: user.ID, Ref: user.PublicID, Text: req.Username})
+
+		before.Custom = schema.Filter(before.Custom, fields.UserVisible)
+		user.Custom = schema.Filter(user.Custom, fields.UserVisible)
+		changes := u.Diff(before, user)
+		if len(changes) > 0 {
+			if err := User.Audit(r.Context(), userID, sdb.AuditUpdateProfile, userID, changes); err != nil {
+				log.Error("failed to record profile changes", sl.Err(err))
+			}
 		}
 
 		log.Info("Successfully updated user")
-		log.Debug(fmt.Sprintf("user: %v to %v with email %v", userContext, req.Username, req.Email))
+		log.Debug(fmt.Sprintf("user: %v to %v with email %v", userID, req.Username, req.Email))
 
-		render.JSON(w, r, user)
-	}
+		return u.UpdatedUser{TableUser: user, Changes: changes}, nil
+	})
 }
 
 // UpdatePassword godoc
 // @Summary Update user' Password
+// @ID changePassword
 // @Description Updates the user's password with new data provided in the JSON payload.
+// Users who must change their password may call it with their restricted token, then refresh the tokens to use the other routes.
 // The user must be authenticated and provide a valid JWT token.
 // @Tags user
 // @Accept json
 // @Produce json
 // @Param Password body u.Pwd true "New password"
 // @Security BearerAuth
-// @Success 200 {object} string "Profile successfully updated."
-// @Failure 400 {object} string "failed to deserialize json request."
-// @Failure 404 {object} string "No such user."
-// @Failure 500 {object} string "Internal error."
+// @Success 200 {object} string "Password changed."
+// @Failure 400 {object} util.Problem "failed to deserialize json request."
+// @Failure 400 {object} util.Problem "Invalid input, or a password violating the password policy."
+// @Failure 404 {object} util.Problem "No such user."
+// @Failure 500 {object} util.Problem "Internal error."
 // @Router /user/profile/reset-password [put]
-func ChangePassword(log *slog.Logger, User UserHandler) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		const op = "http-server.handlers.user.ChangePassword"
+func ChangePassword(log *slog.Logger, User UserHandler, policy *password.Policy) http.HandlerFunc {
+	const op = "http-server.handlers.user.ChangePassword"
 
-		log.With(util.SlogWith(op, r)...)
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
 
-		userContext, ok := r.Context().Value(access.CxtKey("userContext")).(access.UserContext)
-		if !ok {
-			http.Error(w, "User context not found", http.StatusUnauthorized)
-			return
+		userID, err := contextUser(r)
+		if err != nil {
+			return nil, err
 		}
 
 		var req u.Pwd
-		if err := render.DecodeJSON(r.Body, &req); err != nil {
-			log.Error("failed to decode request", sl.Err(err))
+		if err := util.DecodeJSON(r, &req); err != nil {
+			return nil, err
+		}
 
-			http.Error(w, "failed to deserialize json request", http.StatusBadRequest)
+		if err := util.Validate(req); err != nil {
+			return nil, err
+		}
+		if err := checkPassword(policy, req.Password); err != nil {
+			return nil, err
+		}
 
-			return
+		user, err := User.ChangePassword(r.Context(), req, userID)
+		if err != nil {
+			return nil, util.NotFound(err, "No such user")
 		}
 
-		user, err := User.ChangePassword(req, userContext.UserId)
+		log.Info("User's password successfully changed")
+		log.Debug(fmt.Sprintf("user: %v", user))
+
+		return nil, nil
+	})
+}
+
+// ChangeLogin godoc
+// @Summary Change user's login
+// @ID changeLogin
+// @Description Changes the login of the authenticated user. Logins can be changed once per cooldown period
+// (30 days by default). The old login stays reserved for the user for a while and admins can still find the user by it.
+// The user must be authenticated and provide a valid JWT token.
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param Login body u.LoginRequest true "New login"
+// @Security BearerAuth
+// @Success 200 {object} u.LoginChange "Login changed."
+// @Failure 400 {object} util.Problem "Invalid input."
+// @Failure 404 {object} util.Problem "No such user."
+// @Failure 409 {object} util.Problem "Login already used or reserved."
+// @Failure 429 {object} util.Problem "Login was changed recently, see Retry-After."
+// @Failure 500 {object} util.Problem "Internal error."
+// @Router /user/profile/login [put]
+func ChangeLogin(log *slog.Logger, User UserHandler, cooldown, hold time.Duration) http.HandlerFunc {
+	const op = "http-server.handlers.user.ChangeLogin"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		userID, err := contextUser(r)
 		if err != nil {
-			if err.Error() == "database.postgres.ChangePassword: no such user" {
-				log.Info(err.Error())
+			return nil, err
+		}
 
-				http.Error(w, "No such user", http.StatusNotFound)
+		var req u.LoginRequest
+		if err := util.DecodeJSON(r, &req); err != nil {
+			return nil, err
+		}
+		if err := util.Validate(req); err != nil {
+			return nil, err
+		}
 
-				return
-			}
-			util.InternalError(w, r, log, err)
-			return
+		next, err := User.ChangeLogin(r.Context(), userID, req.Login, cooldown, hold)
+		switch {
+		case errors.Is(err, sdb.ErrCooldown):
+			w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(next).Seconds())+1))
+			return nil, util.WrapError(err, http.StatusTooManyRequests, util.CodeCooldown,
+				fmt.Sprintf("Login can be changed again at %s", next.UTC().Format(time.RFC3339)))
+		case errors.Is(err, sdb.ErrAlreadyExists):
+			return nil, util.WrapError(err, http.StatusConflict, util.CodeConflict, "Login already used")
+		case err != nil:
+			return nil, util.NotFound(err, "No such user")
 		}
 
-		log.Info("User's password successfully changed")
-		log.Debug(fmt.Sprintf("user: %v", user))
+		log.Info("User's login successfully changed")
+
+		return u.LoginChange{Login: req.Login, NextChangeAt: next}, nil
+	})
+}
+
+// directoryAuth signs the user in with the directory, provisioning the local account.
+// It returns no user when the local password should be checked instead: the directory
+// does not know the login, rejects the password or is unreachable, or the login or email belongs to another account.
+func directoryAuth(ctx context.Context, log *slog.Logger, User UserHandler, dir Directory, req u.AuthData) (u.TableUser, error) {
+	id, err := dir.Authenticate(ctx, req.Login, req.Password)
+	if err != nil {
+		if !errors.Is(err, ldap.ErrUnknownUser) && !errors.Is(err, ldap.ErrInvalidCredentials) {
+			log.Error("directory authentication failed", sl.Err(err))
+		}
+		return u.TableUser{}, nil
+	}
+
+	user, err := User.ProvisionUser(ctx, u.ExternalUser{Source: ldap.Source, Login: id.Login, Username: id.Username, Email: id.Email, IsAdmin: id.IsAdmin})
+	if err != nil {
+		if errors.Is(err, sdb.ErrConflict) || errors.Is(err, sdb.ErrAlreadyExists) {
+			log.Warn("directory user clashes with another account", slog.String("login", id.Login), sl.Err(err))
+			return u.TableUser{}, nil
+		}
+		return u.TableUser{}, err
+	}
+
+	log.Info("directory user signed in", slog.String("dn", id.DN))
+	return user, nil
+}
+
+// signInTokens issues and sends the tokens of a sign in, remembering the device with rememberMe
+func signInTokens(w http.ResponseWriter, r *http.Request, User UserHandler, user u.TableUser, rememberMe bool, refreshTTL, rememberTTL time.Duration) (Tokens, error) {
+	if !rememberMe {
+		tokens, err := issueTokens(r.Context(), User, user, refreshTTL)
+		if err != nil {
+			return Tokens{}, err
+		}
+		return sendTokens(w, tokens, refreshTTL)
+	}
+
+	tokens, err := rememberDevice(w, r, User, user, rememberTTL)
+	if err != nil {
+		return Tokens{}, err
+	}
+	return sendTokens(w, tokens, rememberTTL)
+}
+
+// issueTokens signs a new access token for user and rotates their refresh token
+func issueTokens(ctx context.Context, User UserHandler, user u.TableUser, ttl time.Duration) (Tokens, error) {
+	accessToken, err := access.NewRoleToken(user.ID, user.Role, user.Permissions, user.MustChangePassword)
+	if err != nil {
+		return Tokens{}, fmt.Errorf("could not generate JWT accessToken: %w", err)
+	}
+
+	refreshToken, err := access.NewRefreshToken()
+	if err != nil {
+		return Tokens{}, fmt.Errorf("could not generate JWT refreshToken: %w", err)
+	}
+
+	if err := User.SaveRefreshToken(ctx, refreshToken, user.ID, clock.Now().Add(ttl)); err != nil {
+		return Tokens{}, err
+	}
+
+	return Tokens{AccessToken: AccessToken{accessToken}, RefreshToken: RefreshToken{refreshToken}, MustChangePassword: user.MustChangePassword}, nil
+}
+
+// sendTokens sets tokens as cookies in the cookie transport, the refresh token valid for ttl, and returns them
+// as the response body, without the tokens themselves when they went in cookies
+func sendTokens(w http.ResponseWriter, tokens Tokens, ttl time.Duration) (Tokens, error) {
+	if !access.CookiesEnabled() {
+		return tokens, nil
+	}
+
+	now := clock.Now()
+	if err := access.SetTokenCookies(w, tokens.AccessToken.Token, now.Add(access.AccessTTL), tokens.RefreshToken.Token, now.Add(ttl)); err != nil {
+		return Tokens{}, err
+	}
+	tokens.AccessToken, tokens.RefreshToken = AccessToken{}, RefreshToken{}
+	return tokens, nil
+}
+
+func contextUser(r *http.Request) (int, error) {
+	userContext, ok := r.Context().Value(access.CxtKey("userContext")).(access.UserContext)
+	if !ok {
+		return 0, util.NewError(http.StatusUnauthorized, util.CodeUnauthorized, "User context not found")
 	}
+	return userContext.UserId, nil
 }
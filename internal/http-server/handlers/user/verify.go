@@ -0,0 +1,155 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	sdb "github.com/sabbatD/srest-api/internal/database"
+	util "github.com/sabbatD/srest-api/internal/http-server/handleUtil"
+	"github.com/sabbatD/srest-api/internal/lib/clock"
+	"github.com/sabbatD/srest-api/internal/lib/logger/sl"
+	"github.com/sabbatD/srest-api/internal/lib/mail"
+	u "github.com/sabbatD/srest-api/internal/lib/userConfig"
+	"github.com/sabbatD/srest-api/internal/password"
+)
+
+// VerificationHandler issues and consumes email verification tokens, see database.Storage.StartEmailVerification
+type VerificationHandler interface {
+	StartEmailVerification(ctx context.Context, id int, tokenHash string, expires time.Time) (u.TableUser, error)
+	RequestEmailVerification(ctx context.Context, login, tokenHash string, expires time.Time) (u.TableUser, error)
+	VerifyEmail(ctx context.Context, tokenHash string) (int, error)
+}
+
+// Verification emails new accounts a link confirming their email, valid for TTL. The link is Link followed by the token.
+// Accounts are only verified while email is enabled, otherwise they are active from sign up.
+type Verification struct {
+	Store VerificationHandler
+	Mail  Mailer
+	TTL   time.Duration
+	Link  string
+}
+
+func (v *Verification) enabled() bool {
+	return v != nil && v.Mail.Enabled()
+}
+
+// send emails the verification link of a token valid until expires to user
+func (v *Verification) send(ctx context.Context, user u.TableUser, token string, expires time.Time) error {
+	vars := map[string]string{"Username": user.Username, "Expires": expires.UTC().Format(time.RFC1123), "Link": v.Link + token}
+	return v.Mail.Send(ctx, []string{user.Email}, mail.TemplateVerifyEmail, user.Locale, vars)
+}
+
+// start marks the new account id unverified and emails it the verification link
+func (v *Verification) start(ctx context.Context, id int) error {
+	token, hash, err := password.NewToken()
+	if err != nil {
+		return err
+	}
+	expires := clock.Now().Add(v.TTL)
+
+	user, err := v.Store.StartEmailVerification(ctx, id, hash, expires)
+	if err != nil {
+		return err
+	}
+	return v.send(ctx, user, token, expires)
+}
+
+// VerifyEmail godoc
+// @Summary Verify the email of an account
+// @ID verifyEmail
+// @Description Marks the account verified with the single-use token of the link emailed after sign up or POST /verify-email/resend.
+// @Tags user
+// @Produce json
+// @Param token query string true "Verification token"
+// @Success 200 {object} string "Email verified."
+// @Failure 400 {object} util.Problem "Missing token."
+// @Failure 404 {object} util.Problem "Unknown, used or expired verification token."
+// @Failure 500 {object} util.Problem "Internal error."
+// @Router /verify-email [get]
+func VerifyEmail(log *slog.Logger, Verifications VerificationHandler) http.HandlerFunc {
+	const op = "http-server.handlers.user.VerifyEmail"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		token := r.URL.Query().Get("token")
+		if token == "" {
+			return nil, util.NewError(http.StatusBadRequest, util.CodeBadRequest, "Missing token")
+		}
+
+		userID, err := Verifications.VerifyEmail(r.Context(), password.HashToken(token))
+		if err != nil {
+			return nil, util.NotFound(err, "Invalid or expired verification token")
+		}
+
+		log.Info("email verified", slog.Int("user_id", userID))
+
+		return nil, nil
+	})
+}
+
+// ResendVerification godoc
+// @Summary Request a new email verification link
+// @ID resendVerification
+// @Description Emails a new verification link to the unverified account with the given login or email, the earlier link stops working.
+// The answer is the same whether such an account exists or not. Requests are rate limited per client address.
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param Resend body u.VerificationResend true "Login or email of the account"
+// @Success 202 {object} string "Verification link emailed if the account exists and is unverified."
+// @Failure 400 {object} util.Problem "Invalid input."
+// @Failure 429 {object} util.Problem "Too many requests."
+// @Failure 500 {object} util.Problem "Internal error."
+// @Failure 503 {object} util.Problem "Email is not configured."
+// @Router /verify-email/resend [post]
+func ResendVerification(log *slog.Logger, v *Verification) http.HandlerFunc {
+	const op = "http-server.handlers.user.ResendVerification"
+
+	return util.Handle(log, op, func(w http.ResponseWriter, r *http.Request) (any, error) {
+		log := sl.FromContext(r.Context())
+
+		var req u.VerificationResend
+		if err := util.DecodeJSON(r, &req); err != nil {
+			return nil, err
+		}
+
+		log.Info("request body decoded")
+
+		if err := util.Validate(req); err != nil {
+			return nil, err
+		}
+		if !v.enabled() {
+			return nil, util.NewError(http.StatusServiceUnavailable, util.CodeUnavailable, "Email verification is not available")
+		}
+
+		token, hash, err := password.NewToken()
+		if err != nil {
+			return nil, err
+		}
+		expires := clock.Now().Add(v.TTL)
+
+		// Unknown and verified accounts get the same answer, so the route does not tell which logins exist
+		user, err := v.Store.RequestEmailVerification(r.Context(), req.Login, hash, expires)
+		if errors.Is(err, sdb.ErrNotFound) {
+			log.Info("verification requested for an unknown or verified account")
+			w.WriteHeader(http.StatusAccepted)
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if err := v.send(r.Context(), user, token, expires); err != nil {
+			return nil, err
+		}
+
+		log.Info("verification link emailed", slog.Int("user_id", user.ID))
+
+		w.WriteHeader(http.StatusAccepted)
+		return nil, nil
+	})
+}
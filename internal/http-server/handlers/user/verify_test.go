@@ -0,0 +1,102 @@
+package user
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	sdb "github.com/sabbatD/srest-api/internal/database"
+	"github.com/sabbatD/srest-api/internal/lib/mail"
+	u "github.com/sabbatD/srest-api/internal/lib/userConfig"
+	"github.com/sabbatD/srest-api/internal/password"
+)
+
+type memVerifications struct {
+	user     u.TableUser
+	hash     string
+	verified bool
+}
+
+func (m *memVerifications) StartEmailVerification(ctx context.Context, id int, tokenHash string, expires time.Time) (u.TableUser, error) {
+	m.hash, m.verified = tokenHash, false
+	return m.user, nil
+}
+
+func (m *memVerifications) RequestEmailVerification(ctx context.Context, login, tokenHash string, expires time.Time) (u.TableUser, error) {
+	if m.verified || (login != "alice" && login != m.user.Email) {
+		return u.TableUser{}, sdb.ErrNotFound
+	}
+	m.hash = tokenHash
+	return m.user, nil
+}
+
+func (m *memVerifications) VerifyEmail(ctx context.Context, tokenHash string) (int, error) {
+	if tokenHash != m.hash {
+		return 0, sdb.ErrNotFound
+	}
+	m.hash, m.verified = "", true
+	return m.user.ID, nil
+}
+
+func TestVerifyEmail(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := &memVerifications{user: u.TableUser{ID: 7, Username: "Alice", Email: "alice@example.com"}}
+	mailer := &memMailer{enabled: true}
+	v := &Verification{Store: store, Mail: mailer, TTL: time.Hour, Link: "https://example.com/verify-email?token="}
+
+	if err := v.start(context.Background(), 7); err != nil {
+		t.Fatal(err)
+	}
+	resend := ResendVerification(log, v)
+	post := func(body string) int {
+		rec := httptest.NewRecorder()
+		resend.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/verify-email/resend", strings.NewReader(body)))
+		return rec.Code
+	}
+	if code := post(`{"login": "alice@example.com"}`); code != http.StatusAccepted || len(mailer.sent) != 2 {
+		t.Fatalf("resend: status %d, %d emails", code, len(mailer.sent))
+	}
+
+	// Only the last link works
+	links := []string{mailer.sent[0].vars["Link"], mailer.sent[1].vars["Link"]}
+	for _, sent := range mailer.sent {
+		if sent.name != mail.TemplateVerifyEmail || sent.to[0] != "alice@example.com" {
+			t.Errorf("email = %+v", sent)
+		}
+	}
+	token, _ := strings.CutPrefix(links[1], "https://example.com/verify-email?token=")
+	if store.hash != password.HashToken(token) {
+		t.Error("stored hash does not match the emailed token")
+	}
+
+	verify := VerifyEmail(log, store)
+	get := func(url string) int {
+		rec := httptest.NewRecorder()
+		verify.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		return rec.Code
+	}
+	if code := get("/verify-email?token=" + strings.TrimPrefix(links[0], "https://example.com/verify-email?token=")); code != http.StatusNotFound {
+		t.Errorf("replaced token: status %d, want 404", code)
+	}
+	if code := get("/verify-email"); code != http.StatusBadRequest {
+		t.Errorf("missing token: status %d, want 400", code)
+	}
+	if code := get("/verify-email?token=" + token); code != http.StatusOK || !store.verified {
+		t.Errorf("verify: status %d, verified %v", code, store.verified)
+	}
+
+	// Verified accounts get the same answer and no email
+	if code := post(`{"login": "alice"}`); code != http.StatusAccepted || len(mailer.sent) != 2 {
+		t.Errorf("verified account: status %d, %d emails", code, len(mailer.sent))
+	}
+
+	mailer.enabled = false
+	if code := post(`{"login": "alice"}`); code != http.StatusServiceUnavailable {
+		t.Errorf("email disabled: status %d, want 503", code)
+	}
+}
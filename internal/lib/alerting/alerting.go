@@ -0,0 +1,465 @@
+// Package alerting notifies operators when the error rate, failed logins or background job
+// failures cross a threshold, for deployments without a monitoring stack.
+// The rules come from the configuration unless an admin set them in the settings.
+package alerting
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sabbatD/srest-api/internal/lib/clock"
+	"github.com/sabbatD/srest-api/internal/lib/jobs"
+	"github.com/sabbatD/srest-api/internal/lib/logger/sl"
+	"github.com/sabbatD/srest-api/internal/lib/mail"
+	"github.com/sabbatD/srest-api/internal/lib/metrics"
+	"github.com/sabbatD/srest-api/internal/lib/secrets"
+)
+
+// Kinds of alerts
+const (
+	KindErrorRate    = "error_rate"
+	KindFailedLogins = "failed_logins"
+	KindJobFailures  = "job_failures"
+)
+
+// JobWebhook is the job posting an alert to the webhook, see Evaluator.SetJobs
+const JobWebhook = "alert.webhook"
+
+// maxWindow bounds WindowMinutes, counter snapshots are kept that long
+const maxWindow = time.Hour
+
+// Rules hold the alert thresholds over a sliding window and where alerts are sent, a zero threshold disables its alert
+type Rules struct {
+	// ErrorRate is the share of 5xx responses, from 0 to 1
+	ErrorRate float64 `json:"errorRate" yaml:"error_rate" env-default:"0.05" validate:"min=0,max=1"`
+	// MinRequests keeps a few failed requests on a quiet server from raising the error rate alert
+	MinRequests     int `json:"minRequests" yaml:"min_requests" env-default:"50" validate:"min=0"`
+	FailedLogins    int `json:"failedLogins" yaml:"failed_logins" env-default:"50" validate:"min=0"`
+	JobFailures     int `json:"jobFailures" yaml:"job_failures" env-default:"1" validate:"min=0"`
+	WindowMinutes   int `json:"windowMinutes" yaml:"window_minutes" env-default:"5" validate:"min=1,max=60"`
+	CooldownMinutes int `json:"cooldownMinutes" yaml:"cooldown_minutes" env-default:"30" validate:"min=0,max=1440"`
+	// WebhookURL receives every alert as a JSON POST
+	WebhookURL string `json:"webhookUrl,omitempty" yaml:"webhook_url" env:"ALERT_WEBHOOK_URL" validate:"omitempty,url" redact:"true"`
+	// Emails receive every alert when SMTP is configured, see mail.Config
+	Emails []string `json:"emails,omitempty" yaml:"emails" validate:"max=20,dive,email"`
+}
+
+// Config is the default rules and the interval they are checked at, a zero interval disables alerting
+type Config struct {
+	Interval time.Duration `yaml:"interval" env-default:"1m"`
+	// Timeout bounds each notification
+	Timeout time.Duration `yaml:"timeout" env-default:"10s"`
+	Rules   `yaml:",inline"`
+}
+
+// Alert is a crossed threshold
+type Alert struct {
+	Kind string `json:"kind"`
+	// Value is the error rate or the count observed over the window
+	Value         float64   `json:"value"`
+	Threshold     float64   `json:"threshold"`
+	WindowMinutes int       `json:"windowMinutes"`
+	At            time.Time `json:"at"`
+	Message       string    `json:"message"`
+	// Countries counts the failed logins of the window by the country of the client, when a GeoIP database locates them
+	Countries map[string]int64 `json:"countries,omitempty"`
+}
+
+type Store interface {
+	// AlertRules returns the rules set by an admin, false when there are none
+	AlertRules(ctx context.Context) (Rules, bool, error)
+	SetAlertRules(ctx context.Context, actor int, rules Rules) error
+}
+
+// Requests summarizes the served requests, see metrics.Latency
+type Requests interface {
+	Summary(now time.Time, window time.Duration) metrics.Summary
+}
+
+// Jobs runs notifications in the background, see jobs.Worker
+type Jobs interface {
+	Enqueue(queue, kind string, payload any) error
+	Handle(kind string, h jobs.Handler)
+}
+
+// counters are the process totals of failed logins and job failures, they only grow
+type counters struct {
+	at           time.Time
+	failedLogins int64
+	// failedCountries are the failed logins by country, see metrics.FailedLoginCountries
+	failedCountries map[string]int64
+	jobFailures     int64
+}
+
+type Evaluator struct {
+	log      *slog.Logger
+	store    Store
+	requests Requests
+	mailer   *mail.Templates
+	client   *http.Client
+	cfg      Config
+	read     func() counters
+	// secrets keeps the webhook URL sealed, it may carry a token of the chat integration
+	secrets *secrets.Secrets
+	// jobs posts to the webhook with retries when set
+	jobs Jobs
+
+	mu        sync.Mutex
+	snapshots []counters
+	last      map[string]time.Time
+}
+
+// New returns an evaluator of the request summary and the metrics counters, mailer may be nil
+func New(log *slog.Logger, store Store, requests Requests, mailer *mail.Templates, cfg Config) *Evaluator {
+	return &Evaluator{
+		log:      log,
+		store:    store,
+		requests: requests,
+		mailer:   mailer,
+		client:   &http.Client{Timeout: cfg.Timeout},
+		cfg:      cfg,
+		read:     readCounters,
+		last:     map[string]time.Time{},
+	}
+}
+
+func readCounters() counters {
+	c := counters{failedLogins: metrics.FailedLogins.Value(), failedCountries: map[string]int64{}}
+	metrics.FailedLoginCountries.Do(func(kv expvar.KeyValue) {
+		if n, ok := kv.Value.(*expvar.Int); ok {
+			c.failedCountries[kv.Key] = n.Value()
+		}
+	})
+	metrics.JobFailures.Do(func(kv expvar.KeyValue) {
+		if n, ok := kv.Value.(*expvar.Int); ok {
+			c.jobFailures += n.Value()
+		}
+	})
+	return c
+}
+
+// SetSecrets keeps the webhook URL admins set in s instead of the settings. The stored secret overrides
+// the configured URL and Rules leaves it out.
+func (e *Evaluator) SetSecrets(s *secrets.Secrets) {
+	e.secrets = s
+}
+
+// SetJobs posts the alerts to the webhook from the webhooks queue of q, retried while the webhook fails.
+// The job only carries the alert, it is posted to the webhook URL in effect when it runs.
+func (e *Evaluator) SetJobs(q Jobs) {
+	e.jobs = q
+	q.Handle(JobWebhook, e.deliver)
+}
+
+// Rules returns the rules in effect: the admin set ones, or the configured default.
+// A webhook URL kept in the secrets is not returned.
+func (e *Evaluator) Rules(ctx context.Context) (Rules, error) {
+	const op = "lib.alerting.Rules"
+
+	rules, ok, err := e.store.AlertRules(ctx)
+	if err != nil {
+		return Rules{}, fmt.Errorf("%s: %v", op, err)
+	}
+	if !ok {
+		return e.cfg.Rules, nil
+	}
+	return rules, nil
+}
+
+// effective returns the rules in effect with the webhook URL kept in the secrets
+func (e *Evaluator) effective(ctx context.Context) (Rules, error) {
+	rules, err := e.Rules(ctx)
+	if err != nil {
+		return Rules{}, err
+	}
+
+	url, ok, err := e.secrets.Get(ctx, secrets.AlertWebhookURL)
+	if err != nil {
+		return Rules{}, err
+	}
+	if ok {
+		rules.WebhookURL = url
+	}
+	return rules, nil
+}
+
+// SetRules stores the rules set by actor, they apply from the next check. With secrets the webhook URL is sealed
+// in them, an empty one keeps the stored URL.
+func (e *Evaluator) SetRules(ctx context.Context, actor int, rules Rules) error {
+	const op = "lib.alerting.SetRules"
+
+	if e.secrets.Enabled() && rules.WebhookURL != "" {
+		if err := e.secrets.Set(ctx, actor, secrets.AlertWebhookURL, rules.WebhookURL); err != nil {
+			return fmt.Errorf("%s: %v", op, err)
+		}
+		rules.WebhookURL = ""
+	}
+
+	if err := e.store.SetAlertRules(ctx, actor, rules); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	return nil
+}
+
+// Check evaluates the rules over the window ending at now and sends the alerts out of their cooldown.
+// A failing notification does not stop the others, the first error is returned along with the alerts.
+func (e *Evaluator) Check(ctx context.Context, now time.Time) ([]Alert, error) {
+	const op = "lib.alerting.Check"
+
+	rules, err := e.effective(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	e.mu.Lock()
+	alerts := e.evaluate(rules, now)
+	var due []Alert
+	for _, a := range alerts {
+		cooldown := time.Duration(rules.CooldownMinutes) * time.Minute
+		if last, ok := e.last[a.Kind]; ok && now.Sub(last) < cooldown {
+			continue
+		}
+		e.last[a.Kind] = now
+		due = append(due, a)
+	}
+	e.mu.Unlock()
+
+	var first error
+	for _, a := range due {
+		metrics.Alerts.Add(a.Kind, 1)
+		if err := e.notify(ctx, rules, a); err != nil && first == nil {
+			first = fmt.Errorf("%s: %v", op, err)
+		}
+	}
+
+	return due, first
+}
+
+// evaluate records the current counters and returns every crossed threshold, e.mu must be held
+func (e *Evaluator) evaluate(rules Rules, now time.Time) []Alert {
+	window := time.Duration(rules.WindowMinutes) * time.Minute
+	current := e.read()
+	current.at = now
+	base := e.baseline(now.Add(-window))
+	e.record(current)
+
+	var alerts []Alert
+	if rules.ErrorRate > 0 {
+		var requests, failed int
+		for _, route := range e.requests.Summary(now, window).Routes {
+			requests += route.Requests
+			failed += route.ServerErrors
+		}
+		if requests > 0 && requests >= rules.MinRequests {
+			if rate := float64(failed) / float64(requests); rate >= rules.ErrorRate {
+				alerts = append(alerts, Alert{
+					Kind: KindErrorRate, Value: rate, Threshold: rules.ErrorRate,
+					Message: fmt.Sprintf("%d of %d requests failed with a server error", failed, requests),
+				})
+			}
+		}
+	}
+	if n := current.failedLogins - base.failedLogins; rules.FailedLogins > 0 && n >= int64(rules.FailedLogins) {
+		a := Alert{
+			Kind: KindFailedLogins, Value: float64(n), Threshold: float64(rules.FailedLogins),
+			Message: fmt.Sprintf("%d failed logins", n),
+		}
+		for country, total := range current.failedCountries {
+			if d := total - base.failedCountries[country]; d > 0 {
+				if a.Countries == nil {
+					a.Countries = map[string]int64{}
+				}
+				a.Countries[country] = d
+			}
+		}
+		if top := topCountries(a.Countries, 3); top != "" {
+			a.Message += ", most from " + top
+		}
+		alerts = append(alerts, a)
+	}
+	if n := current.jobFailures - base.jobFailures; rules.JobFailures > 0 && n >= int64(rules.JobFailures) {
+		alerts = append(alerts, Alert{
+			Kind: KindJobFailures, Value: float64(n), Threshold: float64(rules.JobFailures),
+			Message: fmt.Sprintf("%d background jobs failed", n),
+		})
+	}
+
+	for i := range alerts {
+		alerts[i].WindowMinutes, alerts[i].At = rules.WindowMinutes, now
+		alerts[i].Message += fmt.Sprintf(" in the last %d minutes", rules.WindowMinutes)
+	}
+	return alerts
+}
+
+// topCountries lists the n countries with the most failed logins, e.g. "DE (30), US (12)"
+func topCountries(countries map[string]int64, n int) string {
+	names := make([]string, 0, len(countries))
+	for c := range countries {
+		names = append(names, c)
+	}
+	slices.SortFunc(names, func(a, b string) int {
+		if c := cmp.Compare(countries[b], countries[a]); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	})
+
+	top := make([]string, 0, n)
+	for _, c := range names[:min(n, len(names))] {
+		top = append(top, fmt.Sprintf("%s (%d)", c, countries[c]))
+	}
+	return strings.Join(top, ", ")
+}
+
+// baseline returns the latest snapshot taken at or before start. Counters start at zero
+// with the process, so without one the totals are the counts.
+func (e *Evaluator) baseline(start time.Time) counters {
+	var base counters
+	for _, s := range e.snapshots {
+		if s.at.After(start) {
+			break
+		}
+		base = s
+	}
+	return base
+}
+
+// record appends a snapshot and drops the ones no window can reach, keeping the latest of those as a baseline
+func (e *Evaluator) record(c counters) {
+	e.snapshots = append(e.snapshots, c)
+	cutoff := c.at.Add(-maxWindow)
+	i := 0
+	for i+1 < len(e.snapshots) && !e.snapshots[i+1].at.After(cutoff) {
+		i++
+	}
+	e.snapshots = e.snapshots[i:]
+}
+
+type webhookAlert struct {
+	Event string `json:"event"`
+	Alert Alert  `json:"alert"`
+}
+
+// notify posts the alert to the webhook and mails it, both are tried
+func (e *Evaluator) notify(ctx context.Context, rules Rules, a Alert) error {
+	const op = "lib.alerting.notify"
+
+	ctx, cancel := context.WithTimeout(ctx, e.cfg.Timeout)
+	defer cancel()
+
+	log := e.log.With(slog.String("op", op), slog.String("kind", a.Kind))
+	log.Warn("alert raised", slog.String("message", a.Message))
+
+	var first error
+	if rules.WebhookURL != "" {
+		var err error
+		if e.jobs != nil {
+			err = e.jobs.Enqueue(jobs.QueueWebhooks, JobWebhook, a)
+		} else {
+			err = e.post(ctx, rules.WebhookURL, a)
+		}
+		if err != nil {
+			log.Error("failed to post alert", sl.Err(err))
+			first = err
+		}
+	}
+	if len(rules.Emails) > 0 && e.mailer.Enabled() {
+		vars := map[string]string{
+			"Kind":      strings.ReplaceAll(a.Kind, "_", " "),
+			"Message":   a.Message,
+			"Threshold": fmt.Sprintf("%g", a.Threshold),
+			"Observed":  fmt.Sprintf("%g", a.Value),
+			"At":        a.At.UTC().Format(time.RFC3339),
+		}
+		// Alerts go to configured addresses rather than users, they are sent in the default locale
+		if err := e.mailer.Send(ctx, rules.Emails, mail.TemplateAlert, "", vars); err != nil {
+			log.Error("failed to mail alert", sl.Err(err))
+			if first == nil {
+				first = err
+			}
+		}
+	}
+
+	return first
+}
+
+// deliver runs the job posting an alert to the webhook, nothing is posted when the webhook was removed since
+func (e *Evaluator) deliver(ctx context.Context, payload json.RawMessage) error {
+	const op = "lib.alerting.deliver"
+
+	var a Alert
+	if err := json.Unmarshal(payload, &a); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	rules, err := e.effective(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	if rules.WebhookURL == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, e.cfg.Timeout)
+	defer cancel()
+	return e.post(ctx, rules.WebhookURL, a)
+}
+
+func (e *Evaluator) post(ctx context.Context, url string, a Alert) error {
+	const op = "lib.alerting.post"
+
+	body, err := json.Marshal(webhookAlert{Event: "alert." + a.Kind, Alert: a})
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s: unexpected status %s", op, resp.Status)
+	}
+
+	return nil
+}
+
+// Run checks the rules every configured interval until ctx is done
+func (e *Evaluator) Run(ctx context.Context) {
+	const op = "lib.alerting.Run"
+
+	if e.cfg.Interval <= 0 {
+		return
+	}
+	log := e.log.With(slog.String("op", op))
+
+	ticker := time.NewTicker(e.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := e.Check(ctx, clock.Now()); err != nil {
+				log.Error("alert check failed", sl.Err(err))
+			}
+		}
+	}
+}
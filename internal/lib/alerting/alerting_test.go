@@ -0,0 +1,237 @@
+package alerting
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sabbatD/srest-api/internal/lib/jobs"
+	"github.com/sabbatD/srest-api/internal/lib/metrics"
+	"github.com/sabbatD/srest-api/internal/lib/secrets"
+)
+
+type memStore struct {
+	rules Rules
+	set   bool
+}
+
+func (m *memStore) AlertRules(ctx context.Context) (Rules, bool, error) {
+	return m.rules, m.set, nil
+}
+
+func (m *memStore) SetAlertRules(ctx context.Context, actor int, rules Rules) error {
+	m.rules, m.set = rules, true
+	return nil
+}
+
+type fakeRequests struct {
+	requests, serverErrors int
+}
+
+func (f *fakeRequests) Summary(now time.Time, window time.Duration) metrics.Summary {
+	return metrics.Summary{Routes: []metrics.RouteSummary{{Route: "GET /todos", Requests: f.requests, ServerErrors: f.serverErrors}}}
+}
+
+func TestCheck(t *testing.T) {
+	var posted []webhookAlert
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var a webhookAlert
+		json.NewDecoder(r.Body).Decode(&a)
+		posted = append(posted, a)
+	}))
+	defer srv.Close()
+
+	requests := &fakeRequests{requests: 100, serverErrors: 2}
+	var current counters
+	cfg := Config{Timeout: time.Second, Rules: Rules{
+		ErrorRate: 0.05, MinRequests: 50, FailedLogins: 10, JobFailures: 1,
+		WindowMinutes: 5, CooldownMinutes: 30, WebhookURL: srv.URL,
+	}}
+	e := New(slog.New(slog.NewTextHandler(io.Discard, nil)), &memStore{}, requests, nil, cfg)
+	e.read = func() counters { return current }
+
+	kinds := func(alerts []Alert) []string {
+		var k []string
+		for _, a := range alerts {
+			k = append(k, a.Kind)
+		}
+		return k
+	}
+
+	start := time.Date(2024, 10, 1, 12, 0, 0, 0, time.UTC)
+	alerts, err := e.Check(context.Background(), start)
+	if err != nil || len(alerts) != 0 {
+		t.Fatalf("quiet check = %v, %v", kinds(alerts), err)
+	}
+
+	// Failed logins count from the snapshot taken a window ago.
+	current.failedLogins = 8
+	e.Check(context.Background(), start.Add(3*time.Minute))
+	current.failedLogins = 9
+	alerts, _ = e.Check(context.Background(), start.Add(6*time.Minute))
+	if len(alerts) != 0 {
+		t.Errorf("9 failed logins in the window raised %v", kinds(alerts))
+	}
+	current.failedLogins = 20
+	current.failedCountries = map[string]int64{"DE": 9, "US": 2, "FR": 1}
+	alerts, _ = e.Check(context.Background(), start.Add(9*time.Minute))
+	if len(alerts) != 1 || alerts[0].Kind != KindFailedLogins || alerts[0].Value != 12 {
+		t.Errorf("12 failed logins in the window raised %+v", alerts)
+	}
+	if len(alerts) == 1 && alerts[0].Message != "12 failed logins, most from DE (9), US (2), FR (1) in the last 5 minutes" {
+		t.Errorf("message = %q", alerts[0].Message)
+	}
+
+	requests.serverErrors = 10
+	current.jobFailures = 1
+	alerts, err = e.Check(context.Background(), start.Add(10*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := kinds(alerts); len(got) != 2 || got[0] != KindErrorRate || got[1] != KindJobFailures {
+		t.Errorf("alerts = %v, want error rate and job failures without the failed logins in cooldown", got)
+	}
+	if len(posted) != 3 || posted[1].Event != "alert.error_rate" || posted[1].Alert.Value != 0.1 {
+		t.Errorf("posted = %+v", posted)
+	}
+
+	// Too few requests for the error rate.
+	requests.requests, requests.serverErrors = 10, 10
+	if alerts, _ := e.Check(context.Background(), start.Add(50*time.Minute)); len(alerts) != 0 {
+		t.Errorf("quiet server raised %v", kinds(alerts))
+	}
+}
+
+type fakeJobs struct {
+	queued   []json.RawMessage
+	handlers map[string]jobs.Handler
+}
+
+func (f *fakeJobs) Enqueue(queue, kind string, payload any) error {
+	data, err := json.Marshal(payload)
+	f.queued = append(f.queued, data)
+	return err
+}
+
+func (f *fakeJobs) Handle(kind string, h jobs.Handler) {
+	f.handlers[kind] = h
+}
+
+func TestJobs(t *testing.T) {
+	var posted []webhookAlert
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var a webhookAlert
+		json.NewDecoder(r.Body).Decode(&a)
+		posted = append(posted, a)
+	}))
+	defer srv.Close()
+
+	store := &memStore{}
+	cfg := Config{Timeout: time.Second, Rules: Rules{JobFailures: 1, WindowMinutes: 5, WebhookURL: srv.URL}}
+	e := New(slog.New(slog.NewTextHandler(io.Discard, nil)), store, &fakeRequests{}, nil, cfg)
+	var current counters
+	e.read = func() counters { return current }
+	q := &fakeJobs{handlers: map[string]jobs.Handler{}}
+	e.SetJobs(q)
+
+	start := time.Date(2024, 10, 1, 12, 0, 0, 0, time.UTC)
+	e.Check(context.Background(), start)
+	current.jobFailures = 1
+	if alerts, err := e.Check(context.Background(), start.Add(time.Minute)); err != nil || len(alerts) != 1 {
+		t.Fatalf("Check() = %v, %v", alerts, err)
+	}
+	if len(q.queued) != 1 || len(posted) != 0 {
+		t.Fatalf("queued %d, posted %d, want the alert queued rather than posted", len(q.queued), len(posted))
+	}
+
+	if err := q.handlers[JobWebhook](context.Background(), q.queued[0]); err != nil {
+		t.Fatal(err)
+	}
+	if len(posted) != 1 || posted[0].Event != "alert.job_failures" {
+		t.Errorf("posted = %+v", posted)
+	}
+
+	// The webhook removed since the alert was queued is not posted to
+	store.rules, store.set = Rules{WindowMinutes: 5}, true
+	if err := q.handlers[JobWebhook](context.Background(), q.queued[0]); err != nil || len(posted) != 1 {
+		t.Errorf("job without a webhook = %v, posted %d", err, len(posted))
+	}
+}
+
+func TestRules(t *testing.T) {
+	store := &memStore{}
+	def := Rules{ErrorRate: 0.05, WindowMinutes: 5}
+	e := New(slog.New(slog.NewTextHandler(io.Discard, nil)), store, &fakeRequests{}, nil, Config{Rules: def})
+
+	if got, err := e.Rules(context.Background()); err != nil || got.ErrorRate != def.ErrorRate {
+		t.Errorf("default rules = %+v, %v", got, err)
+	}
+	set := Rules{FailedLogins: 5, WindowMinutes: 1}
+	if err := e.SetRules(context.Background(), 1, set); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := e.Rules(context.Background()); got.FailedLogins != 5 || got.ErrorRate != 0 {
+		t.Errorf("rules = %+v, want the admin set ones", got)
+	}
+}
+
+type memSecrets map[string][]byte
+
+func (m memSecrets) Secret(ctx context.Context, name string) ([]byte, bool, error) {
+	b, ok := m[name]
+	return b, ok, nil
+}
+
+func (m memSecrets) SealedSecrets(ctx context.Context) (map[string][]byte, error) {
+	return m, nil
+}
+
+func (m memSecrets) SecretInfos(ctx context.Context) ([]secrets.Info, error) {
+	return nil, nil
+}
+
+func (m memSecrets) DeleteSecret(ctx context.Context, actor int, name string) error {
+	delete(m, name)
+	return nil
+}
+
+func (m memSecrets) SetSecret(ctx context.Context, actor int, name string, sealed []byte) error {
+	m[name] = sealed
+	return nil
+}
+
+func TestWebhookSecret(t *testing.T) {
+	ctx := context.Background()
+	store := &memStore{}
+	e := New(slog.New(slog.NewTextHandler(io.Discard, nil)), store, &fakeRequests{}, nil, Config{})
+
+	key, _ := secrets.GenerateKey()
+	c, err := secrets.Load(secrets.Config{Key: key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed := memSecrets{}
+	e.SetSecrets(secrets.New(sealed, c))
+
+	hook := "https://chat.example.com/hooks/token"
+	if err := e.SetRules(ctx, 1, Rules{WindowMinutes: 5, WebhookURL: hook}); err != nil {
+		t.Fatal(err)
+	}
+	if store.rules.WebhookURL != "" || len(sealed[secrets.AlertWebhookURL]) == 0 {
+		t.Errorf("webhook URL stored in the settings: %+v", store.rules)
+	}
+	if got, _ := e.Rules(ctx); got.WebhookURL != "" {
+		t.Errorf("Rules() returned the sealed webhook URL %q", got.WebhookURL)
+	}
+
+	// Rules set without a URL keep the sealed one.
+	e.SetRules(ctx, 1, Rules{WindowMinutes: 10})
+	if got, err := e.effective(ctx); err != nil || got.WebhookURL != hook || got.WindowMinutes != 10 {
+		t.Errorf("effective() = %+v, %v", got, err)
+	}
+}
@@ -2,21 +2,42 @@ package access
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"log/slog"
 	"net/http"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
+	util "github.com/sabbatD/srest-api/internal/http-server/handleUtil"
+	"github.com/sabbatD/srest-api/internal/lib/clock"
+	"github.com/sabbatD/srest-api/internal/lib/logger/sl"
+	rc "github.com/sabbatD/srest-api/internal/lib/roleConfig"
 )
 
-var jwtKey = []byte(`b3BlbnNzaC1rZXktdjEAAAAACmFlczI1Ni1jdHIAAAAGYmNyeXB0AAAAGAAAABDIsCk4b4SwgpWaZXbeuCXUAAAAEAAAAAEAAAGXAAAAB3NzaC1yc2EAAAADAQABAAABgQCwN27MXT2rYoNIzwqPtHxIBiJhlPLWEAakzCxQesr8W0hBHrMBWfsVvYhCF+l4vdPwcTL6Vav6FefAQICrgEpnMtzT3i25KT4vV/4Q07oqhNvNp`)
+// Token expiry is checked against the process clock, see clock.Set.
+// While the clock is frozen, access tokens issued at the frozen time never expire.
+func init() {
+	jwt.TimeFunc = clock.Now
+}
 
 type CxtKey string
 
 type Claims struct {
 	UserId  int  `json:"id"`
 	IsAdmin bool `json:"isAdmin"`
+	IsGuest bool `json:"guest,omitempty"`
+	// MustChangePassword restricts the token to the password change, see PasswordChangeMiddleware
+	MustChangePassword bool `json:"mustChangePassword,omitempty"`
+	// Scope is granted to OpenID Connect clients, whose tokens name them in aud, see NewOIDCAccessToken
+	Scope string `json:"scope,omitempty"`
+	// Role of the user and the Permissions it grants, see RequireScope
+	Role        string   `json:"role,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
 	jwt.StandardClaims
 }
 
@@ -24,20 +45,86 @@ type UserContext struct {
 	UserId    int  `json:"id"`
 	IsAdmin   bool `json:"isAdmin"`
 	IsBlocked bool `json:"isBlocked"`
+	IsGuest   bool `json:"guest,omitempty"`
+	// Role and Permissions of the access token, see UserContext.Can
+	Role        string   `json:"role,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+	// TokenID is the JTI of the access token and TokenExpires its expiry, see Denylist
+	TokenID      string    `json:"-"`
+	TokenExpires time.Time `json:"-"`
+}
+
+// Denylist tells the access tokens revoked before they expire, e.g. on logout
+type Denylist interface {
+	TokenRevoked(ctx context.Context, jti string) (bool, error)
 }
 
-func NewAccessToken(id int, admin bool) (string, error) {
-	expirationTime := time.Now().Add(2 * time.Hour)
+// holder keeps atomic.Value storing a single concrete type
+type holder struct{ Denylist }
+
+var denylist atomic.Value
+
+func init() {
+	denylist.Store(holder{})
+}
+
+// SetDenylist makes the middlewares refuse the tokens d revoked and returns a func restoring the previous denylist,
+// e.g. for t.Cleanup. Without a denylist tokens are valid until they expire.
+func SetDenylist(d Denylist) (restore func()) {
+	prev := denylist.Swap(holder{d})
+	return func() { denylist.Store(prev) }
+}
+
+// revoked reports whether the token was revoked, tokens issued without a JTI cannot be
+func revoked(ctx context.Context, claims *Claims) (bool, error) {
+	d := denylist.Load().(holder).Denylist
+	if d == nil || claims.Id == "" {
+		return false, nil
+	}
+	return d.TokenRevoked(ctx, claims.Id)
+}
+
+// newTokenID returns a random JTI, access tokens are revoked by it
+func newTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// AccessTTL is the lifetime of access tokens
+const AccessTTL = 2 * time.Hour
+
+// NewAccessToken returns the access token of an admin or of a user without permissions, see NewRoleToken
+func NewAccessToken(id int, admin, mustChangePassword bool) (string, error) {
+	if admin {
+		return NewRoleToken(id, rc.Admin, rc.Permissions, mustChangePassword)
+	}
+	return NewRoleToken(id, rc.User, nil, mustChangePassword)
+}
+
+// NewRoleToken returns the access token of a user with the role and the permissions it grants
+func NewRoleToken(id int, role string, permissions []string, mustChangePassword bool) (string, error) {
+	jti, err := newTokenID()
+	if err != nil {
+		return "", err
+	}
+
+	expirationTime := clock.Now().Add(AccessTTL)
 	claims := &Claims{
-		UserId:  id,
-		IsAdmin: admin,
+		UserId:             id,
+		IsAdmin:            role == rc.Admin,
+		MustChangePassword: mustChangePassword,
+		Role:               role,
+		Permissions:        permissions,
 		StandardClaims: jwt.StandardClaims{
+			Id:        jti,
 			ExpiresAt: expirationTime.Unix(),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(jwtKey)
+	tokenString, err := signToken(claims)
 	if err != nil {
 		return "", err
 	}
@@ -45,39 +132,165 @@ func NewAccessToken(id int, admin bool) (string, error) {
 	return tokenString, nil
 }
 
-func NewRefreshToken() (string, error) {
-	token := jwt.New(jwt.SigningMethodHS256)
-	key := []byte(strconv.Itoa(int(time.Now().Unix())))
-	tokenString, err := token.SignedString(key)
+// NewGuestToken returns an access token of a guest, valid for ttl and only on routes allowing guests
+func NewGuestToken(id int, ttl time.Duration) (string, time.Time, error) {
+	jti, err := newTokenID()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	expirationTime := clock.Now().Add(ttl)
+	claims := &Claims{
+		UserId:  id,
+		IsGuest: true,
+		StandardClaims: jwt.StandardClaims{
+			Id:        jti,
+			ExpiresAt: expirationTime.Unix(),
+		},
+	}
+
+	tokenString, err := signToken(claims)
 	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return tokenString, expirationTime, nil
+}
+
+// NewRefreshToken returns a random opaque refresh token, unique per session whatever the clock says
+func NewRefreshToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
 		return "", err
 	}
 
-	return tokenString, nil
+	return base64.RawURLEncoding.EncodeToString(b), nil
 }
 
+// JWTAuthMiddleware authenticates users with the bearer token, guest tokens and users who must
+// change their password are refused
 func JWTAuthMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	return authMiddleware(next, false, false)
+}
 
-		tokenString := r.Header.Get("Authorization")
+// GuestAuthMiddleware authenticates users and guests with the bearer token
+func GuestAuthMiddleware(next http.Handler) http.Handler {
+	return authMiddleware(next, true, false)
+}
 
-		tokenString = strings.TrimPrefix(tokenString, "Bearer ")
+// PasswordChangeMiddleware authenticates users with the bearer token, including those who must change their password
+func PasswordChangeMiddleware(next http.Handler) http.Handler {
+	return authMiddleware(next, false, true)
+}
 
-		claims := &Claims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return jwtKey, nil
-		})
+// AnyTokenMiddleware authenticates every valid bearer token: users, guests and users who must change their password,
+// for routes such as logout
+func AnyTokenMiddleware(next http.Handler) http.Handler {
+	return authMiddleware(next, true, true)
+}
 
-		if err != nil || !token.Valid {
+func authMiddleware(next http.Handler, allowGuests, allowPasswordChange bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, err := parseToken(r)
+		if errors.Is(err, ErrCSRF) {
+			util.WriteError(w, r, util.WrapError(err, http.StatusForbidden, util.CodeForbidden, "Missing or invalid CSRF token"))
+			return
+		}
+		if err != nil {
 			http.Error(w, "Invalid token", http.StatusUnauthorized)
 			return
 		}
+		if ok, err := revoked(r.Context(), claims); err != nil {
+			util.WriteError(w, r, err)
+			return
+		} else if ok {
+			http.Error(w, "Invalid token", http.StatusUnauthorized)
+			return
+		}
+		if claims.IsGuest && !allowGuests {
+			util.WriteError(w, r, util.NewError(http.StatusForbidden, util.CodeForbidden, "Not available to guests, sign up first"))
+			return
+		}
+		if claims.MustChangePassword && !allowPasswordChange {
+			util.WriteError(w, r, util.NewError(http.StatusForbidden, util.CodePasswordChange, "Password change required"))
+			return
+		}
 
 		userContext := UserContext{
-			UserId:  claims.UserId,
-			IsAdmin: claims.IsAdmin,
+			UserId:       claims.UserId,
+			IsAdmin:      claims.IsAdmin,
+			IsGuest:      claims.IsGuest,
+			Role:         claims.Role,
+			Permissions:  claims.Permissions,
+			TokenID:      claims.Id,
+			TokenExpires: time.Unix(claims.ExpiresAt, 0),
 		}
 		ctx := context.WithValue(r.Context(), CxtKey("userContext"), userContext)
+		ctx = sl.With(ctx, slog.Int("user_id", claims.UserId))
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// GuestID returns the guest id from a valid guest bearer token, for routes without authentication
+func GuestID(r *http.Request) (int, bool) {
+	claims, err := parseToken(r)
+	if err != nil || !claims.IsGuest {
+		return 0, false
+	}
+	if ok, err := revoked(r.Context(), claims); err != nil || ok {
+		return 0, false
+	}
+	return claims.UserId, true
+}
+
+// TokenUser returns the user or guest of a valid bearer token, for routes that also serve clients without one
+func TokenUser(r *http.Request) (UserContext, bool) {
+	claims, err := parseToken(r)
+	if err != nil {
+		return UserContext{}, false
+	}
+	if ok, err := revoked(r.Context(), claims); err != nil || ok {
+		return UserContext{}, false
+	}
+	return UserContext{UserId: claims.UserId, IsAdmin: claims.IsAdmin, IsGuest: claims.IsGuest, Role: claims.Role, Permissions: claims.Permissions}, true
+}
+
+// parseToken parses the bearer token of r or, without an Authorization header, its access token cookie
+func parseToken(r *http.Request) (*Claims, error) {
+	tokenString := r.Header.Get("Authorization")
+	if tokenString == "" {
+		var err error
+		if tokenString, err = cookieToken(r); err != nil {
+			return nil, err
+		}
+	}
+
+	tokenString = strings.TrimPrefix(tokenString, "Bearer ")
+
+	claims, err := parseClaims(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Audience != "" {
+		return nil, errClientToken
+	}
+	return claims, nil
+}
+
+// UserKey returns the authenticated user's id as a key, e.g. for rate limiting
+func UserKey(r *http.Request) (string, bool) {
+	id, ok := UserID(r)
+	if !ok {
+		return "", false
+	}
+	return strconv.Itoa(id), true
+}
+
+// UserID returns the id of the authenticated user or guest
+func UserID(r *http.Request) (int, bool) {
+	userContext, ok := r.Context().Value(CxtKey("userContext")).(UserContext)
+	if !ok {
+		return 0, false
+	}
+	return userContext.UserId, true
+}
@@ -0,0 +1,165 @@
+package access
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Cookies of the cookie transport. The CSRF cookie is readable by scripts, which echo it in CSRFHeader.
+const (
+	AccessCookie  = "sapi_access"
+	RefreshCookie = "sapi_refresh"
+	CSRFCookie    = "sapi_csrf"
+	CSRFHeader    = "X-CSRF-Token"
+)
+
+// ErrCSRF is returned for a request authenticated by cookie whose CSRF header is missing or does not match the CSRF cookie
+var ErrCSRF = errors.New("missing or mismatched CSRF token")
+
+// Cookies configures the cookie transport of tokens, header mode stays the default. When Enabled, sign in and refresh
+// also set the tokens as HttpOnly cookies and requests without an Authorization header authenticate with the access cookie.
+// Unsafe methods then need CSRFHeader to match the CSRF cookie (double submit).
+type Cookies struct {
+	Enabled bool
+	// Path scopes the access cookie, the refresh cookie is only sent to Path/auth
+	Path   string
+	Domain string
+	// SameSite is strict or lax, anything but lax is strict
+	SameSite string
+	// Insecure drops the Secure attribute
+	Insecure bool
+}
+
+var cookies atomic.Value
+
+func init() {
+	cookies.Store(Cookies{})
+}
+
+// SetCookies sets the cookie transport and returns a func restoring the previous one, e.g. for t.Cleanup
+func SetCookies(c Cookies) (restore func()) {
+	prev := cookies.Swap(c)
+	return func() { cookies.Store(prev) }
+}
+
+// CookiesEnabled reports whether tokens are sent as cookies too
+func CookiesEnabled() bool {
+	return cookies.Load().(Cookies).Enabled
+}
+
+func (c Cookies) cookie(name, value, path string, expires time.Time) *http.Cookie {
+	sameSite := http.SameSiteStrictMode
+	if c.SameSite == "lax" {
+		sameSite = http.SameSiteLaxMode
+	}
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   c.Domain,
+		HttpOnly: name != CSRFCookie,
+		Secure:   !c.Insecure,
+		SameSite: sameSite,
+	}
+	if expires.IsZero() {
+		cookie.MaxAge = -1
+	} else {
+		cookie.Expires = expires
+	}
+	return cookie
+}
+
+func (c Cookies) paths() (accessPath, refreshPath string) {
+	accessPath = strings.TrimSuffix(c.Path, "/")
+	return accessPath + "/", accessPath + "/auth"
+}
+
+// SetTokenCookies sets the access token cookie valid until accessExpires and, unless refresh is empty, the refresh token
+// cookie valid until refreshExpires, along with a new CSRF token. It does nothing in header mode.
+func SetTokenCookies(w http.ResponseWriter, access string, accessExpires time.Time, refresh string, refreshExpires time.Time) error {
+	c := cookies.Load().(Cookies)
+	if !c.Enabled {
+		return nil
+	}
+
+	csrf, err := NewRefreshToken()
+	if err != nil {
+		return err
+	}
+	accessPath, refreshPath := c.paths()
+
+	expires := accessExpires
+	if refresh != "" {
+		http.SetCookie(w, c.cookie(RefreshCookie, refresh, refreshPath, refreshExpires))
+		expires = refreshExpires
+	}
+	http.SetCookie(w, c.cookie(AccessCookie, access, accessPath, accessExpires))
+	// At / so that scripts of any page can read it, it outlives the access token to let the refresh pass the CSRF check
+	http.SetCookie(w, c.cookie(CSRFCookie, csrf, "/", expires))
+	return nil
+}
+
+// ClearTokenCookies removes the token cookies, e.g. on logout. It does nothing in header mode.
+func ClearTokenCookies(w http.ResponseWriter) {
+	c := cookies.Load().(Cookies)
+	if !c.Enabled {
+		return
+	}
+
+	accessPath, refreshPath := c.paths()
+	http.SetCookie(w, c.cookie(RefreshCookie, "", refreshPath, time.Time{}))
+	http.SetCookie(w, c.cookie(AccessCookie, "", accessPath, time.Time{}))
+	http.SetCookie(w, c.cookie(CSRFCookie, "", "/", time.Time{}))
+}
+
+// RefreshTokenCookie returns the refresh token cookie of r, checking its CSRF token.
+// Returns "" without an error in header mode or when there is no such cookie.
+func RefreshTokenCookie(r *http.Request) (string, error) {
+	if !CookiesEnabled() {
+		return "", nil
+	}
+	cookie, err := r.Cookie(RefreshCookie)
+	if err != nil || cookie.Value == "" {
+		return "", nil
+	}
+	if err := checkCSRF(r); err != nil {
+		return "", err
+	}
+	return cookie.Value, nil
+}
+
+// cookieToken returns the access token cookie of r, checking its CSRF token
+func cookieToken(r *http.Request) (string, error) {
+	if !CookiesEnabled() {
+		return "", nil
+	}
+	cookie, err := r.Cookie(AccessCookie)
+	if err != nil {
+		return "", nil
+	}
+	if err := checkCSRF(r); err != nil {
+		return "", err
+	}
+	return cookie.Value, nil
+}
+
+// checkCSRF requires the CSRF header of unsafe requests to match the CSRF cookie
+func checkCSRF(r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return nil
+	}
+
+	cookie, err := r.Cookie(CSRFCookie)
+	if err != nil || cookie.Value == "" {
+		return ErrCSRF
+	}
+	if subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(r.Header.Get(CSRFHeader))) != 1 {
+		return ErrCSRF
+	}
+	return nil
+}
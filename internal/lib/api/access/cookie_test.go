@@ -0,0 +1,87 @@
+package access
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCookieTransport(t *testing.T) {
+	token, err := NewAccessToken(1, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expires := time.Now().Add(AccessTTL)
+
+	rec := httptest.NewRecorder()
+	if err := SetTokenCookies(rec, token, expires, "refresh", expires); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.Result().Cookies()) != 0 {
+		t.Fatalf("header mode set cookies: %v", rec.Result().Cookies())
+	}
+
+	t.Cleanup(SetCookies(Cookies{Enabled: true, Path: "/api/v1"}))
+
+	rec = httptest.NewRecorder()
+	if err := SetTokenCookies(rec, token, expires, "refresh", expires); err != nil {
+		t.Fatal(err)
+	}
+	set := map[string]*http.Cookie{}
+	for _, c := range rec.Result().Cookies() {
+		set[c.Name] = c
+	}
+	if c := set[AccessCookie]; c == nil || !c.HttpOnly || !c.Secure || c.Path != "/api/v1/" || c.SameSite != http.SameSiteStrictMode {
+		t.Fatalf("access cookie = %+v", c)
+	}
+	if c := set[RefreshCookie]; c == nil || !c.HttpOnly || c.Path != "/api/v1/auth" || c.Value != "refresh" {
+		t.Fatalf("refresh cookie = %+v", c)
+	}
+	if c := set[CSRFCookie]; c == nil || c.HttpOnly || c.Value == "" {
+		t.Fatalf("CSRF cookie = %+v", c)
+	}
+	csrf := set[CSRFCookie].Value
+
+	handler := JWTAuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	tests := []struct {
+		name   string
+		method string
+		header string
+		bearer bool
+		want   int
+	}{
+		{"safe method", http.MethodGet, "", false, http.StatusOK},
+		{"missing CSRF header", http.MethodPost, "", false, http.StatusForbidden},
+		{"wrong CSRF header", http.MethodPost, "other", false, http.StatusForbidden},
+		{"matching CSRF header", http.MethodPost, csrf, false, http.StatusOK},
+		{"bearer token needs no CSRF header", http.MethodPost, "", true, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/v1/user/profile", nil)
+			req.AddCookie(set[AccessCookie])
+			req.AddCookie(set[CSRFCookie])
+			if tt.header != "" {
+				req.Header.Set(CSRFHeader, tt.header)
+			}
+			if tt.bearer {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+
+	rec = httptest.NewRecorder()
+	ClearTokenCookies(rec)
+	for _, c := range rec.Result().Cookies() {
+		if c.MaxAge >= 0 {
+			t.Fatalf("cookie %s not cleared: %+v", c.Name, c)
+		}
+	}
+}
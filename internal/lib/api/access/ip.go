@@ -0,0 +1,48 @@
+package access
+
+import (
+	"net/http"
+	"net/netip"
+)
+
+// IPv6Prefix is the size of the network an IPv6 client is keyed by. A /64 is the smallest network
+// a site gets and hosts pick their addresses within it freely, so a single address keys nothing.
+const IPv6Prefix = 64
+
+// IPKey returns the client address as a key, for rate limiting unauthenticated routes and locking out sign ins.
+// IPv4 clients are keyed by their address, IPv6 clients by their /64 network, e.g. "2001:db8:1:2::/64".
+// IPv4 addresses mapped to IPv6 by a dual-stack listener are keyed as IPv4, so both stacks share a key.
+func IPKey(r *http.Request) (string, bool) {
+	if r.RemoteAddr == "" {
+		return "", false
+	}
+
+	addr, ok := ClientAddr(r)
+	if !ok {
+		return r.RemoteAddr, true
+	}
+	return addrKey(addr), true
+}
+
+// ClientAddr returns the address of the client, an IPv4 address mapped to IPv6 as IPv4 and without a zone
+func ClientAddr(r *http.Request) (netip.Addr, bool) {
+	addr, err := netip.ParseAddr(r.RemoteAddr)
+	if ap, perr := netip.ParseAddrPort(r.RemoteAddr); perr == nil {
+		addr, err = ap.Addr(), nil
+	}
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap().WithZone(""), true
+}
+
+func addrKey(addr netip.Addr) string {
+	if addr.Is4() {
+		return addr.String()
+	}
+	prefix, err := addr.Prefix(IPv6Prefix)
+	if err != nil {
+		return addr.String()
+	}
+	return prefix.String()
+}
@@ -0,0 +1,36 @@
+package access
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIPKey(t *testing.T) {
+	tests := []struct {
+		remote, want string
+	}{
+		{"192.0.2.10:51234", "192.0.2.10"},
+		{"[2001:db8:1:2:aaaa:bbbb:cccc:dddd]:443", "2001:db8:1:2::/64"},
+		// Another address of the same /64 shares the key.
+		{"[2001:db8:1:2::1]:443", "2001:db8:1:2::/64"},
+		{"[2001:DB8:1:3::1]:443", "2001:db8:1:3::/64"},
+		// IPv4 through a dual-stack listener.
+		{"[::ffff:192.0.2.10]:51234", "192.0.2.10"},
+		{"[fe80::1%eth0]:80", "fe80::/64"},
+		{"192.0.2.10", "192.0.2.10"},
+		{"unix-socket", "unix-socket"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = tt.remote
+		if got, ok := IPKey(r); !ok || got != tt.want {
+			t.Errorf("IPKey(%q) = %q, %v, want %q", tt.remote, got, ok, tt.want)
+		}
+	}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = ""
+	if _, ok := IPKey(r); ok {
+		t.Error("IPKey() of a request without an address found one")
+	}
+}
@@ -0,0 +1,75 @@
+package access
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"testing"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+func TestThumbprint(t *testing.T) {
+	// The example of RFC 7638, 3.1
+	n, err := base64.RawURLEncoding.DecodeString("0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	k := Key{method: jwt.SigningMethodRS256, verify: &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: 65537}}
+
+	if got, want := thumbprint(k), "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs"; got != want {
+		t.Errorf("thumbprint() = %s, want %s", got, want)
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	secret, err := NewHMACKey(make([]byte, MinKeyLength))
+	if err != nil {
+		t.Fatal(err)
+	}
+	setKey(t, secret)
+	if set := JWKS(); len(set.Keys) != 0 {
+		t.Fatalf("HS256: JWKS() = %+v, want no keys", set)
+	}
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, err := ParsePrivateKey(ES256, []byte(pemKey(t, ecKey)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetKey(k)
+
+	set := JWKS()
+	if len(set.Keys) != 1 {
+		t.Fatalf("ES256: JWKS() = %+v, want one key", set)
+	}
+	jwk := set.Keys[0]
+	if jwk.Kty != "EC" || jwk.Crv != "P-256" || jwk.Alg != ES256 || jwk.Kid == "" {
+		t.Errorf("JWK = %+v", jwk)
+	}
+
+	token, err := NewAccessToken(1, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A service knowing only the set verifies the token by its kid
+	claims := &Claims{}
+	_, err = jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (any, error) {
+		if token.Header["kid"] != jwk.Kid {
+			t.Errorf("kid = %v, want %s", token.Header["kid"], jwk.Kid)
+		}
+		x, _ := base64.RawURLEncoding.DecodeString(jwk.X)
+		y, _ := base64.RawURLEncoding.DecodeString(jwk.Y)
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	})
+	if err != nil || claims.UserId != 1 {
+		t.Errorf("verify with the JWK: %v, user %d", err, claims.UserId)
+	}
+}
@@ -0,0 +1,279 @@
+package access
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// Signing algorithms. HS256 signs and verifies with a shared secret, RS256 and ES256 sign with a private key
+// and verify with its public key, so other services can verify tokens without being able to issue them.
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+	ES256 = "ES256"
+)
+
+// MinKeyLength is the shortest HS256 secret accepted, it needs a key at least as long as its hash (RFC 7518, 3.2)
+const MinKeyLength = 32
+
+// minRSABits is the smallest RSA key accepted (RFC 7518, 3.3)
+const minRSABits = 2048
+
+var ErrNoKey = errors.New("JWT signing key is not set: set JWT_KEY or jwt.key_file")
+
+// Key signs and verifies tokens with the algorithm it was loaded for
+type Key struct {
+	method jwt.SigningMethod
+	sign   any
+	verify any
+	// id is the kid header of the key's tokens, see thumbprint
+	id string
+}
+
+// ID returns the kid of the key, the RFC 7638 thumbprint of its public key or, for HS256, of the secret
+func (k Key) ID() string {
+	return k.id
+}
+
+// Algorithm returns the signing algorithm, e.g. RS256
+func (k Key) Algorithm() string {
+	return k.method.Alg()
+}
+
+// PublicKey returns the key verifying the tokens of an RS256 or ES256 key, nil for HS256
+func (k Key) PublicKey() crypto.PublicKey {
+	if k.method == jwt.SigningMethodHS256 {
+		return nil
+	}
+	return k.verify
+}
+
+// Until SetKey the key is random, so tokens do not outlive the process
+func init() {
+	k, err := GenerateKey(HS256)
+	if err != nil {
+		panic(err)
+	}
+	SetKey(k)
+}
+
+// LoadKey returns the key of alg in key, or else in file. The key is the secret for HS256, the default,
+// and a PEM encoded private key (PKCS #1, SEC 1 or PKCS #8) for RS256 and ES256.
+// Only one of key and file may be set, it returns ErrNoKey when neither is.
+func LoadKey(alg, key, file string) (Key, error) {
+	const op = "lib.api.access.LoadKey"
+
+	var b []byte
+	switch {
+	case key != "" && file != "":
+		return Key{}, fmt.Errorf("%s: JWT_KEY and jwt.key_file are both set, set one", op)
+	case key != "":
+		b = []byte(key)
+	case file != "":
+		var err error
+		if b, err = os.ReadFile(file); err != nil {
+			return Key{}, fmt.Errorf("%s: %v", op, err)
+		}
+		b = bytes.TrimRight(b, "\r\n")
+	default:
+		return Key{}, fmt.Errorf("%s: %w", op, ErrNoKey)
+	}
+
+	var (
+		k   Key
+		err error
+	)
+	switch alg {
+	case "", HS256:
+		k, err = NewHMACKey(b)
+	case RS256, ES256:
+		k, err = ParsePrivateKey(alg, b)
+	default:
+		err = fmt.Errorf("unknown JWT algorithm %q: must be %s, %s or %s", alg, HS256, RS256, ES256)
+	}
+	if err != nil {
+		return Key{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return k, nil
+}
+
+// NewHMACKey returns an HS256 key of secret, which must be at least MinKeyLength bytes
+func NewHMACKey(secret []byte) (Key, error) {
+	if len(secret) < MinKeyLength {
+		return Key{}, fmt.Errorf("JWT signing key is %d bytes, at least %d are required", len(secret), MinKeyLength)
+	}
+	return withID(Key{method: jwt.SigningMethodHS256, sign: secret, verify: secret}), nil
+}
+
+// ParsePrivateKey returns the RS256 or ES256 key of a PEM encoded private key.
+// RSA keys must have at least 2048 bits, ECDSA keys must be on the P-256 curve.
+func ParsePrivateKey(alg string, data []byte) (Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return Key{}, errors.New("JWT signing key is not PEM encoded")
+	}
+
+	var parsed any
+	var err error
+	if parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes); err != nil {
+		if parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+			if parsed, err = x509.ParseECPrivateKey(block.Bytes); err != nil {
+				return Key{}, errors.New("JWT signing key is not an RSA or ECDSA private key")
+			}
+		}
+	}
+
+	switch key := parsed.(type) {
+	case *rsa.PrivateKey:
+		if alg != RS256 {
+			return Key{}, fmt.Errorf("JWT signing key is an RSA key, %s needs an ECDSA one", alg)
+		}
+		if key.N.BitLen() < minRSABits {
+			return Key{}, fmt.Errorf("JWT signing key has %d bits, at least %d are required", key.N.BitLen(), minRSABits)
+		}
+		return withID(Key{method: jwt.SigningMethodRS256, sign: key, verify: &key.PublicKey}), nil
+	case *ecdsa.PrivateKey:
+		if alg != ES256 {
+			return Key{}, fmt.Errorf("JWT signing key is an ECDSA key, %s needs an RSA one", alg)
+		}
+		if key.Curve != elliptic.P256() {
+			return Key{}, fmt.Errorf("JWT signing key is on the %s curve, ES256 needs P-256", key.Curve.Params().Name)
+		}
+		return withID(Key{method: jwt.SigningMethodES256, sign: key, verify: &key.PublicKey}), nil
+	default:
+		return Key{}, fmt.Errorf("JWT signing key is a %T, not an RSA or ECDSA private key", parsed)
+	}
+}
+
+func withID(k Key) Key {
+	k.id = thumbprint(k)
+	return k
+}
+
+// GenerateKey returns a new random key of alg: a 32 byte secret, a 2048 bit RSA key or a P-256 ECDSA key
+func GenerateKey(alg string) (Key, error) {
+	switch alg {
+	case "", HS256:
+		b := make([]byte, MinKeyLength)
+		if _, err := rand.Read(b); err != nil {
+			return Key{}, err
+		}
+		return NewHMACKey(b)
+	case RS256:
+		key, err := rsa.GenerateKey(rand.Reader, minRSABits)
+		if err != nil {
+			return Key{}, err
+		}
+		return withID(Key{method: jwt.SigningMethodRS256, sign: key, verify: &key.PublicKey}), nil
+	case ES256:
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return Key{}, err
+		}
+		return withID(Key{method: jwt.SigningMethodES256, sign: key, verify: &key.PublicKey}), nil
+	default:
+		return Key{}, fmt.Errorf("unknown JWT algorithm %q: must be %s, %s or %s", alg, HS256, RS256, ES256)
+	}
+}
+
+// Marshal encodes k for storage: the secret of an HS256 key base64url encoded, else the PKCS #8 PEM private key.
+// UnmarshalKey decodes it.
+func (k Key) Marshal() (string, error) {
+	if secret, ok := k.sign.([]byte); ok {
+		return base64.RawURLEncoding.EncodeToString(secret), nil
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(k.sign)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+}
+
+// UnmarshalKey decodes a key of alg encoded by Key.Marshal
+func UnmarshalKey(alg, data string) (Key, error) {
+	if alg == HS256 {
+		secret, err := base64.RawURLEncoding.DecodeString(data)
+		if err != nil {
+			return Key{}, err
+		}
+		return NewHMACKey(secret)
+	}
+	return ParsePrivateKey(alg, []byte(data))
+}
+
+// keyring holds the key signing tokens and the keys verifying them, the signing one included
+type keyring struct {
+	signer Key
+	verify []Key
+}
+
+var keys atomic.Value
+
+// SetKey makes k sign and verify tokens, it must be called before serving
+func SetKey(k Key) {
+	SetKeys(k)
+}
+
+// SetKeys makes signer sign tokens, the tokens of signer and of the others verify.
+// Tokens of a key no longer set stop verifying.
+func SetKeys(signer Key, others ...Key) {
+	keys.Store(keyring{signer: signer, verify: append([]Key{signer}, others...)})
+}
+
+func currentKeys() keyring {
+	return keys.Load().(keyring)
+}
+
+// signToken returns the token of claims signed by the signing key, its kid header names the key
+func signToken(claims jwt.Claims) (string, error) {
+	k := currentKeys().signer
+	token := jwt.NewWithClaims(k.method, claims)
+	token.Header["kid"] = k.id
+	return token.SignedString(k.sign)
+}
+
+// parseClaims verifies tokenString with each key in turn, a token naming its key by kid is only tried with that one
+func parseClaims(tokenString string) (*Claims, error) {
+	err := errors.New("no key verifies the token")
+	for _, k := range currentKeys().verify {
+		claims := &Claims{}
+		token, perr := jwt.ParseWithClaims(tokenString, claims, k.keyFunc)
+		if perr == nil && token.Valid {
+			return claims, nil
+		}
+		if ve, ok := perr.(*jwt.ValidationError); ok && ve.Inner == errOtherKey {
+			continue
+		}
+		if perr != nil {
+			err = perr
+		}
+	}
+	return nil, err
+}
+
+var errOtherKey = errors.New("token of another key")
+
+// keyFunc returns the key verifying token, tokens of another algorithm or naming another key are refused
+func (k Key) keyFunc(token *jwt.Token) (any, error) {
+	if token.Method.Alg() != k.method.Alg() {
+		return nil, errOtherKey
+	}
+	if kid, ok := token.Header["kid"].(string); ok && kid != k.id {
+		return nil, errOtherKey
+	}
+	return k.verify, nil
+}
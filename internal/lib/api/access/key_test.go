@@ -0,0 +1,126 @@
+package access
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func pemKey(t *testing.T, key any) string {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+}
+
+func setKey(t *testing.T, k Key) {
+	prev := currentKeys()
+	SetKey(k)
+	t.Cleanup(func() { keys.Store(prev) })
+}
+
+func authorized(token string) int {
+	h := JWTAuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestLoadKey(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	smallRSA, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name, alg, key string
+		err            string
+	}{
+		{name: "hmac", alg: "", key: strings.Repeat("k", MinKeyLength)},
+		{name: "short hmac", alg: HS256, key: "secret", err: "at least 32"},
+		{name: "rsa", alg: RS256, key: pemKey(t, rsaKey)},
+		{name: "small rsa", alg: RS256, key: pemKey(t, smallRSA), err: "at least 2048"},
+		{name: "ecdsa", alg: ES256, key: pemKey(t, ecKey)},
+		{name: "wrong curve", alg: ES256, key: pemKey(t, p384), err: "P-384"},
+		{name: "mismatch", alg: ES256, key: pemKey(t, rsaKey), err: "RSA key"},
+		{name: "not pem", alg: RS256, key: strings.Repeat("k", MinKeyLength), err: "not PEM"},
+		{name: "unknown", alg: "none", key: strings.Repeat("k", MinKeyLength), err: "unknown JWT algorithm"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k, err := LoadKey(tt.alg, tt.key, "")
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("LoadKey() error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			setKey(t, k)
+			token, err := NewAccessToken(1, false, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if code := authorized(token); code != http.StatusOK {
+				t.Errorf("%s token: status = %d, want 200", k.Algorithm(), code)
+			}
+		})
+	}
+
+	if _, err := LoadKey("", "", ""); err == nil || !strings.Contains(err.Error(), ErrNoKey.Error()) {
+		t.Errorf("LoadKey() without key = %v, want ErrNoKey", err)
+	}
+}
+
+func TestAlgorithmMismatch(t *testing.T) {
+	secret, err := NewHMACKey([]byte(strings.Repeat("k", MinKeyLength)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	setKey(t, secret)
+	token, err := NewAccessToken(1, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, err := ParsePrivateKey(ES256, []byte(pemKey(t, ecKey)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetKey(k)
+
+	// HS256 tokens are refused once the server signs with ES256
+	if code := authorized(token); code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want 401", code)
+	}
+}
@@ -0,0 +1,75 @@
+package access
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/sabbatD/srest-api/internal/lib/clock"
+)
+
+// errClientToken refuses the tokens of OpenID Connect clients on the API, they name the client in aud
+var errClientToken = errors.New("token issued to an OpenID Connect client")
+
+// ClientToken is a valid access token issued to an OpenID Connect client
+type ClientToken struct {
+	UserId   int
+	ClientID string
+	Scope    string
+}
+
+// NewOIDCAccessToken returns an access token of the user for the OpenID Connect client, valid for AccessTTL.
+// It is only accepted by OIDCToken, e.g. at the userinfo endpoint, the API refuses it.
+func NewOIDCAccessToken(id int, clientID, scope string) (string, time.Time, error) {
+	jti, err := newTokenID()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	expirationTime := clock.Now().Add(AccessTTL)
+	claims := &Claims{
+		UserId: id,
+		Scope:  scope,
+		StandardClaims: jwt.StandardClaims{
+			Id:        jti,
+			Audience:  clientID,
+			ExpiresAt: expirationTime.Unix(),
+		},
+	}
+
+	tokenString, err := signToken(claims)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return tokenString, expirationTime, nil
+}
+
+// SignIDToken signs the claims of an OpenID Connect ID token with the signing key, they have to name the client in aud
+// so the API refuses the token
+func SignIDToken(claims map[string]any) (string, error) {
+	if aud, _ := claims["aud"].(string); aud == "" {
+		return "", errors.New("ID token without an audience")
+	}
+	return signToken(jwt.MapClaims(claims))
+}
+
+// OIDCToken returns the OpenID Connect client token of the Authorization header, see NewOIDCAccessToken.
+// ID tokens name the client too but carry no scope, they are refused.
+func OIDCToken(r *http.Request) (ClientToken, bool) {
+	tokenString, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return ClientToken{}, false
+	}
+
+	claims, err := parseClaims(tokenString)
+	if err != nil || claims.Audience == "" || claims.Scope == "" {
+		return ClientToken{}, false
+	}
+	if ok, err := revoked(r.Context(), claims); err != nil || ok {
+		return ClientToken{}, false
+	}
+	return ClientToken{UserId: claims.UserId, ClientID: claims.Audience, Scope: claims.Scope}, true
+}
@@ -0,0 +1,143 @@
+package access
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/sabbatD/srest-api/internal/lib/clock"
+	kc "github.com/sabbatD/srest-api/internal/lib/keyConfig"
+	"github.com/sabbatD/srest-api/internal/lib/logger/sl"
+	"github.com/sabbatD/srest-api/internal/lib/metrics"
+)
+
+// KeyStore keeps the signing keys created by rotations
+type KeyStore interface {
+	// SigningKeys returns the stored keys, the newest first
+	SigningKeys(ctx context.Context) ([]kc.SigningKey, error)
+	// RotateSigningKey retires the active key and stores key as the active one
+	RotateSigningKey(ctx context.Context, actor int, key kc.SigningKey) error
+}
+
+// Rotator replaces the signing key without invalidating issued tokens: a retired key keeps verifying
+// for grace, the lifetime of the longest lived token. The configured key signs until the first rotation
+// and is retired by it. Stored keys are reloaded every interval, so every instance picks up a rotation.
+type Rotator struct {
+	log      *slog.Logger
+	store    KeyStore
+	base     Key
+	grace    time.Duration
+	interval time.Duration
+	// mu serializes rotations of this instance
+	mu sync.Mutex
+}
+
+func NewRotator(log *slog.Logger, store KeyStore, base Key, grace, interval time.Duration) *Rotator {
+	return &Rotator{log: log, store: store, base: base, grace: grace, interval: interval}
+}
+
+// Load sets the keys from the store: the active stored key signs, else the configured one.
+// Keys retired less than grace ago keep verifying.
+func (rot *Rotator) Load(ctx context.Context) error {
+	const op = "lib.api.access.Load"
+
+	stored, err := rot.store.SigningKeys(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	if len(stored) == 0 {
+		SetKey(rot.base)
+		return nil
+	}
+
+	since := clock.Now().Add(-rot.grace)
+	var signer *Key
+	var others []Key
+	for _, s := range stored {
+		if s.Retired != nil && s.Retired.Before(since) {
+			continue
+		}
+		k, err := UnmarshalKey(s.Algorithm, s.Key)
+		if err != nil {
+			return fmt.Errorf("%s: key %s: %v", op, s.ID, err)
+		}
+		if s.Retired == nil && signer == nil {
+			signer = &k
+			continue
+		}
+		others = append(others, k)
+	}
+	// The configured key was retired by the first rotation
+	if first := stored[len(stored)-1]; !first.Created.Before(since) {
+		others = append(others, rot.base)
+	}
+	if signer == nil {
+		// Every stored key is retired, which only a manual change of the store does
+		SetKeys(rot.base, others...)
+		return nil
+	}
+
+	SetKeys(*signer, others...)
+	return nil
+}
+
+// Rotate creates a key of the configured algorithm that signs from now on, audited as done by actor.
+// Tokens of the replaced key verify until grace has passed.
+func (rot *Rotator) Rotate(ctx context.Context, actor int) (kc.Rotation, error) {
+	const op = "lib.api.access.Rotate"
+
+	rot.mu.Lock()
+	defer rot.mu.Unlock()
+
+	// The signing key may have been rotated by another instance since the last load
+	if err := rot.Load(ctx); err != nil {
+		return kc.Rotation{}, fmt.Errorf("%s: %v", op, err)
+	}
+	retired := currentKeys().signer
+
+	k, err := GenerateKey(rot.base.Algorithm())
+	if err != nil {
+		return kc.Rotation{}, fmt.Errorf("%s: %v", op, err)
+	}
+	data, err := k.Marshal()
+	if err != nil {
+		return kc.Rotation{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	now := clock.Now()
+	err = rot.store.RotateSigningKey(ctx, actor, kc.SigningKey{ID: k.ID(), Algorithm: k.Algorithm(), Key: data, Created: now})
+	if err != nil {
+		return kc.Rotation{}, fmt.Errorf("%s: %v", op, err)
+	}
+	if err := rot.Load(ctx); err != nil {
+		return kc.Rotation{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return kc.Rotation{Kid: k.ID(), Algorithm: k.Algorithm(), RetiredKid: retired.ID(), VerifiesUntil: now.Add(rot.grace)}, nil
+}
+
+// Run reloads the keys every interval until ctx is done
+func (rot *Rotator) Run(ctx context.Context) {
+	const op = "lib.api.access.Run"
+
+	if rot.interval <= 0 {
+		return
+	}
+	log := rot.log.With(slog.String("op", op))
+
+	ticker := time.NewTicker(rot.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := rot.Load(ctx); err != nil {
+				log.Error("signing keys reload failed", sl.Err(err))
+				metrics.JobFailures.Add("jwt_keys", 1)
+			}
+		}
+	}
+}
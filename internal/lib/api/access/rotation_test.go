@@ -0,0 +1,94 @@
+package access
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/sabbatD/srest-api/internal/lib/clock"
+	kc "github.com/sabbatD/srest-api/internal/lib/keyConfig"
+)
+
+type memKeys struct {
+	keys []kc.SigningKey
+}
+
+func (s *memKeys) SigningKeys(ctx context.Context) ([]kc.SigningKey, error) {
+	return s.keys, nil
+}
+
+func (s *memKeys) RotateSigningKey(ctx context.Context, actor int, key kc.SigningKey) error {
+	for i := range s.keys {
+		if s.keys[i].Retired == nil {
+			s.keys[i].Retired = &key.Created
+		}
+	}
+	s.keys = append([]kc.SigningKey{key}, s.keys...)
+	return nil
+}
+
+func TestRotate(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 11, 1, 12, 0, 0, 0, time.UTC))
+	t.Cleanup(clock.Set(fake))
+
+	base, err := GenerateKey(ES256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	setKey(t, base)
+	rot := NewRotator(slog.Default(), &memKeys{}, base, time.Hour, time.Minute)
+	if err := rot.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	before, err := NewAccessToken(1, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := rot.Rotate(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.RetiredKid != base.ID() || first.Kid == base.ID() || first.Algorithm != ES256 {
+		t.Errorf("first rotation = %+v, want %s retired", first, base.ID())
+	}
+	second, err := rot.Rotate(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.RetiredKid != first.Kid {
+		t.Errorf("second rotation retired %s, want %s", second.RetiredKid, first.Kid)
+	}
+
+	after, err := NewAccessToken(1, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(JWKS().Keys); n != 3 {
+		t.Errorf("JWKS() has %d keys, want 3 within the grace", n)
+	}
+	// Tokens signed before the rotations still verify
+	for name, token := range map[string]string{"before": before, "after": after} {
+		if code := authorized(token); code != http.StatusOK {
+			t.Errorf("%s: status = %d, want 200", name, code)
+		}
+	}
+
+	// Once the grace passed the retired keys no longer verify, even tokens that did not expire
+	fake.Advance(time.Hour + time.Minute)
+	if err := rot.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if code := authorized(before); code != http.StatusUnauthorized {
+		t.Errorf("retired key: status = %d, want 401", code)
+	}
+	if code := authorized(after); code != http.StatusOK {
+		t.Errorf("active key: status = %d, want 200", code)
+	}
+	if set := JWKS(); len(set.Keys) != 1 || set.Keys[0].Kid != second.Kid {
+		t.Errorf("JWKS() = %+v, want only %s", set, second.Kid)
+	}
+}
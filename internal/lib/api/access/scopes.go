@@ -0,0 +1,95 @@
+package access
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	util "github.com/sabbatD/srest-api/internal/http-server/handleUtil"
+	rc "github.com/sabbatD/srest-api/internal/lib/roleConfig"
+)
+
+// Can reports whether the token of the user has the permission. Admin tokens issued before tokens carried
+// permissions have every permission.
+func (u UserContext) Can(permission string) bool {
+	if u.IsAdmin && u.Permissions == nil {
+		return slices.Contains(rc.Permissions, permission)
+	}
+	return slices.Contains(u.Permissions, permission)
+}
+
+// Staff reports whether the user has a role granting permissions, e.g. admins and moderators
+func (u UserContext) Staff() bool {
+	return u.IsAdmin || len(u.Permissions) > 0
+}
+
+// Roles tells the current role of users and the permissions it grants, blocked and deleted users have none
+type Roles interface {
+	UserRole(ctx context.Context, id int) (role string, permissions []string, err error)
+}
+
+// rolesHolder keeps atomic.Value storing a single concrete type
+type rolesHolder struct{ Roles }
+
+var roles atomic.Value
+
+func init() {
+	roles.Store(rolesHolder{})
+}
+
+// SetRoles makes RequireScope check the current role of the user rather than the one of the token, so that
+// a role taken from a user or a block takes the permissions at once. It returns a func restoring the previous
+// roles, e.g. for t.Cleanup. Without roles tokens keep their permissions until they expire.
+func SetRoles(r Roles) (restore func()) {
+	prev := roles.Swap(rolesHolder{r})
+	return func() { roles.Store(prev) }
+}
+
+// currentRole replaces the role and the permissions of the token with the current ones of the user, see SetRoles
+func currentRole(ctx context.Context, userContext UserContext) (UserContext, error) {
+	r := roles.Load().(rolesHolder).Roles
+	if r == nil || userContext.IsGuest {
+		return userContext, nil
+	}
+	role, permissions, err := r.UserRole(ctx, userContext.UserId)
+	if err != nil {
+		return userContext, err
+	}
+	userContext.Role, userContext.IsAdmin, userContext.Permissions = role, role == rc.Admin, permissions
+	return userContext, nil
+}
+
+// RequireScope refuses with 403 the requests whose user lacks one of the permissions, it runs after the auth
+// middleware setting the user context and stores the current permissions of the user in it, see SetRoles
+func RequireScope(permissions ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userContext, ok := r.Context().Value(CxtKey("userContext")).(UserContext)
+			if !ok {
+				util.WriteError(w, r, util.NewError(http.StatusUnauthorized, util.CodeUnauthorized, "User context not found"))
+				return
+			}
+			userContext, err := currentRole(r.Context(), userContext)
+			if err != nil {
+				util.WriteError(w, r, err)
+				return
+			}
+			r = r.WithContext(context.WithValue(r.Context(), CxtKey("userContext"), userContext))
+
+			var missing []string
+			for _, p := range permissions {
+				if !userContext.Can(p) {
+					missing = append(missing, p)
+				}
+			}
+			if len(missing) > 0 {
+				util.WriteError(w, r, util.NewError(http.StatusForbidden, util.CodeForbidden, "Missing permissions: "+strings.Join(missing, ", ")))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package access
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	rc "github.com/sabbatD/srest-api/internal/lib/roleConfig"
+)
+
+func TestRequireScope(t *testing.T) {
+	handler := JWTAuthMiddleware(RequireScope(rc.UsersBlock)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+	tests := []struct {
+		role        string
+		permissions []string
+		want        int
+	}{
+		{rc.Admin, rc.Permissions, http.StatusOK},
+		{rc.Moderator, []string{rc.UsersRead, rc.UsersBlock}, http.StatusOK},
+		{"support", []string{rc.UsersRead}, http.StatusForbidden},
+		{rc.User, nil, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.role, func(t *testing.T) {
+			token, err := NewRoleToken(1, tt.role, tt.permissions, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/admin/users/2/block", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
+
+type memRoles map[int]string
+
+func (m memRoles) UserRole(ctx context.Context, id int) (string, []string, error) {
+	role, ok := m[id]
+	if !ok {
+		return rc.User, nil, nil
+	}
+	return role, rc.Granted(role, nil), nil
+}
+
+func TestRequireScopeCurrentRole(t *testing.T) {
+	t.Cleanup(SetRoles(memRoles{1: rc.Admin}))
+
+	var got UserContext
+	handler := JWTAuthMiddleware(RequireScope(rc.UsersBlock)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Context().Value(CxtKey("userContext")).(UserContext)
+	})))
+
+	// The token of a demoted or blocked admin loses the permissions before it expires
+	for id, want := range map[int]int{1: http.StatusOK, 2: http.StatusForbidden} {
+		token, err := NewAccessToken(id, true, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := httptest.NewRequest(http.MethodPost, "/admin/users/3/block", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Fatalf("status of user %d = %d, want %d: %s", id, rec.Code, want, rec.Body)
+		}
+	}
+	if !got.IsAdmin || !got.Can(rc.UsersDelete) {
+		t.Errorf("user context = %+v, want the current admin permissions", got)
+	}
+}
+
+func TestCan(t *testing.T) {
+	tests := []struct {
+		name string
+		user UserContext
+		perm string
+		want bool
+	}{
+		{"granted", UserContext{Permissions: []string{rc.UsersRead}}, rc.UsersRead, true},
+		{"not granted", UserContext{Permissions: []string{rc.UsersRead}}, rc.UsersBlock, false},
+		{"admin token without permissions", UserContext{IsAdmin: true}, rc.UsersDelete, true},
+		{"admin token with permissions", UserContext{IsAdmin: true, Permissions: []string{rc.UsersRead}}, rc.UsersDelete, false},
+		{"user", UserContext{}, rc.UsersRead, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.user.Can(tt.perm); got != tt.want {
+				t.Fatalf("Can(%q) = %v, want %v", tt.perm, got, tt.want)
+			}
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/users", nil)
+	req = req.WithContext(context.WithValue(req.Context(), CxtKey("userContext"), UserContext{Permissions: []string{rc.UsersRead}}))
+	rec := httptest.NewRecorder()
+	RequireScope(rc.UsersRead, rc.UsersWrite)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
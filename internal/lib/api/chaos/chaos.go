@@ -0,0 +1,115 @@
+// Package chaos injects latency and errors into requests, so clients can test their retry
+// and backoff handling against a failing server. It is meant for dev and local environments only.
+package chaos
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sabbatD/srest-api/internal/lib/metrics"
+)
+
+// Config enables fault injection, it is ignored in prod
+type Config struct {
+	Enabled bool `yaml:"enabled" env:"CHAOS_ENABLED"`
+	// Seed makes the injected faults the same on every run, zero seeds at random
+	Seed uint64 `yaml:"seed" env:"CHAOS_SEED"`
+	// Rules are tried in order, the first matching one applies
+	Rules []Rule `yaml:"rules"`
+}
+
+// Rule is the fault injected into the requests of a route
+type Rule struct {
+	// Method matches any method when empty
+	Method string `yaml:"method"`
+	// Path is matched against the request path, a trailing * matches any suffix
+	Path string `yaml:"path"`
+	// Latency delays every matched request, up to Jitter more is added at random
+	Latency time.Duration `yaml:"latency"`
+	Jitter  time.Duration `yaml:"jitter"`
+	// ErrorRate is the share of matched requests answered with Status, from 0 to 1
+	ErrorRate float64 `yaml:"error_rate"`
+	// Status defaults to 503
+	Status int `yaml:"status"`
+}
+
+func (rule Rule) match(r *http.Request) bool {
+	if rule.Method != "" && !strings.EqualFold(rule.Method, r.Method) {
+		return false
+	}
+	if prefix, ok := strings.CutSuffix(rule.Path, "*"); ok {
+		return strings.HasPrefix(r.URL.Path, prefix)
+	}
+	return rule.Path == r.URL.Path
+}
+
+type Injector struct {
+	rules []Rule
+	rand  func() float64
+	sleep func(ctx context.Context, d time.Duration)
+}
+
+func New(cfg Config) *Injector {
+	in := &Injector{rules: cfg.Rules, rand: rand.Float64, sleep: sleep}
+	if cfg.Seed != 0 {
+		var mu sync.Mutex
+		src := rand.New(rand.NewPCG(cfg.Seed, cfg.Seed))
+		in.rand = func() float64 {
+			mu.Lock()
+			defer mu.Unlock()
+			return src.Float64()
+		}
+	}
+	return in
+}
+
+func sleep(ctx context.Context, d time.Duration) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+	case <-t.C:
+	}
+}
+
+// Middleware delays and fails the requests matched by a rule. Injected failures carry
+// the X-Chaos header and are counted in the chaos_injected metric.
+func (in *Injector) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var rule *Rule
+		for i := range in.rules {
+			if in.rules[i].match(r) {
+				rule = &in.rules[i]
+				break
+			}
+		}
+		if rule == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if d := rule.Latency + time.Duration(in.rand()*float64(rule.Jitter)); d > 0 {
+			metrics.Chaos.Add("latency", 1)
+			in.sleep(r.Context(), d)
+		}
+
+		if rule.ErrorRate > 0 && in.rand() < rule.ErrorRate {
+			metrics.Chaos.Add("error", 1)
+
+			status := rule.Status
+			if status == 0 {
+				status = http.StatusServiceUnavailable
+			}
+			w.Header().Set("X-Chaos", "injected")
+			http.Error(w, http.StatusText(status), status)
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,60 @@
+package chaos
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMiddleware(t *testing.T) {
+	in := New(Config{Rules: []Rule{
+		{Method: "POST", Path: "/api/v1/todos/*", ErrorRate: 0.5, Status: http.StatusInternalServerError},
+		{Path: "/api/v1/todos/*", Latency: time.Second, Jitter: time.Second},
+	}})
+	var slept time.Duration
+	in.sleep = func(ctx context.Context, d time.Duration) { slept = d }
+	roll := 0.0
+	in.rand = func() float64 { return roll }
+
+	h := in.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		roll   float64
+		status int
+		slept  time.Duration
+	}{
+		{name: "error", method: "POST", path: "/api/v1/todos/add", roll: 0.4, status: http.StatusInternalServerError},
+		{name: "error rate missed", method: "POST", path: "/api/v1/todos/add", roll: 0.6, status: http.StatusOK},
+		{name: "latency with jitter", method: "GET", path: "/api/v1/todos/", roll: 0.5, status: http.StatusOK, slept: 1500 * time.Millisecond},
+		{name: "no rule", method: "GET", path: "/api/v1/user/me", roll: 0, status: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roll, slept = tt.roll, 0
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+
+			if rec.Code != tt.status || slept != tt.slept {
+				t.Errorf("got %d after %v, want %d after %v", rec.Code, slept, tt.status, tt.slept)
+			}
+			if injected := rec.Header().Get("X-Chaos") != ""; injected != (tt.status != http.StatusOK) {
+				t.Errorf("X-Chaos = %q", rec.Header().Get("X-Chaos"))
+			}
+		})
+	}
+}
+
+func TestSeed(t *testing.T) {
+	a, b := New(Config{Seed: 42}), New(Config{Seed: 42})
+	for i := 0; i < 10; i++ {
+		if x, y := a.rand(), b.rand(); x != y {
+			t.Fatalf("roll %d = %v and %v with the same seed", i, x, y)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+// Package deadline bounds the time a request may spend in handlers and storage.
+package deadline
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// New returns a middleware that sets a deadline of d on the request context.
+// Storage calls made with the request context are cancelled once it passes,
+// handleUtil.InternalError then answers with 504. A non-positive d disables the deadline.
+func New(d time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if d <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
@@ -0,0 +1,59 @@
+// Package deprecation marks routes as deprecated, so clients learn it from every response before the route is removed.
+// Headers follow RFC 9745 (Deprecation) and RFC 8594 (Sunset).
+package deprecation
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	util "github.com/sabbatD/srest-api/internal/http-server/handleUtil"
+	"github.com/sabbatD/srest-api/internal/lib/clients"
+	"github.com/sabbatD/srest-api/internal/lib/clock"
+	"github.com/sabbatD/srest-api/internal/lib/logger/sl"
+	"github.com/sabbatD/srest-api/internal/lib/metrics"
+)
+
+// Policy describes a deprecation: the route is deprecated since Since and removed at Sunset,
+// a zero Sunset sets no date. Link points to the migration guide or the successor route.
+type Policy struct {
+	Since  time.Time
+	Sunset time.Time
+	Link   string
+}
+
+// New returns a middleware marking the routes it wraps as deprecated by p.
+// Each call is logged with the client and counted in the deprecated_calls metric by route.
+// From the sunset on the routes answer 410 Gone.
+func New(p Policy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h := w.Header()
+			h.Set("Deprecation", "@"+strconv.FormatInt(p.Since.Unix(), 10))
+			if !p.Sunset.IsZero() {
+				h.Set("Sunset", p.Sunset.UTC().Format(http.TimeFormat))
+			}
+			if p.Link != "" {
+				h.Add("Link", "<"+p.Link+`>; rel="deprecation"; type="text/html"`)
+			}
+
+			gone := !p.Sunset.IsZero() && !clock.Now().Before(p.Sunset)
+			if gone {
+				util.WriteError(w, r, util.NewError(http.StatusGone, util.CodeGone, "This route was removed, see the Link header"))
+			} else {
+				next.ServeHTTP(w, r)
+			}
+
+			route := r.URL.Path
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+				route = rctx.RoutePattern()
+			}
+			route = r.Method + " " + route
+			metrics.Deprecated.Add(route, 1)
+			sl.FromContext(r.Context()).Info("deprecated route called",
+				slog.String("route", route), slog.String("client", clients.Identify(r)), slog.Bool("gone", gone))
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package deprecation
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	util "github.com/sabbatD/srest-api/internal/http-server/handleUtil"
+	"github.com/sabbatD/srest-api/internal/lib/clock"
+	"github.com/sabbatD/srest-api/internal/lib/metrics"
+)
+
+func TestNew(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 11, 1, 12, 0, 0, 0, time.UTC))
+	t.Cleanup(clock.Set(fake))
+
+	p := Policy{
+		Since:  time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC),
+		Sunset: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		Link:   "https://easydev.club/docs/v2",
+	}
+	router := chi.NewRouter()
+	router.With(New(p)).Get("/old/{id}", func(w http.ResponseWriter, r *http.Request) {})
+
+	const route = "GET /old/{id}"
+	calls := func() int64 {
+		if v, ok := metrics.Deprecated.Get(route).(*expvar.Int); ok {
+			return v.Value()
+		}
+		return 0
+	}
+	before := calls()
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/old/1", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("before sunset: status = %d, want 200", rec.Code)
+	}
+	want := map[string]string{
+		"Deprecation": "@1727740800",
+		"Sunset":      "Wed, 01 Jan 2025 00:00:00 GMT",
+		"Link":        `<https://easydev.club/docs/v2>; rel="deprecation"; type="text/html"`,
+	}
+	for k, v := range want {
+		if got := rec.Header().Get(k); got != v {
+			t.Errorf("%s = %q, want %q", k, got, v)
+		}
+	}
+
+	fake.Advance(62 * 24 * time.Hour)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/old/1", nil))
+	var problem util.Problem
+	json.NewDecoder(rec.Body).Decode(&problem)
+	if rec.Code != http.StatusGone || problem.Code != util.CodeGone {
+		t.Errorf("after sunset: status = %d, code = %q, want 410 %s", rec.Code, problem.Code, util.CodeGone)
+	}
+	if rec.Header().Get("Sunset") == "" {
+		t.Error("after sunset: Sunset header missing")
+	}
+
+	if got := calls(); got != before+2 {
+		t.Errorf("deprecated_calls[%s] = %d, want %d", route, got, before+2)
+	}
+}
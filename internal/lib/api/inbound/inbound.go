@@ -0,0 +1,189 @@
+// Package inbound verifies requests of inbound integrations (chat bots, partner webhooks):
+// an HMAC-SHA256 signature over the timestamp and the body, a timestamp within the tolerance
+// and a nonce seen only once, so a captured request cannot be replayed.
+package inbound
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sabbatD/srest-api/internal/lib/clock"
+	"github.com/sabbatD/srest-api/internal/lib/metrics"
+)
+
+// MaxBody bounds the body read for verification
+const MaxBody = 1 << 20
+
+var (
+	ErrSignature = errors.New("invalid signature")
+	ErrTimestamp = errors.New("timestamp out of tolerance")
+	ErrReplayed  = errors.New("request replayed")
+)
+
+// Scheme describes where a sender puts the signature, the timestamp and the nonce, and what it signs
+type Scheme struct {
+	// SignatureHeader holds the hex signature after Prefix
+	SignatureHeader string
+	Prefix          string
+	// TimestampHeader holds the unix time of the request in seconds
+	TimestampHeader string
+	// NonceHeader is optional, without it the signature is the nonce. A nonce header must be signed.
+	NonceHeader string
+	// Payload returns the signed bytes
+	Payload func(timestamp, nonce string, body []byte) []byte
+}
+
+// Default is the scheme of partners integrating with us: X-Timestamp, X-Nonce and
+// X-Signature: sha256=hex(hmac("<timestamp>.<nonce>.<body>"))
+var Default = Scheme{
+	SignatureHeader: "X-Signature",
+	Prefix:          "sha256=",
+	TimestampHeader: "X-Timestamp",
+	NonceHeader:     "X-Nonce",
+	Payload: func(timestamp, nonce string, body []byte) []byte {
+		return append([]byte(timestamp+"."+nonce+"."), body...)
+	},
+}
+
+// Slack is the Slack request signing scheme
+var Slack = Scheme{
+	SignatureHeader: "X-Slack-Signature",
+	Prefix:          "v0=",
+	TimestampHeader: "X-Slack-Request-Timestamp",
+	Payload: func(timestamp, nonce string, body []byte) []byte {
+		return append([]byte("v0:"+timestamp+":"), body...)
+	},
+}
+
+// Nonces remembers nonces until they expire
+type Nonces struct {
+	mu    sync.Mutex
+	seen  map[string]time.Time
+	sweep time.Time
+}
+
+func NewNonces() *Nonces {
+	return &Nonces{seen: make(map[string]time.Time)}
+}
+
+// Use records nonce until expires and reports whether it was unused
+func (n *Nonces) Use(nonce string, expires time.Time) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	now := clock.Now()
+	if now.After(n.sweep) {
+		for k, exp := range n.seen {
+			if now.After(exp) {
+				delete(n.seen, k)
+			}
+		}
+		n.sweep = now.Add(time.Minute)
+	}
+
+	if exp, found := n.seen[nonce]; found && !now.After(exp) {
+		return false
+	}
+	n.seen[nonce] = expires
+	return true
+}
+
+type Verifier struct {
+	name      string
+	scheme    Scheme
+	secret    []byte
+	tolerance time.Duration
+	nonces    *Nonces
+}
+
+// New returns a verifier of requests signed with secret, name identifies the integration in the inbound_rejected metric.
+// Timestamps may be tolerance off the clock either way, nonces are kept as long as their timestamp is accepted.
+func New(name string, scheme Scheme, secret string, tolerance time.Duration, nonces *Nonces) *Verifier {
+	return &Verifier{name: name, scheme: scheme, secret: []byte(secret), tolerance: tolerance, nonces: nonces}
+}
+
+// Verify checks the signature, the timestamp and the nonce of a request with the given body
+func (v *Verifier) Verify(h http.Header, body []byte) error {
+	const op = "lib.api.inbound.Verify"
+
+	timestamp := h.Get(v.scheme.TimestampHeader)
+	sec, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, ErrTimestamp)
+	}
+	at := time.Unix(sec, 0)
+	if d := clock.Now().Sub(at); d > v.tolerance || d < -v.tolerance {
+		return fmt.Errorf("%s: %w", op, ErrTimestamp)
+	}
+
+	var nonce string
+	if v.scheme.NonceHeader != "" {
+		if nonce = h.Get(v.scheme.NonceHeader); nonce == "" {
+			return fmt.Errorf("%s: %w", op, ErrSignature)
+		}
+	}
+
+	// The timestamp and the nonce are only trusted once the signature covering them is valid.
+	got, ok := strings.CutPrefix(h.Get(v.scheme.SignatureHeader), v.scheme.Prefix)
+	sig, err := hex.DecodeString(got)
+	if !ok || err != nil || !hmac.Equal(sig, v.Sign(timestamp, nonce, body)) {
+		return fmt.Errorf("%s: %w", op, ErrSignature)
+	}
+
+	if nonce == "" {
+		nonce = got
+	}
+	if !v.nonces.Use(v.name+":"+nonce, at.Add(v.tolerance)) {
+		return fmt.Errorf("%s: %w", op, ErrReplayed)
+	}
+
+	return nil
+}
+
+// Sign returns the signature of the payload, e.g. for tests and clients
+func (v *Verifier) Sign(timestamp, nonce string, body []byte) []byte {
+	mac := hmac.New(sha256.New, v.secret)
+	mac.Write(v.scheme.Payload(timestamp, nonce, body))
+	return mac.Sum(nil)
+}
+
+// Middleware rejects requests failing Verify with 401 and restores the body for the handler
+func (v *Verifier) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(io.LimitReader(r.Body, MaxBody+1))
+		if err != nil {
+			http.Error(w, "Failed to read request body", http.StatusBadRequest)
+			return
+		}
+		if len(body) > MaxBody {
+			http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		if err := v.Verify(r.Header, body); err != nil {
+			reason := "signature"
+			switch {
+			case errors.Is(err, ErrTimestamp):
+				reason = "timestamp"
+			case errors.Is(err, ErrReplayed):
+				reason = "replayed"
+			}
+			metrics.InboundRejected.Add(v.name+"."+reason, 1)
+			http.Error(w, "Invalid request signature", http.StatusUnauthorized)
+			return
+		}
+
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,88 @@
+package inbound
+
+import (
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sabbatD/srest-api/internal/lib/clock"
+)
+
+func TestVerify(t *testing.T) {
+	now := time.Date(2024, 10, 1, 12, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(now)
+	t.Cleanup(clock.Set(fake))
+
+	v := New("partner", Default, "secret", 5*time.Minute, NewNonces())
+	body := []byte(`{"event":"ping"}`)
+	signed := func(at time.Time, nonce string, body []byte) http.Header {
+		ts := strconv.FormatInt(at.Unix(), 10)
+		h := http.Header{}
+		h.Set("X-Timestamp", ts)
+		h.Set("X-Nonce", nonce)
+		h.Set("X-Signature", "sha256="+hex.EncodeToString(v.Sign(ts, nonce, body)))
+		return h
+	}
+
+	tampered := signed(now, "n2", body)
+	tampered.Set("X-Nonce", "n3")
+
+	tests := []struct {
+		name string
+		h    http.Header
+		body []byte
+		want error
+	}{
+		{name: "valid", h: signed(now, "n1", body), body: body},
+		{name: "replayed", h: signed(now, "n1", body), body: body, want: ErrReplayed},
+		{name: "other body", h: signed(now, "n2", body), body: []byte(`{}`), want: ErrSignature},
+		{name: "unsigned nonce", h: tampered, body: body, want: ErrSignature},
+		{name: "too old", h: signed(now.Add(-6*time.Minute), "n4", body), body: body, want: ErrTimestamp},
+		{name: "in the future", h: signed(now.Add(6*time.Minute), "n5", body), body: body, want: ErrTimestamp},
+		{name: "missing", h: http.Header{}, body: body, want: ErrTimestamp},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := v.Verify(tt.h, tt.body); !errors.Is(err, tt.want) {
+				t.Errorf("Verify() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	// A nonce is forgotten once its timestamp is out of tolerance anyway.
+	fake.Advance(10 * time.Minute)
+	if err := v.Verify(signed(fake.Now(), "n1", body), body); err != nil {
+		t.Errorf("Verify() with an expired nonce reused = %v", err)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	v := New("slack", Slack, "secret", 5*time.Minute, NewNonces())
+	h := v.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(w, r.Body)
+	}))
+
+	body := "command=/todo&text=buy+milk"
+	ts := strconv.FormatInt(clock.Now().Unix(), 10)
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/integrations/slack", strings.NewReader(body))
+		req.Header.Set("X-Slack-Request-Timestamp", ts)
+		req.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(v.Sign(ts, "", []byte(body))))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := send(); rec.Code != http.StatusOK || rec.Body.String() != body {
+		t.Errorf("signed request: status = %d, body = %q", rec.Code, rec.Body)
+	}
+	if rec := send(); rec.Code != http.StatusUnauthorized {
+		t.Errorf("replayed request: status = %d", rec.Code)
+	}
+}
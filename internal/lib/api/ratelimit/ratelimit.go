@@ -0,0 +1,167 @@
+// Package ratelimit provides fixed window request limits keyed by caller.
+package ratelimit
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	util "github.com/sabbatD/srest-api/internal/http-server/handleUtil"
+	"github.com/sabbatD/srest-api/internal/lib/clock"
+	"github.com/sabbatD/srest-api/internal/lib/metrics"
+)
+
+type window struct {
+	start time.Time
+	count int
+}
+
+// Limiter allows up to limit requests per key in each window, keys may have their own limit, see SetLimit.
+type Limiter struct {
+	name   string
+	limit  int
+	window time.Duration
+
+	mu      sync.Mutex
+	windows map[string]*window
+	limits  map[string]int
+	sweep   time.Time
+}
+
+// New returns a limiter, name identifies it in the rate_limited metric.
+func New(name string, limit int, period time.Duration) *Limiter {
+	return &Limiter{
+		name:    name,
+		limit:   limit,
+		window:  period,
+		windows: make(map[string]*window),
+		limits:  make(map[string]int),
+	}
+}
+
+// SetLimit overrides the limit of key, zero lifts the limit and a negative limit restores the default.
+func (l *Limiter) SetLimit(key string, limit int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if limit < 0 {
+		delete(l.limits, key)
+		return
+	}
+	l.limits[key] = limit
+}
+
+// Limit returns the limit of key, its override or the default.
+func (l *Limiter) Limit(key string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.limitOf(key)
+}
+
+func (l *Limiter) limitOf(key string) int {
+	if limit, found := l.limits[key]; found {
+		return limit
+	}
+	return l.limit
+}
+
+// Allow counts a request for key and reports whether it is within the limit,
+// how many requests are left in the current window and when the window resets.
+// Windows follow the process clock, see clock.Set.
+func (l *Limiter) Allow(key string) (ok bool, remaining int, reset time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := clock.Now()
+	if now.Sub(l.sweep) > l.window {
+		for k, w := range l.windows {
+			if now.Sub(w.start) >= l.window {
+				delete(l.windows, k)
+			}
+		}
+		l.sweep = now
+	}
+
+	w, found := l.windows[key]
+	if !found || now.Sub(w.start) >= l.window {
+		w = &window{start: now}
+		l.windows[key] = w
+	}
+	reset = w.start.Add(l.window)
+
+	limit := l.limitOf(key)
+	if w.count >= limit {
+		return false, 0, reset
+	}
+	w.count++
+
+	return true, limit - w.count, reset
+}
+
+// Peek reports how many requests key has left in the current window and when the window resets, without
+// counting a request. Without a current window the whole limit is left, the window would start now.
+func (l *Limiter) Peek(key string) (remaining int, reset time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := clock.Now()
+	limit := l.limitOf(key)
+	w, found := l.windows[key]
+	if !found || now.Sub(w.start) >= l.window {
+		return limit, now.Add(l.window)
+	}
+	return max(limit-w.count, 0), w.start.Add(l.window)
+}
+
+// Middleware limits the requests of each caller identified by key.
+// Safe methods and requests without a key pass through; a non-positive limit of the key disables the middleware for it.
+// Every response to a request with a key carries the quota headers, so clients can slow down before
+// they are limited; safe methods report the quota without counting against it.
+// Limited requests get 429 with Retry-After and the RATE_LIMITED problem code.
+func (l *Limiter) Middleware(key func(r *http.Request) (string, bool)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			k, found := key(r)
+			if !found {
+				next.ServeHTTP(w, r)
+				return
+			}
+			limit := l.Limit(k)
+			if limit <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				remaining, reset := l.Peek(k)
+				l.quotaHeaders(w, limit, remaining, reset)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ok, remaining, reset := l.Allow(k)
+			l.quotaHeaders(w, limit, remaining, reset)
+			if !ok {
+				metrics.RateLimited.Add(l.name, 1)
+
+				retry := int(reset.Sub(clock.Now()).Seconds()) + 1
+				w.Header().Set("Retry-After", strconv.Itoa(retry))
+
+				util.WriteError(w, r, util.NewError(http.StatusTooManyRequests, util.CodeRateLimited, "Too many requests"))
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (l *Limiter) quotaHeaders(w http.ResponseWriter, limit, remaining int, reset time.Time) {
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(limit))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+}
@@ -0,0 +1,119 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	util "github.com/sabbatD/srest-api/internal/http-server/handleUtil"
+	"github.com/sabbatD/srest-api/internal/lib/clock"
+)
+
+func TestAllow(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 10, 1, 12, 0, 0, 0, time.UTC))
+	t.Cleanup(clock.Set(fake))
+
+	l := New("test", 2, time.Minute)
+
+	tests := []struct {
+		name      string
+		key       string
+		after     time.Duration
+		want      bool
+		remaining int
+	}{
+		{name: "first", key: "1", want: true, remaining: 1},
+		{name: "second", key: "1", want: true, remaining: 0},
+		{name: "over limit", key: "1", after: 30 * time.Second, want: false, remaining: 0},
+		{name: "other key", key: "2", want: true, remaining: 1},
+		{name: "next window", key: "1", after: 30 * time.Second, want: true, remaining: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake.Advance(tt.after)
+
+			ok, remaining, _ := l.Allow(tt.key)
+			if ok != tt.want || remaining != tt.remaining {
+				t.Errorf("Allow() = %v, %v, want %v, %v", ok, remaining, tt.want, tt.remaining)
+			}
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 10, 1, 12, 0, 0, 0, time.UTC))
+	t.Cleanup(clock.Set(fake))
+
+	l := New("test_middleware", 1, time.Minute)
+	h := l.Middleware(func(r *http.Request) (string, bool) { return "1", true })(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+
+	// Reads report the quota without using it
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if got := rec.Header().Get("X-RateLimit-Remaining"); got != "1" {
+		t.Errorf("read: X-RateLimit-Remaining = %s, want 1", got)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("first request: status = %d", rec.Code)
+	}
+	reset := fake.Now().Add(time.Minute).Unix()
+	if rec.Header().Get("X-RateLimit-Limit") != "1" || rec.Header().Get("X-RateLimit-Remaining") != "0" ||
+		rec.Header().Get("X-RateLimit-Reset") != strconv.FormatInt(reset, 10) {
+		t.Errorf("allowed request: headers = %v", rec.Header())
+	}
+
+	fake.Advance(20 * time.Second)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+
+	var problem util.Problem
+	if err := json.NewDecoder(rec.Body).Decode(&problem); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusTooManyRequests || problem.Code != util.CodeRateLimited ||
+		rec.Header().Get("Content-Type") != "application/problem+json" {
+		t.Errorf("limited request: status = %d, problem = %+v", rec.Code, problem)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "41" {
+		t.Errorf("Retry-After = %s, want 41", got)
+	}
+}
+
+func TestSetLimit(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 10, 1, 12, 0, 0, 0, time.UTC))
+	t.Cleanup(clock.Set(fake))
+
+	l := New("test_overrides", 1, time.Minute)
+	l.SetLimit("vip", 3)
+	l.SetLimit("exempt", 0)
+
+	if ok, remaining, _ := l.Allow("vip"); !ok || remaining != 2 {
+		t.Errorf("override: Allow() = %v, %d, want true, 2", ok, remaining)
+	}
+	if ok, _, _ := l.Allow("other"); !ok {
+		t.Error("default: first request limited")
+	}
+	if ok, _, _ := l.Allow("other"); ok {
+		t.Error("default: second request allowed")
+	}
+
+	h := l.Middleware(func(r *http.Request) (string, bool) { return "exempt", true })(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	for i := 0; i < 3; i++ {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+		if rec.Code != http.StatusOK || rec.Header().Get("X-RateLimit-Limit") != "" {
+			t.Errorf("lifted limit: status = %d, headers = %v", rec.Code, rec.Header())
+		}
+	}
+
+	l.SetLimit("vip", -1)
+	if got := l.Limit("vip"); got != 1 {
+		t.Errorf("restored: Limit() = %d, want 1", got)
+	}
+}
@@ -0,0 +1,138 @@
+// Package readonly switches the API to read-only mode: routes that write answer 503 READ_ONLY while reads go on,
+// e.g. during migrations and failovers of the primary database. Admins switch it in the settings, every instance
+// picks the setting up within the refresh interval; the configuration can also force it, e.g. on a replica.
+package readonly
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	util "github.com/sabbatD/srest-api/internal/http-server/handleUtil"
+	"github.com/sabbatD/srest-api/internal/lib/logger/sl"
+	"github.com/sabbatD/srest-api/internal/lib/metrics"
+)
+
+// ErrForced is returned for setting the mode while the configuration forces read-only mode
+var ErrForced = errors.New("read-only mode is forced by the configuration")
+
+// Mode is the read-only setting
+type Mode struct {
+	Enabled bool `json:"enabled"`
+	// Message tells clients why writes are rejected, e.g. until when
+	Message string `json:"message,omitempty" validate:"max=200"`
+	// Forced is set when the configuration enables read-only mode, admins cannot switch it off. It is ignored when set.
+	Forced bool `json:"forced,omitempty"`
+}
+
+type Store interface {
+	// ReadOnly returns the mode set by an admin, off when there is none
+	ReadOnly(ctx context.Context) (enabled bool, message string, err error)
+	SetReadOnly(ctx context.Context, actor int, enabled bool, message string) error
+}
+
+// Switch holds the mode in effect on this instance
+type Switch struct {
+	log   *slog.Logger
+	store Store
+	// forced enables read-only mode whatever the setting
+	forced bool
+	// refresh is the interval the setting is reloaded at, zero loads it only on Refresh
+	refresh time.Duration
+	mode    atomic.Pointer[Mode]
+}
+
+func New(log *slog.Logger, store Store, forced bool, refresh time.Duration) *Switch {
+	s := &Switch{log: log, store: store, forced: forced, refresh: refresh}
+	s.apply(Mode{})
+	return s
+}
+
+func (s *Switch) apply(m Mode) {
+	if s.forced {
+		m.Enabled, m.Forced = true, true
+	}
+	s.mode.Store(&m)
+}
+
+// Mode returns the mode in effect on this instance
+func (s *Switch) Mode(ctx context.Context) (Mode, error) {
+	return *s.mode.Load(), nil
+}
+
+// SetMode stores the mode set by actor and applies it on this instance at once, the others follow on their next refresh.
+// Returns ErrForced while the configuration forces read-only mode.
+func (s *Switch) SetMode(ctx context.Context, actor int, m Mode) error {
+	const op = "lib.readonly.SetMode"
+
+	if s.forced {
+		return fmt.Errorf("%s: %w", op, ErrForced)
+	}
+	if err := s.store.SetReadOnly(ctx, actor, m.Enabled, m.Message); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	s.apply(Mode{Enabled: m.Enabled, Message: m.Message})
+	return nil
+}
+
+// Refresh loads the stored mode, on failure the mode in effect is kept
+func (s *Switch) Refresh(ctx context.Context) error {
+	const op = "lib.readonly.Refresh"
+
+	enabled, message, err := s.store.ReadOnly(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	if prev := s.mode.Load(); prev.Enabled != (enabled || s.forced) {
+		s.log.Warn("read-only mode switched", slog.Bool("enabled", enabled || s.forced))
+	}
+	s.apply(Mode{Enabled: enabled, Message: message})
+	return nil
+}
+
+// Run refreshes the mode every refresh interval until ctx is done
+func (s *Switch) Run(ctx context.Context) {
+	const op = "lib.readonly.Run"
+
+	if s.refresh <= 0 {
+		return
+	}
+	log := s.log.With(slog.String("op", op))
+
+	ticker := time.NewTicker(s.refresh)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Refresh(ctx); err != nil {
+				log.Error("failed to refresh the read-only mode", sl.Err(err))
+				metrics.JobFailures.Add("read_only", 1)
+			}
+		}
+	}
+}
+
+// Middleware rejects the requests it wraps with 503 READ_ONLY while read-only mode is on,
+// see routes.Registry.ReadOnly. The rejections are counted in the read_only_rejected metric.
+func (s *Switch) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m := s.mode.Load()
+		if !m.Enabled {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		metrics.ReadOnlyRejected.Add(1)
+		msg := "The API is in read-only mode, try again later"
+		if m.Message != "" {
+			msg += ": " + m.Message
+		}
+		util.WriteError(w, r, util.NewError(http.StatusServiceUnavailable, util.CodeReadOnly, msg))
+	})
+}
@@ -0,0 +1,76 @@
+package readonly
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type memStore struct {
+	enabled bool
+	message string
+	err     error
+}
+
+func (m *memStore) ReadOnly(ctx context.Context) (bool, string, error) {
+	return m.enabled, m.message, m.err
+}
+
+func (m *memStore) SetReadOnly(ctx context.Context, actor int, enabled bool, message string) error {
+	m.enabled, m.message = enabled, message
+	return nil
+}
+
+func TestSwitch(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := &memStore{}
+	s := New(log, store, false, 0)
+	h := s.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	write := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/todos", nil))
+		return rec
+	}
+
+	if rec := write(); rec.Code != http.StatusOK {
+		t.Fatalf("write in read-write mode: status %d", rec.Code)
+	}
+
+	if err := s.SetMode(context.Background(), 1, Mode{Enabled: true, Message: "failover until 12:00 UTC"}); err != nil {
+		t.Fatal(err)
+	}
+	rec := write()
+	if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), `"code":"READ_ONLY"`) ||
+		!strings.Contains(rec.Body.String(), "failover until 12:00 UTC") {
+		t.Fatalf("write in read-only mode: status %d %s", rec.Code, rec.Body)
+	}
+
+	// Another instance switched it off, a failed refresh keeps the mode in effect
+	store.enabled, store.err = false, errors.New("connection refused")
+	if err := s.Refresh(context.Background()); err == nil {
+		t.Error("Refresh() with a failing store succeeded")
+	}
+	if m, _ := s.Mode(context.Background()); !m.Enabled {
+		t.Error("failed refresh dropped the mode")
+	}
+	store.err = nil
+	if err := s.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if rec := write(); rec.Code != http.StatusOK {
+		t.Errorf("write after refresh: status %d", rec.Code)
+	}
+
+	forced := New(log, store, true, 0)
+	if err := forced.SetMode(context.Background(), 1, Mode{}); !errors.Is(err, ErrForced) {
+		t.Errorf("SetMode() of a forced mode error = %v, want ErrForced", err)
+	}
+	if m, _ := forced.Mode(context.Background()); !m.Enabled || !m.Forced {
+		t.Errorf("forced mode = %+v", m)
+	}
+}
@@ -0,0 +1,359 @@
+// Package routes declares the API routes in one place: each route names who may call it, its rate limit class,
+// the scopes it needs, whether it is deprecated and whether it stays open in read-only mode. The chi router is built from the declarations and the served
+// spec documents them, so middleware wiring and docs cannot drift apart.
+//
+//	reg := routes.New()
+//	reg.Auth(routes.User, access.JWTAuthMiddleware)
+//	reg.Limit("reports", reports.Middleware(access.UserKey))
+//
+//	r := reg.Group("/reports", routes.WithAuth(routes.User), routes.WithLimit("reports"))
+//	r.Post("/", report.Create(log, storage))
+//
+//	err := reg.Mount(router)
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sabbatD/srest-api/internal/lib/api/deprecation"
+)
+
+// Auth is who may call a route, each kind is enforced by the middleware set with Registry.Auth
+type Auth string
+
+const (
+	// Public routes take no token
+	Public Auth = "public"
+	// Token routes take any valid token: of users, guests and users who must change their password
+	Token Auth = "token"
+	// PasswordChange routes take user tokens, also of users who must change their password
+	PasswordChange Auth = "password_change"
+	// User routes take user tokens
+	User Auth = "user"
+	// Guest routes take user and guest tokens
+	Guest Auth = "guest"
+	// Admin routes take user tokens, the handlers check the user has admin permissions
+	Admin Auth = "admin"
+	// SCIM routes take the SCIM token of the identity provider
+	SCIM Auth = "scim"
+	// OIDC routes take the access tokens issued to OpenID Connect clients
+	OIDC Auth = "oidc"
+)
+
+type Middleware = func(http.Handler) http.Handler
+
+// Policy is what a route declares about itself
+type Policy struct {
+	Auth Auth
+	// Limit is the rate limit class, empty for none
+	Limit string
+	// Scopes the token needs, all of them
+	Scopes []string
+	// Deprecated is set on routes to be removed
+	Deprecated *deprecation.Policy
+	// AllowReadOnly serves the route in read-only mode whatever its method: reads sent as POST
+	// and the writes read-only mode must not lock out, e.g. signing in
+	AllowReadOnly bool
+}
+
+// Route is a declared route. Path is relative to the router the registry is mounted on,
+// without a trailing slash, e.g. /todos/{id}.
+type Route struct {
+	Method string
+	Path   string
+	Policy
+}
+
+// Writes tells whether read-only mode rejects the route: its method changes data and it does not allow read-only mode
+func (r Route) Writes() bool {
+	return writes(r.Method, r.Policy)
+}
+
+func writes(method string, p Policy) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	return !p.AllowReadOnly
+}
+
+type settings struct {
+	policy      Policy
+	middlewares []Middleware
+}
+
+type Option func(*settings)
+
+// WithAuth sets who may call the routes
+func WithAuth(a Auth) Option {
+	return func(s *settings) { s.policy.Auth = a }
+}
+
+// WithLimit sets the rate limit class of the routes
+func WithLimit(class string) Option {
+	return func(s *settings) { s.policy.Limit = class }
+}
+
+// WithScopes adds scopes the routes need
+func WithScopes(scopes ...string) Option {
+	return func(s *settings) { s.policy.Scopes = append(slices.Clip(s.policy.Scopes), scopes...) }
+}
+
+// Deprecated marks the routes deprecated by p, see deprecation.New
+func Deprecated(p deprecation.Policy) Option {
+	return func(s *settings) { s.policy.Deprecated = &p }
+}
+
+// AllowReadOnly keeps the routes open in read-only mode, see Policy.AllowReadOnly
+func AllowReadOnly() Option {
+	return func(s *settings) { s.policy.AllowReadOnly = true }
+}
+
+// With adds middlewares run after the auth, rate limit and scope checks, e.g. deadlines
+func With(middlewares ...Middleware) Option {
+	return func(s *settings) { s.middlewares = append(slices.Clip(s.middlewares), middlewares...) }
+}
+
+type entry struct {
+	method  string
+	pattern string
+	handler http.Handler
+	settings
+}
+
+// Group is a set of routes under a path prefix sharing options, routes and subgroups may override them
+type Group struct {
+	prefix string
+	path   string
+	settings
+	entries []entry
+	groups  []*Group
+}
+
+// Group returns a subgroup under prefix, an empty prefix groups routes without one
+func (g *Group) Group(prefix string, opts ...Option) *Group {
+	sub := &Group{prefix: prefix, path: g.path + prefix, settings: g.with(opts)}
+	g.groups = append(g.groups, sub)
+	return sub
+}
+
+// Handle declares the route method pattern under the group
+func (g *Group) Handle(method, pattern string, h http.Handler, opts ...Option) {
+	g.entries = append(g.entries, entry{method: method, pattern: pattern, handler: h, settings: g.with(opts)})
+}
+
+func (g *Group) Get(pattern string, h http.HandlerFunc, opts ...Option) {
+	g.Handle(http.MethodGet, pattern, h, opts...)
+}
+
+func (g *Group) Post(pattern string, h http.HandlerFunc, opts ...Option) {
+	g.Handle(http.MethodPost, pattern, h, opts...)
+}
+
+func (g *Group) Put(pattern string, h http.HandlerFunc, opts ...Option) {
+	g.Handle(http.MethodPut, pattern, h, opts...)
+}
+
+func (g *Group) Patch(pattern string, h http.HandlerFunc, opts ...Option) {
+	g.Handle(http.MethodPatch, pattern, h, opts...)
+}
+
+func (g *Group) Delete(pattern string, h http.HandlerFunc, opts ...Option) {
+	g.Handle(http.MethodDelete, pattern, h, opts...)
+}
+
+func (g *Group) with(opts []Option) settings {
+	s := g.settings
+	for _, opt := range opts {
+		opt(&s)
+	}
+	return s
+}
+
+// Registry holds the declared routes and the middlewares enforcing their policies
+type Registry struct {
+	root Group
+
+	auth   map[Auth]Middleware
+	limits map[string]Middleware
+	scopes func(scopes []string) Middleware
+	// readOnly rejects the writing routes while read-only mode is on
+	readOnly Middleware
+	// scoped are the auth kinds whose routes must declare scopes
+	scoped map[Auth]bool
+}
+
+func New() *Registry {
+	return &Registry{auth: make(map[Auth]Middleware), limits: make(map[string]Middleware), scoped: make(map[Auth]bool)}
+}
+
+// Group returns a group of routes under prefix, see Group.Group
+func (reg *Registry) Group(prefix string, opts ...Option) *Group {
+	return reg.root.Group(prefix, opts...)
+}
+
+// Handle declares a route outside any group
+func (reg *Registry) Handle(method, pattern string, h http.Handler, opts ...Option) {
+	reg.root.Handle(method, pattern, h, opts...)
+}
+
+func (reg *Registry) Get(pattern string, h http.HandlerFunc, opts ...Option) {
+	reg.root.Get(pattern, h, opts...)
+}
+
+func (reg *Registry) Post(pattern string, h http.HandlerFunc, opts ...Option) {
+	reg.root.Post(pattern, h, opts...)
+}
+
+// Auth sets the middleware enforcing an auth kind
+func (reg *Registry) Auth(a Auth, mw Middleware) {
+	reg.auth[a] = mw
+}
+
+// Limit sets the middleware enforcing a rate limit class
+func (reg *Registry) Limit(class string, mw Middleware) {
+	reg.limits[class] = mw
+}
+
+// Scopes sets the middleware factory checking tokens have the scopes of a route.
+// Routes may only declare scopes once it is set.
+func (reg *Registry) Scopes(check func(scopes []string) Middleware) {
+	reg.scopes = check
+}
+
+// ReadOnly sets the middleware guarding the routes that write, see Route.Writes
+func (reg *Registry) ReadOnly(mw Middleware) {
+	reg.readOnly = mw
+}
+
+// RequireScopes makes every route of the auth kind declare scopes, e.g. so no admin route is left
+// to the checks of its handler alone
+func (reg *Registry) RequireScopes(a Auth) {
+	reg.scoped[a] = true
+}
+
+// Mount registers the declared routes on r. Each route runs, in order, the deprecation middleware,
+// the read-only guard when the route writes, the middleware of its auth kind, of its rate limit class, the scope check and its own middlewares.
+// Routes without auth or with an auth kind, limit class or scopes nothing enforces are an error,
+// so are routes without scopes of an auth kind that requires them.
+func (reg *Registry) Mount(r chi.Router) error {
+	const op = "routes.Mount"
+
+	if err := reg.check(&reg.root); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	reg.mount(r, &reg.root)
+	return nil
+}
+
+func (reg *Registry) check(g *Group) error {
+	for _, e := range g.entries {
+		p := e.policy
+		route := e.method + " " + g.path + e.pattern
+		if p.Auth == "" {
+			return fmt.Errorf("%s declares no auth", route)
+		}
+		if _, ok := reg.auth[p.Auth]; !ok && p.Auth != Public {
+			return fmt.Errorf("%s: nothing enforces auth %q", route, p.Auth)
+		}
+		if _, ok := reg.limits[p.Limit]; !ok && p.Limit != "" {
+			return fmt.Errorf("%s: unknown rate limit class %q", route, p.Limit)
+		}
+		if len(p.Scopes) > 0 && reg.scopes == nil {
+			return fmt.Errorf("%s declares scopes but nothing checks them", route)
+		}
+		if len(p.Scopes) == 0 && reg.scoped[p.Auth] {
+			return fmt.Errorf("%s declares no scopes", route)
+		}
+	}
+	for _, sub := range g.groups {
+		if err := reg.check(sub); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mount registers groups as chi subrouters, so /todos and /todos/ both match a route declared as "/"
+func (reg *Registry) mount(r chi.Router, g *Group) {
+	for _, e := range g.entries {
+		r.With(reg.chain(e.method, e.policy, e.middlewares)...).Method(e.method, e.pattern, e.handler)
+	}
+	for _, sub := range g.groups {
+		if sub.prefix == "" {
+			r.Group(func(r chi.Router) { reg.mount(r, sub) })
+		} else {
+			r.Route(sub.prefix, func(r chi.Router) { reg.mount(r, sub) })
+		}
+	}
+}
+
+func (reg *Registry) chain(method string, p Policy, middlewares []Middleware) []Middleware {
+	var chain []Middleware
+	if p.Deprecated != nil {
+		chain = append(chain, deprecation.New(*p.Deprecated))
+	}
+	if reg.readOnly != nil && writes(method, p) {
+		chain = append(chain, reg.readOnly)
+	}
+	if p.Auth != Public {
+		chain = append(chain, reg.auth[p.Auth])
+	}
+	if p.Limit != "" {
+		chain = append(chain, reg.limits[p.Limit])
+	}
+	if len(p.Scopes) > 0 {
+		chain = append(chain, reg.scopes(p.Scopes))
+	}
+	return append(chain, middlewares...)
+}
+
+// Routes returns the declared routes sorted by path and method
+func (reg *Registry) Routes() []Route {
+	var all []Route
+	var walk func(g *Group)
+	walk = func(g *Group) {
+		for _, e := range g.entries {
+			all = append(all, Route{Method: e.method, Path: Normalize(g.path + e.pattern), Policy: e.policy})
+		}
+		for _, sub := range g.groups {
+			walk(sub)
+		}
+	}
+	walk(&reg.root)
+
+	slices.SortFunc(all, func(a, b Route) int {
+		if c := strings.Compare(a.Path, b.Path); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Method, b.Method)
+	})
+	return all
+}
+
+// Normalize turns a chi pattern into a documented path: no trailing slash and no parameter regexps,
+// e.g. /todos/{id:[0-9]+}/ is /todos/{id}
+func Normalize(pattern string) string {
+	var b strings.Builder
+	for {
+		start := strings.Index(pattern, "{")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(pattern[start:], "}") + start
+		name, _, _ := strings.Cut(pattern[start+1:end], ":")
+		b.WriteString(pattern[:start] + "{" + name + "}")
+		pattern = pattern[end+1:]
+	}
+	b.WriteString(pattern)
+
+	path := b.String()
+	if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+	return path
+}
@@ -0,0 +1,129 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// mark returns a middleware appending name to the X-Chain header
+func mark(name string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("X-Chain", name)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func ok(http.ResponseWriter, *http.Request) {}
+
+func TestMount(t *testing.T) {
+	reg := New()
+	reg.Auth(User, mark("user"))
+	reg.Auth(Guest, mark("guest"))
+	reg.Limit("writes", mark("writes"))
+	reg.Scopes(func(scopes []string) Middleware { return mark("scopes:" + strings.Join(scopes, ",")) })
+	reg.ReadOnly(mark("read-only"))
+
+	reg.Get("/meta", ok, WithAuth(Public))
+	todos := reg.Group("/todos", WithAuth(Guest), WithLimit("writes"), With(mark("deadline")))
+	todos.Get("/", ok)
+	todos.Group("", With(mark("long"))).Get("/changes", ok)
+	todos.Post("/search", ok, AllowReadOnly())
+	item := todos.Group("/{id}", With(mark("owner")))
+	item.Put("/", ok, WithAuth(User), WithScopes("todos:write"))
+
+	r := chi.NewRouter()
+	if err := reg.Mount(r); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		method, path string
+		want         string
+	}{
+		{http.MethodGet, "/meta", ""},
+		{http.MethodGet, "/todos", "guest,writes,deadline"},
+		{http.MethodGet, "/todos/", "guest,writes,deadline"},
+		{http.MethodGet, "/todos/changes", "guest,writes,deadline,long"},
+		{http.MethodPost, "/todos/search", "guest,writes,deadline"},
+		{http.MethodPut, "/todos/1", "read-only,user,writes,scopes:todos:write,deadline,owner"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200", rec.Code)
+			}
+			if got := strings.Join(rec.Header().Values("X-Chain"), ","); got != tt.want {
+				t.Errorf("middlewares = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	got := reg.Routes()
+	want := []Route{
+		{Method: http.MethodGet, Path: "/meta", Policy: Policy{Auth: Public}},
+		{Method: http.MethodGet, Path: "/todos", Policy: Policy{Auth: Guest, Limit: "writes"}},
+		{Method: http.MethodGet, Path: "/todos/changes", Policy: Policy{Auth: Guest, Limit: "writes"}},
+		{Method: http.MethodPost, Path: "/todos/search", Policy: Policy{Auth: Guest, Limit: "writes", AllowReadOnly: true}},
+		{Method: http.MethodPut, Path: "/todos/{id}", Policy: Policy{Auth: User, Limit: "writes", Scopes: []string{"todos:write"}}},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Routes() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i].Method != want[i].Method || got[i].Path != want[i].Path || got[i].Auth != want[i].Auth ||
+			got[i].Limit != want[i].Limit || strings.Join(got[i].Scopes, ",") != strings.Join(want[i].Scopes, ",") ||
+			got[i].Writes() != want[i].Writes() {
+			t.Errorf("Routes()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestMountErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		declare func(reg *Registry)
+		want    string
+	}{
+		{name: "no auth", declare: func(reg *Registry) { reg.Get("/meta", ok) }, want: "declares no auth"},
+		{name: "unenforced auth", declare: func(reg *Registry) { reg.Get("/meta", ok, WithAuth(Admin)) }, want: `nothing enforces auth "admin"`},
+		{name: "unknown limit", declare: func(reg *Registry) { reg.Get("/meta", ok, WithAuth(Public), WithLimit("x")) }, want: `unknown rate limit class "x"`},
+		{name: "unchecked scopes", declare: func(reg *Registry) { reg.Get("/meta", ok, WithAuth(Public), WithScopes("a")) }, want: "nothing checks them"},
+		{name: "unscoped admin route", declare: func(reg *Registry) {
+			reg.Auth(Admin, func(h http.Handler) http.Handler { return h })
+			reg.RequireScopes(Admin)
+			reg.Get("/admin/metrics", ok, WithAuth(Admin))
+		}, want: "GET /admin/metrics declares no scopes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg := New()
+			tt.declare(reg)
+			err := reg.Mount(chi.NewRouter())
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Mount() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	for pattern, want := range map[string]string{
+		"/":                    "/",
+		"/todos/":              "/todos",
+		"/todos/{id:[0-9]+}/":  "/todos/{id}",
+		"/todos/{id}/pin":      "/todos/{id}/pin",
+		"/filters/{filter}/to": "/filters/{filter}/to",
+	} {
+		if got := Normalize(pattern); got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", pattern, got, want)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package spec
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/language/display"
+)
+
+// The docs are written in English, the default language. Catalogs translate them to the other languages,
+// one file per language in locales, named by its BCP 47 tag.
+//
+//go:embed locales/*.json
+var locales embed.FS
+
+// Text is a translation, empty fields keep the English text
+type Text struct {
+	Summary     string `json:"summary,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Catalog translates the spec texts into one language: the API description and the operations by their id.
+// Only the description of Info is used.
+type Catalog struct {
+	Info       Text            `json:"info"`
+	Operations map[string]Text `json:"operations"`
+}
+
+var (
+	catalogs  = map[string]Catalog{}
+	languages = []language.Tag{language.English}
+	matcher   language.Matcher
+)
+
+func init() {
+	files, err := locales.ReadDir("locales")
+	if err != nil {
+		panic(err)
+	}
+	for _, f := range files {
+		data, err := locales.ReadFile(path.Join("locales", f.Name()))
+		if err != nil {
+			panic(err)
+		}
+		var c Catalog
+		if err := json.Unmarshal(data, &c); err != nil {
+			panic(fmt.Sprintf("spec: catalog %s: %v", f.Name(), err))
+		}
+		tag := language.MustParse(strings.TrimSuffix(f.Name(), ".json"))
+		catalogs[tag.String()] = c
+		languages = append(languages, tag)
+	}
+	matcher = language.NewMatcher(languages)
+}
+
+// Languages returns the languages the spec is served in, English first
+func Languages() []string {
+	tags := make([]string, len(languages))
+	for i, tag := range languages {
+		tags[i] = tag.String()
+	}
+	return tags
+}
+
+// Language returns the served language best matching lang, e.g. a lang query parameter, or else the Accept-Language
+// header accept. Without a match it is English.
+func Language(lang, accept string) string {
+	_, i := language.MatchStrings(matcher, lang, accept)
+	return languages[i].String()
+}
+
+// Localize returns the spec built by Build with its texts in lang, see Language. Operations the catalog
+// of lang has no text for keep the English one.
+func Localize(data []byte, lang string) ([]byte, error) {
+	const op = "spec.Localize"
+
+	c, ok := catalogs[lang]
+	if !ok {
+		return data, nil
+	}
+
+	var spec map[string]any
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	if info, ok := spec["info"].(map[string]any); ok {
+		translate(info, Text{Description: c.Info.Description})
+	}
+	paths, _ := spec["paths"].(map[string]any)
+	for _, p := range paths {
+		ops, _ := p.(map[string]any)
+		for _, o := range ops {
+			operation, _ := o.(map[string]any)
+			id, _ := operation["operationId"].(string)
+			if text, ok := c.Operations[id]; ok {
+				translate(operation, text)
+			}
+		}
+	}
+
+	return json.Marshal(spec)
+}
+
+// SwaggerUI returns the swagger UI config listing the spec at url in each language, for its language toggle.
+// The browser's language is shown first when the spec is served in it.
+func SwaggerUI(url string) map[string]string {
+	type spec struct {
+		URL  string `json:"url"`
+		Name string `json:"name"`
+		Lang string `json:"lang"`
+	}
+
+	specs := make([]spec, len(languages))
+	for i, tag := range languages {
+		specs[i] = spec{URL: url + "?lang=" + tag.String(), Name: display.Self.Name(tag), Lang: tag.String()}
+	}
+	urls, _ := json.Marshal(specs)
+
+	return map[string]string{
+		"urls":               string(urls),
+		`"urls.primaryName"`: fmt.Sprintf("(%s.find(s => navigator.language.startsWith(s.lang)) || {}).name", urls),
+	}
+}
+
+func translate(v map[string]any, t Text) {
+	if t.Summary != "" {
+		v["summary"] = t.Summary
+	}
+	if t.Description != "" {
+		v["description"] = t.Description
+	}
+}
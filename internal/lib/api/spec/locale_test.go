@@ -0,0 +1,94 @@
+package spec
+
+import (
+	"encoding/json"
+	"testing"
+
+	_ "github.com/sabbatD/srest-api/docs"
+	"github.com/swaggo/swag"
+)
+
+func TestLanguage(t *testing.T) {
+	tests := []struct {
+		lang, accept string
+		want         string
+	}{
+		{"", "", "en"},
+		{"ru", "", "ru"},
+		{"ru-RU", "", "ru"},
+		{"", "ru-RU,ru;q=0.9,en;q=0.8", "ru"},
+		{"en", "ru", "en"},
+		{"", "de-DE,de;q=0.9", "en"},
+		{"fr", "", "en"},
+	}
+	for _, tt := range tests {
+		if got := Language(tt.lang, tt.accept); got != tt.want {
+			t.Errorf("Language(%q, %q) = %q, want %q", tt.lang, tt.accept, got, tt.want)
+		}
+	}
+}
+
+func TestLocalize(t *testing.T) {
+	doc := `{"info": {"title": "sAPI", "description": "API"}, "paths": {
+		"/auth/signin": {"post": {"operationId": "signIn", "summary": "Authenticate user", "description": "Authenticates"}},
+		"/custom": {"get": {"operationId": "notInCatalog", "summary": "Custom"}}
+	}}`
+
+	data, err := Localize([]byte(doc), "en")
+	if err != nil || string(data) != doc {
+		t.Fatalf("Localize(en) = %s, %v, want the spec unchanged", data, err)
+	}
+
+	data, err = Localize([]byte(doc), "ru")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var spec struct {
+		Info  struct{ Title, Description string }
+		Paths map[string]map[string]struct{ Summary, Description string }
+	}
+	if err := json.Unmarshal(data, &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	ru := catalogs["ru"]
+	if spec.Info.Title != "sAPI" || spec.Info.Description != ru.Info.Description {
+		t.Errorf("info = %+v", spec.Info)
+	}
+	if got := spec.Paths["/auth/signin"]["post"]; got.Summary != ru.Operations["signIn"].Summary || got.Description != ru.Operations["signIn"].Description {
+		t.Errorf("signIn = %+v", got)
+	}
+	if got := spec.Paths["/custom"]["get"]; got.Summary != "Custom" {
+		t.Errorf("operation without a translation = %+v, want the English text", got)
+	}
+}
+
+// Catalog entries have to name documented operations, so a renamed @ID does not silently lose its translation
+func TestCatalogs(t *testing.T) {
+	doc, err := swag.ReadDoc()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var spec struct {
+		Paths map[string]map[string]struct {
+			ID string `json:"operationId"`
+		}
+	}
+	if err := json.Unmarshal([]byte(doc), &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	documented := map[string]bool{}
+	for _, ops := range spec.Paths {
+		for _, o := range ops {
+			documented[o.ID] = true
+		}
+	}
+	for lang, c := range catalogs {
+		for id := range c.Operations {
+			if !documented[id] {
+				t.Errorf("catalog %s translates %s, which is not a documented operation", lang, id)
+			}
+		}
+	}
+}
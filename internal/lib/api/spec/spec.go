@@ -0,0 +1,122 @@
+// Package spec builds the API spec of a version from the routes the running server registers,
+// so clients generated from it match the deployed build.
+package spec
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sabbatD/srest-api/internal/lib/api/routes"
+)
+
+// Undocumented tags the operations of registered routes the embedded spec has no docs for
+const Undocumented = "undocumented"
+
+var param = regexp.MustCompile(`\{([^}:]+)(:[^}]*)?\}`)
+
+// Build returns the swagger document of the routes registered under base, from the embedded doc:
+//   - documented operations of registered routes are kept as they are
+//   - registered routes without docs get a bare operation tagged Undocumented
+//   - documented operations the server does not register are left out
+//
+// The declared routes, with paths relative to base, set the security, x-auth, x-rate-limit, deprecation
+// and x-writes, set on operations read-only mode rejects, of their operations, whatever the docs say. Wildcard routes, e.g. the swagger UI, are not part of the API.
+// The host is left out so clients use the one serving the spec; version and commit name the build.
+func Build(doc string, mux chi.Routes, declared []routes.Route, base, version, commit string) ([]byte, error) {
+	const op = "spec.Build"
+
+	var spec map[string]any
+	if err := json.Unmarshal([]byte(doc), &spec); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	documented, _ := spec["paths"].(map[string]any)
+	paths := make(map[string]any)
+
+	err := chi.Walk(mux, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if !strings.HasPrefix(route, base+"/") || strings.HasSuffix(route, "*") {
+			return nil
+		}
+		path := routes.Normalize(strings.TrimPrefix(route, base))
+		method = strings.ToLower(method)
+
+		ops, _ := paths[path].(map[string]any)
+		if ops == nil {
+			ops = make(map[string]any)
+			paths[path] = ops
+		}
+		if known, ok := documented[path].(map[string]any); ok && known[method] != nil {
+			ops[method] = known[method]
+			return nil
+		}
+		ops[method] = undocumented(path)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	for _, route := range declared {
+		ops, _ := paths[route.Path].(map[string]any)
+		if operation, ok := ops[strings.ToLower(route.Method)].(map[string]any); ok {
+			describe(operation, route.Policy)
+			if route.Writes() {
+				operation["x-writes"] = true
+			}
+		}
+	}
+
+	spec["paths"] = paths
+	spec["basePath"] = base
+	delete(spec, "host")
+	if info, ok := spec["info"].(map[string]any); ok {
+		info["version"] = version
+		if commit != "" {
+			info["x-build-commit"] = commit
+		}
+	}
+
+	return json.Marshal(spec)
+}
+
+// describe sets what a route declares on its operation
+func describe(operation map[string]any, p routes.Policy) {
+	operation["x-auth"] = p.Auth
+	if p.Auth == routes.Public {
+		operation["security"] = []any{}
+	} else {
+		scopes := p.Scopes
+		if scopes == nil {
+			scopes = []string{}
+		}
+		operation["security"] = []any{map[string]any{"BearerAuth": scopes}}
+	}
+	if p.Limit != "" {
+		operation["x-rate-limit"] = p.Limit
+	}
+	if p.Deprecated != nil {
+		operation["deprecated"] = true
+		if !p.Deprecated.Sunset.IsZero() {
+			operation["x-sunset"] = p.Deprecated.Sunset.UTC().Format(time.RFC3339)
+		}
+	}
+}
+
+func undocumented(path string) map[string]any {
+	params := []any{}
+	for _, m := range param.FindAllStringSubmatch(path, -1) {
+		params = append(params, map[string]any{"name": m[1], "in": "path", "required": true, "type": "string"})
+	}
+
+	return map[string]any{
+		"tags":       []string{Undocumented},
+		"summary":    "Undocumented route",
+		"parameters": params,
+		"responses":  map[string]any{"default": map[string]any{"description": "Undocumented"}},
+	}
+}
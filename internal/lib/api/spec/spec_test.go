@@ -0,0 +1,113 @@
+package spec
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sabbatD/srest-api/internal/lib/api/deprecation"
+	"github.com/sabbatD/srest-api/internal/lib/api/routes"
+)
+
+const doc = `{
+	"swagger": "2.0",
+	"info": {"title": "sAPI", "version": "v0.3.2"},
+	"host": "easydev.club",
+	"basePath": "/api/v1",
+	"paths": {
+		"/todos": {"get": {"summary": "List todos"}},
+		"/todos/{id}": {"get": {"summary": "Get todo"}, "delete": {"summary": "Delete todo"}},
+		"/removed": {"get": {"summary": "Not registered"}}
+	}
+}`
+
+func TestBuild(t *testing.T) {
+	ok := func(http.ResponseWriter, *http.Request) {}
+
+	r := chi.NewRouter()
+	r.Get("/healthz", ok)
+	r.Route("/api/v1", func(r chi.Router) {
+		r.Get("/swagger/*", ok)
+		r.Route("/todos", func(r chi.Router) {
+			r.Get("/", ok)
+			r.Get("/{id}", ok)
+			r.Put("/{id:[0-9]+}/pin", ok)
+		})
+	})
+
+	sunset := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	declared := []routes.Route{
+		{Method: http.MethodGet, Path: "/todos", Policy: routes.Policy{Auth: routes.Guest, Limit: "todo_writes"}},
+		{Method: http.MethodGet, Path: "/todos/{id}", Policy: routes.Policy{Auth: routes.Public}},
+		{Method: http.MethodPut, Path: "/todos/{id}/pin", Policy: routes.Policy{
+			Auth: routes.User, Scopes: []string{"todos:write"}, Deprecated: &deprecation.Policy{Sunset: sunset},
+		}},
+	}
+
+	data, err := Build(doc, r, declared, "/api/v1", "v1.2.3", "abc123")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var spec struct {
+		Host     string `json:"host"`
+		BasePath string `json:"basePath"`
+		Info     struct {
+			Version string `json:"version"`
+			Commit  string `json:"x-build-commit"`
+		} `json:"info"`
+		Paths map[string]map[string]struct {
+			Summary    string                `json:"summary"`
+			Tags       []string              `json:"tags"`
+			Security   []map[string][]string `json:"security"`
+			Auth       string                `json:"x-auth"`
+			Limit      string                `json:"x-rate-limit"`
+			Deprecated bool                  `json:"deprecated"`
+			Sunset     string                `json:"x-sunset"`
+			Writes     bool                  `json:"x-writes"`
+			Parameters []struct {
+				Name string `json:"name"`
+				In   string `json:"in"`
+			} `json:"parameters"`
+		} `json:"paths"`
+	}
+	if err := json.Unmarshal(data, &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.Host != "" || spec.BasePath != "/api/v1" || spec.Info.Version != "v1.2.3" || spec.Info.Commit != "abc123" {
+		t.Errorf("spec header = %q %q %+v", spec.Host, spec.BasePath, spec.Info)
+	}
+	if len(spec.Paths) != 3 {
+		t.Errorf("paths = %v, want /todos, /todos/{id} and /todos/{id}/pin", spec.Paths)
+	}
+	if spec.Paths["/todos"]["get"].Summary != "List todos" || spec.Paths["/todos/{id}"]["get"].Summary != "Get todo" {
+		t.Errorf("documented operations = %+v", spec.Paths)
+	}
+	// Deleting is documented but not registered
+	if _, found := spec.Paths["/todos/{id}"]["delete"]; found {
+		t.Error("unregistered operation kept")
+	}
+
+	pin := spec.Paths["/todos/{id}/pin"]["put"]
+	if len(pin.Tags) != 1 || pin.Tags[0] != Undocumented || len(pin.Parameters) != 1 || pin.Parameters[0].Name != "id" || pin.Parameters[0].In != "path" {
+		t.Errorf("undocumented operation = %+v", pin)
+	}
+
+	// Declarations win over the docs
+	list := spec.Paths["/todos"]["get"]
+	if list.Auth != "guest" || list.Limit != "todo_writes" || len(list.Security) != 1 || list.Security[0]["BearerAuth"] == nil {
+		t.Errorf("declared list = %+v", list)
+	}
+	if get := spec.Paths["/todos/{id}"]["get"]; get.Auth != "public" || get.Security == nil || len(get.Security) != 0 {
+		t.Errorf("declared public get = %+v", get)
+	}
+	if list.Writes || !pin.Writes {
+		t.Errorf("x-writes of list = %v, of pin = %v", list.Writes, pin.Writes)
+	}
+	if !pin.Deprecated || pin.Sunset != "2025-06-01T00:00:00Z" || len(pin.Security) != 1 || pin.Security[0]["BearerAuth"][0] != "todos:write" {
+		t.Errorf("declared pin = %+v", pin)
+	}
+}
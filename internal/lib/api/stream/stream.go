@@ -0,0 +1,118 @@
+// Package stream writes large JSON lists item by item instead of building them in memory.
+package stream
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Field is a top level member written after the streamed array.
+type Field struct {
+	Key   string
+	Value any
+}
+
+// Emit encodes one item of the array.
+type Emit func(v any) error
+
+// List writes {"data":[...], <fields>} to w. The array is filled by each,
+// which calls emit once per item; fields are evaluated after each returns,
+// so they may be filled in while iterating.
+//
+// Nothing is written until the first item or the end of the list, so an error
+// returned by each before that is returned to the caller, which may still answer
+// with an error status. Once output started, an error aborts the response.
+// The body is gzip compressed when the client accepts it.
+func List(w http.ResponseWriter, r *http.Request, each func(emit Emit) error, fields ...func() Field) error {
+	s := &list{w: w, r: r}
+
+	if err := each(s.emit); err != nil {
+		if s.started {
+			panic(http.ErrAbortHandler)
+		}
+		return err
+	}
+
+	// Write errors past this point mean the client went away, there is nobody to report them to.
+	s.start()
+	io.WriteString(s.out, "]")
+	for _, f := range fields {
+		field := f()
+		key, _ := json.Marshal(field.Key)
+		io.WriteString(s.out, ",")
+		s.out.Write(key)
+		io.WriteString(s.out, ":")
+		s.enc.Encode(field.Value)
+	}
+	io.WriteString(s.out, "}\n")
+	s.close()
+
+	return nil
+}
+
+type list struct {
+	w       http.ResponseWriter
+	r       *http.Request
+	out     *bufio.Writer
+	gz      *gzip.Writer
+	enc     *json.Encoder
+	started bool
+	n       int
+}
+
+func (s *list) start() error {
+	if s.started {
+		return nil
+	}
+	s.started = true
+
+	s.w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	s.w.Header().Add("Vary", "Accept-Encoding")
+
+	var dst io.Writer = s.w
+	if acceptsGzip(s.r) {
+		s.w.Header().Set("Content-Encoding", "gzip")
+		s.gz = gzip.NewWriter(s.w)
+		dst = s.gz
+	}
+	s.w.WriteHeader(http.StatusOK)
+
+	s.out = bufio.NewWriter(dst)
+	s.enc = json.NewEncoder(s.out)
+	_, err := io.WriteString(s.out, `{"data":[`)
+	return err
+}
+
+func (s *list) emit(v any) error {
+	if err := s.start(); err != nil {
+		return err
+	}
+	if s.n > 0 {
+		if _, err := io.WriteString(s.out, ","); err != nil {
+			return err
+		}
+	}
+	s.n++
+	return s.enc.Encode(v)
+}
+
+func (s *list) close() {
+	s.out.Flush()
+	if s.gz != nil {
+		s.gz.Close()
+	}
+}
+
+func acceptsGzip(r *http.Request) bool {
+	for _, enc := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(enc), ";")
+		if strings.EqualFold(strings.TrimSpace(name), "gzip") && strings.ReplaceAll(params, " ", "") != "q=0" {
+			return true
+		}
+	}
+	return false
+}
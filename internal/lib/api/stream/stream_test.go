@@ -0,0 +1,129 @@
+package stream
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/render"
+)
+
+type item struct {
+	ID    int    `json:"id"`
+	Title string `json:"title"`
+}
+
+func items(n int, fn func(item) error) error {
+	for i := 0; i < n; i++ {
+		if err := fn(item{ID: i, Title: fmt.Sprintf("task number %d", i)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func streamItems(w http.ResponseWriter, r *http.Request, n int) error {
+	return List(w, r, func(emit Emit) error {
+		return items(n, func(it item) error { return emit(it) })
+	}, func() Field {
+		return Field{Key: "meta", Value: map[string]int{"totalAmount": n}}
+	})
+}
+
+func TestList(tt *testing.T) {
+	tests := []struct {
+		name     string
+		n        int
+		encoding string
+	}{
+		{name: "empty", n: 0},
+		{name: "plain", n: 3},
+		{name: "gzip", n: 3, encoding: "gzip, deflate"},
+		{name: "gzip refused", n: 3, encoding: "gzip;q=0"},
+	}
+	for _, tc := range tests {
+		tt.Run(tc.name, func(tt *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("Accept-Encoding", tc.encoding)
+			w := httptest.NewRecorder()
+
+			if err := streamItems(w, r, tc.n); err != nil {
+				tt.Fatal(err)
+			}
+
+			var body io.Reader = w.Body
+			if w.Header().Get("Content-Encoding") == "gzip" {
+				gz, err := gzip.NewReader(w.Body)
+				if err != nil {
+					tt.Fatal(err)
+				}
+				body = gz
+			} else if tc.encoding == "gzip, deflate" {
+				tt.Fatal("response is not compressed")
+			}
+
+			var got struct {
+				Data []item         `json:"data"`
+				Meta map[string]int `json:"meta"`
+			}
+			if err := json.NewDecoder(body).Decode(&got); err != nil {
+				tt.Fatal(err)
+			}
+			if got.Data == nil || len(got.Data) != tc.n || got.Meta["totalAmount"] != tc.n {
+				tt.Errorf("got %+v, want %d items", got, tc.n)
+			}
+		})
+	}
+}
+
+func TestListErrorBeforeOutput(tt *testing.T) {
+	want := errors.New("storage is down")
+	w := httptest.NewRecorder()
+
+	err := List(w, httptest.NewRequest(http.MethodGet, "/", nil), func(emit Emit) error { return want })
+	if !errors.Is(err, want) {
+		tt.Fatalf("err = %v, want %v", err, want)
+	}
+	if w.Body.Len() != 0 {
+		tt.Errorf("body = %q, nothing must be written", w.Body)
+	}
+}
+
+// The benchmarks compare the allocated bytes of a 10000 items list:
+//
+//	go test -bench . -benchmem ./internal/lib/api/stream
+const benchItems = 10000
+
+func BenchmarkRender(b *testing.B) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		var data []item
+		items(benchItems, func(it item) error {
+			data = append(data, it)
+			return nil
+		})
+		render.JSON(discard{}, r, map[string]any{"data": data, "meta": map[string]int{"totalAmount": benchItems}})
+	}
+}
+
+func BenchmarkList(b *testing.B) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		streamItems(discard{}, r, benchItems)
+	}
+}
+
+type discard struct{}
+
+func (discard) Header() http.Header         { return http.Header{} }
+func (discard) Write(p []byte) (int, error) { return len(p), nil }
+func (discard) WriteHeader(int)             {}
@@ -0,0 +1,295 @@
+// Package backup dumps the database into the blob store on a schedule or on demand,
+// keeps the newest backups and alerts when one fails.
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/sabbatD/srest-api/internal/lib/jobs"
+	"github.com/sabbatD/srest-api/internal/lib/logger/sl"
+	"github.com/sabbatD/srest-api/internal/lib/metrics"
+	"github.com/sabbatD/srest-api/internal/storage/blob"
+)
+
+// Backup statuses
+const (
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// JobAlert is the job notifying the alerters of a failed backup, see Manager.SetJobs
+const JobAlert = "backup.alert"
+
+// ErrRunning is returned by Trigger while a backup is in progress
+var ErrRunning = errors.New("backup already running")
+
+type Backup struct {
+	ID int `json:"id"`
+	// Key is the blob key of the dump
+	Key      string     `json:"key"`
+	Status   string     `json:"status"`
+	Size     int64      `json:"size"`
+	Error    string     `json:"error,omitempty"`
+	Started  time.Time  `json:"started"`
+	Finished *time.Time `json:"finished,omitempty"`
+}
+
+type ListResponse struct {
+	Data []Backup `json:"data"`
+}
+
+// Dumper writes a full backup of the database, e.g. PgDump.
+// Backends such as cloud snapshot APIs that do not produce a file are not supported yet.
+type Dumper interface {
+	Dump(ctx context.Context, w io.Writer) error
+}
+
+// Recorder keeps the backup records
+type Recorder interface {
+	StartBackup(ctx context.Context, key string) (Backup, error)
+	FinishBackup(ctx context.Context, id int, size int64, errMsg string) (Backup, error)
+	// Backups returns the newest backups first
+	Backups(ctx context.Context, limit int) ([]Backup, error)
+	// ExpiredBackups returns finished backups older than the keep newest succeeded ones
+	ExpiredBackups(ctx context.Context, keep int) ([]Backup, error)
+	DeleteBackup(ctx context.Context, id int) error
+}
+
+// Alerter is notified of failed backups, e.g. Webhook
+type Alerter interface {
+	Alert(ctx context.Context, b Backup) error
+}
+
+// Jobs runs the alerts in the background, see jobs.Worker
+type Jobs interface {
+	Enqueue(queue, kind string, payload any) error
+	Handle(kind string, h jobs.Handler)
+}
+
+type Config struct {
+	// Interval between scheduled backups, zero disables the schedule
+	Interval time.Duration `yaml:"interval" env:"BACKUP_INTERVAL" env-default:"0s"`
+	Command  string        `yaml:"command" env:"BACKUP_COMMAND" env-default:"pg_dump"`
+	Timeout  time.Duration `yaml:"timeout" env-default:"1h"`
+	// Keep is the number of succeeded backups kept, older backups are deleted
+	Keep int `yaml:"keep" env-default:"7"`
+	// AlertURL receives a JSON POST for every failed backup, failures are logged either way
+	AlertURL     string        `yaml:"alert_url" env:"BACKUP_ALERT_URL" redact:"true"`
+	AlertTimeout time.Duration `yaml:"alert_timeout" env-default:"5s"`
+}
+
+// Manager runs one backup at a time
+type Manager struct {
+	log    *slog.Logger
+	cfg    Config
+	dumper Dumper
+	store  blob.Store
+	rec    Recorder
+	alerts []Alerter
+	// jobs sends the alerts with retries when set
+	jobs    Jobs
+	running atomic.Bool
+}
+
+func New(log *slog.Logger, cfg Config, dumper Dumper, store blob.Store, rec Recorder, alerts ...Alerter) *Manager {
+	return &Manager{log: log, cfg: cfg, dumper: dumper, store: store, rec: rec, alerts: alerts}
+}
+
+// FromConfig returns a manager dumping the database at dsn with pg_dump
+func FromConfig(log *slog.Logger, cfg Config, dsn string, store blob.Store, rec Recorder) *Manager {
+	var alerts []Alerter
+	if cfg.AlertURL != "" {
+		alerts = append(alerts, NewWebhook(cfg.AlertURL, cfg.AlertTimeout))
+	}
+	return New(log, cfg, PgDump{Command: cfg.Command, DSN: dsn}, store, rec, alerts...)
+}
+
+// SetJobs sends the alerts of failed backups from the webhooks queue of q, retried while an alerter fails.
+// A retry notifies every alerter again.
+func (m *Manager) SetJobs(q Jobs) {
+	m.jobs = q
+	q.Handle(JobAlert, func(ctx context.Context, payload json.RawMessage) error {
+		var b Backup
+		if err := json.Unmarshal(payload, &b); err != nil {
+			return err
+		}
+		return m.alert(ctx, b)
+	})
+}
+
+// Trigger starts a backup in the background and returns its running record
+func (m *Manager) Trigger(ctx context.Context) (Backup, error) {
+	const op = "lib.backup.Trigger"
+
+	if !m.running.CompareAndSwap(false, true) {
+		return Backup{}, fmt.Errorf("%s: %w", op, ErrRunning)
+	}
+
+	key := "backups/" + time.Now().UTC().Format("20060102T150405Z") + ".dump"
+	b, err := m.rec.StartBackup(ctx, key)
+	if err != nil {
+		m.running.Store(false)
+		return Backup{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	// The backup outlives the request that triggered it.
+	go func() {
+		defer m.running.Store(false)
+
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), m.cfg.Timeout)
+		defer cancel()
+		m.run(ctx, b)
+	}()
+
+	return b, nil
+}
+
+// List returns the newest backups first
+func (m *Manager) List(ctx context.Context, limit int) (ListResponse, error) {
+	const op = "lib.backup.List"
+
+	backups, err := m.rec.Backups(ctx, limit)
+	if err != nil {
+		return ListResponse{}, fmt.Errorf("%s: %v", op, err)
+	}
+	if backups == nil {
+		backups = []Backup{}
+	}
+	return ListResponse{Data: backups}, nil
+}
+
+// Run triggers a backup every configured interval until ctx is done
+func (m *Manager) Run(ctx context.Context) {
+	const op = "lib.backup.Run"
+
+	if m.cfg.Interval <= 0 {
+		return
+	}
+	log := m.log.With(slog.String("op", op))
+
+	ticker := time.NewTicker(m.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := m.Trigger(ctx); err != nil {
+				log.Warn("scheduled backup not started", sl.Err(err))
+			}
+		}
+	}
+}
+
+func (m *Manager) run(ctx context.Context, b Backup) {
+	const op = "lib.backup.run"
+
+	log := m.log.With(slog.String("op", op), slog.Int("backup", b.ID))
+
+	var size countWriter
+	pr, pw := io.Pipe()
+	dumped := make(chan error, 1)
+	go func() {
+		err := m.dumper.Dump(ctx, io.MultiWriter(pw, &size))
+		pw.CloseWithError(err)
+		dumped <- err
+	}()
+	err := m.store.Put(ctx, b.Key, pr, -1, "application/octet-stream")
+	pr.CloseWithError(err)
+	// A failed dump is the more useful error, the store only saw a broken stream.
+	if derr := <-dumped; derr != nil {
+		err = derr
+	}
+
+	// Recording and alerting still happen when the dump ran into the timeout.
+	ctx = context.WithoutCancel(ctx)
+
+	errMsg := ""
+	if err != nil {
+		errMsg = err.Error()
+		if err := m.store.Delete(ctx, b.Key); err != nil {
+			log.Error("failed to delete partial backup", sl.Err(err))
+		}
+	}
+
+	if finished, ferr := m.rec.FinishBackup(ctx, b.ID, int64(size), errMsg); ferr == nil {
+		b = finished
+	} else {
+		log.Error("failed to record backup", sl.Err(ferr))
+		b.Status, b.Size, b.Error = StatusSucceeded, int64(size), errMsg
+		if err != nil {
+			b.Status = StatusFailed
+		}
+	}
+
+	metrics.Backups.Add(b.Status, 1)
+	if err != nil {
+		log.Error("backup failed", sl.Err(err))
+		metrics.JobFailures.Add("backup", 1)
+		switch {
+		case len(m.alerts) == 0:
+		case m.jobs != nil:
+			if err := m.jobs.Enqueue(jobs.QueueWebhooks, JobAlert, b); err != nil {
+				log.Error("failed to queue backup alert", sl.Err(err))
+			}
+		default:
+			if err := m.alert(ctx, b); err != nil {
+				log.Error("failed to send backup alert", sl.Err(err))
+			}
+		}
+		return
+	}
+	log.Info("backup finished", slog.String("key", b.Key), slog.Int64("size", int64(size)))
+
+	m.prune(ctx, log)
+}
+
+// alert notifies every alerter of the failed backup b and returns the first error
+func (m *Manager) alert(ctx context.Context, b Backup) error {
+	var first error
+	for _, a := range m.alerts {
+		if err := a.Alert(ctx, b); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// prune deletes the backups beyond the configured number to keep
+func (m *Manager) prune(ctx context.Context, log *slog.Logger) {
+	if m.cfg.Keep <= 0 {
+		return
+	}
+
+	expired, err := m.rec.ExpiredBackups(ctx, m.cfg.Keep)
+	if err != nil {
+		log.Error("failed to list expired backups", sl.Err(err))
+		return
+	}
+	for _, b := range expired {
+		if b.Status == StatusSucceeded {
+			if err := m.store.Delete(ctx, b.Key); err != nil {
+				log.Error("failed to delete expired backup", slog.String("key", b.Key), sl.Err(err))
+				continue
+			}
+		}
+		if err := m.rec.DeleteBackup(ctx, b.ID); err != nil {
+			log.Error("failed to delete expired backup record", slog.Int("id", b.ID), sl.Err(err))
+		}
+	}
+}
+
+type countWriter int64
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	*c += countWriter(len(p))
+	return len(p), nil
+}
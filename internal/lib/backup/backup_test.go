@@ -0,0 +1,146 @@
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sabbatD/srest-api/internal/storage/blob"
+)
+
+type fakeDumper struct {
+	data string
+	err  error
+}
+
+func (d fakeDumper) Dump(ctx context.Context, w io.Writer) error {
+	if _, err := io.WriteString(w, d.data); err != nil {
+		return err
+	}
+	return d.err
+}
+
+type memRecorder struct {
+	mu      sync.Mutex
+	backups []Backup
+	done    chan Backup
+}
+
+func (m *memRecorder) StartBackup(ctx context.Context, key string) (Backup, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b := Backup{ID: len(m.backups) + 1, Key: key, Status: StatusRunning, Started: time.Now()}
+	m.backups = append(m.backups, b)
+	return b, nil
+}
+
+func (m *memRecorder) FinishBackup(ctx context.Context, id int, size int64, errMsg string) (Backup, error) {
+	m.mu.Lock()
+	b := &m.backups[id-1]
+	now := time.Now()
+	b.Size, b.Error, b.Finished, b.Status = size, errMsg, &now, StatusSucceeded
+	if errMsg != "" {
+		b.Status = StatusFailed
+	}
+	finished := *b
+	m.mu.Unlock()
+	m.done <- finished
+	return finished, nil
+}
+
+func (m *memRecorder) Backups(ctx context.Context, limit int) ([]Backup, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Backup(nil), m.backups...), nil
+}
+
+func (m *memRecorder) ExpiredBackups(ctx context.Context, keep int) ([]Backup, error) {
+	return nil, nil
+}
+
+func (m *memRecorder) DeleteBackup(ctx context.Context, id int) error { return nil }
+
+func TestManager(tt *testing.T) {
+	ctx := context.Background()
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	store, err := blob.NewLocal(tt.TempDir())
+	if err != nil {
+		tt.Fatal(err)
+	}
+
+	alerts := make(chan alert, 1)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var a alert
+		json.NewDecoder(r.Body).Decode(&a)
+		alerts <- a
+	}))
+	defer hook.Close()
+
+	tt.Run("succeeded", func(tt *testing.T) {
+		rec := &memRecorder{done: make(chan Backup, 1)}
+		m := New(log, Config{Timeout: time.Minute}, fakeDumper{data: "dump"}, store, rec)
+
+		b, err := m.Trigger(ctx)
+		if err != nil || b.Status != StatusRunning {
+			tt.Fatalf("Trigger() = %+v, %v", b, err)
+		}
+		done := <-rec.done
+		if done.Status != StatusSucceeded || done.Size != 4 {
+			tt.Errorf("finished backup = %+v", done)
+		}
+
+		info, err := store.Stat(ctx, b.Key)
+		if err != nil || info.Size != 4 {
+			tt.Errorf("stored dump = %+v, %v", info, err)
+		}
+	})
+
+	tt.Run("failed", func(tt *testing.T) {
+		rec := &memRecorder{done: make(chan Backup, 1)}
+		m := New(log, Config{Timeout: time.Minute}, fakeDumper{data: "part", err: errors.New("connection refused")},
+			store, rec, NewWebhook(hook.URL, time.Second))
+
+		b, err := m.Trigger(ctx)
+		if err != nil {
+			tt.Fatal(err)
+		}
+		done := <-rec.done
+		if done.Status != StatusFailed || done.Error != "connection refused" {
+			tt.Errorf("finished backup = %+v", done)
+		}
+		if _, err := store.Stat(ctx, b.Key); !errors.Is(err, blob.ErrNotFound) {
+			tt.Errorf("partial dump kept: %v", err)
+		}
+
+		select {
+		case a := <-alerts:
+			if a.Event != "backup.failed" || a.Backup.ID != b.ID {
+				tt.Errorf("alert = %+v", a)
+			}
+		case <-time.After(5 * time.Second):
+			tt.Error("no alert sent")
+		}
+	})
+}
+
+func TestTriggerRunning(tt *testing.T) {
+	rec := &memRecorder{done: make(chan Backup, 1)}
+	store, err := blob.NewLocal(tt.TempDir())
+	if err != nil {
+		tt.Fatal(err)
+	}
+	m := New(slog.New(slog.NewTextHandler(io.Discard, nil)), Config{Timeout: time.Minute}, fakeDumper{}, store, rec)
+
+	m.running.Store(true)
+	if _, err := m.Trigger(context.Background()); !errors.Is(err, ErrRunning) {
+		tt.Errorf("Trigger() while running: err = %v, want ErrRunning", err)
+	}
+}
@@ -0,0 +1,35 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// PgDump dumps the database with pg_dump in its custom format, restore it with pg_restore
+type PgDump struct {
+	// Command is the pg_dump executable, looked up in PATH unless it is a path
+	Command string
+	// DSN is a libpq connection string, key=value pairs or a postgres:// URL
+	DSN string
+}
+
+func (p PgDump) Dump(ctx context.Context, w io.Writer) error {
+	const op = "lib.backup.PgDump"
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.Command, "--format=custom", "--no-password", "--dbname", p.DSN)
+	cmd.Stdout = w
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%s: %v: %s", op, err, msg)
+		}
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Webhook posts failed backups as JSON to an URL, e.g. a chat or incident tool integration
+type Webhook struct {
+	url    string
+	client *http.Client
+}
+
+func NewWebhook(url string, timeout time.Duration) *Webhook {
+	return &Webhook{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+type alert struct {
+	Event  string `json:"event"`
+	Backup Backup `json:"backup"`
+}
+
+func (wh *Webhook) Alert(ctx context.Context, b Backup) error {
+	const op = "lib.backup.Webhook.Alert"
+
+	body, err := json.Marshal(alert{Event: "backup.failed", Backup: b})
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, wh.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := wh.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s: unexpected status %s", op, resp.Status)
+	}
+
+	return nil
+}
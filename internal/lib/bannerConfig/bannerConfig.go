@@ -0,0 +1,53 @@
+package bannerConfig
+
+import "time"
+
+// Banner severities, clients style banners by them
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// Banner audiences: everyone including signed out clients, signed in users, guests or admins
+const (
+	AudienceAll    = "all"
+	AudienceUsers  = "users"
+	AudienceGuests = "guests"
+	AudienceAdmins = "admins"
+)
+
+// BannerRequest creates or replaces a banner. Starts defaults to now, a banner without Ends shows until it is deleted.
+type BannerRequest struct {
+	Message  string     `json:"message" validate:"required,max=500"`
+	Severity string     `json:"severity" validate:"required,oneof=info warning critical"`
+	Audience string     `json:"audience,omitempty" validate:"omitempty,oneof=all users guests admins"`
+	Starts   *time.Time `json:"starts,omitempty"`
+	Ends     *time.Time `json:"ends,omitempty"`
+}
+
+type Banner struct {
+	ID       int        `json:"-"`
+	PublicID string     `json:"id"`
+	Message  string     `json:"message"`
+	Severity string     `json:"severity"`
+	Audience string     `json:"audience"`
+	Starts   time.Time  `json:"starts"`
+	Ends     *time.Time `json:"ends,omitempty"`
+	Created  time.Time  `json:"created"`
+}
+
+// Audiences returns the audiences whose banners a client sees: signed out clients only see banners for all,
+// guests also the ones for guests, users the ones for users and admins the ones for users and admins
+func Audiences(signedIn, guest, admin bool) []string {
+	switch {
+	case !signedIn:
+		return []string{AudienceAll}
+	case guest:
+		return []string{AudienceAll, AudienceGuests}
+	case admin:
+		return []string{AudienceAll, AudienceUsers, AudienceAdmins}
+	default:
+		return []string{AudienceAll, AudienceUsers}
+	}
+}
@@ -0,0 +1,23 @@
+package bannerConfig
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestAudiences(t *testing.T) {
+	for _, tc := range []struct {
+		name                   string
+		signedIn, guest, admin bool
+		want                   []string
+	}{
+		{"signed out", false, false, false, []string{AudienceAll}},
+		{"guest", true, true, false, []string{AudienceAll, AudienceGuests}},
+		{"user", true, false, false, []string{AudienceAll, AudienceUsers}},
+		{"admin", true, false, true, []string{AudienceAll, AudienceUsers, AudienceAdmins}},
+	} {
+		if got := Audiences(tc.signedIn, tc.guest, tc.admin); !slices.Equal(got, tc.want) {
+			t.Errorf("%s: audiences = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+// Package cache keeps computed responses in memory for a while.
+// Keys of per-user data start with UserKey, so everything cached for a user can be invalidated at once.
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sabbatD/srest-api/internal/lib/clock"
+)
+
+// ErrNoCache is returned for an unknown cache name
+var ErrNoCache = errors.New("no such cache")
+
+// UserKey returns the key of a user's cached value, parts are joined with ':', e.g. "user:42:2024"
+func UserKey(userID int, parts ...any) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "user:%d", userID)
+	for _, p := range parts {
+		fmt.Fprintf(&b, ":%v", p)
+	}
+	return b.String()
+}
+
+// UserPattern matches every key of the user, see UserKey
+func UserPattern(userID int) string {
+	return fmt.Sprintf("user:%d:*", userID)
+}
+
+// Stats of a cache since the start
+type Stats struct {
+	Name          string  `json:"name"`
+	Entries       int     `json:"entries"`
+	MaxEntries    int     `json:"maxEntries"`
+	TTLSeconds    float64 `json:"ttlSeconds"`
+	Hits          int64   `json:"hits"`
+	Misses        int64   `json:"misses"`
+	Invalidations int64   `json:"invalidations"`
+	Evictions     int64   `json:"evictions"`
+}
+
+type entry struct {
+	value   any
+	expires time.Time
+}
+
+// Cache holds values for ttl, at most maxEntries of them: when it is full expired entries are dropped,
+// then all of them. Expiry follows the process clock, see clock.Set.
+type Cache struct {
+	name       string
+	ttl        time.Duration
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[string]entry
+	stats   Stats
+}
+
+func New(name string, ttl time.Duration, maxEntries int) *Cache {
+	return &Cache{name: name, ttl: ttl, maxEntries: maxEntries, entries: make(map[string]entry)}
+}
+
+func (c *Cache) Name() string {
+	return c.name
+}
+
+// Get returns the value stored under key unless it expired
+func (c *Cache) Get(key string) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok || !clock.Now().Before(e.expires) {
+		c.stats.Misses++
+		return nil, false
+	}
+	c.stats.Hits++
+	return e.value, true
+}
+
+// Set stores value under key for the cache's ttl, nothing is stored with a zero ttl
+func (c *Cache) Set(key string, value any) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := clock.Now()
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.maxEntries {
+		for k, e := range c.entries {
+			if !now.Before(e.expires) {
+				delete(c.entries, k)
+				c.stats.Evictions++
+			}
+		}
+		if len(c.entries) >= c.maxEntries {
+			c.stats.Evictions += int64(len(c.entries))
+			clear(c.entries)
+		}
+	}
+	c.entries[key] = entry{value: value, expires: now.Add(c.ttl)}
+}
+
+// Invalidate deletes the entries whose key matches the pattern, see path.Match. Returns how many.
+func (c *Cache) Invalidate(pattern string) (int, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return 0, fmt.Errorf("lib.cache.Invalidate: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := 0
+	for k := range c.entries {
+		if ok, _ := path.Match(pattern, k); ok {
+			delete(c.entries, k)
+			n++
+		}
+	}
+	c.stats.Invalidations += int64(n)
+	return n, nil
+}
+
+func (c *Cache) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s := c.stats
+	s.Name = c.name
+	s.Entries = len(c.entries)
+	s.MaxEntries = c.maxEntries
+	s.TTLSeconds = c.ttl.Seconds()
+	return s
+}
+
+// Group is the caches of the service, see the admin cache endpoints
+type Group []*Cache
+
+// Invalidate deletes the entries matching the pattern in the named cache, or in all of them for an empty name.
+// Returns how many entries were deleted per cache, ErrNoCache for an unknown name.
+func (g Group) Invalidate(name, pattern string) (map[string]int, error) {
+	const op = "lib.cache.Group.Invalidate"
+
+	deleted := make(map[string]int)
+	for _, c := range g {
+		if name != "" && c.name != name {
+			continue
+		}
+		n, err := c.Invalidate(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		deleted[c.name] = n
+	}
+	if len(deleted) == 0 {
+		return nil, fmt.Errorf("%s: %q: %w", op, name, ErrNoCache)
+	}
+	return deleted, nil
+}
+
+func (g Group) Stats() []Stats {
+	stats := make([]Stats, 0, len(g))
+	for _, c := range g {
+		stats = append(stats, c.Stats())
+	}
+	return stats
+}
+
+// Invalidation selects the entries to invalidate: those matching Pattern, or all of the user's for UserID,
+// in the named cache or in all of them
+type Invalidation struct {
+	Cache   string `json:"cache,omitempty"`
+	Pattern string `json:"pattern,omitempty" validate:"required_without=UserID,excluded_with=UserID,max=200"`
+	// UserID is the public id of the user
+	UserID string `json:"userId,omitempty" validate:"omitempty,uuid"`
+}
+
+// Invalidated holds how many entries were deleted per cache
+type Invalidated struct {
+	Deleted map[string]int `json:"deleted"`
+}
@@ -0,0 +1,72 @@
+package cache
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sabbatD/srest-api/internal/lib/clock"
+)
+
+func TestCache(tt *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 10, 1, 12, 0, 0, 0, time.UTC))
+	tt.Cleanup(clock.Set(fake))
+
+	c := New("test", time.Minute, 3)
+	c.Set(UserKey(1, "a"), 1)
+	c.Set(UserKey(1, "b"), 2)
+	c.Set(UserKey(2, "a"), 3)
+
+	if v, ok := c.Get(UserKey(1, "a")); !ok || v != 1 {
+		tt.Errorf("Get() = %v, %v", v, ok)
+	}
+	if n, err := c.Invalidate(UserPattern(1)); err != nil || n != 2 {
+		tt.Errorf("Invalidate(user 1) = %d, %v", n, err)
+	}
+	if _, ok := c.Get(UserKey(1, "b")); ok {
+		tt.Error("invalidated entry served")
+	}
+	if _, ok := c.Get(UserKey(2, "a")); !ok {
+		tt.Error("another user's entry invalidated")
+	}
+
+	fake.Advance(time.Minute)
+	if _, ok := c.Get(UserKey(2, "a")); ok {
+		tt.Error("expired entry served")
+	}
+
+	// A full cache drops expired entries first.
+	c.Set("x", 1)
+	c.Set("y", 2)
+	c.Set("z", 3)
+	if s := c.Stats(); s.Entries != 3 || s.Hits != 2 || s.Misses != 2 || s.Invalidations != 2 || s.Evictions != 1 {
+		tt.Errorf("stats = %+v", s)
+	}
+
+	if _, err := c.Invalidate("["); err == nil {
+		tt.Error("malformed pattern accepted")
+	}
+}
+
+func TestGroup(tt *testing.T) {
+	a, b := New("a", time.Minute, 10), New("b", time.Minute, 10)
+	a.Set(UserKey(1, "x"), 1)
+	b.Set(UserKey(1, "y"), 1)
+	b.Set(UserKey(2, "y"), 1)
+	g := Group{a, b}
+
+	deleted, err := g.Invalidate("b", "*")
+	if err != nil || len(deleted) != 1 || deleted["b"] != 2 {
+		tt.Errorf("Invalidate(b) = %v, %v", deleted, err)
+	}
+	deleted, err = g.Invalidate("", UserPattern(1))
+	if err != nil || deleted["a"] != 1 || deleted["b"] != 0 {
+		tt.Errorf("Invalidate(all) = %v, %v", deleted, err)
+	}
+	if _, err := g.Invalidate("c", "*"); !errors.Is(err, ErrNoCache) {
+		tt.Errorf("unknown cache: %v", err)
+	}
+	if len(g.Stats()) != 2 {
+		tt.Error("missing stats")
+	}
+}
@@ -0,0 +1,100 @@
+package cache
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Config bounds the caches of users' own data, a zero TTL disables a cache
+type Config struct {
+	ProfileTTL time.Duration `yaml:"profile_ttl" env-default:"30s"`
+	TodosTTL   time.Duration `yaml:"todos_ttl" env-default:"30s"`
+	MaxEntries int           `yaml:"max_entries" env-default:"10000"`
+	// MaxListSize is the largest todo list cached, longer ones are always read from the database
+	MaxListSize int `yaml:"max_list_size" env-default:"500"`
+}
+
+// Versions holds a version per user that changes with each of their writes.
+// Keys of a user's cached reads include it, so once a write answered, the user's reads miss
+// the entries stored before it (read-after-write). Versions are kept in process.
+type Versions struct {
+	mu       sync.Mutex
+	versions map[int]uint64
+}
+
+func NewVersions() *Versions {
+	return &Versions{versions: make(map[int]uint64)}
+}
+
+// Version returns the user's current version
+func (v *Versions) Version(userID int) uint64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	return v.versions[userID]
+}
+
+// Bump changes the user's version
+func (v *Versions) Bump(userID int) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.versions[userID]++
+}
+
+// Middleware bumps the version of the user of each write request, user returns who that is.
+// The handler has stored the write by the time it answers, so the version changes right before
+// the response is written: a read sent after the response never gets an entry from before the write.
+func (v *Versions) Middleware(user func(r *http.Request) (int, bool)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id, ok := user(r)
+			if !ok || r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			bw := &bumpWriter{ResponseWriter: w, bump: func() { v.Bump(id) }}
+			next.ServeHTTP(bw, r)
+			// Handlers that write nothing
+			bw.once.Do(bw.bump)
+		})
+	}
+}
+
+// bumpWriter calls bump once before anything is written
+type bumpWriter struct {
+	http.ResponseWriter
+	once sync.Once
+	bump func()
+}
+
+func (w *bumpWriter) WriteHeader(code int) {
+	w.once.Do(w.bump)
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *bumpWriter) Write(b []byte) (int, error) {
+	w.once.Do(w.bump)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *bumpWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// UserCache caches reads of users' own data, its keys include the user's version, see Versions
+type UserCache struct {
+	*Cache
+	versions *Versions
+}
+
+func NewUserCache(c *Cache, versions *Versions) *UserCache {
+	return &UserCache{Cache: c, versions: versions}
+}
+
+// Key returns the key of the user's cached value at their current version
+func (c *UserCache) Key(userID int, parts ...any) string {
+	return UserKey(userID, append([]any{c.versions.Version(userID)}, parts...)...)
+}
@@ -0,0 +1,54 @@
+package cache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestVersions(tt *testing.T) {
+	v := NewVersions()
+	c := NewUserCache(New("test", time.Minute, 10), v)
+	user := func(r *http.Request) (int, bool) { return 1, r.Header.Get("X-User") != "" }
+
+	key := c.Key(1, "list")
+	c.Set(key, "old")
+
+	// The version changes before the write answers, the cached entry is already stale then.
+	var stale bool
+	h := v.Middleware(user)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		_, stale = c.Get(c.Key(1, "list"))
+	}))
+
+	for _, method := range []string{http.MethodGet, http.MethodHead, http.MethodOptions} {
+		req := httptest.NewRequest(method, "/", nil)
+		req.Header.Set("X-User", "1")
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	if c.Key(1, "list") != key {
+		tt.Fatal("reads changed the version")
+	}
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", nil))
+	if c.Key(1, "list") != key {
+		tt.Fatal("a request without a user changed the version")
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("X-User", "1")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if stale {
+		tt.Error("entry from before the write served while answering it")
+	}
+	if c.Key(1, "list") == key || c.Key(2, "list") != UserKey(2, 0, "list") {
+		tt.Errorf("keys after write: %q, %q", c.Key(1, "list"), c.Key(2, "list"))
+	}
+
+	// Handlers that write nothing still change it.
+	v.Middleware(user)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})).ServeHTTP(httptest.NewRecorder(), req)
+	if v.Version(1) != 2 {
+		tt.Errorf("version = %d, want 2", v.Version(1))
+	}
+}
@@ -0,0 +1,194 @@
+// Package clients counts requests by client and route, so the clients still calling a route are known before it is removed.
+// Clients identify themselves with the X-Client header, e.g. "ios/2.3.1", else the user agent is used.
+package clients
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sabbatD/srest-api/internal/lib/clock"
+	"github.com/sabbatD/srest-api/internal/lib/logger/sl"
+	"github.com/sabbatD/srest-api/internal/lib/metrics"
+)
+
+// Header identifies the client app and its version
+const Header = "X-Client"
+
+const (
+	// Unknown is the client of requests without X-Client and User-Agent
+	Unknown = "unknown"
+	// Other is the client of requests counted once MaxEntries is reached until the next flush
+	Other = "other"
+)
+
+// maxClient bounds the length of a client identifier
+const maxClient = 100
+
+// Config sets how often the counts are written to the store, a zero interval disables counting
+type Config struct {
+	Interval time.Duration `yaml:"interval" env-default:"1m"`
+	// MaxEntries bounds the client and route pairs kept between flushes
+	MaxEntries int `yaml:"max_entries" env-default:"10000"`
+}
+
+// Usage is the number of requests of a client to a route on a day, LastSeen is the time of the latest one
+type Usage struct {
+	Day      time.Time `json:"-"`
+	Client   string    `json:"client"`
+	Route    string    `json:"route"`
+	Requests int64     `json:"requests"`
+	LastSeen time.Time `json:"lastSeen"`
+}
+
+// Report holds the usage by client and route since From, the latest seen first
+type Report struct {
+	From    time.Time `json:"from"`
+	Clients []Usage   `json:"clients"`
+}
+
+type Store interface {
+	// RecordClientUsage adds the counts to the stored ones of the same day, client and route
+	RecordClientUsage(ctx context.Context, usage []Usage) error
+}
+
+type key struct {
+	day    time.Time
+	client string
+	route  string
+}
+
+// Recorder counts requests in process and writes the counts to the store every interval
+type Recorder struct {
+	log    *slog.Logger
+	store  Store
+	cfg    Config
+	mu     sync.Mutex
+	counts map[key]*Usage
+}
+
+func New(log *slog.Logger, store Store, cfg Config) *Recorder {
+	return &Recorder{log: log, store: store, cfg: cfg, counts: make(map[key]*Usage)}
+}
+
+// Identify returns the X-Client header of r, else its user agent, else Unknown
+func Identify(r *http.Request) string {
+	client := strings.TrimSpace(r.Header.Get(Header))
+	if client == "" {
+		client = strings.TrimSpace(r.UserAgent())
+	}
+	if client == "" {
+		return Unknown
+	}
+	if runes := []rune(client); len(runes) > maxClient {
+		client = string(runes[:maxClient])
+	}
+	return client
+}
+
+// Middleware counts every request matching a route by client, method and route pattern.
+// It must wrap the router so the pattern is complete when the request is done.
+func (rec *Recorder) Middleware(next http.Handler) http.Handler {
+	if rec.cfg.Interval <= 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r)
+
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			rec.Record(Identify(r), r.Method+" "+rctx.RoutePattern(), clock.Now())
+		}
+	})
+}
+
+// Record counts a request of client to route at the time at. Once MaxEntries pairs are kept,
+// requests of new pairs are counted as the Other client.
+func (rec *Recorder) Record(client, route string, at time.Time) {
+	at = at.UTC()
+	k := key{day: at.Truncate(24 * time.Hour), client: client, route: route}
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	u, ok := rec.counts[k]
+	if !ok && len(rec.counts) >= rec.cfg.MaxEntries {
+		k.client = Other
+		u, ok = rec.counts[k]
+	}
+	if !ok {
+		u = &Usage{Day: k.day, Client: k.client, Route: route}
+		rec.counts[k] = u
+	}
+	u.Requests++
+	if at.After(u.LastSeen) {
+		u.LastSeen = at
+	}
+}
+
+// Flush writes the counts kept since the last flush to the store, they are kept for the next one when it fails
+func (rec *Recorder) Flush(ctx context.Context) error {
+	rec.mu.Lock()
+	counts := rec.counts
+	rec.counts = make(map[key]*Usage)
+	rec.mu.Unlock()
+
+	if len(counts) == 0 {
+		return nil
+	}
+	usage := make([]Usage, 0, len(counts))
+	for _, u := range counts {
+		usage = append(usage, *u)
+	}
+
+	if err := rec.store.RecordClientUsage(ctx, usage); err != nil {
+		rec.mu.Lock()
+		for _, u := range usage {
+			rec.add(u)
+		}
+		rec.mu.Unlock()
+		return err
+	}
+
+	return nil
+}
+
+// add merges u into the counts, rec.mu must be held
+func (rec *Recorder) add(u Usage) {
+	k := key{day: u.Day, client: u.Client, route: u.Route}
+	if kept, ok := rec.counts[k]; ok {
+		kept.Requests += u.Requests
+		if u.LastSeen.After(kept.LastSeen) {
+			kept.LastSeen = u.LastSeen
+		}
+		return
+	}
+	rec.counts[k] = &u
+}
+
+// Run flushes every configured interval until ctx is done
+func (rec *Recorder) Run(ctx context.Context) {
+	const op = "lib.clients.Run"
+
+	if rec.cfg.Interval <= 0 {
+		return
+	}
+	log := rec.log.With(slog.String("op", op))
+
+	ticker := time.NewTicker(rec.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := rec.Flush(ctx); err != nil {
+				log.Error("client usage flush failed", sl.Err(err))
+				metrics.JobFailures.Add("client_usage", 1)
+			}
+		}
+	}
+}
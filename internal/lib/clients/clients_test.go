@@ -0,0 +1,96 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type memStore struct {
+	err   error
+	usage []Usage
+}
+
+func (s *memStore) RecordClientUsage(ctx context.Context, usage []Usage) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.usage = append(s.usage, usage...)
+	return nil
+}
+
+func TestIdentify(t *testing.T) {
+	tests := []struct {
+		name, client, agent, want string
+	}{
+		{"header", "ios/2.3.1", "Mozilla/5.0", "ios/2.3.1"},
+		{"user agent", "", "Mozilla/5.0", "Mozilla/5.0"},
+		{"none", " ", "", Unknown},
+		{"truncated", strings.Repeat("a", 150), "", strings.Repeat("a", maxClient)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set(Header, tt.client)
+			r.Header.Set("User-Agent", tt.agent)
+			if got := Identify(r); got != tt.want {
+				t.Errorf("Identify() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	store := &memStore{}
+	rec := New(slog.Default(), store, Config{Interval: time.Minute, MaxEntries: 2})
+
+	router := chi.NewRouter()
+	router.Use(rec.Middleware)
+	router.Get("/todos/{id}", func(w http.ResponseWriter, r *http.Request) {})
+
+	for _, client := range []string{"ios/2.3.1", "ios/2.3.1", "android/1.0", "web/5", "web/6"} {
+		r := httptest.NewRequest(http.MethodGet, "/todos/1", nil)
+		r.Header.Set(Header, client)
+		router.ServeHTTP(httptest.NewRecorder(), r)
+	}
+	// Unmatched requests are not counted
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/nowhere", nil))
+
+	store.err = errors.New("db is down")
+	if err := rec.Flush(context.Background()); err == nil {
+		t.Fatal("Flush() = nil, want the store error")
+	}
+	store.err = nil
+	if err := rec.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	got := map[string]int64{}
+	for _, u := range store.usage {
+		if u.Route != "GET /todos/{id}" {
+			t.Errorf("route = %q", u.Route)
+		}
+		got[u.Client] += u.Requests
+	}
+	// The counts kept after the failed flush are written by the next one
+	want := map[string]int64{"ios/2.3.1": 2, "android/1.0": 1, Other: 2}
+	if len(got) != len(want) {
+		t.Fatalf("usage = %v, want %v", got, want)
+	}
+	for client, n := range want {
+		if got[client] != n {
+			t.Errorf("%s: requests = %d, want %d", client, got[client], n)
+		}
+	}
+
+	if err := rec.Flush(context.Background()); err != nil || len(store.usage) != 3 {
+		t.Errorf("flushed again: %d entries, %v", len(store.usage), err)
+	}
+}
@@ -0,0 +1,41 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSet(t *testing.T) {
+	at := time.Date(2024, 10, 1, 12, 0, 0, 0, time.UTC)
+	fake := NewFake(at)
+
+	restore := Set(fake)
+	if got := Now(); !got.Equal(at) {
+		t.Errorf("Now() = %v, want %v", got, at)
+	}
+	fake.Advance(time.Hour)
+	if got := Now(); !got.Equal(at.Add(time.Hour)) {
+		t.Errorf("Now() after Advance = %v", got)
+	}
+
+	restore()
+	if got := Now(); time.Since(got) > time.Minute {
+		t.Errorf("Now() after restore = %v, want the wall clock", got)
+	}
+}
+
+func TestFromConfig(t *testing.T) {
+	c, err := FromConfig(Config{Frozen: "2024-10-01T12:00:00Z"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Now(); !got.Equal(time.Date(2024, 10, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("frozen Now() = %v", got)
+	}
+	if _, err := FromConfig(Config{Frozen: "yesterday"}); err == nil {
+		t.Error("FromConfig accepted an invalid time")
+	}
+	if c, _ := FromConfig(Config{}); c != (Real{}) {
+		t.Errorf("FromConfig() = %T, want the wall clock", c)
+	}
+}
@@ -0,0 +1,191 @@
+// Package expiry enforces the password expiry policy on a schedule: users are emailed before
+// their password expires and must change it once it has.
+// The policy comes from the configuration unless an admin set one in the settings.
+package expiry
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/sabbatD/srest-api/internal/lib/clock"
+	"github.com/sabbatD/srest-api/internal/lib/logger/sl"
+	"github.com/sabbatD/srest-api/internal/lib/mail"
+	"github.com/sabbatD/srest-api/internal/lib/metrics"
+)
+
+// Policy holds how many days a password is valid, zero disables expiry
+type Policy struct {
+	Days int `json:"days" yaml:"days" env-default:"0" validate:"min=0,max=3650"`
+	// WarnDays is how long before the expiry users are warned, zero disables the warning
+	WarnDays int `json:"warnDays" yaml:"warn_days" env-default:"14" validate:"min=0,max=365"`
+}
+
+// Expires returns when a password changed at the given time expires, false when passwords do not expire
+func (p Policy) Expires(changed time.Time) (time.Time, bool) {
+	if p.Days <= 0 {
+		return time.Time{}, false
+	}
+	return changed.AddDate(0, 0, p.Days), true
+}
+
+// Warn reports whether a password expiring at the given time is due to be warned about at now
+func (p Policy) Warn(expires, now time.Time) bool {
+	return p.WarnDays > 0 && now.AddDate(0, 0, p.WarnDays).After(expires)
+}
+
+// Config is the default policy and the interval of the expiry job, a zero interval disables the job
+type Config struct {
+	Interval time.Duration `yaml:"interval" env-default:"1h"`
+	Policy   `yaml:",inline"`
+}
+
+// Warning is a user to warn about their password expiring
+type Warning struct {
+	UserID   int
+	Username string
+	Email    string
+	// Locale is the locale the warning is rendered in
+	Locale  string
+	Changed time.Time
+}
+
+// Result holds the number of expired passwords and of warned users
+type Result struct {
+	Expired int64 `json:"expired"`
+	Warned  int   `json:"warned"`
+}
+
+type Store interface {
+	// PasswordExpiry returns the policy set by an admin, false when there is none
+	PasswordExpiry(ctx context.Context) (Policy, bool, error)
+	SetPasswordExpiry(ctx context.Context, actor int, p Policy) error
+	// ExpirePasswords makes users with a password changed before the given time change it
+	ExpirePasswords(ctx context.Context, before time.Time) (int64, error)
+	// PasswordWarnings returns the users not yet warned with a password changed before the given time
+	PasswordWarnings(ctx context.Context, before time.Time) ([]Warning, error)
+	MarkPasswordWarned(ctx context.Context, id int) error
+}
+
+// Mailer sends the warnings, see mail.Templates
+type Mailer interface {
+	Enabled() bool
+	Send(ctx context.Context, to []string, name, locale string, vars map[string]string) error
+}
+
+type Runner struct {
+	log    *slog.Logger
+	store  Store
+	mailer Mailer
+	cfg    Config
+}
+
+func New(log *slog.Logger, store Store, mailer Mailer, cfg Config) *Runner {
+	return &Runner{log: log, store: store, mailer: mailer, cfg: cfg}
+}
+
+// Policy returns the policy in effect: the admin set one, or the configured default
+func (r *Runner) Policy(ctx context.Context) (Policy, error) {
+	const op = "lib.expiry.Policy"
+
+	p, ok, err := r.store.PasswordExpiry(ctx)
+	if err != nil {
+		return Policy{}, fmt.Errorf("%s: %v", op, err)
+	}
+	if !ok {
+		return r.cfg.Policy, nil
+	}
+	return p, nil
+}
+
+// SetPolicy stores the policy set by actor, it applies from the next run
+func (r *Runner) SetPolicy(ctx context.Context, actor int, p Policy) error {
+	const op = "lib.expiry.SetPolicy"
+
+	if err := r.store.SetPasswordExpiry(ctx, actor, p); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	return nil
+}
+
+// RunOnce flags the users whose password expired and emails the ones whose password expires soon.
+// Users are only marked warned once the email is sent, without email nobody is warned but by the profile.
+func (r *Runner) RunOnce(ctx context.Context) (Result, error) {
+	const op = "lib.expiry.RunOnce"
+
+	var result Result
+	p, err := r.Policy(ctx)
+	if err != nil {
+		return result, fmt.Errorf("%s: %v", op, err)
+	}
+	if p.Days <= 0 {
+		return result, nil
+	}
+
+	now := clock.Now()
+	result.Expired, err = r.store.ExpirePasswords(ctx, now.AddDate(0, 0, -p.Days))
+	if err != nil {
+		return result, fmt.Errorf("%s: %v", op, err)
+	}
+
+	if p.WarnDays <= 0 || r.mailer == nil || !r.mailer.Enabled() {
+		return result, nil
+	}
+	warnings, err := r.store.PasswordWarnings(ctx, now.AddDate(0, 0, p.WarnDays-p.Days))
+	if err != nil {
+		return result, fmt.Errorf("%s: %v", op, err)
+	}
+
+	var first error
+	for _, w := range warnings {
+		if w.Email == "" {
+			continue
+		}
+		expires, _ := p.Expires(w.Changed)
+		vars := map[string]string{"Username": w.Username, "Expires": expires.UTC().Format(time.RFC1123)}
+		if err := r.mailer.Send(ctx, []string{w.Email}, mail.TemplatePasswordExpiry, w.Locale, vars); err != nil {
+			if first == nil {
+				first = fmt.Errorf("%s: %v", op, err)
+			}
+			continue
+		}
+		if err := r.store.MarkPasswordWarned(ctx, w.UserID); err != nil {
+			if first == nil {
+				first = fmt.Errorf("%s: %v", op, err)
+			}
+			continue
+		}
+		result.Warned++
+	}
+
+	return result, first
+}
+
+// Run enforces the policy every configured interval until ctx is done
+func (r *Runner) Run(ctx context.Context) {
+	const op = "lib.expiry.Run"
+
+	if r.cfg.Interval <= 0 {
+		return
+	}
+	log := r.log.With(slog.String("op", op))
+
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			result, err := r.RunOnce(ctx)
+			if err != nil {
+				log.Error("password expiry failed", sl.Err(err))
+				metrics.JobFailures.Add("password_expiry", 1)
+			}
+			if result.Expired > 0 || result.Warned > 0 {
+				log.Info("password expiry finished", slog.Int64("expired", result.Expired), slog.Int("warned", result.Warned))
+			}
+		}
+	}
+}
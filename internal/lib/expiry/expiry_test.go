@@ -0,0 +1,124 @@
+package expiry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/sabbatD/srest-api/internal/lib/clock"
+)
+
+type fakeStore struct {
+	policy       *Policy
+	expireBefore time.Time
+	warnBefore   time.Time
+	warnings     []Warning
+	warned       []int
+}
+
+func (f *fakeStore) PasswordExpiry(ctx context.Context) (Policy, bool, error) {
+	if f.policy == nil {
+		return Policy{}, false, nil
+	}
+	return *f.policy, true, nil
+}
+
+func (f *fakeStore) SetPasswordExpiry(ctx context.Context, actor int, p Policy) error {
+	f.policy = &p
+	return nil
+}
+
+func (f *fakeStore) ExpirePasswords(ctx context.Context, before time.Time) (int64, error) {
+	f.expireBefore = before
+	return 3, nil
+}
+
+func (f *fakeStore) PasswordWarnings(ctx context.Context, before time.Time) ([]Warning, error) {
+	f.warnBefore = before
+	return f.warnings, nil
+}
+
+func (f *fakeStore) MarkPasswordWarned(ctx context.Context, id int) error {
+	f.warned = append(f.warned, id)
+	return nil
+}
+
+type fakeMailer struct {
+	sent []string
+	fail string
+}
+
+func (m *fakeMailer) Enabled() bool { return true }
+
+func (m *fakeMailer) Send(ctx context.Context, to []string, name, locale string, vars map[string]string) error {
+	if to[0] == m.fail {
+		return errors.New("mailbox unavailable")
+	}
+	m.sent = append(m.sent, to[0])
+	return nil
+}
+
+func TestRunOnce(tt *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 10, 1, 12, 0, 0, 0, time.UTC)
+	tt.Cleanup(clock.Set(clock.NewFake(now)))
+
+	changed := now.AddDate(0, 0, -85)
+	store := &fakeStore{warnings: []Warning{
+		{UserID: 1, Username: "one", Email: "one@example.com", Changed: changed},
+		{UserID: 2, Username: "two", Changed: changed},
+		{UserID: 3, Username: "three", Email: "three@example.com", Changed: changed},
+	}}
+	mailer := &fakeMailer{fail: "three@example.com"}
+	r := New(slog.New(slog.NewTextHandler(io.Discard, nil)), store, mailer, Config{Policy: Policy{Days: 90, WarnDays: 14}})
+
+	result, err := r.RunOnce(ctx)
+	if err == nil {
+		tt.Error("failing mail not reported")
+	}
+	if result.Expired != 3 || result.Warned != 1 {
+		tt.Errorf("result = %+v", result)
+	}
+	if want := now.AddDate(0, 0, -90); !store.expireBefore.Equal(want) {
+		tt.Errorf("expired before %v, want %v", store.expireBefore, want)
+	}
+	if want := now.AddDate(0, 0, -76); !store.warnBefore.Equal(want) {
+		tt.Errorf("warned before %v, want %v", store.warnBefore, want)
+	}
+	// Users without an email or whose mail failed are not marked, the next run tries again.
+	if len(store.warned) != 1 || store.warned[0] != 1 {
+		tt.Errorf("warned = %v", store.warned)
+	}
+
+	// An admin set policy replaces the configured one, zero days disables expiry.
+	store.expireBefore = time.Time{}
+	if err := r.SetPolicy(ctx, 1, Policy{}); err != nil {
+		tt.Fatal(err)
+	}
+	result, err = r.RunOnce(ctx)
+	if err != nil || result != (Result{}) || !store.expireBefore.IsZero() {
+		tt.Errorf("disabled policy ran: %+v, %v", result, err)
+	}
+}
+
+func TestPolicy(tt *testing.T) {
+	changed := time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)
+	p := Policy{Days: 30, WarnDays: 7}
+
+	expires, ok := p.Expires(changed)
+	if !ok || !expires.Equal(changed.AddDate(0, 0, 30)) {
+		tt.Errorf("Expires() = %v, %v", expires, ok)
+	}
+	if p.Warn(expires, changed.AddDate(0, 0, 20)) {
+		tt.Error("warned 10 days ahead")
+	}
+	if !p.Warn(expires, changed.AddDate(0, 0, 25)) {
+		tt.Error("not warned 5 days ahead")
+	}
+	if _, ok := (Policy{}).Expires(changed); ok {
+		tt.Error("zero days expire")
+	}
+}
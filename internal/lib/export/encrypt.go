@@ -0,0 +1,145 @@
+package export
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// Encrypted archives start with a header: magic, scrypt cost as log2 N, 16 bytes of salt
+// and a 7 byte nonce prefix. The key is derived from the password with scrypt (N, r=8, p=1).
+// The archive follows in chunks of up to chunkSize bytes, each sealed with AES-256-GCM under
+// the nonce prefix, the chunk number and a flag marking the last chunk, with the header as
+// additional data, so reordered, truncated or extended files fail to decrypt.
+const (
+	magic      = "SAPIEXP1"
+	logN       = 15
+	saltSize   = 16
+	prefixSize = 7
+	headerSize = len(magic) + 1 + saltSize + prefixSize
+	chunkSize  = 64 << 10
+)
+
+// Suffix is appended to the name of encrypted archives
+const Suffix = ".enc"
+
+var (
+	// ErrDecrypt is returned for a wrong password or a corrupted or truncated file
+	ErrDecrypt = errors.New("wrong password or corrupted file")
+	// ErrNotEncrypted is returned by Decrypt for input without the encryption header
+	ErrNotEncrypted = errors.New("not an encrypted export")
+)
+
+// IsEncrypted tells whether data starts with the encryption header
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(magic))
+}
+
+// Encrypt writes src encrypted with a key derived from password to dst
+func Encrypt(dst io.Writer, src io.Reader, password string) error {
+	const op = "lib.export.Encrypt"
+
+	header := make([]byte, headerSize)
+	copy(header, magic)
+	header[len(magic)] = logN
+	if _, err := rand.Read(header[len(magic)+1:]); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	aead, err := newAEAD(password, header)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	if _, err := dst.Write(header); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	buf := make([]byte, chunkSize, chunkSize+aead.Overhead())
+	for i := uint32(0); ; i++ {
+		n, err := io.ReadFull(src, buf[:chunkSize])
+		last := errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+		if err != nil && !last {
+			return fmt.Errorf("%s: %v", op, err)
+		}
+
+		sealed := aead.Seal(buf[:0], chunkNonce(header, i, last), buf[:n], header)
+		if _, err := dst.Write(sealed); err != nil {
+			return fmt.Errorf("%s: %v", op, err)
+		}
+		if last {
+			return nil
+		}
+	}
+}
+
+// Decrypt writes the decrypted content of src to dst. Chunks are written as they are
+// authenticated, on error the output written so far must be discarded.
+func Decrypt(dst io.Writer, src io.Reader, password string) error {
+	const op = "lib.export.Decrypt"
+
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(src, header); err != nil || !IsEncrypted(header) {
+		return fmt.Errorf("%s: %w", op, ErrNotEncrypted)
+	}
+	if header[len(magic)] > 20 {
+		return fmt.Errorf("%s: unsupported scrypt cost: %w", op, ErrDecrypt)
+	}
+
+	aead, err := newAEAD(password, header)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	buf := make([]byte, chunkSize+aead.Overhead())
+	for i := uint32(0); ; i++ {
+		n, err := io.ReadFull(src, buf)
+		if errors.Is(err, io.EOF) {
+			return fmt.Errorf("%s: truncated: %w", op, ErrDecrypt)
+		}
+		last := errors.Is(err, io.ErrUnexpectedEOF)
+		if err != nil && !last {
+			return fmt.Errorf("%s: %v", op, err)
+		}
+
+		plain, err := aead.Open(buf[:0], chunkNonce(header, i, last), buf[:n], header)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, ErrDecrypt)
+		}
+		if _, err := dst.Write(plain); err != nil {
+			return fmt.Errorf("%s: %v", op, err)
+		}
+		if last {
+			return nil
+		}
+	}
+}
+
+func newAEAD(password string, header []byte) (cipher.AEAD, error) {
+	salt := header[len(magic)+1 : len(magic)+1+saltSize]
+	key, err := scrypt.Key([]byte(password), salt, 1<<header[len(magic)], 8, 1, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func chunkNonce(header []byte, i uint32, last bool) []byte {
+	nonce := make([]byte, 12)
+	copy(nonce, header[headerSize-prefixSize:])
+	binary.BigEndian.PutUint32(nonce[prefixSize:], i)
+	if last {
+		nonce[11] = 1
+	}
+	return nonce
+}
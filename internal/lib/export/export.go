@@ -0,0 +1,186 @@
+// Package export builds export archives: a zip file holding the exported files and
+// manifest.json with their SHA-256 checksums, optionally encrypted with a password, see Encrypt.
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ManifestName is the archive member holding the manifest, always the last one
+const ManifestName = "manifest.json"
+
+// ErrChecksum is returned by Verify when a file is missing or does not match the manifest
+var ErrChecksum = errors.New("checksum mismatch")
+
+type Manifest struct {
+	Created time.Time      `json:"created"`
+	Files   []ManifestFile `json:"files"`
+}
+
+type ManifestFile struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Archive writes a zip archive file by file, Close adds the manifest
+type Archive struct {
+	zw       *zip.Writer
+	manifest Manifest
+}
+
+func NewArchive(w io.Writer) *Archive {
+	return &Archive{zw: zip.NewWriter(w), manifest: Manifest{Created: time.Now().UTC(), Files: []ManifestFile{}}}
+}
+
+// Add writes the content of r as the archive member name
+func (a *Archive) Add(name string, r io.Reader) error {
+	const op = "lib.export.Archive.Add"
+
+	if name == ManifestName {
+		return fmt.Errorf("%s: %s is reserved", op, ManifestName)
+	}
+
+	fw, err := a.zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: a.manifest.Created})
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(fw, h), r)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	a.manifest.Files = append(a.manifest.Files, ManifestFile{Name: name, Size: n, SHA256: hex.EncodeToString(h.Sum(nil))})
+	return nil
+}
+
+// AddJSON writes v encoded as JSON as the archive member name
+func (a *Archive) AddJSON(name string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("lib.export.Archive.AddJSON: %v", err)
+	}
+	return a.Add(name, bytes.NewReader(data))
+}
+
+// Close writes the manifest and finishes the archive, it does not close the underlying writer
+func (a *Archive) Close() error {
+	const op = "lib.export.Archive.Close"
+
+	fw, err := a.zw.CreateHeader(&zip.FileHeader{Name: ManifestName, Method: zip.Deflate, Modified: a.manifest.Created})
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	enc := json.NewEncoder(fw)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(a.manifest); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	if err := a.zw.Close(); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	return nil
+}
+
+// Verify checks every file listed in the archive's manifest against its checksum and size.
+// Members missing from the manifest are reported as well, an archive can only be trusted as a whole.
+func Verify(r io.ReaderAt, size int64) (Manifest, error) {
+	const op = "lib.export.Verify"
+
+	var m Manifest
+
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return m, fmt.Errorf("%s: %v", op, err)
+	}
+
+	members := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		members[f.Name] = f
+	}
+
+	mf, ok := members[ManifestName]
+	if !ok {
+		return m, fmt.Errorf("%s: no %s: %w", op, ManifestName, ErrChecksum)
+	}
+	rc, err := mf.Open()
+	if err != nil {
+		return m, fmt.Errorf("%s: %v", op, err)
+	}
+	err = json.NewDecoder(rc).Decode(&m)
+	rc.Close()
+	if err != nil {
+		return m, fmt.Errorf("%s: %s: %v", op, ManifestName, err)
+	}
+	delete(members, ManifestName)
+
+	for _, want := range m.Files {
+		f, ok := members[want.Name]
+		if !ok {
+			return m, fmt.Errorf("%s: %s is missing: %w", op, want.Name, ErrChecksum)
+		}
+		delete(members, want.Name)
+
+		rc, err := f.Open()
+		if err != nil {
+			return m, fmt.Errorf("%s: %v", op, err)
+		}
+		h := sha256.New()
+		n, err := io.Copy(h, rc)
+		rc.Close()
+		if err != nil {
+			return m, fmt.Errorf("%s: %s: %v", op, want.Name, err)
+		}
+		if n != want.Size || hex.EncodeToString(h.Sum(nil)) != want.SHA256 {
+			return m, fmt.Errorf("%s: %s: %w", op, want.Name, ErrChecksum)
+		}
+	}
+
+	for name := range members {
+		return m, fmt.Errorf("%s: %s is not in the manifest: %w", op, name, ErrChecksum)
+	}
+
+	return m, nil
+}
+
+// Write builds an archive with build and writes it to w, encrypted when password is not empty.
+// Export handlers take the password from the request, it is never stored.
+func Write(w io.Writer, password string, build func(a *Archive) error) error {
+	const op = "lib.export.Write"
+
+	if password == "" {
+		a := NewArchive(w)
+		if err := build(a); err != nil {
+			return fmt.Errorf("%s: %v", op, err)
+		}
+		return a.Close()
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		a := NewArchive(pw)
+		err := build(a)
+		if err == nil {
+			err = a.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	err := Encrypt(w, pr, password)
+	pr.CloseWithError(err)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	return nil
+}
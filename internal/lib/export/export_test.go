@@ -0,0 +1,119 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestArchive(tt *testing.T) {
+	var buf bytes.Buffer
+	a := NewArchive(&buf)
+	if err := a.AddJSON("profile.json", map[string]string{"login": "user"}); err != nil {
+		tt.Fatal(err)
+	}
+	if err := a.Add("todos.json", strings.NewReader(`[{"title":"one"}]`)); err != nil {
+		tt.Fatal(err)
+	}
+	if err := a.Add(ManifestName, strings.NewReader("{}")); err == nil {
+		tt.Error("adding a second manifest succeeded")
+	}
+	if err := a.Close(); err != nil {
+		tt.Fatal(err)
+	}
+
+	m, err := Verify(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		tt.Fatal(err)
+	}
+	if len(m.Files) != 2 || m.Files[1].Name != "todos.json" || m.Files[1].Size != 17 {
+		tt.Errorf("manifest = %+v", m)
+	}
+
+	// An archive with a changed file and the original manifest fails verification.
+	zr, _ := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	var tampered bytes.Buffer
+	zw := zip.NewWriter(&tampered)
+	for _, f := range zr.File {
+		w, _ := zw.Create(f.Name)
+		if f.Name == "todos.json" {
+			w.Write([]byte(`[{"title":"two"}]`))
+			continue
+		}
+		rc, _ := f.Open()
+		var content bytes.Buffer
+		content.ReadFrom(rc)
+		rc.Close()
+		w.Write(content.Bytes())
+	}
+	zw.Close()
+	if _, err := Verify(bytes.NewReader(tampered.Bytes()), int64(tampered.Len())); !errors.Is(err, ErrChecksum) {
+		tt.Errorf("tampered archive: err = %v, want ErrChecksum", err)
+	}
+}
+
+func TestEncrypt(tt *testing.T) {
+	for _, size := range []int{0, 100, chunkSize, 2*chunkSize + 7} {
+		plain := bytes.Repeat([]byte("x"), size)
+
+		var enc bytes.Buffer
+		if err := Encrypt(&enc, bytes.NewReader(plain), "secret"); err != nil {
+			tt.Fatal(err)
+		}
+		if !IsEncrypted(enc.Bytes()) {
+			tt.Fatalf("%d bytes: no header", size)
+		}
+
+		var dec bytes.Buffer
+		if err := Decrypt(&dec, bytes.NewReader(enc.Bytes()), "secret"); err != nil {
+			tt.Fatalf("%d bytes: %v", size, err)
+		}
+		if !bytes.Equal(dec.Bytes(), plain) {
+			tt.Errorf("%d bytes: decrypted %d bytes", size, dec.Len())
+		}
+
+		if err := Decrypt(&dec, bytes.NewReader(enc.Bytes()), "wrong"); !errors.Is(err, ErrDecrypt) {
+			tt.Errorf("%d bytes, wrong password: err = %v, want ErrDecrypt", size, err)
+		}
+	}
+
+	var enc bytes.Buffer
+	Encrypt(&enc, bytes.NewReader(bytes.Repeat([]byte("x"), 2*chunkSize+7)), "secret")
+	for name, data := range map[string][]byte{
+		"truncated at chunk": enc.Bytes()[:headerSize+chunkSize+16],
+		"truncated":          enc.Bytes()[:enc.Len()-1],
+	} {
+		if err := Decrypt(&bytes.Buffer{}, bytes.NewReader(data), "secret"); !errors.Is(err, ErrDecrypt) {
+			tt.Errorf("%s: err = %v, want ErrDecrypt", name, err)
+		}
+	}
+
+	if err := Decrypt(&bytes.Buffer{}, strings.NewReader("PK plain zip"), "secret"); !errors.Is(err, ErrNotEncrypted) {
+		tt.Errorf("plain input: err = %v, want ErrNotEncrypted", err)
+	}
+}
+
+func TestWrite(tt *testing.T) {
+	var enc bytes.Buffer
+	err := Write(&enc, "secret", func(a *Archive) error {
+		return a.AddJSON("todos.json", []string{"one"})
+	})
+	if err != nil {
+		tt.Fatal(err)
+	}
+
+	var dec bytes.Buffer
+	if err := Decrypt(&dec, &enc, "secret"); err != nil {
+		tt.Fatal(err)
+	}
+	if _, err := Verify(bytes.NewReader(dec.Bytes()), int64(dec.Len())); err != nil {
+		tt.Error(err)
+	}
+
+	build := errors.New("query failed")
+	if err := Write(&bytes.Buffer{}, "secret", func(a *Archive) error { return build }); err == nil {
+		tt.Error("Write() succeeded with a failing build")
+	}
+}
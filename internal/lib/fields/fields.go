@@ -0,0 +1,227 @@
+// Package fields defines custom fields added to records at runtime and validates their values.
+// Values are kept as a JSON object keyed by field key.
+package fields
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// Field types
+const (
+	TypeText    = "text"
+	TypeNumber  = "number"
+	TypeBoolean = "boolean"
+	TypeDate    = "date"
+	TypeSelect  = "select"
+)
+
+// Visibility of user profile fields
+const (
+	// VisibilityEditable fields are shown to the user and set by the user or an admin
+	VisibilityEditable = "editable"
+	// VisibilityVisible fields are shown to the user and set by admins only
+	VisibilityVisible = "visible"
+	// VisibilityAdmin fields are only shown to admins
+	VisibilityAdmin = "admin"
+)
+
+const (
+	// DateFormat is the format of date values
+	DateFormat = "2006-01-02"
+	// MaxText is the maximum length of a text value
+	MaxText = 1000
+)
+
+// ErrInvalid is returned for an invalid schema or value, the message tells what is wrong
+var ErrInvalid = errors.New("invalid custom field")
+
+type Field struct {
+	// Key is the name of the value, lower case letters, digits and underscores
+	Key      string `json:"key" validate:"required,max=40"`
+	Label    string `json:"label" validate:"max=100"`
+	Type     string `json:"type" validate:"required,oneof=text number boolean date select"`
+	Required bool   `json:"required"`
+	// Visibility applies to user profile fields, it defaults to editable
+	Visibility string `json:"visibility,omitempty" validate:"omitempty,oneof=editable visible admin"`
+	// Options are the values of a select field
+	Options []string `json:"options,omitempty" validate:"max=100,dive,required,max=100"`
+}
+
+type Schema struct {
+	Fields []Field `json:"fields" validate:"max=50,dive"`
+}
+
+// Check validates what the validate tags cannot: key format, duplicate keys and select options
+func (s Schema) Check() error {
+	seen := make(map[string]bool, len(s.Fields))
+	for _, f := range s.Fields {
+		for _, c := range f.Key {
+			if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '_' {
+				return fmt.Errorf("key %q may only hold lower case letters, digits and underscores: %w", f.Key, ErrInvalid)
+			}
+		}
+		if seen[f.Key] {
+			return fmt.Errorf("key %q is defined twice: %w", f.Key, ErrInvalid)
+		}
+		seen[f.Key] = true
+
+		if f.Type == TypeSelect && len(f.Options) == 0 {
+			return fmt.Errorf("select field %q needs options: %w", f.Key, ErrInvalid)
+		}
+		if f.Type != TypeSelect && len(f.Options) > 0 {
+			return fmt.Errorf("only select fields have options, %q is %s: %w", f.Key, f.Type, ErrInvalid)
+		}
+	}
+	return nil
+}
+
+// Field returns the field with the key
+func (s Schema) Field(key string) (Field, bool) {
+	for _, f := range s.Fields {
+		if f.Key == key {
+			return f, true
+		}
+	}
+	return Field{}, false
+}
+
+// Only returns the schema of the fields allowed by keep
+func (s Schema) Only(keep func(Field) bool) Schema {
+	out := Schema{Fields: []Field{}}
+	for _, f := range s.Fields {
+		if keep(f) {
+			out.Fields = append(out.Fields, f)
+		}
+	}
+	return out
+}
+
+// Apply validates changes to values and returns the values after them, a nil change removes a value.
+// Only fields allowed by editable may change, and only they are required: a caller cannot be
+// blocked by a required field it may not set.
+func (s Schema) Apply(values, changes map[string]any, editable func(Field) bool) (map[string]any, error) {
+	out := make(map[string]any, len(values)+len(changes))
+	for k, v := range values {
+		out[k] = v
+	}
+
+	for key, v := range changes {
+		f, ok := s.Field(key)
+		if !ok {
+			return nil, fmt.Errorf("unknown field %q: %w", key, ErrInvalid)
+		}
+		if !editable(f) {
+			return nil, fmt.Errorf("field %q cannot be changed: %w", key, ErrInvalid)
+		}
+		if v == nil {
+			delete(out, key)
+			continue
+		}
+		value, err := f.Normalize(v)
+		if err != nil {
+			return nil, err
+		}
+		out[key] = value
+	}
+
+	for _, f := range s.Fields {
+		if _, ok := out[f.Key]; f.Required && !ok && editable(f) {
+			return nil, fmt.Errorf("field %q is required: %w", f.Key, ErrInvalid)
+		}
+	}
+	return out, nil
+}
+
+// Filter returns the values of fields allowed by visible, values of fields no longer defined are left out
+func (s Schema) Filter(values map[string]any, visible func(Field) bool) map[string]any {
+	out := make(map[string]any)
+	for _, f := range s.Fields {
+		if v, ok := values[f.Key]; ok && visible(f) {
+			out[f.Key] = v
+		}
+	}
+	return out
+}
+
+// Normalize checks a JSON decoded value against the field type
+func (f Field) Normalize(v any) (any, error) {
+	invalid := func(want string) error {
+		return fmt.Errorf("field %q must be %s: %w", f.Key, want, ErrInvalid)
+	}
+
+	switch f.Type {
+	case TypeText:
+		s, ok := v.(string)
+		if !ok || utf8.RuneCountInString(s) > MaxText {
+			return nil, invalid(fmt.Sprintf("a text of at most %d characters", MaxText))
+		}
+		return s, nil
+	case TypeNumber:
+		n, ok := v.(float64)
+		if !ok {
+			return nil, invalid("a number")
+		}
+		return n, nil
+	case TypeBoolean:
+		b, ok := v.(bool)
+		if !ok {
+			return nil, invalid("true or false")
+		}
+		return b, nil
+	case TypeDate:
+		s, ok := v.(string)
+		if !ok {
+			return nil, invalid("a date as YYYY-MM-DD")
+		}
+		if _, err := time.Parse(DateFormat, s); err != nil {
+			return nil, invalid("a date as YYYY-MM-DD")
+		}
+		return s, nil
+	case TypeSelect:
+		s, ok := v.(string)
+		if !ok || !slices.Contains(f.Options, s) {
+			return nil, invalid(fmt.Sprintf("one of %v", f.Options))
+		}
+		return s, nil
+	}
+	return nil, fmt.Errorf("field %q has unknown type %q: %w", f.Key, f.Type, ErrInvalid)
+}
+
+// Parse converts a query parameter to a value of the field, e.g. for filters
+func (f Field) Parse(s string) (any, error) {
+	switch f.Type {
+	case TypeNumber:
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, fmt.Errorf("field %q must be a number: %w", f.Key, ErrInvalid)
+		}
+		return n, nil
+	case TypeBoolean:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, fmt.Errorf("field %q must be true or false: %w", f.Key, ErrInvalid)
+		}
+		return b, nil
+	}
+	return f.Normalize(s)
+}
+
+// UserEditable tells whether users may set the profile field themselves
+func UserEditable(f Field) bool {
+	return f.Visibility == "" || f.Visibility == VisibilityEditable
+}
+
+// UserVisible tells whether users see the profile field
+func UserVisible(f Field) bool {
+	return f.Visibility != VisibilityAdmin
+}
+
+// All allows every field, for admins
+func All(Field) bool {
+	return true
+}
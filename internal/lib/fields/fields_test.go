@@ -0,0 +1,102 @@
+package fields
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+var schema = Schema{Fields: []Field{
+	{Key: "employee_id", Type: TypeText, Required: true, Visibility: VisibilityVisible},
+	{Key: "department", Type: TypeSelect, Options: []string{"sales", "it"}, Required: true},
+	{Key: "salary", Type: TypeNumber, Visibility: VisibilityAdmin},
+	{Key: "started", Type: TypeDate},
+	{Key: "remote", Type: TypeBoolean},
+}}
+
+func TestCheck(t *testing.T) {
+	if err := schema.Check(); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, s := range map[string]Schema{
+		"key format":        {Fields: []Field{{Key: "Employee ID", Type: TypeText}}},
+		"duplicate key":     {Fields: []Field{{Key: "a", Type: TypeText}, {Key: "a", Type: TypeNumber}}},
+		"select no options": {Fields: []Field{{Key: "a", Type: TypeSelect}}},
+		"text with options": {Fields: []Field{{Key: "a", Type: TypeText, Options: []string{"x"}}}},
+	} {
+		if err := s.Check(); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%s: err = %v, want ErrInvalid", name, err)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	values := map[string]any{"employee_id": "E-1", "department": "it", "salary": 100.0}
+
+	got, err := schema.Apply(values, map[string]any{"department": "sales", "started": "2024-10-01", "remote": true}, UserEditable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{"employee_id": "E-1", "department": "sales", "salary": 100.0, "started": "2024-10-01", "remote": true}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Apply = %v, want %v", got, want)
+	}
+	if values["department"] != "it" {
+		t.Error("Apply changed its input")
+	}
+
+	got, err = schema.Apply(want, map[string]any{"started": nil}, UserEditable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := got["started"]; ok {
+		t.Error("a null change kept the value")
+	}
+
+	// The user may not set employee_id, so its absence does not block the user's own changes.
+	if _, err := schema.Apply(map[string]any{}, map[string]any{"department": "it"}, UserEditable); err != nil {
+		t.Errorf("required field the user cannot set: %v", err)
+	}
+
+	for name, changes := range map[string]map[string]any{
+		"unknown field":   {"nickname": "x"},
+		"not editable":    {"employee_id": "E-2"},
+		"wrong type":      {"remote": "yes"},
+		"wrong date":      {"started": "01.10.2024"},
+		"unknown option":  {"department": "hr"},
+		"required remove": {"department": nil},
+	} {
+		if _, err := schema.Apply(values, changes, UserEditable); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%s: err = %v, want ErrInvalid", name, err)
+		}
+	}
+
+	if _, err := schema.Apply(map[string]any{"department": "it"}, map[string]any{"salary": 1.0}, All); !errors.Is(err, ErrInvalid) {
+		t.Errorf("admin change without a required field: err = %v, want ErrInvalid", err)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	values := map[string]any{"employee_id": "E-1", "salary": 100.0, "removed": "x"}
+
+	got := schema.Filter(values, UserVisible)
+	if want := map[string]any{"employee_id": "E-1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Filter = %v, want %v", got, want)
+	}
+}
+
+func TestParse(t *testing.T) {
+	salary, _ := schema.Field("salary")
+	if v, err := salary.Parse("1.5"); err != nil || v != 1.5 {
+		t.Errorf("Parse number = %v, %v", v, err)
+	}
+	remote, _ := schema.Field("remote")
+	if v, err := remote.Parse("true"); err != nil || v != true {
+		t.Errorf("Parse boolean = %v, %v", v, err)
+	}
+	department, _ := schema.Field("department")
+	if _, err := department.Parse("hr"); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Parse unknown option: err = %v, want ErrInvalid", err)
+	}
+}
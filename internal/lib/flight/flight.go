@@ -0,0 +1,50 @@
+// Package flight coalesces concurrent identical reads: requests asking for a key while its read is in progress
+// wait for that read and share its result instead of querying the database again.
+package flight
+
+import (
+	"context"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/sabbatD/srest-api/internal/lib/metrics"
+)
+
+// Group coalesces reads by key, name labels its metrics
+type Group struct {
+	name string
+	g    singleflight.Group
+}
+
+func New(name string) *Group {
+	return &Group{name: name}
+}
+
+// Do returns the result of fn for the key, running it once for concurrent calls with the same key.
+// fn gets a context that keeps the values and deadline of the first caller's but not its cancellation,
+// so a caller going away does not fail the others. A caller whose own context is done returns its error
+// without waiting. Calls that shared another's read are counted in metrics.Coalesced.
+func (g *Group) Do(ctx context.Context, key string, fn func(ctx context.Context) (any, error)) (any, error) {
+	ran := false
+	ch := g.g.DoChan(key, func() (any, error) {
+		ran = true
+
+		readCtx := context.WithoutCancel(ctx)
+		if deadline, ok := ctx.Deadline(); ok {
+			var cancel context.CancelFunc
+			readCtx, cancel = context.WithDeadline(readCtx, deadline)
+			defer cancel()
+		}
+		return fn(readCtx)
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-ch:
+		if !ran {
+			metrics.Coalesced.Add(g.name, 1)
+		}
+		return res.Val, res.Err
+	}
+}
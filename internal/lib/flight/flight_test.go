@@ -0,0 +1,84 @@
+package flight
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sabbatD/srest-api/internal/lib/metrics"
+)
+
+func coalesced(name string) int64 {
+	if v, ok := metrics.Coalesced.Get(name).(interface{ Value() int64 }); ok {
+		return v.Value()
+	}
+	return 0
+}
+
+func TestDo(tt *testing.T) {
+	g := New("test_do")
+	before := coalesced("test_do")
+
+	var mu sync.Mutex
+	reads := 0
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	read := func(ctx context.Context) (any, error) {
+		mu.Lock()
+		reads++
+		mu.Unlock()
+		started <- struct{}{}
+		<-release
+		return "list", ctx.Err()
+	}
+
+	// The first caller goes away, the read goes on for the others.
+	first, cancel := context.WithCancel(context.Background())
+	firstErr := make(chan error)
+	go func() {
+		_, err := g.Do(first, "k", read)
+		firstErr <- err
+	}()
+	<-started
+	cancel()
+	if err := <-firstErr; !errors.Is(err, context.Canceled) {
+		tt.Fatalf("canceled caller: err = %v", err)
+	}
+
+	const callers = 5
+	var wg sync.WaitGroup
+	results := make(chan any, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := g.Do(context.Background(), "k", read)
+			if err != nil {
+				tt.Error(err)
+			}
+			results <- v
+		}()
+	}
+	// Let the callers join the read in progress.
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(results)
+
+	for v := range results {
+		if v != "list" {
+			tt.Errorf("result = %v", v)
+		}
+	}
+	// Each call either read or shared a read
+	if n := coalesced("test_do") - before; n == 0 || int64(reads)+n != callers+1 {
+		tt.Errorf("reads = %d, coalesced = %d, want one read for %d calls", reads, n, callers+1)
+	}
+
+	// Once it finished, the key is read again.
+	if v, _ := g.Do(context.Background(), "k", func(context.Context) (any, error) { return "new", nil }); v != "new" {
+		tt.Errorf("read after the first = %v", v)
+	}
+}
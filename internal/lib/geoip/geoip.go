@@ -0,0 +1,170 @@
+// Package geoip locates client addresses by country and city with a MaxMind DB, e.g. GeoLite2-City,
+// for the sign in history and security alerts. The database is optional and reloaded when its file changes,
+// so it is updated (e.g. by geoipupdate) without a restart.
+package geoip
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sabbatD/srest-api/internal/lib/logger/sl"
+	"github.com/sabbatD/srest-api/internal/lib/metrics"
+)
+
+// Config locates the database, an empty path disables the lookups
+type Config struct {
+	Path string `yaml:"path" env:"GEOIP_DB"`
+	// ReloadInterval is how often the file is checked for changes, zero only reads it on startup
+	ReloadInterval time.Duration `yaml:"reload_interval" env-default:"1m"`
+}
+
+// Location of an address, fields the database does not know are empty
+type Location struct {
+	// Country is the ISO 3166-1 alpha-2 code, e.g. DE
+	Country     string `json:"country,omitempty"`
+	CountryName string `json:"countryName,omitempty"`
+	City        string `json:"city,omitempty"`
+}
+
+// String returns the location as "City, Country", empty when it is unknown
+func (l Location) String() string {
+	switch {
+	case l.City != "" && l.Country != "":
+		return l.City + ", " + l.Country
+	case l.City != "":
+		return l.City
+	}
+	return l.Country
+}
+
+// DB locates addresses with the configured database. A nil DB, without one, locates none.
+type DB struct {
+	log    *slog.Logger
+	cfg    Config
+	reader atomic.Pointer[Reader]
+
+	mu sync.Mutex
+	// modified and size of the file the reader was read from, a change reloads it
+	modified time.Time
+	size     int64
+}
+
+// Open reads the configured database, it returns nil without one
+func Open(log *slog.Logger, cfg Config) (*DB, error) {
+	const op = "lib.geoip.Open"
+
+	if cfg.Path == "" {
+		return nil, nil
+	}
+
+	db := &DB{log: log, cfg: cfg}
+	if _, err := db.Reload(); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+	return db, nil
+}
+
+// Reload reads the database again when its file changed since the last read and reports whether it did.
+// A file that cannot be read keeps the database read before.
+func (db *DB) Reload() (bool, error) {
+	const op = "lib.geoip.Reload"
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	st, err := os.Stat(db.cfg.Path)
+	if err != nil {
+		return false, fmt.Errorf("%s: %v", op, err)
+	}
+	if db.reader.Load() != nil && st.ModTime().Equal(db.modified) && st.Size() == db.size {
+		return false, nil
+	}
+
+	r, err := OpenReader(db.cfg.Path)
+	if err != nil {
+		return false, fmt.Errorf("%s: %v", op, err)
+	}
+	db.reader.Store(r)
+	db.modified, db.size = st.ModTime(), st.Size()
+	return true, nil
+}
+
+// Metadata describes the database in use, false without one
+func (db *DB) Metadata() (Metadata, bool) {
+	if db == nil {
+		return Metadata{}, false
+	}
+	return db.reader.Load().Metadata(), true
+}
+
+// Lookup returns the location of addr, false when it is unknown
+func (db *DB) Lookup(addr netip.Addr) (Location, bool) {
+	if db == nil || !addr.IsValid() {
+		return Location{}, false
+	}
+
+	record, ok, err := db.reader.Load().Lookup(addr)
+	if err != nil {
+		db.log.Warn("geoip lookup failed", slog.String("addr", addr.String()), sl.Err(err))
+		return Location{}, false
+	}
+	if !ok {
+		return Location{}, false
+	}
+
+	country, _ := record["country"].(map[string]any)
+	if country == nil {
+		// Anonymous networks only have the country they are registered in
+		country, _ = record["registered_country"].(map[string]any)
+	}
+	city, _ := record["city"].(map[string]any)
+
+	var l Location
+	l.Country, _ = country["iso_code"].(string)
+	l.CountryName = name(country)
+	l.City = name(city)
+	return l, l != Location{}
+}
+
+// name returns the English name of a place of a record
+func name(place map[string]any) string {
+	names, _ := place["names"].(map[string]any)
+	s, _ := names["en"].(string)
+	return s
+}
+
+// Run reloads the database every ReloadInterval when its file changed, until ctx is done
+func (db *DB) Run(ctx context.Context) {
+	const op = "lib.geoip.Run"
+
+	if db == nil || db.cfg.ReloadInterval <= 0 {
+		return
+	}
+	log := db.log.With(slog.String("op", op))
+
+	ticker := time.NewTicker(db.cfg.ReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := db.Reload()
+			if err != nil {
+				log.Error("geoip database reload failed", sl.Err(err))
+				metrics.JobFailures.Add("geoip", 1)
+				continue
+			}
+			if reloaded {
+				meta, _ := db.Metadata()
+				log.Info("geoip database reloaded", slog.String("type", meta.Type), slog.Time("built", meta.Built))
+			}
+		}
+	}
+}
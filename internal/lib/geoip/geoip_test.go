@@ -0,0 +1,243 @@
+package geoip
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// writeDB writes a MaxMind DB with 24 bit records of an IPv6 tree holding the records by network
+func writeDB(t *testing.T, path string, networks map[string]map[string]any) {
+	t.Helper()
+
+	type node struct {
+		child [2]*node
+		leaf  [2]int
+	}
+	newNode := func() *node { return &node{leaf: [2]int{-1, -1}} }
+
+	root := newNode()
+	var data bytes.Buffer
+	for network, record := range networks {
+		prefix := netip.MustParsePrefix(network)
+		ip, bits := prefix.Addr().As16(), prefix.Bits()
+		if prefix.Addr().Is4() {
+			// IPv4 lives in ::/96
+			ip = [16]byte{}
+			a := prefix.Addr().As4()
+			copy(ip[12:], a[:])
+			bits += 96
+		}
+
+		off := data.Len()
+		encode(&data, record)
+
+		n := root
+		for i := 0; i < bits; i++ {
+			b := ip[i/8] >> (7 - i%8) & 1
+			if i == bits-1 {
+				n.leaf[b] = off
+				break
+			}
+			if n.child[b] == nil {
+				n.child[b] = newNode()
+			}
+			n = n.child[b]
+		}
+	}
+
+	var nodes []*node
+	var number func(n *node)
+	number = func(n *node) {
+		nodes = append(nodes, n)
+		for _, c := range n.child {
+			if c != nil {
+				number(c)
+			}
+		}
+	}
+	number(root)
+
+	var file bytes.Buffer
+	for _, n := range nodes {
+		for b := range 2 {
+			v := len(nodes)
+			if n.child[b] != nil {
+				v = slices.Index(nodes, n.child[b])
+			} else if n.leaf[b] >= 0 {
+				v = len(nodes) + dataSeparator + n.leaf[b]
+			}
+			file.Write([]byte{byte(v >> 16), byte(v >> 8), byte(v)})
+		}
+	}
+	file.Write(make([]byte, dataSeparator))
+	file.Write(data.Bytes())
+	file.Write(metadataMarker)
+	encode(&file, map[string]any{
+		"node_count":                  uint64(len(nodes)),
+		"record_size":                 uint64(24),
+		"ip_version":                  uint64(6),
+		"database_type":               "Test-City",
+		"binary_format_major_version": uint64(2),
+		"binary_format_minor_version": uint64(0),
+		"build_epoch":                 uint64(1700000000),
+		"languages":                   []any{"en"},
+	})
+
+	if err := os.WriteFile(path, file.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func encode(buf *bytes.Buffer, v any) {
+	switch v := v.(type) {
+	case string:
+		control(buf, typeString, len(v))
+		buf.WriteString(v)
+	case uint64:
+		var b []byte
+		for ; v > 0; v >>= 8 {
+			b = append([]byte{byte(v)}, b...)
+		}
+		control(buf, typeUint64, len(b))
+		buf.Write(b)
+	case []any:
+		control(buf, typeArray, len(v))
+		for _, e := range v {
+			encode(buf, e)
+		}
+	case map[string]any:
+		control(buf, typeMap, len(v))
+		for k, e := range v {
+			encode(buf, k)
+			encode(buf, e)
+		}
+	default:
+		panic("cannot encode")
+	}
+}
+
+func control(buf *bytes.Buffer, typ, size int) {
+	t := typ
+	if typ > 7 {
+		t = typeExtended
+	}
+	var extra []byte
+	if size >= 29 {
+		extra, size = []byte{byte(size - 29)}, 29
+	}
+	buf.WriteByte(byte(t<<5 | size))
+	if typ > 7 {
+		buf.WriteByte(byte(typ - 7))
+	}
+	buf.Write(extra)
+}
+
+func place(name string) map[string]any {
+	return map[string]any{"names": map[string]any{"en": name, "de": "x"}}
+}
+
+func TestLookup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "city.mmdb")
+	london := map[string]any{
+		"city":    place("London"),
+		"country": map[string]any{"iso_code": "GB", "names": place("United Kingdom")["names"]},
+	}
+	writeDB(t, path, map[string]map[string]any{
+		"81.2.69.0/24":  london,
+		"2001:db8::/32": {"registered_country": map[string]any{"iso_code": "DE", "names": place("Germany")["names"]}},
+	})
+
+	db, err := Open(slog.New(slog.NewTextHandler(io.Discard, nil)), Config{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta, _ := db.Metadata(); meta.Type != "Test-City" || !meta.Built.Equal(time.Unix(1700000000, 0)) {
+		t.Fatalf("metadata = %+v", meta)
+	}
+
+	tests := []struct {
+		addr string
+		want Location
+		ok   bool
+	}{
+		{"81.2.69.142", Location{Country: "GB", CountryName: "United Kingdom", City: "London"}, true},
+		{"::ffff:81.2.69.1", Location{Country: "GB", CountryName: "United Kingdom", City: "London"}, true},
+		{"2001:db8:1::5", Location{Country: "DE", CountryName: "Germany"}, true},
+		{"81.2.70.1", Location{}, false},
+		{"2001:db9::1", Location{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			got, ok := db.Lookup(netip.MustParseAddr(tt.addr))
+			if got != tt.want || ok != tt.ok {
+				t.Fatalf("Lookup = %+v, %v, want %+v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+
+	var none *DB
+	if _, ok := none.Lookup(netip.MustParseAddr("81.2.69.142")); ok {
+		t.Fatal("nil DB located an address")
+	}
+	if got := (Location{Country: "GB", City: "London"}).String(); got != "London, GB" {
+		t.Fatalf("String = %q", got)
+	}
+}
+
+func TestReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "city.mmdb")
+	writeDB(t, path, map[string]map[string]any{"10.0.0.0/8": {"country": map[string]any{"iso_code": "GB"}}})
+
+	db, err := Open(slog.New(slog.NewTextHandler(io.Discard, nil)), Config{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloaded, err := db.Reload(); err != nil || reloaded {
+		t.Fatalf("Reload of an unchanged file = %v, %v", reloaded, err)
+	}
+
+	writeDB(t, path, map[string]map[string]any{"10.0.0.0/8": {"country": map[string]any{"iso_code": "FR"}}})
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if reloaded, err := db.Reload(); err != nil || !reloaded {
+		t.Fatalf("Reload of a changed file = %v, %v", reloaded, err)
+	}
+	if got, _ := db.Lookup(netip.MustParseAddr("10.1.2.3")); got.Country != "FR" {
+		t.Fatalf("country after reload = %q", got.Country)
+	}
+
+	// A broken update keeps the database read before
+	if err := os.WriteFile(path, []byte("not a database"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Reload(); err == nil {
+		t.Fatal("Reload of a broken file succeeded")
+	}
+	if got, _ := db.Lookup(netip.MustParseAddr("10.1.2.3")); got.Country != "FR" {
+		t.Fatalf("country after a failed reload = %q", got.Country)
+	}
+}
+
+func TestDecodePointer(t *testing.T) {
+	// "en", then a pointer to it
+	d := decoder{buf: []byte{0x42, 'e', 'n', 0x20, 0x00}}
+	v, next, err := d.decode(3, 0)
+	if err != nil || v != "en" || next != 5 {
+		t.Fatalf("decode = %v, %d, %v", v, next, err)
+	}
+
+	// A pointer to itself
+	d = decoder{buf: []byte{0x20, 0x00}}
+	if _, _, err := d.decode(0, 0); err == nil {
+		t.Fatal("decoded a pointer loop")
+	}
+}
@@ -0,0 +1,336 @@
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"net/netip"
+	"os"
+	"time"
+)
+
+// metadataMarker precedes the metadata at the end of a MaxMind DB file
+var metadataMarker = []byte("\xAB\xCD\xEFMaxMind.com")
+
+// dataSeparator is the size of the zeros between the search tree and the data section
+const dataSeparator = 16
+
+// maxDepth bounds the nesting of decoded values, so a corrupt file cannot recurse forever
+const maxDepth = 32
+
+// ErrInvalid is returned for a file that is not a MaxMind DB or is corrupt
+var ErrInvalid = errors.New("invalid MaxMind DB")
+
+// Metadata describes a MaxMind DB file
+type Metadata struct {
+	// Type is the database type, e.g. GeoLite2-City
+	Type  string
+	Built time.Time
+}
+
+// Reader looks addresses up in a MaxMind DB file, the format of the GeoIP2 and GeoLite2 databases,
+// see https://maxmind.github.io/MaxMind-DB/
+type Reader struct {
+	tree       []byte
+	data       decoder
+	nodeCount  uint
+	recordSize uint
+	ipVersion  uint
+	// ipv4Start is the node IPv4 lookups start at in an IPv6 tree, IPv4 lives in ::/96
+	ipv4Start uint
+	meta      Metadata
+}
+
+// OpenReader reads the MaxMind DB file at path
+func OpenReader(path string) (*Reader, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewReader(b)
+}
+
+// NewReader reads a MaxMind DB from its content
+func NewReader(b []byte) (*Reader, error) {
+	i := bytes.LastIndex(b, metadataMarker)
+	if i < 0 {
+		return nil, fmt.Errorf("%w: no metadata", ErrInvalid)
+	}
+	v, _, err := decoder{buf: b[i+len(metadataMarker):]}.decode(0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("%w: metadata: %v", ErrInvalid, err)
+	}
+	meta, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: metadata is not a map", ErrInvalid)
+	}
+
+	r := &Reader{
+		nodeCount:  uintOf(meta["node_count"]),
+		recordSize: uintOf(meta["record_size"]),
+		ipVersion:  uintOf(meta["ip_version"]),
+	}
+	r.meta.Type, _ = meta["database_type"].(string)
+	r.meta.Built = time.Unix(int64(uintOf(meta["build_epoch"])), 0).UTC()
+
+	if major := uintOf(meta["binary_format_major_version"]); major != 2 {
+		return nil, fmt.Errorf("%w: format version %d", ErrInvalid, major)
+	}
+	if r.recordSize != 24 && r.recordSize != 28 && r.recordSize != 32 {
+		return nil, fmt.Errorf("%w: record size %d", ErrInvalid, r.recordSize)
+	}
+	if r.ipVersion != 4 && r.ipVersion != 6 {
+		return nil, fmt.Errorf("%w: IP version %d", ErrInvalid, r.ipVersion)
+	}
+
+	treeSize := r.nodeCount * r.recordSize / 4
+	if treeSize+dataSeparator > uint(i) {
+		return nil, fmt.Errorf("%w: search tree past the end of the file", ErrInvalid)
+	}
+	r.tree = b[:treeSize]
+	r.data = decoder{buf: b[treeSize+dataSeparator : i]}
+
+	if r.ipVersion == 6 {
+		node := uint(0)
+		for j := 0; j < 96 && node < r.nodeCount; j++ {
+			node = r.record(node, 0)
+		}
+		r.ipv4Start = node
+	}
+	return r, nil
+}
+
+// Metadata describes the file
+func (r *Reader) Metadata() Metadata {
+	return r.meta
+}
+
+// Lookup returns the record of the network addr belongs to, false when the file has none
+func (r *Reader) Lookup(addr netip.Addr) (map[string]any, bool, error) {
+	addr = addr.Unmap()
+
+	var ip []byte
+	node := uint(0)
+	switch {
+	case addr.Is4():
+		a := addr.As4()
+		ip, node = a[:], r.ipv4Start
+	case r.ipVersion == 4:
+		return nil, false, nil
+	default:
+		a := addr.As16()
+		ip = a[:]
+	}
+
+	for i := 0; i < len(ip)*8 && node < r.nodeCount; i++ {
+		node = r.record(node, uint(ip[i>>3]>>(7-i&7))&1)
+	}
+	switch {
+	case node == r.nodeCount:
+		return nil, false, nil
+	case node < r.nodeCount:
+		return nil, false, fmt.Errorf("%w: search tree deeper than the address", ErrInvalid)
+	}
+
+	v, _, err := r.data.decode(node-r.nodeCount-dataSeparator, 0)
+	if err != nil {
+		return nil, false, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	record, ok := v.(map[string]any)
+	if !ok {
+		return nil, false, fmt.Errorf("%w: record is not a map", ErrInvalid)
+	}
+	return record, true, nil
+}
+
+// record returns the left (bit 0) or right (bit 1) record of node
+func (r *Reader) record(node, bit uint) uint {
+	b := r.tree[node*r.recordSize/4:]
+	switch r.recordSize {
+	case 24:
+		b = b[bit*3:]
+		return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+	case 28:
+		if bit == 0 {
+			return uint(b[3]&0xF0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return uint(b[3]&0x0F)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6])
+	default:
+		return uint(binary.BigEndian.Uint32(b[bit*4:]))
+	}
+}
+
+// decoder decodes the values of a data section, pointers are offsets into buf
+type decoder struct {
+	buf []byte
+}
+
+// Types of the data section
+const (
+	typeExtended = iota
+	typePointer
+	typeString
+	typeDouble
+	typeBytes
+	typeUint16
+	typeUint32
+	typeMap
+	typeInt32
+	typeUint64
+	typeUint128
+	typeArray
+	typeContainer
+	typeEndMarker
+	typeBool
+	typeFloat
+)
+
+// decode returns the value at off and the offset following it
+func (d decoder) decode(off uint, depth int) (any, uint, error) {
+	if depth > maxDepth {
+		return nil, 0, errors.New("values nested too deep")
+	}
+
+	ctrl, off, err := d.byte(off)
+	if err != nil {
+		return nil, 0, err
+	}
+	typ := uint(ctrl >> 5)
+
+	if typ == typePointer {
+		n := uint(ctrl>>3)&3 + 1
+		b, next, err := d.bytes(off, n)
+		if err != nil {
+			return nil, 0, err
+		}
+		p := uint(0)
+		if n < 4 {
+			p = uint(ctrl & 7)
+		}
+		for _, c := range b {
+			p = p<<8 | uint(c)
+		}
+		p += [...]uint{0, 2048, 526336, 0}[n-1]
+		v, _, err := d.decode(p, depth+1)
+		return v, next, err
+	}
+
+	if typ == typeExtended {
+		var ext byte
+		if ext, off, err = d.byte(off); err != nil {
+			return nil, 0, err
+		}
+		typ = 7 + uint(ext)
+	}
+
+	size := uint(ctrl & 0x1f)
+	if size >= 29 {
+		n := size - 28
+		b, next, err := d.bytes(off, n)
+		if err != nil {
+			return nil, 0, err
+		}
+		size = 0
+		for _, c := range b {
+			size = size<<8 | uint(c)
+		}
+		size += [...]uint{29, 285, 65821}[n-1]
+		off = next
+	}
+
+	switch typ {
+	case typeMap:
+		m := make(map[string]any, size)
+		for i := uint(0); i < size; i++ {
+			k, next, err := d.decode(off, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			key, ok := k.(string)
+			if !ok {
+				return nil, 0, errors.New("map key is not a string")
+			}
+			if m[key], off, err = d.decode(next, depth+1); err != nil {
+				return nil, 0, err
+			}
+		}
+		return m, off, nil
+	case typeArray:
+		a := make([]any, 0, min(size, 1024))
+		for i := uint(0); i < size; i++ {
+			var v any
+			if v, off, err = d.decode(off, depth+1); err != nil {
+				return nil, 0, err
+			}
+			a = append(a, v)
+		}
+		return a, off, nil
+	case typeBool:
+		return size != 0, off, nil
+	}
+
+	b, next, err := d.bytes(off, size)
+	if err != nil {
+		return nil, 0, err
+	}
+	switch typ {
+	case typeString:
+		return string(b), next, nil
+	case typeBytes:
+		return bytes.Clone(b), next, nil
+	case typeDouble:
+		if size != 8 {
+			return nil, 0, fmt.Errorf("double of %d bytes", size)
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), next, nil
+	case typeFloat:
+		if size != 4 {
+			return nil, 0, fmt.Errorf("float of %d bytes", size)
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), next, nil
+	case typeUint16, typeUint32, typeUint64:
+		if size > 8 {
+			return nil, 0, fmt.Errorf("unsigned integer of %d bytes", size)
+		}
+		n := uint64(0)
+		for _, c := range b {
+			n = n<<8 | uint64(c)
+		}
+		return n, next, nil
+	case typeUint128:
+		return new(big.Int).SetBytes(b), next, nil
+	case typeInt32:
+		if size > 4 {
+			return nil, 0, fmt.Errorf("integer of %d bytes", size)
+		}
+		n := uint32(0)
+		for _, c := range b {
+			n = n<<8 | uint32(c)
+		}
+		return int64(int32(n)), next, nil
+	default:
+		return nil, 0, fmt.Errorf("unknown type %d", typ)
+	}
+}
+
+func (d decoder) byte(off uint) (byte, uint, error) {
+	if off >= uint(len(d.buf)) {
+		return 0, 0, errors.New("value past the end of the section")
+	}
+	return d.buf[off], off + 1, nil
+}
+
+func (d decoder) bytes(off, n uint) ([]byte, uint, error) {
+	if off+n > uint(len(d.buf)) || off+n < off {
+		return nil, 0, errors.New("value past the end of the section")
+	}
+	return d.buf[off : off+n], off + n, nil
+}
+
+func uintOf(v any) uint {
+	n, _ := v.(uint64)
+	return uint(n)
+}
@@ -0,0 +1,406 @@
+// Package jobs runs background work such as webhook deliveries from in-memory queues.
+// Workers take jobs from the queue with the highest priority that is below its concurrency limit,
+// a full queue rejects new jobs. Failed jobs are retried with exponential backoff, the ones out of attempts
+// are stored as dead letters admins may requeue. Queued jobs are lost on restart, dead letters are kept.
+package jobs
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/sabbatD/srest-api/internal/lib/clock"
+	"github.com/sabbatD/srest-api/internal/lib/logger/sl"
+	"github.com/sabbatD/srest-api/internal/lib/metrics"
+)
+
+// Queues the API enqueues to, created with DefaultQueue settings when the configuration leaves them out
+const (
+	QueueWebhooks = "webhooks"
+)
+
+// maxBackoff bounds the delay before a retry
+const maxBackoff = time.Hour
+
+var (
+	// ErrFull is returned by Enqueue when the queue holds its capacity
+	ErrFull = errors.New("job queue is full")
+	// ErrUnknownQueue is returned by Enqueue for a queue missing from the configuration
+	ErrUnknownQueue = errors.New("unknown job queue")
+	// ErrDisabled is returned by Enqueue when no workers are configured, the job would never run
+	ErrDisabled = errors.New("job worker is disabled")
+	// errUnknownKind fails a job no handler is registered for, it is not retried
+	errUnknownKind = errors.New("no handler for the job kind")
+)
+
+// Queue configures a queue, zero values take the DefaultQueue ones
+type Queue struct {
+	// Priority orders the queues, workers take jobs from higher ones first
+	Priority int `yaml:"priority" json:"priority"`
+	// Concurrency bounds the jobs of the queue running at once
+	Concurrency int `yaml:"concurrency" json:"concurrency"`
+	// Capacity bounds the jobs waiting in the queue, Enqueue fails with ErrFull beyond it
+	Capacity int `yaml:"capacity" json:"capacity"`
+	// MaxAttempts is the number of runs before a failing job becomes a dead letter
+	MaxAttempts int `yaml:"max_attempts" json:"maxAttempts"`
+	// Backoff is the delay before the first retry, it doubles with every further one
+	Backoff time.Duration `yaml:"backoff" json:"-"`
+}
+
+// DefaultQueue fills the settings a queue leaves out
+var DefaultQueue = Queue{Concurrency: 1, Capacity: 1000, MaxAttempts: 5, Backoff: 30 * time.Second}
+
+type Config struct {
+	// Workers is the number of jobs running at once over every queue, zero disables the worker
+	Workers int              `yaml:"workers" env-default:"4"`
+	Queues  map[string]Queue `yaml:"queues"`
+}
+
+// Handler runs a job with the payload it was enqueued with
+type Handler func(ctx context.Context, payload json.RawMessage) error
+
+// DeadJob is a job that failed every attempt
+type DeadJob struct {
+	ID    int64  `json:"id"`
+	Queue string `json:"queue"`
+	Kind  string `json:"kind"`
+	// Payload is the JSON the job was enqueued with
+	Payload  json.RawMessage `json:"payload" swaggertype:"object"`
+	Attempts int             `json:"attempts"`
+	// Error is the error of the last attempt
+	Error    string    `json:"error"`
+	FailedAt time.Time `json:"failedAt"`
+}
+
+type DeadList struct {
+	Data []DeadJob `json:"data"`
+}
+
+// QueueStats is the state of a queue on this instance
+type QueueStats struct {
+	Name string `json:"name"`
+	Queue
+	BackoffSeconds int `json:"backoffSeconds"`
+	Queued         int `json:"queued"`
+	Running        int `json:"running"`
+}
+
+type Stats struct {
+	Queues []QueueStats `json:"queues"`
+}
+
+// Store keeps the dead letters
+type Store interface {
+	AddDeadJob(ctx context.Context, job DeadJob) error
+	// DeadJobs returns the newest dead letters first
+	DeadJobs(ctx context.Context, limit int) ([]DeadJob, error)
+	// RequeueDeadJob removes the dead letter if enqueue succeeds, and records the requeue by actor in the audit log
+	RequeueDeadJob(ctx context.Context, actor int, id int64, enqueue func(DeadJob) error) error
+	// DeleteDeadJob removes the dead letter and records the deletion by actor in the audit log
+	DeleteDeadJob(ctx context.Context, actor int, id int64) error
+}
+
+type job struct {
+	kind     string
+	payload  json.RawMessage
+	attempts int
+}
+
+type queue struct {
+	name    string
+	cfg     Queue
+	jobs    []job
+	running int
+}
+
+type Worker struct {
+	log     *slog.Logger
+	store   Store
+	workers int
+
+	mu sync.Mutex
+	// ready is signalled when a job is queued or a running one finishes
+	ready *sync.Cond
+	// queues are ordered by priority, highest first
+	queues   []*queue
+	byName   map[string]*queue
+	handlers map[string]Handler
+	stopped  bool
+}
+
+func New(log *slog.Logger, store Store, cfg Config) *Worker {
+	w := &Worker{log: log, store: store, workers: cfg.Workers, byName: map[string]*queue{}, handlers: map[string]Handler{}}
+	w.ready = sync.NewCond(&w.mu)
+
+	add := func(name string, q Queue) {
+		q.Concurrency = cmp.Or(q.Concurrency, DefaultQueue.Concurrency)
+		q.Capacity = cmp.Or(q.Capacity, DefaultQueue.Capacity)
+		q.MaxAttempts = cmp.Or(q.MaxAttempts, DefaultQueue.MaxAttempts)
+		q.Backoff = cmp.Or(q.Backoff, DefaultQueue.Backoff)
+		w.queues = append(w.queues, &queue{name: name, cfg: q})
+		w.byName[name] = w.queues[len(w.queues)-1]
+	}
+	for name, q := range cfg.Queues {
+		add(name, q)
+	}
+	for _, name := range []string{QueueWebhooks} {
+		if _, ok := w.byName[name]; !ok {
+			add(name, DefaultQueue)
+		}
+	}
+	slices.SortFunc(w.queues, func(a, b *queue) int {
+		return cmp.Or(cmp.Compare(b.cfg.Priority, a.cfg.Priority), cmp.Compare(a.name, b.name))
+	})
+
+	return w
+}
+
+// Handle registers the handler of the jobs of kind, before Run
+func (w *Worker) Handle(kind string, h Handler) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.handlers[kind] = h
+}
+
+// Enqueue queues a job of kind with payload marshaled as JSON. Payloads are stored with dead letters,
+// they should name what to deliver rather than carry secrets such as webhook URLs.
+// Returns ErrFull when the queue holds its capacity, ErrUnknownQueue for an unknown queue and ErrDisabled without workers.
+func (w *Worker) Enqueue(queue, kind string, payload any) error {
+	const op = "lib.jobs.Enqueue"
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	if err := w.enqueue(queue, job{kind: kind, payload: data}); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (w *Worker) enqueue(name string, j job) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.workers <= 0 {
+		return ErrDisabled
+	}
+	q, ok := w.byName[name]
+	if !ok {
+		return fmt.Errorf("%q: %w", name, ErrUnknownQueue)
+	}
+	if len(q.jobs) >= q.cfg.Capacity {
+		metrics.JobsRejected.Add(name, 1)
+		return fmt.Errorf("%q: %w", name, ErrFull)
+	}
+	w.push(q, j)
+	metrics.JobsEnqueued.Add(name, 1)
+	return nil
+}
+
+// push queues j, the capacity must be checked, w.mu must be held
+func (w *Worker) push(q *queue, j job) {
+	q.jobs = append(q.jobs, j)
+	metrics.JobsQueued.Add(q.name, 1)
+	w.ready.Signal()
+}
+
+// Stats returns the queues by priority, highest first
+func (w *Worker) Stats() Stats {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	stats := Stats{Queues: make([]QueueStats, 0, len(w.queues))}
+	for _, q := range w.queues {
+		stats.Queues = append(stats.Queues, QueueStats{Name: q.name, Queue: q.cfg, BackoffSeconds: int(q.cfg.Backoff.Seconds()),
+			Queued: len(q.jobs), Running: q.running})
+	}
+	return stats
+}
+
+// DeadJobs returns the newest dead letters first
+func (w *Worker) DeadJobs(ctx context.Context, limit int) (DeadList, error) {
+	const op = "lib.jobs.DeadJobs"
+
+	dead, err := w.store.DeadJobs(ctx, limit)
+	if err != nil {
+		return DeadList{}, fmt.Errorf("%s: %v", op, err)
+	}
+	if dead == nil {
+		dead = []DeadJob{}
+	}
+	return DeadList{Data: dead}, nil
+}
+
+// Requeue queues the dead letter with id again with every attempt, on this instance.
+// Returns ErrFull, ErrUnknownQueue or ErrDisabled when it cannot be queued, it stays a dead letter then.
+func (w *Worker) Requeue(ctx context.Context, actor int, id int64) error {
+	const op = "lib.jobs.Requeue"
+
+	err := w.store.RequeueDeadJob(ctx, actor, id, func(d DeadJob) error {
+		return w.enqueue(d.Queue, job{kind: d.Kind, payload: d.Payload})
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// Discard deletes the dead letter with id
+func (w *Worker) Discard(ctx context.Context, actor int, id int64) error {
+	const op = "lib.jobs.Discard"
+
+	if err := w.store.DeleteDeadJob(ctx, actor, id); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// next waits for a job of the highest priority queue below its concurrency limit, false once the worker stops
+func (w *Worker) next() (*queue, job, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for !w.stopped {
+		for _, q := range w.queues {
+			if len(q.jobs) == 0 || q.running >= q.cfg.Concurrency {
+				continue
+			}
+			j := q.jobs[0]
+			q.jobs[0] = job{}
+			q.jobs = q.jobs[1:]
+			q.running++
+			metrics.JobsQueued.Add(q.name, -1)
+			return q, j, true
+		}
+		w.ready.Wait()
+	}
+	return nil, job{}, false
+}
+
+// Run processes jobs with the configured number of workers until ctx is done
+func (w *Worker) Run(ctx context.Context) {
+	const op = "lib.jobs.Run"
+
+	if w.workers <= 0 {
+		return
+	}
+	log := w.log.With(slog.String("op", op))
+
+	go func() {
+		<-ctx.Done()
+		w.mu.Lock()
+		w.stopped = true
+		w.mu.Unlock()
+		w.ready.Broadcast()
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < w.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				q, j, ok := w.next()
+				if !ok {
+					return
+				}
+				w.process(ctx, log, q, j)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// process runs j, then retries it after a backoff or stores it as a dead letter when it fails
+func (w *Worker) process(ctx context.Context, log *slog.Logger, q *queue, j job) {
+	j.attempts++
+	err := w.run(ctx, j)
+
+	w.mu.Lock()
+	q.running--
+	w.mu.Unlock()
+	// A free slot of the queue may let any worker take its next job
+	w.ready.Broadcast()
+
+	if err == nil {
+		metrics.JobsDone.Add(q.name, 1)
+		return
+	}
+	// Jobs interrupted by the shutdown are lost like the queued ones
+	if ctx.Err() != nil {
+		return
+	}
+
+	log = log.With(slog.String("queue", q.name), slog.String("kind", j.kind), slog.Int("attempt", j.attempts))
+	if j.attempts < q.cfg.MaxAttempts && !errors.Is(err, errUnknownKind) {
+		log.Warn("job failed, retrying", sl.Err(err))
+		metrics.JobsRetried.Add(q.name, 1)
+		time.AfterFunc(backoff(q.cfg.Backoff, j.attempts), func() { w.retry(ctx, log, q, j, err) })
+		return
+	}
+
+	w.bury(ctx, log, q, j, err)
+}
+
+// retry queues j again after its backoff, when the queue is full it goes to the dead letters instead
+func (w *Worker) retry(ctx context.Context, log *slog.Logger, q *queue, j job, err error) {
+	if ctx.Err() != nil {
+		return
+	}
+
+	w.mu.Lock()
+	full := len(q.jobs) >= q.cfg.Capacity
+	if !full {
+		w.push(q, j)
+	}
+	w.mu.Unlock()
+
+	if full {
+		metrics.JobsRejected.Add(q.name, 1)
+		w.bury(ctx, log, q, j, fmt.Errorf("%w, retry rejected after: %v", ErrFull, err))
+	}
+}
+
+// bury stores j that failed with err as a dead letter
+func (w *Worker) bury(ctx context.Context, log *slog.Logger, q *queue, j job, err error) {
+	log.Error("job failed, moved to the dead letters", sl.Err(err))
+	metrics.JobsDead.Add(q.name, 1)
+	metrics.JobFailures.Add(j.kind, 1)
+	dead := DeadJob{Queue: q.name, Kind: j.kind, Payload: j.payload, Attempts: j.attempts, Error: err.Error(), FailedAt: clock.Now()}
+	if err := w.store.AddDeadJob(ctx, dead); err != nil {
+		log.Error("failed to store the dead letter", sl.Err(err))
+	}
+}
+
+// run calls the handler of j, a panic fails the job
+func (w *Worker) run(ctx context.Context, j job) (err error) {
+	w.mu.Lock()
+	h, ok := w.handlers[j.kind]
+	w.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("%q: %w", j.kind, errUnknownKind)
+	}
+
+	defer func() {
+		if v := recover(); v != nil {
+			err = fmt.Errorf("panic: %v", v)
+		}
+	}()
+	return h(ctx, j.payload)
+}
+
+// backoff returns the delay before the retry following attempt
+func backoff(base time.Duration, attempt int) time.Duration {
+	d := base
+	for i := 1; i < attempt && d < maxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, maxBackoff)
+}
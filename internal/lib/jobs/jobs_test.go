@@ -0,0 +1,281 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+)
+
+type memStore struct {
+	mu   sync.Mutex
+	dead []DeadJob
+}
+
+func (m *memStore) AddDeadJob(ctx context.Context, d DeadJob) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	d.ID = int64(len(m.dead) + 1)
+	m.dead = append(m.dead, d)
+	return nil
+}
+
+func (m *memStore) DeadJobs(ctx context.Context, limit int) ([]DeadJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]DeadJob(nil), m.dead...), nil
+}
+
+func (m *memStore) RequeueDeadJob(ctx context.Context, actor int, id int64, enqueue func(DeadJob) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, d := range m.dead {
+		if d.ID == id {
+			if err := enqueue(d); err != nil {
+				return err
+			}
+			m.dead = append(m.dead[:i], m.dead[i+1:]...)
+			return nil
+		}
+	}
+	return errors.New("not found")
+}
+
+func (m *memStore) DeleteDeadJob(ctx context.Context, actor int, id int64) error {
+	return nil
+}
+
+func (m *memStore) count() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.dead)
+}
+
+func testWorker(store Store, cfg Config) *Worker {
+	return New(slog.New(slog.NewTextHandler(io.Discard, nil)), store, cfg)
+}
+
+func TestPriority(t *testing.T) {
+	w := testWorker(&memStore{}, Config{Workers: 1, Queues: map[string]Queue{
+		"low":  {Priority: 0},
+		"high": {Priority: 10},
+	}})
+
+	var mu sync.Mutex
+	var order []string
+	done := make(chan struct{}, 4)
+	w.Handle("record", func(ctx context.Context, payload json.RawMessage) error {
+		var s string
+		json.Unmarshal(payload, &s)
+		mu.Lock()
+		order = append(order, s)
+		mu.Unlock()
+		done <- struct{}{}
+		return nil
+	})
+
+	// Queued before the worker starts, the high priority jobs run first
+	for _, q := range []string{"low", "high", "low", "high"} {
+		if err := w.Enqueue(q, "record", q); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Enqueue("missing", "record", ""); !errors.Is(err, ErrUnknownQueue) {
+		t.Errorf("Enqueue() to a missing queue error = %v, want ErrUnknownQueue", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Run(ctx)
+	for i := 0; i < 4; i++ {
+		<-done
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if want := []string{"high", "high", "low", "low"}; len(order) != 4 || order[0] != want[0] || order[1] != want[1] {
+		t.Errorf("order = %v, want %v", order, want)
+	}
+}
+
+func TestBackpressure(t *testing.T) {
+	w := testWorker(&memStore{}, Config{Workers: 4, Queues: map[string]Queue{"slow": {Concurrency: 1, Capacity: 2}}})
+
+	release := make(chan struct{})
+	var mu sync.Mutex
+	running, peak := 0, 0
+	w.Handle("slow", func(ctx context.Context, payload json.RawMessage) error {
+		mu.Lock()
+		running++
+		peak = max(peak, running)
+		mu.Unlock()
+		<-release
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return nil
+	})
+
+	for i := 0; i < 2; i++ {
+		if err := w.Enqueue("slow", "slow", i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Enqueue("slow", "slow", 2); !errors.Is(err, ErrFull) {
+		t.Errorf("Enqueue() to a full queue error = %v, want ErrFull", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Run(ctx)
+
+	release <- struct{}{}
+	release <- struct{}{}
+	waitFor(t, func() bool { return w.Stats().Queues[0].Running == 0 && w.Stats().Queues[0].Queued == 0 })
+
+	mu.Lock()
+	defer mu.Unlock()
+	if peak != 1 {
+		t.Errorf("jobs running at once = %d, want the concurrency of 1", peak)
+	}
+}
+
+func TestDeadLetters(t *testing.T) {
+	store := &memStore{}
+	w := testWorker(store, Config{Workers: 1, Queues: map[string]Queue{QueueWebhooks: {MaxAttempts: 3, Backoff: time.Millisecond}}})
+
+	var mu sync.Mutex
+	attempts, fail := 0, true
+	w.Handle("deliver", func(ctx context.Context, payload json.RawMessage) error {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		if fail {
+			return errors.New("unexpected status 502")
+		}
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Run(ctx)
+
+	if err := w.Enqueue(QueueWebhooks, "deliver", map[string]string{"kind": "error_rate"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Enqueue(QueueWebhooks, "unknown", nil); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { return store.count() == 2 })
+
+	list, _ := w.DeadJobs(ctx, 10)
+	var dead DeadJob
+	for _, d := range list.Data {
+		if d.Kind == "unknown" && d.Attempts != 1 {
+			t.Errorf("job of an unknown kind = %+v, want no retries", d)
+		}
+		if d.Kind == "deliver" {
+			dead = d
+		}
+	}
+	mu.Lock()
+	if attempts != 3 || dead.Attempts != 3 || dead.Error != "unexpected status 502" || string(dead.Payload) != `{"kind":"error_rate"}` {
+		t.Errorf("attempts = %d, dead letter = %+v", attempts, dead)
+	}
+	fail = false
+	mu.Unlock()
+
+	if err := w.Requeue(ctx, 1, dead.ID); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return attempts == 4
+	})
+	if n := store.count(); n != 1 {
+		t.Errorf("dead letters after the requeue = %d, want 1", n)
+	}
+}
+
+func TestRetryFull(t *testing.T) {
+	store := &memStore{}
+	w := testWorker(store, Config{Workers: 1, Queues: map[string]Queue{QueueWebhooks: {Capacity: 1, MaxAttempts: 3, Backoff: 200 * time.Millisecond}}})
+
+	var mu sync.Mutex
+	failed := 0
+	release, started := make(chan struct{}), make(chan struct{}, 2)
+	w.Handle("fail", func(ctx context.Context, payload json.RawMessage) error {
+		mu.Lock()
+		defer mu.Unlock()
+		failed++
+		return errors.New("unexpected status 502")
+	})
+	w.Handle("block", func(ctx context.Context, payload json.RawMessage) error {
+		started <- struct{}{}
+		<-release
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	defer close(release)
+	go w.Run(ctx)
+
+	if err := w.Enqueue(QueueWebhooks, "fail", nil); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return failed == 1
+	})
+	// One job runs and one fills the queue when the retry is due
+	w.Enqueue(QueueWebhooks, "block", nil)
+	<-started
+	if err := w.Enqueue(QueueWebhooks, "block", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(t, func() bool { return store.count() == 1 })
+	dead, _ := w.DeadJobs(ctx, 10)
+	if d := dead.Data[0]; d.Kind != "fail" || d.Attempts != 1 {
+		t.Errorf("dead letter = %+v, want the retry rejected by the full queue", d)
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	w := testWorker(&memStore{}, Config{Workers: 0})
+	if err := w.Enqueue(QueueWebhooks, "deliver", nil); !errors.Is(err, ErrDisabled) {
+		t.Errorf("Enqueue() without workers error = %v, want ErrDisabled", err)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for _, tt := range []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, time.Second},
+		{3, 4 * time.Second},
+		{40, maxBackoff},
+	} {
+		if got := backoff(time.Second, tt.attempt); got != tt.want {
+			t.Errorf("backoff(1s, %d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if cond() {
+			return
+		}
+	}
+	t.Fatal("timed out waiting")
+}
@@ -0,0 +1,22 @@
+package keyConfig
+
+import "time"
+
+// SigningKey is a JWT signing key created by a rotation. Key is the secret of an HS256 key, base64url encoded,
+// or the PEM private key of an RS256 or ES256 one. The active key has no Retired time.
+type SigningKey struct {
+	ID        string
+	Algorithm string
+	Key       string
+	Created   time.Time
+	Retired   *time.Time
+}
+
+// Rotation is the result of a key rotation: the key now signing tokens and the key it replaced,
+// whose tokens keep verifying until VerifiesUntil
+type Rotation struct {
+	Kid           string    `json:"kid"`
+	Algorithm     string    `json:"algorithm"`
+	RetiredKid    string    `json:"retiredKid"`
+	VerifiesUntil time.Time `json:"verifiesUntil"`
+}
@@ -0,0 +1,149 @@
+package ldap
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Just enough BER for LDAPv3 (RFC 4511): single byte tags and definite lengths.
+
+const (
+	tagBoolean     = 0x01
+	tagInteger     = 0x02
+	tagOctetString = 0x04
+	tagEnumerated  = 0x0a
+	tagSequence    = 0x30
+	tagSet         = 0x31
+
+	// maxPacket bounds a message read from the server
+	maxPacket = 16 << 20
+)
+
+var errMalformed = errors.New("malformed BER")
+
+type element struct {
+	tag     byte
+	content []byte
+}
+
+func tlv(tag byte, content ...[]byte) []byte {
+	n := 0
+	for _, c := range content {
+		n += len(c)
+	}
+	out := append([]byte{tag}, berLength(n)...)
+	for _, c := range content {
+		out = append(out, c...)
+	}
+	return out
+}
+
+func berLength(n int) []byte {
+	if n < 0x80 {
+		return []byte{byte(n)}
+	}
+	var b []byte
+	for ; n > 0; n >>= 8 {
+		b = append([]byte{byte(n)}, b...)
+	}
+	return append([]byte{0x80 | byte(len(b))}, b...)
+}
+
+func berInt(tag byte, v int) []byte {
+	b := []byte{byte(v)}
+	for v >>= 8; v > 0; v >>= 8 {
+		b = append([]byte{byte(v)}, b...)
+	}
+	if b[0]&0x80 != 0 {
+		b = append([]byte{0}, b...)
+	}
+	return tlv(tag, b)
+}
+
+func berString(tag byte, s string) []byte {
+	return tlv(tag, []byte(s))
+}
+
+func berBool(v bool) []byte {
+	if v {
+		return tlv(tagBoolean, []byte{0xff})
+	}
+	return tlv(tagBoolean, []byte{0})
+}
+
+// readElement reads one element from r
+func readElement(r *bufio.Reader) (element, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return element{}, err
+	}
+	n, err := r.ReadByte()
+	if err != nil {
+		return element{}, err
+	}
+
+	size := int(n)
+	if n&0x80 != 0 {
+		count := int(n &^ 0x80)
+		if count == 0 || count > 4 {
+			return element{}, fmt.Errorf("length of %d bytes: %w", count, errMalformed)
+		}
+		size = 0
+		for i := 0; i < count; i++ {
+			b, err := r.ReadByte()
+			if err != nil {
+				return element{}, err
+			}
+			size = size<<8 | int(b)
+		}
+	}
+	if size > maxPacket {
+		return element{}, fmt.Errorf("message of %d bytes: %w", size, errMalformed)
+	}
+
+	content := make([]byte, size)
+	if _, err := io.ReadFull(r, content); err != nil {
+		return element{}, err
+	}
+	return element{tag: tag, content: content}, nil
+}
+
+// children splits the content of a constructed element
+func (e element) children() ([]element, error) {
+	var out []element
+	data := e.content
+	for len(data) > 0 {
+		if len(data) < 2 {
+			return nil, errMalformed
+		}
+		tag, n := data[0], int(data[1])
+		data = data[2:]
+		if n&0x80 != 0 {
+			count := n &^ 0x80
+			if count == 0 || count > 4 || len(data) < count {
+				return nil, errMalformed
+			}
+			n = 0
+			for _, b := range data[:count] {
+				n = n<<8 | int(b)
+			}
+			data = data[count:]
+		}
+		if n > len(data) {
+			return nil, errMalformed
+		}
+		out = append(out, element{tag: tag, content: data[:n]})
+		data = data[n:]
+	}
+	return out, nil
+}
+
+func (e element) int() int {
+	v := 0
+	for _, b := range e.content {
+		v = v<<8 | int(b)
+	}
+	return v
+}
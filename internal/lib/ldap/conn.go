@@ -0,0 +1,268 @@
+package ldap
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// LDAP message tags
+const (
+	appBindRequest    = 0x60
+	appBindResponse   = 0x61
+	appUnbindRequest  = 0x42
+	appSearchRequest  = 0x63
+	appSearchEntry    = 0x64
+	appSearchDone     = 0x65
+	appSearchRef      = 0x73
+	ctxSimpleAuth     = 0x80
+	ctxEqualityFilter = 0xa3
+)
+
+// Result codes
+const (
+	resultSuccess            = 0
+	resultInvalidCredentials = 49
+)
+
+// ResultError is a non-success LDAP result
+type ResultError struct {
+	Code    int
+	Message string
+}
+
+func (e *ResultError) Error() string {
+	return fmt.Sprintf("ldap result %d: %s", e.Code, e.Message)
+}
+
+// Entry is a search result, attribute names are lower case
+type Entry struct {
+	DN    string
+	Attrs map[string][]string
+}
+
+// Get returns the first value of the attribute
+func (e Entry) Get(attr string) string {
+	if v := e.Attrs[strings.ToLower(attr)]; len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// Conn is a connection to an LDAP server, it is not safe for concurrent use
+type Conn struct {
+	conn  net.Conn
+	r     *bufio.Reader
+	msgID int
+}
+
+// Dial connects to an ldap:// or ldaps:// URL, the connection is bound to ctx's deadline
+func Dial(ctx context.Context, rawURL string) (*Conn, error) {
+	const op = "lib.ldap.Dial"
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	host := u.Host
+	var conn net.Conn
+	switch u.Scheme {
+	case "ldap":
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "389")
+		}
+		conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", host)
+	case "ldaps":
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "636")
+		}
+		d := &tls.Dialer{Config: &tls.Config{ServerName: u.Hostname(), MinVersion: tls.VersionTLS12}}
+		conn, err = d.DialContext(ctx, "tcp", host)
+	default:
+		return nil, fmt.Errorf("%s: unsupported scheme %q", op, u.Scheme)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	return &Conn{conn: conn, r: bufio.NewReader(conn)}, nil
+}
+
+// Close unbinds and closes the connection
+func (c *Conn) Close() error {
+	c.send(tlv(appUnbindRequest))
+	return c.conn.Close()
+}
+
+// Bind authenticates the connection with a simple bind. An empty password would be
+// an unauthenticated bind that servers accept for any DN, so it is refused here.
+func (c *Conn) Bind(dn, password string) error {
+	const op = "lib.ldap.Bind"
+
+	if password == "" {
+		return fmt.Errorf("%s: %w", op, &ResultError{Code: resultInvalidCredentials, Message: "empty password"})
+	}
+
+	id, err := c.send(tlv(appBindRequest, berInt(tagInteger, 3), berString(tagOctetString, dn), berString(ctxSimpleAuth, password)))
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	resp, err := c.receive(id)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	if resp.tag != appBindResponse {
+		return fmt.Errorf("%s: unexpected response 0x%x: %w", op, resp.tag, errMalformed)
+	}
+	if err := result(resp); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// Search returns the entries below base whose attr equals value, with the given attributes
+func (c *Conn) Search(base, attr, value string, attrs []string) ([]Entry, error) {
+	const op = "lib.ldap.Search"
+
+	var list []byte
+	for _, a := range attrs {
+		list = append(list, berString(tagOctetString, a)...)
+	}
+
+	id, err := c.send(tlv(appSearchRequest,
+		berString(tagOctetString, base),
+		berInt(tagEnumerated, 2), // whole subtree
+		berInt(tagEnumerated, 0), // never dereference aliases
+		berInt(tagInteger, 2),    // two entries are enough to tell an ambiguous login
+		berInt(tagInteger, 0),
+		berBool(false),
+		tlv(ctxEqualityFilter, berString(tagOctetString, attr), berString(tagOctetString, value)),
+		tlv(tagSequence, list),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	var entries []Entry
+	for {
+		msg, err := c.receive(id)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+
+		switch msg.tag {
+		case appSearchEntry:
+			e, err := parseEntry(msg)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", op, err)
+			}
+			entries = append(entries, e)
+		case appSearchRef:
+			// Referrals to other servers are not followed.
+		case appSearchDone:
+			if err := result(msg); err != nil {
+				return nil, fmt.Errorf("%s: %w", op, err)
+			}
+			return entries, nil
+		default:
+			return nil, fmt.Errorf("%s: unexpected response 0x%x: %w", op, msg.tag, errMalformed)
+		}
+	}
+}
+
+func (c *Conn) send(protocolOp []byte) (int, error) {
+	c.msgID++
+	_, err := c.conn.Write(tlv(tagSequence, berInt(tagInteger, c.msgID), protocolOp))
+	return c.msgID, err
+}
+
+// receive returns the protocol op of the next message, which must answer id
+func (c *Conn) receive(id int) (element, error) {
+	msg, err := readElement(c.r)
+	if err != nil {
+		if ne, ok := err.(net.Error); ok && ne.Timeout() {
+			return element{}, fmt.Errorf("ldap server timed out: %w", context.DeadlineExceeded)
+		}
+		return element{}, err
+	}
+	if msg.tag != tagSequence {
+		return element{}, errMalformed
+	}
+
+	parts, err := msg.children()
+	if err != nil {
+		return element{}, err
+	}
+	if len(parts) < 2 || parts[0].tag != tagInteger {
+		return element{}, errMalformed
+	}
+	if got := parts[0].int(); got != id {
+		return element{}, fmt.Errorf("response to message %d, want %d: %w", got, id, errMalformed)
+	}
+	return parts[1], nil
+}
+
+// result returns the error of an LDAPResult, nil for success
+func result(e element) error {
+	parts, err := e.children()
+	if err != nil {
+		return err
+	}
+	if len(parts) < 3 || parts[0].tag != tagEnumerated {
+		return errMalformed
+	}
+	if code := parts[0].int(); code != resultSuccess {
+		return &ResultError{Code: code, Message: string(parts[2].content)}
+	}
+	return nil
+}
+
+func parseEntry(e element) (Entry, error) {
+	parts, err := e.children()
+	if err != nil {
+		return Entry{}, err
+	}
+	if len(parts) != 2 {
+		return Entry{}, errMalformed
+	}
+
+	entry := Entry{DN: string(parts[0].content), Attrs: make(map[string][]string)}
+	attrs, err := parts[1].children()
+	if err != nil {
+		return Entry{}, err
+	}
+	for _, a := range attrs {
+		kv, err := a.children()
+		if err != nil {
+			return Entry{}, err
+		}
+		if len(kv) != 2 {
+			return Entry{}, errMalformed
+		}
+		vals, err := kv[1].children()
+		if err != nil {
+			return Entry{}, err
+		}
+		name := strings.ToLower(string(kv[0].content))
+		for _, v := range vals {
+			entry.Attrs[name] = append(entry.Attrs[name], string(v.content))
+		}
+	}
+	return entry, nil
+}
+
+// IsInvalidCredentials tells whether err is a rejected bind
+func IsInvalidCredentials(err error) bool {
+	var re *ResultError
+	return errors.As(err, &re) && re.Code == resultInvalidCredentials
+}
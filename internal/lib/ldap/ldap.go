@@ -0,0 +1,133 @@
+// Package ldap authenticates users against an LDAP or Active Directory server.
+// It speaks just the part of LDAPv3 needed for that: simple bind and an equality search.
+package ldap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Source is the auth source of accounts provisioned from the directory
+const Source = "ldap"
+
+var (
+	// ErrInvalidCredentials is returned for a wrong password
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	// ErrUnknownUser is returned when no directory entry has the login
+	ErrUnknownUser = errors.New("unknown user")
+)
+
+// Config of the directory, configured by environment only. An empty URL disables it.
+type Config struct {
+	// URL is ldap://host[:port] or ldaps://host[:port]
+	URL string `env:"LDAP_URL"`
+	// BindDN and BindPassword are the service account searching for users, anonymous when empty
+	BindDN       string `env:"LDAP_BIND_DN"`
+	BindPassword string `env:"LDAP_BIND_PASSWORD" redact:"true"`
+	BaseDN       string `env:"LDAP_BASE_DN"`
+	// UserAttr holds the login, sAMAccountName for Active Directory
+	UserAttr     string `env:"LDAP_USER_ATTR" env-default:"uid"`
+	EmailAttr    string `env:"LDAP_EMAIL_ATTR" env-default:"mail"`
+	UsernameAttr string `env:"LDAP_USERNAME_ATTR" env-default:"displayName"`
+	// AdminGroup is the DN of the group whose members are admins, read from memberOf.
+	// Everybody else is a regular user, the role is updated on every sign in.
+	AdminGroup string        `env:"LDAP_ADMIN_GROUP"`
+	Timeout    time.Duration `env:"LDAP_TIMEOUT" env-default:"5s"`
+}
+
+// Identity is a user authenticated by the directory
+type Identity struct {
+	DN       string
+	Login    string
+	Username string
+	Email    string
+	IsAdmin  bool
+}
+
+type Directory struct {
+	cfg Config
+}
+
+func New(cfg Config) *Directory {
+	return &Directory{cfg: cfg}
+}
+
+// Authenticate looks the login up and binds as its entry with password
+func (d *Directory) Authenticate(ctx context.Context, login, password string) (Identity, error) {
+	const op = "lib.ldap.Authenticate"
+
+	if password == "" {
+		return Identity{}, fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, d.cfg.Timeout)
+	defer cancel()
+
+	conn, err := Dial(ctx, d.cfg.URL)
+	if err != nil {
+		return Identity{}, fmt.Errorf("%s: %v", op, err)
+	}
+	defer conn.Close()
+
+	if d.cfg.BindDN != "" {
+		if err := conn.Bind(d.cfg.BindDN, d.cfg.BindPassword); err != nil {
+			return Identity{}, fmt.Errorf("%s: service account: %v", op, err)
+		}
+	}
+
+	entries, err := conn.Search(d.cfg.BaseDN, d.cfg.UserAttr, login, []string{d.cfg.UserAttr, d.cfg.EmailAttr, d.cfg.UsernameAttr, "cn", "memberOf"})
+	if err != nil {
+		return Identity{}, fmt.Errorf("%s: %v", op, err)
+	}
+	switch len(entries) {
+	case 0:
+		return Identity{}, fmt.Errorf("%s: %w", op, ErrUnknownUser)
+	case 1:
+	default:
+		return Identity{}, fmt.Errorf("%s: %d entries have the login %q", op, len(entries), login)
+	}
+	entry := entries[0]
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if IsInvalidCredentials(err) {
+			return Identity{}, fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+		}
+		return Identity{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	id := Identity{DN: entry.DN, Login: login, Username: entry.Get(d.cfg.UsernameAttr), Email: entry.Get(d.cfg.EmailAttr)}
+	// The directory's spelling of the login keeps one account however the user types it.
+	if v := entry.Get(d.cfg.UserAttr); v != "" {
+		id.Login = v
+	}
+	if id.Email == "" {
+		return Identity{}, fmt.Errorf("%s: %s has no %s", op, entry.DN, d.cfg.EmailAttr)
+	}
+	if id.Username == "" {
+		id.Username = entry.Get("cn")
+	}
+	if id.Username == "" {
+		id.Username = login
+	}
+	if d.cfg.AdminGroup != "" {
+		for _, group := range entry.Attrs["memberof"] {
+			if strings.EqualFold(normalizeDN(group), normalizeDN(d.cfg.AdminGroup)) {
+				id.IsAdmin = true
+			}
+		}
+	}
+
+	return id, nil
+}
+
+// normalizeDN drops the spaces around RDN separators, servers differ in writing them
+func normalizeDN(dn string) string {
+	parts := strings.Split(dn, ",")
+	for i, p := range parts {
+		parts[i] = strings.TrimSpace(p)
+	}
+	return strings.Join(parts, ",")
+}
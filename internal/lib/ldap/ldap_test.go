@@ -0,0 +1,161 @@
+package ldap
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+type fakeEntry struct {
+	dn       string
+	password string
+	attrs    map[string][]string
+}
+
+// fakeServer answers binds and equality searches on uid from entries
+func fakeServer(t *testing.T, entries []fakeEntry) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveFake(conn, entries)
+		}
+	}()
+
+	return "ldap://" + ln.Addr().String()
+}
+
+func serveFake(conn net.Conn, entries []fakeEntry) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	for {
+		msg, err := readElement(r)
+		if err != nil {
+			return
+		}
+		parts, err := msg.children()
+		if err != nil || len(parts) < 2 {
+			return
+		}
+		id, req := parts[0].int(), parts[1]
+		reply := func(op []byte) { conn.Write(tlv(tagSequence, berInt(tagInteger, id), op)) }
+		done := func(tag byte, code int) {
+			reply(tlv(tag, berInt(tagEnumerated, code), berString(tagOctetString, ""), berString(tagOctetString, "")))
+		}
+
+		fields, _ := req.children()
+		switch req.tag {
+		case appBindRequest:
+			dn, password := string(fields[1].content), string(fields[2].content)
+			code := resultInvalidCredentials
+			for _, e := range entries {
+				if e.dn == dn && e.password == password {
+					code = resultSuccess
+				}
+			}
+			done(appBindResponse, code)
+		case appSearchRequest:
+			filter, _ := fields[6].children()
+			value := string(filter[1].content)
+			for _, e := range entries {
+				if len(e.attrs["uid"]) == 0 || e.attrs["uid"][0] != value {
+					continue
+				}
+				var attrs []byte
+				for name, vals := range e.attrs {
+					var set []byte
+					for _, v := range vals {
+						set = append(set, berString(tagOctetString, v)...)
+					}
+					attrs = append(attrs, tlv(tagSequence, berString(tagOctetString, name), tlv(tagSet, set))...)
+				}
+				reply(tlv(appSearchEntry, berString(tagOctetString, e.dn), tlv(tagSequence, attrs)))
+			}
+			done(appSearchDone, resultSuccess)
+		default:
+			return
+		}
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	admins := "cn=admins,ou=groups,dc=example,dc=com"
+	url := fakeServer(t, []fakeEntry{
+		{dn: "cn=service,dc=example,dc=com", password: "secret"},
+		{dn: "uid=alice,ou=people,dc=example,dc=com", password: "wonderland", attrs: map[string][]string{
+			"uid": {"alice"}, "mail": {"alice@example.com"}, "displayName": {"Alice"}, "memberOf": {"cn=admins, ou=groups, dc=example, dc=com"},
+		}},
+		{dn: "uid=bob,ou=people,dc=example,dc=com", password: "builder", attrs: map[string][]string{
+			"uid": {"bob"}, "mail": {"bob@example.com"}, "cn": {"Bob B"},
+		}},
+		{dn: "uid=carol,ou=people,dc=example,dc=com", password: "nomail", attrs: map[string][]string{"uid": {"carol"}}},
+	})
+
+	d := New(Config{
+		URL: url, BindDN: "cn=service,dc=example,dc=com", BindPassword: "secret", BaseDN: "dc=example,dc=com",
+		UserAttr: "uid", EmailAttr: "mail", UsernameAttr: "displayName", AdminGroup: admins, Timeout: time.Second,
+	})
+	ctx := context.Background()
+
+	alice, err := d.Authenticate(ctx, "alice", "wonderland")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Identity{DN: "uid=alice,ou=people,dc=example,dc=com", Login: "alice", Username: "Alice", Email: "alice@example.com", IsAdmin: true}
+	if alice != want {
+		t.Errorf("alice = %+v, want %+v", alice, want)
+	}
+
+	bob, err := d.Authenticate(ctx, "bob", "builder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bob.IsAdmin || bob.Username != "Bob B" {
+		t.Errorf("bob = %+v, want a user named after cn", bob)
+	}
+
+	if _, err := d.Authenticate(ctx, "alice", "wrong"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("wrong password: err = %v, want ErrInvalidCredentials", err)
+	}
+	if _, err := d.Authenticate(ctx, "alice", ""); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("empty password: err = %v, want ErrInvalidCredentials", err)
+	}
+	if _, err := d.Authenticate(ctx, "mallory", "x"); !errors.Is(err, ErrUnknownUser) {
+		t.Errorf("unknown login: err = %v, want ErrUnknownUser", err)
+	}
+	if _, err := d.Authenticate(ctx, "carol", "nomail"); err == nil {
+		t.Error("entry without mail authenticated")
+	}
+
+	d.cfg.BindPassword = "wrong"
+	if _, err := d.Authenticate(ctx, "alice", "wonderland"); err == nil || errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("rejected service account: err = %v, want a non credential error", err)
+	}
+}
+
+func TestBERLength(t *testing.T) {
+	for _, n := range []int{0, 1, 127, 128, 255, 256, 70000} {
+		data := make([]byte, n)
+		e, err := element{content: tlv(tagOctetString, data)}.children()
+		if err != nil {
+			t.Fatalf("length %d: %v", n, err)
+		}
+		if len(e) != 1 || len(e[0].content) != n {
+			t.Errorf("length %d: decoded %d elements", n, len(e))
+		}
+	}
+}
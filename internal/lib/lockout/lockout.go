@@ -0,0 +1,87 @@
+// Package lockout stops brute-force attacks on sign in: after repeated failures a login is locked
+// for the client address they came from, so an attacker cannot lock the owner out from elsewhere.
+// IPv6 clients are locked by their /64 network, see access.IPKey.
+package lockout
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sabbatD/srest-api/internal/lib/clock"
+)
+
+// Config locks a login after MaxFailures failed sign ins within Window, until the window
+// holds fewer. A zero MaxFailures disables the lockout.
+type Config struct {
+	MaxFailures int           `yaml:"max_failures" env-default:"5"`
+	Window      time.Duration `yaml:"window" env-default:"15m"`
+}
+
+type Store interface {
+	RecordFailedLogin(ctx context.Context, login, ip string, at, expired time.Time) error
+	FailedLogins(ctx context.Context, login, ip string, since time.Time, limit int) ([]time.Time, error)
+	ClearFailedLogins(ctx context.Context, login, ip string) error
+}
+
+// Guard tracks the failed sign ins in the store, so every instance sees them
+type Guard struct {
+	store Store
+	cfg   Config
+}
+
+func New(store Store, cfg Config) *Guard {
+	return &Guard{store: store, cfg: cfg}
+}
+
+// Locked returns until when login is locked for ip, the zero time when it is not.
+// Attempts while locked are not recorded, the lock ends when the oldest of the failures leaves the window.
+func (g *Guard) Locked(ctx context.Context, login, ip string) (time.Time, error) {
+	const op = "lockout.Locked"
+
+	if g.cfg.MaxFailures <= 0 {
+		return time.Time{}, nil
+	}
+
+	now := clock.Now()
+	failures, err := g.store.FailedLogins(ctx, login, ip, now.Add(-g.cfg.Window), g.cfg.MaxFailures)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s: %v", op, err)
+	}
+	if len(failures) < g.cfg.MaxFailures {
+		return time.Time{}, nil
+	}
+
+	return failures[len(failures)-1].Add(g.cfg.Window), nil
+}
+
+// Fail records a failed sign in of login from ip
+func (g *Guard) Fail(ctx context.Context, login, ip string) error {
+	const op = "lockout.Fail"
+
+	if g.cfg.MaxFailures <= 0 {
+		return nil
+	}
+
+	now := clock.Now()
+	if err := g.store.RecordFailedLogin(ctx, login, ip, now, now.Add(-g.cfg.Window)); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// Succeed forgets the failures of login from ip once it signed in
+func (g *Guard) Succeed(ctx context.Context, login, ip string) error {
+	const op = "lockout.Succeed"
+
+	if g.cfg.MaxFailures <= 0 {
+		return nil
+	}
+
+	if err := g.store.ClearFailedLogins(ctx, login, ip); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
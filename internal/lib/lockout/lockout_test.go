@@ -0,0 +1,84 @@
+package lockout
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sabbatD/srest-api/internal/lib/clock"
+)
+
+type memStore struct {
+	failures map[string][]time.Time
+}
+
+func (m *memStore) RecordFailedLogin(_ context.Context, login, ip string, at, expired time.Time) error {
+	k := login + "@" + ip
+	m.failures[k] = append(m.failures[k], at)
+	return nil
+}
+
+func (m *memStore) FailedLogins(_ context.Context, login, ip string, since time.Time, limit int) ([]time.Time, error) {
+	var got []time.Time
+	failures := m.failures[login+"@"+ip]
+	for i := len(failures) - 1; i >= 0 && len(got) < limit; i-- {
+		if failures[i].After(since) {
+			got = append(got, failures[i])
+		}
+	}
+	return got, nil
+}
+
+func (m *memStore) ClearFailedLogins(_ context.Context, login, ip string) error {
+	delete(m.failures, login+"@"+ip)
+	return nil
+}
+
+func TestGuard(t *testing.T) {
+	t0 := time.Date(2024, 11, 3, 12, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(t0)
+	t.Cleanup(clock.Set(fake))
+	ctx := context.Background()
+
+	g := New(&memStore{failures: make(map[string][]time.Time)}, Config{MaxFailures: 3, Window: 15 * time.Minute})
+
+	for i := 0; i < 3; i++ {
+		if until, _ := g.Locked(ctx, "victim", "192.0.2.1"); !until.IsZero() {
+			t.Fatalf("locked after %d failures", i)
+		}
+		g.Fail(ctx, "victim", "192.0.2.1")
+		fake.Advance(time.Minute)
+	}
+
+	// The first failure leaves the window 15 minutes after it happened
+	until, err := g.Locked(ctx, "victim", "192.0.2.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := t0.Add(15 * time.Minute); !until.Equal(want) {
+		t.Errorf("Locked() = %v, want %v", until, want)
+	}
+	if until, _ := g.Locked(ctx, "victim", "192.0.2.2"); !until.IsZero() {
+		t.Errorf("locked for another address until %v", until)
+	}
+
+	fake.Set(t0.Add(15 * time.Minute))
+	if until, _ := g.Locked(ctx, "victim", "192.0.2.1"); !until.IsZero() {
+		t.Errorf("still locked until %v after the window", until)
+	}
+
+	g.Fail(ctx, "victim", "192.0.2.1")
+	g.Succeed(ctx, "victim", "192.0.2.1")
+	g.Fail(ctx, "victim", "192.0.2.1")
+	if until, _ := g.Locked(ctx, "victim", "192.0.2.1"); !until.IsZero() {
+		t.Errorf("failures before a sign in still count, locked until %v", until)
+	}
+
+	off := New(&memStore{failures: make(map[string][]time.Time)}, Config{})
+	for i := 0; i < 10; i++ {
+		off.Fail(ctx, "victim", "192.0.2.1")
+	}
+	if until, _ := off.Locked(ctx, "victim", "192.0.2.1"); !until.IsZero() {
+		t.Errorf("disabled lockout locked until %v", until)
+	}
+}
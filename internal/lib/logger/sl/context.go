@@ -0,0 +1,54 @@
+package sl
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+type ctxKey struct{}
+
+// NewContext returns a copy of ctx carrying log
+func NewContext(ctx context.Context, log *slog.Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, log)
+}
+
+// FromContext returns the request scoped logger carried by ctx,
+// or slog.Default() when there is none, e.g. outside of a request.
+func FromContext(ctx context.Context) *slog.Logger {
+	if log, ok := ctx.Value(ctxKey{}).(*slog.Logger); ok {
+		return log
+	}
+	return slog.Default()
+}
+
+// Ensure returns ctx unchanged when it carries a logger, otherwise a copy carrying log.
+func Ensure(ctx context.Context, log *slog.Logger) context.Context {
+	if _, ok := ctx.Value(ctxKey{}).(*slog.Logger); ok {
+		return ctx
+	}
+	return NewContext(ctx, log)
+}
+
+// With returns a copy of ctx whose logger has args added
+func With(ctx context.Context, args ...any) context.Context {
+	return NewContext(ctx, FromContext(ctx).With(args...))
+}
+
+// Middleware puts log with the request id, method and path into the request context.
+// Later middlewares add to it with With, handlers and storage read it with FromContext.
+// It must run after middleware.RequestID.
+func Middleware(log *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := NewContext(r.Context(), log.With(
+				slog.String("request_id", middleware.GetReqID(r.Context())),
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+			))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
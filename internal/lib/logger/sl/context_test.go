@@ -0,0 +1,49 @@
+package sl
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+func TestMiddleware(tt *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	h := middleware.RequestID(Middleware(log)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := With(r.Context(), slog.Int("user_id", 7))
+		FromContext(ctx).Info("deep call site")
+	})))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/todos", nil))
+
+	var rec map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		tt.Fatal(err)
+	}
+	if rec["request_id"] == "" || rec["request_id"] == nil {
+		tt.Errorf("request_id is missing: %v", rec)
+	}
+	if rec["method"] != "POST" || rec["path"] != "/todos" || rec["user_id"] != float64(7) {
+		tt.Errorf("record = %v", rec)
+	}
+}
+
+func TestEnsure(tt *testing.T) {
+	log := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	other := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if got := FromContext(req.Context()); got != slog.Default() {
+		tt.Errorf("FromContext without a logger = %p, want slog.Default()", got)
+	}
+
+	ctx := Ensure(req.Context(), log)
+	if got := FromContext(Ensure(ctx, other)); got != log {
+		tt.Errorf("Ensure replaced the request logger")
+	}
+}
@@ -0,0 +1,130 @@
+package sl
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Record is a log record kept by Recent, attributes of groups are keyed by their dotted path
+type Record struct {
+	Time    time.Time         `json:"time"`
+	Level   string            `json:"level"`
+	Message string            `json:"message"`
+	Attrs   map[string]string `json:"attrs,omitempty"`
+}
+
+// Recent keeps the last records at or above a level in memory, e.g. the recent errors for a support bundle.
+// Its Handler wraps the handler the records are written to.
+type Recent struct {
+	level slog.Level
+
+	mu      sync.Mutex
+	records []Record
+	next    int
+	full    bool
+}
+
+// NewRecent keeps the last size records at level or above
+func NewRecent(size int, level slog.Level) *Recent {
+	return &Recent{level: level, records: make([]Record, size)}
+}
+
+// Handler returns a handler writing to next and keeping the records at the level of r
+func (r *Recent) Handler(next slog.Handler) slog.Handler {
+	return &recentHandler{next: next, recent: r}
+}
+
+// Records returns the kept records, oldest first
+func (r *Recent) Records() []Record {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.full {
+		return append([]Record{}, r.records[:r.next]...)
+	}
+	return append(append([]Record{}, r.records[r.next:]...), r.records[:r.next]...)
+}
+
+func (r *Recent) add(rec Record) {
+	if len(r.records) == 0 {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.records[r.next] = rec
+	r.next++
+	if r.next == len(r.records) {
+		r.next = 0
+		r.full = true
+	}
+}
+
+type recentHandler struct {
+	next   slog.Handler
+	recent *Recent
+	// attrs are those added with WithAttrs, keyed with the groups open at the time
+	attrs  map[string]string
+	prefix string
+}
+
+func (h *recentHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.recent.level || h.next.Enabled(ctx, level)
+}
+
+func (h *recentHandler) Handle(ctx context.Context, rec slog.Record) error {
+	if rec.Level >= h.recent.level {
+		attrs := make(map[string]string, len(h.attrs)+rec.NumAttrs())
+		for k, v := range h.attrs {
+			attrs[k] = v
+		}
+		rec.Attrs(func(a slog.Attr) bool {
+			addAttr(attrs, h.prefix, a)
+			return true
+		})
+		h.recent.add(Record{Time: rec.Time, Level: rec.Level.String(), Message: rec.Message, Attrs: attrs})
+	}
+
+	if !h.next.Enabled(ctx, rec.Level) {
+		return nil
+	}
+	return h.next.Handle(ctx, rec)
+}
+
+func (h *recentHandler) WithAttrs(as []slog.Attr) slog.Handler {
+	attrs := make(map[string]string, len(h.attrs)+len(as))
+	for k, v := range h.attrs {
+		attrs[k] = v
+	}
+	for _, a := range as {
+		addAttr(attrs, h.prefix, a)
+	}
+	return &recentHandler{next: h.next.WithAttrs(as), recent: h.recent, attrs: attrs, prefix: h.prefix}
+}
+
+func (h *recentHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &recentHandler{next: h.next.WithGroup(name), recent: h.recent, attrs: h.attrs, prefix: h.prefix + name + "."}
+}
+
+func addAttr(attrs map[string]string, prefix string, a slog.Attr) {
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range v.Group() {
+			addAttr(attrs, prefix, ga)
+		}
+		return
+	}
+	if a.Key == "" {
+		return
+	}
+	attrs[prefix+a.Key] = v.String()
+}
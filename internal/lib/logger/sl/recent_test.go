@@ -0,0 +1,35 @@
+package sl
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestRecent(tt *testing.T) {
+	var buf bytes.Buffer
+	recent := NewRecent(2, slog.LevelError)
+	log := slog.New(recent.Handler(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo})))
+
+	log.Debug("not written")
+	log.Info("written, not kept")
+	log.With(slog.String("op", "first")).Error("first")
+	log.WithGroup("req").Error("second", slog.Int("status", 500))
+	log.Error("third", slog.Group("db", slog.String("table", "todos")))
+
+	if strings.Contains(buf.String(), "not written") || !strings.Contains(buf.String(), "written, not kept") {
+		tt.Errorf("the wrapped handler's level is not kept: %s", buf.String())
+	}
+
+	records := recent.Records()
+	if len(records) != 2 {
+		tt.Fatalf("records = %v, want the last 2", records)
+	}
+	if records[0].Message != "second" || records[0].Attrs["req.status"] != "500" {
+		tt.Errorf("records[0] = %+v", records[0])
+	}
+	if records[1].Message != "third" || records[1].Attrs["db.table"] != "todos" || records[1].Level != "ERROR" {
+		tt.Errorf("records[1] = %+v", records[1])
+	}
+}
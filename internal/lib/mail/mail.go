@@ -0,0 +1,100 @@
+// Package mail sends plain text email through an SMTP server,
+// rendered from built-in templates admins may replace, see Templates.
+package mail
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sabbatD/srest-api/internal/lib/secrets"
+)
+
+// Config is the SMTP server mail is sent through, an empty host disables sending
+type Config struct {
+	Host     string `yaml:"host" env:"SMTP_HOST"`
+	Port     int    `yaml:"port" env:"SMTP_PORT" env-default:"587"`
+	Username string `yaml:"-" env:"SMTP_USERNAME"`
+	Password string `yaml:"-" env:"SMTP_PASSWORD" redact:"true"`
+	From     string `yaml:"from" env:"SMTP_FROM" env-default:"sapi@localhost"`
+}
+
+type sendFunc func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+
+type Mailer struct {
+	cfg  Config
+	send sendFunc
+	// secrets may hold the password set by an admin, it overrides the configured one
+	secrets *secrets.Secrets
+}
+
+func New(cfg Config) *Mailer {
+	return &Mailer{cfg: cfg, send: smtp.SendMail}
+}
+
+// SetSecrets makes the SMTP password stored in s, when set, override the configured one
+func (m *Mailer) SetSecrets(s *secrets.Secrets) {
+	m.secrets = s
+}
+
+// Enabled reports whether an SMTP server is configured
+func (m *Mailer) Enabled() bool {
+	return m != nil && m.cfg.Host != ""
+}
+
+// Send sends a plain text message to every address in to. smtp.SendMail takes no context,
+// ctx is only checked before sending.
+func (m *Mailer) Send(ctx context.Context, to []string, subject, body string) error {
+	const op = "lib.mail.Send"
+
+	if !m.Enabled() {
+		return fmt.Errorf("%s: smtp is not configured", op)
+	}
+	if len(to) == 0 {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	var auth smtp.Auth
+	if m.cfg.Username != "" {
+		password, ok, err := m.secrets.Get(ctx, secrets.SMTPPassword)
+		if err != nil {
+			return fmt.Errorf("%s: %v", op, err)
+		}
+		if !ok {
+			password = m.cfg.Password
+		}
+		auth = smtp.PlainAuth("", m.cfg.Username, password, m.cfg.Host)
+	}
+	addr := net.JoinHostPort(m.cfg.Host, strconv.Itoa(m.cfg.Port))
+	if err := m.send(addr, auth, m.cfg.From, to, m.message(to, subject, body)); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+func (m *Mailer) message(to []string, subject, body string) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + m.cfg.From + "\r\n")
+	b.WriteString("To: " + strings.Join(to, ", ") + "\r\n")
+	// Subjects of localized emails are not ASCII, they are encoded as RFC 2047 words
+	b.WriteString("Subject: " + mime.BEncoding.Encode("utf-8", oneLine(subject)) + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+// oneLine keeps a header value from injecting further headers
+func oneLine(s string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(s)
+}
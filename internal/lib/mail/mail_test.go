@@ -0,0 +1,36 @@
+package mail
+
+import (
+	"context"
+	"net/smtp"
+	"strings"
+	"testing"
+)
+
+func TestSend(t *testing.T) {
+	m := New(Config{Host: "smtp.example.com", Port: 587, From: "sapi@example.com"})
+
+	var addr string
+	var msg []byte
+	m.send = func(a string, auth smtp.Auth, from string, to []string, body []byte) error {
+		addr, msg = a, body
+		return nil
+	}
+
+	if err := m.Send(context.Background(), []string{"ops@example.com"}, "Alert\r\nBcc: x@example.com", "line 1\nline 2"); err != nil {
+		t.Fatal(err)
+	}
+	if addr != "smtp.example.com:587" {
+		t.Errorf("addr = %q", addr)
+	}
+	if strings.Contains(string(msg), "\r\nBcc:") {
+		t.Errorf("subject injected a header: %q", msg)
+	}
+	if !strings.HasSuffix(string(msg), "\r\n\r\nline 1\r\nline 2") {
+		t.Errorf("message = %q", msg)
+	}
+
+	if err := New(Config{}).Send(context.Background(), []string{"ops@example.com"}, "s", "b"); err == nil {
+		t.Error("sent without a server")
+	}
+}
@@ -0,0 +1,349 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	"golang.org/x/text/language"
+)
+
+// Names of the templates of the emails the server sends
+const (
+	TemplatePasswordExpiry   = "password_expiry"
+	TemplateCredentialsReset = "credentials_reset"
+	TemplatePasswordForgot   = "password_forgot"
+	TemplateVerifyEmail      = "verify_email"
+	TemplateConfirmSignIn    = "confirm_sign_in"
+	TemplateAlert            = "alert"
+)
+
+var (
+	// ErrUnknownTemplate is returned for a name that is not one of the templates
+	ErrUnknownTemplate = errors.New("unknown template")
+	// ErrInvalidTemplate is returned for a template that does not parse or uses a variable its email does not have
+	ErrInvalidTemplate = errors.New("invalid template")
+	// ErrInvalidLocale is returned for a locale that is not a BCP 47 language tag
+	ErrInvalidLocale = errors.New("invalid locale")
+)
+
+// Template is the subject and body of an email in text/template syntax, the variables of the email are used as {{.Name}}
+type Template struct {
+	Subject string `json:"subject" validate:"required,max=200"`
+	Body    string `json:"body" validate:"required,max=20000"`
+}
+
+// TemplateInfo is the template in effect for an email
+type TemplateInfo struct {
+	Name string `json:"name"`
+	// Locale is the locale of the template in effect, empty for the default one
+	Locale string `json:"locale,omitempty"`
+	Template
+	// Vars are the variables the template may use, with the sample values previews are rendered with
+	Vars map[string]string `json:"vars"`
+	// Custom tells whether an admin changed the template, otherwise it is the built-in one
+	Custom bool `json:"custom"`
+}
+
+// Rendered is a rendered email
+type Rendered struct {
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+type builtin struct {
+	Template
+	vars map[string]string
+	// locales holds the built-in translations by locale
+	locales map[string]Template
+}
+
+var builtins = map[string]builtin{
+	TemplatePasswordExpiry: {
+		Template: Template{
+			Subject: "Your password expires soon",
+			Body: "Hello, {{.Username}}.\n\nYour password expires on {{.Expires}}. Change it in your profile before then,\n" +
+				"after that you will have to change it on your next sign in.\n",
+		},
+		vars: map[string]string{"Username": "alice", "Expires": "Mon, 02 Jan 2006 15:04:05 UTC"},
+		locales: map[string]Template{
+			"ru": {
+				Subject: "Срок действия пароля скоро истекает",
+				Body: "Здравствуйте, {{.Username}}.\n\nСрок действия вашего пароля истекает {{.Expires}}. Смените его в профиле до этого,\n" +
+					"иначе его придётся сменить при следующем входе.\n",
+			},
+		},
+	},
+	TemplateCredentialsReset: {
+		Template: Template{
+			Subject: "Your password was reset",
+			Body: "Hello, {{.Username}}.\n\nAn administrator reset the credentials of your account, your password no longer works.\n" +
+				"Set a new password by {{.Expires}}:\n\n{{.Link}}\n",
+		},
+		vars: map[string]string{"Username": "alice", "Expires": "Mon, 02 Jan 2006 15:04:05 UTC", "Link": "https://easydev.club/reset-password?token=sample"},
+		locales: map[string]Template{
+			"ru": {
+				Subject: "Ваш пароль сброшен",
+				Body: "Здравствуйте, {{.Username}}.\n\nАдминистратор сбросил учётные данные вашей учётной записи, старый пароль больше не действует.\n" +
+					"Задайте новый пароль до {{.Expires}}:\n\n{{.Link}}\n",
+			},
+		},
+	},
+	TemplatePasswordForgot: {
+		Template: Template{
+			Subject: "Reset your password",
+			Body: "Hello, {{.Username}}.\n\nSomeone asked to reset the password of your account. Set a new password by {{.Expires}}:\n\n" +
+				"{{.Link}}\n\nIf it was not you, ignore this email, your password stays the same.\n",
+		},
+		vars: map[string]string{"Username": "alice", "Expires": "Mon, 02 Jan 2006 15:04:05 UTC", "Link": "https://easydev.club/reset-password?token=sample"},
+		locales: map[string]Template{
+			"ru": {
+				Subject: "Сброс пароля",
+				Body: "Здравствуйте, {{.Username}}.\n\nПоступил запрос на сброс пароля вашей учётной записи. Задайте новый пароль до {{.Expires}}:\n\n" +
+					"{{.Link}}\n\nЕсли это были не вы, просто проигнорируйте письмо, пароль останется прежним.\n",
+			},
+		},
+	},
+	TemplateVerifyEmail: {
+		Template: Template{
+			Subject: "Confirm your email",
+			Body: "Hello, {{.Username}}.\n\nConfirm the email of your new account by {{.Expires}}:\n\n" +
+				"{{.Link}}\n\nIf you did not sign up, ignore this email.\n",
+		},
+		vars: map[string]string{"Username": "alice", "Expires": "Mon, 02 Jan 2006 15:04:05 UTC", "Link": "https://easydev.club/api/v1/verify-email?token=sample"},
+		locales: map[string]Template{
+			"ru": {
+				Subject: "Подтвердите адрес почты",
+				Body: "Здравствуйте, {{.Username}}.\n\nПодтвердите адрес почты вашей новой учётной записи до {{.Expires}}:\n\n" +
+					"{{.Link}}\n\nЕсли вы не регистрировались, просто проигнорируйте письмо.\n",
+			},
+		},
+	},
+	TemplateConfirmSignIn: {
+		Template: Template{
+			Subject: "Confirm your sign in",
+			Body: "Hello, {{.Username}}.\n\nSomeone signed in to your account from {{.Location}} ({{.IP}}) with {{.Device}}.\n" +
+				"If it was you, confirm the sign in by {{.Expires}}:\n\n{{.Link}}\n\n" +
+				"If it was not you, do not follow the link and change your password.\n",
+		},
+		vars: map[string]string{"Username": "alice", "Location": "London, GB", "IP": "81.2.69.142", "Device": "Mozilla/5.0",
+			"Expires": "Mon, 02 Jan 2006 15:04:05 UTC", "Link": "https://easydev.club/api/v1/auth/signin/confirm?token=sample"},
+		locales: map[string]Template{
+			"ru": {
+				Subject: "Подтвердите вход",
+				Body: "Здравствуйте, {{.Username}}.\n\nВ вашу учётную запись вошли из {{.Location}} ({{.IP}}) с {{.Device}}.\n" +
+					"Если это были вы, подтвердите вход до {{.Expires}}:\n\n{{.Link}}\n\n" +
+					"Если это были не вы, не переходите по ссылке и смените пароль.\n",
+			},
+		},
+	},
+	TemplateAlert: {
+		Template: Template{
+			Subject: "[sAPI] Alert: {{.Kind}}",
+			Body:    "{{.Message}}.\n\nThreshold: {{.Threshold}}\nObserved: {{.Observed}}\nAt: {{.At}}\n",
+		},
+		vars: map[string]string{"Kind": "error rate", "Message": "Error rate is 12% over the last 5 minutes",
+			"Threshold": "0.05", "Observed": "0.12", "At": "2006-01-02T15:04:05Z"},
+		locales: map[string]Template{
+			"ru": {
+				Subject: "[sAPI] Оповещение: {{.Kind}}",
+				Body:    "{{.Message}}.\n\nПорог: {{.Threshold}}\nЗначение: {{.Observed}}\nВремя: {{.At}}\n",
+			},
+		},
+	},
+}
+
+// ParseLocale returns the canonical form of the BCP 47 language tag s, e.g. "pt-BR" for "pt_br".
+// The empty locale is the default one.
+func ParseLocale(s string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	tag, err := language.Parse(strings.ReplaceAll(s, "_", "-"))
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", s, ErrInvalidLocale)
+	}
+	return tag.String(), nil
+}
+
+// fallbacks returns the locales tried in turn for locale, from the most specific one to the default one,
+// e.g. "pt-BR", "pt" and "". An invalid locale only gets the default one.
+func fallbacks(locale string) []string {
+	tag, err := language.Parse(strings.ReplaceAll(locale, "_", "-"))
+	if locale == "" || err != nil {
+		return []string{""}
+	}
+
+	var chain []string
+	for ; tag != language.Und; tag = tag.Parent() {
+		chain = append(chain, tag.String())
+	}
+	return append(chain, "")
+}
+
+// TemplateStore keeps the templates changed by admins, by name and locale, the empty locale is the default one
+type TemplateStore interface {
+	// EmailTemplate returns the template an admin set, false when there is none
+	EmailTemplate(ctx context.Context, name, locale string) (Template, bool, error)
+	SetEmailTemplate(ctx context.Context, actor int, name, locale string, t Template) error
+	DeleteEmailTemplate(ctx context.Context, actor int, name, locale string) error
+}
+
+// Templates renders emails with the templates admins set, or the built-in ones, and sends them.
+// Emails are rendered in the locale of the recipient: the template for the most specific locale
+// of its fallbacks is used, one set by an admin before a built-in one of the same locale.
+type Templates struct {
+	store  TemplateStore
+	mailer *Mailer
+}
+
+func NewTemplates(store TemplateStore, mailer *Mailer) *Templates {
+	return &Templates{store: store, mailer: mailer}
+}
+
+// Enabled reports whether emails are sent, see Mailer.Enabled
+func (t *Templates) Enabled() bool {
+	return t != nil && t.mailer.Enabled()
+}
+
+// Get returns the template in effect for name in locale
+func (t *Templates) Get(ctx context.Context, name, locale string) (TemplateInfo, error) {
+	const op = "lib.mail.Templates.Get"
+
+	b, ok := builtins[name]
+	if !ok {
+		return TemplateInfo{}, fmt.Errorf("%s: %s: %w", op, name, ErrUnknownTemplate)
+	}
+
+	info := TemplateInfo{Name: name, Template: b.Template, Vars: b.vars}
+	for _, loc := range fallbacks(locale) {
+		custom, ok, err := t.store.EmailTemplate(ctx, name, loc)
+		if err != nil {
+			return info, fmt.Errorf("%s: %v", op, err)
+		}
+		if ok {
+			info.Locale, info.Template, info.Custom = loc, custom, true
+			return info, nil
+		}
+		if tpl, ok := b.locales[loc]; ok {
+			info.Locale, info.Template = loc, tpl
+			return info, nil
+		}
+	}
+
+	return info, nil
+}
+
+// List returns the templates in effect for every email in locale, by name
+func (t *Templates) List(ctx context.Context, locale string) ([]TemplateInfo, error) {
+	names := make([]string, 0, len(builtins))
+	for name := range builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	list := make([]TemplateInfo, 0, len(names))
+	for _, name := range names {
+		info, err := t.Get(ctx, name, locale)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, info)
+	}
+
+	return list, nil
+}
+
+// Set stores the template set by actor for name in locale once it renders with the variables of its email,
+// otherwise the error wrapping ErrInvalidTemplate tells what is wrong
+func (t *Templates) Set(ctx context.Context, actor int, name, locale string, tpl Template) error {
+	const op = "lib.mail.Templates.Set"
+
+	b, ok := builtins[name]
+	if !ok {
+		return fmt.Errorf("%s: %s: %w", op, name, ErrUnknownTemplate)
+	}
+	locale, err := ParseLocale(locale)
+	if err != nil {
+		return err
+	}
+	if _, err := render(tpl, b.vars); err != nil {
+		return err
+	}
+
+	if err := t.store.SetEmailTemplate(ctx, actor, name, locale, tpl); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	return nil
+}
+
+// Reset deletes the template set for name in locale, the built-in one or the one of the next fallback applies again
+func (t *Templates) Reset(ctx context.Context, actor int, name, locale string) error {
+	const op = "lib.mail.Templates.Reset"
+
+	if _, ok := builtins[name]; !ok {
+		return fmt.Errorf("%s: %s: %w", op, name, ErrUnknownTemplate)
+	}
+	locale, err := ParseLocale(locale)
+	if err != nil {
+		return err
+	}
+	if err := t.store.DeleteEmailTemplate(ctx, actor, name, locale); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	return nil
+}
+
+// Preview renders tpl, or the template in effect in locale when tpl is nil, with the sample values of the variables of name
+func (t *Templates) Preview(ctx context.Context, name, locale string, tpl *Template) (Rendered, error) {
+	info, err := t.Get(ctx, name, locale)
+	if err != nil {
+		return Rendered{}, err
+	}
+	if tpl == nil {
+		tpl = &info.Template
+	}
+
+	return render(*tpl, info.Vars)
+}
+
+// Send renders the email name in locale with vars and sends it to every address in to
+func (t *Templates) Send(ctx context.Context, to []string, name, locale string, vars map[string]string) error {
+	const op = "lib.mail.Templates.Send"
+
+	info, err := t.Get(ctx, name, locale)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	out, err := render(info.Template, vars)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return t.mailer.Send(ctx, to, out.Subject, out.Body)
+}
+
+// render executes the subject and body of tpl with vars, a variable missing from vars fails it.
+// Its errors are meant for the admin editing the template.
+func render(tpl Template, vars map[string]string) (Rendered, error) {
+	var out Rendered
+	for _, part := range []struct {
+		name string
+		text string
+		out  *string
+	}{{"subject", tpl.Subject, &out.Subject}, {"body", tpl.Body, &out.Body}} {
+		parsed, err := template.New(part.name).Option("missingkey=error").Parse(part.text)
+		if err != nil {
+			return out, fmt.Errorf("%v: %w", err, ErrInvalidTemplate)
+		}
+		var b strings.Builder
+		if err := parsed.Execute(&b, vars); err != nil {
+			return out, fmt.Errorf("%v: %w", err, ErrInvalidTemplate)
+		}
+		*part.out = b.String()
+	}
+	return out, nil
+}
@@ -0,0 +1,157 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"net/smtp"
+	"strings"
+	"testing"
+)
+
+type memTemplates map[string]Template
+
+func (m memTemplates) EmailTemplate(ctx context.Context, name, locale string) (Template, bool, error) {
+	t, ok := m[name+"/"+locale]
+	return t, ok, nil
+}
+
+func (m memTemplates) SetEmailTemplate(ctx context.Context, actor int, name, locale string, t Template) error {
+	m[name+"/"+locale] = t
+	return nil
+}
+
+func (m memTemplates) DeleteEmailTemplate(ctx context.Context, actor int, name, locale string) error {
+	delete(m, name+"/"+locale)
+	return nil
+}
+
+func TestTemplates(t *testing.T) {
+	ctx := context.Background()
+
+	m := New(Config{Host: "smtp.example.com", Port: 587, From: "sapi@example.com"})
+	var msg string
+	m.send = func(a string, auth smtp.Auth, from string, to []string, body []byte) error {
+		msg = string(body)
+		return nil
+	}
+	store := memTemplates{}
+	templates := NewTemplates(store, m)
+
+	// Every built-in template renders with its own variables
+	list, err := templates.List(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, info := range list {
+		if _, err := templates.Preview(ctx, info.Name, "", nil); err != nil || info.Custom {
+			t.Errorf("%s: custom = %v, preview err = %v", info.Name, info.Custom, err)
+		}
+	}
+
+	if err := templates.Set(ctx, 1, TemplatePasswordExpiry, "", Template{Subject: "Hi {{.Nickname}}", Body: "b"}); !errors.Is(err, ErrInvalidTemplate) {
+		t.Errorf("unknown variable: err = %v, want ErrInvalidTemplate", err)
+	}
+	if err := templates.Set(ctx, 1, TemplatePasswordExpiry, "", Template{Subject: "s", Body: "{{.Username"}); !errors.Is(err, ErrInvalidTemplate) {
+		t.Errorf("syntax error: err = %v, want ErrInvalidTemplate", err)
+	}
+	if err := templates.Set(ctx, 1, "digest", "", Template{Subject: "s", Body: "b"}); !errors.Is(err, ErrUnknownTemplate) {
+		t.Errorf("unknown template: err = %v, want ErrUnknownTemplate", err)
+	}
+	if len(store) != 0 {
+		t.Fatalf("invalid templates were stored: %v", store)
+	}
+
+	custom := Template{Subject: "Password expiry for {{.Username}}", Body: "Expires {{.Expires}}."}
+	if err := templates.Set(ctx, 1, TemplatePasswordExpiry, "", custom); err != nil {
+		t.Fatal(err)
+	}
+	preview, err := templates.Preview(ctx, TemplatePasswordExpiry, "", nil)
+	if err != nil || preview.Subject != "Password expiry for alice" {
+		t.Errorf("preview = %+v, err = %v", preview, err)
+	}
+
+	err = templates.Send(ctx, []string{"bob@example.com"}, TemplatePasswordExpiry, "", map[string]string{"Username": "bob", "Expires": "tomorrow"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(msg, "Subject: Password expiry for bob\r\n") || !strings.HasSuffix(msg, "Expires tomorrow.") {
+		t.Errorf("message = %q", msg)
+	}
+
+	if err := templates.Reset(ctx, 1, TemplatePasswordExpiry, ""); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := templates.Get(ctx, TemplatePasswordExpiry, ""); info.Custom || info.Subject != "Your password expires soon" {
+		t.Errorf("after reset = %+v", info)
+	}
+}
+
+func TestTemplateLocales(t *testing.T) {
+	ctx := context.Background()
+
+	m := New(Config{Host: "smtp.example.com", Port: 587, From: "sapi@example.com"})
+	var msg string
+	m.send = func(a string, auth smtp.Auth, from string, to []string, body []byte) error {
+		msg = string(body)
+		return nil
+	}
+	store := memTemplates{}
+	templates := NewTemplates(store, m)
+
+	// Every built-in translation renders with the variables of its email
+	for name, b := range builtins {
+		for locale, tpl := range b.locales {
+			if _, err := render(tpl, b.vars); err != nil {
+				t.Errorf("%s/%s: %v", name, locale, err)
+			}
+		}
+	}
+
+	// ru-RU falls back to the built-in ru template, unknown and invalid locales to the default one
+	for locale, want := range map[string]string{"ru-RU": "ru", "ru": "ru", "de": "", "not a locale": "", "": ""} {
+		info, err := templates.Get(ctx, TemplatePasswordExpiry, locale)
+		if err != nil || info.Locale != want || info.Custom {
+			t.Errorf("%q: locale = %q, custom = %v, err = %v, want %q", locale, info.Locale, info.Custom, err, want)
+		}
+	}
+
+	if err := templates.Set(ctx, 1, TemplateAlert, "en_x_bad!", Template{Subject: "s", Body: "b"}); !errors.Is(err, ErrInvalidLocale) {
+		t.Errorf("invalid locale: err = %v, want ErrInvalidLocale", err)
+	}
+
+	// A custom pt template serves pt-BR, a custom default one does not override the built-in ru one
+	if err := templates.Set(ctx, 1, TemplateAlert, "pt", Template{Subject: "Alerta: {{.Kind}}", Body: "{{.Message}}"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := templates.Set(ctx, 1, TemplateAlert, "", Template{Subject: "Alert: {{.Kind}}", Body: "{{.Message}}"}); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := templates.Get(ctx, TemplateAlert, "pt-BR"); info.Locale != "pt" || !info.Custom {
+		t.Errorf("pt-BR = %+v", info)
+	}
+	if info, _ := templates.Get(ctx, TemplateAlert, "ru"); info.Locale != "ru" || info.Custom {
+		t.Errorf("ru = %+v", info)
+	}
+
+	vars := map[string]string{"Username": "boris", "Expires": "tomorrow"}
+	if err := templates.Send(ctx, []string{"boris@example.com"}, TemplatePasswordExpiry, "ru-RU", vars); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(msg, "Subject: =?utf-8?b?") || !strings.Contains(msg, "Здравствуйте, boris.") {
+		t.Errorf("message = %q", msg)
+	}
+
+	// Canonical forms are stored, pt_br is pt-BR
+	if err := templates.Set(ctx, 1, TemplateAlert, "pt_br", Template{Subject: "Alerta BR", Body: "{{.Message}}"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store[TemplateAlert+"/pt-BR"]; !ok {
+		t.Errorf("store = %v", store)
+	}
+	if err := templates.Reset(ctx, 1, TemplateAlert, "pt-BR"); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := templates.Get(ctx, TemplateAlert, "pt-BR"); info.Subject != "Alerta: {{.Kind}}" {
+		t.Errorf("after reset pt-BR = %+v", info)
+	}
+}
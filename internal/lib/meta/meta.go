@@ -0,0 +1,106 @@
+// Package meta describes the deployment to clients: the build, enabled features, sign in methods and branding,
+// so one client can adapt to each deployment.
+package meta
+
+import (
+	"net/http"
+	"runtime/debug"
+)
+
+// Version and Commit identify the build, they are set with
+//
+//	go build -ldflags "-X github.com/sabbatD/srest-api/internal/lib/meta.Version=v0.3.2 -X github.com/sabbatD/srest-api/internal/lib/meta.Commit=$(git rev-parse HEAD)"
+var (
+	Version = "dev"
+	Commit  = ""
+)
+
+// Features clients may adapt their UI to, see Info.Features
+const (
+	FeatureGuests     = "guests"
+	FeatureRememberMe = "remember_me"
+	FeatureBatch      = "batch"
+	FeatureEmail      = "email"
+	FeatureLDAP       = "ldap"
+	FeatureSCIM       = "scim"
+	FeatureOIDC       = "oidc"
+	FeatureModeration = "moderation"
+	// FeatureVerifiedSignIn tells accounts sign in only once their email is verified
+	FeatureVerifiedSignIn = "verified_sign_in"
+	// FeatureSignInConfirmation tells sign ins from a new country or device may wait for confirmation by email
+	FeatureSignInConfirmation = "signin_confirmation"
+)
+
+// Sign in methods, see Info.AuthMethods
+const (
+	AuthPassword = "password"
+	AuthLDAP     = "ldap"
+	AuthGuest    = "guest"
+	AuthGoogle   = "google"
+	AuthGitHub   = "github"
+)
+
+// Branding holds the deployment's strings clients show, empty ones are left to the client
+type Branding struct {
+	Name         string `json:"name" yaml:"name" env-default:"EasyDev"`
+	Tagline      string `json:"tagline,omitempty" yaml:"tagline"`
+	LogoURL      string `json:"logoUrl,omitempty" yaml:"logo_url"`
+	PrimaryColor string `json:"primaryColor,omitempty" yaml:"primary_color"`
+	SupportEmail string `json:"supportEmail,omitempty" yaml:"support_email"`
+	TermsURL     string `json:"termsUrl,omitempty" yaml:"terms_url"`
+	PrivacyURL   string `json:"privacyUrl,omitempty" yaml:"privacy_url"`
+}
+
+// Info describes the deployment
+type Info struct {
+	Version     string   `json:"version"`
+	Commit      string   `json:"commit"`
+	Features    []string `json:"features"`
+	AuthMethods []string `json:"authMethods"`
+	Branding    Branding `json:"branding"`
+}
+
+// New returns the info of this build with the given features, sign in methods and branding
+func New(features, authMethods []string, branding Branding) Info {
+	return Info{
+		Version:     Version,
+		Commit:      BuildCommit(),
+		Features:    features,
+		AuthMethods: authMethods,
+		Branding:    branding,
+	}
+}
+
+// BuildCommit returns Commit, or the VCS revision Go stamped into the binary when it is not set
+func BuildCommit() string {
+	if Commit != "" {
+		return Commit
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			if s.Key == "vcs.revision" {
+				return s.Value
+			}
+		}
+	}
+	return "unknown"
+}
+
+// ShortCommit returns the first 7 characters of BuildCommit
+func ShortCommit() string {
+	commit := BuildCommit()
+	if len(commit) > 7 {
+		return commit[:7]
+	}
+	return commit
+}
+
+// Middleware tells which build answered: Server holds the version and short commit, X-API-Version the version
+func Middleware(next http.Handler) http.Handler {
+	server := "sapi/" + Version + " (" + ShortCommit() + ")"
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", server)
+		w.Header().Set("X-API-Version", Version)
+		next.ServeHTTP(w, r)
+	})
+}
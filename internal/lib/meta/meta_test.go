@@ -0,0 +1,37 @@
+package meta
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNew(tt *testing.T) {
+	defer func(version, commit string) { Version, Commit = version, commit }(Version, Commit)
+
+	// Without a commit set at build time the one Go stamped in is used, test binaries have none.
+	Version, Commit = "v1.2.3", ""
+	if got := New(nil, nil, Branding{}).Commit; got == "" {
+		tt.Error("empty commit")
+	}
+
+	Commit = "abc123"
+	info := New([]string{FeatureGuests}, []string{AuthPassword}, Branding{Name: "Acme"})
+	if info.Version != "v1.2.3" || info.Commit != "abc123" || info.Branding.Name != "Acme" || len(info.Features) != 1 {
+		tt.Errorf("info = %+v", info)
+	}
+}
+
+func TestMiddleware(tt *testing.T) {
+	defer func(version, commit string) { Version, Commit = version, commit }(Version, Commit)
+	Version, Commit = "v1.2.3", "abc1234def"
+
+	w := httptest.NewRecorder()
+	Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if got := w.Header().Get("Server"); got != "sapi/v1.2.3 (abc1234)" {
+		tt.Errorf("Server = %q", got)
+	}
+	if got := w.Header().Get("X-API-Version"); got != "v1.2.3" {
+		tt.Errorf("X-API-Version = %q", got)
+	}
+}
@@ -0,0 +1,159 @@
+package metrics
+
+import (
+	"math"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/sabbatD/srest-api/internal/lib/clock"
+)
+
+// Config sizes the in-process request metrics
+type Config struct {
+	// LatencySamples is the number of requests kept for the latency summary, at least 1
+	LatencySamples int `yaml:"latency_samples" env-default:"100000"`
+}
+
+type sample struct {
+	route    string
+	status   int
+	duration time.Duration
+	at       time.Time
+}
+
+// Latency keeps the latest requests in a ring buffer for summaries of latency and error rates by route.
+// Memory is bounded by the capacity, under heavy traffic a summary covers less than the window asked for.
+type Latency struct {
+	mu      sync.Mutex
+	samples []sample
+	next    int
+	full    bool
+}
+
+// NewLatency returns a Latency keeping the latest capacity requests
+func NewLatency(capacity int) *Latency {
+	return &Latency{samples: make([]sample, max(capacity, 1))}
+}
+
+// Middleware records the route pattern, status and duration of every request.
+// It must wrap the router so the pattern is complete when the request is done.
+func (l *Latency) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The duration is measured on the wall clock, the request is placed in the summary window by clock
+		start, at := time.Now(), clock.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r)
+
+		route := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		l.Record(r.Method+" "+route, status, time.Since(start), at)
+	})
+}
+
+// Record adds a request to the buffer, overwriting the oldest one when it is full
+func (l *Latency) Record(route string, status int, d time.Duration, at time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.samples[l.next] = sample{route: route, status: status, duration: d, at: at}
+	l.next = (l.next + 1) % len(l.samples)
+	if l.next == 0 {
+		l.full = true
+	}
+}
+
+type RouteSummary struct {
+	// Route is the method and the route pattern, e.g. "GET /api/v1/todos/{id}/"
+	Route    string `json:"route"`
+	Requests int    `json:"requests"`
+	// ServerErrors counts 5xx responses, ClientErrors 4xx ones
+	ServerErrors int `json:"serverErrors"`
+	ClientErrors int `json:"clientErrors"`
+	// ErrorRate is the share of 5xx responses
+	ErrorRate float64 `json:"errorRate"`
+	P50Ms     float64 `json:"p50Ms"`
+	P95Ms     float64 `json:"p95Ms"`
+}
+
+// Summary covers the requests from From to To, From is later than asked when the buffer holds fewer
+type Summary struct {
+	From   time.Time      `json:"from"`
+	To     time.Time      `json:"to"`
+	Routes []RouteSummary `json:"routes"`
+}
+
+// Summary returns latency percentiles and error rates by route of the requests within window before now,
+// busiest routes first
+func (l *Latency) Summary(now time.Time, window time.Duration) Summary {
+	since := now.Add(-window)
+	durations := make(map[string][]time.Duration)
+	res := Summary{From: now, To: now, Routes: []RouteSummary{}}
+	byRoute := make(map[string]*RouteSummary)
+
+	l.mu.Lock()
+	n := l.next
+	if l.full {
+		n = len(l.samples)
+	}
+	for i := 0; i < n; i++ {
+		s := l.samples[i]
+		if s.at.Before(since) || s.at.After(now) {
+			continue
+		}
+		if s.at.Before(res.From) {
+			res.From = s.at
+		}
+		rs, ok := byRoute[s.route]
+		if !ok {
+			rs = &RouteSummary{Route: s.route}
+			byRoute[s.route] = rs
+		}
+		rs.Requests++
+		switch {
+		case s.status >= 500:
+			rs.ServerErrors++
+		case s.status >= 400:
+			rs.ClientErrors++
+		}
+		durations[s.route] = append(durations[s.route], s.duration)
+	}
+	l.mu.Unlock()
+
+	for route, rs := range byRoute {
+		d := durations[route]
+		slices.Sort(d)
+		rs.ErrorRate = float64(rs.ServerErrors) / float64(rs.Requests)
+		rs.P50Ms = percentile(d, 0.50)
+		rs.P95Ms = percentile(d, 0.95)
+		res.Routes = append(res.Routes, *rs)
+	}
+	slices.SortFunc(res.Routes, func(a, b RouteSummary) int {
+		if a.Requests != b.Requests {
+			return b.Requests - a.Requests
+		}
+		if a.Route < b.Route {
+			return -1
+		}
+		return 1
+	})
+
+	return res
+}
+
+// percentile returns the nearest rank percentile of sorted durations in milliseconds
+func percentile(sorted []time.Duration, p float64) float64 {
+	i := int(math.Ceil(float64(len(sorted))*p)) - 1
+	i = min(max(i, 0), len(sorted)-1)
+	return float64(sorted[i]) / float64(time.Millisecond)
+}
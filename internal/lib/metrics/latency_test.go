@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sabbatD/srest-api/internal/lib/clock"
+)
+
+func TestSummary(t *testing.T) {
+	l := NewLatency(100)
+	now := time.Date(2024, 10, 21, 12, 0, 0, 0, time.UTC)
+
+	for i := 1; i <= 20; i++ {
+		status := http.StatusOK
+		if i%10 == 0 {
+			status = http.StatusInternalServerError
+		}
+		l.Record("GET /todos", status, time.Duration(i)*time.Millisecond, now.Add(-time.Minute))
+	}
+	l.Record("POST /todos", http.StatusBadRequest, 5*time.Millisecond, now.Add(-time.Minute))
+	l.Record("POST /todos", http.StatusOK, 5*time.Millisecond, now.Add(-2*time.Hour))
+
+	s := l.Summary(now, time.Hour)
+	if len(s.Routes) != 2 || !s.From.Equal(now.Add(-time.Minute)) {
+		t.Fatalf("summary = %+v", s)
+	}
+	get := s.Routes[0]
+	if get.Route != "GET /todos" || get.Requests != 20 || get.ServerErrors != 2 || get.ErrorRate != 0.1 || get.P50Ms != 10 || get.P95Ms != 19 {
+		t.Errorf("busiest route = %+v", get)
+	}
+	if post := s.Routes[1]; post.Requests != 1 || post.ClientErrors != 1 || post.ErrorRate != 0 {
+		t.Errorf("requests older than the window are counted: %+v", post)
+	}
+}
+
+func TestRingBuffer(t *testing.T) {
+	l := NewLatency(3)
+	now := time.Now()
+	for i := 0; i < 5; i++ {
+		l.Record("GET /", http.StatusOK, time.Millisecond, now)
+	}
+	if s := l.Summary(now, time.Hour); s.Routes[0].Requests != 3 {
+		t.Errorf("requests = %d, want the capacity", s.Routes[0].Requests)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	// Requests fall in the window of the clock, frozen in dev
+	fake := clock.NewFake(time.Date(2024, 10, 1, 12, 0, 0, 0, time.UTC))
+	t.Cleanup(clock.Set(fake))
+
+	l := NewLatency(10)
+	r := chi.NewRouter()
+	r.Use(l.Middleware)
+	r.Get("/todos/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/todos/1", nil))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/todos/2", nil))
+
+	s := l.Summary(fake.Now(), time.Hour)
+	if len(s.Routes) != 1 || s.Routes[0].Route != "GET /todos/{id}" || s.Routes[0].ClientErrors != 2 {
+		t.Errorf("summary = %+v, want requests grouped by pattern", s.Routes)
+	}
+}
@@ -0,0 +1,52 @@
+// Package metrics holds process counters published through expvar.
+// They are served as JSON by the admin metrics endpoint, latencies by route by its summary.
+package metrics
+
+import "expvar"
+
+var (
+	// SlowQueries counts statements over the slow query threshold by storage method
+	SlowQueries = expvar.NewMap("db_slow_queries")
+	// RateLimited counts requests rejected with 429 by limiter
+	RateLimited = expvar.NewMap("rate_limited")
+	// Errors counts error responses written by handlers by error code
+	Errors = expvar.NewMap("http_errors")
+	// Backups counts finished backups by status
+	Backups = expvar.NewMap("backups")
+	// Purged counts rows deleted by the retention policy by kind of data
+	Purged = expvar.NewMap("retention_purged")
+	// FailedLogins counts sign in attempts rejected for invalid credentials
+	FailedLogins = expvar.NewInt("auth_failed_logins")
+	// FailedLoginCountries counts the failed sign ins by the country of the client when it is known, see lib/geoip
+	FailedLoginCountries = expvar.NewMap("auth_failed_logins_by_country")
+	// LockedLogins counts sign in attempts rejected because the login is locked, see lib/lockout
+	LockedLogins = expvar.NewInt("auth_locked_logins")
+	// HeldLogins counts sign ins held back for confirmation by email by reason, new_country or new_device
+	HeldLogins = expvar.NewMap("auth_held_logins")
+	// JobFailures counts failed runs of background jobs by job
+	JobFailures = expvar.NewMap("job_failures")
+	// Alerts counts sent alerts by kind
+	Alerts = expvar.NewMap("alerts")
+	// Chaos counts faults injected in dev by kind, latency or error
+	Chaos = expvar.NewMap("chaos_injected")
+	// InboundRejected counts inbound integration requests failing verification by integration and reason
+	InboundRejected = expvar.NewMap("inbound_rejected")
+	// Coalesced counts requests that shared a concurrent identical read by read, see lib/flight
+	Coalesced = expvar.NewMap("requests_coalesced")
+	// Deprecated counts calls of deprecated routes by route, see api/deprecation
+	Deprecated = expvar.NewMap("deprecated_calls")
+	// ReadOnlyRejected counts writes rejected while the API is in read-only mode, see lib/api/readonly
+	ReadOnlyRejected = expvar.NewInt("read_only_rejected")
+	// JobsEnqueued counts jobs accepted by queue, see lib/jobs
+	JobsEnqueued = expvar.NewMap("jobs_enqueued")
+	// JobsRejected counts jobs rejected because their queue was full by queue
+	JobsRejected = expvar.NewMap("jobs_rejected")
+	// JobsQueued is the number of jobs waiting by queue
+	JobsQueued = expvar.NewMap("jobs_queued")
+	// JobsDone counts jobs that succeeded by queue
+	JobsDone = expvar.NewMap("jobs_done")
+	// JobsRetried counts failed attempts retried later by queue
+	JobsRetried = expvar.NewMap("jobs_retried")
+	// JobsDead counts jobs moved to the dead letters after their last attempt by queue
+	JobsDead = expvar.NewMap("jobs_dead")
+)
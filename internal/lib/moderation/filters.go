@@ -0,0 +1,109 @@
+package moderation
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Wordlist flags text containing one of its words, matched case-insensitively as whole words
+type Wordlist struct {
+	words map[string]struct{}
+}
+
+func NewWordlist(words []string) *Wordlist {
+	w := &Wordlist{words: make(map[string]struct{}, len(words))}
+	for _, word := range words {
+		if word = strings.ToLower(strings.TrimSpace(word)); word != "" {
+			w.words[word] = struct{}{}
+		}
+	}
+	return w
+}
+
+func (w *Wordlist) Check(ctx context.Context, text string) (Verdict, error) {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, field := range fields {
+		if _, ok := w.words[field]; ok {
+			return Verdict{Flagged: true, Reason: "wordlist"}, nil
+		}
+	}
+	return Verdict{}, nil
+}
+
+// ReadWordlist reads one word per line, skipping blank lines and # comments
+func ReadWordlist(name string) ([]string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var words []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			words = append(words, line)
+		}
+	}
+	return words, sc.Err()
+}
+
+// API asks an external moderation service. It POSTs {"text": "..."} and expects
+// {"flagged": bool, "reason": "..."} with status 200.
+type API struct {
+	url    string
+	client *http.Client
+}
+
+func NewAPI(url string, timeout time.Duration) *API {
+	return &API{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+func (a *API) Check(ctx context.Context, text string) (Verdict, error) {
+	const op = "lib.moderation.API.Check"
+
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return Verdict{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.url, bytes.NewReader(body))
+	if err != nil {
+		return Verdict{}, fmt.Errorf("%s: %v", op, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return Verdict{}, fmt.Errorf("%s: %v", op, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Verdict{}, fmt.Errorf("%s: unexpected status %s", op, resp.Status)
+	}
+
+	var v struct {
+		Flagged bool   `json:"flagged"`
+		Reason  string `json:"reason"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&v); err != nil {
+		return Verdict{}, fmt.Errorf("%s: %v", op, err)
+	}
+	if v.Flagged && v.Reason == "" {
+		v.Reason = "api"
+	}
+
+	return Verdict{Flagged: v.Flagged, Reason: v.Reason}, nil
+}
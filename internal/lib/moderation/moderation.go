@@ -0,0 +1,161 @@
+// Package moderation screens user written text (todo titles, usernames) with pluggable filters.
+// Flagged text is either rejected or accepted and recorded for admins, depending on the action.
+package moderation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/sabbatD/srest-api/internal/lib/logger/sl"
+)
+
+// Actions taken on flagged text
+const (
+	ActionReject = "reject"
+	ActionFlag   = "flag"
+)
+
+// Kinds of moderated content
+const (
+	KindTodoTitle = "todo_title"
+	KindUsername  = "username"
+)
+
+// ErrRejected is returned by Check for flagged text when the action is reject
+var ErrRejected = errors.New("content rejected")
+
+type Verdict struct {
+	Flagged bool
+	Reason  string
+}
+
+// Filter decides whether text is objectionable
+type Filter interface {
+	Check(ctx context.Context, text string) (Verdict, error)
+}
+
+// Flag is flagged content accepted under the flag action
+type Flag struct {
+	PublicID string `json:"id"`
+	Kind     string `json:"kind"`
+	UserID   int    `json:"-"`
+	User     string `json:"user"`
+	// Ref is the public ID of the todo or user holding the text
+	Ref     string `json:"ref"`
+	Text    string `json:"text"`
+	Reason  string `json:"reason"`
+	Created string `json:"created"`
+}
+
+type FlagsResponse struct {
+	Data []Flag    `json:"data"`
+	Meta FlagsMeta `json:"meta"`
+}
+
+type FlagsMeta struct {
+	TotalAmount int    `json:"totalAmount"`
+	Kind        string `json:"kind,omitempty"`
+}
+
+// Recorder stores flags for the admin listing
+type Recorder interface {
+	FlagContent(ctx context.Context, f Flag) error
+}
+
+// Config enables moderation when words, a wordlist file or an API are set
+type Config struct {
+	Action string   `yaml:"action" env:"MODERATION_ACTION" env-default:"flag"`
+	Words  []string `yaml:"words"`
+	// WordlistFile holds one word per line, lines starting with # are comments
+	WordlistFile string        `yaml:"wordlist_file" env:"MODERATION_WORDLIST"`
+	API          string        `yaml:"api" env:"MODERATION_API"`
+	APITimeout   time.Duration `yaml:"api_timeout" env-default:"2s"`
+}
+
+// Moderator runs the filters and applies the action. A nil Moderator accepts everything.
+type Moderator struct {
+	log     *slog.Logger
+	action  string
+	rec     Recorder
+	filters []Filter
+}
+
+func New(log *slog.Logger, action string, rec Recorder, filters ...Filter) *Moderator {
+	return &Moderator{log: log, action: action, rec: rec, filters: filters}
+}
+
+// FromConfig builds the configured moderator, or returns nil when no filter is configured
+func FromConfig(log *slog.Logger, cfg Config, rec Recorder) (*Moderator, error) {
+	const op = "lib.moderation.FromConfig"
+
+	if cfg.Action != ActionReject && cfg.Action != ActionFlag {
+		return nil, fmt.Errorf("%s: action must be %s or %s, got %q", op, ActionReject, ActionFlag, cfg.Action)
+	}
+
+	var filters []Filter
+	words := cfg.Words
+	if cfg.WordlistFile != "" {
+		list, err := ReadWordlist(cfg.WordlistFile)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+		words = append(words, list...)
+	}
+	if len(words) > 0 {
+		filters = append(filters, NewWordlist(words))
+	}
+	if cfg.API != "" {
+		filters = append(filters, NewAPI(cfg.API, cfg.APITimeout))
+	}
+
+	if len(filters) == 0 {
+		return nil, nil
+	}
+	return New(log, cfg.Action, rec, filters...), nil
+}
+
+// Check runs the filters in order, the first flagging one decides.
+// Under the reject action flagged text is an error wrapping ErrRejected, under the flag action
+// the verdict is returned and the caller passes it to Record once the text is stored.
+// Filters that fail are skipped: an unreachable moderation API does not block writes.
+func (m *Moderator) Check(ctx context.Context, text string) (Verdict, error) {
+	const op = "lib.moderation.Check"
+
+	if m == nil || text == "" {
+		return Verdict{}, nil
+	}
+
+	for _, f := range m.filters {
+		v, err := f.Check(ctx, text)
+		if err != nil {
+			sl.FromContext(sl.Ensure(ctx, m.log)).Warn("moderation filter failed", slog.String("op", op), sl.Err(err))
+			continue
+		}
+		if !v.Flagged {
+			continue
+		}
+		if m.action == ActionReject {
+			return v, fmt.Errorf("%s: %s: %w", op, v.Reason, ErrRejected)
+		}
+		return v, nil
+	}
+
+	return Verdict{}, nil
+}
+
+// Record stores f when v is flagged. Failures are logged, the content is already written.
+func (m *Moderator) Record(ctx context.Context, v Verdict, f Flag) {
+	const op = "lib.moderation.Record"
+
+	if m == nil || !v.Flagged || m.rec == nil {
+		return
+	}
+
+	f.Reason = v.Reason
+	if err := m.rec.FlagContent(ctx, f); err != nil {
+		sl.FromContext(sl.Ensure(ctx, m.log)).Error("failed to record flagged content", slog.String("op", op), sl.Err(err))
+	}
+}
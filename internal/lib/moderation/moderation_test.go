@@ -0,0 +1,85 @@
+package moderation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type memFlags []Flag
+
+func (m *memFlags) FlagContent(ctx context.Context, f Flag) error {
+	*m = append(*m, f)
+	return nil
+}
+
+var discard = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func TestModerator(tt *testing.T) {
+	ctx := context.Background()
+	words := NewWordlist([]string{"Darn"})
+
+	var flags memFlags
+	flag := New(discard, ActionFlag, &flags, words)
+
+	v, err := flag.Check(ctx, "buy milk")
+	if err != nil || v.Flagged {
+		tt.Errorf("clean text: %+v, %v", v, err)
+	}
+
+	v, err = flag.Check(ctx, "darn, the milk!")
+	if err != nil || !v.Flagged {
+		tt.Fatalf("flag action: %+v, %v", v, err)
+	}
+	flag.Record(ctx, v, Flag{Kind: KindTodoTitle, Text: "darn, the milk!"})
+	if len(flags) != 1 || flags[0].Reason != "wordlist" {
+		tt.Errorf("flags = %+v", flags)
+	}
+
+	// Whole words only.
+	if v, _ := flag.Check(ctx, "darning socks"); v.Flagged {
+		tt.Error("substring was flagged")
+	}
+
+	reject := New(discard, ActionReject, nil, words)
+	if _, err := reject.Check(ctx, "DARN"); !errors.Is(err, ErrRejected) {
+		tt.Errorf("reject action: err = %v, want ErrRejected", err)
+	}
+
+	var none *Moderator
+	if v, err := none.Check(ctx, "darn"); err != nil || v.Flagged {
+		tt.Errorf("nil moderator: %+v, %v", v, err)
+	}
+}
+
+func TestAPI(tt *testing.T) {
+	ctx := context.Background()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct{ Text string }
+		json.NewDecoder(r.Body).Decode(&req)
+		json.NewEncoder(w).Encode(map[string]any{"flagged": strings.Contains(req.Text, "spam"), "reason": "spam"})
+	}))
+	defer srv.Close()
+
+	m := New(discard, ActionReject, nil, NewAPI(srv.URL, time.Second))
+	if _, err := m.Check(ctx, "cheap spam here"); !errors.Is(err, ErrRejected) {
+		tt.Errorf("flagged by api: err = %v", err)
+	}
+	if _, err := m.Check(ctx, "hello"); err != nil {
+		tt.Errorf("clean by api: err = %v", err)
+	}
+
+	// An unreachable API fails open.
+	down := New(discard, ActionReject, nil, NewAPI("http://127.0.0.1:1", time.Second))
+	if v, err := down.Check(ctx, "cheap spam here"); err != nil || v.Flagged {
+		tt.Errorf("unreachable api: %+v, %v", v, err)
+	}
+}
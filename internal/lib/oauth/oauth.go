@@ -0,0 +1,284 @@
+// Package oauth signs users in with their Google or GitHub account over the OAuth 2.0 authorization code flow.
+// The provider redirects back with a code, Exchange trades it for an access token and reads the identity with it.
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sabbatD/srest-api/internal/lib/secrets"
+)
+
+// Providers users can sign in with, also the auth source of the accounts they create
+const (
+	Google = "google"
+	GitHub = "github"
+)
+
+var (
+	// ErrUnknownProvider is returned for a provider that is not supported or not configured
+	ErrUnknownProvider = errors.New("unknown provider")
+	// ErrNoEmail is returned when the provider does not tell the email of the account
+	ErrNoEmail = errors.New("provider account has no email")
+)
+
+// Config of the providers, a provider is enabled by setting its client ID. The client secrets are only taken
+// from the environment, or the secrets set by admins.
+type Config struct {
+	// RedirectBase is the public URL of the API base path providers redirect back to, e.g. https://api.example.com/api/v1.
+	// The callback URL registered with a provider is <RedirectBase>/auth/<provider>/callback.
+	RedirectBase       string        `yaml:"redirect_base" env:"OAUTH_REDIRECT_BASE"`
+	Timeout            time.Duration `yaml:"timeout" env-default:"10s"`
+	GoogleClientID     string        `yaml:"google_client_id" env:"OAUTH_GOOGLE_CLIENT_ID"`
+	GoogleClientSecret string        `yaml:"-" env:"OAUTH_GOOGLE_CLIENT_SECRET" redact:"true"`
+	GitHubClientID     string        `yaml:"github_client_id" env:"OAUTH_GITHUB_CLIENT_ID"`
+	GitHubClientSecret string        `yaml:"-" env:"OAUTH_GITHUB_CLIENT_SECRET" redact:"true"`
+}
+
+// Identity is a user authenticated by a provider
+type Identity struct {
+	Provider string
+	// Subject is the stable id of the account at the provider
+	Subject  string
+	Username string
+	Email    string
+	// EmailVerified tells the provider verified the email belongs to the user
+	EmailVerified bool
+}
+
+type provider struct {
+	authURL, tokenURL, userURL string
+	// emailsURL lists the emails of a GitHub account, the profile only has the public one
+	emailsURL string
+	scopes    []string
+
+	clientID, clientSecret string
+	// secretName is the secret overriding clientSecret, see secrets.Secrets
+	secretName string
+}
+
+// Client signs users in with the configured providers
+type Client struct {
+	cfg       Config
+	http      *http.Client
+	providers map[string]*provider
+	secrets   *secrets.Secrets
+}
+
+func New(cfg Config) *Client {
+	c := &Client{cfg: cfg, http: &http.Client{Timeout: cfg.Timeout}, providers: map[string]*provider{}}
+	if cfg.GoogleClientID != "" {
+		c.providers[Google] = &provider{
+			authURL:      "https://accounts.google.com/o/oauth2/v2/auth",
+			tokenURL:     "https://oauth2.googleapis.com/token",
+			userURL:      "https://openidconnect.googleapis.com/v1/userinfo",
+			scopes:       []string{"openid", "email", "profile"},
+			clientID:     cfg.GoogleClientID,
+			clientSecret: cfg.GoogleClientSecret,
+			secretName:   secrets.GoogleClientSecret,
+		}
+	}
+	if cfg.GitHubClientID != "" {
+		c.providers[GitHub] = &provider{
+			authURL:      "https://github.com/login/oauth/authorize",
+			tokenURL:     "https://github.com/login/oauth/access_token",
+			userURL:      "https://api.github.com/user",
+			emailsURL:    "https://api.github.com/user/emails",
+			scopes:       []string{"read:user", "user:email"},
+			clientID:     cfg.GitHubClientID,
+			clientSecret: cfg.GitHubClientSecret,
+			secretName:   secrets.GitHubClientSecret,
+		}
+	}
+	return c
+}
+
+// SetSecrets makes the client secrets stored in s, when set, override the configured ones
+func (c *Client) SetSecrets(s *secrets.Secrets) {
+	c.secrets = s
+}
+
+// Providers returns the configured providers, sorted
+func (c *Client) Providers() []string {
+	names := make([]string, 0, len(c.providers))
+	for name := range c.providers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// AuthURL returns the URL of the provider's sign in page, it redirects back to the callback with a code and state
+func (c *Client) AuthURL(name, state string) (string, error) {
+	p, ok := c.providers[name]
+	if !ok {
+		return "", fmt.Errorf("%q: %w", name, ErrUnknownProvider)
+	}
+
+	q := url.Values{
+		"response_type": {"code"},
+		"client_id":     {p.clientID},
+		"redirect_uri":  {c.redirectURI(name)},
+		"scope":         {strings.Join(p.scopes, " ")},
+		"state":         {state},
+	}
+	return p.authURL + "?" + q.Encode(), nil
+}
+
+// Exchange trades the code the provider redirected back with for the identity of the user
+func (c *Client) Exchange(ctx context.Context, name, code string) (Identity, error) {
+	const op = "lib.oauth.Exchange"
+
+	p, ok := c.providers[name]
+	if !ok {
+		return Identity{}, fmt.Errorf("%s: %q: %w", op, name, ErrUnknownProvider)
+	}
+
+	secret, set, err := c.secrets.Get(ctx, p.secretName)
+	if err != nil {
+		return Identity{}, fmt.Errorf("%s: %v", op, err)
+	}
+	if !set {
+		secret = p.clientSecret
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {c.redirectURI(name)},
+		"client_id":     {p.clientID},
+		"client_secret": {secret},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return Identity{}, fmt.Errorf("%s: %v", op, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var token struct {
+		AccessToken      string `json:"access_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	// GitHub answers errors of the exchange with 200
+	if err := c.do(req, &token); err != nil && token.Error == "" {
+		return Identity{}, fmt.Errorf("%s: token: %v", op, err)
+	}
+	if token.Error != "" || token.AccessToken == "" {
+		return Identity{}, fmt.Errorf("%s: token: %s %s", op, token.Error, token.ErrorDescription)
+	}
+
+	var id Identity
+	switch name {
+	case Google:
+		id, err = c.google(ctx, p, token.AccessToken)
+	case GitHub:
+		id, err = c.github(ctx, p, token.AccessToken)
+	}
+	if err != nil {
+		return Identity{}, fmt.Errorf("%s: %w", op, err)
+	}
+	id.Provider = name
+	if id.Email == "" {
+		return Identity{}, fmt.Errorf("%s: %w", op, ErrNoEmail)
+	}
+	return id, nil
+}
+
+func (c *Client) google(ctx context.Context, p *provider, token string) (Identity, error) {
+	var info struct {
+		Sub           string `json:"sub"`
+		Name          string `json:"name"`
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+	}
+	if err := c.get(ctx, p.userURL, token, &info); err != nil {
+		return Identity{}, fmt.Errorf("userinfo: %v", err)
+	}
+	if info.Sub == "" {
+		return Identity{}, errors.New("userinfo: no subject")
+	}
+
+	id := Identity{Subject: info.Sub, Username: info.Name, Email: info.Email, EmailVerified: info.EmailVerified}
+	if id.Username == "" {
+		id.Username, _, _ = strings.Cut(info.Email, "@")
+	}
+	return id, nil
+}
+
+func (c *Client) github(ctx context.Context, p *provider, token string) (Identity, error) {
+	var user struct {
+		ID    int64  `json:"id"`
+		Login string `json:"login"`
+		Name  string `json:"name"`
+	}
+	if err := c.get(ctx, p.userURL, token, &user); err != nil {
+		return Identity{}, fmt.Errorf("user: %v", err)
+	}
+	if user.ID == 0 {
+		return Identity{}, errors.New("user: no id")
+	}
+
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := c.get(ctx, p.emailsURL, token, &emails); err != nil {
+		return Identity{}, fmt.Errorf("emails: %v", err)
+	}
+
+	id := Identity{Subject: strconv.FormatInt(user.ID, 10), Username: user.Name}
+	if id.Username == "" {
+		id.Username = user.Login
+	}
+	for _, e := range emails {
+		if e.Primary {
+			id.Email, id.EmailVerified = e.Email, e.Verified
+		}
+	}
+	return id, nil
+}
+
+func (c *Client) get(ctx context.Context, url, token string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return c.do(req, v)
+}
+
+// do sends req and decodes the JSON response into v, also for an error status
+func (c *Client) do(req *http.Request, v any) error {
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	decodeErr := json.Unmarshal(body, v)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return decodeErr
+}
+
+func (c *Client) redirectURI(name string) string {
+	return strings.TrimSuffix(c.cfg.RedirectBase, "/") + "/auth/" + name + "/callback"
+}
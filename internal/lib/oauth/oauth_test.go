@@ -0,0 +1,91 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestExchange(t *testing.T) {
+	var form url.Values
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		form = r.PostForm
+		if r.PostForm.Get("code") != "good" {
+			// Like GitHub, the error comes with 200.
+			json.NewEncoder(w).Encode(map[string]string{"error": "bad_verification_code"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "at"})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer at" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"sub": "1234", "email": "alice@example.com", "email_verified": true})
+	})
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"id": 42, "login": "octocat"})
+	})
+	mux.HandleFunc("/user/emails", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]map[string]any{
+			{"email": "old@example.com", "primary": false, "verified": true},
+			{"email": "octo@example.com", "primary": true, "verified": false},
+		})
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	c := New(Config{RedirectBase: "https://api.example.com/api/v1/", GoogleClientID: "gid", GoogleClientSecret: "gsecret", GitHubClientID: "hid"})
+	for _, p := range c.providers {
+		p.tokenURL, p.userURL, p.emailsURL = srv.URL+"/token", srv.URL+"/userinfo", srv.URL+"/user/emails"
+	}
+	c.providers[GitHub].userURL = srv.URL + "/user"
+
+	if got := c.Providers(); len(got) != 2 || got[0] != GitHub || got[1] != Google {
+		t.Errorf("Providers() = %v", got)
+	}
+
+	authURL, err := c.AuthURL(Google, "st")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(authURL, "state=st") || !strings.Contains(authURL, url.QueryEscape("https://api.example.com/api/v1/auth/google/callback")) {
+		t.Errorf("AuthURL() = %s", authURL)
+	}
+	if _, err := c.AuthURL("facebook", "st"); !errors.Is(err, ErrUnknownProvider) {
+		t.Errorf("AuthURL() of an unknown provider = %v", err)
+	}
+
+	id, err := c.Exchange(context.Background(), Google, "good")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Identity{Provider: Google, Subject: "1234", Username: "alice", Email: "alice@example.com", EmailVerified: true}
+	if id != want {
+		t.Errorf("Google identity = %+v, want %+v", id, want)
+	}
+	if form.Get("client_secret") != "gsecret" || form.Get("redirect_uri") != "https://api.example.com/api/v1/auth/google/callback" {
+		t.Errorf("token request = %v", form)
+	}
+
+	id, err = c.Exchange(context.Background(), GitHub, "good")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = Identity{Provider: GitHub, Subject: "42", Username: "octocat", Email: "octo@example.com"}
+	if id != want {
+		t.Errorf("GitHub identity = %+v, want %+v", id, want)
+	}
+
+	if _, err := c.Exchange(context.Background(), Google, "bad"); err == nil || !strings.Contains(err.Error(), "bad_verification_code") {
+		t.Errorf("Exchange() of a bad code = %v", err)
+	}
+}
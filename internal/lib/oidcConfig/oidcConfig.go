@@ -0,0 +1,74 @@
+package oidcConfig
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// Scopes of the OpenID Connect provider: openid is required, profile adds the username and email the email
+const (
+	ScopeOpenID  = "openid"
+	ScopeProfile = "profile"
+	ScopeEmail   = "email"
+)
+
+// Scopes lists the supported scopes
+var Scopes = []string{ScopeOpenID, ScopeProfile, ScopeEmail}
+
+// ClientRequest registers a client (relying party), codes are only sent to one of its redirect URIs
+type ClientRequest struct {
+	Name         string   `json:"name" validate:"required,max=100"`
+	RedirectURIs []string `json:"redirectUris" validate:"required,min=1,max=10,dive,url"`
+}
+
+type Client struct {
+	ID           int       `json:"-"`
+	ClientID     string    `json:"clientId"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirectUris"`
+	Created      time.Time `json:"created"`
+	// Secret is only returned once, when the client is registered
+	Secret     string `json:"clientSecret,omitempty"`
+	SecretHash string `json:"-"`
+}
+
+// Grant is what an authorization code grants the client: the user's tokens with Scope. The code is only redeemed
+// with the redirect URI it was sent to and, with a PKCE CodeChallenge, the verifier of it.
+type Grant struct {
+	ClientID      int
+	UserID        int
+	RedirectURI   string
+	Scope         string
+	Nonce         string
+	CodeChallenge string
+}
+
+// ConsentDecision is the user's answer on the consent screen to the authorization request Request, the query string
+// of /oidc/authorize. Scopes are the requested scopes the user grants, openid included, Deny refuses them all.
+type ConsentDecision struct {
+	Request string   `json:"request" validate:"required"`
+	Scopes  []string `json:"scopes"`
+	Deny    bool     `json:"deny"`
+}
+
+// AuthorizedApp is a client the user granted Scopes to, last on Granted
+type AuthorizedApp struct {
+	ClientID string    `json:"clientId"`
+	Name     string    `json:"name"`
+	Scopes   []string  `json:"scopes"`
+	Granted  time.Time `json:"granted"`
+}
+
+// ParseScope returns the supported scopes of a space separated scope, in the order of Scopes
+func ParseScope(scope string) []string {
+	requested := strings.Fields(scope)
+
+	var granted []string
+	for _, s := range Scopes {
+		if slices.Contains(requested, s) {
+			granted = append(granted, s)
+		}
+	}
+	return granted
+}
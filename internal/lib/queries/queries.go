@@ -0,0 +1,303 @@
+// Package queries runs named report queries for admins, so ops questions are answered without a database shell.
+// Only the queries defined here or in the config can run, with typed parameters bound as $1, $2...,
+// in a read-only transaction and with a bounded number of rows.
+package queries
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Parameter types
+const (
+	TypeInt    = "int"
+	TypeString = "string"
+	TypeBool   = "bool"
+	// TypeDate is a date as YYYY-MM-DD
+	TypeDate = "date"
+)
+
+var (
+	// ErrUnknownQuery is returned for a name that is not one of the queries
+	ErrUnknownQuery = errors.New("unknown query")
+	// ErrInvalidParams is returned for missing, unknown or mistyped parameters
+	ErrInvalidParams = errors.New("invalid query parameters")
+)
+
+type Param struct {
+	Name        string `yaml:"name" json:"name"`
+	Type        string `yaml:"type" json:"type"`
+	Description string `yaml:"description" json:"description,omitempty"`
+	// Default is used when the parameter is not given, a parameter without one is required
+	Default any `yaml:"default" json:"default,omitempty"`
+} // @name ReportParam
+
+type Query struct {
+	Name        string `yaml:"name" json:"name"`
+	Description string `yaml:"description" json:"description"`
+	// SQL is a single SELECT, the parameters are bound as $1, $2... in their order
+	SQL    string  `yaml:"sql" json:"-"`
+	Params []Param `yaml:"params" json:"params"`
+} // @name ReportQuery
+
+// Config adds queries to the built-in ones, a query named like a built-in one replaces it.
+// Results are cut at MaxRows, statements are cancelled after Timeout.
+type Config struct {
+	Queries []Query       `yaml:"queries"`
+	MaxRows int           `yaml:"max_rows" env-default:"1000"`
+	Timeout time.Duration `yaml:"timeout" env-default:"10s"`
+}
+
+// Result is a query result as a table
+type Result struct {
+	Query   string   `json:"query"`
+	Columns []string `json:"columns"`
+	Rows    [][]any  `json:"rows"`
+	// Truncated tells more rows matched than the configured maximum
+	Truncated bool `json:"truncated"`
+} // @name ReportResult
+
+type Store interface {
+	RunQuery(ctx context.Context, actor int, name, query string, args []any, maxRows int, timeout time.Duration) (columns []string, rows [][]any, truncated bool, err error)
+}
+
+var builtin = []Query{
+	{
+		Name:        "signups_by_day",
+		Description: "Sign ups by day (UTC) over the last days, guests counted apart",
+		SQL: `SELECT (date AT TIME ZONE 'UTC')::date AS day, COUNT(*) FILTER (WHERE NOT is_guest) AS users, COUNT(*) FILTER (WHERE is_guest) AS guests
+			FROM public.users WHERE date >= NOW() - make_interval(days => $1) GROUP BY 1 ORDER BY 1`,
+		Params: []Param{{Name: "days", Type: TypeInt, Description: "Days to look back", Default: 30}},
+	},
+	{
+		Name:        "users_by_state",
+		Description: "Users by state: active, pending, blocked and deleted, guests left out",
+		SQL: `SELECT CASE WHEN deleted_at IS NOT NULL THEN 'deleted' WHEN is_blocked THEN 'blocked'
+				WHEN NOT is_verified THEN 'pending' ELSE 'active' END AS state, COUNT(*) AS users
+			FROM public.users WHERE NOT is_guest GROUP BY 1 ORDER BY 1`,
+	},
+	{
+		Name:        "top_todo_users",
+		Description: "Users with the most tasks",
+		SQL: `SELECT u.public_id, u.login, COUNT(*) AS todos, COUNT(*) FILTER (WHERE t.is_done) AS done
+			FROM public.todos t JOIN public.users u ON u.id = t.user_id
+			GROUP BY u.id ORDER BY todos DESC, u.id LIMIT $1`,
+		Params: []Param{{Name: "limit", Type: TypeInt, Description: "Number of users", Default: 20}},
+	},
+	{
+		Name:        "audit_actions",
+		Description: "Audit log actions over the last days",
+		SQL: `SELECT action, COUNT(*) AS actions, MAX(created) AS last
+			FROM public.audit_log WHERE created >= NOW() - make_interval(days => $1) GROUP BY action ORDER BY actions DESC, action`,
+		Params: []Param{{Name: "days", Type: TypeInt, Description: "Days to look back", Default: 7}},
+	},
+	{
+		Name:        "failed_logins_by_ip",
+		Description: "Failed sign ins by client address within the lockout window",
+		SQL: `SELECT ip, COUNT(*) AS failures, COUNT(DISTINCT login) AS logins, MAX(failed_at) AS last
+			FROM public.failed_logins GROUP BY ip ORDER BY failures DESC, ip`,
+	},
+	{
+		Name:        "open_reports_by_reason",
+		Description: "Open abuse reports by reason",
+		SQL:         `SELECT reason, COUNT(*) AS reports, MIN(created) AS oldest FROM public.reports WHERE status = 'open' GROUP BY reason ORDER BY reports DESC, reason`,
+	},
+}
+
+var (
+	placeholder = regexp.MustCompile(`\$(\d+)`)
+	selectStart = regexp.MustCompile(`(?i)^\s*(SELECT|WITH)\s`)
+)
+
+// Runner runs the defined queries
+type Runner struct {
+	store   Store
+	cfg     Config
+	queries map[string]Query
+}
+
+// New returns a runner of the built-in queries and those of cfg. Queries that are not a single SELECT,
+// or whose placeholders do not match their parameters, are an error.
+func New(store Store, cfg Config) (*Runner, error) {
+	const op = "queries.New"
+
+	r := &Runner{store: store, cfg: cfg, queries: make(map[string]Query)}
+	for _, q := range append(slices.Clone(builtin), cfg.Queries...) {
+		if err := check(q); err != nil {
+			return nil, fmt.Errorf("%s: %s: %v", op, q.Name, err)
+		}
+		r.queries[q.Name] = q
+	}
+	return r, nil
+}
+
+func check(q Query) error {
+	if q.Name == "" {
+		return errors.New("no name")
+	}
+	if !selectStart.MatchString(q.SQL) || strings.Contains(strings.TrimRight(strings.TrimSpace(q.SQL), ";"), ";") {
+		return errors.New("not a single SELECT")
+	}
+
+	highest := 0
+	for _, m := range placeholder.FindAllStringSubmatch(q.SQL, -1) {
+		n, _ := strconv.Atoi(m[1])
+		highest = max(highest, n)
+	}
+	if highest != len(q.Params) {
+		return fmt.Errorf("uses %d placeholders for %d parameters", highest, len(q.Params))
+	}
+
+	for _, p := range q.Params {
+		switch p.Type {
+		case TypeInt, TypeString, TypeBool, TypeDate:
+		default:
+			return fmt.Errorf("parameter %s has unknown type %q", p.Name, p.Type)
+		}
+		if p.Default == nil {
+			continue
+		}
+		if _, err := convert(p, p.Default); err != nil {
+			return fmt.Errorf("default of %s: %v", p.Name, err)
+		}
+	}
+	return nil
+}
+
+// List returns the queries sorted by name
+func (r *Runner) List() []Query {
+	list := make([]Query, 0, len(r.queries))
+	for _, q := range r.queries {
+		list = append(list, q)
+	}
+	slices.SortFunc(list, func(a, b Query) int { return strings.Compare(a.Name, b.Name) })
+	return list
+}
+
+// Run runs the query name with params for actor, the run is recorded in the audit log by the store
+func (r *Runner) Run(ctx context.Context, actor int, name string, params map[string]any) (Result, error) {
+	const op = "queries.Run"
+
+	q, ok := r.queries[name]
+	if !ok {
+		return Result{}, fmt.Errorf("%s: %s: %w", op, name, ErrUnknownQuery)
+	}
+
+	args, err := bind(q, params)
+	if err != nil {
+		return Result{}, fmt.Errorf("%s: %s: %w", op, name, err)
+	}
+
+	columns, rows, truncated, err := r.store.RunQuery(ctx, actor, name, q.SQL, args, r.cfg.MaxRows, r.cfg.Timeout)
+	if err != nil {
+		return Result{}, fmt.Errorf("%s: %s: %v", op, name, err)
+	}
+	if rows == nil {
+		rows = [][]any{}
+	}
+
+	return Result{Query: name, Columns: columns, Rows: rows, Truncated: truncated}, nil
+}
+
+// bind returns the arguments of q from params in parameter order
+func bind(q Query, params map[string]any) ([]any, error) {
+	for name := range params {
+		if !slices.ContainsFunc(q.Params, func(p Param) bool { return p.Name == name }) {
+			return nil, fmt.Errorf("unknown parameter %s: %w", name, ErrInvalidParams)
+		}
+	}
+
+	args := make([]any, len(q.Params))
+	for i, p := range q.Params {
+		v, given := params[p.Name]
+		if !given || v == nil {
+			if p.Default == nil {
+				return nil, fmt.Errorf("missing parameter %s: %w", p.Name, ErrInvalidParams)
+			}
+			v = p.Default
+		}
+
+		arg, err := convert(p, v)
+		if err != nil {
+			return nil, fmt.Errorf("parameter %s: %v: %w", p.Name, err, ErrInvalidParams)
+		}
+		args[i] = arg
+	}
+	return args, nil
+}
+
+// convert returns v, as decoded from JSON or YAML, as a value of the type of p
+func convert(p Param, v any) (any, error) {
+	switch p.Type {
+	case TypeInt:
+		switch n := v.(type) {
+		case int:
+			return int64(n), nil
+		case int64:
+			return n, nil
+		case float64:
+			if n == math.Trunc(n) && math.Abs(n) < 1<<53 {
+				return int64(n), nil
+			}
+		case string:
+			return strconv.ParseInt(n, 10, 64)
+		}
+		return nil, fmt.Errorf("%v is not an integer", v)
+	case TypeBool:
+		switch b := v.(type) {
+		case bool:
+			return b, nil
+		case string:
+			return strconv.ParseBool(b)
+		}
+		return nil, fmt.Errorf("%v is not a boolean", v)
+	case TypeDate:
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%v is not a date", v)
+		}
+		return time.Parse(time.DateOnly, s)
+	default:
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%v is not a string", v)
+		}
+		return s, nil
+	}
+}
+
+// WriteCSV writes res to w as CSV with a header row of the columns. Times are written as RFC 3339, NULLs as empty fields.
+func WriteCSV(w io.Writer, res Result) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(res.Columns); err != nil {
+		return err
+	}
+
+	record := make([]string, len(res.Columns))
+	for _, row := range res.Rows {
+		for i, v := range row {
+			switch v := v.(type) {
+			case nil:
+				record[i] = ""
+			case time.Time:
+				record[i] = v.Format(time.RFC3339)
+			default:
+				record[i] = fmt.Sprint(v)
+			}
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
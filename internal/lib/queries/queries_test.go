@@ -0,0 +1,97 @@
+package queries
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type memStore struct {
+	args    []any
+	maxRows int
+}
+
+func (m *memStore) RunQuery(ctx context.Context, actor int, name, query string, args []any, maxRows int, timeout time.Duration) ([]string, [][]any, bool, error) {
+	m.args, m.maxRows = args, maxRows
+	return []string{"day", "users", "note"}, [][]any{{time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC), int64(3), nil}, {time.Date(2024, 11, 2, 0, 0, 0, 0, time.UTC), int64(1), "a, b"}}, true, nil
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name  string
+		query Query
+		want  string
+	}{
+		{name: "write", query: Query{Name: "q", SQL: "DELETE FROM public.users"}, want: "not a single SELECT"},
+		{name: "two statements", query: Query{Name: "q", SQL: "SELECT 1; DROP TABLE public.users"}, want: "not a single SELECT"},
+		{name: "placeholders", query: Query{Name: "q", SQL: "SELECT $1, $2", Params: []Param{{Name: "a", Type: TypeInt}}}, want: "2 placeholders for 1 parameters"},
+		{name: "type", query: Query{Name: "q", SQL: "SELECT $1", Params: []Param{{Name: "a", Type: "float"}}}, want: "unknown type"},
+		{name: "default", query: Query{Name: "q", SQL: "SELECT $1", Params: []Param{{Name: "a", Type: TypeDate, Default: "yesterday"}}}, want: "default of a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(&memStore{}, Config{Queries: []Query{tt.query}})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("New() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	if _, err := New(&memStore{}, Config{Queries: []Query{{Name: "q", SQL: "WITH x AS (SELECT 1) SELECT * FROM x;"}}}); err != nil {
+		t.Errorf("New() = %v", err)
+	}
+}
+
+func TestRun(t *testing.T) {
+	store := &memStore{}
+	r, err := New(store, Config{MaxRows: 10, Queries: []Query{{
+		Name: "custom",
+		SQL:  "SELECT $1, $2, $3",
+		Params: []Param{
+			{Name: "days", Type: TypeInt, Default: 7},
+			{Name: "since", Type: TypeDate},
+			{Name: "guests", Type: TypeBool, Default: false},
+		},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Numbers decoded from JSON are float64
+	res, err := r.Run(context.Background(), 1, "custom", map[string]any{"days": float64(30), "since": "2024-11-01"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	since := time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC)
+	if store.args[0] != int64(30) || store.args[1] != since || store.args[2] != false || store.maxRows != 10 {
+		t.Errorf("args = %v, max rows %d", store.args, store.maxRows)
+	}
+	if res.Query != "custom" || len(res.Rows) != 2 || !res.Truncated {
+		t.Errorf("Run() = %+v", res)
+	}
+
+	for name, params := range map[string]map[string]any{
+		"missing":  {},
+		"unknown":  {"since": "2024-11-01", "user": "alice"},
+		"fraction": {"since": "2024-11-01", "days": 1.5},
+		"date":     {"since": "11/01/2024"},
+	} {
+		if _, err := r.Run(context.Background(), 1, "custom", params); !errors.Is(err, ErrInvalidParams) {
+			t.Errorf("Run() with %s parameters = %v", name, err)
+		}
+	}
+	if _, err := r.Run(context.Background(), 1, "nope", nil); !errors.Is(err, ErrUnknownQuery) {
+		t.Errorf("Run() of an unknown query = %v", err)
+	}
+
+	var b strings.Builder
+	if err := WriteCSV(&b, res); err != nil {
+		t.Fatal(err)
+	}
+	want := "day,users,note\n2024-11-01T00:00:00Z,3,\n2024-11-02T00:00:00Z,1,\"a, b\"\n"
+	if b.String() != want {
+		t.Errorf("WriteCSV() = %q, want %q", b.String(), want)
+	}
+}
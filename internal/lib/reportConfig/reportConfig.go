@@ -0,0 +1,49 @@
+package reportConfig
+
+// Report target types. Shared lists will add their own type once they exist.
+const (
+	TargetUser = "user"
+)
+
+// Report statuses
+const (
+	StatusOpen      = "open"
+	StatusResolved  = "resolved"
+	StatusDismissed = "dismissed"
+)
+
+type ReportRequest struct {
+	TargetType string `json:"targetType" validate:"required,oneof=user"`
+	TargetID   string `json:"targetId" validate:"required,uuid"`
+	Reason     string `json:"reason" validate:"required,oneof=spam abuse impersonation other"`
+	Details    string `json:"details,omitempty" validate:"max=1000"`
+}
+
+// ReviewRequest closes a report, the resolution note is kept with it
+type ReviewRequest struct {
+	Resolution string `json:"resolution,omitempty" validate:"max=1000"`
+}
+
+type Report struct {
+	ID         int     `json:"-"`
+	PublicID   string  `json:"id"`
+	Reporter   *string `json:"reporter"`
+	TargetType string  `json:"targetType"`
+	Target     string  `json:"target"`
+	Reason     string  `json:"reason"`
+	Details    string  `json:"details,omitempty"`
+	Status     string  `json:"status"`
+	Resolution string  `json:"resolution,omitempty"`
+	Created    string  `json:"created"`
+	ResolvedAt *string `json:"resolvedAt,omitempty"`
+}
+
+type Meta struct {
+	TotalAmount int    `json:"totalAmount"`
+	Status      string `json:"status,omitempty"`
+}
+
+type MetaResponse struct {
+	Data []Report `json:"data"`
+	Meta Meta     `json:"meta"`
+}
@@ -0,0 +1,154 @@
+// Package retention deletes old data on a schedule by the retention policy.
+// The policy comes from the configuration unless an admin set one in the settings.
+package retention
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/sabbatD/srest-api/internal/lib/clock"
+	"github.com/sabbatD/srest-api/internal/lib/logger/sl"
+	"github.com/sabbatD/srest-api/internal/lib/metrics"
+)
+
+// Kinds of purged data
+const (
+	KindLoginHistory = "login_history"
+	KindDeletedUsers = "deleted_users"
+	KindAuditLog     = "audit_log"
+	KindGuests       = "guests"
+)
+
+// Policy holds how many days each kind of data is kept, zero keeps it forever
+type Policy struct {
+	// LoginHistoryDays applies to former logins, a login still reserved for its former owner is kept
+	LoginHistoryDays int `json:"loginHistoryDays" yaml:"login_history_days" env-default:"0" validate:"min=0,max=36500"`
+	// DeletedUsersDays is the time soft-deleted users are kept before they are deleted with all their data
+	DeletedUsersDays int `json:"deletedUsersDays" yaml:"deleted_users_days" env-default:"0" validate:"min=0,max=36500"`
+	AuditLogDays     int `json:"auditLogDays" yaml:"audit_log_days" env-default:"0" validate:"min=0,max=36500"`
+	// GuestDays is the time guests are kept after their session started, with their todos
+	GuestDays int `json:"guestDays" yaml:"guest_days" env-default:"0" validate:"min=0,max=36500"`
+}
+
+// Config is the default policy and the interval of the purge job, a zero interval disables the job
+type Config struct {
+	Interval time.Duration `yaml:"interval" env-default:"24h"`
+	Policy   `yaml:",inline"`
+}
+
+// Result holds the number of purged rows by kind
+type Result map[string]int64
+
+type Store interface {
+	// RetentionPolicy returns the policy set by an admin, false when there is none
+	RetentionPolicy(ctx context.Context) (Policy, bool, error)
+	SetRetentionPolicy(ctx context.Context, actor int, p Policy) error
+	PurgeLoginHistory(ctx context.Context, before time.Time) (int64, error)
+	PurgeDeletedUsers(ctx context.Context, before time.Time) (int64, error)
+	PurgeAuditLog(ctx context.Context, before time.Time) (int64, error)
+	PurgeGuests(ctx context.Context, before time.Time) (int64, error)
+}
+
+type Runner struct {
+	log   *slog.Logger
+	store Store
+	cfg   Config
+}
+
+func New(log *slog.Logger, store Store, cfg Config) *Runner {
+	return &Runner{log: log, store: store, cfg: cfg}
+}
+
+// Policy returns the policy in effect: the admin set one, or the configured default
+func (r *Runner) Policy(ctx context.Context) (Policy, error) {
+	const op = "lib.retention.Policy"
+
+	p, ok, err := r.store.RetentionPolicy(ctx)
+	if err != nil {
+		return Policy{}, fmt.Errorf("%s: %v", op, err)
+	}
+	if !ok {
+		return r.cfg.Policy, nil
+	}
+	return p, nil
+}
+
+// SetPolicy stores the policy set by actor, it applies from the next run
+func (r *Runner) SetPolicy(ctx context.Context, actor int, p Policy) error {
+	const op = "lib.retention.SetPolicy"
+
+	if err := r.store.SetRetentionPolicy(ctx, actor, p); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	return nil
+}
+
+// RunOnce purges every kind of data past its retention. A failing kind does not stop the others,
+// the first error is returned along with what was purged.
+func (r *Runner) RunOnce(ctx context.Context) (Result, error) {
+	const op = "lib.retention.RunOnce"
+
+	p, err := r.Policy(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	now := clock.Now()
+	jobs := []struct {
+		kind  string
+		days  int
+		purge func(context.Context, time.Time) (int64, error)
+	}{
+		{KindLoginHistory, p.LoginHistoryDays, r.store.PurgeLoginHistory},
+		{KindDeletedUsers, p.DeletedUsersDays, r.store.PurgeDeletedUsers},
+		{KindAuditLog, p.AuditLogDays, r.store.PurgeAuditLog},
+		{KindGuests, p.GuestDays, r.store.PurgeGuests},
+	}
+
+	result := Result{}
+	var first error
+	for _, job := range jobs {
+		if job.days <= 0 {
+			continue
+		}
+		n, err := job.purge(ctx, now.AddDate(0, 0, -job.days))
+		if err != nil {
+			if first == nil {
+				first = fmt.Errorf("%s: %s: %v", op, job.kind, err)
+			}
+			continue
+		}
+		result[job.kind] = n
+		metrics.Purged.Add(job.kind, n)
+	}
+
+	return result, first
+}
+
+// Run purges every configured interval until ctx is done
+func (r *Runner) Run(ctx context.Context) {
+	const op = "lib.retention.Run"
+
+	if r.cfg.Interval <= 0 {
+		return
+	}
+	log := r.log.With(slog.String("op", op))
+
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			result, err := r.RunOnce(ctx)
+			if err != nil {
+				log.Error("retention purge failed", sl.Err(err))
+				metrics.JobFailures.Add("retention", 1)
+			}
+			log.Info("retention purge finished", slog.Any("purged", result))
+		}
+	}
+}
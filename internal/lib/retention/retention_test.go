@@ -0,0 +1,86 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/sabbatD/srest-api/internal/lib/clock"
+)
+
+type fakeStore struct {
+	policy *Policy
+	before map[string]time.Time
+	fail   string
+}
+
+func (f *fakeStore) RetentionPolicy(ctx context.Context) (Policy, bool, error) {
+	if f.policy == nil {
+		return Policy{}, false, nil
+	}
+	return *f.policy, true, nil
+}
+
+func (f *fakeStore) SetRetentionPolicy(ctx context.Context, actor int, p Policy) error {
+	f.policy = &p
+	return nil
+}
+
+func (f *fakeStore) purge(kind string, before time.Time) (int64, error) {
+	if kind == f.fail {
+		return 0, errors.New("connection reset")
+	}
+	f.before[kind] = before
+	return 2, nil
+}
+
+func (f *fakeStore) PurgeLoginHistory(ctx context.Context, before time.Time) (int64, error) {
+	return f.purge(KindLoginHistory, before)
+}
+
+func (f *fakeStore) PurgeDeletedUsers(ctx context.Context, before time.Time) (int64, error) {
+	return f.purge(KindDeletedUsers, before)
+}
+
+func (f *fakeStore) PurgeAuditLog(ctx context.Context, before time.Time) (int64, error) {
+	return f.purge(KindAuditLog, before)
+}
+
+func (f *fakeStore) PurgeGuests(ctx context.Context, before time.Time) (int64, error) {
+	return f.purge(KindGuests, before)
+}
+
+func TestRunOnce(tt *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 10, 1, 12, 0, 0, 0, time.UTC)
+	tt.Cleanup(clock.Set(clock.NewFake(now)))
+	store := &fakeStore{before: map[string]time.Time{}, fail: KindAuditLog}
+	r := New(slog.New(slog.NewTextHandler(io.Discard, nil)), store, Config{Policy: Policy{DeletedUsersDays: 30, AuditLogDays: 90}})
+
+	result, err := r.RunOnce(ctx)
+	if err == nil {
+		tt.Error("failing audit log purge not reported")
+	}
+	if len(result) != 1 || result[KindDeletedUsers] != 2 {
+		tt.Errorf("result = %v", result)
+	}
+	if got, want := store.before[KindDeletedUsers], now.AddDate(0, 0, -30); !got.Equal(want) {
+		tt.Errorf("deleted users purged before %v, want %v", got, want)
+	}
+
+	// An admin set policy replaces the configured one.
+	store.before, store.fail = map[string]time.Time{}, ""
+	if err := r.SetPolicy(ctx, 1, Policy{LoginHistoryDays: 7}); err != nil {
+		tt.Fatal(err)
+	}
+	result, err = r.RunOnce(ctx)
+	if err != nil {
+		tt.Fatal(err)
+	}
+	if len(result) != 1 || result[KindLoginHistory] != 2 {
+		tt.Errorf("result with admin policy = %v", result)
+	}
+}
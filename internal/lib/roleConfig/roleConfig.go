@@ -0,0 +1,118 @@
+package roleConfig
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+)
+
+// Permissions of admin routes, a role grants a set of them and access tokens carry the permissions of the user's role
+const (
+	UsersRead       = "users:read"
+	UsersWrite      = "users:write"
+	UsersBlock      = "users:block"
+	UsersDelete     = "users:delete"
+	RolesRead       = "roles:read"
+	RolesWrite      = "roles:write"
+	ModerationRead  = "moderation:read"
+	ModerationWrite = "moderation:write"
+	BannersWrite    = "banners:write"
+	SettingsRead    = "settings:read"
+	SettingsWrite   = "settings:write"
+	SecretsWrite    = "secrets:write"
+	ClientsWrite    = "clients:write"
+	SystemRead      = "system:read"
+	SystemWrite     = "system:write"
+)
+
+// Permissions lists every permission, the admin role has them all
+var Permissions = []string{
+	UsersRead, UsersWrite, UsersBlock, UsersDelete,
+	RolesRead, RolesWrite,
+	ModerationRead, ModerationWrite,
+	BannersWrite,
+	SettingsRead, SettingsWrite,
+	SecretsWrite,
+	ClientsWrite,
+	SystemRead, SystemWrite,
+}
+
+// Built-in roles, they cannot be deleted. The permissions of admin and user cannot be changed.
+const (
+	Admin     = "admin"
+	Moderator = "moderator"
+	User      = "user"
+)
+
+var (
+	// ErrUnknownPermission is returned for a permission not in Permissions
+	ErrUnknownPermission = errors.New("unknown permission")
+	// ErrInvalidName is returned for a role name not matching NamePattern
+	ErrInvalidName = errors.New("role names are 2 to 50 lowercase letters, digits, - and _, starting with a letter")
+)
+
+// NamePattern is the format of role names
+var NamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{1,49}$`)
+
+// Role grants its users Permissions
+type Role struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+	// Builtin roles cannot be deleted
+	Builtin bool `json:"builtin"`
+	// Users is the number of users with the role
+	Users int `json:"users"`
+}
+
+// RoleRequest creates a role, or replaces the description and permissions of one without a name
+type RoleRequest struct {
+	Name        string   `json:"name,omitempty"`
+	Description string   `json:"description" validate:"max=200"`
+	Permissions []string `json:"permissions" validate:"max=50"`
+}
+
+// UserRole assigns a role to a user
+type UserRole struct {
+	Role string `json:"role" validate:"required"`
+}
+
+// Check returns the permissions sorted as in Permissions and without duplicates, ErrUnknownPermission for one not in it
+func Check(permissions []string) ([]string, error) {
+	for _, p := range permissions {
+		if !slices.Contains(Permissions, p) {
+			return nil, fmt.Errorf("%q: %w", p, ErrUnknownPermission)
+		}
+	}
+
+	return known(permissions), nil
+}
+
+// Granted returns the permissions the role grants out of the stored ones, sorted as in Permissions.
+// Admins are granted every permission, permissions no longer in Permissions are left out.
+func Granted(role string, permissions []string) []string {
+	if role == Admin {
+		return slices.Clone(Permissions)
+	}
+	return known(permissions)
+}
+
+// known returns the permissions found in Permissions, in its order
+func known(permissions []string) []string {
+	found := []string{}
+	for _, p := range Permissions {
+		if slices.Contains(permissions, p) {
+			found = append(found, p)
+		}
+	}
+	return found
+}
+
+// CheckName returns ErrInvalidName for a name not matching NamePattern
+func CheckName(name string) error {
+	if !NamePattern.MatchString(name) {
+		return ErrInvalidName
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package roleConfig
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	got, err := Check([]string{ModerationWrite, UsersRead, ModerationWrite})
+	if err != nil || !slices.Equal(got, []string{UsersRead, ModerationWrite}) {
+		t.Errorf("Check() = %v, %v, want sorted without duplicates", got, err)
+	}
+	if _, err := Check([]string{UsersRead, "users:everything"}); !errors.Is(err, ErrUnknownPermission) {
+		t.Errorf("Check() of an unknown permission error = %v, want ErrUnknownPermission", err)
+	}
+
+	for name, want := range map[string]bool{"support": true, "on-call_2": true, "a": false, "Support": false, "2nd": false, "a b": false} {
+		if got := CheckName(name) == nil; got != want {
+			t.Errorf("CheckName(%q) accepted = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestGranted(t *testing.T) {
+	for _, tc := range []struct {
+		name, role string
+		stored     []string
+		want       []string
+	}{
+		{"admin", Admin, nil, Permissions},
+		{"moderator", Moderator, []string{ModerationRead, UsersBlock}, []string{UsersBlock, ModerationRead}},
+		{"removed permission", "support", []string{UsersRead, "todos:purge"}, []string{UsersRead}},
+		{"user", User, nil, []string{}},
+	} {
+		if got := Granted(tc.role, tc.stored); !slices.Equal(got, tc.want) {
+			t.Errorf("%s: granted = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package scan
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const clamChunk = 64 << 10
+
+// ClamAV scans with a clamd daemon over its INSTREAM command
+type ClamAV struct {
+	network string
+	addr    string
+	timeout time.Duration
+}
+
+// NewClamAV returns a scanner for the clamd at addr, network is "tcp" or "unix".
+// The stream must not exceed clamd's StreamMaxLength, keep Policy.MaxSize below it.
+func NewClamAV(network, addr string, timeout time.Duration) *ClamAV {
+	return &ClamAV{network: network, addr: addr, timeout: timeout}
+}
+
+func (c *ClamAV) Scan(ctx context.Context, r io.Reader) error {
+	const op = "lib.scan.ClamAV.Scan"
+
+	d := net.Dialer{Timeout: c.timeout}
+	conn, err := d.DialContext(ctx, c.network, c.addr)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(c.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+
+	if _, err := io.WriteString(conn, "zINSTREAM\x00"); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	// Chunks are prefixed with their length, a zero length ends the stream.
+	buf := make([]byte, 4+clamChunk)
+	for {
+		n, err := r.Read(buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf, uint32(n))
+			if _, err := conn.Write(buf[:4+n]); err != nil {
+				return fmt.Errorf("%s: %v", op, err)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %v", op, err)
+		}
+	}
+	if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && reply == "" {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	reply = strings.TrimRight(reply, "\x00\n")
+
+	// Replies are "stream: OK", "stream: <signature> FOUND" or "<message> ERROR"
+	switch {
+	case strings.HasSuffix(reply, " OK"):
+		return nil
+	case strings.HasSuffix(reply, " FOUND"):
+		signature := strings.TrimSuffix(strings.TrimPrefix(reply, "stream: "), " FOUND")
+		return &Rejection{Code: CodeInfected, Status: http.StatusUnprocessableEntity,
+			Message: fmt.Sprintf("File is infected: %s", signature)}
+	default:
+		return fmt.Errorf("%s: clamd: %s", op, reply)
+	}
+}
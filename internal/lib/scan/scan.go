@@ -0,0 +1,166 @@
+// Package scan checks uploaded files before they are stored:
+// size limit, content type sniffed from the bytes, and optional virus scanners such as ClamAV.
+package scan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Rejection codes, handlers pass them on as the problem code
+const (
+	CodeTooLarge        = "FILE_TOO_LARGE"
+	CodeUnsupportedType = "UNSUPPORTED_TYPE"
+	CodeInfected        = "FILE_INFECTED"
+)
+
+// Rejection is returned when an upload violates the policy or a scanner flags it
+type Rejection struct {
+	Code    string
+	Status  int
+	Message string
+}
+
+func (r *Rejection) Error() string { return r.Message }
+
+// Scanner inspects the content of an upload, e.g. for viruses.
+// It returns a *Rejection for bad content and other errors when it could not scan.
+type Scanner interface {
+	Scan(ctx context.Context, r io.Reader) error
+}
+
+// Policy limits what uploads are accepted
+type Policy struct {
+	MaxSize int64
+	// Types are accepted content types or type prefixes ending with "/", e.g. "image/".
+	// Empty accepts anything.
+	Types []string
+}
+
+// Config is the instance wide upload configuration
+type Config struct {
+	MaxSize int64 `yaml:"max_size" env:"UPLOAD_MAX_SIZE" env-default:"10485760"`
+	// ClamAV is the clamd address as "unix:/path/clamd.sock" or "tcp:host:3310", empty disables scanning
+	ClamAV        string        `yaml:"clamav" env:"CLAMAV_ADDR"`
+	ClamAVTimeout time.Duration `yaml:"clamav_timeout" env-default:"30s"`
+}
+
+type Checker struct {
+	policy   Policy
+	scanners []Scanner
+}
+
+func New(policy Policy, scanners ...Scanner) *Checker {
+	return &Checker{policy: policy, scanners: scanners}
+}
+
+// FromConfig returns a checker with the configured size limit and scanners accepting the given types
+func FromConfig(cfg Config, types ...string) (*Checker, error) {
+	const op = "lib.scan.FromConfig"
+
+	var scanners []Scanner
+	if cfg.ClamAV != "" {
+		network, addr, ok := strings.Cut(cfg.ClamAV, ":")
+		if !ok || network != "unix" && network != "tcp" {
+			return nil, fmt.Errorf("%s: clamav address must be unix:<path> or tcp:<host:port>, got %q", op, cfg.ClamAV)
+		}
+		scanners = append(scanners, NewClamAV(network, addr, cfg.ClamAVTimeout))
+	}
+
+	return New(Policy{MaxSize: cfg.MaxSize, Types: types}, scanners...), nil
+}
+
+// Upload is a checked upload spooled to a temporary file, Close removes it
+type Upload struct {
+	*os.File
+	Size        int64
+	ContentType string
+}
+
+func (u *Upload) Close() error {
+	err := u.File.Close()
+	os.Remove(u.File.Name())
+	return err
+}
+
+// Check spools r to a temporary file while enforcing the size limit, sniffs the content type
+// and runs the scanners. The returned upload is positioned at the start.
+// The client's declared content type is ignored, only the sniffed one counts.
+func (c *Checker) Check(ctx context.Context, r io.Reader) (*Upload, error) {
+	const op = "lib.scan.Check"
+
+	f, err := os.CreateTemp("", "upload-*")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+	upload := &Upload{File: f}
+
+	ok := false
+	defer func() {
+		if !ok {
+			upload.Close()
+		}
+	}()
+
+	src := r
+	if c.policy.MaxSize > 0 {
+		src = io.LimitReader(r, c.policy.MaxSize+1)
+	}
+	upload.Size, err = io.Copy(f, src)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+	if c.policy.MaxSize > 0 && upload.Size > c.policy.MaxSize {
+		return nil, &Rejection{Code: CodeTooLarge, Status: http.StatusRequestEntityTooLarge,
+			Message: fmt.Sprintf("File is larger than %d bytes", c.policy.MaxSize)}
+	}
+
+	head := make([]byte, 512)
+	n, err := f.ReadAt(head, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+	upload.ContentType, _, _ = strings.Cut(http.DetectContentType(head[:n]), ";")
+	if !c.allowed(upload.ContentType) {
+		return nil, &Rejection{Code: CodeUnsupportedType, Status: http.StatusUnsupportedMediaType,
+			Message: fmt.Sprintf("File type %s is not accepted", upload.ContentType)}
+	}
+
+	for _, s := range c.scanners {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+		if err := s.Scan(ctx, f); err != nil {
+			var rej *Rejection
+			if errors.As(err, &rej) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	ok = true
+	return upload, nil
+}
+
+func (c *Checker) allowed(contentType string) bool {
+	if len(c.policy.Types) == 0 {
+		return true
+	}
+	for _, t := range c.policy.Types {
+		if t == contentType || strings.HasSuffix(t, "/") && strings.HasPrefix(contentType, t) {
+			return true
+		}
+	}
+	return false
+}
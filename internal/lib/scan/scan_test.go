@@ -0,0 +1,109 @@
+package scan
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+var png = append([]byte("\x89PNG\x0d\x0a\x1a\x0a"), make([]byte, 64)...)
+
+// fakeClamd answers INSTREAM requests, content containing "EICAR" is reported infected
+func fakeClamd(tt *testing.T) string {
+	tt.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		tt.Skip("cannot listen on loopback:", err)
+	}
+	tt.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				if cmd, _ := r.ReadString(0); cmd != "zINSTREAM\x00" {
+					io.WriteString(conn, "UNKNOWN COMMAND ERROR\x00")
+					return
+				}
+				var data []byte
+				for {
+					var size uint32
+					if binary.Read(r, binary.BigEndian, &size) != nil || size == 0 {
+						break
+					}
+					chunk := make([]byte, size)
+					io.ReadFull(r, chunk)
+					data = append(data, chunk...)
+				}
+				if bytes.Contains(data, []byte("EICAR")) {
+					io.WriteString(conn, "stream: Eicar-Test-Signature FOUND\x00")
+					return
+				}
+				io.WriteString(conn, "stream: OK\x00")
+			}()
+		}
+	}()
+
+	return ln.Addr().String()
+}
+
+func TestCheck(tt *testing.T) {
+	addr := fakeClamd(tt)
+	checker := New(Policy{MaxSize: 1024, Types: []string{"image/"}}, NewClamAV("tcp", addr, time.Second))
+
+	tests := []struct {
+		name string
+		data []byte
+		code string
+	}{
+		{name: "clean image", data: png},
+		{name: "too large", data: append(png, make([]byte, 1024)...), code: CodeTooLarge},
+		{name: "wrong type", data: []byte("<html><body>hi</body></html>"), code: CodeUnsupportedType},
+		{name: "infected", data: append(png, []byte("EICAR")...), code: CodeInfected},
+	}
+	for _, tc := range tests {
+		tt.Run(tc.name, func(tt *testing.T) {
+			upload, err := checker.Check(context.Background(), bytes.NewReader(tc.data))
+
+			var rej *Rejection
+			if tc.code != "" {
+				if !errors.As(err, &rej) || rej.Code != tc.code {
+					tt.Fatalf("err = %v, want rejection %s", err, tc.code)
+				}
+				return
+			}
+			if err != nil {
+				tt.Fatal(err)
+			}
+			defer upload.Close()
+
+			got, _ := io.ReadAll(upload)
+			if !bytes.Equal(got, tc.data) || upload.Size != int64(len(tc.data)) || upload.ContentType != "image/png" {
+				tt.Errorf("upload = %d bytes of %s, size %d", len(got), upload.ContentType, upload.Size)
+			}
+		})
+	}
+}
+
+func TestClamAVUnavailable(tt *testing.T) {
+	checker := New(Policy{}, NewClamAV("unix", "/nonexistent/clamd.sock", time.Second))
+
+	_, err := checker.Check(context.Background(), strings.NewReader("text"))
+	var rej *Rejection
+	if err == nil || errors.As(err, &rej) {
+		tt.Errorf("err = %v, want a scan failure rather than a rejection", err)
+	}
+}
@@ -0,0 +1,336 @@
+// Package secrets keeps the credentials of integrations (SMTP password, chat bot tokens, signing secrets) encrypted
+// at rest. Admins set them through the API, they are sealed with AES-256-GCM under a master key only the process
+// holds and the database never sees them in plain text. Integrations read them with Secrets.Get.
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Names of the secrets integrations read, only these can be set
+const (
+	SMTPPassword       = "smtp.password"
+	AlertWebhookURL    = "alerting.webhook_url"
+	SlackSigningSecret = "slack.signing_secret"
+	TelegramBotToken   = "telegram.bot_token"
+	GoogleClientSecret = "oauth.google_client_secret"
+	GitHubClientSecret = "oauth.github_client_secret"
+)
+
+// Names lists the secrets that can be set
+var Names = []string{SMTPPassword, AlertWebhookURL, SlackSigningSecret, TelegramBotToken, GoogleClientSecret, GitHubClientSecret}
+
+// KeySize is the size of master keys, they are AES-256 keys
+const KeySize = 32
+
+// Sealed secrets start with a version byte and the id of the key they are sealed with, followed by the nonce
+// and the ciphertext. The name of the secret is the additional data, so a value moved to another name fails to open.
+const (
+	version   = 1
+	keyIDSize = 8
+)
+
+var (
+	// ErrNoKey is returned by Load when no master key is configured, secrets cannot be stored then
+	ErrNoKey = errors.New("secrets master key is not set: set SECRETS_KEY or secrets.key_file")
+	// ErrDecrypt is returned for a secret sealed with an unknown key or tampered with
+	ErrDecrypt = errors.New("secret sealed with an unknown key or corrupted")
+	// ErrUnknownName is returned for a name not in Names
+	ErrUnknownName = errors.New("unknown secret")
+)
+
+// Config locates the master key: Key or the content of KeyFile, base64 encoded 32 bytes. The key itself is only taken
+// from the environment. PreviousKeys, comma separated, still open secrets sealed before a key rotation.
+type Config struct {
+	Key          string `yaml:"-" env:"SECRETS_KEY" redact:"true"`
+	KeyFile      string `yaml:"key_file" env:"SECRETS_KEY_FILE"`
+	PreviousKeys string `yaml:"-" env:"SECRETS_PREVIOUS_KEYS" redact:"true"`
+}
+
+// Cipher seals secrets with the master key and opens those sealed with it or a previous key
+type Cipher struct {
+	keys []key
+}
+
+type key struct {
+	id   []byte
+	aead cipher.AEAD
+}
+
+// Load returns the cipher of the configured keys, ErrNoKey when there is no master key
+func Load(cfg Config) (*Cipher, error) {
+	const op = "lib.secrets.Load"
+
+	var master string
+	switch {
+	case cfg.Key != "" && cfg.KeyFile != "":
+		return nil, fmt.Errorf("%s: SECRETS_KEY and secrets.key_file are both set, set one", op)
+	case cfg.Key != "":
+		master = cfg.Key
+	case cfg.KeyFile != "":
+		b, err := os.ReadFile(cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+		master = string(bytes.TrimSpace(b))
+	default:
+		return nil, fmt.Errorf("%s: %w", op, ErrNoKey)
+	}
+
+	encoded := []string{master}
+	for _, k := range strings.Split(cfg.PreviousKeys, ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			encoded = append(encoded, k)
+		}
+	}
+
+	keys := make([][]byte, len(encoded))
+	for i, k := range encoded {
+		b, err := base64.StdEncoding.DecodeString(k)
+		if err != nil {
+			return nil, fmt.Errorf("%s: key %d is not base64: %v", op, i, err)
+		}
+		keys[i] = b
+	}
+
+	c, err := NewCipher(keys[0], keys[1:]...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+	return c, nil
+}
+
+// NewCipher returns a cipher sealing with master and opening with it and previous
+func NewCipher(master []byte, previous ...[]byte) (*Cipher, error) {
+	c := &Cipher{}
+	for _, b := range append([][]byte{master}, previous...) {
+		if len(b) != KeySize {
+			return nil, fmt.Errorf("master keys must be %d bytes, got %d", KeySize, len(b))
+		}
+		block, err := aes.NewCipher(b)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(b)
+		c.keys = append(c.keys, key{id: sum[:keyIDSize], aead: aead})
+	}
+	return c, nil
+}
+
+// GenerateKey returns a random master key, base64 encoded as Config expects it
+func GenerateKey() (string, error) {
+	b := make([]byte, KeySize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// Seal encrypts the value of the secret name with the master key
+func (c *Cipher) Seal(name, value string) ([]byte, error) {
+	k := c.keys[0]
+
+	header := append([]byte{version}, k.id...)
+	nonce := make([]byte, k.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	out := append(header, nonce...)
+	return k.aead.Seal(out, nonce, []byte(value), []byte(name)), nil
+}
+
+// Open decrypts the secret name sealed by Seal with the master key or a previous one
+func (c *Cipher) Open(name string, sealed []byte) (string, error) {
+	k, ok := c.key(sealed)
+	if !ok {
+		return "", ErrDecrypt
+	}
+
+	rest := sealed[1+keyIDSize:]
+	if len(rest) < k.aead.NonceSize() {
+		return "", ErrDecrypt
+	}
+	plain, err := k.aead.Open(nil, rest[:k.aead.NonceSize()], rest[k.aead.NonceSize():], []byte(name))
+	if err != nil {
+		return "", ErrDecrypt
+	}
+	return string(plain), nil
+}
+
+// Current tells whether sealed is sealed with the master key, others are resealed by Secrets.Rekey
+func (c *Cipher) Current(sealed []byte) bool {
+	k, ok := c.key(sealed)
+	return ok && bytes.Equal(k.id, c.keys[0].id)
+}
+
+func (c *Cipher) key(sealed []byte) (key, bool) {
+	if len(sealed) < 1+keyIDSize || sealed[0] != version {
+		return key{}, false
+	}
+	for _, k := range c.keys {
+		if bytes.Equal(k.id, sealed[1:1+keyIDSize]) {
+			return k, true
+		}
+	}
+	return key{}, false
+}
+
+// Info describes a secret without its value: whether it is set and when
+type Info struct {
+	Name    string     `json:"name"`
+	Set     bool       `json:"set"`
+	Updated *time.Time `json:"updated,omitempty"`
+}
+
+// Value is the value of a secret set by an admin
+type Value struct {
+	Value string `json:"value" validate:"required,max=4096"`
+}
+
+// Store keeps the sealed secrets
+type Store interface {
+	// Secret returns the sealed secret, false when it is not set
+	Secret(ctx context.Context, name string) ([]byte, bool, error)
+	// SealedSecrets returns every sealed secret by name
+	SealedSecrets(ctx context.Context) (map[string][]byte, error)
+	// SecretInfos returns the stored secrets
+	SecretInfos(ctx context.Context) ([]Info, error)
+	// SetSecret stores the sealed secret and records the change by actor in the audit log, without one for 0
+	SetSecret(ctx context.Context, actor int, name string, sealed []byte) error
+	DeleteSecret(ctx context.Context, actor int, name string) error
+}
+
+// Secrets reads and writes the secrets of store sealed by cipher. A nil Secrets, without a master key,
+// has none and cannot store any.
+type Secrets struct {
+	store  Store
+	cipher *Cipher
+}
+
+func New(store Store, c *Cipher) *Secrets {
+	return &Secrets{store: store, cipher: c}
+}
+
+// Enabled reports whether secrets can be stored, i.e. a master key is configured
+func (s *Secrets) Enabled() bool {
+	return s != nil
+}
+
+// Get returns the value of the secret name, false when it is not set
+func (s *Secrets) Get(ctx context.Context, name string) (string, bool, error) {
+	const op = "lib.secrets.Get"
+
+	if s == nil {
+		return "", false, nil
+	}
+
+	sealed, ok, err := s.store.Secret(ctx, name)
+	if err != nil {
+		return "", false, fmt.Errorf("%s: %v", op, err)
+	}
+	if !ok {
+		return "", false, nil
+	}
+
+	value, err := s.cipher.Open(name, sealed)
+	if err != nil {
+		return "", false, fmt.Errorf("%s: %s: %w", op, name, err)
+	}
+	return value, true, nil
+}
+
+// Set seals and stores the value of the secret name, recording the change by actor in the audit log
+func (s *Secrets) Set(ctx context.Context, actor int, name, value string) error {
+	const op = "lib.secrets.Set"
+
+	if !slices.Contains(Names, name) {
+		return fmt.Errorf("%s: %q: %w", op, name, ErrUnknownName)
+	}
+
+	sealed, err := s.cipher.Seal(name, value)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	if err := s.store.SetSecret(ctx, actor, name, sealed); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// Delete deletes the secret name, recording the deletion by actor in the audit log
+func (s *Secrets) Delete(ctx context.Context, actor int, name string) error {
+	const op = "lib.secrets.Delete"
+
+	if err := s.store.DeleteSecret(ctx, actor, name); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// List describes every secret of Names, set or not, without the values
+func (s *Secrets) List(ctx context.Context) ([]Info, error) {
+	const op = "lib.secrets.List"
+
+	stored, err := s.store.SecretInfos(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", op, err)
+	}
+
+	infos := make([]Info, len(Names))
+	for i, name := range Names {
+		infos[i] = Info{Name: name}
+		for _, info := range stored {
+			if info.Name == name {
+				infos[i] = info
+			}
+		}
+	}
+	return infos, nil
+}
+
+// Rekey reseals the secrets sealed with a previous key with the master key and returns how many.
+// Once it ran the previous keys can be dropped from the configuration.
+func (s *Secrets) Rekey(ctx context.Context) (int, error) {
+	const op = "lib.secrets.Rekey"
+
+	all, err := s.store.SealedSecrets(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", op, err)
+	}
+
+	n := 0
+	for name, sealed := range all {
+		if s.cipher.Current(sealed) {
+			continue
+		}
+		value, err := s.cipher.Open(name, sealed)
+		if err != nil {
+			return n, fmt.Errorf("%s: %s: %w", op, name, err)
+		}
+		if sealed, err = s.cipher.Seal(name, value); err != nil {
+			return n, fmt.Errorf("%s: %v", op, err)
+		}
+		if err := s.store.SetSecret(ctx, 0, name, sealed); err != nil {
+			return n, fmt.Errorf("%s: %v", op, err)
+		}
+		n++
+	}
+	return n, nil
+}
@@ -0,0 +1,145 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+)
+
+type memStore struct {
+	sealed map[string][]byte
+}
+
+func (m *memStore) Secret(ctx context.Context, name string) ([]byte, bool, error) {
+	b, ok := m.sealed[name]
+	return b, ok, nil
+}
+
+func (m *memStore) SealedSecrets(ctx context.Context) (map[string][]byte, error) {
+	return m.sealed, nil
+}
+
+func (m *memStore) SecretInfos(ctx context.Context) ([]Info, error) {
+	var infos []Info
+	for name := range m.sealed {
+		infos = append(infos, Info{Name: name, Set: true})
+	}
+	return infos, nil
+}
+
+func (m *memStore) SetSecret(ctx context.Context, actor int, name string, sealed []byte) error {
+	m.sealed[name] = sealed
+	return nil
+}
+
+func (m *memStore) DeleteSecret(ctx context.Context, actor int, name string) error {
+	delete(m.sealed, name)
+	return nil
+}
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, KeySize)
+}
+
+func TestSealOpen(t *testing.T) {
+	c, err := NewCipher(testKey(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sealed, err := c.Seal(SMTPPassword, "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(sealed, []byte("hunter2")) {
+		t.Error("sealed secret holds the value in plain text")
+	}
+	if got, err := c.Open(SMTPPassword, sealed); err != nil || got != "hunter2" {
+		t.Errorf("Open() = %q, %v", got, err)
+	}
+
+	// The name is authenticated, a value moved to another secret does not open.
+	if _, err := c.Open(TelegramBotToken, sealed); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Open() under another name = %v, want ErrDecrypt", err)
+	}
+	sealed[len(sealed)-1] ^= 1
+	if _, err := c.Open(SMTPPassword, sealed); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Open() of a tampered secret = %v, want ErrDecrypt", err)
+	}
+
+	other, _ := NewCipher(testKey(2))
+	sealed, _ = c.Seal(SMTPPassword, "hunter2")
+	if _, err := other.Open(SMTPPassword, sealed); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Open() with an unknown key = %v, want ErrDecrypt", err)
+	}
+
+	if _, err := NewCipher([]byte("short")); err == nil {
+		t.Error("NewCipher() accepted a short key")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	if _, err := Load(Config{}); !errors.Is(err, ErrNoKey) {
+		t.Errorf("Load() without a key = %v, want ErrNoKey", err)
+	}
+
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous, _ := GenerateKey()
+	if _, err := Load(Config{Key: key, PreviousKeys: previous + ", "}); err != nil {
+		t.Errorf("Load() = %v", err)
+	}
+	if _, err := Load(Config{Key: "not base64!"}); err == nil {
+		t.Error("Load() accepted a key that is not base64")
+	}
+}
+
+func TestSecrets(t *testing.T) {
+	ctx := context.Background()
+	store := &memStore{sealed: map[string][]byte{}}
+
+	var disabled *Secrets
+	if _, ok, err := disabled.Get(ctx, SMTPPassword); ok || err != nil || disabled.Enabled() {
+		t.Errorf("nil Secrets has a secret: %v, %v", ok, err)
+	}
+
+	old, _ := NewCipher(testKey(1))
+	s := New(store, old)
+	if err := s.Set(ctx, 1, "unknown", "x"); !errors.Is(err, ErrUnknownName) {
+		t.Errorf("Set() of an unknown name = %v, want ErrUnknownName", err)
+	}
+	if err := s.Set(ctx, 1, SlackSigningSecret, "signing"); err != nil {
+		t.Fatal(err)
+	}
+
+	infos, err := s.List(ctx)
+	if err != nil || len(infos) != len(Names) {
+		t.Fatalf("List() = %+v, %v", infos, err)
+	}
+	for _, i := range infos {
+		if i.Set != (i.Name == SlackSigningSecret) {
+			t.Errorf("List() has %s set = %v", i.Name, i.Set)
+		}
+	}
+
+	// After a rotation the secrets sealed with the previous key still open and are resealed once.
+	rotated, _ := NewCipher(testKey(2), testKey(1))
+	s = New(store, rotated)
+	if got, ok, err := s.Get(ctx, SlackSigningSecret); err != nil || !ok || got != "signing" {
+		t.Errorf("Get() after rotation = %q, %v, %v", got, ok, err)
+	}
+	if n, err := s.Rekey(ctx); err != nil || n != 1 {
+		t.Errorf("Rekey() = %d, %v, want 1", n, err)
+	}
+	if n, _ := s.Rekey(ctx); n != 0 {
+		t.Errorf("second Rekey() resealed %d", n)
+	}
+
+	current, _ := NewCipher(testKey(2))
+	if got, _, err := New(store, current).Get(ctx, SlackSigningSecret); err != nil || got != "signing" {
+		t.Errorf("Get() without the previous key = %q, %v", got, err)
+	}
+}
@@ -0,0 +1,215 @@
+// Package support builds support bundles: a zip archive with what is needed to look into a problem
+// on a self-hosted server, to attach to a bug report. It holds the configuration with secrets redacted,
+// the recent error logs, the migration status and a snapshot of the metrics.
+package support
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/sabbatD/srest-api/internal/lib/clock"
+	"github.com/sabbatD/srest-api/internal/lib/export"
+	"github.com/sabbatD/srest-api/internal/lib/logger/sl"
+	m "github.com/sabbatD/srest-api/internal/lib/meta"
+	"github.com/sabbatD/srest-api/internal/lib/metrics"
+)
+
+// Redacted replaces the value of set secrets, unset ones stay empty so the bundle tells which are set
+const Redacted = "[redacted]"
+
+// summaryWindow is the time the latency summary in the bundle covers
+const summaryWindow = time.Hour
+
+// Migration is a migration known to the database or found in the migrations directory.
+// AppliedAt is empty for migrations not applied yet.
+type Migration struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name,omitempty"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"appliedAt,omitempty"`
+}
+
+type MigrationStatus struct {
+	// Current is the version of the last applied migration
+	Current    int64       `json:"current"`
+	Migrations []Migration `json:"migrations"`
+}
+
+// Migrations reads the migration status of the database
+type Migrations interface {
+	MigrationStatus(ctx context.Context) (MigrationStatus, error)
+}
+
+// Bundle writes support bundles
+type Bundle struct {
+	config     any
+	recent     *sl.Recent
+	migrations Migrations
+	latency    *metrics.Latency
+}
+
+// New bundles config, redacted with Redact, the records kept by recent, the migration status and the metrics
+func New(config any, recent *sl.Recent, migrations Migrations, latency *metrics.Latency) *Bundle {
+	return &Bundle{config: config, recent: recent, migrations: migrations, latency: latency}
+}
+
+// Write writes a bundle to w. A bundle is most needed when something is broken, so parts
+// that cannot be read, e.g. the migration status without a database, hold the error instead.
+func (b *Bundle) Write(ctx context.Context, w io.Writer) error {
+	const op = "lib.support.Bundle.Write"
+
+	err := export.Write(w, "", func(a *export.Archive) error {
+		about := map[string]any{
+			"version": m.Version,
+			"commit":  m.BuildCommit(),
+			"created": clock.Now().UTC(),
+		}
+		if err := a.AddJSON("about.json", about); err != nil {
+			return err
+		}
+
+		if err := a.AddJSON("config.json", Redact(b.config)); err != nil {
+			return err
+		}
+
+		if err := a.AddJSON("errors.json", b.recent.Records()); err != nil {
+			return err
+		}
+
+		var migrations any
+		status, err := b.migrations.MigrationStatus(ctx)
+		if err != nil {
+			migrations = map[string]string{"error": err.Error()}
+		} else {
+			migrations = status
+		}
+		if err := a.AddJSON("migrations.json", migrations); err != nil {
+			return err
+		}
+
+		if err := a.Add("metrics.json", snapshot()); err != nil {
+			return err
+		}
+
+		return a.AddJSON("latency.json", b.latency.Summary(clock.Now(), summaryWindow))
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+// snapshot returns the expvar variables as JSON, as the admin metrics endpoint serves them
+func snapshot() io.Reader {
+	var buf bytes.Buffer
+	buf.WriteString("{\n")
+	first := true
+	expvar.Do(func(kv expvar.KeyValue) {
+		if !first {
+			buf.WriteString(",\n")
+		}
+		first = false
+		fmt.Fprintf(&buf, "%q: %s", kv.Key, kv.Value)
+	})
+	buf.WriteString("\n}\n")
+	return &buf
+}
+
+// Redact returns v as a tree of maps keyed by the yaml names of its fields, or their env names
+// for fields read from the environment only. Set fields tagged redact:"true" are replaced with Redacted.
+func Redact(v any) any {
+	return redact(reflect.ValueOf(v))
+}
+
+func redact(v reflect.Value) any {
+	if !v.IsValid() {
+		return nil
+	}
+	if d, ok := v.Interface().(time.Duration); ok {
+		return d.String()
+	}
+	if _, ok := v.Interface().(json.Marshaler); ok {
+		return v.Interface()
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return redact(v.Elem())
+	case reflect.Struct:
+		out := make(map[string]any)
+		redactFields(v, out)
+		return out
+	case reflect.Slice, reflect.Array:
+		out := make([]any, v.Len())
+		for i := range out {
+			out[i] = redact(v.Index(i))
+		}
+		return out
+	case reflect.Map:
+		out := make(map[string]any, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			out[fmt.Sprint(iter.Key().Interface())] = redact(iter.Value())
+		}
+		return out
+	default:
+		return v.Interface()
+	}
+}
+
+func redactFields(v reflect.Value, out map[string]any) {
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		name, inline := fieldName(f)
+		if inline {
+			if fv := reflect.Indirect(v.Field(i)); fv.Kind() == reflect.Struct {
+				redactFields(fv, out)
+			}
+			continue
+		}
+
+		if f.Tag.Get("redact") == "true" {
+			if v.Field(i).IsZero() {
+				out[name] = ""
+			} else {
+				out[name] = Redacted
+			}
+			continue
+		}
+		out[name] = redact(v.Field(i))
+	}
+}
+
+// fieldName names f by its yaml tag, env tag or Go name. Embedded fields without a name
+// and fields tagged ",inline" have their fields merged into their parent's.
+func fieldName(f reflect.StructField) (string, bool) {
+	name, opts, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+	if opts == "inline" {
+		return "", true
+	}
+	if name != "" && name != "-" {
+		return name, false
+	}
+	if env := f.Tag.Get("env"); env != "" {
+		return env, false
+	}
+	if f.Anonymous && name == "" {
+		return "", true
+	}
+	return f.Name, false
+}
@@ -0,0 +1,121 @@
+package support
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sabbatD/srest-api/internal/config"
+	"github.com/sabbatD/srest-api/internal/lib/export"
+	"github.com/sabbatD/srest-api/internal/lib/logger/sl"
+	"github.com/sabbatD/srest-api/internal/lib/metrics"
+)
+
+type migrations struct {
+	status MigrationStatus
+	err    error
+}
+
+func (m migrations) MigrationStatus(context.Context) (MigrationStatus, error) {
+	return m.status, m.err
+}
+
+func TestRedact(tt *testing.T) {
+	var cfg config.Config
+	cfg.DbString = "postgres://sapi:hunter2@db/sapi"
+	cfg.SMTP.Host = "smtp.example.com"
+	cfg.SMTP.Password = "smtp-secret"
+	cfg.LDAP.BindPassword = "ldap-secret"
+	cfg.Blob.S3.SecretKey = "s3-secret"
+	cfg.Alerting.WebhookURL = "https://hooks.example.com/secret"
+	cfg.SCIM.Token = "scim-secret"
+	cfg.Timeout = 4 * time.Second
+
+	data, err := json.Marshal(Redact(cfg))
+	if err != nil {
+		tt.Fatal(err)
+	}
+	out := string(data)
+
+	for _, secret := range []string{"hunter2", "smtp-secret", "ldap-secret", "s3-secret", "hooks.example.com", "scim-secret"} {
+		if strings.Contains(out, secret) {
+			tt.Errorf("%s is not redacted: %s", secret, out)
+		}
+	}
+
+	var tree map[string]any
+	if err := json.Unmarshal(data, &tree); err != nil {
+		tt.Fatal(err)
+	}
+	smtp := tree["smtp"].(map[string]any)
+	if smtp["host"] != "smtp.example.com" || smtp["SMTP_PASSWORD"] != Redacted || smtp["SMTP_USERNAME"] != "" {
+		tt.Errorf("smtp = %v", smtp)
+	}
+	if server := tree["http_server"].(map[string]any); server["timeout"] != "4s" {
+		tt.Errorf("http_server = %v", server)
+	}
+	// Inlined rules sit next to the interval
+	if alerting := tree["alerting"].(map[string]any); alerting["webhook_url"] != Redacted || alerting["interval"] == nil {
+		tt.Errorf("alerting = %v", alerting)
+	}
+}
+
+func TestWrite(tt *testing.T) {
+	recent := sl.NewRecent(10, slog.LevelError)
+	slog.New(recent.Handler(slog.NewTextHandler(io.Discard, nil))).Error("query failed")
+
+	bundle := New(config.Config{DbString: "postgres://secret"}, recent, migrations{err: errors.New("connection refused")}, metrics.NewLatency(10))
+
+	var buf bytes.Buffer
+	if err := bundle.Write(context.Background(), &buf); err != nil {
+		tt.Fatal(err)
+	}
+
+	manifest, err := export.Verify(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		tt.Fatal(err)
+	}
+	if len(manifest.Files) != 6 {
+		tt.Errorf("files = %v", manifest.Files)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		tt.Fatal(err)
+	}
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			tt.Fatal(err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(data)
+	}
+
+	if strings.Contains(files["config.json"], "postgres://secret") {
+		tt.Errorf("config.json holds the database password: %s", files["config.json"])
+	}
+	if !strings.Contains(files["errors.json"], "query failed") {
+		tt.Errorf("errors.json = %s", files["errors.json"])
+	}
+	// The database being down does not keep the bundle from being written
+	if !strings.Contains(files["migrations.json"], "connection refused") {
+		tt.Errorf("migrations.json = %s", files["migrations.json"])
+	}
+	var snapshot map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(files["metrics.json"]), &snapshot); err != nil {
+		tt.Errorf("metrics.json is not JSON: %v", err)
+	}
+	if _, ok := snapshot["http_errors"]; !ok {
+		tt.Errorf("metrics.json = %s", files["metrics.json"])
+	}
+}
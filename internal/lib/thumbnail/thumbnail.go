@@ -0,0 +1,138 @@
+// Package thumbnail renders downscaled JPEG variants of images kept in the blob store.
+// Rendering runs in a Worker so uploads return before the variants exist.
+package thumbnail
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"path"
+
+	_ "image/gif"
+	_ "image/png"
+
+	"github.com/sabbatD/srest-api/internal/storage/blob"
+)
+
+// Size is a variant fitting into a Max x Max square, smaller images are not upscaled
+type Size struct {
+	Name string
+	Max  int
+}
+
+var DefaultSizes = []Size{{Name: "small", Max: 64}, {Name: "medium", Max: 256}}
+
+// maxPixels guards against decompression bombs, larger images are skipped
+const maxPixels = 40_000_000
+
+// Key is the blob key of a variant of the image stored under key,
+// e.g. "thumbs/small/avatars/1.png.jpg"
+func Key(key string, size Size) string {
+	return path.Join("thumbs", size.Name, key) + ".jpg"
+}
+
+// Variants returns the variant keys by size name, for attachment metadata.
+// The variants may not have been rendered yet.
+func Variants(key string, sizes ...Size) map[string]string {
+	if len(sizes) == 0 {
+		sizes = DefaultSizes
+	}
+	v := make(map[string]string, len(sizes))
+	for _, s := range sizes {
+		v[s.Name] = Key(key, s)
+	}
+	return v
+}
+
+// Generate renders and stores every variant of the image under key
+func Generate(ctx context.Context, store blob.Store, key string, sizes ...Size) error {
+	const op = "lib.thumbnail.Generate"
+
+	if len(sizes) == 0 {
+		sizes = DefaultSizes
+	}
+
+	rc, _, err := store.Get(ctx, key)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	if cfg.Width*cfg.Height > maxPixels {
+		return fmt.Errorf("%s: image of %dx%d is too large", op, cfg.Width, cfg.Height)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	for _, s := range sizes {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, scale(src, s.Max), &jpeg.Options{Quality: 85}); err != nil {
+			return fmt.Errorf("%s: %v", op, err)
+		}
+		if err := store.Put(ctx, Key(key, s), &buf, int64(buf.Len()), "image/jpeg"); err != nil {
+			return fmt.Errorf("%s: %v", op, err)
+		}
+	}
+
+	return nil
+}
+
+// scale downsizes src to fit into max x max by averaging the source pixels covered by each target pixel
+func scale(src image.Image, max int) image.Image {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > max || h > max {
+		if w >= h {
+			w, h = max, h*max/w
+		} else {
+			w, h = w*max/h, max
+		}
+	}
+	w, h = maxInt(w, 1), maxInt(h, 1)
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0, y1 := b.Min.Y+y*b.Dy()/h, b.Min.Y+(y+1)*b.Dy()/h
+		y1 = maxInt(y1, y0+1)
+		for x := 0; x < w; x++ {
+			x0, x1 := b.Min.X+x*b.Dx()/w, b.Min.X+(x+1)*b.Dx()/w
+			x1 = maxInt(x1, x0+1)
+
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, bl, a, n = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca), n+1
+				}
+			}
+			i := dst.PixOffset(x, y)
+			dst.Pix[i+0] = uint8(r / n >> 8)
+			dst.Pix[i+1] = uint8(g / n >> 8)
+			dst.Pix[i+2] = uint8(bl / n >> 8)
+			dst.Pix[i+3] = uint8(a / n >> 8)
+		}
+	}
+	return dst
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
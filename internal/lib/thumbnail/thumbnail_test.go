@@ -0,0 +1,74 @@
+package thumbnail
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/sabbatD/srest-api/internal/storage/blob"
+)
+
+func TestWorker(tt *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	store, err := blob.NewLocal(tt.TempDir())
+	if err != nil {
+		tt.Fatal(err)
+	}
+
+	src := image.NewRGBA(image.Rect(0, 0, 400, 100))
+	for x := 0; x < 400; x++ {
+		for y := 0; y < 100; y++ {
+			src.Set(x, y, color.RGBA{R: 200, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	png.Encode(&buf, src)
+	if err := store.Put(ctx, "avatars/1.png", &buf, int64(buf.Len()), "image/png"); err != nil {
+		tt.Fatal(err)
+	}
+
+	w := NewWorker(slog.New(slog.NewTextHandler(io.Discard, nil)), store, 1)
+	go w.Run(ctx, 1)
+	if !w.Enqueue("avatars/1.png") {
+		tt.Fatal("queue is full")
+	}
+
+	want := map[string]image.Point{"small": {64, 16}, "medium": {256, 64}}
+	for name, key := range Variants("avatars/1.png") {
+		var info blob.Info
+		for ctx.Err() == nil {
+			if info, err = store.Stat(ctx, key); err == nil {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if info.ContentType != "image/jpeg" {
+			tt.Fatalf("%s: %+v, %v", key, info, err)
+		}
+
+		rc, _, err := store.Get(ctx, key)
+		if err != nil {
+			tt.Fatal(err)
+		}
+		img, err := jpeg.Decode(rc)
+		rc.Close()
+		if err != nil {
+			tt.Fatal(err)
+		}
+		if got := img.Bounds().Size(); got != want[name] {
+			tt.Errorf("%s: size = %v, want %v", name, got, want[name])
+		}
+		if r, _, _, _ := img.At(10, 10).RGBA(); r>>8 < 190 || r>>8 > 210 {
+			tt.Errorf("%s: red = %d, want about 200", name, r>>8)
+		}
+	}
+}
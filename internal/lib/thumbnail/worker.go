@@ -0,0 +1,62 @@
+package thumbnail
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"github.com/sabbatD/srest-api/internal/lib/logger/sl"
+	"github.com/sabbatD/srest-api/internal/storage/blob"
+)
+
+// Worker renders variants in the background from an in-memory queue.
+// Queued jobs are lost on restart, Generate can be rerun for images without variants.
+type Worker struct {
+	log   *slog.Logger
+	store blob.Store
+	sizes []Size
+	jobs  chan string
+}
+
+func NewWorker(log *slog.Logger, store blob.Store, queue int, sizes ...Size) *Worker {
+	if len(sizes) == 0 {
+		sizes = DefaultSizes
+	}
+	return &Worker{log: log, store: store, sizes: sizes, jobs: make(chan string, queue)}
+}
+
+// Enqueue schedules the image under key, it reports false when the queue is full
+func (w *Worker) Enqueue(key string) bool {
+	select {
+	case w.jobs <- key:
+		return true
+	default:
+		return false
+	}
+}
+
+// Run processes jobs with n goroutines until ctx is done
+func (w *Worker) Run(ctx context.Context, n int) {
+	const op = "lib.thumbnail.Worker.Run"
+
+	log := w.log.With(slog.String("op", op))
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case key := <-w.jobs:
+					if err := Generate(ctx, w.store, key, w.sizes...); err != nil {
+						log.Error("failed to generate thumbnails", slog.String("key", key), sl.Err(err))
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
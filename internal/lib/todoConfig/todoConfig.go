@@ -1,17 +1,100 @@
 package todoconfig
 
+import "time"
+
+// DateFormat is the format of due dates
+const DateFormat = "2006-01-02"
+
 type Todo struct {
-	ID      uint   `json:"id"`
-	Title   string `json:"title"`
-	Created string `json:"created"`
-	IsDone  bool   `json:"isDone"`
+	ID       uint   `json:"-"`
+	PublicID string `json:"id"`
+	Title    string `json:"title"`
+	Created  string `json:"created"`
+	// Due is the due date as YYYY-MM-DD
+	Due string `json:"due,omitempty"`
+	// Status is a status of the owner's workflow, IsDone tells whether it completes the task
+	Status string `json:"status"`
+	IsDone bool   `json:"isDone"`
+	// Custom holds the values of the user's todo fields
+	Custom map[string]any `json:"custom,omitempty"`
+	// Pinned tasks are listed first
+	Pinned bool `json:"pinned,omitempty"`
+	// Blocked tells whether a task this one depends on is still open, it is not part of the changes feed
+	Blocked bool `json:"blocked,omitempty"`
+	// BlockedBy and Blocks are the ids of the tasks this one depends on and of the ones depending on it,
+	// only a single retrieved task has them
+	BlockedBy []string `json:"blockedBy,omitempty"`
+	Blocks    []string `json:"blocks,omitempty"`
 }
 
 type Todos []Todo
 
 type TodoRequest struct {
-	Title  string `json:"title,omitempty"`
-	IsDone *bool  `json:"isDone,omitempty"`
+	Title string `json:"title,omitempty"`
+	// Status moves the task in the workflow, without it isDone completes or reopens the task
+	Status string `json:"status,omitempty"`
+	IsDone *bool  `json:"isDone,omitempty" extensions:"x-nullable"`
+	// Due sets the due date as YYYY-MM-DD, an empty string removes it
+	Due *string `json:"due,omitempty" extensions:"x-nullable"`
+	// Custom holds the changed custom field values, null removes a value
+	Custom map[string]any `json:"custom,omitempty"`
+}
+
+// TodoQuery selects the tasks of a user
+type TodoQuery struct {
+	// Filter is the completion: all, completed or inWork
+	Filter string
+	// Status is a status of the workflow, empty matches every status
+	Status string
+	// Custom filters by custom field values, each must match exactly
+	Custom map[string]any
+	// Blocked filters by whether a task this one depends on is still open, nil matches every task
+	Blocked *bool
+	// DueFrom and DueTo bound the due date as YYYY-MM-DD, both inclusive, an empty bound is open.
+	// Tasks without a due date only match when both are empty.
+	DueFrom string
+	DueTo   string
+}
+
+// SavedFilter is a named snapshot of the GET /todos query parameters
+type SavedFilter struct {
+	ID       int               `json:"-"`
+	PublicID string            `json:"id"`
+	Name     string            `json:"name"`
+	Query    map[string]string `json:"query"`
+	Created  string            `json:"created"`
+}
+
+type FilterRequest struct {
+	Name string `json:"name" validate:"required,max=100"`
+	// Query holds GET /todos query parameters, e.g. {"status": "in_progress", "custom.priority": "high"}
+	Query map[string]string `json:"query" validate:"max=20"`
+}
+
+type CalendarDay struct {
+	Date      string `json:"date"`
+	Total     int    `json:"total"`
+	Completed int    `json:"completed"`
+	Todos     []Todo `json:"todos"`
+}
+
+// CalendarResponse lists the days from From to To that have tasks due, in date order
+type CalendarResponse struct {
+	From string        `json:"from"`
+	To   string        `json:"to"`
+	Days []CalendarDay `json:"days"`
+}
+
+type HeatmapDay struct {
+	Date  string `json:"date"`
+	Count int    `json:"count"`
+}
+
+// HeatmapResponse holds the completions of a year per day, days without completions are left out
+type HeatmapResponse struct {
+	Year  int          `json:"year"`
+	Total int          `json:"total"`
+	Days  []HeatmapDay `json:"days"`
 }
 
 type TodoInfo struct {
@@ -29,3 +112,93 @@ type MetaResponse struct {
 	Info TodoInfo `json:"info"`
 	Meta Meta     `json:"meta"`
 }
+
+// Change types reported by the changes feed
+const (
+	ChangeCreated = "created"
+	ChangeUpdated = "updated"
+	ChangeDeleted = "deleted"
+)
+
+type Change struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+	Todo *Todo  `json:"todo,omitempty"`
+}
+
+type ChangesResponse struct {
+	Changes []Change `json:"changes"`
+	Cursor  string   `json:"cursor"`
+	HasMore bool     `json:"hasMore"`
+}
+
+// Sync mutation operations
+const (
+	OpCreate = "create"
+	OpUpdate = "update"
+	OpDelete = "delete"
+)
+
+// Sync conflict strategies
+const (
+	StrategyLastWriteWins = "lww"
+	StrategyReject        = "reject"
+)
+
+// Sync result statuses
+const (
+	SyncApplied  = "applied"
+	SyncConflict = "conflict"
+	SyncRejected = "rejected"
+)
+
+type Mutation struct {
+	Op     string  `json:"op"`
+	ID     string  `json:"id,omitempty"`
+	Title  string  `json:"title,omitempty"`
+	Status string  `json:"status,omitempty"`
+	IsDone *bool   `json:"isDone,omitempty" extensions:"x-nullable"`
+	Due    *string `json:"due,omitempty" extensions:"x-nullable"`
+	// Custom holds the changed custom field values of create and update, null removes a value
+	Custom map[string]any `json:"custom,omitempty"`
+}
+
+type SyncRequest struct {
+	Cursor    string     `json:"cursor"`
+	Strategy  string     `json:"strategy,omitempty"`
+	Mutations []Mutation `json:"mutations"`
+}
+
+type SyncResult struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+	Todo   *Todo  `json:"todo,omitempty"`
+}
+
+type SyncResponse struct {
+	Results []SyncResult `json:"results"`
+	Changes []Change     `json:"changes"`
+	Cursor  string       `json:"cursor"`
+	HasMore bool         `json:"hasMore"`
+}
+
+// SyncState is the server-side state of a todo as seen by the sync protocol
+type SyncState struct {
+	ID      int
+	Version int64
+	Deleted bool
+}
+
+// RestoreResult tells how a user's todos changed when restored to AsOf
+type RestoreResult struct {
+	AsOf time.Time `json:"asOf"`
+	// Restored counts deleted todos brought back, Reverted changed ones set back to their old state
+	// and Deleted the ones created after AsOf
+	Restored int `json:"restored"`
+	Reverted int `json:"reverted"`
+	Deleted  int `json:"deleted"`
+	// Skipped are ids of todos that belong to another user by now, e.g. after an account merge
+	Skipped []string `json:"skipped,omitempty"`
+	DryRun  bool     `json:"dryRun"`
+}
@@ -1,9 +1,14 @@
 package userConfig
 
+import (
+	"sort"
+	"time"
+)
+
 type User struct {
 	Login       string `json:"login" validate:"required,min=2,max=60,alpha"`
 	Username    string `json:"username" validate:"required,min=1,max=60,alphanumunicode"`
-	Password    string `json:"password" validate:"required,min=6,max=60,alphanumunicode"`
+	Password    string `json:"password" validate:"required,max=60"`
 	Email       string `json:"email" validate:"required,email"`
 	PhoneNumber string `json:"phoneNumber" validate:"omitempty,e164"`
 }
@@ -12,31 +17,197 @@ type PutUser struct {
 	Username    string `json:"username,omitempty" validate:"min=1,max=60,alphanumunicode"`
 	Email       string `json:"email,omitempty" validate:"min=6,max=60,alphanumunicode"`
 	PhoneNumber string `json:"phoneNumber" validate:"omitempty,e164"`
+	// Locale is the BCP 47 language tag emails to the user are rendered in, e.g. "ru" or "pt-BR".
+	// It is stored in its canonical form, see mail.ParseLocale.
+	Locale string `json:"locale,omitempty" validate:"omitempty,max=35"`
+	// Custom holds the changed custom field values, null removes a value
+	Custom map[string]any `json:"custom,omitempty"`
 }
 
 type Pwd struct {
-	Password string `json:"password" validate:"required,min=6,max=60,alphanumunicode"`
+	Password string `json:"password" validate:"required,max=60"`
+}
+
+// PasswordChange sets a new password, proving the user knows the current one
+type PasswordChange struct {
+	CurrentPassword string `json:"currentPassword" validate:"required,max=60"`
+	NewPassword     string `json:"newPassword" validate:"required,max=60"`
+}
+
+// PasswordChanged is the result of a password change
+type PasswordChanged struct {
+	// RevokedSessions is the number of refresh tokens and remembered devices revoked, the user signs in again everywhere
+	RevokedSessions int64 `json:"revokedSessions"`
+}
+
+// PasswordReset sets a new password with a reset token
+type PasswordReset struct {
+	Token    string `json:"token" validate:"required,max=128"`
+	Password string `json:"password" validate:"required,max=60"`
+}
+
+// PasswordForgot requests a password reset link by email for the account with the login or email Login
+type PasswordForgot struct {
+	Login string `json:"login" validate:"required,max=254"`
+}
+
+// VerificationResend requests a new email verification link for the account with the login or email Login
+type VerificationResend struct {
+	Login string `json:"login" validate:"required,max=254"`
+}
+
+// CredentialsReset is the result of an admin credentials reset. The reset token is only
+// returned when it was not emailed, the admin hands it over to the user.
+type CredentialsReset struct {
+	Emailed    bool      `json:"emailed"`
+	ResetToken string    `json:"resetToken,omitempty"`
+	Expires    time.Time `json:"expires"`
+}
+
+type LoginRequest struct {
+	Login string `json:"login" validate:"required,min=2,max=60,alpha"`
+}
+
+// LoginChange is the result of a login change, NextChangeAt is the earliest time of the next one
+type LoginChange struct {
+	Login        string    `json:"login"`
+	NextChangeAt time.Time `json:"nextChangeAt"`
 }
 
 type AuthData struct {
 	Login    string `json:"login" validate:"required"`
 	Password string `json:"password" validate:"required"`
+	// RememberMe remembers the device: its refresh token lives longer and only works with the device cookie
+	RememberMe bool `json:"rememberMe"`
+}
+
+// ExternalUser is a user authenticated by an external source such as an LDAP directory
+type ExternalUser struct {
+	Source   string
+	Login    string
+	Username string
+	Email    string
+	IsAdmin  bool
+}
+
+// DirectoryUser is a user as managed by an identity provider over SCIM
+type DirectoryUser struct {
+	ID          int       `json:"-"`
+	PublicID    string    `json:"id"`
+	ExternalID  string    `json:"externalId"`
+	Login       string    `json:"login"`
+	Username    string    `json:"username"`
+	Email       string    `json:"email"`
+	PhoneNumber string    `json:"phoneNumber"`
+	Active      bool      `json:"active"`
+	Created     time.Time `json:"created"`
+}
+
+// DirectoryQuery filters directory users by exact login or external id, empty matches all
+type DirectoryQuery struct {
+	Login      string
+	ExternalID string
+	Limit      int
+	Offset     int
 }
 
 type TableUser struct {
-	ID          int    `json:"id"`
+	ID          int    `json:"-"`
+	PublicID    string `json:"id"`
 	Username    string `json:"username"`
 	Email       string `json:"email"`
 	Date        string `json:"date"`
 	IsBlocked   bool   `json:"isBlocked"`
 	IsAdmin     bool   `json:"isAdmin"`
 	PhoneNumber string `json:"phoneNumber"`
+	// Locale is the locale emails to the user are rendered in, empty for the default one
+	Locale string `json:"locale"`
+	// MustChangePassword restricts the user to changing their password until they do
+	MustChangePassword bool `json:"mustChangePassword"`
+	// IsVerified tells the user confirmed their email, accounts are unverified from sign up until they do
+	IsVerified bool `json:"isVerified"`
+	// Role grants the user the admin Permissions, IsAdmin tells it is the admin role
+	Role        string   `json:"role"`
+	Permissions []string `json:"-"`
+	// PasswordChanged is when the password was last changed, nil for users without a local password.
+	// The expiry policy counts from it.
+	PasswordChanged *time.Time `json:"-"`
+	// PasswordExpires is set on the profile when passwords expire, PasswordExpiresSoon once the warning is due
+	PasswordExpires     *time.Time `json:"passwordExpires,omitempty"`
+	PasswordExpiresSoon bool       `json:"passwordExpiresSoon,omitempty"`
+	// Custom holds the values of the custom profile fields, users only see the fields visible to them
+	Custom map[string]any `json:"custom,omitempty"`
+}
+
+// FieldChange is a changed user field, named and valued as in TableUser's JSON
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   any    `json:"old"`
+	New   any    `json:"new"`
+}
+
+// UpdatedUser is the user after an update together with the fields it changed
+type UpdatedUser struct {
+	TableUser
+	Changes []FieldChange `json:"changes"`
+}
+
+// Diff returns the fields that differ between two states of a user
+func Diff(before, after TableUser) []FieldChange {
+	changes := []FieldChange{}
+	add := func(field string, old, new any) {
+		if old != new {
+			changes = append(changes, FieldChange{Field: field, Old: old, New: new})
+		}
+	}
+
+	add("username", before.Username, after.Username)
+	add("email", before.Email, after.Email)
+	add("phoneNumber", before.PhoneNumber, after.PhoneNumber)
+	add("locale", before.Locale, after.Locale)
+	add("isBlocked", before.IsBlocked, after.IsBlocked)
+	add("isAdmin", before.IsAdmin, after.IsAdmin)
+	add("role", before.Role, after.Role)
+	add("mustChangePassword", before.MustChangePassword, after.MustChangePassword)
+
+	keys := make([]string, 0, len(before.Custom)+len(after.Custom))
+	for key := range before.Custom {
+		keys = append(keys, key)
+	}
+	for key := range after.Custom {
+		if _, ok := before.Custom[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		add("custom."+key, before.Custom[key], after.Custom[key])
+	}
+
+	return changes
+}
+
+// User states used by the admin filter
+const (
+	StateActive  = "active"
+	StateBlocked = "blocked"
+	StateDeleted = "deleted"
+	StatePending = "pending"
+)
+
+type StateSummary struct {
+	Active  int `json:"active"`
+	Blocked int `json:"blocked"`
+	Deleted int `json:"deleted"`
+	Pending int `json:"pending"`
 }
 
 type Meta struct {
-	TotalAmount int    `json:"totalAmount"`
-	SortBy      string `json:"sortBy"`
-	SortOrder   string `json:"sortOrder"`
+	TotalAmount int          `json:"totalAmount"`
+	SortBy      string       `json:"sortBy"`
+	SortOrder   string       `json:"sortOrder"`
+	State       string       `json:"state,omitempty"`
+	Summary     StateSummary `json:"summary"`
 }
 
 type MetaResponse struct {
@@ -49,6 +220,65 @@ type GetAllQuery struct {
 	SortBy     string
 	SortOrder  string
 	IsBlocked  bool
-	Limit      int
-	Offset     int
+	State      string
+	// Role filters by role when it is not empty
+	Role string
+	// Custom filters by custom field values, each must match exactly
+	Custom map[string]any
+	Limit  int
+	Offset int
+}
+
+// MergeRequest merges the duplicate account into the primary one, both are public IDs
+type MergeRequest struct {
+	Primary   string `json:"primary" validate:"required,uuid"`
+	Duplicate string `json:"duplicate" validate:"required,uuid"`
+	DryRun    bool   `json:"dryRun"`
+}
+
+// MergeResult reports what a merge changed, or would change for a dry run
+type MergeResult struct {
+	Primary         string `json:"primary"`
+	Duplicate       string `json:"duplicate"`
+	TodosMoved      int64  `json:"todosMoved"`
+	SessionsRevoked int64  `json:"sessionsRevoked"`
+	DryRun          bool   `json:"dryRun"`
+}
+
+// Device is a remembered device, its refresh token outlives the one of a regular sign in
+type Device struct {
+	ID       int       `json:"-"`
+	PublicID string    `json:"id"`
+	Name     string    `json:"name"`
+	LastUsed time.Time `json:"lastUsed"`
+	Expires  time.Time `json:"expires"`
+	Created  time.Time `json:"created"`
+}
+
+// SignIn is a successful sign in, located when a GeoIP database is configured
+type SignIn struct {
+	// Method is password, ldap, google or github
+	Method    string    `json:"method"`
+	IP        string    `json:"ip"`
+	Country   string    `json:"country,omitempty"`
+	City      string    `json:"city,omitempty"`
+	UserAgent string    `json:"userAgent,omitempty"`
+	At        time.Time `json:"at"`
+}
+
+// PendingSignIn is a sign in held back until the user confirms it by email
+type PendingSignIn struct {
+	UserID     int
+	RememberMe bool
+	Confirmed  bool
+	Expires    time.Time
+	SignIn
+}
+
+// Limits overrides the configured limits for one user, a missing field keeps the default.
+// TodoWrites and Reports are requests per rate limit window, zero lifts the limit; MaxTodos caps the number of todos.
+type Limits struct {
+	TodoWrites *int `json:"todoWrites,omitempty" validate:"omitempty,min=0" extensions:"x-nullable"`
+	Reports    *int `json:"reports,omitempty" validate:"omitempty,min=0" extensions:"x-nullable"`
+	MaxTodos   *int `json:"maxTodos,omitempty" validate:"omitempty,min=0" extensions:"x-nullable"`
 }
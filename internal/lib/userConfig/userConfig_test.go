@@ -0,0 +1,22 @@
+package userConfig
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(tt *testing.T) {
+	before := TableUser{Username: "old", Email: "a@example.com", IsAdmin: false}
+	after := TableUser{Username: "new", Email: "a@example.com", IsAdmin: true}
+
+	want := []FieldChange{
+		{Field: "username", Old: "old", New: "new"},
+		{Field: "isAdmin", Old: false, New: true},
+	}
+	if got := Diff(before, after); !reflect.DeepEqual(got, want) {
+		tt.Errorf("Diff = %+v, want %+v", got, want)
+	}
+	if got := Diff(after, after); got == nil || len(got) != 0 {
+		tt.Errorf("Diff of equal users = %#v, want empty", got)
+	}
+}
@@ -0,0 +1,133 @@
+// Package workflow defines the statuses a todo moves through and the allowed transitions between them.
+package workflow
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// Statuses of the default workflow
+const (
+	StatusBacklog    = "backlog"
+	StatusInProgress = "in_progress"
+	StatusDone       = "done"
+)
+
+// ErrInvalid is returned for an invalid workflow, unknown status or transition, the message tells what is wrong
+var ErrInvalid = errors.New("invalid status")
+
+type Status struct {
+	// Key is stored on the todos, lower case letters, digits and underscores
+	Key   string `json:"key" validate:"required,max=40"`
+	Label string `json:"label" validate:"max=100"`
+	// Done statuses complete the task, isDone is true in them
+	Done bool `json:"done"`
+	// Next lists the statuses a task may move to, empty allows every status
+	Next []string `json:"next,omitempty" validate:"max=50,dive,required"`
+}
+
+// Workflow is the ordered list of statuses, the first not done status is the one of new tasks
+type Workflow struct {
+	Statuses []Status `json:"statuses" validate:"required,max=50,dive"`
+}
+
+// Default is the workflow of users who did not define one
+func Default() Workflow {
+	return Workflow{Statuses: []Status{
+		{Key: StatusBacklog, Label: "Backlog", Next: []string{StatusInProgress, StatusDone}},
+		{Key: StatusInProgress, Label: "In progress", Next: []string{StatusBacklog, StatusDone}},
+		{Key: StatusDone, Label: "Done", Done: true, Next: []string{StatusInProgress}},
+	}}
+}
+
+// Check validates what the validate tags cannot: key format, duplicate and unknown keys,
+// and that tasks can be both open and done
+func (w Workflow) Check() error {
+	seen := make(map[string]bool, len(w.Statuses))
+	var open, done bool
+	for _, s := range w.Statuses {
+		for _, c := range s.Key {
+			if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '_' {
+				return fmt.Errorf("key %q may only hold lower case letters, digits and underscores: %w", s.Key, ErrInvalid)
+			}
+		}
+		if seen[s.Key] {
+			return fmt.Errorf("status %q is defined twice: %w", s.Key, ErrInvalid)
+		}
+		seen[s.Key] = true
+		open, done = open || !s.Done, done || s.Done
+	}
+	if !open || !done {
+		return fmt.Errorf("the workflow needs an open and a done status: %w", ErrInvalid)
+	}
+
+	for _, s := range w.Statuses {
+		for _, next := range s.Next {
+			if !seen[next] {
+				return fmt.Errorf("status %q leads to unknown status %q: %w", s.Key, next, ErrInvalid)
+			}
+		}
+	}
+	return nil
+}
+
+// Status returns the status with the key
+func (w Workflow) Status(key string) (Status, bool) {
+	for _, s := range w.Statuses {
+		if s.Key == key {
+			return s, true
+		}
+	}
+	return Status{}, false
+}
+
+// Initial is the status of new tasks and of tasks reopened by old clients through isDone
+func (w Workflow) Initial() string {
+	for _, s := range w.Statuses {
+		if !s.Done {
+			return s.Key
+		}
+	}
+	return StatusBacklog
+}
+
+// Completed is the status of tasks completed by old clients through isDone
+func (w Workflow) Completed() string {
+	for _, s := range w.Statuses {
+		if s.Done {
+			return s.Key
+		}
+	}
+	return StatusDone
+}
+
+// Keys returns the keys of all statuses and of the done ones
+func (w Workflow) Keys() (all, done []string) {
+	all, done = []string{}, []string{}
+	for _, s := range w.Statuses {
+		all = append(all, s.Key)
+		if s.Done {
+			done = append(done, s.Key)
+		}
+	}
+	return all, done
+}
+
+// Move validates the transition of a task from one status to another and returns the target status.
+// An empty from is a new task, which may start in any status. A task in a status that is no longer
+// part of the workflow may move anywhere, so it is never stuck.
+func (w Workflow) Move(from, to string) (Status, error) {
+	target, ok := w.Status(to)
+	if !ok {
+		return target, fmt.Errorf("unknown status %q: %w", to, ErrInvalid)
+	}
+	if from == "" || from == to {
+		return target, nil
+	}
+	current, ok := w.Status(from)
+	if !ok || len(current.Next) == 0 || slices.Contains(current.Next, to) {
+		return target, nil
+	}
+	return target, fmt.Errorf("a task cannot move from %q to %q: %w", from, to, ErrInvalid)
+}
@@ -0,0 +1,59 @@
+package workflow
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	if err := Default().Check(); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, w := range map[string]Workflow{
+		"key format":     {Statuses: []Status{{Key: "To Do"}, {Key: "done", Done: true}}},
+		"duplicate key":  {Statuses: []Status{{Key: "todo"}, {Key: "todo", Done: true}}},
+		"no done status": {Statuses: []Status{{Key: "todo"}, {Key: "doing"}}},
+		"no open status": {Statuses: []Status{{Key: "done", Done: true}}},
+		"unknown next":   {Statuses: []Status{{Key: "todo", Next: []string{"review"}}, {Key: "done", Done: true}}},
+	} {
+		if err := w.Check(); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%s: err = %v, want ErrInvalid", name, err)
+		}
+	}
+}
+
+func TestMove(t *testing.T) {
+	w := Workflow{Statuses: []Status{
+		{Key: "todo", Next: []string{"doing"}},
+		{Key: "doing", Next: []string{"review"}},
+		{Key: "review", Next: []string{"doing", "shipped"}},
+		{Key: "shipped", Done: true},
+	}}
+
+	if s, err := w.Move("doing", "review"); err != nil || s.Done {
+		t.Errorf("allowed move = %+v, %v", s, err)
+	}
+	if s, err := w.Move("review", "shipped"); err != nil || !s.Done {
+		t.Errorf("move to a done status = %+v, %v", s, err)
+	}
+	if _, err := w.Move("shipped", "todo"); err != nil {
+		t.Errorf("a status without next allows every move: %v", err)
+	}
+	if _, err := w.Move("", "review"); err != nil {
+		t.Errorf("new task: %v", err)
+	}
+	if _, err := w.Move("archived", "todo"); err != nil {
+		t.Errorf("a removed status allows every move: %v", err)
+	}
+	if _, err := w.Move("todo", "shipped"); !errors.Is(err, ErrInvalid) {
+		t.Errorf("forbidden move: err = %v, want ErrInvalid", err)
+	}
+	if _, err := w.Move("todo", "blocked"); !errors.Is(err, ErrInvalid) {
+		t.Errorf("unknown status: err = %v, want ErrInvalid", err)
+	}
+
+	if w.Initial() != "todo" || w.Completed() != "shipped" {
+		t.Errorf("Initial() = %q, Completed() = %q", w.Initial(), w.Completed())
+	}
+}
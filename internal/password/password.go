@@ -1,6 +1,9 @@
 package password
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 
 	"golang.org/x/crypto/bcrypt"
@@ -28,3 +31,22 @@ func CheckPassword(hashedPassword []byte, password string) error {
 
 	return nil
 }
+
+// NewToken returns a random token and the hash it is stored by, e.g. of a password reset or a remembered device
+func NewToken() (token, hash string, err error) {
+	const op = "password.NewToken"
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("%s: %v", op, err)
+	}
+	token = hex.EncodeToString(b)
+
+	return token, HashToken(token), nil
+}
+
+// HashToken returns the hash a token is stored by, tokens are random so SHA-256 is enough
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
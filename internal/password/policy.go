@@ -0,0 +1,116 @@
+package password
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Rules a password may violate, returned by Policy.Check
+const (
+	RuleMinLength = "min_length"
+	RuleUpper     = "uppercase"
+	RuleLower     = "lowercase"
+	RuleDigit     = "digit"
+	RuleSymbol    = "symbol"
+	RuleCommon    = "common"
+)
+
+//go:embed common.txt
+var common string
+
+// Config holds the password strength rules, a false Require field leaves that character class optional
+type Config struct {
+	MinLength     int  `yaml:"min_length" env-default:"8"`
+	RequireUpper  bool `yaml:"require_upper"`
+	RequireLower  bool `yaml:"require_lower"`
+	RequireDigit  bool `yaml:"require_digit"`
+	RequireSymbol bool `yaml:"require_symbol"`
+	// Blacklist is a file of common passwords, one per line, refused besides the built-in ones
+	Blacklist string `yaml:"blacklist"`
+}
+
+// Policy checks new passwords against the configured rules
+type Policy struct {
+	cfg       Config
+	blacklist map[string]bool
+}
+
+// NewPolicy returns the policy of cfg with the built-in common passwords and the ones of cfg.Blacklist
+func NewPolicy(cfg Config) (*Policy, error) {
+	const op = "password.NewPolicy"
+
+	p := &Policy{cfg: cfg, blacklist: make(map[string]bool)}
+	p.add(strings.NewReader(common))
+
+	if cfg.Blacklist != "" {
+		f, err := os.Open(cfg.Blacklist)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+		defer f.Close()
+
+		if err := p.add(f); err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+	}
+
+	return p, nil
+}
+
+func (p *Policy) add(r io.Reader) error {
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		if line := strings.TrimSpace(sc.Text()); line != "" && !strings.HasPrefix(line, "#") {
+			p.blacklist[strings.ToLower(line)] = true
+		}
+	}
+	return sc.Err()
+}
+
+// Check returns the rules password violates, none when it is strong enough.
+// Common passwords are matched regardless of case.
+func (p *Policy) Check(password string) []string {
+	var violated []string
+
+	if utf8.RuneCountInString(password) < p.cfg.MinLength {
+		violated = append(violated, RuleMinLength)
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+	if p.cfg.RequireUpper && !upper {
+		violated = append(violated, RuleUpper)
+	}
+	if p.cfg.RequireLower && !lower {
+		violated = append(violated, RuleLower)
+	}
+	if p.cfg.RequireDigit && !digit {
+		violated = append(violated, RuleDigit)
+	}
+	if p.cfg.RequireSymbol && !symbol {
+		violated = append(violated, RuleSymbol)
+	}
+
+	if p.blacklist[strings.ToLower(password)] {
+		violated = append(violated, RuleCommon)
+	}
+
+	return violated
+}
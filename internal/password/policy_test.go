@@ -0,0 +1,44 @@
+package password
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestPolicyCheck(t *testing.T) {
+	blacklist := filepath.Join(t.TempDir(), "blacklist.txt")
+	if err := os.WriteFile(blacklist, []byte("# ours\nEasyDev2024\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := NewPolicy(Config{MinLength: 10, RequireUpper: true, RequireLower: true, RequireDigit: true, RequireSymbol: true, Blacklist: blacklist})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		want     []string
+	}{
+		{name: "strong", password: "Correct-Horse-7"},
+		{name: "short", password: "Ab1-", want: []string{RuleMinLength}},
+		{name: "classes", password: "longlowercase", want: []string{RuleUpper, RuleDigit, RuleSymbol}},
+		{name: "unicode", password: "Пароль-1234", want: nil},
+		{name: "built-in common", password: "PASSWORD123", want: []string{RuleLower, RuleSymbol, RuleCommon}},
+		{name: "configured common", password: "easydev2024", want: []string{RuleUpper, RuleSymbol, RuleCommon}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.Check(tt.password); !slices.Equal(got, tt.want) {
+				t.Errorf("Check(%q) = %v, want %v", tt.password, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := NewPolicy(Config{Blacklist: filepath.Join(t.TempDir(), "missing")}); err == nil {
+		t.Error("NewPolicy() with a missing blacklist succeeded")
+	}
+}
@@ -0,0 +1,88 @@
+// Package blob stores files (avatars, attachments, export archives) outside of the database,
+// on the local disk or in an S3 compatible object storage such as MinIO.
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned when no object is stored under the key
+	ErrNotFound = errors.New("blob not found")
+	// ErrInvalidKey is returned for empty keys, absolute keys and keys with ".." segments
+	ErrInvalidKey = errors.New("invalid blob key")
+)
+
+// Drivers accepted by New
+const (
+	DriverLocal = "local"
+	DriverS3    = "s3"
+)
+
+// Info describes a stored object
+type Info struct {
+	Key         string
+	Size        int64
+	ContentType string
+	Modified    time.Time
+}
+
+// Store keeps objects under slash separated keys, e.g. "avatars/<user uuid>.png".
+type Store interface {
+	// Put stores r under key, replacing any previous object. size is the length of r,
+	// or -1 when unknown; large and unknown sizes are uploaded in parts where the backend supports it.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get returns the object's content, the caller closes it.
+	Get(ctx context.Context, key string) (io.ReadCloser, Info, error)
+	Stat(ctx context.Context, key string) (Info, error)
+	// Delete removes the object, deleting a missing object is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// Config selects and configures the backend, see New.
+// Credentials are only read from the environment.
+type Config struct {
+	Driver string `yaml:"driver" env:"BLOB_DRIVER" env-default:"local"`
+	// Dir is the root directory of the local driver
+	Dir string   `yaml:"dir" env:"BLOB_DIR" env-default:"./data/blobs"`
+	S3  S3Config `yaml:"s3"`
+}
+
+// New returns the store selected by cfg.Driver
+func New(cfg Config) (Store, error) {
+	const op = "storage.blob.New"
+
+	switch cfg.Driver {
+	case DriverLocal, "":
+		s, err := NewLocal(cfg.Dir)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+		return s, nil
+	case DriverS3:
+		s, err := NewS3(cfg.S3)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", op, err)
+		}
+		return s, nil
+	default:
+		return nil, fmt.Errorf("%s: unknown driver %q", op, cfg.Driver)
+	}
+}
+
+func checkKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, `\`) {
+		return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	for _, seg := range strings.Split(key, "/") {
+		if seg == "" || seg == "." || seg == ".." {
+			return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// Local stores objects as files below a root directory.
+// The content type is not stored, it is derived from the key's extension.
+type Local struct {
+	root string
+}
+
+func NewLocal(dir string) (*Local, error) {
+	if dir == "" {
+		return nil, errors.New("local blob directory is not set")
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	return &Local{root: dir}, nil
+}
+
+// Put writes to a temporary file first, readers never see a partially written object.
+func (l *Local) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	const op = "storage.blob.Local.Put"
+
+	if err := checkKey(key); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	name := l.path(key)
+	if err := os.MkdirAll(filepath.Dir(name), 0o750); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if _, err := io.Copy(tmp, &ctxReader{ctx: ctx, r: r}); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+func (l *Local) Get(ctx context.Context, key string) (io.ReadCloser, Info, error) {
+	const op = "storage.blob.Local.Get"
+
+	if err := checkKey(key); err != nil {
+		return nil, Info{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	f, err := os.Open(l.path(key))
+	if err != nil {
+		return nil, Info{}, fmt.Errorf("%s: %w", op, notFound(err))
+	}
+
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, Info{}, fmt.Errorf("%s: %v", op, err)
+	}
+
+	return f, l.info(key, st), nil
+}
+
+func (l *Local) Stat(ctx context.Context, key string) (Info, error) {
+	const op = "storage.blob.Local.Stat"
+
+	if err := checkKey(key); err != nil {
+		return Info{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	st, err := os.Stat(l.path(key))
+	if err != nil {
+		return Info{}, fmt.Errorf("%s: %w", op, notFound(err))
+	}
+
+	return l.info(key, st), nil
+}
+
+func (l *Local) Delete(ctx context.Context, key string) error {
+	const op = "storage.blob.Local.Delete"
+
+	if err := checkKey(key); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := os.Remove(l.path(key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+
+	return nil
+}
+
+func (l *Local) path(key string) string {
+	return filepath.Join(l.root, filepath.FromSlash(key))
+}
+
+func (l *Local) info(key string, st fs.FileInfo) Info {
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return Info{Key: key, Size: st.Size(), ContentType: contentType, Modified: st.ModTime()}
+}
+
+func notFound(err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// ctxReader stops a copy once ctx is done
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *ctxReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
@@ -0,0 +1,388 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// minPartSize is the smallest part S3 accepts, except for the last one
+	minPartSize = 5 << 20
+	// maxParts is the S3 limit of parts per upload
+	maxParts = 10000
+
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+)
+
+// S3Config configures an S3 compatible backend
+type S3Config struct {
+	// Endpoint is the service URL, e.g. https://s3.eu-central-1.amazonaws.com or http://minio:9000
+	Endpoint  string `yaml:"endpoint" env:"S3_ENDPOINT"`
+	Region    string `yaml:"region" env:"S3_REGION" env-default:"us-east-1"`
+	Bucket    string `yaml:"bucket" env:"S3_BUCKET"`
+	AccessKey string `yaml:"-" env:"S3_ACCESS_KEY" redact:"true"`
+	SecretKey string `yaml:"-" env:"S3_SECRET_KEY" redact:"true"`
+	// PathStyle addresses the bucket in the path instead of the host name, as MinIO expects by default
+	PathStyle bool `yaml:"path_style" env:"S3_PATH_STYLE"`
+	// PartSize is the multipart upload part size, objects up to it are uploaded with a single request
+	PartSize int64 `yaml:"part_size" env:"S3_PART_SIZE" env-default:"16777216"`
+}
+
+// S3 stores objects in a bucket of an S3 compatible service.
+// Requests are signed with AWS Signature Version 4, payloads are not hashed (UNSIGNED-PAYLOAD),
+// so the endpoint should be HTTPS outside of a private network.
+type S3 struct {
+	cfg      S3Config
+	endpoint *url.URL
+	client   *http.Client
+	now      func() time.Time
+}
+
+func NewS3(cfg S3Config) (*S3, error) {
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", cfg.Endpoint)
+	}
+	if cfg.Bucket == "" || cfg.AccessKey == "" || cfg.SecretKey == "" {
+		return nil, errors.New("S3 bucket and credentials are required")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if cfg.PartSize < minPartSize {
+		cfg.PartSize = minPartSize
+	}
+
+	return &S3{cfg: cfg, endpoint: endpoint, client: &http.Client{}, now: time.Now}, nil
+}
+
+func (s *S3) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	const op = "storage.blob.S3.Put"
+
+	if err := checkKey(key); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	if size >= 0 && size <= s.cfg.PartSize {
+		header := http.Header{"Content-Type": {contentType}}
+		res, err := s.do(ctx, http.MethodPut, key, nil, header, io.LimitReader(r, size), size)
+		if err != nil {
+			return fmt.Errorf("%s: %v", op, err)
+		}
+		res.Body.Close()
+		return nil
+	}
+
+	if err := s.multipart(ctx, key, r, contentType); err != nil {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	return nil
+}
+
+func (s *S3) Get(ctx context.Context, key string) (io.ReadCloser, Info, error) {
+	const op = "storage.blob.S3.Get"
+
+	if err := checkKey(key); err != nil {
+		return nil, Info{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := s.do(ctx, http.MethodGet, key, nil, nil, nil, 0)
+	if err != nil {
+		return nil, Info{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return res.Body, s.info(key, res), nil
+}
+
+func (s *S3) Stat(ctx context.Context, key string) (Info, error) {
+	const op = "storage.blob.S3.Stat"
+
+	if err := checkKey(key); err != nil {
+		return Info{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := s.do(ctx, http.MethodHead, key, nil, nil, nil, 0)
+	if err != nil {
+		return Info{}, fmt.Errorf("%s: %w", op, err)
+	}
+	res.Body.Close()
+
+	return s.info(key, res), nil
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	const op = "storage.blob.S3.Delete"
+
+	if err := checkKey(key); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := s.do(ctx, http.MethodDelete, key, nil, nil, nil, 0)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("%s: %v", op, err)
+	}
+	if res != nil {
+		res.Body.Close()
+	}
+
+	return nil
+}
+
+// multipart uploads r in parts of PartSize, the upload is aborted on failure
+// so the service does not keep the uploaded parts.
+func (s *S3) multipart(ctx context.Context, key string, r io.Reader, contentType string) error {
+	res, err := s.do(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, http.Header{"Content-Type": {contentType}}, nil, 0)
+	if err != nil {
+		return fmt.Errorf("initiate multipart upload: %v", err)
+	}
+	var initiated struct {
+		UploadID string `xml:"UploadId"`
+	}
+	err = xml.NewDecoder(res.Body).Decode(&initiated)
+	res.Body.Close()
+	if err != nil {
+		return fmt.Errorf("initiate multipart upload: %v", err)
+	}
+
+	upload := url.Values{"uploadId": {initiated.UploadID}}
+	if err := s.uploadParts(ctx, key, r, upload); err != nil {
+		// Abort with a fresh context, ctx may be the reason of the failure.
+		abortCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if res, abortErr := s.do(abortCtx, http.MethodDelete, key, upload, nil, nil, 0); abortErr == nil {
+			res.Body.Close()
+		}
+		return err
+	}
+	return nil
+}
+
+type completedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+func (s *S3) uploadParts(ctx context.Context, key string, r io.Reader, upload url.Values) error {
+	var parts []completedPart
+	buf := make([]byte, s.cfg.PartSize)
+
+	for n := 1; ; n++ {
+		read, err := io.ReadFull(r, buf)
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("read part %d: %v", n, err)
+		}
+		if read == 0 && n > 1 {
+			break
+		}
+		if n > maxParts {
+			return fmt.Errorf("object exceeds %d parts of %d bytes", maxParts, s.cfg.PartSize)
+		}
+
+		q := url.Values{"partNumber": {strconv.Itoa(n)}, "uploadId": upload["uploadId"]}
+		res, err := s.do(ctx, http.MethodPut, key, q, nil, bytes.NewReader(buf[:read]), int64(read))
+		if err != nil {
+			return fmt.Errorf("upload part %d: %v", n, err)
+		}
+		res.Body.Close()
+		parts = append(parts, completedPart{PartNumber: n, ETag: res.Header.Get("ETag")})
+
+		if read < len(buf) {
+			break
+		}
+	}
+
+	body, err := xml.Marshal(struct {
+		XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+		Parts   []completedPart `xml:"Part"`
+	}{Parts: parts})
+	if err != nil {
+		return err
+	}
+
+	res, err := s.do(ctx, http.MethodPost, key, upload, http.Header{"Content-Type": {"application/xml"}}, bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		return fmt.Errorf("complete multipart upload: %v", err)
+	}
+	defer res.Body.Close()
+
+	// S3 may answer 200 with an error document once the parts are being assembled.
+	var result struct {
+		XMLName xml.Name
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	if err := xml.NewDecoder(res.Body).Decode(&result); err == nil && result.XMLName.Local == "Error" {
+		return fmt.Errorf("complete multipart upload: %s: %s", result.Code, result.Message)
+	}
+	return nil
+}
+
+// do sends a signed request, non 2xx answers are returned as errors, 404 as ErrNotFound.
+func (s *S3) do(ctx context.Context, method, key string, query url.Values, header http.Header, body io.Reader, size int64) (*http.Response, error) {
+	u := *s.endpoint
+	if s.cfg.PathStyle {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.cfg.Bucket + "/" + key
+	} else {
+		u.Host = s.cfg.Bucket + "." + u.Host
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + key
+	}
+	// The path is sent exactly as it is signed
+	u.RawPath = canonicalURI(u.Path)
+	u.RawQuery = canonicalQuery(query)
+
+	if body != nil && size == 0 {
+		// A zero length with a non-nil body would be sent chunked, which S3 rejects
+		body = http.NoBody
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	s.sign(req, unsignedPayload, s.now())
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode/100 == 2 {
+		return res, nil
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+	return nil, fmt.Errorf("%s %s: %s: %s", method, key, res.Status, bytes.TrimSpace(msg))
+}
+
+func (s *S3) info(key string, res *http.Response) Info {
+	modified, _ := http.ParseTime(res.Header.Get("Last-Modified"))
+	return Info{Key: key, Size: res.ContentLength, ContentType: res.Header.Get("Content-Type"), Modified: modified}
+}
+
+// sign adds the AWS Signature Version 4 headers to req. The signed headers are
+// host, content-type, range and all x-amz-* headers.
+func (s *S3) sign(req *http.Request, payloadHash string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		k = strings.ToLower(k)
+		if k == "content-type" || k == "range" || strings.HasPrefix(k, "x-amz-") {
+			headers[k] = strings.TrimSpace(strings.Join(v, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalURI(req.URL.Path),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.cfg.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hashHex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+s.cfg.SecretKey), date)
+	key = hmacSHA256(key, s.cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.cfg.AccessKey, scope, signedHeaders, signature))
+}
+
+// canonicalQuery encodes q sorted by key as SigV4 requires, it is also used as the request query
+func canonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, k := range keys {
+		for _, v := range q[k] {
+			parts = append(parts, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func canonicalURI(path string) string {
+	if path == "" {
+		return "/"
+	}
+	return uriEncode(path, false)
+}
+
+// uriEncode percent-encodes everything but unreserved characters, and slashes unless encodeSlash
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hashHex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
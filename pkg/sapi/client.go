@@ -0,0 +1,287 @@
+// Package sapi is a Go client of the API. The methods and models in sapi.gen.go are generated from
+// docs/swagger.json by cmd/sdkgen, this file holds the transport: tokens, refreshing and errors.
+//
+//	c := sapi.New("https://easydev.club/api/v1")
+//	if _, err := c.SignIn(ctx, sapi.AuthData{Login: "alice", Password: "..."}); err != nil {
+//		return err
+//	}
+//	todos, err := c.ListTodos(ctx, &sapi.ListTodosParams{Filter: "inWork"})
+//
+// Signing in stores the tokens in the client. An expired access token is refreshed once with the refresh token
+// and the request retried; use WithTokenHook to persist the new tokens.
+package sapi
+
+//go:generate go run ../../cmd/sdkgen -spec ../../docs/swagger.json -out sapi.gen.go
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultClientName is sent in the X-Client header unless WithClientName is given
+const DefaultClientName = "sapi-go/1"
+
+type Client struct {
+	// BaseURL is the API base path, e.g. https://easydev.club/api/v1
+	BaseURL string
+
+	http *http.Client
+	name string
+	hook func(access, refresh string)
+
+	mu      sync.Mutex
+	access  string
+	refresh string
+}
+
+type Option func(*Client)
+
+// WithHTTPClient sends requests with hc. Give it a cookie jar to keep remembered devices working.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.http = hc }
+}
+
+// WithTokens starts the client with stored tokens instead of signing in
+func WithTokens(access, refresh string) Option {
+	return func(c *Client) { c.access, c.refresh = access, refresh }
+}
+
+// WithClientName sets the X-Client header identifying the application, e.g. "cli/1.4.0"
+func WithClientName(name string) Option {
+	return func(c *Client) { c.name = name }
+}
+
+// WithTokenHook calls hook whenever the tokens change: on sign in, refresh and log out (with empty tokens)
+func WithTokenHook(hook func(access, refresh string)) Option {
+	return func(c *Client) { c.hook = hook }
+}
+
+func New(baseURL string, opts ...Option) *Client {
+	jar, _ := cookiejar.New(nil)
+	c := &Client{
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		http:    &http.Client{Jar: jar, Timeout: 30 * time.Second},
+		name:    DefaultClientName,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Tokens returns the current access and refresh tokens
+func (c *Client) Tokens() (access, refresh string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.access, c.refresh
+}
+
+// SetTokens replaces the tokens, e.g. after signing in elsewhere
+func (c *Client) SetTokens(access, refresh string) {
+	c.mu.Lock()
+	c.access, c.refresh = access, refresh
+	c.mu.Unlock()
+	if c.hook != nil {
+		c.hook(access, refresh)
+	}
+}
+
+// Error is a failed request. Problem holds the RFC 7807 body the API answers errors with, if any.
+type Error struct {
+	StatusCode int
+	Problem    *Problem
+	// Body is the raw body of errors that are not problems
+	Body string
+	// RetryAfter is set on rate limited and locked requests
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	if e.Problem != nil {
+		if e.Problem.Detail != "" {
+			return fmt.Sprintf("sapi: %d %s: %s", e.StatusCode, e.Problem.Code, e.Problem.Detail)
+		}
+		return fmt.Sprintf("sapi: %d %s: %s", e.StatusCode, e.Problem.Code, e.Problem.Title)
+	}
+	return fmt.Sprintf("sapi: %d %s", e.StatusCode, strings.TrimSpace(e.Body))
+}
+
+// Code returns the problem code of err, e.g. RATE_LIMITED, or "" when err is no API problem
+func Code(err error) string {
+	var e *Error
+	if errors.As(err, &e) && e.Problem != nil {
+		return e.Problem.Code
+	}
+	return ""
+}
+
+// do sends a request to path under the base path and decodes the response into out
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, in, out any) error {
+	return c.send(ctx, method, c.BaseURL+path, path, query, in, out)
+}
+
+// doRoot sends a request to path at the root of the host, for the routes outside the base path
+func (c *Client) doRoot(ctx context.Context, method, path string, query url.Values, in, out any) error {
+	base, err := url.Parse(c.BaseURL)
+	if err != nil {
+		return err
+	}
+	base.Path = ""
+	return c.send(ctx, method, base.String()+path, path, query, in, out)
+}
+
+func (c *Client) send(ctx context.Context, method, target, path string, query url.Values, in, out any) error {
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return err
+		}
+	}
+
+	access, _ := c.Tokens()
+	resp, err := c.request(ctx, method, target, body, access)
+	if err != nil {
+		return err
+	}
+	// The auth routes answer 401 for bad credentials, refreshing would not help
+	if resp.StatusCode == http.StatusUnauthorized && !strings.HasPrefix(path, "/auth/") {
+		unauthorized := readError(resp)
+		resp.Body.Close()
+		refreshed, err := c.refreshTokens(ctx, access)
+		if err != nil {
+			return err
+		}
+		if refreshed == "" {
+			return unauthorized
+		}
+		access = refreshed
+		if resp, err = c.request(ctx, method, target, body, access); err != nil {
+			return err
+		}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return readError(resp)
+	}
+	if err := decode(resp, out); err != nil {
+		return err
+	}
+
+	switch t := out.(type) {
+	case *Tokens:
+		c.SetTokens(t.AccessToken, t.RefreshToken)
+	case *GuestSession:
+		c.SetTokens(t.AccessToken, "")
+	}
+	if path == "/auth/logout" {
+		c.SetTokens("", "")
+	}
+	return nil
+}
+
+func (c *Client) request(ctx context.Context, method, target string, body []byte, access string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("X-Client", c.name)
+	if access != "" {
+		req.Header.Set("Authorization", "Bearer "+access)
+	}
+	return c.http.Do(req)
+}
+
+// refreshTokens trades the refresh token for new tokens and returns the new access token, "" without a refresh token.
+// Concurrent requests failing with the same expired token refresh once: the others find the token already changed.
+func (c *Client) refreshTokens(ctx context.Context, expired string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.access != expired {
+		return c.access, nil
+	}
+	if c.refresh == "" {
+		return "", nil
+	}
+
+	body, err := json.Marshal(RefreshToken{RefreshToken: c.refresh})
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.request(ctx, http.MethodPost, c.BaseURL+"/auth/refresh", body, "")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return "", readError(resp)
+	}
+
+	var tokens Tokens
+	if err := decode(resp, &tokens); err != nil {
+		return "", err
+	}
+	c.access, c.refresh = tokens.AccessToken, tokens.RefreshToken
+	if c.hook != nil {
+		c.hook(c.access, c.refresh)
+	}
+	return c.access, nil
+}
+
+func decode(resp *http.Response, out any) error {
+	switch out := out.(type) {
+	case nil:
+		_, err := io.Copy(io.Discard, resp.Body)
+		return err
+	case *[]byte:
+		data, err := io.ReadAll(resp.Body)
+		*out = data
+		return err
+	}
+	if resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func readError(resp *http.Response) error {
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	e := &Error{StatusCode: resp.StatusCode}
+	if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		e.RetryAfter = time.Duration(secs) * time.Second
+	}
+	if typ, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); typ == "application/problem+json" {
+		var p Problem
+		if json.Unmarshal(data, &p) == nil {
+			e.Problem = &p
+			return e
+		}
+	}
+	e.Body = string(data)
+	return e
+}
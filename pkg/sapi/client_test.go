@@ -0,0 +1,125 @@
+package sapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRefresh(t *testing.T) {
+	var mu sync.Mutex
+	refreshes := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/auth/refresh", func(w http.ResponseWriter, r *http.Request) {
+		var req RefreshToken
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken != "refresh-1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mu.Lock()
+		refreshes++
+		mu.Unlock()
+		json.NewEncoder(w).Encode(Tokens{AccessToken: "access-2", RefreshToken: "refresh-2"})
+	})
+	mux.HandleFunc("GET /api/v1/todos/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer access-2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if got := r.Header.Get("X-Client"); got != DefaultClientName {
+			t.Errorf("X-Client = %q, want %q", got, DefaultClientName)
+		}
+		json.NewEncoder(w).Encode(Todo{ID: "7", Title: r.PathValue("id")})
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	var hooked string
+	c := New(srv.URL+"/api/v1", WithTokens("access-1", "refresh-1"), WithTokenHook(func(access, _ string) { hooked = access }))
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			todo, err := c.GetTodo(context.Background(), "a b")
+			if err != nil {
+				t.Errorf("GetTodo() error = %v", err)
+				return
+			}
+			if todo.Title != "a b" {
+				t.Errorf("GetTodo() title = %q, want the escaped path parameter", todo.Title)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if refreshes != 1 {
+		t.Errorf("refreshes = %d, want 1", refreshes)
+	}
+	if access, refresh := c.Tokens(); access != "access-2" || refresh != "refresh-2" || hooked != "access-2" {
+		t.Errorf("Tokens() = %s, %s, hook got %s, want the refreshed tokens", access, refresh, hooked)
+	}
+}
+
+func TestError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/auth/signin":
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.Header().Set("Retry-After", "60")
+			w.WriteHeader(http.StatusLocked)
+			json.NewEncoder(w).Encode(Problem{Code: "LOCKED", Status: http.StatusLocked, Title: "Locked"})
+		default:
+			// Without a refresh token the first 401 is the answer
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte("unauthorized\n"))
+		}
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL + "/api/v1")
+	_, err := c.SignIn(context.Background(), AuthData{Login: "alice", Password: "wrong"})
+	if Code(err) != "LOCKED" {
+		t.Fatalf("SignIn() error = %v, want a LOCKED problem", err)
+	}
+	if e := err.(*Error); e.StatusCode != http.StatusLocked || e.RetryAfter != time.Minute {
+		t.Errorf("SignIn() error = %+v, want 423 retried after a minute", e)
+	}
+
+	_, err = c.GetProfile(context.Background())
+	if e, ok := err.(*Error); !ok || e.StatusCode != http.StatusUnauthorized || e.Body != "unauthorized\n" {
+		t.Errorf("GetProfile() error = %v, want the 401", err)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/healthz" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(Health{Status: "ok"})
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL + "/api/v1")
+	blocked := false
+	values := (&ListUsersParams{Search: "al", IsBlocked: &blocked, Limit: 5, Custom: map[string]string{"team": "qa"}}).values()
+	if got, want := values.Encode(), "custom.team=qa&isBlocked=false&limit=5&search=al"; got != want {
+		t.Errorf("values() = %s, want %s", got, want)
+	}
+	if got := (*ListUsersParams)(nil).values(); len(got) != 0 {
+		t.Errorf("nil values() = %v, want none", got)
+	}
+
+	// healthz is served outside the base path
+	health, err := c.Healthz(context.Background())
+	if err != nil || health.Status != "ok" {
+		t.Errorf("Healthz() = %+v, %v, want ok", health, err)
+	}
+}